@@ -0,0 +1,149 @@
+package cmd
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/spf13/cobra"
+	"go.keploy.io/server/pkg/platform/kube"
+	"go.keploy.io/server/pkg/service/agent"
+	"go.uber.org/zap"
+)
+
+func NewCmdAgent(logger *zap.Logger) *Agent {
+	return &Agent{
+		agent:  agent.NewAgent(logger),
+		logger: logger,
+	}
+}
+
+type Agent struct {
+	agent  agent.Agent
+	logger *zap.Logger
+}
+
+func (a *Agent) GetCmd() *cobra.Command {
+	var agentCmd = &cobra.Command{
+		Use:   "agent [-- flags of keploy record and test]",
+		Short: "record or test the application of a kubernetes pod, run as its sidecar or as a DaemonSet",
+		Example: `keploy agent --selector app=payments
+keploy agent --selector app=payments --node $NODE_NAME --session-duration 1h -- --delay 10`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var options agent.Options
+			var err error
+			options.Path, err = cmd.Flags().GetString("path")
+			if err != nil {
+				a.logger.Error("failed to read the path flag", zap.Error(err))
+				return err
+			}
+			if options.Path == "" {
+				options.Path, err = os.Getwd()
+				if err != nil {
+					a.logger.Error("failed to get the path of current directory", zap.Error(err))
+					return err
+				}
+			}
+			options.Path, err = filepath.Abs(options.Path)
+			if err != nil {
+				a.logger.Error("failed to get the absolute path from relative path", zap.Error(err))
+				return err
+			}
+			options.ConfigPath, err = cmd.Flags().GetString("config-path")
+			if err != nil {
+				a.logger.Error("failed to read the config path")
+				return err
+			}
+			options.Selector, err = cmd.Flags().GetString("selector")
+			if err != nil {
+				a.logger.Error("failed to read the selector flag", zap.Error(err))
+				return err
+			}
+			if options.Selector == "" {
+				a.logger.Error("the label selector of the pods is required, like --selector app=payments")
+				return errors.New("missing the label selector of the pods")
+			}
+			options.Namespace, err = cmd.Flags().GetString("namespace")
+			if err != nil {
+				a.logger.Error("failed to read the namespace flag", zap.Error(err))
+				return err
+			}
+			if options.Namespace == "" {
+				// the pods of the namespace of the agent by default
+				if options.Namespace, err = kube.Namespace(); err != nil {
+					a.logger.Error("failed to read the namespace of the pod of keploy, set it with --namespace", zap.Error(err))
+					return err
+				}
+			}
+			options.Node, err = cmd.Flags().GetString("node")
+			if err != nil {
+				a.logger.Error("failed to read the node flag", zap.Error(err))
+				return err
+			}
+			options.Container, err = cmd.Flags().GetString("container")
+			if err != nil {
+				a.logger.Error("failed to read the container flag", zap.Error(err))
+				return err
+			}
+			options.Mode, err = cmd.Flags().GetString("mode")
+			if err != nil {
+				a.logger.Error("failed to read the mode flag", zap.Error(err))
+				return err
+			}
+			if options.Mode != agent.ModeRecord && options.Mode != agent.ModeTest && options.Mode != agent.ModeOff {
+				a.logger.Error("the mode of the agent is record, test or off", zap.String("mode", options.Mode))
+				return errors.New("invalid --mode flag")
+			}
+			options.PollInterval, err = cmd.Flags().GetDuration("poll-interval")
+			if err != nil {
+				a.logger.Error("failed to read the poll-interval flag", zap.Error(err))
+				return err
+			}
+			if options.PollInterval <= 0 {
+				a.logger.Error("the poll interval must be positive", zap.Duration("poll-interval", options.PollInterval))
+				return errors.New("invalid --poll-interval flag")
+			}
+			options.SessionDuration, err = cmd.Flags().GetDuration("session-duration")
+			if err != nil {
+				a.logger.Error("failed to read the session-duration flag", zap.Error(err))
+				return err
+			}
+			options.MetricsAddr, err = cmd.Flags().GetString("metrics-addr")
+			if err != nil {
+				a.logger.Error("failed to read the metrics-addr flag", zap.Error(err))
+				return err
+			}
+			options.Args = args
+
+			options.Storage, err = remoteStorage(options.ConfigPath, a.logger)
+			if err != nil {
+				a.logger.Error("failed to set up the storage of the test sets", zap.Error(err))
+				return err
+			}
+			return a.agent.Run(options)
+		},
+	}
+
+	agentCmd.Flags().StringP("selector", "l", "", "Label selector of the pods of the application, like app=payments")
+
+	agentCmd.Flags().String("namespace", os.Getenv("POD_NAMESPACE"), "Namespace of the pods, the one of the pod of keploy by default")
+
+	agentCmd.Flags().String("node", os.Getenv("NODE_NAME"), "Node of the pods, set to the node of a DaemonSet pod")
+
+	agentCmd.Flags().String("container", "", "Container of the pods running the application, the first one whose process is found by default")
+
+	agentCmd.Flags().String("mode", agent.ModeRecord, "Mode of the pods without the keploy.io/mode annotation: record, test or off")
+
+	agentCmd.Flags().Duration("poll-interval", 10*time.Second, "Interval between the reads of the pods and of their annotations")
+
+	agentCmd.Flags().Duration("session-duration", 0, "Duration after which the recording is pushed to the storage and a new test set started, never by default")
+
+	agentCmd.Flags().String("metrics-addr", "", "Address the metrics of the agent and of the recording or test run of its pod are served on in the Prometheus format, like :9090, at /metrics")
+
+	agentCmd.Flags().StringP("path", "p", "", "Path to the local directory where the test sets are kept")
+
+	agentCmd.Flags().String("config-path", ".", "Path to the local directory where keploy configuration file is stored")
+
+	return agentCmd
+}
@@ -0,0 +1,124 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"go.keploy.io/server/pkg/service/contract"
+	"go.keploy.io/server/pkg/shutdown"
+	"go.uber.org/zap"
+	"gopkg.in/yaml.v3"
+)
+
+func NewCmdContract(logger *zap.Logger) *Contract {
+	verifier := contract.NewContract(logger)
+	return &Contract{
+		contract: verifier,
+		logger:   logger,
+	}
+}
+
+type Contract struct {
+	contract contract.Contract
+	logger   *zap.Logger
+}
+
+func (c *Contract) GetCmd() *cobra.Command {
+	var contractCmd = &cobra.Command{
+		Use:   "contract",
+		Short: "check the compatibility of the services with the traffic recorded by their consumers",
+	}
+
+	var verifyCmd = &cobra.Command{
+		Use:   "verify",
+		Short: "replay the http mocks recorded by a consumer against the provider, exiting with 1 when a response is incompatible",
+		Example: `keploy contract verify -p /path/to/consumer --provider-url http://localhost:8080 --host orders:8080
+keploy contract verify -t test-set-0 --provider-url http://localhost:8080 --report contract-report.yaml`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			path, err := keployPath(cmd, c.logger)
+			if err != nil {
+				return err
+			}
+			testSets, err := cmd.Flags().GetStringSlice("testsets")
+			if err != nil {
+				c.logger.Error("failed to read the testsets flag", zap.Error(err))
+				return err
+			}
+			var options contract.VerifyOptions
+			options.ProviderURL, err = cmd.Flags().GetString("provider-url")
+			if err != nil {
+				c.logger.Error("failed to read the provider-url flag", zap.Error(err))
+				return err
+			}
+			if options.ProviderURL == "" {
+				c.logger.Error("the url of the provider is required, like --provider-url http://localhost:8080")
+				return errors.New("missing the provider url")
+			}
+			options.Hosts, err = cmd.Flags().GetStringSlice("host")
+			if err != nil {
+				c.logger.Error("failed to read the host flag", zap.Error(err))
+				return err
+			}
+			options.ApiTimeout, err = cmd.Flags().GetUint64("apiTimeout")
+			if err != nil {
+				c.logger.Error("failed to read the apiTimeout flag", zap.Error(err))
+				return err
+			}
+			reportPath, err := cmd.Flags().GetString("report")
+			if err != nil {
+				c.logger.Error("failed to read the report flag", zap.Error(err))
+				return err
+			}
+
+			report, err := c.contract.Verify(path, testSets, options)
+			if err != nil {
+				c.logger.Error("failed to verify the provider", zap.Error(err))
+				return err
+			}
+			for _, interaction := range report.Interactions {
+				if interaction.Status == contract.StatusFailed {
+					c.logger.Error("the response of the provider is incompatible with the one recorded by the consumer", zap.String("testSet", interaction.TestSet), zap.String("mock", interaction.Mock), zap.String("method", interaction.Method), zap.String("path", interaction.Path), zap.Strings("errors", interaction.Errors))
+				}
+			}
+			if reportPath != "" {
+				if err := writeContractReport(reportPath, report); err != nil {
+					c.logger.Error("failed to write the contract report", zap.String("report", reportPath), zap.Error(err))
+					return err
+				}
+			}
+			c.logger.Info("contract verification completed", zap.String("provider", report.Provider), zap.Int("passed", report.Passed), zap.Int("failed", report.Failed))
+			if report.Failed > 0 {
+				shutdown.Exit(c.logger, 1, "failed contract verification")
+			}
+			return nil
+		},
+	}
+	verifyCmd.Flags().StringP("path", "p", "", "Path to the local directory of the consumer where the generated testcases/mocks are stored")
+	verifyCmd.Flags().StringSliceP("testsets", "t", []string{}, "Test sets of the consumer to verify, all of them by default")
+	verifyCmd.Flags().String("provider-url", "", "Base url of the running provider, like http://localhost:8080")
+	verifyCmd.Flags().StringSlice("host", []string{}, "Hosts of the provider in the recorded requests, like orders:8080, all the http mocks being replayed by default")
+	verifyCmd.Flags().Uint64("apiTimeout", 5, "User provided timeout for calling the provider")
+	verifyCmd.Flags().String("report", "", "File of the report of the verification, written as JSON when it ends in .json and as YAML otherwise")
+
+	contractCmd.AddCommand(verifyCmd)
+	return contractCmd
+}
+
+// writeContractReport writes the report of the verification to its file.
+func writeContractReport(path string, report *contract.Report) error {
+	var data []byte
+	var err error
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		data, err = json.MarshalIndent(report, "", "  ")
+	} else {
+		data, err = yaml.Marshal(report)
+	}
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
@@ -0,0 +1,80 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+	"go.keploy.io/server/pkg/platform/fs"
+	"go.keploy.io/server/pkg/service/control"
+	"go.uber.org/zap"
+)
+
+func NewCmdControl(logger *zap.Logger) *Control {
+	return &Control{
+		controller: control.NewController(logger),
+		logger:     logger,
+	}
+}
+
+type Control struct {
+	controller control.Controller
+	logger     *zap.Logger
+}
+
+func (c *Control) GetCmd() *cobra.Command {
+	var controlCmd = &cobra.Command{
+		Use:     "control",
+		Short:   "serve the API starting and stopping keploy record and test, for the IDEs and the orchestration tools",
+		Example: `keploy control --address 127.0.0.1:16791 -p /path/to/localdir`,
+		Args:    cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var options control.Options
+			var err error
+			options.Address, err = cmd.Flags().GetString("address")
+			if err != nil {
+				c.logger.Error("failed to read the address flag", zap.Error(err))
+				return err
+			}
+			options.Path, err = cmd.Flags().GetString("path")
+			if err != nil {
+				c.logger.Error("failed to read the path flag", zap.Error(err))
+				return err
+			}
+			if options.Path == "" {
+				options.Path, err = os.Getwd()
+				if err != nil {
+					c.logger.Error("failed to get the path of current directory", zap.Error(err))
+					return err
+				}
+			}
+			options.Path, err = filepath.Abs(options.Path)
+			if err != nil {
+				c.logger.Error("failed to get the absolute path from relative path", zap.Error(err))
+				return err
+			}
+			options.ConfigPath, err = cmd.Flags().GetString("config-path")
+			if err != nil {
+				c.logger.Error("failed to read the config path")
+				return err
+			}
+			options.Token = os.Getenv("KEPLOY_CONTROL_TOKEN")
+			options.TokenFile, err = cmd.Flags().GetString("token-file")
+			if err != nil {
+				c.logger.Error("failed to read the token-file flag", zap.Error(err))
+				return err
+			}
+			return c.controller.Serve(options)
+		},
+	}
+
+	controlCmd.Flags().String("address", "127.0.0.1:16791", "Address the control API listens on")
+
+	controlCmd.Flags().StringP("path", "p", "", "Path to the local directory where the test sets are kept")
+
+	controlCmd.Flags().String("config-path", ".", "Path to the local directory where keploy configuration file is stored")
+
+	controlCmd.Flags().String("token-file", filepath.Join(fs.UserHomeDir(true), "control-token"), "File the bearer token of the control API is written to when KEPLOY_CONTROL_TOKEN isn't set, readable by its user alone")
+
+	return controlCmd
+}
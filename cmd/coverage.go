@@ -0,0 +1,62 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+	"go.keploy.io/server/pkg/service/coverage"
+	"go.keploy.io/server/pkg/shutdown"
+	"go.uber.org/zap"
+)
+
+func NewCmdCoverage(logger *zap.Logger) *Coverage {
+	cov := coverage.NewCoverage(logger)
+	return &Coverage{
+		coverage: cov,
+		logger:   logger,
+	}
+}
+
+type Coverage struct {
+	coverage coverage.Coverage
+	logger   *zap.Logger
+}
+
+func (c *Coverage) GetCmd() *cobra.Command {
+	var coverageCmd = &cobra.Command{
+		Use:   "coverage [profile]...",
+		Short: "print the coverage of go coverage profiles, merging them, and check it against a threshold",
+		Example: `keploy coverage --threshold 80
+keploy coverage keploy/coverage-reports/coverage.out unit.out -o combined.out`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			output, err := cmd.Flags().GetString("output")
+			if err != nil {
+				c.logger.Error("failed to read the output flag", zap.Error(err))
+				return err
+			}
+			threshold, err := cmd.Flags().GetFloat64("threshold")
+			if err != nil {
+				c.logger.Error("failed to read the threshold flag", zap.Error(err))
+				return err
+			}
+			profiles := args
+			if len(profiles) == 0 {
+				// the coverage merged by keploy test -withCoverage in the current directory
+				profiles = []string{"keploy/coverage-reports/coverage.out"}
+			}
+			profile, err := c.coverage.Merge(profiles, output)
+			if err != nil {
+				c.logger.Error("failed to read the coverage profiles", zap.Error(err))
+				return err
+			}
+			if err := c.coverage.Report(profile, threshold); err != nil {
+				// the exit code fails the pipeline when the coverage is below the threshold
+				shutdown.Exit(c.logger, 1, "coverage below the threshold")
+			}
+			return nil
+		},
+	}
+
+	coverageCmd.Flags().StringP("output", "o", "", "Path of the merged profile, when several profiles are given")
+	coverageCmd.Flags().Float64("threshold", 0, "Minimum total coverage percentage, the command exiting with 1 below it")
+
+	return coverageCmd
+}
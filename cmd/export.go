@@ -0,0 +1,237 @@
+package cmd
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"go.keploy.io/server/pkg/service/export"
+	"go.uber.org/zap"
+	"gopkg.in/yaml.v3"
+)
+
+func NewCmdExport(logger *zap.Logger) *Export {
+	exporter := export.NewExporter(logger)
+	return &Export{
+		exporter: exporter,
+		logger:   logger,
+	}
+}
+
+type Export struct {
+	exporter export.Exporter
+	logger   *zap.Logger
+}
+
+func (e *Export) GetCmd() *cobra.Command {
+	var exportCmd = &cobra.Command{
+		Use:   "export",
+		Short: "export the recorded tests and mocks to other formats",
+	}
+
+	var openapiCmd = &cobra.Command{
+		Use:     "openapi",
+		Short:   "synthesize an OpenAPI 3 document of the paths, methods and schemas exercised by the recorded tests",
+		Example: "keploy export openapi -p /path/to/localdir -t test-set-0 -o openapi.yaml",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			path, testSets, output, err := e.readFlags(cmd)
+			if err != nil {
+				return err
+			}
+			title, err := cmd.Flags().GetString("title")
+			if err != nil {
+				e.logger.Error("failed to read the title flag", zap.Error(err))
+				return err
+			}
+			doc, err := e.exporter.OpenAPI(path, testSets, title)
+			if err != nil {
+				e.logger.Error("failed to export the tests to openapi", zap.Error(err))
+				return err
+			}
+			var data []byte
+			if strings.EqualFold(filepath.Ext(output), ".json") {
+				data, err = json.MarshalIndent(doc, "", "  ")
+			} else {
+				data, err = yaml.Marshal(doc)
+			}
+			if err != nil {
+				e.logger.Error("failed to marshal the openapi document", zap.Error(err))
+				return err
+			}
+			return e.write(output, data, "openapi")
+		},
+	}
+	openapiCmd.Flags().StringP("output", "o", "openapi.yaml", "File of the OpenAPI document, written as JSON when it ends in .json and as YAML otherwise")
+	openapiCmd.Flags().String("title", "Keploy recorded API", "Title of the API in the OpenAPI document")
+
+	var harCmd = &cobra.Command{
+		Use:     "har",
+		Short:   "write the requests of the recorded tests along with their responses as an HTTP Archive",
+		Example: "keploy export har -p /path/to/localdir -t test-set-0 -o tests.har",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			path, testSets, output, err := e.readFlags(cmd)
+			if err != nil {
+				return err
+			}
+			har, err := e.exporter.HAR(path, testSets)
+			if err != nil {
+				e.logger.Error("failed to export the tests to har", zap.Error(err))
+				return err
+			}
+			data, err := json.MarshalIndent(har, "", "  ")
+			if err != nil {
+				e.logger.Error("failed to marshal the har", zap.Error(err))
+				return err
+			}
+			return e.write(output, data, "har")
+		},
+	}
+	harCmd.Flags().StringP("output", "o", "tests.har", "File of the HTTP Archive")
+
+	var wiremockCmd = &cobra.Command{
+		Use:     "wiremock",
+		Short:   "convert the recorded http mocks into WireMock stub mappings",
+		Example: "keploy export wiremock -p /path/to/localdir -t test-set-0 -o mappings.json",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			path, testSets, output, err := e.readFlags(cmd)
+			if err != nil {
+				return err
+			}
+			stubs, err := e.exporter.WireMock(path, testSets)
+			if err != nil {
+				e.logger.Error("failed to export the mocks to wiremock", zap.Error(err))
+				return err
+			}
+			data, err := json.MarshalIndent(stubs, "", "  ")
+			if err != nil {
+				e.logger.Error("failed to marshal the wiremock stub mappings", zap.Error(err))
+				return err
+			}
+			return e.write(output, data, "wiremock")
+		},
+	}
+	wiremockCmd.Flags().StringP("output", "o", "mappings.json", "File of the WireMock stub mappings")
+
+	var mockoonCmd = &cobra.Command{
+		Use:     "mockoon",
+		Short:   "convert the recorded http mocks into a Mockoon environment",
+		Example: "keploy export mockoon -p /path/to/localdir -t test-set-0 -o mockoon.json --port 3000",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			path, testSets, output, err := e.readFlags(cmd)
+			if err != nil {
+				return err
+			}
+			name, err := cmd.Flags().GetString("name")
+			if err != nil {
+				e.logger.Error("failed to read the name flag", zap.Error(err))
+				return err
+			}
+			port, err := cmd.Flags().GetInt("port")
+			if err != nil {
+				e.logger.Error("failed to read the port flag", zap.Error(err))
+				return err
+			}
+			env, err := e.exporter.Mockoon(path, testSets, name, port)
+			if err != nil {
+				e.logger.Error("failed to export the mocks to mockoon", zap.Error(err))
+				return err
+			}
+			data, err := json.MarshalIndent(env, "", "  ")
+			if err != nil {
+				e.logger.Error("failed to marshal the mockoon environment", zap.Error(err))
+				return err
+			}
+			return e.write(output, data, "mockoon")
+		},
+	}
+	mockoonCmd.Flags().StringP("output", "o", "mockoon.json", "File of the Mockoon environment")
+	mockoonCmd.Flags().String("name", "Keploy mocks", "Name of the Mockoon environment")
+	mockoonCmd.Flags().Int("port", 3000, "Port which Mockoon serves the environment on")
+
+	loadTests := []struct {
+		tool    string
+		short   string
+		output  string
+		example string
+		export  func(path string, testSets []string, options export.LoadTestOptions) (string, error)
+	}{
+		{"k6", "generate a k6 script sending the recorded requests", "script.js", "keploy export k6 -t test-set-0 -o script.js --think-time && k6 run --vus 20 --duration 1m script.js", e.exporter.K6},
+		{"vegeta", "generate the vegeta targets of the recorded requests", "targets.json", "keploy export vegeta -t test-set-0 -o targets.json && vegeta attack -format=json -targets=targets.json -rate=50 -duration=1m", e.exporter.Vegeta},
+		{"locust", "generate a locustfile sending the recorded requests", "locustfile.py", "keploy export locust -t test-set-0 -o locustfile.py --think-time && locust -f locustfile.py", e.exporter.Locust},
+	}
+	var loadTestCmds []*cobra.Command
+	for _, loadTest := range loadTests {
+		loadTest := loadTest
+		loadTestCmd := &cobra.Command{
+			Use:     loadTest.tool,
+			Short:   loadTest.short,
+			Example: loadTest.example,
+			RunE: func(cmd *cobra.Command, args []string) error {
+				path, testSets, output, err := e.readFlags(cmd)
+				if err != nil {
+					return err
+				}
+				var options export.LoadTestOptions
+				options.BaseURL, err = cmd.Flags().GetString("base-url")
+				if err != nil {
+					e.logger.Error("failed to read the base-url flag", zap.Error(err))
+					return err
+				}
+				options.ThinkTime, err = cmd.Flags().GetBool("think-time")
+				if err != nil {
+					e.logger.Error("failed to read the think-time flag", zap.Error(err))
+					return err
+				}
+				script, err := loadTest.export(path, testSets, options)
+				if err != nil {
+					e.logger.Error("failed to export the tests to "+loadTest.tool, zap.Error(err))
+					return err
+				}
+				return e.write(output, []byte(script), loadTest.tool)
+			},
+		}
+		loadTestCmd.Flags().StringP("output", "o", loadTest.output, "File of the "+loadTest.tool+" script")
+		loadTestCmd.Flags().String("base-url", "", "Scheme and host replacing the ones of the recorded urls, like http://staging:8080")
+		loadTestCmd.Flags().Bool("think-time", false, "Wait before each request for the time the client took to send it after the previous response during the recording")
+		loadTestCmds = append(loadTestCmds, loadTestCmd)
+	}
+
+	for _, subCmd := range append([]*cobra.Command{openapiCmd, harCmd, wiremockCmd, mockoonCmd}, loadTestCmds...) {
+		subCmd.Flags().StringP("path", "p", "", "Path to the local directory where the generated testcases/mocks are stored")
+		subCmd.Flags().StringSliceP("testsets", "t", []string{}, "Test sets to export, all of them by default")
+		exportCmd.AddCommand(subCmd)
+	}
+
+	return exportCmd
+}
+
+// readFlags returns the keploy directory along with the test sets to export and the output file.
+func (e *Export) readFlags(cmd *cobra.Command) (string, []string, string, error) {
+	path, err := keployPath(cmd, e.logger)
+	if err != nil {
+		return "", nil, "", err
+	}
+	testSets, err := cmd.Flags().GetStringSlice("testsets")
+	if err != nil {
+		e.logger.Error("failed to read the testsets flag", zap.Error(err))
+		return "", nil, "", err
+	}
+	output, err := cmd.Flags().GetString("output")
+	if err != nil {
+		e.logger.Error("failed to read the output flag", zap.Error(err))
+		return "", nil, "", err
+	}
+	return path, testSets, output, nil
+}
+
+// write writes the exported document to the output file.
+func (e *Export) write(output string, data []byte, format string) error {
+	if err := os.WriteFile(output, data, 0644); err != nil {
+		e.logger.Error("failed to write the exported tests", zap.String("format", format), zap.String("output", output), zap.Error(err))
+		return err
+	}
+	e.logger.Info("exported the tests", zap.String("format", format), zap.String("output", output))
+	return nil
+}
@@ -0,0 +1,102 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+	"go.keploy.io/server/pkg/service/importer"
+	"go.uber.org/zap"
+)
+
+func NewCmdGenerate(logger *zap.Logger) *Generate {
+	imp := importer.NewImporter(logger)
+	return &Generate{
+		importer: imp,
+		logger:   logger,
+	}
+}
+
+type Generate struct {
+	importer importer.Importer
+	logger   *zap.Logger
+}
+
+func (g *Generate) GetCmd() *cobra.Command {
+	var generateCmd = &cobra.Command{
+		Use:   "generate",
+		Short: "generate keploy tests from the description of the api of the application",
+		Example: `keploy generate --openapi openapi.yaml
+keploy generate --openapi openapi.json --url http://localhost:8080 -t test-set-3`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			spec, err := cmd.Flags().GetString("openapi")
+			if err != nil {
+				g.logger.Error("failed to read the openapi flag", zap.Error(err))
+				return err
+			}
+			if spec == "" {
+				return cmd.Help()
+			}
+			path, testSet, err := g.readFlags(cmd)
+			if err != nil {
+				return err
+			}
+			baseURL, err := cmd.Flags().GetString("url")
+			if err != nil {
+				g.logger.Error("failed to read the url flag", zap.Error(err))
+				return err
+			}
+			if _, err := g.importer.OpenAPI(path, spec, baseURL, testSet); err != nil {
+				g.logger.Error("failed to generate the tests of the openapi document", zap.Error(err))
+				return err
+			}
+			return nil
+		},
+	}
+	generateCmd.Flags().String("openapi", "", "OpenAPI 3 document, in yaml or JSON, to generate a test for each of its operations")
+	generateCmd.Flags().String("url", "", "Base url of the application the generated requests are sent to, the url of the first server of the document by default")
+
+	var grpcCmd = &cobra.Command{
+		Use:   "grpc <address>",
+		Short: "generate a skeleton test for each method of a gRPC server, listed by its reflection service",
+		Example: `keploy generate grpc localhost:50051
+keploy generate grpc api.example.com:443 --tls -t test-set-3`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			path, testSet, err := g.readFlags(cmd)
+			if err != nil {
+				return err
+			}
+			useTLS, err := cmd.Flags().GetBool("tls")
+			if err != nil {
+				g.logger.Error("failed to read the tls flag", zap.Error(err))
+				return err
+			}
+			if _, err := g.importer.GRPC(path, args[0], testSet, useTLS); err != nil {
+				g.logger.Error("failed to generate the tests of the grpc server", zap.Error(err))
+				return err
+			}
+			return nil
+		},
+	}
+	grpcCmd.Flags().Bool("tls", false, "Connect to the server over TLS rather than in plaintext")
+	generateCmd.AddCommand(grpcCmd)
+
+	for _, c := range []*cobra.Command{generateCmd, grpcCmd} {
+		c.Flags().StringP("path", "p", "", "Path to the local directory where the generated testcases/mocks are stored")
+		c.Flags().StringP("testset", "t", "", "Test set to write the tests into, after its existing tests, a new one by default")
+	}
+
+	return generateCmd
+}
+
+// readFlags returns the keploy directory along with the test set to generate the tests into.
+func (g *Generate) readFlags(cmd *cobra.Command) (string, string, error) {
+	path, err := keployPath(cmd, g.logger)
+	if err != nil {
+		return "", "", err
+	}
+	testSet, err := cmd.Flags().GetString("testset")
+	if err != nil {
+		g.logger.Error("failed to read the testset flag", zap.Error(err))
+		return "", "", err
+	}
+	return path, testSet, nil
+}
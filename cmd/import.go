@@ -0,0 +1,117 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+	"go.keploy.io/server/pkg/service/importer"
+	"go.uber.org/zap"
+)
+
+func NewCmdImport(logger *zap.Logger) *Import {
+	imp := importer.NewImporter(logger)
+	return &Import{
+		importer: imp,
+		logger:   logger,
+	}
+}
+
+type Import struct {
+	importer importer.Importer
+	logger   *zap.Logger
+}
+
+func (i *Import) GetCmd() *cobra.Command {
+	var importCmd = &cobra.Command{
+		Use:   "import",
+		Short: "convert the requests defined by other tools into keploy tests",
+	}
+
+	var postmanCmd = &cobra.Command{
+		Use:   "postman <collection>",
+		Short: "convert the requests of a Postman collection into the tests of a test set",
+		Example: `keploy import postman collection.json
+keploy import postman collection.json --env staging.postman_environment.json -t test-set-3`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			path, testSet, err := i.readFlags(cmd)
+			if err != nil {
+				return err
+			}
+			env, err := cmd.Flags().GetString("env")
+			if err != nil {
+				i.logger.Error("failed to read the env flag", zap.Error(err))
+				return err
+			}
+			if _, err := i.importer.Postman(path, args[0], env, testSet); err != nil {
+				i.logger.Error("failed to import the postman collection", zap.Error(err))
+				return err
+			}
+			return nil
+		},
+	}
+	postmanCmd.Flags().String("env", "", "Postman environment file with the values of the variables of the collection")
+
+	var curlCmd = &cobra.Command{
+		Use:     "curl <file>",
+		Short:   "convert the curl commands of a file into the tests of a test set",
+		Example: "keploy import curl requests.sh -p /path/to/localdir",
+		Args:    cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			path, testSet, err := i.readFlags(cmd)
+			if err != nil {
+				return err
+			}
+			if _, err := i.importer.Curl(path, args[0], testSet); err != nil {
+				i.logger.Error("failed to import the curl commands", zap.Error(err))
+				return err
+			}
+			return nil
+		},
+	}
+
+	var harCmd = &cobra.Command{
+		Use:   "har <file>",
+		Short: "convert the entries of an HTTP Archive, saved by a browser or a proxy, into the tests of a test set",
+		Example: `keploy import har session.har
+keploy import har session.har --filter "^https://api\\.example\\.com/"`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			path, testSet, err := i.readFlags(cmd)
+			if err != nil {
+				return err
+			}
+			filter, err := cmd.Flags().GetString("filter")
+			if err != nil {
+				i.logger.Error("failed to read the filter flag", zap.Error(err))
+				return err
+			}
+			if _, err := i.importer.HAR(path, args[0], testSet, filter); err != nil {
+				i.logger.Error("failed to import the har file", zap.Error(err))
+				return err
+			}
+			return nil
+		},
+	}
+	harCmd.Flags().String("filter", "", "Regex of the urls of the entries to import, like the ones of the api rather than of the assets")
+
+	for _, subCmd := range []*cobra.Command{postmanCmd, curlCmd, harCmd} {
+		subCmd.Flags().StringP("path", "p", "", "Path to the local directory where the generated testcases/mocks are stored")
+		subCmd.Flags().StringP("testset", "t", "", "Test set to write the tests into, after its existing tests, a new one by default")
+		importCmd.AddCommand(subCmd)
+	}
+
+	return importCmd
+}
+
+// readFlags returns the keploy directory along with the test set to import into.
+func (i *Import) readFlags(cmd *cobra.Command) (string, string, error) {
+	path, err := keployPath(cmd, i.logger)
+	if err != nil {
+		return "", "", err
+	}
+	testSet, err := cmd.Flags().GetString("testset")
+	if err != nil {
+		i.logger.Error("failed to read the testset flag", zap.Error(err))
+		return "", "", err
+	}
+	return path, testSet, nil
+}
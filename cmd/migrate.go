@@ -0,0 +1,50 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+	"go.keploy.io/server/pkg/service/migrate"
+	"go.uber.org/zap"
+)
+
+func NewCmdMigrate(logger *zap.Logger) *Migrate {
+	migrator := migrate.NewMigrator(logger)
+	return &Migrate{
+		migrator: migrator,
+		logger:   logger,
+	}
+}
+
+type Migrate struct {
+	migrator migrate.Migrator
+	logger   *zap.Logger
+}
+
+func (m *Migrate) GetCmd() *cobra.Command {
+	var migrateCmd = &cobra.Command{
+		Use:   "migrate [test-set]...",
+		Short: "upgrade the tests and mocks recorded by older keploy versions to the current schema in place",
+		Example: `keploy migrate -p /path/to/localdir
+keploy migrate test-set-1 --dry-run`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			path, err := keployPath(cmd, m.logger)
+			if err != nil {
+				return err
+			}
+			dryRun, err := cmd.Flags().GetBool("dry-run")
+			if err != nil {
+				m.logger.Error("failed to read the dry-run flag", zap.Error(err))
+				return err
+			}
+			if _, err := m.migrator.Migrate(path, args, dryRun); err != nil {
+				m.logger.Error("failed to migrate the test sets", zap.Error(err))
+				return err
+			}
+			return nil
+		},
+	}
+
+	migrateCmd.Flags().StringP("path", "p", "", "Path to the local directory where the generated testcases/mocks are stored")
+	migrateCmd.Flags().Bool("dry-run", false, "Report the documents which would be upgraded without rewriting them")
+
+	return migrateCmd
+}
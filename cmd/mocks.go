@@ -0,0 +1,208 @@
+package cmd
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+	"go.keploy.io/server/pkg/service/mocks"
+	"go.uber.org/zap"
+)
+
+func NewCmdMocks(logger *zap.Logger) *Mocks {
+	mocksEditor := mocks.NewMocks(logger)
+	return &Mocks{
+		mocks:  mocksEditor,
+		logger: logger,
+	}
+}
+
+type Mocks struct {
+	mocks  mocks.Mocks
+	logger *zap.Logger
+}
+
+func (m *Mocks) GetCmd() *cobra.Command {
+	var mocksCmd = &cobra.Command{
+		Use:     "mocks",
+		Aliases: []string{"mock"},
+		Short:   "edit the recorded mocks of the test sets, or serve them",
+	}
+
+	var normalizeCmd = &cobra.Command{
+		Use:     "normalize",
+		Short:   "remove the duplicated mocks of the test sets and give the remaining ones unique names",
+		Example: "keploy mocks normalize -p /path/to/localdir -t test-set-0",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			path, testSets, err := m.readFlags(cmd)
+			if err != nil {
+				return err
+			}
+			if err := m.mocks.Normalize(path, testSets); err != nil {
+				m.logger.Error("failed to normalize the mocks", zap.Error(err))
+				return err
+			}
+			return nil
+		},
+	}
+
+	var pruneCmd = &cobra.Command{
+		Use:     "prune",
+		Short:   "remove the mocks of the test sets never consumed by their last replays",
+		Example: "keploy mocks prune -p /path/to/localdir -t test-set-0 --dry-run",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			path, testSets, err := m.readFlags(cmd)
+			if err != nil {
+				return err
+			}
+			dryRun, err := cmd.Flags().GetBool("dry-run")
+			if err != nil {
+				m.logger.Error("failed to read the dry-run flag", zap.Error(err))
+				return err
+			}
+			if err := m.mocks.Prune(path, testSets, dryRun); err != nil {
+				m.logger.Error("failed to prune the mocks", zap.Error(err))
+				return err
+			}
+			return nil
+		},
+	}
+	pruneCmd.Flags().Bool("dry-run", false, "List the mocks which would be pruned without removing them")
+
+	var mergeCmd = &cobra.Command{
+		Use:     "merge",
+		Short:   "merge the testcases and the mocks of test sets into a new test set",
+		Example: "keploy mocks merge -p /path/to/localdir -t test-set-0,test-set-1 --into test-set-5",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			path, testSets, err := m.readFlags(cmd)
+			if err != nil {
+				return err
+			}
+			into, err := cmd.Flags().GetString("into")
+			if err != nil {
+				m.logger.Error("failed to read the into flag", zap.Error(err))
+				return err
+			}
+			if _, err := m.mocks.Merge(path, testSets, into); err != nil {
+				m.logger.Error("failed to merge the test sets", zap.Error(err))
+				return err
+			}
+			return nil
+		},
+	}
+	mergeCmd.Flags().String("into", "", "Name of the new test set, the next test-set-N by default")
+
+	var convertCmd = &cobra.Command{
+		Use:     "convert",
+		Short:   "store the mocks of the test sets in yaml or in cbor, a binary format quicker to load",
+		Example: "keploy mocks convert -p /path/to/localdir -t test-set-0 --to cbor",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			path, testSets, err := m.readFlags(cmd)
+			if err != nil {
+				return err
+			}
+			format, err := cmd.Flags().GetString("to")
+			if err != nil {
+				m.logger.Error("failed to read the to flag", zap.Error(err))
+				return err
+			}
+			if err := validateMockFormat(format); err != nil || format == "" {
+				m.logger.Error("the format of the mocks is required, like --to cbor or --to yaml")
+				return errors.New("invalid --to flag")
+			}
+			if err := m.mocks.Convert(path, testSets, format); err != nil {
+				m.logger.Error("failed to convert the mocks", zap.Error(err))
+				return err
+			}
+			return nil
+		},
+	}
+	convertCmd.Flags().String("to", "", "Format the mocks are converted to: yaml or cbor")
+
+	for _, subCmd := range []*cobra.Command{normalizeCmd, pruneCmd, mergeCmd, convertCmd} {
+		subCmd.Flags().StringP("path", "p", "", "Path to the local directory where the generated testcases/mocks are stored")
+		subCmd.Flags().StringSliceP("testsets", "t", []string{}, "Test sets to edit, all of them by default")
+		mocksCmd.AddCommand(subCmd)
+	}
+
+	var serveCmd = &cobra.Command{
+		Use:     "serve",
+		Short:   "serve the recorded http mocks of a test set over the network, without running the application",
+		Example: "keploy mock serve --port 8081 --test-set test-set-1 --host payments.internal",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			path, err := keployPath(cmd, m.logger)
+			if err != nil {
+				return err
+			}
+			testSet, err := cmd.Flags().GetString("test-set")
+			if err != nil {
+				m.logger.Error("failed to read the test-set flag", zap.Error(err))
+				return err
+			}
+			if testSet == "" {
+				m.logger.Error("the test set of the mocks is required, like --test-set test-set-1")
+				return errors.New("missing the --test-set flag")
+			}
+			port, err := cmd.Flags().GetUint32("port")
+			if err != nil {
+				m.logger.Error("failed to read the port flag", zap.Error(err))
+				return err
+			}
+			host, err := cmd.Flags().GetString("host")
+			if err != nil {
+				m.logger.Error("failed to read the host flag", zap.Error(err))
+				return err
+			}
+			if err := m.mocks.Serve(path, testSet, port, host); err != nil {
+				m.logger.Error("failed to serve the mocks", zap.Error(err))
+				return err
+			}
+			return nil
+		},
+	}
+	serveCmd.Flags().StringP("path", "p", "", "Path to the local directory where the generated testcases/mocks are stored")
+	serveCmd.Flags().String("test-set", "", "Test set whose mocks are served")
+	serveCmd.Flags().Uint32("port", 8081, "Port the mocks are served on")
+	serveCmd.Flags().String("host", "", "Host of the dependency whose mocks are served, all of them by default")
+	mocksCmd.AddCommand(serveCmd)
+
+	return mocksCmd
+}
+
+// readFlags returns the keploy directory along with the test sets to edit.
+func (m *Mocks) readFlags(cmd *cobra.Command) (string, []string, error) {
+	path, err := keployPath(cmd, m.logger)
+	if err != nil {
+		return "", nil, err
+	}
+	testSets, err := cmd.Flags().GetStringSlice("testsets")
+	if err != nil {
+		m.logger.Error("failed to read the testsets flag", zap.Error(err))
+		return "", nil, err
+	}
+	return path, testSets, nil
+}
+
+// keployPath returns the absolute path of the keploy directory in the directory of the path flag, the current one
+// by default.
+func keployPath(cmd *cobra.Command, logger *zap.Logger) (string, error) {
+	path, err := cmd.Flags().GetString("path")
+	if err != nil {
+		logger.Error("failed to read the testcase path input", zap.Error(err))
+		return "", err
+	}
+	if len(path) == 0 {
+		path, err = os.Getwd()
+		if err != nil {
+			logger.Error("failed to get the path of current directory", zap.Error(err))
+			return "", err
+		}
+	}
+	path, err = filepath.Abs(path)
+	if err != nil {
+		logger.Error("failed to get the absolute path from relative path", zap.Error(err))
+		return "", err
+	}
+	return path + "/keploy", nil
+}
@@ -9,11 +9,13 @@ import (
 	"time"
 
 	"github.com/spf13/cobra"
+	"go.keploy.io/server/pkg/hooks"
+	"go.keploy.io/server/pkg/hooks/connection"
+	"go.keploy.io/server/pkg/metrics"
 	"go.keploy.io/server/pkg/models"
 	"go.keploy.io/server/pkg/service/record"
 	"go.keploy.io/server/utils"
 	"go.uber.org/zap"
-	yamlLib "gopkg.in/yaml.v3"
 )
 
 func NewCmdRecord(logger *zap.Logger) *Record {
@@ -24,56 +26,85 @@ func NewCmdRecord(logger *zap.Logger) *Record {
 	}
 }
 
-func readRecordConfig(configPath string) (*models.Record, error) {
-	file, err := os.OpenFile(configPath, os.O_RDONLY, os.ModePerm)
-	if err != nil {
-		return nil, err
-	}
-	defer file.Close()
-	decoder := yamlLib.NewDecoder(file)
-	var doc models.Config
-	err = decoder.Decode(&doc)
-	if err != nil {
-		return nil, err
-	}
-	return &doc.Record, nil
-}
-
-var filters = models.Filters{}
-
-func (t *Record) GetRecordConfig(path *string, proxyPort *uint32, appCmd *string, appContainer, networkName *string, Delay *uint64, buildDelay *time.Duration, passThroughPorts *[]uint, configPath string) error {
+// GetRecordConfig merges the record section of the config file of the config path into conf, which holds the values
+// of the flags: the flags given take precedence, and the lists of the config file follow the ones of the flags.
+func (t *Record) GetRecordConfig(conf *models.Record, configPath string) error {
 	configFilePath := filepath.Join(configPath, "keploy-config.yaml")
 	if isExist := utils.CheckFileExists(configFilePath); !isExist {
 		return errFileNotFound
 	}
-	confRecord, err := readRecordConfig(configFilePath)
+	confRecord, err := record.ReadRecordConfig(configFilePath)
 	if err != nil {
 		return fmt.Errorf("failed to get the record config from config file due to error: %s", err)
 	}
-	if len(*path) == 0 {
-		*path = confRecord.Path
+	if len(conf.Path) == 0 {
+		conf.Path = confRecord.Path
+	}
+	conf.Filters = confRecord.Filters
+	if conf.ProxyPort == 0 {
+		conf.ProxyPort = confRecord.ProxyPort
+	}
+	if conf.Command == "" {
+		conf.Command = confRecord.Command
+	}
+	if conf.ContainerName == "" {
+		conf.ContainerName = confRecord.ContainerName
 	}
-	filters = confRecord.Filters
-	if *proxyPort == 0 {
-		*proxyPort = confRecord.ProxyPort
+	if conf.NetworkName == "" {
+		conf.NetworkName = confRecord.NetworkName
 	}
-	if *appCmd == "" {
-		*appCmd = confRecord.Command
+	if conf.Delay == 5 {
+		conf.Delay = confRecord.Delay
 	}
-	if *appContainer == "" {
-		*appContainer = confRecord.ContainerName
+	if conf.BuildDelay == 30*time.Second && confRecord.BuildDelay != 0 {
+		conf.BuildDelay = confRecord.BuildDelay
 	}
-	if *networkName == "" {
-		*networkName = confRecord.NetworkName
+	if len(conf.PassThroughPorts) == 0 {
+		conf.PassThroughPorts = confRecord.PassThroughPorts
 	}
-	if *Delay == 5 {
-		*Delay = confRecord.Delay
+	if len(conf.ProtoDescriptors) == 0 {
+		conf.ProtoDescriptors = confRecord.ProtoDescriptors
 	}
-	if *buildDelay == 30*time.Second && confRecord.BuildDelay != 0 {
-		*buildDelay = confRecord.BuildDelay
+	conf.ProtobufRoutes = confRecord.ProtobufRoutes
+	if conf.DenoisePasses == 0 {
+		conf.DenoisePasses = confRecord.DenoisePasses
 	}
-	if len(*passThroughPorts) == 0 {
-		*passThroughPorts = confRecord.PassThroughPorts
+	conf.Dedup = conf.Dedup || confRecord.Dedup
+	conf.DeterministicRandom = conf.DeterministicRandom || confRecord.DeterministicRandom
+	conf.HeaderFidelity = conf.HeaderFidelity || confRecord.HeaderFidelity
+	if conf.BodyStorage.MaxInlineSize == 0 {
+		conf.BodyStorage.MaxInlineSize = confRecord.BodyStorage.MaxInlineSize
+	}
+	conf.BodyStorage.Compress = confRecord.BodyStorage.Compress
+	if conf.MockFormat == "" {
+		conf.MockFormat = confRecord.MockFormat
+	}
+	if conf.MaxBuffer == 0 {
+		conf.MaxBuffer = confRecord.MaxBuffer
+	}
+	if conf.MaxMessage == 0 {
+		conf.MaxMessage = confRecord.MaxMessage
+	}
+	conf.Noise = append(append([]string{}, conf.Noise...), confRecord.Noise...)
+	conf.WatchConfig = conf.WatchConfig || confRecord.WatchConfig
+	conf.Redact = confRecord.Redact
+	conf.PassThrough = append(append([]models.PassThroughRule{}, conf.PassThrough...), confRecord.PassThrough...)
+	conf.UnixSockets = append(conf.UnixSockets, confRecord.UnixSockets...)
+	if conf.Ingress.Port == 0 {
+		conf.Ingress.Port = confRecord.Ingress.Port
+	}
+	if conf.Ingress.AppPort == 0 {
+		conf.Ingress.AppPort = confRecord.Ingress.AppPort
+		conf.Ingress.AppPorts = confRecord.Ingress.AppPorts
+	}
+	if conf.CaptureMode == "" {
+		conf.CaptureMode = confRecord.CaptureMode
+	}
+	if conf.Compose.File == "" {
+		conf.Compose.File = confRecord.Compose.File
+	}
+	if conf.Compose.Service == "" {
+		conf.Compose.Service = confRecord.Compose.Service
 	}
 	return nil
 }
@@ -81,6 +112,8 @@ func (t *Record) GetRecordConfig(path *string, proxyPort *uint32, appCmd *string
 type Record struct {
 	recorder record.Recorder
 	logger   *zap.Logger
+	// shadow is the candidate build which keploy shadow replays the recorded calls to
+	shadow models.Shadow
 }
 
 func (r *Record) GetCmd() *cobra.Command {
@@ -140,6 +173,144 @@ func (r *Record) GetCmd() *cobra.Command {
 				return err
 			}
 
+			protoDescriptors, err := cmd.Flags().GetStringSlice("protoDescriptors")
+			if err != nil {
+				r.logger.Error("failed to read the protobuf descriptor sets")
+				return err
+			}
+
+			denoisePasses, err := cmd.Flags().GetInt("denoise-passes")
+			if err != nil {
+				r.logger.Error("failed to read the denoise passes")
+				return err
+			}
+
+			dedup, err := cmd.Flags().GetBool("dedup")
+			if err != nil {
+				r.logger.Error("failed to read the dedup flag")
+				return err
+			}
+
+			deterministicRandom, err := cmd.Flags().GetBool("deterministic-random")
+			if err != nil {
+				r.logger.Error("failed to read the deterministic-random flag", zap.Error(err))
+				return err
+			}
+
+			headerFidelity, err := cmd.Flags().GetBool("header-fidelity")
+			if err != nil {
+				r.logger.Error("failed to read the header-fidelity flag", zap.Error(err))
+				return err
+			}
+
+			bodyStorage := models.BodyStorage{}
+			bodyStorage.MaxInlineSize, err = cmd.Flags().GetInt("max-inline-body")
+			if err != nil {
+				r.logger.Error("failed to read the max-inline-body flag", zap.Error(err))
+				return err
+			}
+
+			mockFormat, err := cmd.Flags().GetString("mock-format")
+			if err != nil {
+				r.logger.Error("failed to read the mock-format flag", zap.Error(err))
+				return err
+			}
+
+			maxBuffer, err := cmd.Flags().GetInt("max-buffer")
+			if err != nil {
+				r.logger.Error("failed to read the max-buffer flag", zap.Error(err))
+				return err
+			}
+
+			maxMessage, err := cmd.Flags().GetInt("max-message")
+			if err != nil {
+				r.logger.Error("failed to read the max-message flag", zap.Error(err))
+				return err
+			}
+
+			noise, err := cmd.Flags().GetStringSlice("noise")
+			if err != nil {
+				r.logger.Error("failed to read the noise flag", zap.Error(err))
+				return err
+			}
+
+			watchConfig, err := cmd.Flags().GetBool("watch-config")
+			if err != nil {
+				r.logger.Error("failed to read the watch-config flag", zap.Error(err))
+				return err
+			}
+
+			metricsAddr, err := cmd.Flags().GetString("metrics-addr")
+			if err != nil {
+				r.logger.Error("failed to read the metrics-addr flag", zap.Error(err))
+				return err
+			}
+
+			passThroughEntries, err := cmd.Flags().GetStringSlice("passThrough")
+			if err != nil {
+				r.logger.Error("failed to read the pass through rules")
+				return err
+			}
+			passThrough, err := parsePassThroughRules(passThroughEntries)
+			if err != nil {
+				r.logger.Error("failed to parse the pass through rules", zap.Error(err))
+				return err
+			}
+
+			unixSocketEntries, err := cmd.Flags().GetStringSlice("unix-socket")
+			if err != nil {
+				r.logger.Error("failed to read the unix-socket flag", zap.Error(err))
+				return err
+			}
+			unixSockets, err := parseUnixSockets(unixSocketEntries)
+			if err != nil {
+				r.logger.Error("failed to parse the unix sockets", zap.Error(err))
+				return err
+			}
+
+			var ingress models.Ingress
+			ingress.Port, err = cmd.Flags().GetUint32("ingress-port")
+			if err != nil {
+				r.logger.Error("failed to read the ingress-port flag", zap.Error(err))
+				return err
+			}
+			appPorts, err := cmd.Flags().GetUintSlice("app-port")
+			if err != nil {
+				r.logger.Error("failed to read the app-port flag", zap.Error(err))
+				return err
+			}
+			for i, appPort := range appPorts {
+				if i == 0 {
+					ingress.AppPort = uint32(appPort)
+				} else {
+					ingress.AppPorts = append(ingress.AppPorts, uint32(appPort))
+				}
+			}
+
+			captureMode, err := cmd.Flags().GetString("capture-mode")
+			if err != nil {
+				r.logger.Error("failed to read the capture-mode flag", zap.Error(err))
+				return err
+			}
+
+			var compose models.Compose
+			compose.File, err = cmd.Flags().GetString("compose")
+			if err != nil {
+				r.logger.Error("failed to read the compose flag", zap.Error(err))
+				return err
+			}
+			compose.Service, err = cmd.Flags().GetString("service")
+			if err != nil {
+				r.logger.Error("failed to read the service flag", zap.Error(err))
+				return err
+			}
+
+			pid, err := cmd.Flags().GetUint32("pid")
+			if err != nil {
+				r.logger.Error("failed to read the pid flag", zap.Error(err))
+				return err
+			}
+
 			configPath, err := cmd.Flags().GetString("config-path")
 			if err != nil {
 				r.logger.Error("failed to read the config path")
@@ -152,7 +323,43 @@ func (r *Record) GetCmd() *cobra.Command {
 				return err
 			}
 
-			err = r.GetRecordConfig(&path, &proxyPort, &appCmd, &appContainer, &networkName, &delay, &buildDelay, &ports, configPath)
+			opts := record.RecordOptions{
+				Record: models.Record{
+					Path:                path,
+					Command:             appCmd,
+					ProxyPort:           proxyPort,
+					ContainerName:       appContainer,
+					NetworkName:         networkName,
+					Delay:               delay,
+					BuildDelay:          buildDelay,
+					PassThroughPorts:    ports,
+					ProtoDescriptors:    protoDescriptors,
+					DenoisePasses:       denoisePasses,
+					PassThrough:         passThrough,
+					Dedup:               dedup,
+					DeterministicRandom: deterministicRandom,
+					UnixSockets:         unixSockets,
+					Ingress:             ingress,
+					CaptureMode:         captureMode,
+					Compose:             compose,
+					HeaderFidelity:      headerFidelity,
+					BodyStorage:         bodyStorage,
+					MockFormat:          mockFormat,
+					MaxBuffer:           maxBuffer,
+					MaxMessage:          maxMessage,
+					Noise:               noise,
+					WatchConfig:         watchConfig,
+				},
+				// the values of the flags are kept apart from the ones of the config file, whose changes are applied
+				// along with them
+				FlagNoise:            noise,
+				FlagPassThrough:      passThrough,
+				FlagPassThroughPorts: ports,
+				Shadow:               r.shadow,
+				Pid:                  pid,
+				EnableTele:           enableTele,
+			}
+			err = r.GetRecordConfig(&opts.Record, configPath)
 			if err != nil {
 				if err == errFileNotFound {
 					r.logger.Info("continuing without configuration file because file not found")
@@ -161,7 +368,44 @@ func (r *Record) GetCmd() *cobra.Command {
 				}
 			}
 
-			if appCmd == "" {
+			// the config file is watched for the changes applied to the recording in progress
+			if opts.WatchConfig {
+				if err == nil {
+					opts.ConfigFile = filepath.Join(configPath, "keploy-config.yaml")
+				} else {
+					r.logger.Warn("the config file isn't watched, it couldn't be read", zap.String("configPath", configPath))
+				}
+			}
+
+			if err := validateCaptureMode(opts.CaptureMode); err != nil {
+				r.logger.Error("invalid capture mode", zap.Error(err))
+				return err
+			}
+
+			if err := validateMockFormat(opts.MockFormat); err != nil {
+				r.logger.Error("invalid mock format", zap.Error(err))
+				return err
+			}
+
+			if err := connection.ValidateFilters(opts.Filters); err != nil {
+				r.logger.Error("invalid record filters in the config file", zap.Error(err))
+				return err
+			}
+
+			if opts.Compose.File != "" {
+				if opts.Command != "" {
+					r.logger.Error("the application is either run by its command or by the service of a docker compose file", zap.String("appCmd", opts.Command), zap.String("compose", opts.Compose.File))
+					return errors.New("both -c and --compose are given")
+				}
+				// the stack of the docker compose file is brought up, keploy capturing the calls of the service
+				opts.Command, opts.ContainerName, err = hooks.ComposeCommand(opts.Compose, models.MODE_RECORD)
+				if err != nil {
+					r.logger.Error("failed to run the service of the docker compose file", zap.Error(err))
+					return err
+				}
+			}
+
+			if opts.Command == "" && opts.Pid == 0 {
 				r.logger.Error("missing required -c flag or appCmd in config file")
 				if isDockerCmd {
 					r.logger.Info(`Example usage: keploy record -c "docker run -p 8080:8080 --network myNetworkName myApplicationImageName" --delay 6`)
@@ -172,45 +416,53 @@ func (r *Record) GetCmd() *cobra.Command {
 			}
 
 			//if user provides relative path
-			if len(path) > 0 && path[0] != '/' {
-				absPath, err := filepath.Abs(path)
+			if len(opts.Path) > 0 && opts.Path[0] != '/' {
+				absPath, err := filepath.Abs(opts.Path)
 				if err != nil {
 					r.logger.Error("failed to get the absolute path from relative path", zap.Error(err))
 				}
-				path = absPath
-			} else if len(path) == 0 { // if user doesn't provide any path
+				opts.Path = absPath
+			} else if len(opts.Path) == 0 { // if user doesn't provide any path
 				cdirPath, err := os.Getwd()
 				if err != nil {
 					r.logger.Error("failed to get the path of current directory", zap.Error(err))
 				}
-				path = cdirPath
+				opts.Path = cdirPath
 			} else {
 				// user provided the absolute path
 			}
 
-			if isDockerCmd && buildDelay <= 30*time.Second {
-				r.logger.Warn(fmt.Sprintf("buildDelay is set to %v, incase your docker container takes more time to build use --buildDelay to set custom delay", buildDelay))
+			if isDockerCmd && opts.BuildDelay <= 30*time.Second {
+				r.logger.Warn(fmt.Sprintf("buildDelay is set to %v, incase your docker container takes more time to build use --buildDelay to set custom delay", opts.BuildDelay))
 				r.logger.Info(`Example usage: keploy record -c "docker-compose up --build" --buildDelay 35s`)
 			}
 
-			path += "/keploy"
+			opts.Path += "/keploy"
 
-			r.logger.Info("", zap.Any("keploy test and mock path", path))
+			r.logger.Info("", zap.Any("keploy test and mock path", opts.Path))
 
 			var hasContainerName bool
 			if isDockerCmd {
-				if strings.Contains(appCmd, "--name") {
+				if strings.Contains(opts.Command, "--name") {
 					hasContainerName = true
 				}
-				if !hasContainerName && appContainer == "" {
+				if !hasContainerName && opts.ContainerName == "" {
 					r.logger.Error("Couldn't find containerName")
 					r.logger.Info(`Example usage: keploy record -c "docker run -p 8080:8080 --network myNetworkName myApplicationImageName" --delay 6`)
 					return errors.New("missing required --containerName flag or containerName in config file")
 				}
 			}
 
-			r.logger.Debug("the ports are", zap.Any("ports", ports))
-			r.recorder.CaptureTraffic(path, proxyPort, appCmd, appContainer, networkName, delay, buildDelay, ports, &filters, enableTele)
+			if opts.DenoisePasses < 0 {
+				r.logger.Error("the denoise passes can't be negative", zap.Any("denoisePasses", opts.DenoisePasses))
+				return errors.New("invalid --denoise-passes flag or denoisePasses in config file")
+			}
+
+			r.logger.Debug("the ports are", zap.Any("ports", opts.PassThroughPorts))
+			if metricsAddr != "" {
+				metrics.Serve(metricsAddr, metrics.Handler(metrics.Default, r.logger), r.logger)
+			}
+			r.recorder.CaptureTraffic(opts)
 			return nil
 		},
 	}
@@ -231,6 +483,45 @@ func (r *Record) GetCmd() *cobra.Command {
 
 	recordCmd.Flags().UintSlice("passThroughPorts", []uint{}, "Ports of Outgoing dependency calls to be ignored as mocks")
 
+	recordCmd.Flags().StringSlice("protoDescriptors", []string{}, "Paths of the protobuf descriptor sets (protoc --descriptor_set_out --include_imports) used to decode the gRPC messages of the dependencies")
+
+	recordCmd.Flags().Int("denoise-passes", 0, "Number of times each captured request is replayed right after its capture to mark the fields of the response which change as noise")
+
+	recordCmd.Flags().Bool("dedup", false, "Skip the requests identical to one already recorded in the session, counting them in the metadata of its testcase")
+
+	recordCmd.Flags().Bool("deterministic-random", false, "Feed the application the random bytes generated from a seed saved with the test set, so that keploy test --deterministic-random generates the same ids and tokens")
+
+	recordCmd.Flags().Bool("header-fidelity", false, "Record the order and the case of the headers of the tests and the mocks, which are replayed with them byte for byte")
+
+	recordCmd.Flags().Int("max-inline-body", 0, "Size in bytes above which the http bodies of the tests and mocks are stored in files next to their yaml file, 0 keeping them inline")
+
+	recordCmd.Flags().Int("max-buffer", 0, "Bytes of the calls passed through the proxy reads before writing them to the other side, holding back the faster side of the connection, 0 for no bound")
+	recordCmd.Flags().Int("max-message", 0, "Bytes of the messages the proxy reads whole to record them, the calls sending larger ones being passed through, 0 for 64 MiB and -1 for no bound")
+
+	recordCmd.Flags().StringSlice("noise", []string{}, "Fields, like header.Date or body.updatedAt, written as noise on every recorded testcase")
+
+	recordCmd.Flags().Bool("watch-config", false, "Apply the changes of the noise, the pass through rules and ports, and the filters of the config file to the recording in progress")
+
+	recordCmd.Flags().String("metrics-addr", "", "Address the metrics of the recording are served on in the Prometheus format, like :9090, at /metrics")
+
+	recordCmd.Flags().String("mock-format", "", "Format the recorded mocks are stored in: yaml (default) or cbor, a compact binary format quicker to load for the large recordings")
+
+	recordCmd.Flags().StringSlice("passThrough", []string{}, "Hosts, CIDRs, ports or host:port of the outgoing calls forwarded untouched to their server")
+
+	recordCmd.Flags().String("capture-mode", "", "How the calls of the application are captured: auto (eBPF, or the userspace proxy when it can't be loaded), ebpf, or without privileges proxy (HTTP_PROXY and ALL_PROXY) and preload (LD_PRELOAD connect())")
+
+	recordCmd.Flags().Uint32("ingress-port", 0, "Port keploy receives the calls to the application on when they are captured without the eBPF hooks, 16790 by default")
+
+	recordCmd.Flags().UintSlice("app-port", []uint{}, "Ports of the application the calls received on the ingress ports are forwarded to when they are captured without the eBPF hooks. The testcases of an application listening on several ports are tagged with their port")
+
+	recordCmd.Flags().StringSlice("unix-socket", []string{}, "Unix sockets of the dependencies captured, as path or path=protocol, e.g. /var/run/mysqld/mysqld.sock=mysql")
+
+	recordCmd.Flags().String("compose", "", "Docker compose file whose stack is brought up, the calls of its --service being recorded")
+
+	recordCmd.Flags().String("service", "", "Service of the --compose file running the application, the other services being its dependencies")
+
+	recordCmd.Flags().Uint32("pid", 0, "Pid of the running application whose calls are recorded, instead of starting it with -c")
+
 	recordCmd.Flags().String("config-path", ".", "Path to the local directory where keploy configuration file is stored")
 
 	recordCmd.Flags().Bool("enableTele", true, "Switch for telemetry")
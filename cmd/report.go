@@ -0,0 +1,58 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+	"go.keploy.io/server/pkg/models"
+	"go.keploy.io/server/pkg/service/report"
+	"go.keploy.io/server/pkg/shutdown"
+	"go.uber.org/zap"
+)
+
+func NewCmdReport(logger *zap.Logger) *Report {
+	reporter := report.NewReporter(logger)
+	return &Report{
+		reporter: reporter,
+		logger:   logger,
+	}
+}
+
+type Report struct {
+	reporter report.Reporter
+	logger   *zap.Logger
+}
+
+func (r *Report) GetCmd() *cobra.Command {
+	var reportCmd = &cobra.Command{
+		Use:   "report",
+		Short: "combine the test reports of the runs",
+	}
+
+	var mergeCmd = &cobra.Command{
+		Use:     "merge <shard-report>...",
+		Short:   "merge the reports of the shards of a run, exiting with 1 when a test set failed",
+		Example: "keploy report merge shard-1-of-2.yaml shard-2-of-2.yaml -o merged-report.yaml",
+		Args:    cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			output, err := cmd.Flags().GetString("output")
+			if err != nil {
+				r.logger.Error("failed to read the output flag", zap.Error(err))
+				return err
+			}
+			merged, err := r.reporter.Merge(args, output)
+			if err != nil {
+				r.logger.Error("failed to merge the reports of the shards", zap.Error(err))
+				return err
+			}
+			r.logger.Info("test run completed", zap.Int("total", merged.Total), zap.Int("passed", merged.Success), zap.Int("failed", merged.Failure), zap.Bool("passed overall", merged.Status == string(models.TestRunStatusPassed)))
+			// the exit code gates the pipeline once its shards are done
+			if merged.Status != string(models.TestRunStatusPassed) {
+				shutdown.Exit(r.logger, 1, "failed test run")
+			}
+			return nil
+		},
+	}
+	mergeCmd.Flags().StringP("output", "o", "merged-report.yaml", "Path of the merged report")
+
+	reportCmd.AddCommand(mergeCmd)
+	return reportCmd
+}
@@ -0,0 +1,61 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+	"go.keploy.io/server/pkg/service/test"
+	"go.uber.org/zap"
+)
+
+func NewCmdReview(logger *zap.Logger) *Review {
+	return &Review{
+		tester: test.NewTester(logger),
+		logger: logger,
+	}
+}
+
+type Review struct {
+	tester test.Tester
+	logger *zap.Logger
+}
+
+func (r *Review) GetCmd() *cobra.Command {
+	var reviewCmd = &cobra.Command{
+		Use:     "review",
+		Short:   "go through the failed tests of the last runs to mark fields as noise, update the expected responses or delete the tests",
+		Example: `keploy review -t "test-set-1, test-set-2"`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			path, err := cmd.Flags().GetString("path")
+			if err != nil {
+				r.logger.Error("failed to read the path flag", zap.Error(err))
+				return err
+			}
+			if path == "" {
+				path, err = os.Getwd()
+				if err != nil {
+					r.logger.Error("failed to get the path of current directory", zap.Error(err))
+					return err
+				}
+			}
+			path, err = filepath.Abs(path)
+			if err != nil {
+				r.logger.Error("failed to get the absolute path from relative path", zap.Error(err))
+				return err
+			}
+			testSets, err := cmd.Flags().GetStringSlice("testsets")
+			if err != nil {
+				r.logger.Error("failed to read the testsets flag", zap.Error(err))
+				return err
+			}
+			return r.tester.Review(filepath.Join(path, "keploy", "testReports"), testSets)
+		},
+	}
+
+	reviewCmd.Flags().StringP("path", "p", "", "Path to local directory where generated testcases/mocks are stored")
+
+	reviewCmd.Flags().StringSliceP("testsets", "t", []string{}, "Testsets whose failed tests are reviewed e.g. --testsets \"test-set-1, test-set-2\"")
+
+	return reviewCmd
+}
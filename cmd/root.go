@@ -3,18 +3,28 @@ package cmd
 import (
 	"bytes"
 	"errors"
+	"fmt"
 	"log"
+	"net"
 	"net/http"
 	_ "net/http/pprof"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"runtime"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/TheZeroSlave/zapsentry"
 	sentry "github.com/getsentry/sentry-go"
 	"github.com/spf13/cobra"
+	"go.keploy.io/server/pkg/hooks"
+	"go.keploy.io/server/pkg/logging"
+	"go.keploy.io/server/pkg/models"
 	"go.keploy.io/server/pkg/platform/fs"
+	"go.keploy.io/server/pkg/platform/yaml"
+	"go.keploy.io/server/pkg/shutdown"
 	"go.keploy.io/server/utils"
 	"go.uber.org/zap"
 	"go.uber.org/zap/buffer"
@@ -33,6 +43,10 @@ type Root struct {
 
 var debugMode bool
 
+// logLevel are the levels of the logs of keploy and of its subsystems, like info,proxy=debug, and logFormat their
+// format, console or json.
+var logLevel, logFormat string
+
 type colorConsoleEncoder struct {
 	*zapcore.EncoderConfig
 	zapcore.Encoder
@@ -88,25 +102,50 @@ func setupLogger() *zap.Logger {
 		"./keploy-logs.txt",
 	}
 
+	if logFormat == logging.FormatJSON {
+		// one JSON object per log, without colors, for the CI and the log collectors
+		logCfg.Encoding = "json"
+		logCfg.EncoderConfig = zap.NewProductionEncoderConfig()
+		logCfg.EncoderConfig.EncodeTime = zapcore.ISO8601TimeEncoder
+	}
+
+	defaultLevel := zapcore.InfoLevel
+	if debugMode {
+		defaultLevel = zapcore.DebugLevel
+	}
+	levels, levelErr := logging.ParseLevels(logLevel, defaultLevel)
+	if levelErr != nil {
+		levels = logging.Levels{Default: defaultLevel}
+	}
+
 	if debugMode {
 		go func() {
 			defer utils.HandlePanic()
 			log.Println(http.ListenAndServe("localhost:6060", nil))
 		}()
 
-		logCfg.Level = zap.NewAtomicLevelAt(zap.DebugLevel)
 		logCfg.DisableStacktrace = false
 	} else {
-		logCfg.Level = zap.NewAtomicLevelAt(zap.InfoLevel)
 		logCfg.DisableStacktrace = true
 		logCfg.EncoderConfig.EncodeCaller = nil
+		logCfg.EncoderConfig.CallerKey = ""
 	}
+	// the logs are enabled from the lowest level, each subsystem logging from its own one
+	logCfg.Level = zap.NewAtomicLevelAt(levels.Min())
 
-	logger, err := logCfg.Build()
+	logger, err := logCfg.Build(zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+		return logging.NewCore(core, levels)
+	}))
 	if err != nil {
 		log.Panic(Emoji, "failed to start the logger for the CLI")
 		return nil
 	}
+	if levelErr != nil {
+		logger.Warn("ignoring the --log-level flag", zap.Error(levelErr))
+	}
+	if logFormat != "" && logFormat != logging.FormatConsole && logFormat != logging.FormatJSON {
+		logger.Warn("unknown --log-format, logging for the console", zap.String("logFormat", logFormat))
+	}
 	return logger
 }
 
@@ -220,6 +259,88 @@ func checkForDebugFlag(args []string) bool {
 	return false
 }
 
+// parsePassThroughRules parses the entries of the passThrough flag, each one being a CIDR ("10.0.0.0/8"), a port
+// ("5000"), a host or an IP, or a host or an IP along with a port ("license.example.com:443").
+func parsePassThroughRules(entries []string) ([]models.PassThroughRule, error) {
+	var rules []models.PassThroughRule
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		if strings.Contains(entry, "/") {
+			if _, _, err := net.ParseCIDR(entry); err != nil {
+				return nil, fmt.Errorf("invalid CIDR %q in the pass through rules: %v", entry, err)
+			}
+			rules = append(rules, models.PassThroughRule{CIDR: entry})
+			continue
+		}
+		if port, err := strconv.ParseUint(entry, 10, 16); err == nil {
+			rules = append(rules, models.PassThroughRule{Port: uint32(port)})
+			continue
+		}
+		if host, portStr, err := net.SplitHostPort(entry); err == nil {
+			port, err := strconv.ParseUint(portStr, 10, 16)
+			if err != nil {
+				return nil, fmt.Errorf("invalid port in the pass through rule %q: %v", entry, err)
+			}
+			rules = append(rules, models.PassThroughRule{Host: host, Port: uint32(port)})
+			continue
+		}
+		rules = append(rules, models.PassThroughRule{Host: entry})
+	}
+	return rules, nil
+}
+
+// parseUnixSockets parses the entries of the unix-socket flag, each one being the path of a unix socket, optionally
+// followed by the protocol of its calls ("/var/run/mysqld/mysqld.sock=mysql").
+func parseUnixSockets(entries []string) ([]models.UnixSocket, error) {
+	var sockets []models.UnixSocket
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		path, protocol, _ := strings.Cut(entry, "=")
+		if !filepath.IsAbs(path) {
+			return nil, fmt.Errorf("the path of the unix socket %q must be absolute", path)
+		}
+		sockets = append(sockets, models.UnixSocket{Path: path, Protocol: strings.ToLower(protocol)})
+	}
+	return sockets, nil
+}
+
+// validateCaptureMode checks the mode of the capture of the calls of the application given by the capture-mode flag or
+// the config file.
+func validateCaptureMode(mode string) error {
+	switch mode {
+	case "", hooks.CaptureAuto, hooks.CaptureEBPF, hooks.CaptureProxy, hooks.CapturePreload:
+		return nil
+	}
+	return fmt.Errorf("invalid capture mode %q, expected one of %s, %s, %s and %s", mode, hooks.CaptureAuto, hooks.CaptureEBPF, hooks.CaptureProxy, hooks.CapturePreload)
+}
+
+func validateMockFormat(format string) error {
+	switch format {
+	case "", yaml.FormatYaml, yaml.FormatCbor:
+		return nil
+	}
+	return fmt.Errorf("invalid mock format %q, expected %s or %s", format, yaml.FormatYaml, yaml.FormatCbor)
+}
+
+// checkForFlag returns the value of the flag given as --name value or --name=value, empty when it isn't given.
+func checkForFlag(args []string, name string) string {
+	for i, arg := range args {
+		if arg == "--"+name && i+1 < len(args) {
+			return args[i+1]
+		}
+		if value, ok := strings.CutPrefix(arg, "--"+name+"="); ok {
+			return value
+		}
+	}
+	return ""
+}
+
 func deleteLogs(logger *zap.Logger) {
 	//Check if keploy-log.txt exists
 	_, err := os.Stat("keploy-logs.txt")
@@ -245,14 +366,20 @@ func (r *Root) execute() {
 	rootCmd.SetHelpTemplate(rootCustomHelpTemplate)
 
 	rootCmd.PersistentFlags().BoolVar(&debugMode, "debug", false, "Run in debug mode")
+	rootCmd.PersistentFlags().StringVar(&logLevel, "log-level", "", "Levels of the logs, of keploy and of its subsystems (hooks, proxy, replayer and the parsers like mysqlparser), e.g. info,proxy=debug,mysqlparser=debug")
+	rootCmd.PersistentFlags().StringVar(&logFormat, "log-format", logging.FormatConsole, "Format of the logs: console, or json for one JSON object per log")
 
 	// Manually parse flags to determine debug mode early
 	debugMode = checkForDebugFlag(os.Args[1:])
+	logLevel = checkForFlag(os.Args[1:], "log-level")
+	logFormat = checkForFlag(os.Args[1:], "log-format")
 	// Now that flags are parsed, set up the l722ogger
 	r.logger = setupLogger()
 	r.logger = modifyToSentryLogger(r.logger, sentry.CurrentHub().Client())
 	defer deleteLogs(r.logger)
-	r.subCommands = append(r.subCommands, NewCmdRecord(r.logger), NewCmdTest(r.logger), NewCmdServe(r.logger), NewCmdExample(r.logger), NewCmdMockRecord(r.logger), NewCmdMockTest(r.logger), NewCmdGenerateConfig(r.logger))
+	// the cleanups of the subsystems are run on every exit path, the signals included
+	shutdown.Listen(r.logger)
+	r.subCommands = append(r.subCommands, NewCmdRecord(r.logger), NewCmdTest(r.logger), NewCmdServe(r.logger), NewCmdExample(r.logger), NewCmdMockRecord(r.logger), NewCmdMockTest(r.logger), NewCmdGenerateConfig(r.logger), NewCmdMocks(r.logger), NewCmdTestSet(r.logger), NewCmdReport(r.logger), NewCmdCoverage(r.logger), NewCmdStorage(r.logger), NewCmdMigrate(r.logger), NewCmdExport(r.logger), NewCmdImport(r.logger), NewCmdGenerate(r.logger), NewCmdContract(r.logger), NewCmdAgent(r.logger), NewCmdControl(r.logger), NewCmdReview(r.logger), NewCmdUpdate(r.logger), NewCmdShadow(r.logger))
 
 	// add the registered keploy plugins as subcommands to the rootCmd
 	for _, sc := range r.subCommands {
@@ -261,8 +388,9 @@ func (r *Root) execute() {
 
 	if err := rootCmd.Execute(); err != nil {
 		r.logger.Error("failed to start the CLI.", zap.Any("error", err.Error()))
-		os.Exit(1)
+		shutdown.Exit(r.logger, 1, "error")
 	}
+	shutdown.Run(r.logger, "exit")
 }
 
 // Plugins is an interface used to define plugins.
@@ -0,0 +1,65 @@
+package cmd
+
+import (
+	"errors"
+	"net/url"
+
+	"github.com/spf13/cobra"
+	"go.keploy.io/server/pkg/service/record"
+	"go.uber.org/zap"
+)
+
+func NewCmdShadow(logger *zap.Logger) *Shadow {
+	return &Shadow{
+		recorder: record.NewRecorder(logger),
+		logger:   logger,
+	}
+}
+
+type Shadow struct {
+	recorder record.Recorder
+	logger   *zap.Logger
+}
+
+// GetCmd returns keploy record, whose recorded calls are replayed to the candidate build as well.
+func (s *Shadow) GetCmd() *cobra.Command {
+	recorder := &Record{recorder: s.recorder, logger: s.logger}
+	recordCmd := recorder.GetCmd()
+	runRecord := recordCmd.RunE
+
+	var shadowCmd = &cobra.Command{
+		Use:     "shadow",
+		Short:   "record the API calls while replaying them to a candidate build and reporting the differences of its responses",
+		Example: `sudo -E env PATH=$PATH keploy shadow -c "/path/to/user/app" --candidate http://localhost:8081`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var err error
+			recorder.shadow.Candidate, err = cmd.Flags().GetString("candidate")
+			if err != nil {
+				s.logger.Error("failed to read the candidate flag", zap.Error(err))
+				return err
+			}
+			if recorder.shadow.Candidate == "" {
+				s.logger.Error("the url of the candidate build is required, like --candidate http://localhost:8081")
+				return errors.New("missing the --candidate flag")
+			}
+			if candidate, err := url.Parse(recorder.shadow.Candidate); err != nil || candidate.Scheme == "" || candidate.Host == "" {
+				s.logger.Error("invalid url of the candidate build, like http://localhost:8081", zap.String("candidate", recorder.shadow.Candidate))
+				return errors.New("invalid --candidate flag")
+			}
+			recorder.shadow.Noise, err = cmd.Flags().GetStringSlice("shadow-noise")
+			if err != nil {
+				s.logger.Error("failed to read the shadow-noise flag", zap.Error(err))
+				return err
+			}
+			return runRecord(cmd, args)
+		},
+	}
+
+	shadowCmd.Flags().AddFlagSet(recordCmd.Flags())
+
+	shadowCmd.Flags().String("candidate", "", "Base url of the candidate build which the recorded calls are replayed to, like http://localhost:8081")
+
+	shadowCmd.Flags().StringSlice("shadow-noise", []string{}, "Fields whose differences are ignored, besides header.Date, e.g. --shadow-noise \"body.id,header.Etag\"")
+
+	return shadowCmd
+}
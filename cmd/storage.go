@@ -0,0 +1,134 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+	"go.keploy.io/server/pkg/models"
+	"go.keploy.io/server/pkg/service/storage"
+	"go.keploy.io/server/utils"
+	"go.uber.org/zap"
+	yamlLib "gopkg.in/yaml.v3"
+)
+
+func NewCmdStorage(logger *zap.Logger) *Storage {
+	return &Storage{
+		logger: logger,
+	}
+}
+
+type Storage struct {
+	logger *zap.Logger
+}
+
+// readStorageConfig reads the storage section of the config file, empty when there is no config file.
+func readStorageConfig(configPath string) (models.Storage, error) {
+	configFilePath := filepath.Join(configPath, "keploy-config.yaml")
+	if isExist := utils.CheckFileExists(configFilePath); !isExist {
+		return models.Storage{}, nil
+	}
+	file, err := os.OpenFile(configFilePath, os.O_RDONLY, os.ModePerm)
+	if err != nil {
+		return models.Storage{}, err
+	}
+	defer file.Close()
+	var doc models.Config
+	if err := yamlLib.NewDecoder(file).Decode(&doc); err != nil {
+		return models.Storage{}, fmt.Errorf("failed to get the storage config from config file due to error: %s", err)
+	}
+	return doc.Storage, nil
+}
+
+// remoteStorage returns the storage of the config file, nil when none is configured.
+func remoteStorage(configPath string, logger *zap.Logger) (storage.Storage, error) {
+	config, err := readStorageConfig(configPath)
+	if err != nil || config.Type == "" {
+		return nil, err
+	}
+	return storage.NewStorage(logger, config)
+}
+
+func (s *Storage) GetCmd() *cobra.Command {
+	var storageCmd = &cobra.Command{
+		Use:   "storage",
+		Short: "push the test sets to the storage of the config file and pull them from it",
+	}
+
+	// storageFor returns the storage of the config file of the command, an error being returned when none is configured
+	storageFor := func(cmd *cobra.Command) (storage.Storage, error) {
+		configPath, err := cmd.Flags().GetString("config-path")
+		if err != nil {
+			s.logger.Error("failed to read the config path", zap.Error(err))
+			return nil, err
+		}
+		store, err := remoteStorage(configPath, s.logger)
+		if err != nil {
+			s.logger.Error("failed to set up the storage", zap.Error(err))
+			return nil, err
+		}
+		if store == nil {
+			err = fmt.Errorf("no storage in %s", filepath.Join(configPath, "keploy-config.yaml"))
+			s.logger.Error("failed to set up the storage", zap.Error(err))
+			return nil, err
+		}
+		return store, nil
+	}
+
+	var pushCmd = &cobra.Command{
+		Use:     "push [test-set]...",
+		Short:   "upload the test sets, all of them when none is given, to the storage",
+		Example: "keploy storage push test-set-1 -p /path/to/localdir",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			path, err := keployPath(cmd, s.logger)
+			if err != nil {
+				return err
+			}
+			store, err := storageFor(cmd)
+			if err != nil {
+				return err
+			}
+			if err := store.Push(path, args); err != nil {
+				s.logger.Error("failed to push the test sets", zap.Error(err))
+				return err
+			}
+			return nil
+		},
+	}
+
+	var pullCmd = &cobra.Command{
+		Use:     "pull [test-set]...",
+		Short:   "download the test sets, all of them when none is given, from the storage",
+		Example: "keploy storage pull --force -p /path/to/localdir",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			path, err := keployPath(cmd, s.logger)
+			if err != nil {
+				return err
+			}
+			force, err := cmd.Flags().GetBool("force")
+			if err != nil {
+				s.logger.Error("failed to read the force flag", zap.Error(err))
+				return err
+			}
+			store, err := storageFor(cmd)
+			if err != nil {
+				return err
+			}
+			if _, err := store.Pull(path, args, force); err != nil {
+				s.logger.Error("failed to pull the test sets", zap.Error(err))
+				return err
+			}
+			return nil
+		},
+	}
+	pullCmd.Flags().BoolP("force", "f", false, "Replace the test sets already in the keploy directory")
+
+	for _, subCmd := range []*cobra.Command{pushCmd, pullCmd} {
+		subCmd.Flags().StringP("path", "p", "", "Path to the local directory where the generated testcases/mocks are stored")
+		subCmd.Flags().String("config-path", ".", "Path to the local directory where keploy configuration file is stored")
+		storageCmd.AddCommand(subCmd)
+	}
+
+	return storageCmd
+}
@@ -9,8 +9,11 @@ import (
 	"time"
 
 	"github.com/spf13/cobra"
+	"go.keploy.io/server/pkg/hooks"
+	"go.keploy.io/server/pkg/metrics"
 	"go.keploy.io/server/pkg/models"
 	"go.keploy.io/server/pkg/service/test"
+	"go.keploy.io/server/pkg/shutdown"
 	"go.keploy.io/server/utils"
 	"go.uber.org/zap"
 	yamlLib "gopkg.in/yaml.v3"
@@ -39,7 +42,7 @@ func readTestConfig(configPath string) (*models.Test, error) {
 	return &doc.Test, nil
 }
 
-func (t *Test) getTestConfig(path *string, proxyPort *uint32, appCmd *string, tests *map[string][]string, appContainer, networkName *string, Delay *uint64, buildDelay *time.Duration, passThorughPorts *[]uint, apiTimeout *uint64, globalNoise *models.GlobalNoise, testSetNoise *models.TestsetNoise, coverageReportPath *string, withCoverage *bool, configPath string) error {
+func (t *Test) getTestConfig(path *string, proxyPort *uint32, appCmd *string, tests *map[string][]string, appContainer, networkName *string, Delay *uint64, buildDelay *time.Duration, passThorughPorts *[]uint, apiTimeout *uint64, globalNoise *models.GlobalNoise, testSetNoise *models.TestsetNoise, coverageReportPath *string, withCoverage *bool, protoDescriptors *[]string, protobufRoutes *[]models.ProtobufRoute, httpConfig *models.HttpConfig, sqlConfig *models.SqlConfig, matchers *[]models.MatcherRule, mockMatching *models.MockMatching, passThrough *[]models.PassThroughRule, parallel *int, reportFormats *[]string, unitCoverage *[]string, language *string, tags *[]string, watch *models.Watch, timeFreezing *models.TimeFreezing, deterministicRandom *bool, simulateLatency *float64, chaos *models.Chaos, unixSockets *[]models.UnixSocket, captureMode *string, compose *models.Compose, configPath string) error {
 	configFilePath := filepath.Join(configPath, "keploy-config.yaml")
 	if isExist := utils.CheckFileExists(configFilePath); !isExist {
 		return errFileNotFound
@@ -81,6 +84,59 @@ func (t *Test) getTestConfig(path *string, proxyPort *uint32, appCmd *string, te
 		*coverageReportPath = confTest.CoverageReportPath
 	}
 	*withCoverage = *withCoverage || confTest.WithCoverage
+	if len(*protoDescriptors) == 0 {
+		*protoDescriptors = confTest.ProtoDescriptors
+	}
+	*protobufRoutes = confTest.ProtobufRoutes
+	*httpConfig = confTest.Http
+	*sqlConfig = confTest.Sql
+	*matchers = confTest.Matchers
+	*mockMatching = confTest.MockMatching
+	*passThrough = append(*passThrough, confTest.PassThrough...)
+	if *parallel == 0 {
+		*parallel = confTest.Parallel
+	}
+	if len(*reportFormats) == 0 {
+		*reportFormats = confTest.ReportFormats
+	}
+	if len(*unitCoverage) == 0 {
+		*unitCoverage = confTest.UnitCoverage
+	}
+	if *language == "" {
+		*language = confTest.Language
+	}
+	if len(*tags) == 0 {
+		*tags = confTest.Tags
+	}
+	if len(watch.Paths) == 0 {
+		watch.Paths = confTest.Watch.Paths
+	}
+	if watch.BuildCmd == "" {
+		watch.BuildCmd = confTest.Watch.BuildCmd
+	}
+	timeFreezing.Enabled = timeFreezing.Enabled || confTest.TimeFreezing.Enabled
+	if timeFreezing.LibPath == "" {
+		timeFreezing.LibPath = confTest.TimeFreezing.LibPath
+	}
+	if timeFreezing.Header == "" {
+		timeFreezing.Header = confTest.TimeFreezing.Header
+	}
+	*deterministicRandom = *deterministicRandom || confTest.DeterministicRandom
+	if *simulateLatency == 0 {
+		*simulateLatency = confTest.SimulateLatency
+	}
+	chaos.Enabled = chaos.Enabled || confTest.Chaos.Enabled
+	chaos.Faults = confTest.Chaos.Faults
+	*unixSockets = append(*unixSockets, confTest.UnixSockets...)
+	if *captureMode == "" {
+		*captureMode = confTest.CaptureMode
+	}
+	if compose.File == "" {
+		compose.File = confTest.Compose.File
+	}
+	if compose.Service == "" {
+		compose.Service = confTest.Compose.Service
+	}
 	if *apiTimeout == 5 {
 		*apiTimeout = confTest.ApiTimeout
 	}
@@ -117,6 +173,11 @@ func (t *Test) GetCmd() *cobra.Command {
 				t.logger.Error("failed to read the go coverage directory path", zap.Error(err))
 				return err
 			}
+			protoDescriptors, err := cmd.Flags().GetStringSlice("protoDescriptors")
+			if err != nil {
+				t.logger.Error("failed to read the protobuf descriptor sets", zap.Error(err))
+				return err
+			}
 
 			appCmd, err := cmd.Flags().GetString("command")
 			if err != nil {
@@ -166,6 +227,52 @@ func (t *Test) GetCmd() *cobra.Command {
 				return err
 			}
 
+			passThroughEntries, err := cmd.Flags().GetStringSlice("passThrough")
+			if err != nil {
+				t.logger.Error("failed to read the pass through rules")
+				return err
+			}
+			passThrough, err := parsePassThroughRules(passThroughEntries)
+			if err != nil {
+				t.logger.Error("failed to parse the pass through rules", zap.Error(err))
+				return err
+			}
+
+			unixSocketEntries, err := cmd.Flags().GetStringSlice("unix-socket")
+			if err != nil {
+				t.logger.Error("failed to read the unix-socket flag", zap.Error(err))
+				return err
+			}
+			unixSockets, err := parseUnixSockets(unixSocketEntries)
+			if err != nil {
+				t.logger.Error("failed to parse the unix sockets", zap.Error(err))
+				return err
+			}
+
+			captureMode, err := cmd.Flags().GetString("capture-mode")
+			if err != nil {
+				t.logger.Error("failed to read the capture-mode flag", zap.Error(err))
+				return err
+			}
+
+			var compose models.Compose
+			compose.File, err = cmd.Flags().GetString("compose")
+			if err != nil {
+				t.logger.Error("failed to read the compose flag", zap.Error(err))
+				return err
+			}
+			compose.Service, err = cmd.Flags().GetString("service")
+			if err != nil {
+				t.logger.Error("failed to read the service flag", zap.Error(err))
+				return err
+			}
+
+			pid, err := cmd.Flags().GetUint32("pid")
+			if err != nil {
+				t.logger.Error("failed to read the pid flag", zap.Error(err))
+				return err
+			}
+
 			configPath, err := cmd.Flags().GetString("config-path")
 			if err != nil {
 				t.logger.Error("failed to read the config path")
@@ -178,6 +285,143 @@ func (t *Test) GetCmd() *cobra.Command {
 				return err
 			}
 
+			parallel, err := cmd.Flags().GetInt("parallel")
+			if err != nil {
+				t.logger.Error("failed to read the parallel flag", zap.Error(err))
+				return err
+			}
+
+			workerFlag, err := cmd.Flags().GetString("worker")
+			if err != nil {
+				t.logger.Error("failed to read the worker flag", zap.Error(err))
+				return err
+			}
+			var worker test.Partition
+			if workerFlag != "" {
+				worker, err = test.ParsePartition(workerFlag)
+				if err != nil {
+					t.logger.Error("invalid worker flag", zap.Error(err))
+					return err
+				}
+			}
+
+			shardFlag, err := cmd.Flags().GetString("shard")
+			if err != nil {
+				t.logger.Error("failed to read the shard flag", zap.Error(err))
+				return err
+			}
+			var shard test.Partition
+			if shardFlag != "" {
+				shard, err = test.ParsePartition(shardFlag)
+				if err != nil {
+					t.logger.Error("invalid shard flag", zap.Error(err))
+					return err
+				}
+			}
+
+			reportFormats, err := cmd.Flags().GetStringSlice("report-format")
+			if err != nil {
+				t.logger.Error("failed to read the report formats", zap.Error(err))
+				return err
+			}
+
+			unitCoverage, err := cmd.Flags().GetStringSlice("unitCoverage")
+			if err != nil {
+				t.logger.Error("failed to read the unit tests coverage profiles", zap.Error(err))
+				return err
+			}
+
+			language, err := cmd.Flags().GetString("language")
+			if err != nil {
+				t.logger.Error("failed to read the programming language of the application", zap.Error(err))
+				return err
+			}
+
+			testPatterns, err := cmd.Flags().GetStringSlice("tests")
+			if err != nil {
+				t.logger.Error("failed to read the tests flag", zap.Error(err))
+				return err
+			}
+
+			tags, err := cmd.Flags().GetStringSlice("tags")
+			if err != nil {
+				t.logger.Error("failed to read the tags flag", zap.Error(err))
+				return err
+			}
+
+			watch, err := cmd.Flags().GetBool("watch")
+			if err != nil {
+				t.logger.Error("failed to read the watch flag", zap.Error(err))
+				return err
+			}
+
+			var watchConfig models.Watch
+			watchConfig.Paths, err = cmd.Flags().GetStringSlice("watch-path")
+			if err != nil {
+				t.logger.Error("failed to read the watch-path flag", zap.Error(err))
+				return err
+			}
+
+			watchConfig.BuildCmd, err = cmd.Flags().GetString("build-cmd")
+			if err != nil {
+				t.logger.Error("failed to read the build-cmd flag", zap.Error(err))
+				return err
+			}
+
+			rerun, err := cmd.Flags().GetStringSlice("rerun")
+			if err != nil {
+				t.logger.Error("failed to read the rerun flag", zap.Error(err))
+				return err
+			}
+
+			flakeRuns, err := cmd.Flags().GetInt("flake-detect")
+			if err != nil {
+				t.logger.Error("failed to read the flake-detect flag", zap.Error(err))
+				return err
+			}
+
+			flakeFix, err := cmd.Flags().GetBool("flake-fix")
+			if err != nil {
+				t.logger.Error("failed to read the flake-fix flag", zap.Error(err))
+				return err
+			}
+			if flakeFix && flakeRuns < 2 {
+				t.logger.Error("--flake-fix needs the testcases to be run several times with --flake-detect N")
+				return errors.New("--flake-fix without --flake-detect")
+			}
+
+			var timeFreezing models.TimeFreezing
+			timeFreezing.Enabled, err = cmd.Flags().GetBool("freeze-time")
+			if err != nil {
+				t.logger.Error("failed to read the freeze-time flag", zap.Error(err))
+				return err
+			}
+
+			deterministicRandom, err := cmd.Flags().GetBool("deterministic-random")
+			if err != nil {
+				t.logger.Error("failed to read the deterministic-random flag", zap.Error(err))
+				return err
+			}
+
+			simulateLatency, err := cmd.Flags().GetFloat64("simulate-latency")
+			if err != nil {
+				t.logger.Error("failed to read the simulate-latency flag", zap.Error(err))
+				return err
+			}
+
+			metricsAddr, err := cmd.Flags().GetString("metrics-addr")
+			if err != nil {
+				t.logger.Error("failed to read the metrics-addr flag", zap.Error(err))
+				return err
+			}
+
+			var chaos models.Chaos
+			chaos.Enabled, err = cmd.Flags().GetBool("chaos")
+			if err != nil {
+				t.logger.Error("failed to read the chaos flag", zap.Error(err))
+				return err
+			}
+
 			tests := map[string][]string{}
 
 			testsets, err := cmd.Flags().GetStringSlice("testsets")
@@ -192,8 +436,13 @@ func (t *Test) GetCmd() *cobra.Command {
 
 			globalNoise := make(models.GlobalNoise)
 			testsetNoise := make(models.TestsetNoise)
+			httpConfig := models.HttpConfig{}
+			sqlConfig := models.SqlConfig{}
+			var matchers []models.MatcherRule
+			mockMatching := models.MockMatching{}
+			var protobufRoutes []models.ProtobufRoute
 
-			err = t.getTestConfig(&path, &proxyPort, &appCmd, &tests, &appContainer, &networkName, &delay, &buildDelay, &ports, &apiTimeout, &globalNoise, &testsetNoise, &coverageReportPath, &withCoverage, configPath)
+			err = t.getTestConfig(&path, &proxyPort, &appCmd, &tests, &appContainer, &networkName, &delay, &buildDelay, &ports, &apiTimeout, &globalNoise, &testsetNoise, &coverageReportPath, &withCoverage, &protoDescriptors, &protobufRoutes, &httpConfig, &sqlConfig, &matchers, &mockMatching, &passThrough, &parallel, &reportFormats, &unitCoverage, &language, &tags, &watchConfig, &timeFreezing, &deterministicRandom, &simulateLatency, &chaos, &unixSockets, &captureMode, &compose, configPath)
 			if err != nil {
 				if err == errFileNotFound {
 					t.logger.Info("continuing without configuration file because file not found")
@@ -202,7 +451,30 @@ func (t *Test) GetCmd() *cobra.Command {
 				}
 			}
 
-			if appCmd == "" {
+			if err := validateCaptureMode(captureMode); err != nil {
+				t.logger.Error("invalid capture mode", zap.Error(err))
+				return err
+			}
+
+			if simulateLatency < 0 {
+				t.logger.Error("the scale of the simulated latency can't be negative", zap.Float64("simulate-latency", simulateLatency))
+				return errors.New("invalid --simulate-latency flag")
+			}
+
+			if compose.File != "" {
+				if appCmd != "" {
+					t.logger.Error("the application is either run by its command or by the service of a docker compose file", zap.String("appCmd", appCmd), zap.String("compose", compose.File))
+					return errors.New("both -c and --compose are given")
+				}
+				// the service is brought up without the services it depends on, which are mocked
+				appCmd, appContainer, err = hooks.ComposeCommand(compose, models.MODE_TEST)
+				if err != nil {
+					t.logger.Error("failed to run the service of the docker compose file", zap.Error(err))
+					return err
+				}
+			}
+
+			if appCmd == "" && pid == 0 {
 				t.logger.Error("Couldn't find appCmd")
 				if isDockerCmd {
 					t.logger.Info(`Example usage: keploy test -c "docker run -p 8080:8080 --network myNetworkName myApplicationImageName" --delay 6`)
@@ -249,6 +521,26 @@ func (t *Test) GetCmd() *cobra.Command {
 
 			t.logger.Info("", zap.Any("keploy test and mock path", path), zap.Any("keploy testReport path", testReportPath))
 
+			// the test sets missing from the keploy directory are pulled from the storage, by the run rather than by
+			// its workers
+			if workerFlag == "" {
+				store, err := remoteStorage(configPath, t.logger)
+				if err != nil {
+					t.logger.Error("failed to set up the storage of the test sets", zap.Error(err))
+					return err
+				}
+				if store != nil {
+					testSets := []string{}
+					for testSet := range tests {
+						testSets = append(testSets, testSet)
+					}
+					if _, err := store.Pull(path, testSets, false); err != nil {
+						t.logger.Error("failed to pull the test sets from the storage", zap.Error(err))
+						return err
+					}
+				}
+			}
+
 			var hasContainerName bool
 			if isDockerCmd {
 				if strings.Contains(appCmd, "--name") {
@@ -263,6 +555,14 @@ func (t *Test) GetCmd() *cobra.Command {
 
 			t.logger.Debug("the ports are", zap.Any("ports", ports))
 
+			selection, err := test.ParseSelection(testPatterns, tags)
+			if err != nil {
+				t.logger.Error("invalid selection of the tests", zap.Error(err))
+				return err
+			}
+			// the runs of a watch run only run the testcases which failed in the previous one
+			selection = selection.Only(rerun)
+
 			mongoPassword, err := cmd.Flags().GetString("mongoPassword")
 			if err != nil {
 				t.logger.Error("failed to read the ports of outgoing calls to be ignored")
@@ -270,21 +570,76 @@ func (t *Test) GetCmd() *cobra.Command {
 			}
 			t.logger.Debug("the configuration for mocking mongo connection", zap.Any("password", mongoPassword))
 
-			t.tester.Test(path, testReportPath, appCmd, test.TestOptions{
-				Tests:              tests,
-				AppContainer:       appContainer,
-				AppNetwork:         networkName,
-				MongoPassword:      mongoPassword,
-				Delay:              delay,
-				BuildDelay:         buildDelay,
-				PassThroughPorts:   ports,
-				ApiTimeout:         apiTimeout,
-				ProxyPort:          proxyPort,
-				GlobalNoise:        globalNoise,
-				TestsetNoise:       testsetNoise,
-				WithCoverage:       withCoverage,
-				CoverageReportPath: coverageReportPath,
-			}, enableTele)
+			postgresPassword, err := cmd.Flags().GetString("postgresPassword")
+			if err != nil {
+				t.logger.Error("failed to read the postgresPassword flag", zap.Error(err))
+				return err
+			}
+
+			options := test.TestOptions{
+				Tests:               tests,
+				AppContainer:        appContainer,
+				AppNetwork:          networkName,
+				MongoPassword:       mongoPassword,
+				PostgresPassword:    postgresPassword,
+				Delay:               delay,
+				BuildDelay:          buildDelay,
+				PassThroughPorts:    ports,
+				ApiTimeout:          apiTimeout,
+				ProxyPort:           proxyPort,
+				GlobalNoise:         globalNoise,
+				TestsetNoise:        testsetNoise,
+				WithCoverage:        withCoverage,
+				CoverageReportPath:  coverageReportPath,
+				ProtoDescriptors:    protoDescriptors,
+				ProtobufRoutes:      protobufRoutes,
+				HttpConfig:          httpConfig,
+				SqlConfig:           sqlConfig,
+				Matchers:            matchers,
+				MockMatching:        mockMatching,
+				PassThrough:         passThrough,
+				Shard:               shard,
+				Worker:              worker,
+				ReportFormats:       reportFormats,
+				UnitCoverage:        unitCoverage,
+				Language:            language,
+				Selection:           selection,
+				FlakeDetection:      test.FlakeDetection{Runs: flakeRuns, FixNoise: flakeFix},
+				TimeFreezing:        timeFreezing,
+				DeterministicRandom: deterministicRandom,
+				SimulateLatency:     simulateLatency,
+				Chaos:               chaos,
+				UnixSockets:         unixSockets,
+				CaptureMode:         captureMode,
+				Pid:                 pid,
+				MetricsAddr:         metricsAddr,
+			}
+
+			// the runs of a watch run are the keploy processes started by it
+			if watch && workerFlag == "" {
+				t.tester.TestWatch(path, testReportPath, appCmd, options, test.WatchOptions{
+					Paths:    watchConfig.Paths,
+					BuildCmd: watchConfig.BuildCmd,
+				})
+				return nil
+			}
+
+			// the workers of a parallel run are the keploy processes started by the run
+			if parallel > 1 && workerFlag == "" {
+				if !t.tester.TestParallel(path, testReportPath, appCmd, options, parallel) {
+					shutdown.Exit(t.logger, 1, "failed parallel test run")
+				}
+				return nil
+			}
+
+			if metricsAddr != "" {
+				metrics.Serve(metricsAddr, metrics.Handler(metrics.Default, t.logger), t.logger)
+			}
+			passed := t.tester.Test(path, testReportPath, appCmd, options, enableTele)
+			// the exit code tells the parallel run whether the test sets of the worker passed
+			if !passed && workerFlag != "" {
+				shutdown.Exit(t.logger, 1, "failed test sets of the worker")
+			}
 
 			return nil
 		},
@@ -309,15 +664,67 @@ func (t *Test) GetCmd() *cobra.Command {
 
 	testCmd.Flags().UintSlice("passThroughPorts", []uint{}, "Ports of Outgoing dependency calls to be ignored as mocks")
 
+	testCmd.Flags().StringSlice("passThrough", []string{}, "Hosts, CIDRs, ports or host:port of the outgoing calls forwarded untouched to their server")
+
+	testCmd.Flags().String("capture-mode", "", "How the calls of the application are captured: auto (eBPF, or the userspace proxy when it can't be loaded), ebpf, or without privileges proxy (HTTP_PROXY and ALL_PROXY) and preload (LD_PRELOAD connect())")
+
+	testCmd.Flags().StringSlice("unix-socket", []string{}, "Unix sockets of the dependencies mocked, as path or path=protocol, e.g. /var/run/mysqld/mysqld.sock=mysql")
+
+	testCmd.Flags().String("compose", "", "Docker compose file whose --service is brought up alone and tested, the services it depends on being mocked")
+
+	testCmd.Flags().String("service", "", "Service of the --compose file running the application")
+
+	testCmd.Flags().Uint32("pid", 0, "Pid of the running application which is tested, instead of starting it with -c")
+
 	testCmd.Flags().String("config-path", ".", "Path to the local directory where keploy configuration file is stored")
 
+	testCmd.Flags().StringSlice("protoDescriptors", []string{}, "Paths of the protobuf descriptor sets (protoc --descriptor_set_out --include_imports) used to decode the gRPC messages of the dependencies")
+
 	testCmd.Flags().String("mongoPassword", "default123", "Authentication password for mocking MongoDB connection")
 
+	testCmd.Flags().String("postgresPassword", "", "Password of the postgres user, to emulate the SCRAM-SHA-256 authentication of the recorded connections")
+
 	testCmd.Flags().String("coverageReportPath", "", "Write a go coverage profile to the file in the given directory.")
 
+	testCmd.Flags().Int("parallel", 0, "Number of test sets run concurrently, each one by a keploy process with its own proxy and application container")
+
+	testCmd.Flags().StringSlice("report-format", []string{}, "Formats of the test reports besides yaml (json, junit, html) e.g. --report-format junit,html")
+
+	testCmd.Flags().String("shard", "", "Share i/n of the selected test sets run by this machine of a distributed run, whose reports are merged through keploy report merge")
+
+	testCmd.Flags().String("worker", "", "Share i/n of the test sets run by a worker of a parallel run")
+	testCmd.Flags().MarkHidden("worker")
+
 	testCmd.Flags().Bool("enableTele", true, "Switch for telemetry")
 	testCmd.Flags().MarkHidden("enableTele")
 
+	testCmd.Flags().StringSlice("tests", []string{}, "Names or regexes of the testcases to run, qualified like test-set-0/test-3 for a single test set e.g. --tests \"test-1,test-7\"")
+	testCmd.Flags().StringSlice("tags", []string{}, "Run only the testcases having one of the tags of their metadata e.g. --tags smoke")
+
+	testCmd.Flags().Int("flake-detect", 0, "Run each testcase N times in a row with the same mocks and report the flaky ones along with their changing fields")
+	testCmd.Flags().Bool("flake-fix", false, "Add the fields changing from a run to the next, found by --flake-detect, to the noise of the testcases")
+
+	testCmd.Flags().Bool("deterministic-random", false, "Feed the application the random bytes generated from the seed of the recording of the test sets recorded with --deterministic-random")
+
+	testCmd.Flags().Float64("simulate-latency", 0, "Delay the responses of the mocks by their recorded latency, multiplied by the scale e.g. --simulate-latency or --simulate-latency=0.5")
+	testCmd.Flags().Lookup("simulate-latency").NoOptDefVal = "1"
+
+	testCmd.Flags().Bool("chaos", false, "Replay the mocks selected by the faults of the chaos section of the config file as failures: connection resets, error statuses, truncated bodies or extra latency")
+
+	testCmd.Flags().String("metrics-addr", "", "Address the metrics of the test run are served on in the Prometheus format, like :9090, at /metrics")
+
+	testCmd.Flags().Bool("freeze-time", false, "Make the application observe the recorded time of the testcases, preloading libfaketime in it and adding the time to the replayed requests")
+
+	testCmd.Flags().Bool("watch", false, "Run the testcases again when the application changes, only the failing ones while some fail")
+	testCmd.Flags().StringSlice("watch-path", []string{}, "Files and directories of the sources or the binary of the application watched, the current directory by default")
+	testCmd.Flags().String("build-cmd", "", "Command rebuilding the application once it changed, before the testcases are run again e.g. --build-cmd \"go build -o app .\"")
+
+	testCmd.Flags().StringSlice("rerun", []string{}, "Testcases run by a run of a watch run, named like test-set-0/test-3")
+	testCmd.Flags().MarkHidden("rerun")
+
+	testCmd.Flags().StringSlice("unitCoverage", []string{}, "Go coverage profiles of the unit tests (go test -coverprofile) merged with the coverage of the test run")
+	testCmd.Flags().StringP("language", "l", "", "Programming language of the application whose coverage is captured: go, node, python or java")
+
 	testCmd.Flags().Bool("withCoverage", false, "Capture the code coverage of the go binary in the command flag.")
 	testCmd.Flags().Lookup("withCoverage").NoOptDefVal = "true"
 	testCmd.SilenceUsage = true
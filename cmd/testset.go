@@ -0,0 +1,137 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	"go.keploy.io/server/pkg/service/testset"
+	"go.keploy.io/server/utils"
+	"go.uber.org/zap"
+)
+
+func NewCmdTestSet(logger *zap.Logger) *TestSet {
+	testSets := testset.NewTestSets(logger)
+	return &TestSet{
+		testSets: testSets,
+		logger:   logger,
+	}
+}
+
+type TestSet struct {
+	testSets testset.TestSets
+	logger   *zap.Logger
+}
+
+func (t *TestSet) GetCmd() *cobra.Command {
+	var testSetCmd = &cobra.Command{
+		Use:   "testset",
+		Short: "list, rename, delete and copy the recorded test sets",
+	}
+
+	var lsCmd = &cobra.Command{
+		Use:     "ls",
+		Short:   "list the test sets with their number of testcases and mocks",
+		Example: "keploy testset ls -p /path/to/localdir",
+		Args:    cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			path, err := keployPath(cmd, t.logger)
+			if err != nil {
+				return err
+			}
+			infos, err := t.testSets.List(path)
+			if err != nil {
+				t.logger.Error("failed to list the test sets", zap.Error(err))
+				return err
+			}
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "TEST SET\tTESTCASES\tMOCKS\tLAST RUN")
+			for _, info := range infos {
+				lastRun := info.LastRun
+				if lastRun == "" {
+					lastRun = "-"
+				}
+				fmt.Fprintf(w, "%s\t%d\t%d\t%s\n", info.Name, info.Testcases, info.Mocks, lastRun)
+			}
+			return w.Flush()
+		},
+	}
+
+	var rmCmd = &cobra.Command{
+		Use:     "rm <test-set>",
+		Short:   "delete a test set",
+		Example: "keploy testset rm test-set-3 -p /path/to/localdir",
+		Args:    cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			path, err := keployPath(cmd, t.logger)
+			if err != nil {
+				return err
+			}
+			force, err := cmd.Flags().GetBool("force")
+			if err != nil {
+				t.logger.Error("failed to read the force flag", zap.Error(err))
+				return err
+			}
+			if !force {
+				remove, err := utils.AskForConfirmation(fmt.Sprintf("Do you want to delete the test set %s along with its testcases and mocks?", args[0]))
+				if err != nil {
+					t.logger.Error("failed to ask for confirmation", zap.Error(err))
+					return err
+				}
+				if !remove {
+					return nil
+				}
+			}
+			if err := t.testSets.Remove(path, args[0]); err != nil {
+				t.logger.Error("failed to delete the test set", zap.Error(err))
+				return err
+			}
+			return nil
+		},
+	}
+	rmCmd.Flags().BoolP("force", "f", false, "Delete the test set without asking for confirmation")
+
+	var mvCmd = &cobra.Command{
+		Use:     "mv <test-set> <new-test-set>",
+		Short:   "rename a test set",
+		Example: "keploy testset mv test-set-3 test-set-7 -p /path/to/localdir",
+		Args:    cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			path, err := keployPath(cmd, t.logger)
+			if err != nil {
+				return err
+			}
+			if err := t.testSets.Move(path, args[0], args[1]); err != nil {
+				t.logger.Error("failed to rename the test set", zap.Error(err))
+				return err
+			}
+			return nil
+		},
+	}
+
+	var cpCmd = &cobra.Command{
+		Use:     "cp <test-set> <new-test-set>",
+		Short:   "copy a test set",
+		Example: "keploy testset cp test-set-3 test-set-7 -p /path/to/localdir",
+		Args:    cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			path, err := keployPath(cmd, t.logger)
+			if err != nil {
+				return err
+			}
+			if err := t.testSets.Copy(path, args[0], args[1]); err != nil {
+				t.logger.Error("failed to copy the test set", zap.Error(err))
+				return err
+			}
+			return nil
+		},
+	}
+
+	for _, subCmd := range []*cobra.Command{lsCmd, rmCmd, mvCmd, cpCmd} {
+		subCmd.Flags().StringP("path", "p", "", "Path to the local directory where the generated testcases/mocks are stored")
+		testSetCmd.AddCommand(subCmd)
+	}
+
+	return testSetCmd
+}
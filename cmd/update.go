@@ -0,0 +1,116 @@
+package cmd
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+	"go.keploy.io/server/pkg/service/test"
+	"go.uber.org/zap"
+)
+
+func NewCmdUpdate(logger *zap.Logger) *Update {
+	return &Update{
+		tester: test.NewTester(logger),
+		logger: logger,
+	}
+}
+
+type Update struct {
+	tester test.Tester
+	logger *zap.Logger
+}
+
+// GetCmd returns keploy test, run for a single test set, whose expected responses are then updated with the actual
+// ones of the failed tests.
+func (u *Update) GetCmd() *cobra.Command {
+	testCmd := (&Test{tester: u.tester, logger: u.logger}).GetCmd()
+	runTests := testCmd.RunE
+
+	var updateCmd = &cobra.Command{
+		Use:     "update",
+		Short:   "run the selected testcases and update their expected responses with the actual ones when they don't match",
+		Example: `sudo -E env PATH=$PATH keploy update -c "/path/to/user/app" --test-set test-set-3 --tests test-12 --yes`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			testSet, err := cmd.Flags().GetString("test-set")
+			if err != nil {
+				u.logger.Error("failed to read the test-set flag", zap.Error(err))
+				return err
+			}
+			if testSet == "" {
+				u.logger.Error("the test set of the testcases is required, like --test-set test-set-3")
+				return errors.New("missing the --test-set flag")
+			}
+			yes, err := cmd.Flags().GetBool("yes")
+			if err != nil {
+				u.logger.Error("failed to read the yes flag", zap.Error(err))
+				return err
+			}
+			if err := cmd.Flags().Set("testsets", testSet); err != nil {
+				return err
+			}
+			// the testcases are run by this process, so that their report is the last one of the test set
+			if err := cmd.Flags().Set("parallel", "1"); err != nil {
+				return err
+			}
+			if err := runTests(cmd, args); err != nil {
+				return err
+			}
+
+			path, err := u.testsPath(cmd)
+			if err != nil {
+				return err
+			}
+			updated, err := u.tester.UpdateExpected(filepath.Join(path, "keploy", "testReports"), []string{testSet}, yes)
+			if err != nil {
+				return err
+			}
+			u.logger.Info("updated the expected responses of the failed testcases", zap.String("test-set", testSet), zap.Int("updated", updated))
+			return nil
+		},
+	}
+
+	updateCmd.Flags().AddFlagSet(testCmd.Flags())
+	for _, flag := range []string{"testsets", "parallel", "watch", "shard", "worker", "rerun"} {
+		updateCmd.Flags().MarkHidden(flag)
+	}
+
+	updateCmd.Flags().String("test-set", "", "Test set of the testcases to update")
+
+	updateCmd.Flags().Bool("yes", false, "Update the expected responses without asking for each testcase")
+
+	return updateCmd
+}
+
+// testsPath returns the directory of the keploy directory, which the path flag or the config file set like for keploy
+// test, the current directory by default.
+func (u *Update) testsPath(cmd *cobra.Command) (string, error) {
+	path, err := cmd.Flags().GetString("path")
+	if err != nil {
+		u.logger.Error("failed to read the path flag", zap.Error(err))
+		return "", err
+	}
+	if path == "" {
+		configPath, err := cmd.Flags().GetString("config-path")
+		if err != nil {
+			u.logger.Error("failed to read the config path", zap.Error(err))
+			return "", err
+		}
+		if confTest, err := readTestConfig(filepath.Join(configPath, "keploy-config.yaml")); err == nil {
+			path = confTest.Path
+		}
+	}
+	if path == "" {
+		if path, err = os.Getwd(); err != nil {
+			u.logger.Error("failed to get the path of current directory", zap.Error(err))
+			return "", err
+		}
+	}
+	path, err = filepath.Abs(path)
+	if err != nil {
+		u.logger.Error("failed to get the absolute path from relative path", zap.Error(err))
+		return "", err
+	}
+	return path, nil
+}
@@ -0,0 +1,59 @@
+package docker
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// invalidProjectChars are the characters removed from the names of the docker compose projects.
+var invalidProjectChars = regexp.MustCompile(`[^a-z0-9_-]`)
+
+// ComposeServiceContainer returns the name of the container of the service of the docker compose file: its
+// container_name, or the name given to it by docker compose, <project>-<service>-1. The project is named by
+// COMPOSE_PROJECT_NAME, the name of the file or its directory.
+func ComposeServiceContainer(filePath, service string) (string, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return "", err
+	}
+	var compose Compose
+	if err := yaml.Unmarshal(data, &compose); err != nil {
+		return "", fmt.Errorf("failed to parse the docker compose file %s: %v", filePath, err)
+	}
+
+	var serviceNode *yaml.Node
+	var services []string
+	for i := 0; i+1 < len(compose.Services.Content); i += 2 {
+		services = append(services, compose.Services.Content[i].Value)
+		if compose.Services.Content[i].Value == service {
+			serviceNode = compose.Services.Content[i+1]
+		}
+	}
+	if serviceNode == nil {
+		return "", fmt.Errorf("the docker compose file %s has no service %q, its services are %s", filePath, service, strings.Join(services, ", "))
+	}
+	for i := 0; i+1 < len(serviceNode.Content); i += 2 {
+		if serviceNode.Content[i].Value == "container_name" {
+			return serviceNode.Content[i+1].Value, nil
+		}
+	}
+
+	project := os.Getenv("COMPOSE_PROJECT_NAME")
+	if project == "" {
+		project = compose.Name
+	}
+	if project == "" {
+		absPath, err := filepath.Abs(filePath)
+		if err != nil {
+			return "", err
+		}
+		project = filepath.Base(filepath.Dir(absPath))
+	}
+	project = invalidProjectChars.ReplaceAllString(strings.ToLower(project), "")
+	return fmt.Sprintf("%s-%s-1", project, service), nil
+}
@@ -205,6 +205,7 @@ func (idc *internalDockerClient) CreateCustomNetwork(networkName string) error {
 
 // Compose structure to represent all the fields of a Docker Compose file
 type Compose struct {
+	Name     string    `yaml:"name,omitempty"`
 	Version  string    `yaml:"version,omitempty"`
 	Services yaml.Node `yaml:"services,omitempty"`
 	Networks yaml.Node `yaml:"networks,omitempty"`
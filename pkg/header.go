@@ -0,0 +1,158 @@
+package pkg
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// HeaderOrder returns the names of the header lines of the http message, in the order and the case they were sent
+// in. A name sent on several lines is repeated.
+func HeaderOrder(message []byte) []string {
+	end := bytes.Index(message, []byte("\r\n\r\n"))
+	if end < 0 {
+		return nil
+	}
+	lines := strings.Split(string(message[:end]), "\r\n")
+	var order []string
+	// the first line is the request or the status line
+	for _, line := range lines[1:] {
+		colon := strings.IndexByte(line, ':')
+		if colon <= 0 || line[0] == ' ' || line[0] == '\t' {
+			continue
+		}
+		order = append(order, line[:colon])
+	}
+	return order
+}
+
+// WriteOrderedHeader writes the header lines following the recorded order and case of their names, the headers left
+// out of it (like the ones added on replay) following them sorted, a line for each of their values. The values of a
+// name sent on several lines are split back on them when their number matches, else they are sent on its first line.
+func WriteOrderedHeader(w io.Writer, header http.Header, order []string) error {
+	keys := map[string]string{}
+	for key := range header {
+		keys[strings.ToLower(key)] = key
+	}
+	occurrences := map[string]int{}
+	for _, name := range order {
+		occurrences[strings.ToLower(name)]++
+	}
+
+	written := map[string]bool{}
+	seen := map[string]int{}
+	for _, name := range order {
+		lower := strings.ToLower(name)
+		key, ok := keys[lower]
+		if !ok {
+			continue
+		}
+		values := header[key]
+		line := seen[lower]
+		seen[lower]++
+		var value string
+		if occurrences[lower] == len(values) {
+			value = strings.TrimSpace(values[line])
+		} else if line == 0 {
+			value = strings.TrimSpace(strings.Join(values, ","))
+		} else {
+			continue
+		}
+		if _, err := fmt.Fprintf(w, "%s: %s\r\n", name, value); err != nil {
+			return err
+		}
+		written[key] = true
+	}
+
+	var rest []string
+	for key := range header {
+		if !written[key] {
+			rest = append(rest, key)
+		}
+	}
+	sort.Strings(rest)
+	for _, key := range rest {
+		for _, value := range header[key] {
+			if _, err := fmt.Fprintf(w, "%s: %s\r\n", key, value); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// sendOrderedRequest writes the request on a connection of its own, its header lines following the order and the
+// case of the names, and returns the response read from it. The body of the response is read before the connection
+// is closed.
+func sendOrderedRequest(req *http.Request, body []byte, order []string, timeout time.Duration) (*http.Response, error) {
+	address := req.URL.Host
+	if req.URL.Port() == "" {
+		port := "80"
+		if req.URL.Scheme == "https" {
+			port = "443"
+		}
+		address = net.JoinHostPort(req.URL.Hostname(), port)
+	}
+	dialer := &net.Dialer{Timeout: timeout}
+	var (
+		conn net.Conn
+		err  error
+	)
+	if req.URL.Scheme == "https" {
+		conn, err = tls.DialWithDialer(dialer, "tcp", address, &tls.Config{ServerName: req.URL.Hostname()})
+	} else {
+		conn, err = dialer.Dial("tcp", address)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	if timeout > 0 {
+		if err := conn.SetDeadline(time.Now().Add(timeout)); err != nil {
+			return nil, err
+		}
+	}
+
+	major, minor := req.ProtoMajor, req.ProtoMinor
+	if major != 1 {
+		major, minor = 1, 1
+	}
+	header := req.Header.Clone()
+	if _, ok := header["Host"]; !ok {
+		header["Host"] = []string{req.URL.Host}
+	}
+	// the body is sent whole, with its length
+	delete(header, "Transfer-Encoding")
+	if _, ok := header["Content-Length"]; ok || len(body) > 0 {
+		header["Content-Length"] = []string{fmt.Sprint(len(body))}
+	}
+	var message bytes.Buffer
+	fmt.Fprintf(&message, "%s %s HTTP/%d.%d\r\n", req.Method, req.URL.RequestURI(), major, minor)
+	if err := WriteOrderedHeader(&message, header, order); err != nil {
+		return nil, err
+	}
+	message.WriteString("\r\n")
+	message.Write(body)
+	if _, err := conn.Write(message.Bytes()); err != nil {
+		return nil, err
+	}
+
+	resp, err := http.ReadResponse(bufio.NewReader(conn), req)
+	if err != nil {
+		return nil, err
+	}
+	respBody, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(respBody))
+	return resp, nil
+}
@@ -1,6 +1,6 @@
 // Code generated by bpf2go; DO NOT EDIT.
-//go:build arm64
-// +build arm64
+//go:build linux && arm64
+// +build linux,arm64
 
 package hooks
 
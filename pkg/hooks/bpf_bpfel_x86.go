@@ -1,5 +1,6 @@
 // Code generated by bpf2go; DO NOT EDIT.
-//go:build 386 || amd64
+//go:build linux && (386 || amd64)
+// +build linux
 // +build 386 amd64
 
 package hooks
@@ -0,0 +1,32 @@
+package hooks
+
+import (
+	"math/rand"
+	"time"
+
+	"go.keploy.io/server/pkg/models"
+)
+
+// Fault returns the failure which the matched mock is replayed as, instead of its recorded response, nil when it is
+// replayed normally. The latency faults are not returned, the hooks delaying the matched mocks themselves.
+func (h *Hook) Fault(mock *models.Mock) *models.Fault {
+	fault := mock.Fault
+	if fault == nil || fault.Type == models.FaultLatency || !faultOccurs(fault) {
+		return nil
+	}
+	return fault
+}
+
+// injectLatency delays the response of the matched mock by the latency of its fault.
+func (h *Hook) injectLatency(mock *models.Mock) {
+	fault := mock.Fault
+	if fault == nil || fault.Type != models.FaultLatency || !faultOccurs(fault) {
+		return
+	}
+	time.Sleep(fault.Latency)
+}
+
+// faultOccurs tells whether the fault is injected in this replay of its mock, following its probability.
+func faultOccurs(fault *models.Fault) bool {
+	return fault.Probability <= 0 || fault.Probability >= 1 || rand.Float64() < fault.Probability
+}
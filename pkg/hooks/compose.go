@@ -0,0 +1,43 @@
+package hooks
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"go.keploy.io/server/pkg/clients/docker"
+	"go.keploy.io/server/pkg/models"
+)
+
+// composeFileFlag matches the -f <file> of a docker compose command.
+var composeFileFlag = regexp.MustCompile(`-f\s+("[^"]+"|'[^']+'|\S+)`)
+
+// ComposeCommand returns the command running the service of the docker compose file, along with the name of its
+// container, the one whose calls are captured. In record mode the whole stack is brought up, the other services
+// being the real dependencies of the application whose calls are recorded as mocks. In test mode the service is
+// brought up alone, without the services it depends on, its calls to them being answered with the mocks.
+func ComposeCommand(compose models.Compose, mode models.Mode) (string, string, error) {
+	if compose.Service == "" {
+		return "", "", fmt.Errorf("the service of the docker compose file %s running the application is missing", compose.File)
+	}
+	if _, err := os.Stat(compose.File); err != nil {
+		return "", "", err
+	}
+	container, err := docker.ComposeServiceContainer(compose.File, compose.Service)
+	if err != nil {
+		return "", "", err
+	}
+	if mode == models.MODE_TEST {
+		return fmt.Sprintf("docker compose -f %s up --no-deps %s", compose.File, compose.Service), container, nil
+	}
+	return fmt.Sprintf("docker compose -f %s up", compose.File), container, nil
+}
+
+// composeFile returns the docker compose file of the command, given with -f or else the one of the current directory.
+func composeFile(appCmd string) string {
+	if match := composeFileFlag.FindStringSubmatch(appCmd); match != nil {
+		return strings.Trim(match[1], `"'`)
+	}
+	return findDockerComposeFile()
+}
@@ -0,0 +1,83 @@
+package connection
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"reflect"
+	"strconv"
+	"time"
+
+	"go.uber.org/zap"
+
+	"go.keploy.io/server/pkg"
+	"go.keploy.io/server/pkg/models"
+	"go.keploy.io/server/pkg/platform/yaml"
+	"go.keploy.io/server/pkg/proxy/integrations/protohttp"
+)
+
+// denoiseHeader marks the requests replayed to find the noisy fields, so that they aren't recorded as testcases.
+const denoiseHeader = "Keploy-Denoise-Pass"
+
+// denoiseTimeout is how long a replayed request waits for its response.
+const denoiseTimeout = 30 * time.Second
+
+// denoise replays the request of the testcase the given number of times and returns the fields of the response
+// which changed in any of the passes, like the timestamps, uuids and counters, as noise.
+func denoise(tc *models.TestCase, passes int, logger *zap.Logger) map[string][]string {
+	noise := map[string][]string{}
+	recorded, err := yaml.FlattenHttpResponse(pkg.ToHttpHeader(tc.HttpResp.Header), tc.HttpResp.Body)
+	if err != nil {
+		logger.Error("failed to flatten the recorded http response", zap.Error(err))
+		return noise
+	}
+	client := &http.Client{
+		Timeout: denoiseTimeout,
+		// the redirects are a part of the response of the testcase
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	for pass := 1; pass <= passes; pass++ {
+		req, err := http.NewRequest(string(tc.HttpReq.Method), tc.HttpReq.URL, bytes.NewReader(protohttp.RequestBody(tc.HttpReq)))
+		if err != nil {
+			logger.Error("failed to create the request replayed to find the noisy fields", zap.Error(err))
+			return noise
+		}
+		req.Header = pkg.ToHttpHeader(tc.HttpReq.Header)
+		req.Header.Set(denoiseHeader, strconv.Itoa(pass))
+		resp, err := client.Do(req)
+		if err != nil {
+			logger.Warn("failed to replay the request to find the noisy fields", zap.Any("url", tc.HttpReq.URL), zap.Error(err))
+			return noise
+		}
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			logger.Warn("failed to read the response of the replayed request", zap.Any("url", tc.HttpReq.URL), zap.Error(err))
+			return noise
+		}
+		if resp.StatusCode != tc.HttpResp.StatusCode {
+			logger.Warn("the status code changed on replaying the request, the noisy fields may be inaccurate", zap.Any("url", tc.HttpReq.URL), zap.Any("recorded", tc.HttpResp.StatusCode), zap.Any("replayed", resp.StatusCode))
+		}
+		replayed, err := yaml.FlattenHttpResponse(resp.Header, protohttp.ResponseBody(body, resp.Header.Get("Content-Type"), tc.HttpReq))
+		if err != nil {
+			logger.Error("failed to flatten the replayed http response", zap.Error(err))
+			return noise
+		}
+		for key, values := range recorded {
+			if other, ok := replayed[key]; !ok || !reflect.DeepEqual(values, other) {
+				noise[key] = []string{}
+			}
+		}
+		for key := range replayed {
+			if _, ok := recorded[key]; !ok {
+				noise[key] = []string{}
+			}
+		}
+	}
+	if len(noise) > 0 {
+		logger.Debug("found the noisy fields of the testcase", zap.Any("url", tc.HttpReq.URL), zap.Any("noise", noise))
+	}
+	return noise
+}
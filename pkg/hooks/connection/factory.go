@@ -4,7 +4,9 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
+	"strconv"
 	"sync"
 	"time"
 
@@ -14,6 +16,7 @@ import (
 	"go.keploy.io/server/pkg/hooks/structs"
 	"go.keploy.io/server/pkg/models"
 	"go.keploy.io/server/pkg/platform"
+	"go.keploy.io/server/pkg/proxy/integrations/protohttp"
 )
 
 var Emoji = "\U0001F430" + " Keploy:"
@@ -24,15 +27,26 @@ type Factory struct {
 	inactivityThreshold time.Duration
 	mutex               *sync.RWMutex
 	logger              *zap.Logger
+	// denoisePasses is how many times the captured requests are replayed to find their noisy fields
+	denoisePasses int
+	// shadow replays the captured requests to a candidate build of the application, when set
+	shadow *Shadow
+	// sampler bounds the calls recorded following the sampling of the filters, created with the first call
+	sampler *sampler
+	// captureMutex serialises the captures which are run in the background while the requests are replayed
+	captureMutex *sync.Mutex
 }
 
 // NewFactory creates a new instance of the factory.
-func NewFactory(inactivityThreshold time.Duration, logger *zap.Logger) *Factory {
+func NewFactory(inactivityThreshold time.Duration, logger *zap.Logger, denoisePasses int, shadow *Shadow) *Factory {
 	return &Factory{
 		connections:         make(map[structs.ConnID]*Tracker),
 		mutex:               &sync.RWMutex{},
 		inactivityThreshold: inactivityThreshold,
 		logger:              logger,
+		denoisePasses:       denoisePasses,
+		shadow:              shadow,
+		captureMutex:        &sync.Mutex{},
 	}
 }
 
@@ -62,9 +76,35 @@ func (factory *Factory) HandleReadyConnections(db platform.TestCaseDB, ctx conte
 
 			switch models.GetMode() {
 			case models.MODE_RECORD:
-				// capture the ingress call for record cmd
+				// capture the ingress call for record cmd, with the filters of the config file reloaded last
+				filters := currentFilters(filters)
+				if isFiltered(filters, parsedHttpReq) {
+					factory.logger.Debug("skipping the ingress call left out by the record filters", zap.Any("method", parsedHttpReq.Method), zap.Any("url", parsedHttpReq.URL.String()))
+					break
+				}
+				if factory.sampler == nil {
+					factory.sampler = newSampler(filters)
+				}
+				if !factory.sampler.sample(parsedHttpReq) {
+					factory.logger.Debug("skipping the ingress call left out by the sampling", zap.Any("method", parsedHttpReq.Method), zap.Any("url", parsedHttpReq.URL.String()))
+					break
+				}
 				factory.logger.Debug("capturing ingress call from tracker in record mode")
-				capture(db, parsedHttpReq, parsedHttpRes, factory.logger, ctx, reqTimestampTest, resTimestampTest, filters)
+				var orders [2][]string
+				if headerFidelity, _ := ctx.Value("headerFidelity").(bool); headerFidelity {
+					// the parsed messages lose the order and the case of their headers
+					orders = [2][]string{pkg.HeaderOrder(requestBuf), pkg.HeaderOrder(responseBuf)}
+				}
+				if factory.denoisePasses == 0 {
+					capture(db, parsedHttpReq, parsedHttpRes, factory.logger, ctx, reqTimestampTest, resTimestampTest, filters, 0, factory.shadow, orders)
+					break
+				}
+				// the requests are replayed in the background so that the trackers of the replays are handled meanwhile
+				go func(req *http.Request, resp *http.Response, reqTime, resTime time.Time) {
+					factory.captureMutex.Lock()
+					defer factory.captureMutex.Unlock()
+					capture(db, req, resp, factory.logger, ctx, reqTime, resTime, filters, factory.denoisePasses, factory.shadow, orders)
+				}(parsedHttpReq, parsedHttpRes, reqTimestampTest, resTimestampTest)
 			case models.MODE_TEST:
 				factory.logger.Debug("skipping tracker in test mode")
 			default:
@@ -96,7 +136,15 @@ func (factory *Factory) GetOrCreate(connectionID structs.ConnID) *Tracker {
 	return tracker
 }
 
-func capture(db platform.TestCaseDB, req *http.Request, resp *http.Response, logger *zap.Logger, ctx context.Context, reqTimeTest time.Time, resTimeTest time.Time, filters *models.Filters) {
+func capture(db platform.TestCaseDB, req *http.Request, resp *http.Response, logger *zap.Logger, ctx context.Context, reqTimeTest time.Time, resTimeTest time.Time, filters *models.Filters, denoisePasses int, shadow *Shadow, headerOrders [2][]string) {
+	if req.Header.Get(denoiseHeader) != "" {
+		logger.Debug("skipping the request replayed to find the noisy fields", zap.Any("url", req.URL.String()))
+		return
+	}
+	if req.Header.Get(shadowHeader) != "" {
+		logger.Debug("skipping the request replayed to the candidate build", zap.Any("url", req.URL.String()))
+		return
+	}
 	reqBody, err := io.ReadAll(req.Body)
 	if err != nil {
 		logger.Error("failed to read the http request body", zap.Error(err))
@@ -109,7 +157,7 @@ func capture(db platform.TestCaseDB, req *http.Request, resp *http.Response, log
 		logger.Error("failed to read the http response body", zap.Error(err))
 		return
 	}
-	err = db.WriteTestcase(&models.TestCase{
+	tc := &models.TestCase{
 		Version: models.GetVersion(),
 		Name:    pkg.ToYamlHttpHeader(req.Header)["Keploy-Test-Name"],
 		Kind:    models.HTTP,
@@ -135,9 +183,44 @@ func capture(db platform.TestCaseDB, req *http.Request, resp *http.Response, log
 		},
 		Noise: map[string][]string{},
 		// Mocks: mocks,
-	}, ctx, filters)
+	}
+	tc.HttpReq.HeaderOrder, tc.HttpResp.HeaderOrder = headerOrders[0], headerOrders[1]
+	// the protobuf bodies are stored in their JSON form, along with their bytes
+	if decoded, original, ok := protohttp.DecodeBody(reqBody, req.Header.Get("Content-Type"), req.Method, req.URL.Path, false); ok {
+		tc.HttpReq.Body, tc.HttpReq.Binary = decoded, original
+	}
+	if decoded, original, ok := protohttp.DecodeBody(respBody, resp.Header.Get("Content-Type"), req.Method, req.URL.Path, true); ok {
+		tc.HttpResp.Body, tc.HttpResp.Binary = decoded, original
+	}
+	if tagPorts, _ := ctx.Value("tagPorts").(bool); tagPorts {
+		// the application is recorded on several ports, whose testcases are told apart by their tag
+		tc.Tags = append(tc.Tags, portTag(req))
+	}
+	if denoisePasses > 0 {
+		tc.Noise = denoise(tc, denoisePasses, logger)
+	}
+	for _, field := range currentNoise() {
+		tc.Noise[field] = []string{}
+	}
+	if shadow != nil {
+		// the request is read before the testcase is written, its values being replaced with variables then
+		shadow.Compare(tc)
+	}
+	err = db.WriteTestcase(tc, ctx, filters)
 	if err != nil {
 		logger.Error("failed to record the ingress requests", zap.Error(err))
 		return
 	}
 }
+
+// portTag returns the tag of the testcases received on the port of the request, e.g. "port-8080".
+func portTag(req *http.Request) string {
+	_, port, err := net.SplitHostPort(req.Host)
+	if err != nil || port == "" {
+		port = "80"
+	}
+	if _, err := strconv.Atoi(port); err != nil {
+		port = "80"
+	}
+	return "port-" + port
+}
@@ -0,0 +1,145 @@
+package connection
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	"go.keploy.io/server/pkg/models"
+)
+
+// patterns caches the compiled regular expressions of the filters.
+var patterns sync.Map
+
+func matchPattern(pattern, value string) bool {
+	if cached, ok := patterns.Load(pattern); ok {
+		return cached.(*regexp.Regexp).MatchString(value)
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		// an invalid pattern matches nothing
+		return false
+	}
+	patterns.Store(pattern, re)
+	return re.MatchString(value)
+}
+
+// requestPort returns the port of the application the request is sent to, from its host.
+func requestPort(req *http.Request) uint32 {
+	_, port, err := net.SplitHostPort(req.Host)
+	if err != nil {
+		return 80
+	}
+	p, err := strconv.ParseUint(port, 10, 32)
+	if err != nil {
+		return 0
+	}
+	return uint32(p)
+}
+
+func matchRule(rule models.FilterRule, req *http.Request) bool {
+	if rule.Path != "" && !matchPattern(rule.Path, req.URL.Path) {
+		return false
+	}
+	if len(rule.Methods) != 0 {
+		matched := false
+		for _, method := range rule.Methods {
+			if strings.EqualFold(method, req.Method) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	if len(rule.Ports) != 0 {
+		port, matched := requestPort(req), false
+		for _, p := range rule.Ports {
+			if p == port {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	for name, pattern := range rule.Headers {
+		values, ok := req.Header[http.CanonicalHeaderKey(name)]
+		if !ok || !matchPattern(pattern, strings.Join(values, ", ")) {
+			return false
+		}
+	}
+	return true
+}
+
+// isFiltered tells whether the inbound call is left out of the recording by the include and exclude rules of the filters.
+func isFiltered(filters *models.Filters, req *http.Request) bool {
+	if filters == nil {
+		return false
+	}
+	if len(filters.Include) != 0 {
+		included := false
+		for _, rule := range filters.Include {
+			if matchRule(rule, req) {
+				included = true
+				break
+			}
+		}
+		if !included {
+			return true
+		}
+	}
+	for _, rule := range filters.Exclude {
+		if matchRule(rule, req) {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidateFilters checks the regular expressions of the include, exclude and sampling rules of the filters, and the
+// bounds of the sampling.
+func ValidateFilters(filters models.Filters) error {
+	sampling := filters.Sampling
+	if err := validateSamplingBounds(sampling.Rate, sampling.PerMinute, sampling.MaxPerRoute); err != nil {
+		return err
+	}
+	for _, rule := range sampling.Rules {
+		if _, err := regexp.Compile(rule.Path); err != nil {
+			return fmt.Errorf("invalid path pattern %q of the sampling rule: %v", rule.Path, err)
+		}
+		if err := validateSamplingBounds(rule.Rate, rule.PerMinute, rule.MaxPerRoute); err != nil {
+			return fmt.Errorf("invalid sampling rule of the path %q: %v", rule.Path, err)
+		}
+	}
+	for _, rule := range append(append([]models.FilterRule{}, filters.Include...), filters.Exclude...) {
+		if rule.Path != "" {
+			if _, err := regexp.Compile(rule.Path); err != nil {
+				return fmt.Errorf("invalid path pattern %q: %v", rule.Path, err)
+			}
+		}
+		for name, pattern := range rule.Headers {
+			if _, err := regexp.Compile(pattern); err != nil {
+				return fmt.Errorf("invalid pattern %q of the header %s: %v", pattern, name, err)
+			}
+		}
+	}
+	return nil
+}
+
+func validateSamplingBounds(rate float64, perMinute, maxPerRoute int) error {
+	if rate < 0 || rate > 1 {
+		return fmt.Errorf("the sampling rate %v isn't between 0 and 1", rate)
+	}
+	if perMinute < 0 || maxPerRoute < 0 {
+		return errors.New("the numbers of calls recorded per minute and per route can't be negative")
+	}
+	return nil
+}
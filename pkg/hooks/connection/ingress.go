@@ -0,0 +1,142 @@
+package connection
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"strconv"
+	"sync"
+	"time"
+
+	"go.keploy.io/server/pkg/models"
+	"go.keploy.io/server/pkg/platform"
+	"go.uber.org/zap"
+)
+
+// DefaultIngressPort is the port the ingress listens on when none is given.
+const DefaultIngressPort = 16790
+
+type ingressCall struct{}
+
+// ingressRequest is the request received by the ingress, kept to be recorded along with its response.
+type ingressRequest struct {
+	body []byte
+	at   time.Time
+}
+
+// Ingress records the calls to the application when they are captured in userspace, without the eBPF hooks reading
+// them from its sockets: the calls are sent to the ingress, which forwards them to the application and records them
+// along with its responses.
+type Ingress struct {
+	server  *http.Server
+	appPort uint32
+	logger  *zap.Logger
+}
+
+// NewIngress returns the ingress listening on the port, which forwards the calls to the application listening on
+// the appPort of the local host and records them in the db.
+func NewIngress(port, appPort uint32, db platform.TestCaseDB, ctx context.Context, filters *models.Filters, denoisePasses int, shadow *Shadow, logger *zap.Logger) *Ingress {
+	if port == 0 {
+		port = DefaultIngressPort
+	}
+	appAddress := net.JoinHostPort("127.0.0.1", strconv.Itoa(int(appPort)))
+	captureMutex := &sync.Mutex{}
+	sampler := newSampler(filters)
+	proxy := &httputil.ReverseProxy{
+		Director: func(req *http.Request) {
+			req.URL.Scheme = "http"
+			req.URL.Host = appAddress
+			// the testcases are replayed to the application itself
+			host, _, err := net.SplitHostPort(req.Host)
+			if err != nil {
+				host = req.Host
+			}
+			if host == "" {
+				host = "localhost"
+			}
+			req.Host = net.JoinHostPort(host, strconv.Itoa(int(appPort)))
+		},
+		ModifyResponse: func(resp *http.Response) error {
+			call, ok := resp.Request.Context().Value(ingressCall{}).(*ingressRequest)
+			if !ok || models.GetMode() != models.MODE_RECORD {
+				return nil
+			}
+			respBody, err := io.ReadAll(resp.Body)
+			resp.Body.Close()
+			if err != nil {
+				return fmt.Errorf("failed to read the response of the application: %v", err)
+			}
+			resp.Body = io.NopCloser(bytes.NewReader(respBody))
+
+			req := resp.Request.Clone(context.Background())
+			req.Body = io.NopCloser(bytes.NewReader(call.body))
+			recorded := *resp
+			recorded.Header = resp.Header.Clone()
+			recorded.Body = io.NopCloser(bytes.NewReader(respBody))
+			// the filters of the config file reloaded last
+			filters := currentFilters(filters)
+			if isFiltered(filters, req) {
+				logger.Debug("skipping the ingress call left out by the record filters", zap.Any("method", req.Method), zap.Any("url", req.URL.String()))
+				return nil
+			}
+			if !sampler.sample(req) {
+				logger.Debug("skipping the ingress call left out by the sampling", zap.Any("method", req.Method), zap.Any("url", req.URL.String()))
+				return nil
+			}
+			captureMutex.Lock()
+			defer captureMutex.Unlock()
+			// the calls received by the ingress are parsed by net/http, which doesn't keep the order of their headers
+			capture(db, req, &recorded, logger, ctx, call.at, time.Now(), filters, denoisePasses, shadow, [2][]string{})
+			return nil
+		},
+		ErrorHandler: func(w http.ResponseWriter, req *http.Request, err error) {
+			logger.Error("failed to forward the call to the application", zap.String("address", appAddress), zap.Error(err))
+			w.WriteHeader(http.StatusBadGateway)
+		},
+	}
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		body, err := io.ReadAll(req.Body)
+		if err != nil {
+			logger.Error("failed to read the request sent to the application", zap.Error(err))
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		req.Body = io.NopCloser(bytes.NewReader(body))
+		call := &ingressRequest{body: body, at: time.Now()}
+		proxy.ServeHTTP(w, req.WithContext(context.WithValue(req.Context(), ingressCall{}, call)))
+	})
+
+	return &Ingress{
+		server:  &http.Server{Addr: fmt.Sprintf(":%d", port), Handler: handler},
+		appPort: appPort,
+		logger:  logger,
+	}
+}
+
+// Start listens for the calls to the application in the background.
+func (i *Ingress) Start() error {
+	listener, err := net.Listen("tcp", i.server.Addr)
+	if err != nil {
+		return err
+	}
+	i.logger.Info(fmt.Sprintf("send the calls to port %v of the application to port %v, keploy records them and forwards them to it", i.appPort, i.server.Addr))
+	go func() {
+		if err := i.server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			i.logger.Error("the ingress stopped", zap.Error(err))
+		}
+	}()
+	return nil
+}
+
+// Stop stops listening for the calls to the application.
+func (i *Ingress) Stop() {
+	if err := i.server.Close(); err != nil {
+		i.logger.Error("failed to stop the ingress", zap.Error(err))
+	}
+}
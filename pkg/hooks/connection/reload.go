@@ -0,0 +1,47 @@
+package connection
+
+import (
+	"sync"
+
+	"go.keploy.io/server/pkg/models"
+)
+
+// reloadMutex guards the filters and the noise of the recording, which the reload of the config file replaces while
+// the calls are captured.
+var reloadMutex sync.RWMutex
+
+// recordNoise are the fields written as noise on every recorded testcase.
+var recordNoise []string
+
+// ReloadFilters replaces the filters of the recording with the reloaded ones, applied to the calls captured next. The
+// sampling keeps the counts of the calls already recorded.
+func ReloadFilters(filters *models.Filters, reloaded models.Filters) {
+	reloadMutex.Lock()
+	defer reloadMutex.Unlock()
+	*filters = reloaded
+}
+
+// SetNoise sets the fields, like header.Date or body.updatedAt, written as noise on the testcases recorded next.
+func SetNoise(noise []string) {
+	reloadMutex.Lock()
+	defer reloadMutex.Unlock()
+	recordNoise = append([]string{}, noise...)
+}
+
+// currentFilters returns a copy of the filters, which the reload doesn't change. The reload replaces the filters as a
+// whole, so the copy shares their rules.
+func currentFilters(filters *models.Filters) *models.Filters {
+	if filters == nil {
+		return nil
+	}
+	reloadMutex.RLock()
+	defer reloadMutex.RUnlock()
+	current := *filters
+	return &current
+}
+
+func currentNoise() []string {
+	reloadMutex.RLock()
+	defer reloadMutex.RUnlock()
+	return recordNoise
+}
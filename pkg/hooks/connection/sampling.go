@@ -0,0 +1,96 @@
+package connection
+
+import (
+	"math/rand"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"go.keploy.io/server/pkg/models"
+)
+
+// idSegment matches the segments of the paths which identify a resource, like 42, a uuid or a long hex string.
+var idSegment = regexp.MustCompile(`^([0-9]+|[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}|[0-9a-fA-F]{16,})$`)
+
+// sampler decides which of the calls selected by the filters are recorded, following their sampling.
+type sampler struct {
+	// filters are the filters of the recording, whose sampling is read on each call to follow their reload
+	filters *models.Filters
+	mutex   sync.Mutex
+	routes  map[string]*routeSamples
+}
+
+// routeSamples counts the calls of a route recorded in the session and in its current minute.
+type routeSamples struct {
+	total    int
+	window   time.Time
+	inWindow int
+}
+
+func newSampler(filters *models.Filters) *sampler {
+	return &sampler{filters: filters, routes: map[string]*routeSamples{}}
+}
+
+// route returns the route of the call, like GET /orders/:id.
+func route(req *http.Request) string {
+	segments := strings.Split(req.URL.Path, "/")
+	for i, segment := range segments {
+		if idSegment.MatchString(segment) {
+			segments[i] = ":id"
+		}
+	}
+	return req.Method + " " + strings.Join(segments, "/")
+}
+
+// bounds returns the bounds of the call, the ones of the first rule it matches or the ones of the sampling.
+func (s *sampler) bounds(req *http.Request) (float64, int, int) {
+	var sampling models.Sampling
+	if filters := currentFilters(s.filters); filters != nil {
+		sampling = filters.Sampling
+	}
+	for _, rule := range sampling.Rules {
+		if matchRule(models.FilterRule{Path: rule.Path, Methods: rule.Methods}, req) {
+			return rule.Rate, rule.PerMinute, rule.MaxPerRoute
+		}
+	}
+	return sampling.Rate, sampling.PerMinute, sampling.MaxPerRoute
+}
+
+// sample tells whether the call is recorded, counting it in its route when it is.
+func (s *sampler) sample(req *http.Request) bool {
+	if req.Header.Get(denoiseHeader) != "" || req.Header.Get(shadowHeader) != "" {
+		// the replays aren't recorded, nor counted
+		return true
+	}
+	rate, perMinute, maxPerRoute := s.bounds(req)
+	if rate > 0 && rate < 1 && rand.Float64() >= rate {
+		return false
+	}
+	if perMinute <= 0 && maxPerRoute <= 0 {
+		return true
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	key := route(req)
+	samples, ok := s.routes[key]
+	if !ok {
+		samples = &routeSamples{}
+		s.routes[key] = samples
+	}
+	if maxPerRoute > 0 && samples.total >= maxPerRoute {
+		return false
+	}
+	now := time.Now()
+	if now.Sub(samples.window) >= time.Minute {
+		samples.window, samples.inWindow = now, 0
+	}
+	if perMinute > 0 && samples.inWindow >= perMinute {
+		return false
+	}
+	samples.total++
+	samples.inWindow++
+	return true
+}
@@ -0,0 +1,224 @@
+package connection
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	yamlLib "gopkg.in/yaml.v3"
+
+	"go.keploy.io/server/pkg"
+	"go.keploy.io/server/pkg/models"
+	"go.keploy.io/server/pkg/platform/yaml"
+	"go.keploy.io/server/pkg/proxy/integrations/protohttp"
+)
+
+// shadowHeader marks the calls replayed to the candidate build, so that they aren't recorded as testcases when its
+// calls are captured too.
+const shadowHeader = "Keploy-Shadow"
+
+// shadowTimeout is how long a call replayed to the candidate waits for its response.
+const shadowTimeout = 30 * time.Second
+
+// defaultShadowNoise are the fields which differ from a response to the next whatever the build.
+var defaultShadowNoise = []string{"header.Date"}
+
+// Shadow replays the recorded calls to a candidate build of the application, and reports the differences of its
+// responses with the recorded ones in the shadow report of the test set. The calls are replayed in the background, so
+// that the recorded application isn't slowed down.
+type Shadow struct {
+	candidate  *url.URL
+	noise      []string
+	client     *http.Client
+	reportPath string
+	logger     *zap.Logger
+	// mutex guards the report, which is rewritten after each call
+	mutex   sync.Mutex
+	report  models.ShadowReport
+	pending sync.WaitGroup
+}
+
+// NewShadow returns the shadow writing the report of the test set in the reportsDir, like keploy/shadowReports.
+func NewShadow(shadow models.Shadow, reportsDir, testSet string, logger *zap.Logger) (*Shadow, error) {
+	candidate, err := url.Parse(shadow.Candidate)
+	if err != nil || candidate.Scheme == "" || candidate.Host == "" {
+		return nil, fmt.Errorf("invalid url of the candidate %q, like http://localhost:8081", shadow.Candidate)
+	}
+	if err := os.MkdirAll(reportsDir, os.ModePerm); err != nil {
+		return nil, fmt.Errorf("failed to create the directory of the shadow reports: %v", err)
+	}
+	return &Shadow{
+		candidate: candidate,
+		noise:     append(append([]string{}, defaultShadowNoise...), shadow.Noise...),
+		client: &http.Client{
+			Timeout: shadowTimeout,
+			// the redirects are a part of the responses compared
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		reportPath: filepath.Join(reportsDir, testSet+".yaml"),
+		logger:     logger,
+		report: models.ShadowReport{
+			Version:   models.GetVersion(),
+			TestSet:   testSet,
+			Candidate: shadow.Candidate,
+		},
+	}, nil
+}
+
+// Compare replays the call of the testcase to the candidate in the background. The testcase isn't read once it
+// returns.
+func (s *Shadow) Compare(tc *models.TestCase) {
+	call := models.ShadowCall{
+		Method:    tc.HttpReq.Method,
+		URL:       tc.HttpReq.URL,
+		Timestamp: tc.HttpReq.Timestamp,
+	}
+	target, err := url.Parse(tc.HttpReq.URL)
+	if err != nil {
+		s.logger.Error("failed to parse the url of the call replayed to the candidate", zap.String("url", tc.HttpReq.URL), zap.Error(err))
+		return
+	}
+	target.Scheme, target.Host = s.candidate.Scheme, s.candidate.Host
+	target.Path = strings.TrimSuffix(s.candidate.Path, "/") + target.Path
+	req, err := http.NewRequest(string(tc.HttpReq.Method), target.String(), bytes.NewReader(protohttp.RequestBody(tc.HttpReq)))
+	if err != nil {
+		s.logger.Error("failed to create the call replayed to the candidate", zap.Error(err))
+		return
+	}
+	req.Header = pkg.ToHttpHeader(tc.HttpReq.Header)
+	req.Header.Set(shadowHeader, "true")
+
+	recorded, err := yaml.FlattenHttpResponse(pkg.ToHttpHeader(tc.HttpResp.Header), tc.HttpResp.Body)
+	if err != nil {
+		s.logger.Error("failed to flatten the recorded http response", zap.Error(err))
+		return
+	}
+	noise := append([]string{}, s.noise...)
+	for field := range tc.Noise {
+		noise = append(noise, field)
+	}
+	call.StatusCode.Expected = tc.HttpResp.StatusCode
+
+	s.pending.Add(1)
+	go func() {
+		defer s.pending.Done()
+		s.replay(req, call, recorded, noise)
+	}()
+}
+
+func (s *Shadow) replay(req *http.Request, call models.ShadowCall, recorded map[string][]string, noise []string) {
+	resp, err := s.client.Do(req)
+	if err != nil {
+		call.Error = err.Error()
+		s.logger.Warn("the candidate failed to answer the call", zap.String("url", call.URL), zap.Error(err))
+		s.add(call, false)
+		return
+	}
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		call.Error = fmt.Sprintf("failed to read the response: %v", err)
+		s.logger.Warn("failed to read the response of the candidate", zap.String("url", call.URL), zap.Error(err))
+		s.add(call, false)
+		return
+	}
+	call.StatusCode.Actual = resp.StatusCode
+	call.StatusCode.Normal = call.StatusCode.Expected == call.StatusCode.Actual
+
+	candidate, err := yaml.FlattenHttpResponse(resp.Header, protohttp.ResponseBody(body, resp.Header.Get("Content-Type"), models.HttpReq{Method: call.Method, URL: call.URL}))
+	if err != nil {
+		s.logger.Error("failed to flatten the http response of the candidate", zap.Error(err))
+	}
+	call.Fields = shadowFields(recorded, candidate, noise)
+	if call.StatusCode.Normal && len(call.Fields) == 0 {
+		s.add(call, true)
+		return
+	}
+	s.logger.Warn("the response of the candidate differs from the recorded one", zap.String("method", string(call.Method)), zap.String("url", call.URL), zap.Int("recorded status", call.StatusCode.Expected), zap.Int("candidate status", call.StatusCode.Actual), zap.Int("fields", len(call.Fields)))
+	s.add(call, true)
+}
+
+// add counts the call in the report, which is written again when the call is listed.
+func (s *Shadow) add(call models.ShadowCall, answered bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.report.Total++
+	switch {
+	case !answered:
+		s.report.Failed++
+	case call.StatusCode.Normal && len(call.Fields) == 0:
+		s.report.Matched++
+		return
+	default:
+		s.report.Mismatched++
+	}
+	s.report.Calls = append(s.report.Calls, call)
+	if err := s.write(); err != nil {
+		s.logger.Error("failed to write the shadow report", zap.String("path", s.reportPath), zap.Error(err))
+	}
+}
+
+func (s *Shadow) write() error {
+	data, err := yamlLib.Marshal(s.report)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(s.reportPath, data, 0644)
+}
+
+// Close waits for the calls being replayed and writes the final report.
+func (s *Shadow) Close() {
+	s.pending.Wait()
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if err := s.write(); err != nil {
+		s.logger.Error("failed to write the shadow report", zap.String("path", s.reportPath), zap.Error(err))
+		return
+	}
+	s.logger.Info("shadow report of "+s.report.TestSet, zap.String("candidate", s.report.Candidate), zap.Int("total", s.report.Total), zap.Int("matched", s.report.Matched), zap.Int("mismatched", s.report.Mismatched), zap.Int("failed", s.report.Failed), zap.String("path", s.reportPath))
+}
+
+// shadowFields returns the fields of the responses whose values differ, the noisy ones excluded.
+func shadowFields(recorded, candidate map[string][]string, noise []string) []models.ShadowField {
+	var fields []models.ShadowField
+	for path, values := range recorded {
+		other, ok := candidate[path]
+		if isShadowNoise(path, noise) || (ok && strings.Join(values, ",") == strings.Join(other, ",")) {
+			continue
+		}
+		fields = append(fields, models.ShadowField{Path: path, Recorded: strings.Join(values, ","), Candidate: strings.Join(other, ",")})
+	}
+	for path, values := range candidate {
+		if _, ok := recorded[path]; ok || isShadowNoise(path, noise) {
+			continue
+		}
+		fields = append(fields, models.ShadowField{Path: path, Candidate: strings.Join(values, ",")})
+	}
+	sort.Slice(fields, func(i, j int) bool { return fields[i].Path < fields[j].Path })
+	return fields
+}
+
+// isShadowNoise reports whether the field, or one of its parents, is noisy, the headers being matched whatever their
+// case.
+func isShadowNoise(path string, noise []string) bool {
+	for _, field := range noise {
+		if path == field || strings.HasPrefix(path, field+".") {
+			return true
+		}
+		if strings.HasPrefix(field, "header.") && strings.EqualFold(path, field) {
+			return true
+		}
+	}
+	return false
+}
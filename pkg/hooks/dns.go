@@ -0,0 +1,31 @@
+package hooks
+
+import "go.keploy.io/server/pkg/models"
+
+// SetDNSMocks sets the DNS mocks answering the queries of the application, which are kept apart from the config
+// mocks matched by the parsers.
+func (h *Hook) SetDNSMocks(m []*models.Mock) {
+	h.dnsMocksMutex.Lock()
+	defer h.dnsMocksMutex.Unlock()
+	h.dnsMocks = m
+}
+
+// GetDNSMocks returns the DNS mocks set for the test set, which are never consumed.
+func (h *Hook) GetDNSMocks() []*models.Mock {
+	h.dnsMocksMutex.RLock()
+	defer h.dnsMocksMutex.RUnlock()
+	return h.dnsMocks
+}
+
+// SplitDNSMocks returns the DNS mocks of the config mocks, and the other ones.
+func SplitDNSMocks(mocks []*models.Mock) ([]*models.Mock, []*models.Mock) {
+	var dnsMocks, others []*models.Mock
+	for _, mock := range mocks {
+		if mock.Kind == models.DNS {
+			dnsMocks = append(dnsMocks, mock)
+			continue
+		}
+		others = append(others, mock)
+	}
+	return dnsMocks, others
+}
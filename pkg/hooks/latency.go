@@ -0,0 +1,55 @@
+package hooks
+
+import (
+	"time"
+
+	"go.keploy.io/server/pkg/models"
+)
+
+// latencyMetadata is the key of the metadata of the mocks holding the latency of their calls, like 12.5ms.
+const latencyMetadata = "latency"
+
+// SetLatencyScale sets the scale of the recorded latency of the mocks which delays their responses, 0 answering at
+// once.
+func (h *Hook) SetLatencyScale(scale float64) {
+	h.latencyScale = scale
+}
+
+// recordLatency adds the time the dependency took to answer the call of the mock to its metadata.
+func recordLatency(mock *models.Mock) {
+	latency := mockLatency(mock)
+	if latency <= 0 {
+		return
+	}
+	if mock.Spec.Metadata == nil {
+		mock.Spec.Metadata = map[string]string{}
+	}
+	if _, ok := mock.Spec.Metadata[latencyMetadata]; !ok {
+		mock.Spec.Metadata[latencyMetadata] = latency.Round(time.Microsecond).String()
+	}
+}
+
+// mockLatency returns the latency of the call of the mock, from its metadata or else from its timestamps, 0 when
+// neither is known.
+func mockLatency(mock *models.Mock) time.Duration {
+	if value, ok := mock.Spec.Metadata[latencyMetadata]; ok {
+		if latency, err := time.ParseDuration(value); err == nil {
+			return latency
+		}
+	}
+	req, res := mock.Spec.ReqTimestampMock, mock.Spec.ResTimestampMock
+	if req.IsZero() || !res.After(req) {
+		return 0
+	}
+	return res.Sub(req)
+}
+
+// simulateLatency delays the response of the matched mock by its latency, multiplied by the latency scale.
+func (h *Hook) simulateLatency(mock *models.Mock) {
+	if h.latencyScale <= 0 {
+		return
+	}
+	if delay := time.Duration(float64(mockLatency(mock)) * h.latencyScale); delay > 0 {
+		time.Sleep(delay)
+	}
+}
@@ -5,7 +5,6 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"log"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -15,7 +14,6 @@ import (
 	"syscall"
 	"time"
 
-	"github.com/cilium/ebpf"
 	"github.com/docker/docker/api/types"
 	"github.com/docker/docker/api/types/events"
 	"github.com/docker/docker/api/types/filters"
@@ -73,8 +71,8 @@ func (h *Hook) LaunchUserApplication(appCmd, appContainer, appNetwork string, De
 					return fmt.Errorf(Emoji + "container name not found")
 				}
 
-				//finding the user docker-compose file in the current directory.
-				dockerComposeFile := findDockerComposeFile()
+				//finding the user docker-compose file, given with -f or in the current directory.
+				dockerComposeFile := composeFile(appCmd)
 
 				if dockerComposeFile == "" {
 					return fmt.Errorf("Can't find the docker compose file of user. Are you in the right directory?")
@@ -82,6 +80,8 @@ func (h *Hook) LaunchUserApplication(appCmd, appContainer, appNetwork string, De
 
 				// kdocker-compose.yaml file will be run instead of the user docker-compose.yaml file acc to below cases
 				newComposeFile := "kdocker-compose.yaml"
+				// it's written next to the user docker-compose file, keeping its project and relative paths
+				newComposePath := filepath.Join(filepath.Dir(dockerComposeFile), newComposeFile)
 
 				// Check if docker compose file uses relative file names for bind mounts
 				hasRelativeBindMounts := h.idc.CheckBindMounts(dockerComposeFile)
@@ -94,8 +94,8 @@ func (h *Hook) LaunchUserApplication(appCmd, appContainer, appNetwork string, De
 					}
 					h.logger.Info("Created kdocker-compose.yml file and Replaced relative file paths in docker compose file.")
 					//Now replace the running command to run the kdocker-compose.yaml file instead of user docker compose file.
-					appCmd = modifyDockerComposeCommand(appCmd, newComposeFile)
-					dockerComposeFile = newComposeFile
+					appCmd = modifyDockerComposeCommand(appCmd, newComposePath)
+					dockerComposeFile = newComposePath
 				}
 
 				// Checking info about the network and whether its external:true
@@ -153,7 +153,7 @@ func (h *Hook) LaunchUserApplication(appCmd, appContainer, appNetwork string, De
 
 						oldCmd := appCmd
 						//Now replace the running command to run the kdocker-compose.yaml file instead of user docker compose file.
-						appCmd = modifyDockerComposeCommand(appCmd, newComposeFile)
+						appCmd = modifyDockerComposeCommand(appCmd, newComposePath)
 						h.logger.Debug(fmt.Sprintf("docker compose run command changed from %v to %v", oldCmd, appCmd))
 					}
 				} else {
@@ -194,9 +194,8 @@ func (h *Hook) LaunchUserApplication(appCmd, appContainer, appNetwork string, De
 
 					// time.Sleep(5 * time.Second)
 					oldCmd := appCmd
-					path := "./" + newComposeFile
 					//Now replace the running command to run the kdocker-compose.yaml file instead of user docker compose file.
-					appCmd = modifyDockerComposeCommand(appCmd, path)
+					appCmd = modifyDockerComposeCommand(appCmd, newComposePath)
 					h.logger.Debug(fmt.Sprintf("docker compose run command changed from %v to %v", oldCmd, appCmd))
 				}
 
@@ -243,9 +242,9 @@ func (h *Hook) LaunchUserApplication(appCmd, appContainer, appNetwork string, De
 			h.logger.Debug("Running user application on Linux", zap.Any("pid of keploy", os.Getpid()))
 
 			// to notify the kernel hooks that the user application command is running in native linux.
-			key := 0
-			value := false
-			h.objects.DockerCmdMap.Update(uint32(key), &value, ebpf.UpdateAny)
+			if !h.userspace {
+				h.setDockerCmdInKernel(false)
+			}
 
 			// Recover from panic and gracefully shutdown
 			defer h.Recover(pkg.GenerateRandomID())
@@ -260,9 +259,7 @@ func (h *Hook) LaunchUserApplication(appCmd, appContainer, appNetwork string, De
 
 func (h *Hook) processDockerEnv(appCmd, appContainer, appNetwork string, buildDelay time.Duration) error {
 	// to notify the kernel hooks that the user application is related to Docker.
-	key := 0
-	value := true
-	h.objects.DockerCmdMap.Update(uint32(key), &value, ebpf.UpdateAny)
+	h.setDockerCmdInKernel(true)
 
 	stopListenContainer := make(chan bool)
 	stopApplicationErrors := false
@@ -471,15 +468,14 @@ func (h *Hook) processDockerEnv(appCmd, appContainer, appNetwork string, buildDe
 // It runs the application using the given command
 func (h *Hook) runApp(appCmd string, isUnitTestIntegration bool) error {
 	// Create a new command with your appCmd
-	cmd := exec.Command("sh", "-c", appCmd)
-
-	cmd.SysProcAttr = &syscall.SysProcAttr{
-		Setpgid: true,
-	}
+	cmd := newAppCommand(appCmd)
 
 	// Set the output of the command
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
+	if h.userspace && !h.CapturesWithPreload() {
+		cmd.Env = append(os.Environ(), h.userspaceEnv()...)
+	}
 	h.userAppCmd = cmd
 
 	// Run the app as the user who invoked sudo
@@ -516,7 +512,7 @@ func (h *Hook) runApp(appCmd string, isUnitTestIntegration bool) error {
 		}
 
 		// Switch the user
-		cmd.SysProcAttr.Credential = &syscall.Credential{Uid: uint32(uid), Gid: uint32(gid)}
+		runAsUser(cmd, uint32(uid), uint32(gid))
 	}
 
 	h.logger.Debug("", zap.Any("executing cmd", cmd.String()))
@@ -567,7 +563,7 @@ func (h *Hook) injectNetworkToKeploy(appNetwork string) error {
 		return err
 	}
 
-	var newProxyIpString string
+	var newProxyIpString, newProxyIp6String string
 	keployNetworks := kInspect.NetworkSettings.Networks
 	//Here we considering that the application would use only one custom network.
 	//TODO: handle for application having multiple custom networks
@@ -575,6 +571,7 @@ func (h *Hook) injectNetworkToKeploy(appNetwork string) error {
 		if networkName != "bridge" {
 			appNetwork = networkName
 			newProxyIpString = networkSettings.IPAddress
+			newProxyIp6String = networkSettings.GlobalIPv6Address
 			h.logger.Debug(fmt.Sprintf("Network Name: %s, New Proxy IP: %s\n", networkName, networkSettings.IPAddress))
 		}
 	}
@@ -583,14 +580,24 @@ func (h *Hook) injectNetworkToKeploy(appNetwork string) error {
 		return fmt.Errorf("failed to convert ip string:[%v] to 32-bit integer", newProxyIpString)
 	}
 
+	// the IPv6 connections of the application are redirected to the IPv6 address of keploy on the network, or to its
+	// IPv4-mapped address when the network is IPv4 only, the loopback of the application container not being keploy's
+	proxyIp6 := IPv4MappedIPv6(proxyIp)
+	if newProxyIp6String != "" {
+		proxyIp6, err = ConvertIPv6ToUint32(newProxyIp6String)
+		if err != nil {
+			return fmt.Errorf("failed to convert ipv6 string:[%v] to 32-bit integers", newProxyIp6String)
+		}
+	}
+
 	proxyPort := h.GetProxyPort()
-	err = h.SendProxyInfo(proxyIp, proxyPort, [4]uint32{0000, 0000, 0000, 0001})
+	err = h.SendProxyInfo(proxyIp, proxyPort, proxyIp6)
 	if err != nil {
 		h.logger.Error("failed to send new proxy ip to kernel", zap.Any("NewProxyIp", proxyIp))
 		return err
 	}
 
-	h.logger.Debug(fmt.Sprintf("New proxy ip:%v & proxy port:%v sent to kernel", proxyIp, proxyPort))
+	h.logger.Debug(fmt.Sprintf("New proxy ip:%v, ipv6:%v & proxy port:%v sent to kernel", proxyIp, proxyIp6, proxyPort))
 	h.logger.Info("Successfully injected network to the keploy container", zap.Any("Keploy container", KeployContainerName), zap.Any("appNetwork", appNetwork))
 	return nil
 }
@@ -651,38 +658,6 @@ func parseDockerCommand(dockerCmd string) (string, string, error) {
 	return containerName, networkName, nil
 }
 
-func getInodeNumber(pid int) uint64 {
-
-	filepath := filepath.Join("/proc", strconv.Itoa(pid), "ns", "pid")
-
-	f, err := os.Stat(filepath)
-	if err != nil {
-		fmt.Errorf("%v failed to get the inode number or namespace Id:", Emoji, err)
-		return 0
-	}
-	// Dev := (f.Sys().(*syscall.Stat_t)).Dev
-	Ino := (f.Sys().(*syscall.Stat_t)).Ino
-	if Ino != 0 {
-		return Ino
-	}
-	return 0
-}
-
-func getSelfInodeNumber() uint64 {
-	filepath := filepath.Join("/proc", "self", "ns", "pid")
-
-	f, err := os.Stat(filepath)
-	if err != nil {
-		log.Fatal(Emoji, "failed to get the self inode number or namespace Id:", err)
-	}
-	// Dev := (f.Sys().(*syscall.Stat_t)).Dev
-	Ino := (f.Sys().(*syscall.Stat_t)).Ino
-	if Ino != 0 {
-		return Ino
-	}
-	return 0
-}
-
 func findDockerComposeFile() string {
 	filenames := []string{"docker-compose.yml", "docker-compose.yaml", "compose.yml", "compose.yaml"}
 
@@ -697,18 +672,14 @@ func findDockerComposeFile() string {
 
 func modifyDockerComposeCommand(appCmd, newComposeFile string) string {
 	// Ensure newComposeFile starts with ./
-	if !strings.HasPrefix(newComposeFile, "./") {
+	if !strings.HasPrefix(newComposeFile, "./") && !filepath.IsAbs(newComposeFile) {
 		newComposeFile = "./" + newComposeFile
 	}
 
-	// Define a regular expression pattern to match "-f <file>"
-	pattern := `(-f\s+("[^"]+"|'[^']+'|\S+))`
-	re := regexp.MustCompile(pattern)
-
 	// Check if the "-f <file>" pattern exists in the appCmd
-	if re.MatchString(appCmd) {
+	if composeFileFlag.MatchString(appCmd) {
 		// Replace it with the new Compose file
-		return re.ReplaceAllString(appCmd, fmt.Sprintf("-f %s", newComposeFile))
+		return composeFileFlag.ReplaceAllString(appCmd, fmt.Sprintf("-f %s", newComposeFile))
 	}
 
 	// If the pattern doesn't exist, inject the new Compose file right after "docker-compose" or "docker compose"
@@ -0,0 +1,648 @@
+//go:build linux
+
+package hooks
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"runtime"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/cilium/ebpf"
+	"github.com/cilium/ebpf/link"
+	"github.com/cilium/ebpf/rlimit"
+	"go.uber.org/zap"
+
+	"go.keploy.io/server/pkg"
+	"go.keploy.io/server/pkg/hooks/connection"
+	"go.keploy.io/server/pkg/hooks/settings"
+	"go.keploy.io/server/pkg/hooks/structs"
+	"go.keploy.io/server/pkg/models"
+	"go.keploy.io/server/utils"
+)
+
+// kernelHooks are the eBPF maps and links of the hooks attached by LoadHooks.
+type kernelHooks struct {
+	proxyInfoMap     *ebpf.Map
+	inodeMap         *ebpf.Map
+	redirectProxyMap *ebpf.Map
+	keployModeMap    *ebpf.Map
+	keployPid        *ebpf.Map
+	appPidMap        *ebpf.Map
+	keployServerPort *ebpf.Map
+	passthroughPorts *ebpf.Map
+
+	// ebpf objects and events
+	stopper  chan os.Signal
+	socket   link.Link
+	connect4 link.Link
+	bind     link.Link
+	gp4      link.Link
+	udpp4    link.Link
+	tcppv4   link.Link
+	tcpv4    link.Link
+	tcpv4Ret link.Link
+	connect6 link.Link
+	gp6      link.Link
+	tcppv6   link.Link
+	tcpv6    link.Link
+	tcpv6Ret link.Link
+
+	accept      link.Link
+	acceptRet   link.Link
+	accept4     link.Link
+	accept4Ret  link.Link
+	read        link.Link
+	readRet     link.Link
+	write       link.Link
+	writeRet    link.Link
+	close       link.Link
+	closeRet    link.Link
+	sendto      link.Link
+	sendtoRet   link.Link
+	recvfrom    link.Link
+	recvfromRet link.Link
+	objects     bpfObjects
+	writev      link.Link
+	writevRet   link.Link
+}
+
+// SendPassThroughPorts sends the destination ports of the server which should not be intercepted by keploy proxy.
+func (h *Hook) SendPassThroughPorts(filterPorts []uint) error {
+	portsSize := len(filterPorts)
+	if portsSize > 10 {
+		h.logger.Error("can not send more than 10 ports to be filtered to the ebpf program")
+		return fmt.Errorf("passthrough ports limit exceeded")
+	}
+
+	var ports [10]int32
+
+	for i := 0; i < 10; i++ {
+		if i < portsSize {
+			// Convert uint to int32
+			ports[i] = int32(filterPorts[i])
+		} else {
+			// Fill the remaining elements with -1
+			ports[i] = -1
+		}
+	}
+
+	for i, v := range ports {
+		h.logger.Debug(fmt.Sprintf("PassthroughPort(%v):[%v]", i, v))
+		err := h.passthroughPorts.Update(uint32(i), &v, ebpf.UpdateAny)
+		if err != nil {
+			h.logger.Error("failed to send the passthrough ports to the ebpf program", zap.Any("error thrown by ebpf map", err.Error()))
+			return err
+		}
+	}
+	return nil
+}
+
+// SendKeployServerPort sends the keploy graphql server port to be filtered in the eBPF program.
+func (h *Hook) SendKeployServerPort(port uint32) error {
+	h.logger.Debug("sending keploy server port", zap.Any("port", port))
+	key := 0
+	err := h.keployServerPort.Update(uint32(key), &port, ebpf.UpdateAny)
+	if err != nil {
+		h.logger.Error("failed to send keploy server port to the epbf program", zap.Any("Keploy server port", port), zap.Any("error thrown by ebpf map", err.Error()))
+		return err
+	}
+	return nil
+}
+
+// This function sends the IP and Port of the running proxy in the eBPF program.
+func (h *Hook) SendProxyInfo(ip4, port uint32, ip6 [4]uint32) error {
+	key := 0
+	err := h.proxyInfoMap.Update(uint32(key), structs.ProxyInfo{IP4: ip4, Ip6: ip6, Port: port}, ebpf.UpdateAny)
+	if err != nil {
+		h.logger.Error("failed to send the proxy IP & Port to the epbf program", zap.Any("error thrown by ebpf map", err.Error()))
+		return err
+	}
+	return nil
+}
+
+// This function is helpful when user application in running inside a docker container.
+func (h *Hook) SendNameSpaceId(key uint32, inode uint64) error {
+	err := h.inodeMap.Update(uint32(key), &inode, ebpf.UpdateAny)
+	if err != nil {
+		h.logger.Error("failed to send the namespace id to the epbf program", zap.Any("error thrown by ebpf map", err.Error()), zap.Any("key", key), zap.Any("Inode", inode))
+		return err
+	}
+	return nil
+}
+
+func (h *Hook) CleanProxyEntry(srcPort uint16) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	err := h.redirectProxyMap.Delete(srcPort)
+	if err != nil {
+		h.logger.Error("no such key present in the redirect proxy map", zap.Any("error thrown by ebpf map", err.Error()))
+	}
+	h.logger.Debug("successfully removed entry from redirect proxy map", zap.Any("(Key)/SourcePort", srcPort))
+}
+
+// // printing the whole map
+func (h *Hook) PrintRedirectProxyMap() {
+	h.logger.Debug("--------Redirect Proxy Map-------")
+	itr := h.redirectProxyMap.Iterate()
+	var key uint16
+	dest := structs.DestInfo{}
+
+	for itr.Next(&key, &dest) {
+		h.logger.Debug(fmt.Sprintf("Redirect Proxy:  [key:%v] || [value:%v]\n", key, dest))
+	}
+	h.logger.Debug("--------Redirect Proxy Map-------")
+}
+
+// GetDestinationInfo retrieves destination information associated with a source port.
+func (h *Hook) GetDestinationInfo(srcPort uint16) (*structs.DestInfo, error) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	destInfo := structs.DestInfo{}
+	if err := h.redirectProxyMap.Lookup(srcPort, &destInfo); err != nil {
+		return nil, err
+	}
+	return &destInfo, nil
+}
+
+// SendAppPid sends the application's process ID (PID) to the kernel.
+// This function is used when running Keploy tests along with unit tests of the application.
+func (h *Hook) SendAppPid(pid uint32) error {
+	h.logger.Debug("Sending app pid to kernel", zap.Any("app Pid", pid))
+	err := h.appPidMap.Update(uint32(0), &pid, ebpf.UpdateAny)
+	if err != nil {
+		h.logger.Error("failed to send the app pid to the ebpf program", zap.Any("app Pid", pid), zap.Any("error thrown by ebpf map", err.Error()))
+		return err
+	}
+	return nil
+}
+
+func (h *Hook) SendKeployPid(kPid uint32) error {
+	h.logger.Debug("Sending keploy pid to kernel", zap.Any("pid", kPid))
+	err := h.keployPid.Update(uint32(0), &kPid, ebpf.UpdateAny)
+	if err != nil {
+		h.logger.Error("failed to send the keploy pid to the ebpf program", zap.Any("Keploy Pid", kPid), zap.Any("error thrown by ebpf map", err.Error()))
+		return err
+	}
+	return nil
+}
+
+func (h *Hook) SetKeployModeInKernel(mode uint32) {
+	key := 0
+	err := h.keployModeMap.Update(uint32(key), &mode, ebpf.UpdateAny)
+	if err != nil {
+		h.logger.Error("failed to set keploy mode in the epbf program", zap.Any("error thrown by ebpf map", err.Error()))
+	}
+}
+
+// setDockerCmdInKernel tells the kernel hooks whether the command of the user application runs in docker.
+func (h *Hook) setDockerCmdInKernel(docker bool) {
+	key := 0
+	h.objects.DockerCmdMap.Update(uint32(key), &docker, ebpf.UpdateAny)
+}
+
+// releaseKernel closes the readers of the eBPF events and detaches the hooks.
+func (h *Hook) releaseKernel() {
+	// closing all readers.
+	for _, reader := range PerfEventReaders {
+		if err := reader.Close(); err != nil {
+			h.logger.Error("failed to close the eBPF perf reader", zap.Error(err))
+		}
+	}
+	for _, reader := range RingEventReaders {
+		if err := reader.Close(); err != nil {
+			h.logger.Error("failed to close the eBPF ringbuf reader", zap.Error(err))
+		}
+	}
+
+	if h.userspace {
+		return
+	}
+
+	// closing all events
+	links := []link.Link{
+		//other
+		h.socket,
+		//egress
+		h.bind, h.udpp4,
+		//ipv4
+		h.connect4, h.gp4, h.tcppv4, h.tcpv4, h.tcpv4Ret,
+		//ipv6
+		h.connect6, h.gp6, h.tcppv6, h.tcpv6, h.tcpv6Ret,
+		//ingress
+		h.accept, h.acceptRet, h.accept4, h.accept4Ret, h.close, h.closeRet, h.read, h.readRet, h.write, h.writeRet,
+		h.writev, h.writevRet,
+	}
+	for _, l := range links {
+		if l != nil {
+			l.Close()
+		}
+	}
+	h.objects.Close()
+	h.logger.Info("eBPF resources released successfully...")
+}
+
+// LoadHooks is used to attach the eBPF hooks into the linux kernel. Hooks are attached for outgoing and incoming network requests.
+//
+// proxyPorts is used for redirecting outgoing network calls to the unoccupied proxy server.
+//
+// $BPF_CLANG and $BPF_CFLAGS are set by the Makefile.
+//
+//go:generate go run github.com/cilium/ebpf/cmd/bpf2go -cc $BPF_CLANG -cflags $BPF_CFLAGS -no-global-types -tags linux -target $TARGET bpf keploy_ebpf.c -- -I./headers -I./headers/$TARGET
+func (h *Hook) LoadHooks(appCmd, appContainer string, pid uint32, ctx context.Context, filters *models.Filters) error {
+	// the application and the hooks are stopped on every exit path of keploy
+	h.registerCleanup()
+	if err := settings.InitRealTimeOffset(); err != nil {
+		h.logger.Error("failed to fix the BPF clock", zap.Error(err))
+		return err
+	}
+
+	stopper := make(chan os.Signal, 1)
+	signal.Notify(stopper, os.Interrupt, os.Kill, syscall.SIGHUP, syscall.SIGINT, syscall.SIGQUIT, syscall.SIGTERM)
+
+	// Allow the current process to lock memory for eBPF resources.
+	if err := rlimit.RemoveMemlock(); err != nil {
+		h.logger.Error("failed to lock memory for eBPF resources", zap.Error(err))
+		return err
+	}
+
+	// Load pre-compiled programs and maps into the kernel.
+	objs := bpfObjects{}
+	if err := loadBpfObjects(&objs, nil); err != nil {
+		h.logger.Error("failed to load eBPF objects", zap.Error(err))
+		return err
+	}
+
+	//getting all the ebpf maps
+	h.proxyInfoMap = objs.ProxyInfoMap
+	h.inodeMap = objs.InodeMap
+	h.redirectProxyMap = objs.RedirectProxyMap
+	h.keployModeMap = objs.KeployModeMap
+	h.keployPid = objs.KeployNamespacePidMap
+	h.appPidMap = objs.AppNsPidMap
+	h.keployServerPort = objs.KeployServerPort
+	h.passthroughPorts = objs.PassThroughPorts
+
+	h.stopper = stopper
+	h.objects = objs
+
+	connectionFactory := connection.NewFactory(time.Minute, h.logger, h.denoisePasses, h.shadow)
+	go func() {
+		// Recover from panic and gracefully shutdown
+		defer h.Recover(pkg.GenerateRandomID())
+		defer utils.HandlePanic()
+		for {
+			connectionFactory.HandleReadyConnections(h.TestCaseDB, ctx, filters)
+			// time.Sleep(1 * time.Second)
+		}
+	}()
+
+	// ----- used in case of wsl -----
+	socket, err := link.Kprobe("sys_socket", objs.SyscallProbeEntrySocket, nil)
+	if err != nil {
+		log.Fatalf(Emoji, "opening sys_socket kprobe: %s", err)
+	}
+	h.socket = socket
+
+	// ------------ For Egress -------------
+
+	bind, err := link.Kprobe("sys_bind", objs.SyscallProbeEntryBind, nil)
+	if err != nil {
+		log.Fatalf(Emoji, "opening sys_bind kprobe: %s", err)
+	}
+	h.bind = bind
+
+	udpp_c4, err := link.Kprobe("udp_pre_connect", objs.SyscallProbeEntryUdpPreConnect, nil)
+	if err != nil {
+		log.Fatalf(Emoji, "opening udp_pre_connect kprobe: %s", err)
+	}
+	h.udpp4 = udpp_c4
+
+	// FOR IPV4
+	tcpp_c4, err := link.Kprobe("tcp_v4_pre_connect", objs.SyscallProbeEntryTcpV4PreConnect, nil)
+	if err != nil {
+		log.Fatalf(Emoji, "opening tcp_v4_pre_connect kprobe: %s", err)
+	}
+	h.tcppv4 = tcpp_c4
+
+	tcp_c4, err := link.Kprobe("tcp_v4_connect", objs.SyscallProbeEntryTcpV4Connect, nil)
+	if err != nil {
+		log.Fatalf(Emoji, "opening tcp_v4_connect kprobe: %s", err)
+	}
+	h.tcpv4 = tcp_c4
+
+	tcp_r_c4, err := link.Kretprobe("tcp_v4_connect", objs.SyscallProbeRetTcpV4Connect, &link.KprobeOptions{RetprobeMaxActive: 1024})
+	if err != nil {
+		log.Fatalf(Emoji, "opening tcp_v4_connect kretprobe: %s", err)
+	}
+	h.tcpv4Ret = tcp_r_c4
+
+	// Get the first-mounted cgroupv2 path.
+	cgroupPath, err := detectCgroupPath()
+	if err != nil {
+		h.logger.Error("failed to detect the cgroup path", zap.Error(err))
+		return err
+	}
+
+	c4, err := link.AttachCgroup(link.CgroupOptions{
+		Path:    cgroupPath,
+		Attach:  ebpf.AttachCGroupInet4Connect,
+		Program: objs.K_connect4,
+	})
+
+	if err != nil {
+		h.logger.Error("failed to attach the connect4 cgroup hook", zap.Error(err))
+		return err
+	}
+	h.connect4 = c4
+
+	gp4, err := link.AttachCgroup(link.CgroupOptions{
+		Path:    cgroupPath,
+		Attach:  ebpf.AttachCgroupInet4GetPeername,
+		Program: objs.K_getpeername4,
+	})
+
+	if err != nil {
+		h.logger.Error("failed to attach GetPeername4 cgroup hook", zap.Error(err))
+		return err
+	}
+	h.gp4 = gp4
+
+	// FOR IPV6
+
+	tcpp_c6, err := link.Kprobe("tcp_v6_pre_connect", objs.SyscallProbeEntryTcpV6PreConnect, nil)
+	if err != nil {
+		log.Fatalf(Emoji, "opening tcp_v6_pre_connect kprobe: %s", err)
+	}
+	h.tcppv6 = tcpp_c6
+
+	tcp_c6, err := link.Kprobe("tcp_v6_connect", objs.SyscallProbeEntryTcpV6Connect, nil)
+	if err != nil {
+		log.Fatalf(Emoji, "opening tcp_v6_connect kprobe: %s", err)
+	}
+	h.tcpv6 = tcp_c6
+
+	tcp_r_c6, err := link.Kretprobe("tcp_v6_connect", objs.SyscallProbeRetTcpV6Connect, &link.KprobeOptions{RetprobeMaxActive: 1024})
+	if err != nil {
+		log.Fatalf(Emoji, "opening tcp_v6_connect kretprobe: %s", err)
+	}
+	h.tcpv6Ret = tcp_r_c6
+
+	c6, err := link.AttachCgroup(link.CgroupOptions{
+		Path:    cgroupPath,
+		Attach:  ebpf.AttachCGroupInet6Connect,
+		Program: objs.K_connect6,
+	})
+
+	if err != nil {
+		h.logger.Error("failed to attach the connect6 cgroup hook", zap.Error(err))
+		return err
+	}
+	h.connect6 = c6
+
+	gp6, err := link.AttachCgroup(link.CgroupOptions{
+		Path:    cgroupPath,
+		Attach:  ebpf.AttachCgroupInet6GetPeername,
+		Program: objs.K_getpeername6,
+	})
+
+	if err != nil {
+		h.logger.Error("failed to attach GetPeername6 cgroup hook", zap.Error(err))
+		return err
+	}
+	h.gp6 = gp6
+
+	//Open a kprobe at the entry of sendto syscall
+	snd, err := link.Kprobe("sys_sendto", objs.SyscallProbeEntrySendto, nil)
+	if err != nil {
+		h.logger.Error("failed to attach the kprobe hook on sys_sendto", zap.Error(err))
+		return err
+	}
+	h.sendto = snd
+
+	//Opening a kretprobe at the exit of sendto syscall
+	sndr, err := link.Kretprobe("sys_sendto", objs.SyscallProbeRetSendto, &link.KprobeOptions{RetprobeMaxActive: 1024})
+	if err != nil {
+		h.logger.Error("failed to attach the kretprobe hook on sys_sendto", zap.Error(err))
+		return err
+	}
+	h.sendtoRet = sndr
+
+	// ------------ For Ingress using Kprobes --------------
+
+	// Open a Kprobe at the entry point of the kernel function and attach the
+	// pre-compiled program.
+	ac, err := link.Kprobe("sys_accept", objs.SyscallProbeEntryAccept, nil)
+	if err != nil {
+		h.logger.Error("failed to attach the kprobe hook on sys_accept", zap.Error(err))
+		return err
+	}
+	h.accept = ac
+
+	// Open a Kprobe at the exit point of the kernel function and attach the
+	// pre-compiled program.
+	ac_, err := link.Kretprobe("sys_accept", objs.SyscallProbeRetAccept, &link.KprobeOptions{RetprobeMaxActive: 1024})
+	if err != nil {
+		h.logger.Error("failed to attach the kretprobe hook on sys_accept", zap.Error(err))
+		return err
+	}
+	h.acceptRet = ac_
+
+	// Open a Kprobe at the entry point of the kernel function and attach the
+	// pre-compiled program.
+	ac4, err := link.Kprobe("sys_accept4", objs.SyscallProbeEntryAccept4, nil)
+	if err != nil {
+		h.logger.Error("failed to attach the kprobe hook on sys_accept4", zap.Error(err))
+		return err
+	}
+	h.accept4 = ac4
+
+	// Open a Kprobe at the exit point of the kernel function and attach the
+	// pre-compiled program.
+	ac4_, err := link.Kretprobe("sys_accept4", objs.SyscallProbeRetAccept4, &link.KprobeOptions{RetprobeMaxActive: 1024})
+	if err != nil {
+		h.logger.Error("failed to attach the kretprobe hook on sys_accept4", zap.Error(err))
+		return err
+	}
+	h.accept4Ret = ac4_
+
+	// Open a Kprobe at the entry point of the kernel function and attach the
+	// pre-compiled program.
+	rd, err := link.Kprobe("sys_read", objs.SyscallProbeEntryRead, nil)
+	if err != nil {
+		h.logger.Error("failed to attach the kprobe hook on sys_read", zap.Error(err))
+		return err
+	}
+	h.read = rd
+
+	// Open a Kprobe at the exit point of the kernel function and attach the
+	// pre-compiled program.
+	rd_, err := link.Kretprobe("sys_read", objs.SyscallProbeRetRead, &link.KprobeOptions{RetprobeMaxActive: 1024})
+	if err != nil {
+		h.logger.Error("failed to attach the kretprobe hook on sys_read", zap.Error(err))
+		return err
+	}
+	h.readRet = rd_
+
+	// Open a Kprobe at the entry point of the kernel function and attach the
+	// pre-compiled program.
+	wt, err := link.Kprobe("sys_write", objs.SyscallProbeEntryWrite, nil)
+	if err != nil {
+		h.logger.Error("failed to attach the kprobe hook on sys_write", zap.Error(err))
+		return err
+	}
+	h.write = wt
+
+	// Open a Kprobe at the exit point of the kernel function and attach the
+	// pre-compiled program.
+	wt_, err := link.Kretprobe("sys_write", objs.SyscallProbeRetWrite, &link.KprobeOptions{RetprobeMaxActive: 1024})
+	if err != nil {
+		h.logger.Error("failed to attach the kretprobe hook on sys_write", zap.Error(err))
+		return err
+	}
+	h.writeRet = wt_
+
+	// Open a Kprobe at the entry point of the kernel function and attach the
+	// pre-compiled program for writev.
+	wtv, err := link.Kprobe("sys_writev", objs.SyscallProbeEntryWritev, nil)
+	if err != nil {
+		h.logger.Error("failed to attach the kprobe hook on sys_writev", zap.Error(err))
+		return err
+	}
+	h.writev = wtv
+
+	// Open a Kprobe at the exit point of the kernel function and attach the
+	// pre-compiled program for writev.
+	wtv_, err := link.Kretprobe("sys_writev", objs.SyscallProbeRetWritev, &link.KprobeOptions{RetprobeMaxActive: 1024})
+	if err != nil {
+		h.logger.Error("failed to attach the kretprobe hook on sys_writev", zap.Error(err))
+		return err
+	}
+	h.writevRet = wtv_
+
+	// Open a Kprobe at the entry point of the kernel function and attach the
+	// pre-compiled program.
+	cl, err := link.Kprobe("sys_close", objs.SyscallProbeEntryClose, nil)
+	if err != nil {
+		h.logger.Error("failed to attach the kprobe hook on sys_close", zap.Error(err))
+		return err
+	}
+	h.close = cl
+
+	//Attaching a kprobe at the entry of recvfrom syscall
+	rcv, err := link.Kprobe("sys_recvfrom", objs.SyscallProbeEntryRecvfrom, nil)
+	if err != nil {
+		h.logger.Error("failed to attach the kprobe hook on sys_recvfrom", zap.Error(err))
+		return err
+	}
+	h.recvfrom = rcv
+
+	//Attaching a kretprobe at the exit of recvfrom syscall
+	rcvr, err := link.Kretprobe("sys_recvfrom", objs.SyscallProbeRetRecvfrom, &link.KprobeOptions{RetprobeMaxActive: 1024})
+	if err != nil {
+		h.logger.Error("failed to attach the kretprobe hook on sys_recvfrom", zap.Error(err))
+		return err
+	}
+	h.recvfromRet = rcvr
+
+	// Open a Kprobe at the exit point of the kernel function and attach the
+	// pre-compiled program.
+	cl_, err := link.Kretprobe("sys_close", objs.SyscallProbeRetClose, &link.KprobeOptions{RetprobeMaxActive: 1024})
+	if err != nil {
+		h.logger.Error("failed to attach the kretprobe hook on sys_close", zap.Error(err))
+		return err
+	}
+	h.closeRet = cl_
+
+	h.LaunchPerfBufferConsumers(connectionFactory)
+
+	h.logger.Info("keploy initialized and probes added to the kernel.")
+
+	switch models.GetMode() {
+	case models.MODE_RECORD:
+		h.SetKeployModeInKernel(1)
+	case models.MODE_TEST:
+		h.SetKeployModeInKernel(2)
+	}
+
+	//sending keploy pid to kernel to get filtered
+	k_inode := getSelfInodeNumber()
+	h.logger.Debug("", zap.Any("Keploy Inode number", k_inode))
+	h.SendNameSpaceId(1, k_inode)
+	h.SendKeployPid(uint32(os.Getpid()))
+	h.logger.Debug("Keploy Pid sent successfully...")
+
+	//send app pid to kernel to get filtered in case of integration with unit test file
+	// app pid here is the pid of the unit test file process or application pid
+	if pid != 0 {
+		h.SendAppPid(pid)
+	}
+
+	return nil
+}
+
+// detectCgroupPath returns the first-found mount point of type cgroup2
+// and stores it in the cgroupPath global variable.
+func detectCgroupPath() (string, error) {
+	f, err := os.Open("/proc/mounts")
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		// example fields: cgroup2 /sys/fs/cgroup/unified cgroup2 rw,nosuid,nodev,noexec,relatime 0 0
+		fields := strings.Split(scanner.Text(), " ")
+		if len(fields) >= 3 && fields[2] == "cgroup2" {
+			return fields[1], nil
+		}
+	}
+
+	return "", errors.New("cgroup2 not mounted")
+}
+
+func platformPrefix(symbol string) string {
+	// per https://github.com/golang/go/blob/master/src/go/build/syslist.go
+	// and https://github.com/libbpf/libbpf/blob/master/src/libbpf.c#L10047
+	var prefix string
+	switch runtime.GOARCH {
+	case "386":
+		prefix = "ia32"
+	case "amd64", "amd64p32":
+		prefix = "x64"
+
+	case "arm", "armbe":
+		prefix = "arm"
+	case "arm64", "arm64be":
+		prefix = "arm64"
+
+	case "mips", "mipsle", "mips64", "mips64le", "mips64p32", "mips64p32le":
+		prefix = "mips"
+
+	case "s390":
+		prefix = "s390"
+	case "s390x":
+		prefix = "s390x"
+
+	case "riscv", "riscv64":
+		prefix = "riscv"
+
+	case "ppc":
+		prefix = "powerpc"
+	case "ppc64", "ppc64le":
+		prefix = "powerpc64"
+
+	default:
+		return symbol
+	}
+
+	return fmt.Sprintf("__%s_%s", prefix, symbol)
+}
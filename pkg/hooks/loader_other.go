@@ -0,0 +1,63 @@
+//go:build !linux
+
+package hooks
+
+import (
+	"context"
+	"errors"
+
+	"go.keploy.io/server/pkg/hooks/structs"
+	"go.keploy.io/server/pkg/models"
+)
+
+// errKernelHooks is returned by the calls to the eBPF hooks on the platforms other than linux, where the calls of
+// the application are captured with the userspace proxy, see LoadCapture.
+var errKernelHooks = errors.New("the eBPF hooks are only available on linux")
+
+// kernelHooks is empty on the platforms other than linux, which have no eBPF hooks.
+type kernelHooks struct{}
+
+func (h *Hook) SendPassThroughPorts(filterPorts []uint) error {
+	return errKernelHooks
+}
+
+func (h *Hook) SendKeployServerPort(port uint32) error {
+	return errKernelHooks
+}
+
+func (h *Hook) SendProxyInfo(ip4, port uint32, ip6 [4]uint32) error {
+	return errKernelHooks
+}
+
+func (h *Hook) SendNameSpaceId(key uint32, inode uint64) error {
+	return errKernelHooks
+}
+
+func (h *Hook) CleanProxyEntry(srcPort uint16) {}
+
+func (h *Hook) PrintRedirectProxyMap() {}
+
+func (h *Hook) GetDestinationInfo(srcPort uint16) (*structs.DestInfo, error) {
+	return nil, errKernelHooks
+}
+
+func (h *Hook) SendAppPid(pid uint32) error {
+	return errKernelHooks
+}
+
+func (h *Hook) SendKeployPid(kPid uint32) error {
+	return errKernelHooks
+}
+
+func (h *Hook) SetKeployModeInKernel(mode uint32) {}
+
+func (h *Hook) setDockerCmdInKernel(docker bool) {}
+
+func (h *Hook) releaseKernel() {}
+
+// LoadHooks fails on the platforms other than linux, which have no eBPF hooks.
+func (h *Hook) LoadHooks(appCmd, appContainer string, pid uint32, ctx context.Context, filters *models.Filters) error {
+	// the application is stopped on every exit path of keploy
+	h.registerCleanup()
+	return errKernelHooks
+}
@@ -0,0 +1,157 @@
+package hooks
+
+import (
+	"hash/fnv"
+	"net/url"
+	"strconv"
+	"sync"
+
+	"go.keploy.io/server/pkg/models"
+)
+
+// mockIndex indexes the unconsumed tcs mocks of the test set by their kind and by the request they were recorded for,
+// so that the parsers look up the mocks which may match a request rather than scanning all of them. It is built when
+// the mocks of the test set are loaded and follows their consumption.
+type mockIndex struct {
+	mutex sync.RWMutex
+	// kinds holds the mocks of each kind, sorted by their request timestamp
+	kinds map[models.Kind][]*models.Mock
+	// requests holds the mocks of each kind and request key, sorted by their request timestamp
+	requests map[string][]*models.Mock
+	// sequences holds the strict-sequence mocks of each kind and connections the connection-sequence mocks of each
+	// recorded connection, the first one of each being the one available
+	sequences   map[models.Kind][]*models.Mock
+	connections map[string][]*models.Mock
+}
+
+// RequestKey returns the key of the request the mock was recorded for: the method and the path of the http mocks,
+// the hash of the first message of the generic ones. It is empty for the mocks of the other kinds.
+func RequestKey(mock *models.Mock) string {
+	switch mock.Kind {
+	case models.HTTP:
+		if mock.Spec.HttpReq == nil {
+			return ""
+		}
+		parsed, err := url.Parse(mock.Spec.HttpReq.URL)
+		if err != nil {
+			return ""
+		}
+		return HttpRequestKey(string(mock.Spec.HttpReq.Method), parsed.Path)
+	case models.GENERIC:
+		if len(mock.Spec.GenericRequests) == 0 || len(mock.Spec.GenericRequests[0].Message) == 0 {
+			return ""
+		}
+		return GenericRequestKey(mock.Spec.GenericRequests[0].Message[0].Data)
+	}
+	return ""
+}
+
+// HttpRequestKey returns the request key of the http requests with the method and the path.
+func HttpRequestKey(method, path string) string {
+	return method + " " + path
+}
+
+// GenericRequestKey returns the request key of the generic requests whose first message has the data, encoded like
+// in the mocks.
+func GenericRequestKey(data string) string {
+	hash := fnv.New64a()
+	hash.Write([]byte(data))
+	return strconv.FormatUint(hash.Sum64(), 16)
+}
+
+func requestIndexKey(kind models.Kind, key string) string {
+	return string(kind) + "\x00" + key
+}
+
+// build indexes the mocks, sorted by their request timestamp, in place of the ones indexed before.
+func (idx *mockIndex) build(mocks []*models.Mock) {
+	idx.mutex.Lock()
+	defer idx.mutex.Unlock()
+	idx.kinds = map[models.Kind][]*models.Mock{}
+	idx.requests = map[string][]*models.Mock{}
+	idx.sequences = map[models.Kind][]*models.Mock{}
+	idx.connections = map[string][]*models.Mock{}
+	for _, mock := range mocks {
+		idx.kinds[mock.Kind] = append(idx.kinds[mock.Kind], mock)
+		if key := RequestKey(mock); key != "" {
+			k := requestIndexKey(mock.Kind, key)
+			idx.requests[k] = append(idx.requests[k], mock)
+		}
+		switch mock.Policy {
+		case models.MockPolicyStrictSequence:
+			idx.sequences[mock.Kind] = append(idx.sequences[mock.Kind], mock)
+		case models.MockPolicyConnectionSequence:
+			if connection := mock.Spec.Metadata[models.ConnectionMetadata]; connection != "" {
+				idx.connections[connection] = append(idx.connections[connection], mock)
+			}
+		}
+	}
+}
+
+// remove drops the consumed mock from the index.
+func (idx *mockIndex) remove(mock *models.Mock) {
+	idx.mutex.Lock()
+	defer idx.mutex.Unlock()
+	if idx.kinds == nil {
+		return
+	}
+	idx.kinds[mock.Kind] = withoutMock(idx.kinds[mock.Kind], mock)
+	if key := RequestKey(mock); key != "" {
+		k := requestIndexKey(mock.Kind, key)
+		if idx.requests[k] = withoutMock(idx.requests[k], mock); len(idx.requests[k]) == 0 {
+			delete(idx.requests, k)
+		}
+	}
+	switch mock.Policy {
+	case models.MockPolicyStrictSequence:
+		idx.sequences[mock.Kind] = withoutMock(idx.sequences[mock.Kind], mock)
+	case models.MockPolicyConnectionSequence:
+		if connection := mock.Spec.Metadata[models.ConnectionMetadata]; connection != "" {
+			if idx.connections[connection] = withoutMock(idx.connections[connection], mock); len(idx.connections[connection]) == 0 {
+				delete(idx.connections, connection)
+			}
+		}
+	}
+}
+
+// lookup returns the available mocks of the kind, only the ones recorded for the request key when it is set, in the
+// order of availableMocks.
+func (idx *mockIndex) lookup(kind models.Kind, key string) []*models.Mock {
+	idx.mutex.RLock()
+	defer idx.mutex.RUnlock()
+	mocks := idx.kinds[kind]
+	if key != "" {
+		mocks = idx.requests[requestIndexKey(kind, key)]
+	}
+	var available, repeatable []*models.Mock
+	for _, mock := range mocks {
+		switch mock.Policy {
+		case models.MockPolicyStrictSequence:
+			if idx.sequences[kind][0] != mock {
+				continue
+			}
+		case models.MockPolicyConnectionSequence:
+			if connection := mock.Spec.Metadata[models.ConnectionMetadata]; connection != "" && idx.connections[connection][0] != mock {
+				continue
+			}
+		case models.MockPolicyRepeatable:
+			repeatable = append(repeatable, mock)
+			continue
+		}
+		available = append(available, mock)
+	}
+	return append(available, repeatable...)
+}
+
+func (idx *mockIndex) reset() {
+	idx.build(nil)
+}
+
+func withoutMock(mocks []*models.Mock, mock *models.Mock) []*models.Mock {
+	for i, m := range mocks {
+		if m == mock {
+			return append(mocks[:i:i], mocks[i+1:]...)
+		}
+	}
+	return mocks
+}
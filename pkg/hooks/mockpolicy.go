@@ -0,0 +1,42 @@
+package hooks
+
+import (
+	"go.keploy.io/server/pkg/models"
+)
+
+// availableMocks returns the mocks which can be matched, out of the unconsumed ones sorted by their request
+// timestamp. Only the first strict-sequence mock of each kind, and the first connection-sequence mock of each recorded
+// connection, are available, and the repeatable mocks come after the others so that the parsers prefer the mocks
+// which are consumed by their match.
+func availableMocks(mocks []*models.Mock) []*models.Mock {
+	var (
+		available   []*models.Mock
+		repeatable  []*models.Mock
+		sequenced   = map[models.Kind]bool{}
+		connections = map[string]bool{}
+	)
+	for _, mock := range mocks {
+		switch mock.Policy {
+		case models.MockPolicyStrictSequence:
+			if sequenced[mock.Kind] {
+				continue
+			}
+			sequenced[mock.Kind] = true
+			available = append(available, mock)
+		case models.MockPolicyConnectionSequence:
+			// the mocks recorded before the connections were tagged are consumed in any order
+			if connection := mock.Spec.Metadata[models.ConnectionMetadata]; connection != "" {
+				if connections[connection] {
+					continue
+				}
+				connections[connection] = true
+			}
+			available = append(available, mock)
+		case models.MockPolicyRepeatable:
+			repeatable = append(repeatable, mock)
+		default:
+			available = append(available, mock)
+		}
+	}
+	return append(available, repeatable...)
+}
@@ -0,0 +1,22 @@
+package preload
+
+import (
+	_ "embed"
+	"strconv"
+)
+
+//go:embed src/connect.c
+var connectSource []byte
+
+// ProxyPortEnv is the environment variable holding the port of the proxy the connections are sent to.
+const ProxyPortEnv = "KEPLOY_PROXY_PORT"
+
+// Connect returns the library sending the TCP connections of the application to the proxy listening on the local
+// port, as SOCKS5 requests holding the address of their server, in place of the eBPF hooks redirecting them.
+func Connect(proxyPort uint32) (Library, error) {
+	path, err := library("libkeployconnect", connectSource)
+	if err != nil {
+		return Library{}, err
+	}
+	return Library{Path: path, Env: map[string]string{ProxyPortEnv: strconv.FormatUint(uint64(proxyPort), 10)}}, nil
+}
@@ -0,0 +1,42 @@
+package preload
+
+import (
+	"errors"
+	"fmt"
+	"os"
+)
+
+// errNotPrivate is returned for the directories of $TMPDIR which another user could write in.
+var errNotPrivate = errors.New("the directory is writable by another user")
+
+// sharedDir creates the directory of $TMPDIR, readable by everyone and writable by the user of keploy alone. A
+// directory created before is only accepted when it is one of the user of keploy which the other users can't write
+// in, otherwise they could put their own library or socket in it.
+func sharedDir(dir string) error {
+	err := os.Mkdir(dir, 0755)
+	if err == nil {
+		// the umask may have removed the permissions of the user who invoked sudo
+		os.Chmod(dir, 0755)
+		return nil
+	}
+	if !os.IsExist(err) {
+		return fmt.Errorf("failed to create the directory %s: %v", dir, err)
+	}
+	if !private(dir) {
+		return fmt.Errorf("%s: %w", dir, errNotPrivate)
+	}
+	return nil
+}
+
+// private tells whether the directory or the regular file of the path, which isn't a symbolic link, is owned by the
+// user of keploy and isn't writable by the other users.
+func private(path string) bool {
+	info, err := os.Lstat(path)
+	if err != nil {
+		return false
+	}
+	if !info.IsDir() && !info.Mode().IsRegular() {
+		return false
+	}
+	return info.Mode().Perm()&0022 == 0 && ownedByKeploy(info)
+}
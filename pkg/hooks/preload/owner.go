@@ -0,0 +1,14 @@
+//go:build !windows
+
+package preload
+
+import (
+	"os"
+	"syscall"
+)
+
+// ownedByKeploy tells whether the file is owned by the user keploy runs as.
+func ownedByKeploy(info os.FileInfo) bool {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	return ok && stat.Uid == uint32(os.Geteuid())
+}
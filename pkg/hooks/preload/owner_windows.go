@@ -0,0 +1,8 @@
+package preload
+
+import "os"
+
+// ownedByKeploy is always true on windows, whose temporary directory belongs to the user keploy runs as.
+func ownedByKeploy(info os.FileInfo) bool {
+	return true
+}
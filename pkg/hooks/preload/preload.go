@@ -0,0 +1,76 @@
+// Package preload runs the applications started by keploy with shared libraries preloaded in them, which replace
+// some functions of the C library, like the ones telling the time or generating random bytes.
+package preload
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"go.keploy.io/server/pkg/proxy/util"
+)
+
+// Library is a shared library preloaded in the application.
+type Library struct {
+	// Path of the library
+	Path string
+	// Env are the environment variables setting the library
+	Env map[string]string
+	// Dirs are the directories read by the library, mounted in the containers
+	Dirs []string
+}
+
+// ErrUnsupported is returned for the applications which the libraries can't be preloaded in.
+var ErrUnsupported = errors.New("the libraries can only be preloaded in the native applications and the ones started with docker run")
+
+// dockerRun matches the docker run of an application command, after which the options of the container are added.
+var dockerRun = regexp.MustCompile(`^((?:sudo\s+)?docker\s+(?:container\s+)?run)\s`)
+
+// Command returns the command running the application with the libraries preloaded. The libraries and their
+// directories are mounted at the same paths in the containers started with docker run, whose images must be based on
+// glibc. The statically linked applications, like the go ones built without cgo, don't load them.
+func Command(appCmd string, libs []Library) (string, error) {
+	if len(libs) == 0 {
+		return appCmd, nil
+	}
+	var paths []string
+	env := map[string]string{}
+	var dirs []string
+	for _, lib := range libs {
+		paths = append(paths, lib.Path)
+		for name, value := range lib.Env {
+			env[name] = value
+		}
+		dirs = append(dirs, lib.Dirs...)
+	}
+	names := make([]string, 0, len(env))
+	for name := range env {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	isDocker, kind := util.IsDockerRelatedCommand(appCmd)
+	switch {
+	case !isDocker:
+		exports := []string{fmt.Sprintf("LD_PRELOAD='%s'", strings.Join(paths, ":"))}
+		for _, name := range names {
+			exports = append(exports, fmt.Sprintf("%s='%s'", name, env[name]))
+		}
+		return fmt.Sprintf("export %s; %s", strings.Join(exports, " "), appCmd), nil
+	case kind == "docker" && dockerRun.MatchString(appCmd):
+		var options []string
+		for _, path := range append(paths, dirs...) {
+			options = append(options, fmt.Sprintf("-v %s:%s:ro", path, path))
+		}
+		options = append(options, "-e LD_PRELOAD="+strings.Join(paths, ":"))
+		for _, name := range names {
+			options = append(options, fmt.Sprintf("-e %s='%s'", name, env[name]))
+		}
+		end := dockerRun.FindStringSubmatchIndex(appCmd)[3]
+		return appCmd[:end] + " " + strings.Join(options, " ") + appCmd[end:], nil
+	default:
+		return appCmd, ErrUnsupported
+	}
+}
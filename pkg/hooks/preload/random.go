@@ -0,0 +1,93 @@
+package preload
+
+import (
+	"crypto/sha256"
+	_ "embed"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+)
+
+//go:embed src/random.c
+var randomSource []byte
+
+// SeedEnv is the environment variable holding the seed of the randomness of the application.
+const SeedEnv = "KEPLOY_RANDOM_SEED"
+
+// Random returns the library replacing the random bytes read by the application, from getrandom, getentropy and the
+// /dev/urandom and /dev/random devices, with the ones generated from the seed. The application started twice with
+// the same seed, and handling the same requests in the same order, generates the same ids, nonces and tokens.
+//
+// The library is built with the C compiler of the machine the first time, in a directory readable by the user who
+// invoked sudo, whom the application is run as, and writable by the user of keploy alone.
+func Random(seed uint64) (Library, error) {
+	path, err := library("libkeployrandom", randomSource)
+	if err != nil {
+		return Library{}, err
+	}
+	return Library{Path: path, Env: map[string]string{SeedEnv: strconv.FormatUint(seed, 10)}}, nil
+}
+
+// library returns the path of the shared library of the name built from the C source, which is built the first time.
+// The library built before is only reused when no other user could have written it, since it is loaded in the
+// application.
+func library(name string, source []byte) (string, error) {
+	sum := sha256.Sum256(source)
+	dir, err := libraryDir()
+	if err != nil {
+		return "", err
+	}
+	path := filepath.Join(dir, fmt.Sprintf("%s-%x.so", name, sum[:6]))
+	if private(path) {
+		return path, nil
+	}
+	return path, build(path, source)
+}
+
+// libraryDir returns the directory of the libraries, $TMPDIR/keploy-preload. When another user created it first, the
+// libraries are built in a new directory of $TMPDIR instead, which isn't reused.
+func libraryDir() (string, error) {
+	dir := filepath.Join(os.TempDir(), "keploy-preload")
+	err := sharedDir(dir)
+	if err == nil {
+		return dir, nil
+	}
+	if !errors.Is(err, errNotPrivate) {
+		return "", err
+	}
+	dir, err = os.MkdirTemp("", "keploy-preload-")
+	if err != nil {
+		return "", fmt.Errorf("failed to create the directory of the preloaded libraries: %v", err)
+	}
+	os.Chmod(dir, 0755)
+	return dir, nil
+}
+
+// build compiles the C source into the shared library of the path.
+func build(path string, source []byte) error {
+	compiler, err := exec.LookPath("cc")
+	if err != nil {
+		return fmt.Errorf("a C compiler (cc) is needed to build %s: %v", filepath.Base(path), err)
+	}
+	sourcePath := path + ".c"
+	if err := os.WriteFile(sourcePath, source, 0644); err != nil {
+		return fmt.Errorf("failed to write the source of %s: %v", filepath.Base(path), err)
+	}
+	defer os.Remove(sourcePath)
+	// the library is renamed once built, so that a concurrent keploy never loads it half written
+	tempPath := fmt.Sprintf("%s.%d.tmp", path, os.Getpid())
+	out, err := exec.Command(compiler, "-shared", "-fPIC", "-O2", "-U_FORTIFY_SOURCE", "-o", tempPath, sourcePath, "-ldl", "-lpthread").CombinedOutput()
+	if err != nil {
+		os.Remove(tempPath)
+		return fmt.Errorf("failed to build %s: %v: %s", filepath.Base(path), err, string(out))
+	}
+	os.Chmod(tempPath, 0755)
+	if err := os.Rename(tempPath, path); err != nil {
+		os.Remove(tempPath)
+		return fmt.Errorf("failed to write %s: %v", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,144 @@
+// libkeployconnect sends the TCP connections of the application to the keploy proxy listening on the local port of
+// KEPLOY_PROXY_PORT, which records or mocks them without the eBPF hooks, so without any privilege. connect() connects
+// the socket to the proxy instead of the server and sends it the SOCKS5 request holding the address of the server,
+// before returning. The non-blocking sockets are connected synchronously.
+#define _GNU_SOURCE
+#include <dlfcn.h>
+#include <errno.h>
+#include <fcntl.h>
+#include <netinet/in.h>
+#include <stdint.h>
+#include <stdlib.h>
+#include <string.h>
+#include <sys/socket.h>
+
+static int (*real_connect)(int, const struct sockaddr *, socklen_t);
+
+static int send_all(int fd, const unsigned char *buf, size_t len) {
+    while (len > 0) {
+        ssize_t n = send(fd, buf, len, MSG_NOSIGNAL);
+        if (n < 0 && errno == EINTR) {
+            continue;
+        }
+        if (n <= 0) {
+            return -1;
+        }
+        buf += n;
+        len -= n;
+    }
+    return 0;
+}
+
+static int recv_all(int fd, unsigned char *buf, size_t len) {
+    while (len > 0) {
+        ssize_t n = recv(fd, buf, len, 0);
+        if (n < 0 && errno == EINTR) {
+            continue;
+        }
+        if (n <= 0) {
+            return -1;
+        }
+        buf += n;
+        len -= n;
+    }
+    return 0;
+}
+
+// handshake sends the SOCKS5 CONNECT request to the server of the address, without authentication.
+static int handshake(int fd, const struct sockaddr *addr) {
+    unsigned char buf[32] = {0x05, 0x01, 0x00};
+    if (send_all(fd, buf, 3) || recv_all(fd, buf, 2) || buf[0] != 0x05 || buf[1] != 0x00) {
+        return -1;
+    }
+    size_t len = 0;
+    buf[len++] = 0x05;
+    buf[len++] = 0x01;
+    buf[len++] = 0x00;
+    if (addr->sa_family == AF_INET) {
+        const struct sockaddr_in *in = (const struct sockaddr_in *)addr;
+        buf[len++] = 0x01;
+        memcpy(buf + len, &in->sin_addr, 4);
+        len += 4;
+        memcpy(buf + len, &in->sin_port, 2);
+    } else {
+        const struct sockaddr_in6 *in6 = (const struct sockaddr_in6 *)addr;
+        buf[len++] = 0x04;
+        memcpy(buf + len, &in6->sin6_addr, 16);
+        len += 16;
+        memcpy(buf + len, &in6->sin6_port, 2);
+    }
+    len += 2;
+    // the proxy binds the replies to no IPv4 address
+    if (send_all(fd, buf, len) || recv_all(fd, buf, 10) || buf[1] != 0x00) {
+        return -1;
+    }
+    return 0;
+}
+
+static int is_loopback(const struct sockaddr *addr) {
+    if (addr->sa_family == AF_INET) {
+        return (ntohl(((const struct sockaddr_in *)addr)->sin_addr.s_addr) >> 24) == 127;
+    }
+    const struct in6_addr *a = &((const struct sockaddr_in6 *)addr)->sin6_addr;
+    return IN6_IS_ADDR_LOOPBACK(a) || (IN6_IS_ADDR_V4MAPPED(a) && a->s6_addr[12] == 127);
+}
+
+int connect(int fd, const struct sockaddr *addr, socklen_t len) {
+    if (!real_connect) {
+        real_connect = dlsym(RTLD_NEXT, "connect");
+    }
+    const char *port_env = getenv("KEPLOY_PROXY_PORT");
+    int proxy_port = port_env ? atoi(port_env) : 0;
+    if (!addr || proxy_port <= 0 || (addr->sa_family != AF_INET && addr->sa_family != AF_INET6) ||
+        (addr->sa_family == AF_INET && len < sizeof(struct sockaddr_in)) ||
+        (addr->sa_family == AF_INET6 && len < sizeof(struct sockaddr_in6))) {
+        return real_connect(fd, addr, len);
+    }
+    int type;
+    socklen_t type_len = sizeof(type);
+    if (getsockopt(fd, SOL_SOCKET, SO_TYPE, &type, &type_len) != 0 || type != SOCK_STREAM) {
+        return real_connect(fd, addr, len);
+    }
+    uint16_t port = ntohs(addr->sa_family == AF_INET ? ((const struct sockaddr_in *)addr)->sin_port
+                                                     : ((const struct sockaddr_in6 *)addr)->sin6_port);
+    if (is_loopback(addr) && port == proxy_port) {
+        return real_connect(fd, addr, len);
+    }
+
+    struct sockaddr_storage proxy;
+    socklen_t proxy_len;
+    memset(&proxy, 0, sizeof(proxy));
+    if (addr->sa_family == AF_INET) {
+        struct sockaddr_in *in = (struct sockaddr_in *)&proxy;
+        in->sin_family = AF_INET;
+        in->sin_port = htons(proxy_port);
+        in->sin_addr.s_addr = htonl(INADDR_LOOPBACK);
+        proxy_len = sizeof(*in);
+    } else {
+        // the IPv4-mapped loopback, reached by the dual-stack sockets even when IPv6 is disabled
+        struct sockaddr_in6 *in6 = (struct sockaddr_in6 *)&proxy;
+        in6->sin6_family = AF_INET6;
+        in6->sin6_port = htons(proxy_port);
+        in6->sin6_addr.s6_addr[10] = 0xff;
+        in6->sin6_addr.s6_addr[11] = 0xff;
+        in6->sin6_addr.s6_addr[12] = 127;
+        in6->sin6_addr.s6_addr[15] = 1;
+        proxy_len = sizeof(*in6);
+    }
+
+    int flags = fcntl(fd, F_GETFL);
+    if (flags >= 0 && (flags & O_NONBLOCK)) {
+        fcntl(fd, F_SETFL, flags & ~O_NONBLOCK);
+    }
+    int rc = real_connect(fd, (struct sockaddr *)&proxy, proxy_len);
+    if (rc == 0 && handshake(fd, addr) != 0) {
+        errno = ECONNREFUSED;
+        rc = -1;
+    }
+    int saved = errno;
+    if (flags >= 0 && (flags & O_NONBLOCK)) {
+        fcntl(fd, F_SETFL, flags);
+    }
+    errno = saved;
+    return rc;
+}
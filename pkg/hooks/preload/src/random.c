@@ -0,0 +1,190 @@
+// libkeployrandom replaces the random bytes read by the application, from getrandom, getentropy and the
+// /dev/urandom and /dev/random devices, with the ones generated from the seed of KEPLOY_RANDOM_SEED, so that the
+// application generates the same ids, nonces and tokens each time it is started with the same seed.
+#define _GNU_SOURCE
+#include <dlfcn.h>
+#include <errno.h>
+#include <fcntl.h>
+#include <pthread.h>
+#include <stdarg.h>
+#include <stdint.h>
+#include <stdio.h>
+#include <stdlib.h>
+#include <string.h>
+#include <sys/syscall.h>
+#include <sys/types.h>
+#include <unistd.h>
+
+#define MAX_FDS 4096
+
+static pthread_mutex_t lock = PTHREAD_MUTEX_INITIALIZER;
+static uint64_t state;
+static int seeded;
+// random_fds are the file descriptors of the random devices opened by the application
+static char random_fds[MAX_FDS];
+
+// next returns the next value of splitmix64, which is enough for the ids of the application to be reproducible.
+static uint64_t next(void) {
+    uint64_t z = (state += 0x9e3779b97f4a7c15ULL);
+    z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9ULL;
+    z = (z ^ (z >> 27)) * 0x94d049bb133111ebULL;
+    return z ^ (z >> 31);
+}
+
+static void fill(void *buf, size_t len) {
+    unsigned char *p = buf;
+    pthread_mutex_lock(&lock);
+    if (!seeded) {
+        const char *seed = getenv("KEPLOY_RANDOM_SEED");
+        state = seed ? strtoull(seed, NULL, 10) : 0;
+        seeded = 1;
+    }
+    while (len > 0) {
+        uint64_t r = next();
+        size_t n = len < sizeof(r) ? len : sizeof(r);
+        memcpy(p, &r, n);
+        p += n;
+        len -= n;
+    }
+    pthread_mutex_unlock(&lock);
+}
+
+static int is_random_device(const char *path) {
+    return path && (strcmp(path, "/dev/urandom") == 0 || strcmp(path, "/dev/random") == 0);
+}
+
+static int track(const char *path, int fd) {
+    if (fd >= 0 && fd < MAX_FDS) {
+        random_fds[fd] = is_random_device(path);
+    }
+    return fd;
+}
+
+static int tracked(int fd) {
+    return fd >= 0 && fd < MAX_FDS && random_fds[fd];
+}
+
+ssize_t getrandom(void *buf, size_t len, unsigned int flags) {
+    (void)flags;
+    fill(buf, len);
+    return len;
+}
+
+int getentropy(void *buf, size_t len) {
+    if (len > 256) {
+        errno = EIO;
+        return -1;
+    }
+    fill(buf, len);
+    return 0;
+}
+
+// syscall catches the getrandom system calls made without the wrapper of the C library, like the ones of openssl and
+// libuv.
+long syscall(long number, ...) {
+    static long (*real_syscall)(long, ...);
+    long a[6];
+    va_list ap;
+    va_start(ap, number);
+    for (int i = 0; i < 6; i++) {
+        a[i] = va_arg(ap, long);
+    }
+    va_end(ap);
+    if (number == SYS_getrandom) {
+        fill((void *)a[0], (size_t)a[1]);
+        return a[1];
+    }
+    if (!real_syscall) {
+        real_syscall = dlsym(RTLD_NEXT, "syscall");
+    }
+    return real_syscall(number, a[0], a[1], a[2], a[3], a[4], a[5]);
+}
+
+#define OPEN(name)                                                            \
+    int name(const char *path, int flags, ...) {                              \
+        static int (*real)(const char *, int, ...);                           \
+        mode_t mode = 0;                                                      \
+        if (flags & (O_CREAT | O_TMPFILE)) {                                  \
+            va_list ap;                                                       \
+            va_start(ap, flags);                                              \
+            mode = va_arg(ap, int);                                           \
+            va_end(ap);                                                       \
+        }                                                                     \
+        if (!real) {                                                          \
+            real = dlsym(RTLD_NEXT, #name);                                   \
+        }                                                                     \
+        return track(path, real(path, flags, mode));                          \
+    }
+
+#define OPENAT(name)                                                          \
+    int name(int dirfd, const char *path, int flags, ...) {                   \
+        static int (*real)(int, const char *, int, ...);                      \
+        mode_t mode = 0;                                                      \
+        if (flags & (O_CREAT | O_TMPFILE)) {                                  \
+            va_list ap;                                                       \
+            va_start(ap, flags);                                              \
+            mode = va_arg(ap, int);                                           \
+            va_end(ap);                                                       \
+        }                                                                     \
+        if (!real) {                                                          \
+            real = dlsym(RTLD_NEXT, #name);                                   \
+        }                                                                     \
+        return track(path, real(dirfd, path, flags, mode));                   \
+    }
+
+OPEN(open)
+OPEN(open64)
+OPENAT(openat)
+OPENAT(openat64)
+
+ssize_t read(int fd, void *buf, size_t count) {
+    static ssize_t (*real_read)(int, void *, size_t);
+    if (tracked(fd)) {
+        fill(buf, count);
+        return count;
+    }
+    if (!real_read) {
+        real_read = dlsym(RTLD_NEXT, "read");
+    }
+    return real_read(fd, buf, count);
+}
+
+int close(int fd) {
+    static int (*real_close)(int);
+    if (fd >= 0 && fd < MAX_FDS) {
+        random_fds[fd] = 0;
+    }
+    if (!real_close) {
+        real_close = dlsym(RTLD_NEXT, "close");
+    }
+    return real_close(fd);
+}
+
+static ssize_t cookie_read(void *cookie, char *buf, size_t size) {
+    (void)cookie;
+    fill(buf, size);
+    return size;
+}
+
+static int cookie_close(void *cookie) {
+    (void)cookie;
+    return 0;
+}
+
+// the streams of the C library open their files without going through open, so the random devices opened as streams
+// are replaced by ones reading the generated bytes
+#define FOPEN(name)                                                           \
+    FILE *name(const char *path, const char *mode) {                          \
+        static FILE *(*real)(const char *, const char *);                     \
+        if (is_random_device(path)) {                                         \
+            cookie_io_functions_t io = {cookie_read, NULL, NULL, cookie_close}; \
+            return fopencookie(NULL, mode, io);                               \
+        }                                                                     \
+        if (!real) {                                                          \
+            real = dlsym(RTLD_NEXT, #name);                                   \
+        }                                                                     \
+        return real(path, mode);                                              \
+    }
+
+FOPEN(fopen)
+FOPEN(fopen64)
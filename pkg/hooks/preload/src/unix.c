@@ -0,0 +1,77 @@
+// libkeployunix redirects the connections of the application to the unix sockets listed in KEPLOY_UNIX_SOCKETS, as
+// "original=redirect" entries separated by ";", to the sockets of the keploy proxy, which records or mocks them like
+// the TCP connections redirected by the eBPF hooks.
+#define _GNU_SOURCE
+#include <dlfcn.h>
+#include <pthread.h>
+#include <stddef.h>
+#include <stdlib.h>
+#include <string.h>
+#include <sys/socket.h>
+#include <sys/un.h>
+
+#define MAX_SOCKETS 64
+
+struct redirect {
+    char original[sizeof(((struct sockaddr_un *)0)->sun_path)];
+    char target[sizeof(((struct sockaddr_un *)0)->sun_path)];
+};
+
+static pthread_once_t once = PTHREAD_ONCE_INIT;
+static struct redirect redirects[MAX_SOCKETS];
+static int count;
+
+static void parse(void) {
+    const char *env = getenv("KEPLOY_UNIX_SOCKETS");
+    if (!env) {
+        return;
+    }
+    char *entries = strdup(env);
+    char *save = NULL;
+    for (char *entry = strtok_r(entries, ";", &save); entry && count < MAX_SOCKETS; entry = strtok_r(NULL, ";", &save)) {
+        char *sep = strchr(entry, '=');
+        if (!sep) {
+            continue;
+        }
+        *sep = '\0';
+        strncpy(redirects[count].original, entry, sizeof(redirects[count].original) - 1);
+        strncpy(redirects[count].target, sep + 1, sizeof(redirects[count].target) - 1);
+        count++;
+    }
+    free(entries);
+}
+
+static const char *target(const struct sockaddr_un *addr, socklen_t len) {
+    char path[sizeof(addr->sun_path) + 1];
+    size_t n = len > offsetof(struct sockaddr_un, sun_path) ? len - offsetof(struct sockaddr_un, sun_path) : 0;
+    if (n > sizeof(addr->sun_path)) {
+        n = sizeof(addr->sun_path);
+    }
+    memcpy(path, addr->sun_path, n);
+    path[n] = '\0';
+    pthread_once(&once, parse);
+    for (int i = 0; i < count; i++) {
+        if (strcmp(path, redirects[i].original) == 0) {
+            return redirects[i].target;
+        }
+    }
+    return NULL;
+}
+
+int connect(int fd, const struct sockaddr *addr, socklen_t len) {
+    static int (*real_connect)(int, const struct sockaddr *, socklen_t);
+    if (!real_connect) {
+        real_connect = dlsym(RTLD_NEXT, "connect");
+    }
+    if (addr && addr->sa_family == AF_UNIX) {
+        const char *redirect = target((const struct sockaddr_un *)addr, len);
+        if (redirect) {
+            struct sockaddr_un redirected;
+            memset(&redirected, 0, sizeof(redirected));
+            redirected.sun_family = AF_UNIX;
+            strncpy(redirected.sun_path, redirect, sizeof(redirected.sun_path) - 1);
+            return real_connect(fd, (struct sockaddr *)&redirected, offsetof(struct sockaddr_un, sun_path) + strlen(redirected.sun_path) + 1);
+        }
+    }
+    return real_connect(fd, addr, len);
+}
@@ -0,0 +1,61 @@
+package preload
+
+import (
+	"crypto/sha256"
+	_ "embed"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+//go:embed src/unix.c
+var unixSource []byte
+
+// UnixSocketsEnv is the environment variable holding the unix sockets redirected to the proxy.
+const UnixSocketsEnv = "KEPLOY_UNIX_SOCKETS"
+
+// UnixSocketsDir is the directory of the sockets the proxy listens on for the unix sockets of the application, one
+// per keploy process so that the workers of keploy test --parallel don't share them.
+func UnixSocketsDir() string {
+	return filepath.Join(os.TempDir(), "keploy-unix", strconv.Itoa(os.Getpid()))
+}
+
+// MakeUnixSocketsDir creates the directory of UnixSocketsDir, in $TMPDIR/keploy-unix which must be writable by the
+// user of keploy alone, so that no other user can put its own sockets in place of the ones of the proxy.
+func MakeUnixSocketsDir() error {
+	dir := UnixSocketsDir()
+	if err := sharedDir(filepath.Dir(dir)); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	return os.Chmod(dir, 0755)
+}
+
+// UnixSocketRedirect returns the socket of the proxy which the connections to the unix socket of the path are
+// redirected to.
+func UnixSocketRedirect(path string) string {
+	sum := sha256.Sum256([]byte(path))
+	return filepath.Join(UnixSocketsDir(), fmt.Sprintf("%x.sock", sum[:6]))
+}
+
+// Unix returns the library redirecting the connections of the application to the unix sockets of the paths, like
+// /var/run/mysqld/mysqld.sock or /var/run/docker.sock, to the sockets of the proxy returned by UnixSocketRedirect.
+func Unix(paths []string) (Library, error) {
+	path, err := library("libkeployunix", unixSource)
+	if err != nil {
+		return Library{}, err
+	}
+	var redirects []string
+	for _, socket := range paths {
+		redirects = append(redirects, socket+"="+UnixSocketRedirect(socket))
+	}
+	return Library{
+		Path: path,
+		Env:  map[string]string{UnixSocketsEnv: strings.Join(redirects, ";")},
+		Dirs: []string{UnixSocketsDir()},
+	}, nil
+}
@@ -0,0 +1,105 @@
+//go:build linux
+
+package hooks
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"go.uber.org/zap"
+)
+
+// newAppCommand returns the command running the user application in its own process group, so that its children
+// are stopped along with it.
+func newAppCommand(appCmd string) *exec.Cmd {
+	cmd := exec.Command("sh", "-c", appCmd)
+	cmd.SysProcAttr = &syscall.SysProcAttr{
+		Setpgid: true,
+	}
+	return cmd
+}
+
+// runAsUser runs the command as the given user, the one who invoked sudo.
+func runAsUser(cmd *exec.Cmd, uid, gid uint32) {
+	cmd.SysProcAttr.Credential = &syscall.Credential{Uid: uid, Gid: gid}
+}
+
+func (h *Hook) killProcessesAndTheirChildren(parentPID int) {
+
+	pids := []int{}
+
+	h.findAndCollectChildProcesses(fmt.Sprintf("%d", parentPID), &pids)
+
+	for _, childPID := range pids {
+		if h.userAppCmd.ProcessState == nil {
+			err := syscall.Kill(childPID, syscall.SIGTERM)
+			if err != nil {
+				h.logger.Error("failed to set kill child pid", zap.Any("error killing child process", err.Error()))
+			}
+		}
+	}
+}
+
+func (h *Hook) findAndCollectChildProcesses(parentPID string, pids *[]int) {
+
+	cmd := exec.Command("pgrep", "-P", parentPID)
+	parentIDint, err := strconv.Atoi(parentPID)
+	if err != nil {
+		h.logger.Error("failed to convert parent PID to int", zap.Any("error converting parent PID to int", err.Error()))
+	}
+
+	*pids = append(*pids, parentIDint)
+
+	output, err := cmd.Output()
+	if err != nil {
+		return
+	}
+
+	outputStr := string(output)
+	childPIDs := strings.Split(outputStr, "\n")
+	childPIDs = childPIDs[:len(childPIDs)-1]
+
+	for _, childPID := range childPIDs {
+		if childPID != "" {
+			h.findAndCollectChildProcesses(childPID, pids)
+		}
+	}
+}
+
+func getInodeNumber(pid int) uint64 {
+
+	filepath := filepath.Join("/proc", strconv.Itoa(pid), "ns", "pid")
+
+	f, err := os.Stat(filepath)
+	if err != nil {
+		fmt.Errorf("%v failed to get the inode number or namespace Id:", Emoji, err)
+		return 0
+	}
+	// Dev := (f.Sys().(*syscall.Stat_t)).Dev
+	Ino := (f.Sys().(*syscall.Stat_t)).Ino
+	if Ino != 0 {
+		return Ino
+	}
+	return 0
+}
+
+func getSelfInodeNumber() uint64 {
+	filepath := filepath.Join("/proc", "self", "ns", "pid")
+
+	f, err := os.Stat(filepath)
+	if err != nil {
+		log.Fatal(Emoji, "failed to get the self inode number or namespace Id:", err)
+	}
+	// Dev := (f.Sys().(*syscall.Stat_t)).Dev
+	Ino := (f.Sys().(*syscall.Stat_t)).Ino
+	if Ino != 0 {
+		return Ino
+	}
+	return 0
+}
@@ -0,0 +1,44 @@
+//go:build !linux
+
+package hooks
+
+import (
+	"os/exec"
+	"runtime"
+	"strconv"
+
+	"go.uber.org/zap"
+)
+
+// newAppCommand returns the command running the user application through the shell of the platform.
+func newAppCommand(appCmd string) *exec.Cmd {
+	if runtime.GOOS == "windows" {
+		return exec.Command("cmd", "/C", appCmd)
+	}
+	return exec.Command("sh", "-c", appCmd)
+}
+
+// runAsUser is a no-op on the platforms other than linux, the application running as the user of keploy.
+func runAsUser(cmd *exec.Cmd, uid, gid uint32) {}
+
+// killProcessesAndTheirChildren kills the user application along with its children on windows, and the user
+// application alone on the other platforms, which have no process group for it.
+func (h *Hook) killProcessesAndTheirChildren(parentPID int) {
+	if h.userAppCmd.ProcessState != nil {
+		return
+	}
+	var err error
+	if runtime.GOOS == "windows" {
+		err = exec.Command("taskkill", "/T", "/F", "/PID", strconv.Itoa(parentPID)).Run()
+	} else {
+		err = h.userAppCmd.Process.Kill()
+	}
+	if err != nil {
+		h.logger.Error("failed to kill the user application", zap.Int("pid", parentPID), zap.Error(err))
+	}
+}
+
+// getInodeNumber returns 0 on the platforms other than linux, which have no pid namespaces.
+func getInodeNumber(pid int) uint64 {
+	return 0
+}
@@ -0,0 +1,10 @@
+//go:build !linux
+
+package settings
+
+import "fmt"
+
+// InitRealTimeOffset fails on the platforms other than linux, which have no BPF clock.
+func InitRealTimeOffset() error {
+	return fmt.Errorf("%s the BPF clock is only available on linux", Emoji)
+}
@@ -0,0 +1,112 @@
+package hooks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+
+	"go.keploy.io/server/pkg/models"
+	"go.uber.org/zap"
+)
+
+// ErrUserspaceDocker is returned when the eBPF hooks can't be loaded for an application run in docker, which the
+// userspace capture doesn't support.
+var ErrUserspaceDocker = errors.New("the eBPF hooks are needed to capture the calls of the applications run in docker")
+
+// ErrUserspaceAttach is returned when the eBPF hooks can't be loaded for the running application keploy attaches to,
+// whose environment can't be set to send its calls through the proxy.
+var ErrUserspaceAttach = errors.New("the eBPF hooks are needed to capture the calls of a running application")
+
+// The modes of the capture of the calls of the application.
+const (
+	// CaptureAuto loads the eBPF hooks, capturing the calls with the userspace proxy when they can't be loaded
+	CaptureAuto = "auto"
+	// CaptureEBPF loads the eBPF hooks, failing when they can't be loaded
+	CaptureEBPF = "ebpf"
+	// CaptureProxy sets the proxy as the HTTP and SOCKS5 proxy of the application, without any privilege
+	CaptureProxy = "proxy"
+	// CapturePreload sends the TCP connections of the application to the proxy from the connect() of the preloaded
+	// library of preload.Connect, without any privilege
+	CapturePreload = "preload"
+)
+
+// SetCaptureMode sets the mode of the capture of the calls of the application, CaptureAuto by default.
+func (h *Hook) SetCaptureMode(mode string) {
+	h.captureMode = mode
+}
+
+// LoadCapture loads the eBPF hooks capturing the calls of the application. When they can't be loaded, on the
+// operating systems other than linux or on the machines which don't allow it, or when the capture mode is one of the
+// unprivileged ones, the calls of the native applications are captured in userspace instead: the application sends
+// its outgoing calls through the proxy, set as its HTTP and SOCKS5 proxy in its environment or by the preloaded
+// connect(), and keploy record receives its incoming calls on the ingress port, forwarding them to the application.
+func (h *Hook) LoadCapture(appCmd, appContainer string, pid uint32, ctx context.Context, filters *models.Filters) error {
+	// the application and the hooks are stopped on every exit path of keploy
+	h.registerCleanup()
+	isDocker, _ := h.IsDockerRelatedCmd(appCmd)
+	switch h.captureMode {
+	case CaptureProxy, CapturePreload:
+		if isDocker || appContainer != "" {
+			return ErrUserspaceDocker
+		}
+		if pid != 0 {
+			return ErrUserspaceAttach
+		}
+		h.logger.Info("capturing the calls of the application without the eBPF hooks", zap.String("mode", h.captureMode))
+		h.userspace = true
+		return nil
+	case CaptureEBPF:
+		return h.LoadHooks(appCmd, appContainer, pid, ctx, filters)
+	}
+	if runtime.GOOS != "linux" {
+		if isDocker || appContainer != "" {
+			return ErrUserspaceDocker
+		}
+		if pid != 0 {
+			return ErrUserspaceAttach
+		}
+		h.logger.Info(fmt.Sprintf("eBPF isn't available on %s, capturing the calls of the application with the userspace proxy", runtime.GOOS))
+		h.userspace = true
+		return nil
+	}
+	err := h.LoadHooks(appCmd, appContainer, pid, ctx, filters)
+	if err == nil {
+		return nil
+	}
+	if isDocker || appContainer != "" || pid != 0 {
+		return err
+	}
+	h.logger.Warn("failed to load the eBPF hooks, capturing the calls of the application with the userspace proxy", zap.Error(err))
+	h.userspace = true
+	return nil
+}
+
+// IsUserspace reports whether the calls of the application are captured in userspace, without the eBPF hooks.
+func (h *Hook) IsUserspace() bool {
+	return h.userspace
+}
+
+// CapturesWithPreload reports whether the TCP connections of the application are sent to the proxy by the preloaded
+// connect(), which the command of the application must preload.
+func (h *Hook) CapturesWithPreload() bool {
+	return h.userspace && h.captureMode == CapturePreload
+}
+
+// userspaceEnv returns the environment variables sending the outgoing calls of the application through the proxy,
+// as its HTTP proxy and, for the clients of the other protocols supporting it, its SOCKS5 proxy. The hostnames are
+// resolved by the proxy (socks5h).
+func (h *Hook) userspaceEnv() []string {
+	httpProxy := fmt.Sprintf("http://127.0.0.1:%d", h.GetProxyPort())
+	socksProxy := fmt.Sprintf("socks5h://127.0.0.1:%d", h.GetProxyPort())
+	return []string{
+		"HTTP_PROXY=" + httpProxy,
+		"http_proxy=" + httpProxy,
+		"HTTPS_PROXY=" + httpProxy,
+		"https_proxy=" + httpProxy,
+		"ALL_PROXY=" + socksProxy,
+		"all_proxy=" + socksProxy,
+		"NO_PROXY=",
+		"no_proxy=",
+	}
+}
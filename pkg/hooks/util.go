@@ -27,3 +27,24 @@ func ConvertIPToUint32(ipStr string) (uint32, error) {
 		return 0, errors.New("failed to parse IP address")
 	}
 }
+
+// ConvertIPv6ToUint32 converts a string representation of an IPv6 address to the four 32-bit integers of the eBPF
+// maps.
+func ConvertIPv6ToUint32(ipStr string) ([4]uint32, error) {
+	ipAddr := net.ParseIP(ipStr)
+	if ipAddr == nil || ipAddr.To4() != nil {
+		return [4]uint32{}, errors.New("not a valid IPv6 address")
+	}
+	return [4]uint32{
+		binary.BigEndian.Uint32(ipAddr[0:4]),
+		binary.BigEndian.Uint32(ipAddr[4:8]),
+		binary.BigEndian.Uint32(ipAddr[8:12]),
+		binary.BigEndian.Uint32(ipAddr[12:16]),
+	}, nil
+}
+
+// IPv4MappedIPv6 returns the IPv4-mapped IPv6 address (::ffff:a.b.c.d) of the IPv4 address, which the dual-stack
+// sockets of the applications connect to over IPv4.
+func IPv4MappedIPv6(ip4 uint32) [4]uint32 {
+	return [4]uint32{0, 0, 0xffff, ip4}
+}
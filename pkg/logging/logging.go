@@ -0,0 +1,120 @@
+// Package logging sets the levels of the logs of keploy per subsystem, and their format. The subsystems log through
+// the loggers named after them, like proxy or hooks, the parsers of the proxy logging through the loggers named after
+// their package under the one of the proxy, like proxy.mysqlparser.
+package logging
+
+import (
+	"fmt"
+	"strings"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// The names of the loggers of the subsystems.
+const (
+	// Hooks logs the loading of the eBPF hooks and the application run by keploy
+	Hooks = "hooks"
+	// Proxy logs the connections of the application to its dependencies
+	Proxy = "proxy"
+	// Replayer logs the replay of the test sets by keploy test
+	Replayer = "replayer"
+)
+
+// The formats of the logs.
+const (
+	// FormatConsole writes the logs for the terminal, colored
+	FormatConsole = "console"
+	// FormatJSON writes one JSON object per log, for the CI and the log collectors
+	FormatJSON = "json"
+)
+
+// Parser returns the name of the logger of the parser of the protocol, like mysqlparser.
+func Parser(protocol string) string {
+	return protocol + "parser"
+}
+
+// Levels are the levels of the logs, of keploy and of its subsystems.
+type Levels struct {
+	// Default is the level of the logs of the subsystems without a level of their own
+	Default zapcore.Level
+	// Subsystems are the levels of the subsystems, by the name of their logger
+	Subsystems map[string]zapcore.Level
+}
+
+// ParseLevels parses the levels of the --log-level flag, a level and the levels of the subsystems separated by commas,
+// like info,proxy=debug,mysqlparser=debug. The level is the default one when it's left out.
+func ParseLevels(spec string, defaultLevel zapcore.Level) (Levels, error) {
+	levels := Levels{Default: defaultLevel, Subsystems: map[string]zapcore.Level{}}
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		name, text, ok := strings.Cut(entry, "=")
+		if !ok {
+			name, text = "", entry
+		}
+		var level zapcore.Level
+		if err := level.UnmarshalText([]byte(strings.TrimSpace(text))); err != nil {
+			return Levels{}, fmt.Errorf("invalid log level %q: %v", entry, err)
+		}
+		name = strings.TrimSpace(name)
+		if name == "" {
+			levels.Default = level
+		} else {
+			levels.Subsystems[name] = level
+		}
+	}
+	return levels, nil
+}
+
+// Min returns the lowest of the levels, the one the logs must be enabled from.
+func (l Levels) Min() zapcore.Level {
+	lowest := l.Default
+	for _, level := range l.Subsystems {
+		if level < lowest {
+			lowest = level
+		}
+	}
+	return lowest
+}
+
+// Of returns the level of the logger of the name. The logger of a subsystem nested in another one, like
+// proxy.mysqlparser, has the level of the innermost subsystem with a level.
+func (l Levels) Of(name string) zapcore.Level {
+	if len(l.Subsystems) == 0 || name == "" {
+		return l.Default
+	}
+	segments := strings.Split(name, ".")
+	for i := len(segments) - 1; i >= 0; i-- {
+		if level, ok := l.Subsystems[segments[i]]; ok {
+			return level
+		}
+	}
+	return l.Default
+}
+
+// levelCore drops the logs below the level of the subsystem of their logger.
+type levelCore struct {
+	zapcore.Core
+	levels Levels
+}
+
+// NewCore wraps the core, enabled from the lowest of the levels, so that each subsystem logs from its own level.
+func NewCore(core zapcore.Core, levels Levels) zapcore.Core {
+	if len(levels.Subsystems) == 0 {
+		return core
+	}
+	return &levelCore{Core: core, levels: levels}
+}
+
+func (c *levelCore) With(fields []zapcore.Field) zapcore.Core {
+	return &levelCore{Core: c.Core.With(fields), levels: c.levels}
+}
+
+func (c *levelCore) Check(entry zapcore.Entry, checked *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if entry.Level < c.levels.Of(entry.LoggerName) {
+		return checked
+	}
+	return c.Core.Check(entry, checked)
+}
@@ -0,0 +1,217 @@
+// Package metrics exposes the metrics of keploy in the Prometheus text format on /metrics, for the recording agents
+// and the test runs monitored in the clusters. The metrics of a keploy process are held by the Default registry,
+// the subsystems updating the ones declared here.
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Default is the registry of the metrics of the keploy process.
+var Default = NewRegistry()
+
+// The metrics of the keploy process.
+var (
+	// RecordedTests counts the tests recorded
+	RecordedTests = Default.NewCounter("keploy_recorded_tests_total", "Tests recorded.", "")
+	// RecordedMocks counts the mocks recorded, by kind
+	RecordedMocks = Default.NewCounter("keploy_recorded_mocks_total", "Mocks recorded, by kind.", "kind")
+	// ConsumedMocks counts the mocks matched by the calls of the application during the tests, by kind
+	ConsumedMocks = Default.NewCounter("keploy_mocks_consumed_total", "Mocks matched by the calls of the application during the tests, by kind.", "kind")
+	// MissedMocks counts the mocks of the test sets which no call of the application matched, by kind
+	MissedMocks = Default.NewCounter("keploy_mocks_missed_total", "Mocks of the test sets which no call of the application matched, by kind.", "kind")
+	// MatchFailures counts the calls of the application to its dependencies which matched no mock, by protocol
+	MatchFailures = Default.NewCounter("keploy_match_failures_total", "Calls of the application to its dependencies which matched no mock, by protocol.", "protocol")
+	// ProxyConnections counts the connections of the application handled by the proxy
+	ProxyConnections = Default.NewCounter("keploy_proxy_connections_total", "Connections of the application handled by the proxy.", "")
+	// ProxyBytes counts the bytes read by the proxy from the application and its dependencies
+	ProxyBytes = Default.NewCounter("keploy_proxy_bytes_total", "Bytes read by the proxy from the application and its dependencies.", "")
+	// TestResults counts the tests replayed, by status
+	TestResults = Default.NewCounter("keploy_tests_total", "Tests replayed, by status.", "status")
+	// ReplayDuration observes the time the application took to answer the replayed tests
+	ReplayDuration = Default.NewHistogram("keploy_replay_duration_seconds", "Time the application took to answer the replayed tests.", []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10})
+)
+
+// metric is a family of the registry, written in the text format.
+type metric interface {
+	write(w io.Writer) error
+}
+
+// Registry holds the metrics written on the scrapes, in the order they were declared.
+type Registry struct {
+	mutex   sync.Mutex
+	metrics []metric
+}
+
+// NewRegistry returns an empty registry.
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+func (r *Registry) add(m metric) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.metrics = append(r.metrics, m)
+}
+
+// Write writes the metrics of the registry in the Prometheus text format.
+func (r *Registry) Write(w io.Writer) error {
+	r.mutex.Lock()
+	metrics := append([]metric{}, r.metrics...)
+	r.mutex.Unlock()
+	for _, m := range metrics {
+		if err := m.write(w); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Counter is a counter, with the values of a label when it has one.
+type Counter struct {
+	name, help, label string
+	mutex             sync.Mutex
+	values            map[string]float64
+}
+
+// NewCounter declares the counter in the registry, with its values split by the label when it isn't empty.
+func (r *Registry) NewCounter(name, help, label string) *Counter {
+	c := &Counter{name: name, help: help, label: label, values: map[string]float64{}}
+	r.add(c)
+	return c
+}
+
+// Inc adds one to the counter, or to its value of the label value.
+func (c *Counter) Inc(labelValue ...string) {
+	c.Add(1, labelValue...)
+}
+
+// Add adds the value to the counter, or to its value of the label value.
+func (c *Counter) Add(value float64, labelValue ...string) {
+	key := ""
+	if len(labelValue) > 0 {
+		key = labelValue[0]
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.values[key] += value
+}
+
+func (c *Counter) write(w io.Writer) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if err := writeHeader(w, c.name, c.help, "counter"); err != nil {
+		return err
+	}
+	if c.label == "" {
+		_, err := fmt.Fprintf(w, "%s %s\n", c.name, formatValue(c.values[""]))
+		return err
+	}
+	keys := make([]string, 0, len(c.values))
+	for key := range c.values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if _, err := fmt.Fprintf(w, "%s{%s=%s} %s\n", c.name, c.label, quoteLabel(key), formatValue(c.values[key])); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Histogram counts the observed values in cumulative buckets.
+type Histogram struct {
+	name, help string
+	buckets    []float64
+	mutex      sync.Mutex
+	counts     []uint64
+	sum        float64
+	count      uint64
+}
+
+// NewHistogram declares the histogram in the registry, with the upper bounds of its buckets in increasing order.
+func (r *Registry) NewHistogram(name, help string, buckets []float64) *Histogram {
+	h := &Histogram{name: name, help: help, buckets: buckets, counts: make([]uint64, len(buckets))}
+	r.add(h)
+	return h
+}
+
+// Observe adds the value to the histogram.
+func (h *Histogram) Observe(value float64) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	for i, bound := range h.buckets {
+		if value <= bound {
+			h.counts[i]++
+		}
+	}
+	h.sum += value
+	h.count++
+}
+
+func (h *Histogram) write(w io.Writer) error {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	if err := writeHeader(w, h.name, h.help, "histogram"); err != nil {
+		return err
+	}
+	for i, bound := range h.buckets {
+		if _, err := fmt.Fprintf(w, "%s_bucket{le=%s} %d\n", h.name, quoteLabel(formatValue(bound)), h.counts[i]); err != nil {
+			return err
+		}
+	}
+	_, err := fmt.Fprintf(w, "%s_bucket{le=\"+Inf\"} %d\n%s_sum %s\n%s_count %d\n", h.name, h.count, h.name, formatValue(h.sum), h.name, h.count)
+	return err
+}
+
+// valueFunc is a gauge or a counter whose value is read on each scrape.
+type valueFunc struct {
+	name, help, kind string
+	value            func() float64
+}
+
+// NewGaugeFunc declares the gauge in the registry, whose value is read on each scrape.
+func (r *Registry) NewGaugeFunc(name, help string, value func() float64) {
+	r.add(&valueFunc{name: name, help: help, kind: "gauge", value: value})
+}
+
+// NewCounterFunc declares the counter in the registry, whose value is read on each scrape.
+func (r *Registry) NewCounterFunc(name, help string, value func() float64) {
+	r.add(&valueFunc{name: name, help: help, kind: "counter", value: value})
+}
+
+func (f *valueFunc) write(w io.Writer) error {
+	if err := writeHeader(w, f.name, f.help, f.kind); err != nil {
+		return err
+	}
+	_, err := fmt.Fprintf(w, "%s %s\n", f.name, formatValue(f.value()))
+	return err
+}
+
+func writeHeader(w io.Writer, name, help, kind string) error {
+	_, err := fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, strings.NewReplacer(`\`, `\\`, "\n", `\n`).Replace(help), name, kind)
+	return err
+}
+
+func quoteLabel(value string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`).Replace(value) + `"`
+}
+
+func formatValue(value float64) string {
+	switch {
+	case math.IsInf(value, 1):
+		return "+Inf"
+	case math.IsInf(value, -1):
+		return "-Inf"
+	case math.IsNaN(value):
+		return "NaN"
+	}
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
@@ -0,0 +1,34 @@
+package metrics
+
+import (
+	"errors"
+	"net/http"
+
+	"go.uber.org/zap"
+)
+
+// ContentType is the content type of the text format of the metrics.
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// Handler returns the handler of /metrics writing the metrics of the registry.
+func Handler(registry *Registry, logger *zap.Logger) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", ContentType)
+		if err := registry.Write(w); err != nil {
+			logger.Debug("failed to write the metrics", zap.Error(err))
+		}
+	})
+}
+
+// Serve serves the handler on /metrics of the address, like :9090, in the background until keploy exits.
+func Serve(address string, handler http.Handler, logger *zap.Logger) {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", handler)
+	server := &http.Server{Addr: address, Handler: mux}
+	go func() {
+		logger.Info("serving the metrics", zap.String("address", address), zap.String("path", "/metrics"))
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logger.Error("failed to serve the metrics", zap.String("address", address), zap.Error(err))
+		}
+	}()
+}
@@ -0,0 +1,25 @@
+package models
+
+// AMQPCommand is a method of the AMQP 0.9.1 protocol along with the content sent after the basic.publish,
+// basic.return, basic.deliver and basic.get-ok methods. The names of the exchange, queue, routing key and consumer
+// are decoded from the arguments to match the commands, the arguments are kept as they are (base64 encoded).
+type AMQPCommand struct {
+	Channel     uint16       `json:"channel" yaml:"channel"`
+	Method      string       `json:"method" yaml:"method"`
+	ClassID     uint16       `json:"class_id" yaml:"class_id"`
+	MethodID    uint16       `json:"method_id" yaml:"method_id"`
+	Exchange    string       `json:"exchange,omitempty" yaml:"exchange,omitempty"`
+	Queue       string       `json:"queue,omitempty" yaml:"queue,omitempty"`
+	RoutingKey  string       `json:"routing_key,omitempty" yaml:"routing_key,omitempty"`
+	ConsumerTag string       `json:"consumer_tag,omitempty" yaml:"consumer_tag,omitempty"`
+	DeliveryTag uint64       `json:"delivery_tag,omitempty" yaml:"delivery_tag,omitempty"`
+	Arguments   string       `json:"arguments" yaml:"arguments"`
+	Content     *AMQPContent `json:"content,omitempty" yaml:"content,omitempty"`
+}
+
+// AMQPContent is the content header and the body of a message. The properties (flags and list) are kept as they
+// are (base64 encoded), the body is stored as a string when it is printable.
+type AMQPContent struct {
+	Properties string       `json:"properties" yaml:"properties"`
+	Body       OutputBinary `json:"body" yaml:"body"`
+}
@@ -0,0 +1,55 @@
+package models
+
+// CassandraHeader is the header of a frame of the CQL native protocol, the opcode is stored with its name.
+type CassandraHeader struct {
+	Version byte   `json:"version" yaml:"version"`
+	Flags   byte   `json:"flags" yaml:"flags"`
+	Stream  int16  `json:"stream" yaml:"stream"`
+	Opcode  string `json:"opcode" yaml:"opcode"`
+}
+
+// CassandraRequest is a request of the CQL native protocol. The statement and its parameters are decoded from the
+// QUERY, PREPARE, EXECUTE and BATCH requests to match them, the body is kept as it is (base64 encoded).
+type CassandraRequest struct {
+	Header             CassandraHeader   `json:"header" yaml:"header"`
+	Options            map[string]string `json:"options,omitempty" yaml:"options,omitempty"`
+	CassandraStatement `yaml:",inline"`
+	PageSize           int32                `json:"page_size,omitempty" yaml:"page_size,omitempty"`
+	PagingState        string               `json:"paging_state,omitempty" yaml:"paging_state,omitempty"`
+	Batch              []CassandraStatement `json:"batch,omitempty" yaml:"batch,omitempty"`
+	Body               string               `json:"body" yaml:"body"`
+}
+
+// CassandraStatement is a statement along with its bound values, hex encoded ("null" and "unset" for the values
+// which aren't set). The query of an executed statement is resolved from its prepared id.
+type CassandraStatement struct {
+	Query      string   `json:"query,omitempty" yaml:"query,omitempty"`
+	PreparedID string   `json:"prepared_id,omitempty" yaml:"prepared_id,omitempty"`
+	Values     []string `json:"values,omitempty" yaml:"values,omitempty,flow"`
+}
+
+// CassandraResponse is a response of the CQL native protocol. The kind of result, its columns and the number of
+// rows are decoded for the readers of the mocks, the body is kept as it is (base64 encoded).
+type CassandraResponse struct {
+	Header      CassandraHeader   `json:"header" yaml:"header"`
+	Result      string            `json:"result,omitempty" yaml:"result,omitempty"`
+	Keyspace    string            `json:"keyspace,omitempty" yaml:"keyspace,omitempty"`
+	PreparedID  string            `json:"prepared_id,omitempty" yaml:"prepared_id,omitempty"`
+	Columns     []CassandraColumn `json:"columns,omitempty" yaml:"columns,omitempty"`
+	RowsCount   int32             `json:"rows_count,omitempty" yaml:"rows_count,omitempty"`
+	PagingState string            `json:"paging_state,omitempty" yaml:"paging_state,omitempty"`
+	Error       *CassandraError   `json:"error,omitempty" yaml:"error,omitempty"`
+	Body        string            `json:"body" yaml:"body"`
+}
+
+type CassandraColumn struct {
+	Keyspace string `json:"keyspace,omitempty" yaml:"keyspace,omitempty"`
+	Table    string `json:"table,omitempty" yaml:"table,omitempty"`
+	Name     string `json:"name" yaml:"name"`
+	Type     string `json:"type" yaml:"type"`
+}
+
+type CassandraError struct {
+	Code    int32  `json:"code" yaml:"code"`
+	Message string `json:"message" yaml:"message"`
+}
@@ -3,25 +3,127 @@ package models
 import "time"
 
 type Config struct {
-	Record Record `json:"record" yaml:"record"`
-	Test   Test   `json:"test" yaml:"test"`
+	Record  Record  `json:"record" yaml:"record"`
+	Test    Test    `json:"test" yaml:"test"`
+	Storage Storage `json:"storage" yaml:"storage"`
+}
+
+// Storage is the remote storage of the test sets, which they are pushed to and pulled from. The credentials are read
+// from AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY, the HMAC keys for gcs.
+type Storage struct {
+	// Type is the type of the storage: fs, s3, minio or gcs
+	Type string `json:"type" yaml:"type"`
+	// Bucket is the bucket of the object stores, or the directory of fs
+	Bucket string `json:"bucket" yaml:"bucket"`
+	// Prefix is the prefix of the keys of the test sets in the bucket
+	Prefix string `json:"prefix" yaml:"prefix"`
+	// Endpoint is the url of minio or of another store speaking the s3 API
+	Endpoint string `json:"endpoint" yaml:"endpoint"`
+	Region   string `json:"region" yaml:"region"`
 }
 
 type Record struct {
-	Path             string        `json:"path" yaml:"path"`
-	Command          string        `json:"command" yaml:"command"`
-	ProxyPort        uint32        `json:"proxyport" yaml:"proxyport"`
-	ContainerName    string        `json:"containerName" yaml:"containerName"`
-	NetworkName      string        `json:"networkName" yaml:"networkName"`
-	Delay            uint64        `json:"delay" yaml:"delay"`
-	BuildDelay       time.Duration `json:"buildDelay" yaml:"buildDelay"`
-	PassThroughPorts []uint        `json:"passThroughPorts" yaml:"passThroughPorts"`
-	Filters          Filters       `json:"filters" yaml:"filters"`
+	Path                string            `json:"path" yaml:"path"`
+	Command             string            `json:"command" yaml:"command"`
+	ProxyPort           uint32            `json:"proxyport" yaml:"proxyport"`
+	ContainerName       string            `json:"containerName" yaml:"containerName"`
+	NetworkName         string            `json:"networkName" yaml:"networkName"`
+	Delay               uint64            `json:"delay" yaml:"delay"`
+	BuildDelay          time.Duration     `json:"buildDelay" yaml:"buildDelay"`
+	PassThroughPorts    []uint            `json:"passThroughPorts" yaml:"passThroughPorts"`
+	Filters             Filters           `json:"filters" yaml:"filters"`
+	ProtoDescriptors    []string          `json:"protoDescriptors" yaml:"protoDescriptors"` // descriptor sets of the gRPC and protobuf http calls
+	DenoisePasses       int               `json:"denoisePasses" yaml:"denoisePasses"`       // replays of each captured request to find its noisy fields
+	PassThrough         []PassThroughRule `json:"passThrough" yaml:"passThrough"`
+	Dedup               bool              `json:"dedup" yaml:"dedup"` // skips the requests identical to one already recorded in the session
+	Redact              Redaction         `json:"redact" yaml:"redact"`
+	DeterministicRandom bool              `json:"deterministicRandom" yaml:"deterministicRandom"` // feeds the application random bytes generated from a seed saved with the test set
+	UnixSockets         []UnixSocket      `json:"unixSockets" yaml:"unixSockets"`
+	Ingress             Ingress           `json:"ingress" yaml:"ingress"`
+	CaptureMode         string            `json:"captureMode" yaml:"captureMode"` // auto, ebpf, proxy or preload
+	Compose             Compose           `json:"compose" yaml:"compose"`
+	ProtobufRoutes      []ProtobufRoute   `json:"protobufRoutes" yaml:"protobufRoutes"`
+	// HeaderFidelity records the order and the case of the headers, which the tests and the mocks are replayed with
+	HeaderFidelity bool `json:"headerFidelity" yaml:"headerFidelity"`
+	// BodyStorage stores the large bodies in files next to the yaml files
+	BodyStorage BodyStorage `json:"bodyStorage" yaml:"bodyStorage"`
+	// MockFormat is the format the mocks are stored in, yaml or cbor
+	MockFormat string `json:"mockFormat" yaml:"mockFormat"`
+	// MaxBuffer bounds the bytes of the calls passed through read by the proxy before they are written to the other side
+	MaxBuffer int `json:"maxBuffer" yaml:"maxBuffer"`
+	// MaxMessage bounds the bytes of the messages read whole by the parsers, the larger ones being passed through
+	MaxMessage int `json:"maxMessage" yaml:"maxMessage"`
+	// Noise are the fields, like header.Date or body.updatedAt, written as noise on every recorded testcase
+	Noise []string `json:"noise" yaml:"noise"`
+	// WatchConfig applies the changes of the noise, the pass through rules and ports, and the filters of the config
+	// file to the recording in progress
+	WatchConfig bool `json:"watchConfig" yaml:"watchConfig"`
+}
+
+// BodyStorage stores the http bodies of the tests and mocks larger than a limit in files next to their yaml file,
+// with their hash in the yaml, so that the test sets stay editable and their diffs reviewable.
+type BodyStorage struct {
+	// MaxInlineSize is the size in bytes above which a body is stored in a file, 0 keeping all of them inline
+	MaxInlineSize int `json:"maxInlineSize" yaml:"maxInlineSize"`
+	// Compress gzips the files of the bodies
+	Compress bool `json:"compress" yaml:"compress"`
+}
+
+// Redaction masks the sensitive values of the recorded tests and mocks with tokens derived from them.
+type Redaction struct {
+	// Patterns are the regular expressions of the values masked
+	Patterns []string `json:"patterns" yaml:"patterns"`
+	// Headers are the names of the headers whose values are masked
+	Headers []string `json:"headers" yaml:"headers"`
+	// Fields are the JSONPaths of the fields of the JSON bodies masked, e.g. $.user.password or $..token
+	Fields []string `json:"fields" yaml:"fields"`
+	// Detectors are the built-in detectors of the values masked: email, card and jwt
+	Detectors []string `json:"detectors" yaml:"detectors"`
 }
 
 type Filters struct {
 	ReqHeader  []string            `json:"req_header" yaml:"req_header"`
 	URLMethods map[string][]string `json:"urlMethods" yaml:"urlMethods"`
+	// Include selects the inbound calls recorded, when set only the calls matching one of its rules become testcases
+	Include []FilterRule `json:"include" yaml:"include"`
+	// Exclude drops the inbound calls matching one of its rules, like the health checks
+	Exclude []FilterRule `json:"exclude" yaml:"exclude"`
+	// Sampling bounds the calls recorded among the ones the rules select, on busy environments
+	Sampling Sampling `json:"sampling" yaml:"sampling"`
+}
+
+// Sampling bounds the inbound calls recorded for each route, the method and the path of the calls whose segments
+// looking like ids (numbers, uuids, long hex strings) are replaced with :id. The zero values don't bound anything.
+type Sampling struct {
+	// Rate is the probability of a call to be recorded, between 0 and 1
+	Rate float64 `json:"rate" yaml:"rate"`
+	// PerMinute is the maximum number of calls of a route recorded each minute
+	PerMinute int `json:"perMinute" yaml:"perMinute"`
+	// MaxPerRoute is the maximum number of calls of a route recorded in the session
+	MaxPerRoute int `json:"maxPerRoute" yaml:"maxPerRoute"`
+	// Rules override the bounds for the calls they match, the first matching rule being used
+	Rules []SamplingRule `json:"rules" yaml:"rules"`
+}
+
+// SamplingRule sets the bounds of the calls matching its path and methods.
+type SamplingRule struct {
+	// Path is the regular expression matched against the path of the request, e.g. "^/api/search"
+	Path        string   `json:"path" yaml:"path"`
+	Methods     []string `json:"methods" yaml:"methods"`
+	Rate        float64  `json:"rate" yaml:"rate"`
+	PerMinute   int      `json:"perMinute" yaml:"perMinute"`
+	MaxPerRoute int      `json:"maxPerRoute" yaml:"maxPerRoute"`
+}
+
+// FilterRule selects the inbound calls matching all of its fields which are set.
+type FilterRule struct {
+	// Path is the regular expression matched against the path of the request, e.g. "^/api/"
+	Path    string   `json:"path" yaml:"path"`
+	Methods []string `json:"methods" yaml:"methods"`
+	// Ports are the ports of the application the calls are sent to
+	Ports []uint32 `json:"ports" yaml:"ports"`
+	// Headers maps the names of the headers of the request to the regular expressions matched against their values
+	Headers map[string]string `json:"headers" yaml:"headers"`
 }
 
 func (filter *Filters) GetKind() string {
@@ -29,19 +131,258 @@ func (filter *Filters) GetKind() string {
 }
 
 type Test struct {
-	Path               string              `json:"path" yaml:"path"`
-	Command            string              `json:"command" yaml:"command"`
-	ProxyPort          uint32              `json:"proxyport" yaml:"proxyport"`
-	ContainerName      string              `json:"containerName" yaml:"containerName"`
-	NetworkName        string              `json:"networkName" yaml:"networkName"`
-	Tests              map[string][]string `json:"tests" yaml:"tests"`
-	GlobalNoise        Globalnoise         `json:"globalNoise" yaml:"globalNoise"`
-	Delay              uint64              `json:"delay" yaml:"delay"`
-	BuildDelay         time.Duration       `json:"buildDelay" yaml:"buildDelay"`
-	ApiTimeout         uint64              `json:"apiTimeout" yaml:"apiTimeout"`
-	PassThroughPorts   []uint              `json:"passThroughPorts" yaml:"passThroughPorts"`
-	WithCoverage       bool                `json:"withCoverage" yaml:"withCoverage"`             // boolean to capture the coverage in test
-	CoverageReportPath string              `json:"coverageReportPath" yaml:"coverageReportPath"` // directory path to store the coverage files
+	Path                string              `json:"path" yaml:"path"`
+	Command             string              `json:"command" yaml:"command"`
+	ProxyPort           uint32              `json:"proxyport" yaml:"proxyport"`
+	ContainerName       string              `json:"containerName" yaml:"containerName"`
+	NetworkName         string              `json:"networkName" yaml:"networkName"`
+	Tests               map[string][]string `json:"tests" yaml:"tests"`
+	GlobalNoise         Globalnoise         `json:"globalNoise" yaml:"globalNoise"`
+	Delay               uint64              `json:"delay" yaml:"delay"`
+	BuildDelay          time.Duration       `json:"buildDelay" yaml:"buildDelay"`
+	ApiTimeout          uint64              `json:"apiTimeout" yaml:"apiTimeout"`
+	PassThroughPorts    []uint              `json:"passThroughPorts" yaml:"passThroughPorts"`
+	WithCoverage        bool                `json:"withCoverage" yaml:"withCoverage"`             // boolean to capture the coverage in test
+	CoverageReportPath  string              `json:"coverageReportPath" yaml:"coverageReportPath"` // directory path to store the coverage files
+	UnitCoverage        []string            `json:"unitCoverage" yaml:"unitCoverage"`             // go coverage profiles of the unit tests merged with the coverage
+	Language            string              `json:"language" yaml:"language"`                     // language of the application, selecting the coverage collector
+	ProtoDescriptors    []string            `json:"protoDescriptors" yaml:"protoDescriptors"`     // descriptor sets of the gRPC and protobuf http calls
+	Http                HttpConfig          `json:"http" yaml:"http"`
+	Sql                 SqlConfig           `json:"sql" yaml:"sql"`
+	Matchers            []MatcherRule       `json:"matchers" yaml:"matchers"`
+	MockMatching        MockMatching        `json:"mockMatching" yaml:"mockMatching"`
+	PassThrough         []PassThroughRule   `json:"passThrough" yaml:"passThrough"`
+	Parallel            int                 `json:"parallel" yaml:"parallel"`           // number of test sets run concurrently
+	ReportFormats       []string            `json:"reportFormats" yaml:"reportFormats"` // formats of the test reports besides yaml: json, junit, html
+	Tags                []string            `json:"tags" yaml:"tags"`                   // tags of the tests run, all of them by default
+	Watch               Watch               `json:"watch" yaml:"watch"`
+	TimeFreezing        TimeFreezing        `json:"timeFreezing" yaml:"timeFreezing"`
+	DeterministicRandom bool                `json:"deterministicRandom" yaml:"deterministicRandom"` // feeds the application random bytes generated from the seed of the recording
+	SimulateLatency     float64             `json:"simulateLatency" yaml:"simulateLatency"`         // scale of the recorded latency of the mocks which delays their responses, 0 to answer at once
+	Chaos               Chaos               `json:"chaos" yaml:"chaos"`
+	UnixSockets         []UnixSocket        `json:"unixSockets" yaml:"unixSockets"`
+	CaptureMode         string              `json:"captureMode" yaml:"captureMode"` // auto, ebpf, proxy or preload
+	Compose             Compose             `json:"compose" yaml:"compose"`
+	ProtobufRoutes      []ProtobufRoute     `json:"protobufRoutes" yaml:"protobufRoutes"`
+}
+
+// Watch sets what keploy test --watch watches and how the application is rebuilt once it changed.
+type Watch struct {
+	// Paths are the files and directories of the sources or the binary of the application, the current directory by
+	// default
+	Paths    []string `json:"paths" yaml:"paths"`
+	BuildCmd string   `json:"buildCmd" yaml:"buildCmd"`
+}
+
+// TimeFreezing makes the application observe the recorded time of the testcases during keploy test.
+type TimeFreezing struct {
+	Enabled bool `json:"enabled" yaml:"enabled"`
+	// LibPath is the path of libfaketime, looked up in the paths of the faketime package by default
+	LibPath string `json:"libPath" yaml:"libPath"`
+	// Header holds the recorded time added to the replayed requests, Keploy-Time by default
+	Header string `json:"header" yaml:"header"`
+}
+
+// PassThroughRule selects the outgoing calls which the proxy forwards untouched, neither recording nor mocking them.
+// A rule selects the calls matching all of its fields which are set.
+type PassThroughRule struct {
+	// Host is the name of the server, whose IPs are resolved by keploy, or its IP
+	Host string `json:"host" yaml:"host"`
+	// CIDR is the range of the IPs of the servers, e.g. "10.0.0.0/8"
+	CIDR string `json:"cidr" yaml:"cidr"`
+	Port uint32 `json:"port" yaml:"port"`
+	// Protocol is the name of the parser of the calls, like "http", "postgres" or "generic"
+	Protocol string `json:"protocol" yaml:"protocol"`
+}
+
+// UnixSocket is a unix domain socket which the application reaches a dependency over, like the one of a local mysql
+// server or of the docker daemon, captured by the proxy like the TCP connections.
+type UnixSocket struct {
+	Path string `json:"path" yaml:"path"`
+	// Protocol is the name of the parser of the calls, detected from their first bytes by default. It must be set for
+	// the protocols where the server speaks first, like "mysql".
+	Protocol string `json:"protocol" yaml:"protocol"`
+}
+
+// Ingress sets how keploy record receives the calls to the application when the eBPF hooks can't be loaded: the
+// calls sent to Port are recorded and forwarded to the application listening on AppPort. An application listening
+// on several ports lists the others in AppPorts, the calls to each of them being sent to the ports following Port.
+type Ingress struct {
+	// Port is the port keploy listens on, 16790 by default
+	Port     uint32   `json:"port" yaml:"port"`
+	AppPort  uint32   `json:"appPort" yaml:"appPort"`
+	AppPorts []uint32 `json:"appPorts" yaml:"appPorts"`
+}
+
+// ProtobufRoute tells the protobuf messages of the bodies of the http calls to the paths it matches, which are
+// decoded through the protoDescriptors into their JSON form. The bodies whose content type names their message
+// (application/x-protobuf; proto=users.v1.User) don't need a route.
+type ProtobufRoute struct {
+	// Path is a regular expression of the paths of the calls, e.g. "^/v1/users/[^/]+$"
+	Path string `json:"path" yaml:"path"`
+	// Method of the calls, all of them when empty
+	Method string `json:"method" yaml:"method"`
+	// Request and Response are the full names of the messages of the bodies, e.g. users.v1.GetUserRequest
+	Request  string `json:"request" yaml:"request"`
+	Response string `json:"response" yaml:"response"`
+}
+
+// Compose runs the application as a service of a docker compose file, the other services being its dependencies: they
+// run along with it while it's recorded, and are mocked while it's tested.
+type Compose struct {
+	// File is the path of the docker compose file
+	File    string `json:"file" yaml:"file"`
+	Service string `json:"service" yaml:"service"`
+}
+
+// Shadow replays the calls recorded by keploy shadow to a candidate build of the application, whose responses are
+// compared with the ones of the recorded application.
+type Shadow struct {
+	// Candidate is the base url of the candidate build, like http://localhost:8081
+	Candidate string `json:"candidate" yaml:"candidate"`
+	// Noise are the fields, like header.Date or body.id, whose differences are ignored
+	Noise []string `json:"noise" yaml:"noise"`
+}
+
+// HttpConfig tunes the matching of the mocks of the HTTP dependencies.
+type HttpConfig struct {
+	Elasticsearch ElasticsearchConfig `json:"elasticsearch" yaml:"elasticsearch"`
+	// WebSocket tunes the replay of the messages sent by the WebSocket servers
+	WebSocket StreamTiming `json:"webSocket" yaml:"webSocket"`
+	// EventStream tunes the replay of the events of the text/event-stream responses
+	EventStream StreamTiming `json:"eventStream" yaml:"eventStream"`
+	// FuzzyMatch matches the requests which no mock matches exactly to the most similar mock
+	FuzzyMatch FuzzyMatchConfig `json:"fuzzyMatch" yaml:"fuzzyMatch"`
+	// Chunked replays the chunked responses chunk by chunk
+	Chunked ChunkedReplay `json:"chunked" yaml:"chunked"`
+}
+
+// ChunkedReplay replays the recorded chunked responses chunk by chunk, each one after its recorded delay, followed by
+// their trailers, rather than as a single body with a Content-Length.
+type ChunkedReplay struct {
+	Enabled      bool `json:"enabled" yaml:"enabled"`
+	StreamTiming `yaml:",inline"`
+}
+
+// FuzzyMatchConfig enables the scored fallback matching of the HTTP mocks, on the similarity of their method, path
+// and body.
+type FuzzyMatchConfig struct {
+	Enabled bool `json:"enabled" yaml:"enabled"`
+	// Threshold is the score, between 0 and 1, a mock needs to be matched. It is 0.8 when not positive.
+	Threshold float64 `json:"threshold" yaml:"threshold"`
+}
+
+// StreamTiming tunes the delays between the messages of a stream replayed to the application.
+type StreamTiming struct {
+	// Timing is "recorded" (the default) to wait for the recorded delay before every message of the server, or
+	// "immediate" to send them right away (once the messages of the application preceding them are received).
+	Timing string `json:"timing" yaml:"timing"`
+	// Speed divides the recorded delays, e.g. 2 replays the messages twice as fast. It is ignored when not positive.
+	Speed float64 `json:"speed" yaml:"speed"`
+}
+
+// ElasticsearchConfig enables the matching of the Elasticsearch and OpenSearch requests on their normalised path
+// and body, ignoring the scroll ids and the timestamps.
+type ElasticsearchConfig struct {
+	Enabled bool `json:"enabled" yaml:"enabled"`
+	// Hosts of the clusters, e.g. "elasticsearch:9200". When empty, the requests to the APIs of any host ("/_search",
+	// "/index/_doc/1", ...) are matched in this mode.
+	Hosts []string `json:"hosts" yaml:"hosts"`
+	// FuzzyFields are the keys of the bodies whose values are ignored
+	FuzzyFields []string `json:"fuzzyFields" yaml:"fuzzyFields"`
+}
+
+// SqlConfig tunes the matching of the mocks of the MySQL and Postgres dependencies.
+type SqlConfig struct {
+	// Matching is "exact" (the default) to match the queries on their text only, or "fingerprint" to match the
+	// queries which no mock matches exactly on their fingerprint, whatever the values of their literals.
+	Matching string `json:"matching" yaml:"matching"`
+}
+
+// MatcherRule tunes the matching of the mocks of a kind, e.g. Mongo, Http, SQL (MySQL) or Postgres.
+type MatcherRule struct {
+	Kind Kind `json:"kind" yaml:"kind"`
+	// Strategy is how the requests are matched: "exact", or "fingerprint" for the SQL and Postgres queries. The
+	// strategy of the parser is kept when it is empty.
+	Strategy string `json:"strategy" yaml:"strategy"`
+	// IgnoreFields are the fields of the requests whose values are ignored: the dotted paths of the Mongo commands,
+	// e.g. "lsid" or "txnNumber", or the "header.<name>" and "body.<path>" of the HTTP requests
+	IgnoreFields []string `json:"ignoreFields" yaml:"ignoreFields"`
+}
+
+// MockPolicy is how a mock is consumed when it is matched on replay.
+type MockPolicy string
+
+const (
+	// MockPolicyAnyOrder consumes the mock once, whenever it is matched. It is the default policy.
+	MockPolicyAnyOrder MockPolicy = "any-order"
+	// MockPolicyStrictSequence consumes the mock once, after the mocks of its kind with this policy recorded before it.
+	MockPolicyStrictSequence MockPolicy = "strict-sequence"
+	// MockPolicyConnectionSequence consumes the mock once, after the mocks with this policy recorded before it on the
+	// same connection. The mocks of different connections are matched in any order, so that the connections of a pool
+	// may be used differently than when the mocks were recorded.
+	MockPolicyConnectionSequence MockPolicy = "connection-sequence"
+	// MockPolicyRepeatable never consumes the mock, so that it is matched by the retries of a call too.
+	MockPolicyRepeatable MockPolicy = "repeatable"
+)
+
+// ConnectionMetadata is the metadata of the mocks holding the id of the connection they were recorded on.
+const ConnectionMetadata = "connection"
+
+// MockMatching sets the policies of the mocks of the test sets.
+type MockMatching struct {
+	// Default is the policy of the mocks which no rule selects, any-order when empty
+	Default MockPolicy `json:"default" yaml:"default"`
+	// Rules select the mocks of a policy, the first one selecting a mock setting its policy
+	Rules []MockPolicyRule `json:"rules" yaml:"rules"`
+}
+
+// MockPolicyRule selects the mocks of a policy. The empty fields select any mock, and the names are regexes
+// matching the whole name, e.g. "mock-1[0-9]".
+type MockPolicyRule struct {
+	TestSet string     `json:"testSet" yaml:"testSet"`
+	Name    string     `json:"name" yaml:"name"`
+	Kind    Kind       `json:"kind" yaml:"kind"`
+	Policy  MockPolicy `json:"policy" yaml:"policy"`
+}
+
+// FaultType is the failure a fault replays a mock as.
+type FaultType string
+
+const (
+	// FaultReset resets the connection of the call instead of answering it.
+	FaultReset FaultType = "reset"
+	// FaultStatus answers the call with the status of the fault, 503 by default, instead of the recorded response.
+	FaultStatus FaultType = "status"
+	// FaultTruncate closes the connection of the call in the middle of the body of the recorded response.
+	FaultTruncate FaultType = "truncate"
+	// FaultLatency delays the recorded response by the latency of the fault.
+	FaultLatency FaultType = "latency"
+)
+
+// Chaos replays the mocks selected by its faults as failures during keploy test --chaos, to test the resilience of
+// the application to the failures of its dependencies.
+type Chaos struct {
+	Enabled bool `json:"enabled" yaml:"enabled"`
+	// Faults select the mocks replayed as failures, the first one selecting a mock setting its failure
+	Faults []Fault `json:"faults" yaml:"faults"`
+}
+
+// Fault selects the mocks replayed as a failure. The empty fields select any mock like for the mock policy rules,
+// and the path is a regex matching the path of the url of the HTTP mocks. The reset, status and truncate faults
+// apply to the HTTP mocks, the latency ones to the mocks of any kind.
+type Fault struct {
+	TestSet string    `json:"testSet" yaml:"testSet"`
+	Name    string    `json:"name" yaml:"name"`
+	Kind    Kind      `json:"kind" yaml:"kind"`
+	Path    string    `json:"path" yaml:"path"`
+	Type    FaultType `json:"type" yaml:"type"`
+	// Status is the status of the responses of the status faults, 503 by default
+	Status int `json:"status" yaml:"status"`
+	// Latency is the delay added by the latency faults, e.g. 2s
+	Latency time.Duration `json:"latency" yaml:"latency"`
+	// Probability, between 0 and 1, is the chance that a matched mock fails, 1 when not positive
+	Probability float64 `json:"probability" yaml:"probability"`
 }
 
 type Globalnoise struct {
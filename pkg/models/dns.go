@@ -0,0 +1,15 @@
+package models
+
+// DNSRequest is the question of a DNS query of the application, its name being fully qualified (example.com.) and
+// its type one of A, AAAA, CNAME, MX, SRV, TXT...
+type DNSRequest struct {
+	Name string `json:"name" yaml:"name"`
+	Type string `json:"type" yaml:"type"`
+}
+
+// DNSResponse is the answer of a DNS query: its response code (NOERROR, NXDOMAIN...) and its records, in the
+// presentation format of the zone files, like "example.com.\t300\tIN\tA\t93.184.216.34".
+type DNSResponse struct {
+	Rcode   string   `json:"rcode" yaml:"rcode"`
+	Answers []string `json:"answers,omitempty" yaml:"answers,omitempty"`
+}
@@ -8,18 +8,25 @@ type GrpcHeaders struct {
 type GrpcLengthPrefixedMessage struct {
 	CompressionFlag uint   `json:"compression_flag" yaml:"compression_flag"`
 	MessageLength   uint32 `json:"message_length" yaml:"message_length"`
-	DecodedData     string `json:"decoded_data" yaml:"decoded_data"`
+	// MessageType is the full name of the protobuf message when it was decoded through the descriptor sets of
+	// the config, the decoded data is then its JSON form. It is the protoscope form otherwise.
+	MessageType string `json:"message_type,omitempty" yaml:"message_type,omitempty"`
+	DecodedData string `json:"decoded_data" yaml:"decoded_data"`
 }
 
 type GrpcReq struct {
 	Headers GrpcHeaders               `json:"headers" yaml:"headers"`
 	Body    GrpcLengthPrefixedMessage `json:"body" yaml:"body"`
+	// Stream holds the messages following the body in the client streaming RPCs
+	Stream []GrpcLengthPrefixedMessage `json:"stream,omitempty" yaml:"stream,omitempty"`
 }
 
 type GrpcResp struct {
-	Headers  GrpcHeaders               `json:"headers" yaml:"headers"`
-	Body     GrpcLengthPrefixedMessage `json:"body" yaml:"body"`
-	Trailers GrpcHeaders               `json:"trailers" yaml:"trailers"`
+	Headers GrpcHeaders               `json:"headers" yaml:"headers"`
+	Body    GrpcLengthPrefixedMessage `json:"body" yaml:"body"`
+	// Stream holds the messages following the body in the server streaming RPCs
+	Stream   []GrpcLengthPrefixedMessage `json:"stream,omitempty" yaml:"stream,omitempty"`
+	Trailers GrpcHeaders                 `json:"trailers" yaml:"trailers"`
 }
 
 // GrpcStream is a helper function to combine the request-response model in a single struct.
@@ -17,12 +17,23 @@ type HttpReq struct {
 	Form       []FormData        `json:"form" yaml:"form,omitempty"`
 	Timestamp  time.Time         `json:"timestamp" yaml:"timestamp"`
 	Host       string            `json:"host" yaml:"host"`
+	// HeaderOrder holds the names of the header lines in the order and the case they were sent in, when recorded
+	// with the header fidelity
+	HeaderOrder []string `json:"header_order,omitempty" yaml:"header_order,omitempty"`
+	// BodyFile is the file, relative to the directory of the yaml file, holding the body when it is larger than the
+	// inline limit of the recording, gzipped when it ends with .gz
+	BodyFile string `json:"body_file,omitempty" yaml:"body_file,omitempty"`
+	// BodyHash is the sha256 of the body held by BodyFile
+	BodyHash string `json:"body_hash,omitempty" yaml:"body_hash,omitempty"`
 }
 
+// FormData is a part of a multipart body, the parts being kept in their order. The content of a part is either in
+// Values or, for the large and binary ones, in the file of Paths relative to the directory of the yaml file.
 type FormData struct {
-	Key    string   `json:"key" bson:"key" yaml:"key"`
-	Values []string `json:"values" bson:"values,omitempty" yaml:"values,omitempty"`
-	Paths  []string `json:"paths" bson:"paths,omitempty" yaml:"paths,omitempty"`
+	Key    string            `json:"key" bson:"key" yaml:"key"`
+	Values []string          `json:"values" bson:"values,omitempty" yaml:"values,omitempty"`
+	Paths  []string          `json:"paths" bson:"paths,omitempty" yaml:"paths,omitempty"`
+	Header map[string]string `json:"header" bson:"header,omitempty" yaml:"header,omitempty"`
 }
 
 type HttpResp struct {
@@ -35,4 +46,37 @@ type HttpResp struct {
 	ProtoMinor    int               `json:"proto_minor" yaml:"proto_minor"`
 	Binary        string            `json:"binary" yaml:"binary,omitempty"`
 	Timestamp     time.Time         `json:"timestamp" yaml:"timestamp"`
+	// Events holds the events of a text/event-stream response in their order, they are streamed in place of the body
+	Events []ServerSentEvent `json:"events,omitempty" yaml:"events,omitempty"`
+	// Chunks holds the chunks of a chunked response in their order, the body holding their content
+	Chunks []HttpChunk `json:"chunks,omitempty" yaml:"chunks,omitempty"`
+	// Trailer holds the trailers sent after the chunks
+	Trailer map[string]string `json:"trailer,omitempty" yaml:"trailer,omitempty"`
+	// HeaderOrder holds the names of the header lines in the order and the case they were sent in, when recorded
+	// with the header fidelity
+	HeaderOrder []string `json:"header_order,omitempty" yaml:"header_order,omitempty"`
+	// BodyFile is the file, relative to the directory of the yaml file, holding the body when it is larger than the
+	// inline limit of the recording, gzipped when it ends with .gz
+	BodyFile string `json:"body_file,omitempty" yaml:"body_file,omitempty"`
+	// BodyHash is the sha256 of the body held by BodyFile
+	BodyHash string `json:"body_hash,omitempty" yaml:"body_hash,omitempty"`
+}
+
+// HttpChunk is a chunk of a chunked response: the number of bytes of the body it carried on the wire, and the delay
+// since the previous chunk, in nanoseconds.
+type HttpChunk struct {
+	Size  int   `json:"size" yaml:"size"`
+	Delay int64 `json:"delay,omitempty" yaml:"delay,omitempty"`
+}
+
+// ServerSentEvent is an event of a text/event-stream response. Data joins the lines of its data fields, and Comment
+// holds the comment lines, like the keep-alives. Delay is the time elapsed since the previous event (or since the
+// headers of the response for the first one), in nanoseconds.
+type ServerSentEvent struct {
+	ID      string `json:"id,omitempty" yaml:"id,omitempty"`
+	Event   string `json:"event,omitempty" yaml:"event,omitempty"`
+	Data    string `json:"data,omitempty" yaml:"data,omitempty"`
+	Retry   string `json:"retry,omitempty" yaml:"retry,omitempty"`
+	Comment string `json:"comment,omitempty" yaml:"comment,omitempty"`
+	Delay   int64  `json:"delay,omitempty" yaml:"delay,omitempty"`
 }
@@ -0,0 +1,43 @@
+package models
+
+// KafkaRequestHeader is the header of a request sent to a Kafka broker.
+type KafkaRequestHeader struct {
+	APIKey        int16  `json:"api_key" yaml:"api_key"`
+	APIVersion    int16  `json:"api_version" yaml:"api_version"`
+	CorrelationID int32  `json:"correlation_id" yaml:"correlation_id"`
+	ClientID      string `json:"client_id,omitempty" yaml:"client_id,omitempty"`
+}
+
+// KafkaRequest is a request of the Kafka protocol. The topics are decoded from the body of the Metadata, Produce
+// and Fetch requests to match them, the body is kept as it is (base64 encoded).
+type KafkaRequest struct {
+	Header KafkaRequestHeader `json:"header" yaml:"header"`
+	API    string             `json:"api" yaml:"api"`
+	Acks   int16              `json:"acks,omitempty" yaml:"acks,omitempty"`
+	Topics []KafkaTopic       `json:"topics,omitempty" yaml:"topics,omitempty"`
+	Body   string             `json:"body" yaml:"body"`
+}
+
+type KafkaTopic struct {
+	Name       string           `json:"name" yaml:"name"`
+	Partitions []KafkaPartition `json:"partitions,omitempty" yaml:"partitions,omitempty,flow"`
+}
+
+type KafkaPartition struct {
+	Index       int32 `json:"index" yaml:"index"`
+	FetchOffset int64 `json:"fetch_offset,omitempty" yaml:"fetch_offset,omitempty"`
+}
+
+// KafkaResponse is the response of a broker. The body, following the correlation id, is kept as it is (base64
+// encoded) while the versions supported by the broker are decoded from the ApiVersions responses.
+type KafkaResponse struct {
+	CorrelationID int32             `json:"correlation_id" yaml:"correlation_id"`
+	APIVersions   []KafkaAPIVersion `json:"api_versions,omitempty" yaml:"api_versions,omitempty"`
+	Body          string            `json:"body" yaml:"body"`
+}
+
+type KafkaAPIVersion struct {
+	APIKey     int16 `json:"api_key" yaml:"api_key"`
+	MinVersion int16 `json:"min_version" yaml:"min_version"`
+	MaxVersion int16 `json:"max_version" yaml:"max_version"`
+}
@@ -0,0 +1,32 @@
+package models
+
+// LDAPMessage is a message of the LDAP protocol. The fields of the operation used to match the requests, or worth
+// reading in the mocks, are decoded while the operation itself and the controls are kept as they are (base64
+// encoded BER) to replay the responses. The operation of a bind request isn't kept since it holds the credentials.
+type LDAPMessage struct {
+	MessageID int64  `json:"message_id" yaml:"message_id"`
+	Operation string `json:"operation" yaml:"operation"`
+	// DN is the name of the bind, the base object of the search or the entry the operation is about
+	DN         string `json:"dn,omitempty" yaml:"dn,omitempty"`
+	AuthMethod string `json:"auth_method,omitempty" yaml:"auth_method,omitempty"`
+	Mechanism  string `json:"mechanism,omitempty" yaml:"mechanism,omitempty"`
+	Scope      string `json:"scope,omitempty" yaml:"scope,omitempty"`
+	// Filter is the filter of the search in its string representation (RFC 4515)
+	Filter     string   `json:"filter,omitempty" yaml:"filter,omitempty"`
+	Attributes []string `json:"attributes,omitempty" yaml:"attributes,omitempty"`
+	// Name is the OID of an extended request or response
+	Name              string          `json:"name,omitempty" yaml:"name,omitempty"`
+	ResultCode        *int            `json:"result_code,omitempty" yaml:"result_code,omitempty"`
+	MatchedDN         string          `json:"matched_dn,omitempty" yaml:"matched_dn,omitempty"`
+	DiagnosticMessage string          `json:"diagnostic_message,omitempty" yaml:"diagnostic_message,omitempty"`
+	Entry             []LDAPAttribute `json:"entry,omitempty" yaml:"entry,omitempty"`
+	Payload           string          `json:"payload,omitempty" yaml:"payload,omitempty"`
+	Controls          string          `json:"controls,omitempty" yaml:"controls,omitempty"`
+}
+
+// LDAPAttribute is an attribute of an entry returned by a search, the values which aren't printable are base64
+// encoded.
+type LDAPAttribute struct {
+	Type   string   `json:"type" yaml:"type"`
+	Values []string `json:"values" yaml:"values"`
+}
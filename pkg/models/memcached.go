@@ -0,0 +1,26 @@
+package models
+
+// MemcachedRequest is a command of the memcached text (ASCII and meta commands) or binary protocol. In the text
+// protocol Args holds the tokens of the command line following the keys, in the binary protocol Extras holds the
+// extras of the packet (base64 encoded). The data block of the storage commands is kept in Value.
+type MemcachedRequest struct {
+	Protocol string        `json:"protocol" yaml:"protocol"`
+	Command  string        `json:"command" yaml:"command"`
+	Keys     []string      `json:"keys,omitempty" yaml:"keys,omitempty"`
+	Args     []string      `json:"args,omitempty" yaml:"args,omitempty"`
+	Extras   string        `json:"extras,omitempty" yaml:"extras,omitempty"`
+	Cas      uint64        `json:"cas,omitempty" yaml:"cas,omitempty"`
+	Value    *OutputBinary `json:"value,omitempty" yaml:"value,omitempty"`
+}
+
+// MemcachedResponse is a line of a response of the text protocol, along with the data block following the VALUE
+// and VA lines, or a response packet of the binary protocol.
+type MemcachedResponse struct {
+	Line   string        `json:"line,omitempty" yaml:"line,omitempty"`
+	Opcode string        `json:"opcode,omitempty" yaml:"opcode,omitempty"`
+	Status uint16        `json:"status,omitempty" yaml:"status,omitempty"`
+	Key    string        `json:"key,omitempty" yaml:"key,omitempty"`
+	Extras string        `json:"extras,omitempty" yaml:"extras,omitempty"`
+	Cas    uint64        `json:"cas,omitempty" yaml:"cas,omitempty"`
+	Value  *OutputBinary `json:"value,omitempty" yaml:"value,omitempty"`
+}
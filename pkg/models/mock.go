@@ -8,6 +8,10 @@ type Mock struct {
 	Kind    Kind     `json:"Kind,omitempty"`
 	Spec    MockSpec `json:"Spec,omitempty"`
 	Id      string   `json:"Id,omitempty"`
+	// Policy is how the mock is consumed on replay, set from the config of the test run
+	Policy MockPolicy `json:"-"`
+	// Fault is the failure the mock is replayed as, set from the chaos config of the test run
+	Fault *Fault `json:"-"`
 }
 
 func (m *Mock) GetKind() string {
@@ -40,6 +44,38 @@ type MockSpec struct {
 	//for MySql
 	MySqlRequests  []MySQLRequest  `json:"MySqlRequests,omitempty"`
 	MySqlResponses []MySQLResponse `json:"MySqlResponses,omitempty"`
+	//for Redis
+	RedisRequests  []RedisRequest `json:"RedisRequests,omitempty"`
+	RedisResponses []RedisValue   `json:"RedisResponses,omitempty"`
+	//for Kafka
+	KafkaRequests  []KafkaRequest  `json:"KafkaRequests,omitempty"`
+	KafkaResponses []KafkaResponse `json:"KafkaResponses,omitempty"`
+	//for AMQP
+	AMQPRequests  []AMQPCommand `json:"AMQPRequests,omitempty"`
+	AMQPResponses []AMQPCommand `json:"AMQPResponses,omitempty"`
+	//for Cassandra
+	CassandraRequests  []CassandraRequest  `json:"CassandraRequests,omitempty"`
+	CassandraResponses []CassandraResponse `json:"CassandraResponses,omitempty"`
+	//for SMTP
+	SMTPRequests  []SMTPRequest  `json:"SMTPRequests,omitempty"`
+	SMTPResponses []SMTPResponse `json:"SMTPResponses,omitempty"`
+	//for LDAP
+	LDAPRequests  []LDAPMessage `json:"LDAPRequests,omitempty"`
+	LDAPResponses []LDAPMessage `json:"LDAPResponses,omitempty"`
+	//for MQTT
+	MQTTRequests  []MQTTPacket `json:"MQTTRequests,omitempty"`
+	MQTTResponses []MQTTPacket `json:"MQTTResponses,omitempty"`
+	//for Memcached
+	MemcachedRequests  []MemcachedRequest  `json:"MemcachedRequests,omitempty"`
+	MemcachedResponses []MemcachedResponse `json:"MemcachedResponses,omitempty"`
+	//for NATS
+	NATSRequests  []NATSMessage `json:"NATSRequests,omitempty"`
+	NATSResponses []NATSMessage `json:"NATSResponses,omitempty"`
+	//for WebSocket, the opening handshake is stored in HttpReq and HttpResp
+	WebSocketMessages []WebSocketMessage `json:"WebSocketMessages,omitempty"`
+	//for DNS
+	DNSReq  *DNSRequest  `json:"DNSRequest,omitempty"`
+	DNSResp *DNSResponse `json:"DNSResponse,omitempty"`
 
 	ReqTimestampMock time.Time `json:"ReqTimestampMock,omitempty"`
 	ResTimestampMock time.Time `json:"ResTimestampMock,omitempty"`
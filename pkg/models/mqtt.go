@@ -0,0 +1,28 @@
+package models
+
+// MQTTPacket is a control packet of the MQTT 3.1.1 or 5 protocol. The fields used to match the packets, or worth
+// reading in the mocks, are decoded. The packets sent by the broker also keep their variable header and payload as
+// they are (base64 encoded) in Body, to be replayed along with their v5 properties.
+type MQTTPacket struct {
+	Type          string             `json:"type" yaml:"type"`
+	Flags         uint8              `json:"flags" yaml:"flags"`
+	PacketID      uint16             `json:"packet_id,omitempty" yaml:"packet_id,omitempty"`
+	ProtocolLevel uint8              `json:"protocol_level,omitempty" yaml:"protocol_level,omitempty"`
+	ClientID      string             `json:"client_id,omitempty" yaml:"client_id,omitempty"`
+	Username      string             `json:"username,omitempty" yaml:"username,omitempty"`
+	Topic         string             `json:"topic,omitempty" yaml:"topic,omitempty"`
+	QoS           uint8              `json:"qos,omitempty" yaml:"qos,omitempty"`
+	Retain        bool               `json:"retain,omitempty" yaml:"retain,omitempty"`
+	Subscriptions []MQTTSubscription `json:"subscriptions,omitempty" yaml:"subscriptions,omitempty"`
+	// ReasonCodes holds the return code of a CONNACK, the reason code of an acknowledgement or the ones of a
+	// SUBACK or UNSUBACK
+	ReasonCodes []int         `json:"reason_codes,omitempty" yaml:"reason_codes,omitempty"`
+	Payload     *OutputBinary `json:"payload,omitempty" yaml:"payload,omitempty"`
+	Body        string        `json:"body,omitempty" yaml:"body,omitempty"`
+}
+
+// MQTTSubscription is a topic filter of a SUBSCRIBE or UNSUBSCRIBE packet, along with the maximum QoS requested.
+type MQTTSubscription struct {
+	Filter string `json:"filter" yaml:"filter"`
+	QoS    uint8  `json:"qos,omitempty" yaml:"qos,omitempty"`
+}
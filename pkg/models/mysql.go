@@ -41,6 +41,14 @@ type MySQLHandshakeResponseOk struct {
 	PluginDetails   PluginDetails `yaml:"plugin_details"`
 	RemainingBytes  []byte        `yaml:"remaining_bytes"`
 }
+type MySQLPasswordData struct {
+	PayloadLength uint32 `yaml:"payload_length"`
+	SequenceID    byte   `yaml:"sequence_id"`
+	Payload       []byte `yaml:"payload"`
+}
+type MySQLNextAuthPacket struct {
+	PluginData byte `yaml:"plugin_data"`
+}
 type MySQLHandshakeResponse struct {
 	CapabilityFlags uint32   `yaml:"capability_flags"`
 	MaxPacketSize   uint32   `yaml:"max_packet_size"`
@@ -55,6 +63,23 @@ type MySQLHandshakeResponse struct {
 type MySQLQueryPacket struct {
 	Command byte   `yaml:"command"`
 	Query   string `yaml:"query"`
+	// Parameters are the literals of the query, in order
+	Parameters []string `yaml:"parameters,omitempty"`
+	// Fingerprint is the query with a "?" in place of each literal
+	Fingerprint string `yaml:"fingerprint,omitempty"`
+}
+
+// MySQLLocalInfileRequest is the LOCAL INFILE request answering a LOAD DATA LOCAL INFILE query, asking the client for
+// the content of the file.
+type MySQLLocalInfileRequest struct {
+	Filename string `yaml:"filename"`
+}
+
+// MySQLLocalInfileContent is the content of the file streamed by the client for a LOCAL INFILE request.
+type MySQLLocalInfileContent struct {
+	Content string `yaml:"content"`
+	// Packets is the number of the packets of the content, without the empty one ending it
+	Packets int `yaml:"packets"`
 }
 
 type MySQLComStmtExecute struct {
@@ -91,6 +116,15 @@ type MySQLResultSet struct {
 	OptionalPadding     bool                `yaml:"optionalPadding"`
 	OptionalEOFBytes    []byte              `yaml:"optionalEOFBytes"`
 	EOFAfterColumns     []byte              `yaml:"eofAfterColumns"`
+	BinaryProtocol      bool                `yaml:"binaryProtocol"`
+}
+type MySQLMultiResultSet struct {
+	Results []*MySQLResult `yaml:"results"`
+}
+type MySQLResult struct {
+	ResultSet *MySQLResultSet `yaml:"result_set,omitempty"`
+	OK        *MySQLOKPacket  `yaml:"ok,omitempty"`
+	Err       *MySQLERRPacket `yaml:"err,omitempty"`
 }
 type PacketHeader struct {
 	PacketLength     uint8 `yaml:"packet_length"`
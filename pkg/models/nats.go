@@ -0,0 +1,16 @@
+package models
+
+// NATSMessage is an operation of the NATS client protocol. Options holds the JSON of INFO and CONNECT (without the
+// credentials of the client), Headers the header block of HPUB and HMSG, and Error the message of -ERR.
+type NATSMessage struct {
+	Op      string        `json:"op" yaml:"op"`
+	Subject string        `json:"subject,omitempty" yaml:"subject,omitempty"`
+	Queue   string        `json:"queue,omitempty" yaml:"queue,omitempty"`
+	ReplyTo string        `json:"reply_to,omitempty" yaml:"reply_to,omitempty"`
+	Sid     string        `json:"sid,omitempty" yaml:"sid,omitempty"`
+	MaxMsgs int           `json:"max_msgs,omitempty" yaml:"max_msgs,omitempty"`
+	Options string        `json:"options,omitempty" yaml:"options,omitempty"`
+	Headers string        `json:"headers,omitempty" yaml:"headers,omitempty"`
+	Payload *OutputBinary `json:"payload,omitempty" yaml:"payload,omitempty"`
+	Error   string        `json:"error,omitempty" yaml:"error,omitempty"`
+}
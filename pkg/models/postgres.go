@@ -16,10 +16,13 @@ type Backend struct {
 	Binds               []pgproto3.Bind              `json:"bind,omitempty" yaml:"bind,omitempty"`
 	CancelRequest       pgproto3.CancelRequest       `json:"cancel_request,omitempty" yaml:"cancel_request,omitempty"`
 	Close               pgproto3.Close               `json:"close,omitempty" yaml:"close,omitempty"`
+	Closes              []pgproto3.Close             `json:"closes,omitempty" yaml:"closes,omitempty"`
 	CopyFail            pgproto3.CopyFail            `json:"copy_fail,omitempty" yaml:"copy_fail,omitempty"`
 	CopyData            pgproto3.CopyData            `json:"copy_data,omitempty" yaml:"copy_data,omitempty"`
+	CopyDatas           []string                     `json:"copy_datas,omitempty" yaml:"copy_datas,omitempty"`
 	CopyDone            pgproto3.CopyDone            `json:"copy_done,omitempty" yaml:"copy_done,omitempty"`
 	Describe            pgproto3.Describe            `json:"describe,omitempty" yaml:"describe,omitempty"`
+	Describes           []pgproto3.Describe          `json:"describes,omitempty" yaml:"describes,omitempty"`
 	Execute             pgproto3.Execute             `yaml:"-"`
 	Executes            []pgproto3.Execute           `json:"execute,omitempty" yaml:"execute,omitempty"`
 	Flush               pgproto3.Flush               `json:"flush,omitempty" yaml:"flush,omitempty"`
@@ -28,6 +31,8 @@ type Backend struct {
 	Parse               pgproto3.Parse               `yaml:"-"`
 	Parses              []pgproto3.Parse             `json:"parse,omitempty" yaml:"parse,omitempty"`
 	Query               pgproto3.Query               `json:"query,omitempty" yaml:"query,omitempty"`
+	QueryParameters     []string                     `json:"query_parameters,omitempty" yaml:"query_parameters,omitempty"`
+	QueryFingerprint    string                       `json:"query_fingerprint,omitempty" yaml:"query_fingerprint,omitempty"`
 	SSlRequest          pgproto3.SSLRequest          `json:"ssl_request,omitempty" yaml:"ssl_request,omitempty"`
 	StartupMessage      pgproto3.StartupMessage      `json:"startup_message,omitempty" yaml:"startup_message,omitempty"`
 	Sync                pgproto3.Sync                `json:"sync,omitempty" yaml:"sync,omitempty"`
@@ -0,0 +1,16 @@
+package models
+
+// RedisRequest is a command sent by the client, as the array of its arguments.
+type RedisRequest struct {
+	Command string   `json:"command" yaml:"command"`
+	Args    []string `json:"args,omitempty" yaml:"args,omitempty"`
+}
+
+// RedisValue is a RESP2/RESP3 value. The elements of the aggregates (arrays, sets, pushes) are stored in order,
+// the ones of maps and attributes alternate between the keys and the values.
+type RedisValue struct {
+	Type     string       `json:"type" yaml:"type"`
+	Value    string       `json:"value,omitempty" yaml:"value,omitempty"`
+	Null     bool         `json:"null,omitempty" yaml:"null,omitempty"`
+	Elements []RedisValue `json:"elements,omitempty" yaml:"elements,omitempty"`
+}
@@ -0,0 +1,28 @@
+package models
+
+// AssertionMode is how the body of the response of a testcase is checked.
+type AssertionMode string
+
+const (
+	// AssertionExact compares the body with the recorded one, apart from its noisy fields.
+	AssertionExact AssertionMode = "exact"
+	// AssertionSchema validates the body against the JSON schema inferred from the recorded one.
+	AssertionSchema AssertionMode = "schema"
+)
+
+// JSONSchema is the subset of JSON Schema inferred from the recorded JSON bodies: the type of the values, the
+// properties of the objects along with the required ones, and the schema of the items of the arrays. A schema
+// without type accepts any value.
+type JSONSchema struct {
+	Type       string                 `json:"type,omitempty" yaml:"type,omitempty"`
+	Properties map[string]*JSONSchema `json:"properties,omitempty" yaml:"properties,omitempty"`
+	Required   []string               `json:"required,omitempty" yaml:"required,omitempty"`
+	Items      *JSONSchema            `json:"items,omitempty" yaml:"items,omitempty"`
+}
+
+// SchemaError is a value of a body which doesn't match its schema, Path being like body.data.0.id.
+type SchemaError struct {
+	Path     string `json:"path" yaml:"path"`
+	Expected string `json:"expected" yaml:"expected"`
+	Actual   string `json:"actual" yaml:"actual"`
+}
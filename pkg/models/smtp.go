@@ -0,0 +1,16 @@
+package models
+
+// SMTPRequest is a command sent by the client, or a line sent in answer to a 334 challenge of the AUTH command, in
+// which case the command is empty and the line is kept in Args. The mail sent after the 354 reply to DATA, or the
+// chunk sent along with BDAT, is kept in Message with the dot-stuffing and the terminating line removed.
+type SMTPRequest struct {
+	Command string        `json:"command,omitempty" yaml:"command,omitempty"`
+	Args    string        `json:"args,omitempty" yaml:"args,omitempty"`
+	Message *OutputBinary `json:"message,omitempty" yaml:"message,omitempty"`
+}
+
+// SMTPResponse is a reply of the server, Lines holds the text of every line of a multiline reply.
+type SMTPResponse struct {
+	Code  int      `json:"code" yaml:"code"`
+	Lines []string `json:"lines" yaml:"lines"`
+}
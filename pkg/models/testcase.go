@@ -4,10 +4,17 @@ type Kind string
 type BodyType string
 type Version string
 
-const V1Beta1 = Version("api.keploy.io/v1beta1")
+// the versions of the schema of the tests and mocks, v1beta2 writing the noise of the assertions as a map
+const (
+	V1Beta1 = Version("api.keploy.io/v1beta1")
+	V1Beta2 = Version("api.keploy.io/v1beta2")
+)
+
+// Versions are the versions of the schema of the tests and mocks, from the oldest to the current one.
+var Versions = []Version{V1Beta1, V1Beta2}
 
 var (
-	currentVersion = V1Beta1
+	currentVersion = V1Beta2
 )
 
 func SetVersion(V1 string) {
@@ -25,10 +32,22 @@ const (
 	Postgres       Kind     = "Postgres"
 	GRPC_EXPORT    Kind     = "gRPC"
 	Mongo          Kind     = "Mongo"
+	Redis          Kind     = "Redis"
+	Kafka          Kind     = "Kafka"
+	AMQP           Kind     = "AMQP"
+	Cassandra      Kind     = "Cassandra"
+	SMTP           Kind     = "SMTP"
+	LDAP           Kind     = "LDAP"
+	MQTT           Kind     = "MQTT"
+	Memcached      Kind     = "Memcached"
+	NATS           Kind     = "NATS"
+	WebSocket      Kind     = "WebSocket"
+	DNS            Kind     = "DNS"
 	BodyTypeUtf8   BodyType = "utf-8"
 	BodyTypeBinary BodyType = "binary"
 	BodyTypePlain  BodyType = "PLAIN"
 	BodyTypeJSON   BodyType = "JSON"
+	BodyTypeXML    BodyType = "XML"
 	BodyTypeError  BodyType = "ERROR"
 )
 
@@ -48,6 +67,23 @@ type TestCase struct {
 	Noise    map[string][]string `json:"noise"`
 	Mocks    []*Mock             `json:"mocks"`
 	Type     string              `json:"type"`
+	// Variables are the values of the request taken from the responses of the previous test cases
+	Variables map[string]Variable `json:"variables"`
+	// AssertionMode is how the body of the response is checked, Schema being the one inferred at record time
+	AssertionMode AssertionMode `json:"assertion_mode"`
+	Schema        *JSONSchema   `json:"schema"`
+	// Tags select the test case to be run with the tests having one of the tags, written as metadata.tags
+	Tags []string `json:"tags"`
+}
+
+// Variable is a value of the request of a test case, referenced as {{.name}}, which is taken from the response of a
+// previous test case, like an auth token or the id of a created resource. Path is header.<name> or the path of the
+// value in the JSON body, like body.data.id. Value is the recorded value, used when the test case it is taken from
+// isn't run.
+type Variable struct {
+	From  string `json:"from" yaml:"from"`
+	Path  string `json:"path" yaml:"path"`
+	Value string `json:"value" yaml:"value"`
 }
 
 func (tc *TestCase) GetKind() string {
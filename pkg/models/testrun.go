@@ -1,5 +1,7 @@
 package models
 
+import "time"
+
 type TestReport struct {
 	Version Version      `json:"version" yaml:"version"`
 	Name    string       `json:"name" yaml:"name"`
@@ -9,12 +11,44 @@ type TestReport struct {
 	Total   int          `json:"total" yaml:"total"`
 	Tests   []TestResult `json:"tests" yaml:"tests,omitempty"`
 	TestSet string       `json:"testSet" yaml:"test_set"`
+	// ConsumedMocks are the names of the mocks of the test set matched during the run
+	ConsumedMocks []string `json:"consumedMocks" yaml:"consumed_mocks,omitempty"`
 }
 
 func (tr *TestReport) GetKind() string {
 	return "TestReport"
 }
 
+// RunReport gathers the test reports of the test sets of a sharded run. Each shard writes the report of its own test
+// sets, which are then merged into the report of the whole run.
+type RunReport struct {
+	Version Version `json:"version" yaml:"version"`
+	// Shards are the shards of the run, like 2/4, whose test sets are reported
+	Shards      []string     `json:"shards" yaml:"shards"`
+	Status      string       `json:"status" yaml:"status"`
+	Success     int          `json:"success" yaml:"success"`
+	Failure     int          `json:"failure" yaml:"failure"`
+	Total       int          `json:"total" yaml:"total"`
+	TestReports []TestReport `json:"testReports" yaml:"test_reports"`
+}
+
+func (rr *RunReport) GetKind() string {
+	return "RunReport"
+}
+
+// Add adds the test report of a test set to the run, the run failing as soon as one of its test sets doesn't pass.
+func (rr *RunReport) Add(report TestReport) {
+	rr.TestReports = append(rr.TestReports, report)
+	rr.Success += report.Success
+	rr.Failure += report.Failure
+	rr.Total += report.Total
+	if report.Status != string(TestRunStatusPassed) {
+		rr.Status = string(TestRunStatusFailed)
+	} else if rr.Status != string(TestRunStatusFailed) {
+		rr.Status = string(TestRunStatusPassed)
+	}
+}
+
 type TestResult struct {
 	Kind         Kind       `json:"kind" yaml:"kind"`
 	Name         string     `json:"name" yaml:"name"`
@@ -28,6 +62,8 @@ type TestResult struct {
 	Res          HttpResp   `json:"resp" yaml:"resp,omitempty"`
 	Noise        Noise      `json:"noise" yaml:"noise,omitempty"`
 	Result       Result     `json:"result" yaml:"result"`
+	// GraphQL is the GraphQL operation of the request, like "query GetUser"
+	GraphQL string `json:"graphql,omitempty" yaml:"graphql,omitempty"`
 }
 
 func (tr *TestResult) GetKind() string {
@@ -87,6 +123,8 @@ type BodyResult struct {
 	Type     BodyType `json:"type" bson:"type" yaml:"type"`
 	Expected string   `json:"expected" bson:"expected" yaml:"expected"`
 	Actual   string   `json:"actual" bson:"actual" yaml:"actual"`
+	// SchemaErrors are the values of the actual body which don't match the schema of the testcase
+	SchemaErrors []SchemaError `json:"schema_errors,omitempty" bson:"schema_errors,omitempty" yaml:"schema_errors,omitempty"`
 }
 
 type TestStatus string
@@ -97,3 +135,68 @@ const (
 	TestStatusFailed  TestStatus = "FAILED"
 	TestStatusPassed  TestStatus = "PASSED"
 )
+
+// FlakeReport is the result of the repeated runs of the testcases of a test set, each one being run Runs times in a
+// row with the same mocks.
+type FlakeReport struct {
+	Version Version     `json:"version" yaml:"version"`
+	TestSet string      `json:"testSet" yaml:"test_set"`
+	Runs    int         `json:"runs" yaml:"runs"`
+	Stable  int         `json:"stable" yaml:"stable"`
+	Flaky   int         `json:"flaky" yaml:"flaky"`
+	Tests   []FlakyTest `json:"tests" yaml:"tests"`
+}
+
+type FlakeStatus string
+
+const (
+	FlakeStatusStable FlakeStatus = "STABLE"
+	FlakeStatusFlaky  FlakeStatus = "FLAKY"
+)
+
+// FlakyTest is the result of the repeated runs of a testcase, which is flaky when it both passed and failed or when
+// the values of some fields of its responses changed from a run to the next.
+type FlakyTest struct {
+	Name   string       `json:"name" yaml:"name"`
+	Status FlakeStatus  `json:"status" yaml:"status"`
+	Passed int          `json:"passed" yaml:"passed"`
+	Failed int          `json:"failed" yaml:"failed"`
+	Fields []FlakyField `json:"fields,omitempty" yaml:"fields,omitempty"`
+}
+
+// FlakyField is a field of the responses, like body.data.id or header.Date, along with its values in the runs.
+type FlakyField struct {
+	Path   string   `json:"path" yaml:"path"`
+	Values []string `json:"values" yaml:"values"`
+}
+
+// ShadowReport compares the responses of a candidate build of the application with the recorded ones, for the calls of
+// a test set recorded by keploy shadow. It only lists the calls whose responses differ or which the candidate failed.
+type ShadowReport struct {
+	Version    Version      `json:"version" yaml:"version"`
+	TestSet    string       `json:"testSet" yaml:"test_set"`
+	Candidate  string       `json:"candidate" yaml:"candidate"`
+	Total      int          `json:"total" yaml:"total"`
+	Matched    int          `json:"matched" yaml:"matched"`
+	Mismatched int          `json:"mismatched" yaml:"mismatched"`
+	Failed     int          `json:"failed" yaml:"failed"`
+	Calls      []ShadowCall `json:"calls,omitempty" yaml:"calls,omitempty"`
+}
+
+// ShadowCall is a call whose response from the candidate differs from the recorded one, or which it failed.
+type ShadowCall struct {
+	Method     Method        `json:"method" yaml:"method"`
+	URL        string        `json:"url" yaml:"url"`
+	Timestamp  time.Time     `json:"timestamp" yaml:"timestamp"`
+	StatusCode IntResult     `json:"statusCode" yaml:"status_code"`
+	Fields     []ShadowField `json:"fields,omitempty" yaml:"fields,omitempty"`
+	// Error is the error of the call to the candidate
+	Error string `json:"error,omitempty" yaml:"error,omitempty"`
+}
+
+// ShadowField is a field of the responses, like body.data.id or header.Content-Type, whose values differ.
+type ShadowField struct {
+	Path      string `json:"path" yaml:"path"`
+	Recorded  string `json:"recorded" yaml:"recorded"`
+	Candidate string `json:"candidate" yaml:"candidate"`
+}
@@ -0,0 +1,15 @@
+package models
+
+// WebSocketMessage is a message of the timeline of a WebSocket connection. Sender is "client" for the messages
+// written by the application and "server" for the ones it received, Type is text, binary, ping, pong or close.
+// Delay is the time elapsed since the previous message of the timeline, in nanoseconds.
+type WebSocketMessage struct {
+	Sender  string        `json:"sender" yaml:"sender"`
+	Type    string        `json:"type" yaml:"type"`
+	Payload *OutputBinary `json:"payload,omitempty" yaml:"payload,omitempty"`
+	// Compressed is set when the payload is compressed through the permessage-deflate extension
+	Compressed bool `json:"compressed,omitempty" yaml:"compressed,omitempty"`
+	// CloseCode is the status code of a close message
+	CloseCode int   `json:"close_code,omitempty" yaml:"close_code,omitempty"`
+	Delay     int64 `json:"delay,omitempty" yaml:"delay,omitempty"`
+}
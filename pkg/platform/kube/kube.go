@@ -0,0 +1,190 @@
+// Package kube reads the pods of the kubernetes cluster keploy runs in, through the API server with the credentials
+// of the service account of its pod.
+package kube
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// ServiceAccountDir is the directory where kubernetes mounts the credentials of the service account of the pod.
+const ServiceAccountDir = "/var/run/secrets/kubernetes.io/serviceaccount"
+
+// ErrNotInCluster is returned when keploy doesn't run in a pod of a kubernetes cluster.
+var ErrNotInCluster = errors.New("keploy doesn't run in a kubernetes pod, KUBERNETES_SERVICE_HOST and KUBERNETES_SERVICE_PORT aren't set")
+
+// Pod is a pod of the cluster.
+type Pod struct {
+	Name      string
+	Namespace string
+	UID       string
+	Node      string
+	IP        string
+	// Phase of the pod, like Pending or Running
+	Phase       string
+	Annotations map[string]string
+	// Containers are the running containers of the pod, in the order of its spec
+	Containers []Container
+}
+
+// Container is a running container of a pod.
+type Container struct {
+	Name string
+	// ID of the container, without the prefix of its runtime like containerd://
+	ID string
+}
+
+// Client reads the pods from the API server of the cluster.
+type Client struct {
+	server string
+	client *http.Client
+}
+
+// NewInClusterClient returns the client of the API server of the cluster keploy runs in, which authenticates with the
+// token of the service account of its pod.
+func NewInClusterClient() (*Client, error) {
+	host, port := os.Getenv("KUBERNETES_SERVICE_HOST"), os.Getenv("KUBERNETES_SERVICE_PORT")
+	if host == "" || port == "" {
+		return nil, ErrNotInCluster
+	}
+	ca, err := os.ReadFile(filepath.Join(ServiceAccountDir, "ca.crt"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the certificate of the API server: %v", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(ca) {
+		return nil, fmt.Errorf("invalid certificate of the API server in %s", filepath.Join(ServiceAccountDir, "ca.crt"))
+	}
+	return &Client{
+		server: "https://" + net.JoinHostPort(host, port),
+		client: &http.Client{
+			Timeout:   30 * time.Second,
+			Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: pool}},
+		},
+	}, nil
+}
+
+// Namespace returns the namespace of the pod keploy runs in.
+func Namespace() (string, error) {
+	namespace, err := os.ReadFile(filepath.Join(ServiceAccountDir, "namespace"))
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(namespace)), nil
+}
+
+// podList is the part of the list of pods of the API read by the client.
+type podList struct {
+	Items []struct {
+		Metadata struct {
+			Name        string            `json:"name"`
+			Namespace   string            `json:"namespace"`
+			UID         string            `json:"uid"`
+			Annotations map[string]string `json:"annotations"`
+		} `json:"metadata"`
+		Spec struct {
+			NodeName   string `json:"nodeName"`
+			Containers []struct {
+				Name string `json:"name"`
+			} `json:"containers"`
+		} `json:"spec"`
+		Status struct {
+			Phase             string `json:"phase"`
+			PodIP             string `json:"podIP"`
+			ContainerStatuses []struct {
+				Name        string `json:"name"`
+				ContainerID string `json:"containerID"`
+				State       struct {
+					Running *struct{} `json:"running"`
+				} `json:"state"`
+			} `json:"containerStatuses"`
+		} `json:"status"`
+	} `json:"items"`
+}
+
+// ListPods returns the pods of the namespace, of all of them when it's empty, matching the label selector and
+// scheduled on the node, on any of them when it's empty.
+func (c *Client) ListPods(ctx context.Context, namespace, labelSelector, node string) ([]Pod, error) {
+	path := "/api/v1/pods"
+	if namespace != "" {
+		path = "/api/v1/namespaces/" + url.PathEscape(namespace) + "/pods"
+	}
+	query := url.Values{}
+	if labelSelector != "" {
+		query.Set("labelSelector", labelSelector)
+	}
+	if node != "" {
+		query.Set("fieldSelector", "spec.nodeName="+node)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.server+path+"?"+query.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	// the tokens of the service accounts are rotated, so the mounted one is read for each request
+	token, err := os.ReadFile(filepath.Join(ServiceAccountDir, "token"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the token of the service account: %v", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+strings.TrimSpace(string(token)))
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("the API server answered the list of the pods with %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	var list podList
+	if err := json.Unmarshal(body, &list); err != nil {
+		return nil, fmt.Errorf("failed to decode the pods listed by the API server: %v", err)
+	}
+
+	pods := make([]Pod, 0, len(list.Items))
+	for _, item := range list.Items {
+		pod := Pod{
+			Name:        item.Metadata.Name,
+			Namespace:   item.Metadata.Namespace,
+			UID:         item.Metadata.UID,
+			Node:        item.Spec.NodeName,
+			IP:          item.Status.PodIP,
+			Phase:       item.Status.Phase,
+			Annotations: item.Metadata.Annotations,
+		}
+		ids := map[string]string{}
+		for _, status := range item.Status.ContainerStatuses {
+			if status.State.Running == nil || status.ContainerID == "" {
+				continue
+			}
+			id := status.ContainerID
+			if i := strings.Index(id, "://"); i >= 0 {
+				id = id[i+3:]
+			}
+			ids[status.Name] = id
+		}
+		for _, container := range item.Spec.Containers {
+			if id, ok := ids[container.Name]; ok {
+				pod.Containers = append(pod.Containers, Container{Name: container.Name, ID: id})
+			}
+		}
+		pods = append(pods, pod)
+	}
+	return pods, nil
+}
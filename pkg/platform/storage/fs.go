@@ -0,0 +1,62 @@
+package storage
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// fsBackend stores the objects as the files of a directory, like a mounted network share.
+type fsBackend struct {
+	dir string
+}
+
+func newFsBackend(dir string) *fsBackend {
+	return &fsBackend{dir: dir}
+}
+
+func (f *fsBackend) List(ctx context.Context, prefix string) ([]string, error) {
+	var keys []string
+	err := filepath.Walk(f.dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+		key, err := filepath.Rel(f.dir, path)
+		if err != nil {
+			return err
+		}
+		key = filepath.ToSlash(key)
+		if strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+		return nil
+	})
+	return keys, err
+}
+
+func (f *fsBackend) Get(ctx context.Context, key string) ([]byte, error) {
+	return os.ReadFile(filepath.Join(f.dir, filepath.FromSlash(key)))
+}
+
+func (f *fsBackend) Put(ctx context.Context, key string, data []byte) error {
+	path := filepath.Join(f.dir, filepath.FromSlash(key))
+	if err := os.MkdirAll(filepath.Dir(path), 0777); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+func (f *fsBackend) Delete(ctx context.Context, key string) error {
+	err := os.Remove(filepath.Join(f.dir, filepath.FromSlash(key)))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
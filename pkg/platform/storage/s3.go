@@ -0,0 +1,265 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"go.keploy.io/server/pkg/models"
+)
+
+// s3Backend stores the objects in a bucket of Amazon S3 or of a store speaking its API, like MinIO or the XML API of
+// Google Cloud Storage with HMAC keys. The requests are signed with AWS Signature Version 4.
+type s3Backend struct {
+	bucket    string
+	region    string
+	endpoint  *url.URL
+	pathStyle bool
+	accessKey string
+	secretKey string
+	token     string
+	client    *http.Client
+}
+
+func newS3Backend(config models.Storage) (*s3Backend, error) {
+	if config.Bucket == "" {
+		return nil, fmt.Errorf("the bucket of the %s storage is missing", config.Type)
+	}
+	b := &s3Backend{
+		bucket:    config.Bucket,
+		region:    config.Region,
+		accessKey: os.Getenv("AWS_ACCESS_KEY_ID"),
+		secretKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		token:     os.Getenv("AWS_SESSION_TOKEN"),
+		client:    &http.Client{Timeout: 5 * time.Minute},
+	}
+	if b.accessKey == "" || b.secretKey == "" {
+		return nil, fmt.Errorf("set AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY to the credentials of the %s storage", config.Type)
+	}
+	endpoint := config.Endpoint
+	switch config.Type {
+	case TypeS3:
+		if b.region == "" {
+			b.region = "us-east-1"
+		}
+		if endpoint == "" {
+			endpoint = fmt.Sprintf("https://%s.s3.%s.amazonaws.com", b.bucket, b.region)
+		} else {
+			b.pathStyle = true
+		}
+	case TypeGcs:
+		if b.region == "" {
+			b.region = "auto"
+		}
+		if endpoint == "" {
+			endpoint = "https://storage.googleapis.com"
+		}
+		b.pathStyle = true
+	case TypeMinio:
+		if endpoint == "" {
+			return nil, fmt.Errorf("the endpoint of the minio storage is missing")
+		}
+		if b.region == "" {
+			b.region = "us-east-1"
+		}
+		b.pathStyle = true
+	}
+	u, err := url.Parse(endpoint)
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("invalid endpoint %q of the %s storage", endpoint, config.Type)
+	}
+	b.endpoint = u
+	return b, nil
+}
+
+// objectURL returns the url of the object, or of the bucket for an empty key.
+func (b *s3Backend) objectURL(key string, query url.Values) *url.URL {
+	u := *b.endpoint
+	path := "/" + key
+	if b.pathStyle {
+		path = "/" + b.bucket
+		if key != "" {
+			path += "/" + key
+		}
+	}
+	u.Path = path
+	u.RawPath = uriEncode(path, false)
+	u.RawQuery = canonicalQuery(query)
+	return &u
+}
+
+// uriEncode encodes the string as the canonical requests of Signature Version 4 do, the slashes of the paths being
+// kept.
+func uriEncode(s string, encodeSlash bool) string {
+	var b strings.Builder
+	for _, c := range []byte(s) {
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9', c == '-', c == '_', c == '.', c == '~':
+			b.WriteByte(c)
+		case c == '/' && !encodeSlash:
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+func canonicalQuery(query url.Values) string {
+	keys := make([]string, 0, len(query))
+	for key := range query {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	var parts []string
+	for _, key := range keys {
+		for _, value := range query[key] {
+			parts = append(parts, uriEncode(key, true)+"="+uriEncode(value, true))
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// sign adds the Signature Version 4 authorization to the request.
+func (b *s3Backend) sign(req *http.Request, payload []byte, now time.Time) {
+	amzDate := now.UTC().Format("20060102T150405Z")
+	date := amzDate[:8]
+	payloadHash := sha256Hex(payload)
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	if b.token != "" {
+		req.Header.Set("X-Amz-Security-Token", b.token)
+	}
+
+	names := []string{"host", "x-amz-content-sha256", "x-amz-date"}
+	if b.token != "" {
+		names = append(names, "x-amz-security-token")
+	}
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		value := req.Header.Get(name)
+		if name == "host" {
+			value = req.Host
+		}
+		canonicalHeaders.WriteString(name + ":" + strings.TrimSpace(value) + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.RawQuery,
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := date + "/" + b.region + "/s3/aws4_request"
+	stringToSign := strings.Join([]string{"AWS4-HMAC-SHA256", amzDate, scope, sha256Hex([]byte(canonicalRequest))}, "\n")
+	key := hmacSHA256([]byte("AWS4"+b.secretKey), date)
+	key = hmacSHA256(key, b.region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s", b.accessKey, scope, signedHeaders, signature))
+}
+
+// do sends the signed request and returns the body of its response, an error being returned for the failed ones.
+func (b *s3Backend) do(ctx context.Context, method, key string, query url.Values, payload []byte) ([]byte, int, error) {
+	req, err := http.NewRequestWithContext(ctx, method, b.objectURL(key, query).String(), bytes.NewReader(payload))
+	if err != nil {
+		return nil, 0, err
+	}
+	req.ContentLength = int64(len(payload))
+	b.sign(req, payload, time.Now())
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, resp.StatusCode, err
+	}
+	if resp.StatusCode >= 300 {
+		return nil, resp.StatusCode, fmt.Errorf("%s %s of the bucket %s failed with %s: %s", method, key, b.bucket, resp.Status, strings.TrimSpace(string(body)))
+	}
+	return body, resp.StatusCode, nil
+}
+
+// listBucketResult is the response of the listing of the objects (ListObjects), the version supported by all the stores.
+type listBucketResult struct {
+	IsTruncated bool   `xml:"IsTruncated"`
+	NextMarker  string `xml:"NextMarker"`
+	Contents    []struct {
+		Key string `xml:"Key"`
+	} `xml:"Contents"`
+}
+
+func (b *s3Backend) List(ctx context.Context, prefix string) ([]string, error) {
+	var keys []string
+	marker := ""
+	for {
+		query := url.Values{"prefix": {prefix}}
+		if marker != "" {
+			query.Set("marker", marker)
+		}
+		body, _, err := b.do(ctx, http.MethodGet, "", query, nil)
+		if err != nil {
+			return nil, err
+		}
+		result := listBucketResult{}
+		if err := xml.Unmarshal(body, &result); err != nil {
+			return nil, fmt.Errorf("failed to decode the objects of the bucket %s: %v", b.bucket, err)
+		}
+		for _, content := range result.Contents {
+			keys = append(keys, content.Key)
+		}
+		if !result.IsTruncated || len(result.Contents) == 0 {
+			return keys, nil
+		}
+		marker = result.NextMarker
+		if marker == "" {
+			marker = result.Contents[len(result.Contents)-1].Key
+		}
+	}
+}
+
+func (b *s3Backend) Get(ctx context.Context, key string) ([]byte, error) {
+	body, _, err := b.do(ctx, http.MethodGet, key, nil, nil)
+	return body, err
+}
+
+func (b *s3Backend) Put(ctx context.Context, key string, data []byte) error {
+	_, _, err := b.do(ctx, http.MethodPut, key, nil, data)
+	return err
+}
+
+func (b *s3Backend) Delete(ctx context.Context, key string) error {
+	_, status, err := b.do(ctx, http.MethodDelete, key, nil, nil)
+	if status == http.StatusNotFound {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,43 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+
+	"go.keploy.io/server/pkg/models"
+)
+
+// the types of the storage backends
+const (
+	TypeFs    = "fs"
+	TypeS3    = "s3"
+	TypeMinio = "minio"
+	TypeGcs   = "gcs"
+)
+
+// Backend stores the files of the test sets as objects, whose keys are their paths relative to the keploy directory
+// under the prefix of the backend.
+type Backend interface {
+	// List returns the keys of the objects under the prefix.
+	List(ctx context.Context, prefix string) ([]string, error)
+	Get(ctx context.Context, key string) ([]byte, error)
+	Put(ctx context.Context, key string, data []byte) error
+	Delete(ctx context.Context, key string) error
+}
+
+// New returns the backend of the storage configuration.
+func New(config models.Storage) (Backend, error) {
+	switch config.Type {
+	case TypeFs:
+		if config.Bucket == "" {
+			return nil, fmt.Errorf("the bucket of the fs storage, its directory, is missing")
+		}
+		return newFsBackend(config.Bucket), nil
+	case TypeS3, TypeMinio, TypeGcs:
+		return newS3Backend(config)
+	case "":
+		return nil, fmt.Errorf("no storage is configured")
+	default:
+		return nil, fmt.Errorf("unknown storage type %q, the types are %s, %s, %s and %s", config.Type, TypeFs, TypeS3, TypeMinio, TypeGcs)
+	}
+}
@@ -0,0 +1,142 @@
+package yaml
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"mime"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"go.keploy.io/server/pkg"
+	"go.keploy.io/server/pkg/models"
+	"go.keploy.io/server/pkg/proxy/util"
+	"go.uber.org/zap"
+)
+
+// storeBodies moves the bodies of the request and the response larger than the inline limit into files of the assets
+// directory of dir, their path and hash taking their place. It reports false when no body was moved.
+func (ys *Yaml) storeBodies(dir string, req *models.HttpReq, resp *models.HttpResp) bool {
+	if ys.BodyStorage.MaxInlineSize <= 0 {
+		return false
+	}
+	// the bodies of the encrypted test sets stay in their encrypted documents
+	if aead, err := encryptionCipher(); err != nil || aead != nil {
+		return false
+	}
+	moved := false
+	if req != nil && ys.storeBody(dir, &req.Body, &req.BodyFile, &req.BodyHash, req.Header) {
+		moved = true
+	}
+	if resp != nil && ys.storeBody(dir, &resp.Body, &resp.BodyFile, &resp.BodyHash, resp.Header) {
+		moved = true
+	}
+	return moved
+}
+
+func (ys *Yaml) storeBody(dir string, body, bodyFile, bodyHash *string, header map[string]string) bool {
+	if len(*body) <= ys.BodyStorage.MaxInlineSize {
+		return false
+	}
+	content := *body
+	if ys.Redactor != nil {
+		// the files are masked like the documents
+		content = ys.Redactor.redactString(ys.Redactor.redactBody(content))
+	}
+	path, hash, err := writeBlob(dir, []byte(content), pkg.ToHttpHeader(header).Get("Content-Type"), ys.BodyStorage.Compress)
+	if err != nil {
+		ys.Logger.Error("failed to store the body in a file, keeping it in the yaml", zap.Error(err))
+		return false
+	}
+	*body, *bodyFile, *bodyHash = "", path, hash
+	return true
+}
+
+// writeBlob writes the body in the assets directory of dir under its hash, with the extension of its content type.
+// It returns the path of the file relative to dir and the hash of the body.
+func writeBlob(dir string, content []byte, contentType string, compress bool) (string, string, error) {
+	sum := sha256.Sum256(content)
+	hash := hex.EncodeToString(sum[:])
+	ext := ".bin"
+	if mediaType, _, err := mime.ParseMediaType(contentType); err == nil {
+		if extensions, _ := mime.ExtensionsByType(mediaType); len(extensions) > 0 {
+			ext = extensions[0]
+		}
+	}
+	if !compress {
+		path, err := writeAsset(dir, "body"+ext, content)
+		return path, hash, err
+	}
+	// the compressed files are named after the hash of the body rather than of their bytes
+	name := hash[:16] + ext + ".gz"
+	if err := os.MkdirAll(filepath.Join(dir, assetsDir), os.ModePerm); err != nil {
+		return "", "", err
+	}
+	path := filepath.Join(dir, assetsDir, name)
+	if _, err := os.Stat(path); err == nil {
+		return filepath.Join(assetsDir, name), hash, nil
+	}
+	var compressed bytes.Buffer
+	writer := gzip.NewWriter(&compressed)
+	if _, err := writer.Write(content); err != nil {
+		return "", "", err
+	}
+	if err := writer.Close(); err != nil {
+		return "", "", err
+	}
+	if err := os.WriteFile(path, compressed.Bytes(), 0644); err != nil {
+		return "", "", err
+	}
+	return filepath.Join(assetsDir, name), hash, nil
+}
+
+// loadBody puts back the body stored in a file by storeBodies. The files edited since the recording no longer match
+// their hash, which is only reported.
+func loadBody(dir string, body *string, bodyFile, bodyHash string, logger *zap.Logger) error {
+	if bodyFile == "" || *body != "" {
+		return nil
+	}
+	path, err := util.ValidatePath(filepath.Join(dir, bodyFile))
+	if err != nil {
+		return err
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read the body file %s: %v", bodyFile, err)
+	}
+	if strings.HasSuffix(bodyFile, ".gz") {
+		reader, err := gzip.NewReader(bytes.NewReader(content))
+		if err != nil {
+			return fmt.Errorf("failed to decompress the body file %s: %v", bodyFile, err)
+		}
+		content, err = io.ReadAll(reader)
+		if err != nil {
+			return fmt.Errorf("failed to decompress the body file %s: %v", bodyFile, err)
+		}
+	}
+	sum := sha256.Sum256(content)
+	if bodyHash != "" && hex.EncodeToString(sum[:]) != bodyHash {
+		logger.Warn("the body file doesn't match its hash, it was edited after the recording", zap.String("file", bodyFile))
+	}
+	*body = string(content)
+	return nil
+}
+
+// loadBodies puts back the bodies of the request and the response stored in files.
+func loadBodies(dir string, req *models.HttpReq, resp *models.HttpResp, logger *zap.Logger) error {
+	if req != nil {
+		if err := loadBody(dir, &req.Body, req.BodyFile, req.BodyHash, logger); err != nil {
+			return err
+		}
+	}
+	if resp != nil {
+		if err := loadBody(dir, &resp.Body, resp.BodyFile, resp.BodyHash, logger); err != nil {
+			return err
+		}
+	}
+	return nil
+}
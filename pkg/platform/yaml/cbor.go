@@ -0,0 +1,376 @@
+package yaml
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	yamlLib "gopkg.in/yaml.v3"
+)
+
+// The documents of a file can be stored in CBOR (RFC 8949) rather than in yaml, for the recordings whose mocks weigh
+// hundreds of MB: the CBOR file is a sequence of the documents, each one tagged as CBOR, holding the same tree as the
+// yaml one without having to be scanned as text. The scalars keep their yaml type, the ones CBOR has no type for
+// being stored as a [tag, value] array of tag 27.
+const (
+	// FormatYaml and FormatCbor are the formats the documents of a file are stored in
+	FormatYaml = "yaml"
+	FormatCbor = "cbor"
+
+	cborExt = ".cbor"
+
+	cborUint   = 0
+	cborNegInt = 1
+	cborBytes  = 2
+	cborText   = 3
+	cborArray  = 4
+	cborMap    = 5
+	cborTag    = 6
+	cborSimple = 7
+
+	cborTagDateTime     = 0
+	cborTagObject       = 27
+	cborTagSelfDescribe = 55799
+)
+
+// docsFormat returns the format the documents of the file are stored in, yaml when neither file exists.
+func docsFormat(path, name string) string {
+	if _, err := os.Stat(filepath.Join(path, name+".yaml")); err == nil {
+		return FormatYaml
+	}
+	if _, err := os.Stat(filepath.Join(path, name+cborExt)); err == nil {
+		return FormatCbor
+	}
+	return FormatYaml
+}
+
+// docsExist tells whether the documents of the file are stored, in either format.
+func docsExist(path, name string) bool {
+	for _, ext := range []string{".yaml", cborExt} {
+		if _, err := os.Stat(filepath.Join(path, name+ext)); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// ConvertDocs rewrites the documents of the file in the format, removing the file of the other format. It reports
+// false when they were already stored in it.
+func ConvertDocs(path, name, format string) (bool, error) {
+	if format != FormatYaml && format != FormatCbor {
+		return false, fmt.Errorf("unknown format %q of the documents, expected %s or %s", format, FormatYaml, FormatCbor)
+	}
+	from := docsFormat(path, name)
+	if from == format {
+		return false, nil
+	}
+	docs, err := readDocs(path, name)
+	if err != nil {
+		return false, err
+	}
+	if err := writeDocs(path, name, docs, format); err != nil {
+		return false, err
+	}
+	ext := ".yaml"
+	if from == FormatCbor {
+		ext = cborExt
+	}
+	if err := os.Remove(filepath.Join(path, name+ext)); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// readCborDocs returns the documents of the CBOR file.
+func readCborDocs(path, name string) ([]*NetworkTrafficDoc, error) {
+	data, err := os.ReadFile(filepath.Join(path, name+cborExt))
+	if err != nil {
+		return nil, err
+	}
+	docs := []*NetworkTrafficDoc{}
+	for len(data) > 0 {
+		node, rest, err := decodeCbor(data, 0)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode the cbor file documents. error: %v", err.Error())
+		}
+		data = rest
+		var doc NetworkTrafficDoc
+		if err := node.Decode(&doc); err != nil {
+			return nil, fmt.Errorf("failed to decode the cbor file documents. error: %v", err.Error())
+		}
+		if err := decryptDoc(&doc); err != nil {
+			return nil, err
+		}
+		docs = append(docs, &doc)
+	}
+	return docs, nil
+}
+
+// marshalCbor returns the CBOR of the document.
+func marshalCbor(doc *NetworkTrafficDoc) ([]byte, error) {
+	var node yamlLib.Node
+	if err := node.Encode(doc); err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	writeCborHead(&buf, cborTag, cborTagSelfDescribe)
+	if err := encodeCbor(&buf, &node); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func writeCborHead(buf *bytes.Buffer, major byte, n uint64) {
+	switch {
+	case n < 24:
+		buf.WriteByte(major<<5 | byte(n))
+	case n <= math.MaxUint8:
+		buf.WriteByte(major<<5 | 24)
+		buf.WriteByte(byte(n))
+	case n <= math.MaxUint16:
+		buf.WriteByte(major<<5 | 25)
+		buf.Write(binary.BigEndian.AppendUint16(nil, uint16(n)))
+	case n <= math.MaxUint32:
+		buf.WriteByte(major<<5 | 26)
+		buf.Write(binary.BigEndian.AppendUint32(nil, uint32(n)))
+	default:
+		buf.WriteByte(major<<5 | 27)
+		buf.Write(binary.BigEndian.AppendUint64(nil, n))
+	}
+}
+
+func writeCborText(buf *bytes.Buffer, text string) {
+	writeCborHead(buf, cborText, uint64(len(text)))
+	buf.WriteString(text)
+}
+
+// encodeCbor writes the CBOR of the yaml node, the aliases being written as the node they refer to.
+func encodeCbor(buf *bytes.Buffer, node *yamlLib.Node) error {
+	switch node.Kind {
+	case yamlLib.DocumentNode:
+		if len(node.Content) != 1 {
+			return errors.New("the yaml document has no content")
+		}
+		return encodeCbor(buf, node.Content[0])
+	case yamlLib.AliasNode:
+		return encodeCbor(buf, node.Alias)
+	case yamlLib.SequenceNode:
+		writeCborHead(buf, cborArray, uint64(len(node.Content)))
+		for _, item := range node.Content {
+			if err := encodeCbor(buf, item); err != nil {
+				return err
+			}
+		}
+		return nil
+	case yamlLib.MappingNode:
+		writeCborHead(buf, cborMap, uint64(len(node.Content)/2))
+		for _, item := range node.Content {
+			if err := encodeCbor(buf, item); err != nil {
+				return err
+			}
+		}
+		return nil
+	case yamlLib.ScalarNode:
+		encodeCborScalar(buf, node)
+		return nil
+	}
+	return fmt.Errorf("unknown kind %d of the yaml node", node.Kind)
+}
+
+func encodeCborScalar(buf *bytes.Buffer, node *yamlLib.Node) {
+	tag := node.ShortTag()
+	switch tag {
+	case "!!str":
+		writeCborText(buf, node.Value)
+		return
+	case "!!null":
+		buf.WriteByte(cborSimple<<5 | 22)
+		return
+	case "!!bool":
+		switch strings.ToLower(node.Value) {
+		case "true":
+			buf.WriteByte(cborSimple<<5 | 21)
+			return
+		case "false":
+			buf.WriteByte(cborSimple<<5 | 20)
+			return
+		}
+	case "!!int":
+		if n, err := strconv.ParseInt(node.Value, 0, 64); err == nil {
+			if n >= 0 {
+				writeCborHead(buf, cborUint, uint64(n))
+			} else {
+				writeCborHead(buf, cborNegInt, uint64(-1-n))
+			}
+			return
+		}
+	case "!!float":
+		if f, err := strconv.ParseFloat(node.Value, 64); err == nil {
+			buf.WriteByte(cborSimple<<5 | 27)
+			buf.Write(binary.BigEndian.AppendUint64(nil, math.Float64bits(f)))
+			return
+		}
+	case "!!binary":
+		if b, err := base64.StdEncoding.DecodeString(node.Value); err == nil {
+			writeCborHead(buf, cborBytes, uint64(len(b)))
+			buf.Write(b)
+			return
+		}
+	case "!!timestamp":
+		writeCborHead(buf, cborTag, cborTagDateTime)
+		writeCborText(buf, node.Value)
+		return
+	}
+	// the scalars CBOR has no type for keep their yaml tag
+	writeCborHead(buf, cborTag, cborTagObject)
+	writeCborHead(buf, cborArray, 2)
+	writeCborText(buf, tag)
+	writeCborText(buf, node.Value)
+}
+
+// readCborHead returns the major type, the additional information and the argument of the head of the item.
+func readCborHead(data []byte) (byte, byte, uint64, []byte, error) {
+	if len(data) == 0 {
+		return 0, 0, 0, nil, errors.New("unexpected end of the cbor data")
+	}
+	major, info := data[0]>>5, data[0]&0x1f
+	data = data[1:]
+	size := 0
+	switch {
+	case info < 24:
+		return major, info, uint64(info), data, nil
+	case info == 24:
+		size = 1
+	case info == 25:
+		size = 2
+	case info == 26:
+		size = 4
+	case info == 27:
+		size = 8
+	default:
+		return 0, 0, 0, nil, fmt.Errorf("unsupported additional information %d of the cbor item", info)
+	}
+	if len(data) < size {
+		return 0, 0, 0, nil, errors.New("unexpected end of the cbor data")
+	}
+	var n uint64
+	for _, b := range data[:size] {
+		n = n<<8 | uint64(b)
+	}
+	return major, info, n, data[size:], nil
+}
+
+func scalarNode(tag, value string) *yamlLib.Node {
+	return &yamlLib.Node{Kind: yamlLib.ScalarNode, Tag: tag, Value: value}
+}
+
+// decodeCbor returns the yaml node of the first CBOR item of the data, and the data following it.
+func decodeCbor(data []byte, depth int) (*yamlLib.Node, []byte, error) {
+	if depth > 10000 {
+		return nil, nil, errors.New("the cbor items are nested too deep")
+	}
+	major, info, n, data, err := readCborHead(data)
+	if err != nil {
+		return nil, nil, err
+	}
+	switch major {
+	case cborUint:
+		return scalarNode("!!int", strconv.FormatUint(n, 10)), data, nil
+	case cborNegInt:
+		if n > math.MaxInt64 {
+			return nil, nil, fmt.Errorf("the negative integer -1-%d overflows", n)
+		}
+		return scalarNode("!!int", strconv.FormatInt(-1-int64(n), 10)), data, nil
+	case cborBytes, cborText:
+		if uint64(len(data)) < n {
+			return nil, nil, errors.New("unexpected end of the cbor data")
+		}
+		value := data[:n]
+		if major == cborBytes {
+			return scalarNode("!!binary", base64.StdEncoding.EncodeToString(value)), data[n:], nil
+		}
+		return scalarNode("!!str", string(value)), data[n:], nil
+	case cborArray, cborMap:
+		count := n
+		kind := yamlLib.SequenceNode
+		if major == cborMap {
+			count, kind = 2*n, yamlLib.MappingNode
+		}
+		// each item takes a byte at least
+		if uint64(len(data)) < count {
+			return nil, nil, errors.New("unexpected end of the cbor data")
+		}
+		node := &yamlLib.Node{Kind: kind, Content: make([]*yamlLib.Node, 0, count)}
+		if kind == yamlLib.SequenceNode {
+			node.Tag = "!!seq"
+		} else {
+			node.Tag = "!!map"
+		}
+		for i := uint64(0); i < count; i++ {
+			var item *yamlLib.Node
+			item, data, err = decodeCbor(data, depth+1)
+			if err != nil {
+				return nil, nil, err
+			}
+			node.Content = append(node.Content, item)
+		}
+		return node, data, nil
+	case cborTag:
+		switch n {
+		case cborTagSelfDescribe:
+			return decodeCbor(data, depth+1)
+		case cborTagDateTime:
+			node, rest, err := decodeCbor(data, depth+1)
+			if err != nil {
+				return nil, nil, err
+			}
+			node.Tag = "!!timestamp"
+			return node, rest, nil
+		case cborTagObject:
+			node, rest, err := decodeCbor(data, depth+1)
+			if err != nil {
+				return nil, nil, err
+			}
+			if node.Kind != yamlLib.SequenceNode || len(node.Content) != 2 {
+				return nil, nil, errors.New("the tagged scalar isn't a [tag, value] array")
+			}
+			return scalarNode(node.Content[0].Value, node.Content[1].Value), rest, nil
+		}
+		return nil, nil, fmt.Errorf("unsupported tag %d of the cbor item", n)
+	case cborSimple:
+		switch info {
+		case 20:
+			return scalarNode("!!bool", "false"), data, nil
+		case 21:
+			return scalarNode("!!bool", "true"), data, nil
+		case 22, 23:
+			return scalarNode("!!null", "null"), data, nil
+		case 26:
+			return scalarNode("!!float", strconv.FormatFloat(float64(math.Float32frombits(uint32(n))), 'g', -1, 32)), data, nil
+		case 27:
+			return scalarNode("!!float", formatFloat(math.Float64frombits(n))), data, nil
+		}
+		return nil, nil, fmt.Errorf("unsupported simple value %d of the cbor item", info)
+	}
+	return nil, nil, fmt.Errorf("unsupported major type %d of the cbor item", major)
+}
+
+// formatFloat returns the yaml of the float, including the infinities and NaN.
+func formatFloat(f float64) string {
+	switch {
+	case math.IsInf(f, 1):
+		return ".inf"
+	case math.IsInf(f, -1):
+		return "-.inf"
+	case math.IsNaN(f):
+		return ".nan"
+	}
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
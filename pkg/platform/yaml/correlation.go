@@ -0,0 +1,182 @@
+package yaml
+
+import (
+	"encoding/json"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.keploy.io/server/pkg"
+	"go.keploy.io/server/pkg/models"
+)
+
+// minCorrelatedLength is the length under which the values of the responses aren't looked for in the next requests,
+// the short ones being likely to be found there by chance.
+const minCorrelatedLength = 6
+
+// maxCorrelatedTests is the number of previous test cases whose responses are looked for in a request.
+const maxCorrelatedTests = 50
+
+// recordedResponse holds the values of the JSON response of a recorded test case, along with their paths.
+type recordedResponse struct {
+	name   string
+	done   time.Time
+	values map[string]string
+}
+
+// templatize replaces the values of the request of the test case which were returned by the previous test cases
+// with references to variables, so that they are taken from the actual responses of these test cases when the
+// test case is replayed. The values of its response are then kept for the next test cases.
+func (ys *Yaml) templatize(tc *models.TestCase, name string) {
+	ys.mutex.Lock()
+	defer ys.mutex.Unlock()
+
+	// the requests which already look like templates are kept as they are
+	if !strings.Contains(tc.HttpReq.URL+tc.HttpReq.Body, "{{") && !headerContains(tc.HttpReq.Header, "{{") {
+		for i := len(ys.responses) - 1; i >= 0; i-- {
+			previous := ys.responses[i]
+			if !previous.done.Before(tc.HttpReq.Timestamp) {
+				continue
+			}
+			values := make([]string, 0, len(previous.values))
+			for value := range previous.values {
+				values = append(values, value)
+			}
+			// the longer values are replaced first, a value may contain a shorter one
+			sort.Slice(values, func(a, b int) bool {
+				if len(values[a]) != len(values[b]) {
+					return len(values[a]) > len(values[b])
+				}
+				return values[a] < values[b]
+			})
+			for _, value := range values {
+				variable := models.Variable{From: previous.name, Path: previous.values[value], Value: value}
+				name := variableName(tc, variable)
+				if replaceInRequest(&tc.HttpReq, value, "{{."+name+"}}") {
+					if tc.Variables == nil {
+						tc.Variables = map[string]models.Variable{}
+					}
+					tc.Variables[name] = variable
+				}
+			}
+		}
+	}
+
+	ys.responses = append(ys.responses, recordedResponse{
+		name:   name,
+		done:   tc.HttpResp.Timestamp,
+		values: correlatableValues(tc.HttpResp.Body),
+	})
+	if len(ys.responses) > maxCorrelatedTests {
+		ys.responses = ys.responses[len(ys.responses)-maxCorrelatedTests:]
+	}
+}
+
+// correlatableValues returns the values of a JSON body which are distinctive enough to be looked for in the next
+// requests, each one with its path.
+func correlatableValues(body string) map[string]string {
+	values := map[string]string{}
+	for path, value := range pkg.JsonLeaves(body) {
+		if len(value) < minCorrelatedLength || pkg.IsTime(value) || strings.Contains(value, "{{") {
+			continue
+		}
+		// the values which are escaped in JSON wouldn't be found as they are in the requests
+		if quoted, err := json.Marshal(value); err != nil || string(quoted) != `"`+value+`"` {
+			continue
+		}
+		if existing, ok := values[value]; !ok || path < existing {
+			values[value] = path
+		}
+	}
+	return values
+}
+
+// variableName returns the name of the variable in the test case, derived from the last key of its path.
+func variableName(tc *models.TestCase, variable models.Variable) string {
+	base := "value"
+	segments := strings.Split(variable.Path, ".")
+	for i := len(segments) - 1; i > 0; i-- {
+		if _, err := strconv.Atoi(segments[i]); err != nil {
+			base = segments[i]
+			break
+		}
+	}
+	base = strings.Map(func(r rune) rune {
+		if r == '_' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' {
+			return r
+		}
+		return '_'
+	}, base)
+	if base == "" {
+		base = "value"
+	} else if base[0] >= '0' && base[0] <= '9' {
+		base = "v" + base
+	}
+
+	name := base
+	for i := 2; ; i++ {
+		existing, ok := tc.Variables[name]
+		if !ok || existing.From == variable.From && existing.Path == variable.Path {
+			return name
+		}
+		name = base + strconv.Itoa(i)
+	}
+}
+
+// replaceInRequest replaces the occurrences of the value in the url, the headers and the body of the request.
+func replaceInRequest(req *models.HttpReq, value, reference string) bool {
+	var replaced, ok bool
+	req.URL, ok = replaceValue(req.URL, value, reference)
+	replaced = replaced || ok
+	for key, headerValue := range req.Header {
+		if strings.HasPrefix(strings.ToLower(key), "keploy") {
+			continue
+		}
+		req.Header[key], ok = replaceValue(headerValue, value, reference)
+		replaced = replaced || ok
+	}
+	req.Body, ok = replaceValue(req.Body, value, reference)
+	return replaced || ok
+}
+
+// replaceValue replaces the occurrences of the value which aren't part of a longer word, or of a reference, of the
+// text.
+func replaceValue(text, value, reference string) (string, bool) {
+	var buf strings.Builder
+	replaced := false
+	written := 0
+	for from := 0; ; {
+		i := strings.Index(text[from:], value)
+		if i < 0 {
+			break
+		}
+		start, end := from+i, from+i+len(value)
+		from = start + 1
+		if start > 0 && isWordByte(text[start-1]) || end < len(text) && isWordByte(text[end]) {
+			continue
+		}
+		if strings.LastIndex(text[:start], "{{") > strings.LastIndex(text[:start], "}}") {
+			continue
+		}
+		buf.WriteString(text[written:start])
+		buf.WriteString(reference)
+		written, from = end, end
+		replaced = true
+	}
+	buf.WriteString(text[written:])
+	return buf.String(), replaced
+}
+
+func isWordByte(b byte) bool {
+	return b >= 'a' && b <= 'z' || b >= 'A' && b <= 'Z' || b >= '0' && b <= '9'
+}
+
+func headerContains(header map[string]string, substr string) bool {
+	for _, value := range header {
+		if strings.Contains(value, substr) {
+			return true
+		}
+	}
+	return false
+}
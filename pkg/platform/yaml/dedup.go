@@ -0,0 +1,169 @@
+package yaml
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"go.keploy.io/server/pkg"
+	"go.keploy.io/server/pkg/models"
+	"go.keploy.io/server/pkg/platform/yaml/spec"
+	"go.keploy.io/server/pkg/proxy/integrations/graphql"
+	"go.uber.org/zap"
+)
+
+// volatileHeaders are the headers which differ between the identical requests, left out of their comparison.
+var volatileHeaders = map[string]bool{
+	"Date":             true,
+	"Content-Length":   true,
+	"User-Agent":       true,
+	"Keploy-Test-Name": true,
+	"X-Request-Id":     true,
+	"Traceparent":      true,
+	"Tracestate":       true,
+}
+
+// capturedRequest is a request recorded as a test case of the session, along with the number of its captures.
+type capturedRequest struct {
+	name  string
+	doc   *NetworkTrafficDoc
+	count int
+}
+
+// isTimestamp tells whether the value is a date, like the noisy fields of the responses. The short and the numeric
+// values, like the ids, aren't taken as dates.
+func isTimestamp(value string) bool {
+	return len(value) >= 10 && strings.ContainsAny(value, "-:/ ") && pkg.IsTime(value)
+}
+
+// normalizeValue replaces the dates of the decoded JSON value, so that the requests differing by them are identical.
+func normalizeValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, field := range v {
+			v[key] = normalizeValue(field)
+		}
+	case []interface{}:
+		for i, item := range v {
+			v[i] = normalizeValue(item)
+		}
+	case string:
+		if isTimestamp(v) {
+			return "<date>"
+		}
+	}
+	return value
+}
+
+// requestKey returns the key of the request of the test case, equal for the identical requests once their noise,
+// the volatile headers, the dates and the order of the query parameters and the JSON fields, is normalized.
+func requestKey(tc *models.TestCase) string {
+	var b strings.Builder
+	b.WriteString(string(tc.HttpReq.Method))
+	b.WriteString(" ")
+	if parsedURL, err := url.Parse(tc.HttpReq.URL); err == nil {
+		b.WriteString(parsedURL.Path)
+		b.WriteString("?")
+		b.WriteString(parsedURL.Query().Encode())
+	} else {
+		b.WriteString(tc.HttpReq.URL)
+	}
+	b.WriteString("\n")
+
+	names := make([]string, 0, len(tc.HttpReq.Header))
+	for name := range tc.HttpReq.Header {
+		if !volatileHeaders[http.CanonicalHeaderKey(name)] {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		value := tc.HttpReq.Header[name]
+		if isTimestamp(value) {
+			value = "<date>"
+		}
+		b.WriteString(http.CanonicalHeaderKey(name) + ": " + value + "\n")
+	}
+
+	var body interface{}
+	if err := json.Unmarshal([]byte(tc.HttpReq.Body), &body); err == nil {
+		if gql, ok := graphql.Parse([]byte(tc.HttpReq.Body)); ok {
+			// the GraphQL documents differing only by their formatting are the same request
+			body = map[string]interface{}{"operation": gql.Operation(), "query": graphql.Normalize(gql.Query), "variables": gql.Variables}
+		}
+		// the fields of the objects are marshalled in the order of their names
+		if normalized, err := json.Marshal(normalizeValue(body)); err == nil {
+			b.Write(normalized)
+		}
+	} else {
+		b.WriteString(tc.HttpReq.Body)
+	}
+
+	sum := sha256.Sum256([]byte(b.String()))
+	return hex.EncodeToString(sum[:])
+}
+
+// duplicate tells whether an identical request was already recorded as a test case of the session, in which case the
+// capture is counted in the metadata of that test case.
+func (ys *Yaml) duplicate(key string) bool {
+	ys.mutex.Lock()
+	defer ys.mutex.Unlock()
+	if ys.captured == nil {
+		ys.captured = map[string]*capturedRequest{}
+	}
+	captured, ok := ys.captured[key]
+	if !ok {
+		ys.captured[key] = &capturedRequest{count: 1}
+		return false
+	}
+	captured.count++
+	// the count is written once the test case of the first capture is
+	if captured.doc != nil {
+		ys.writeCaptures(captured)
+	}
+	return true
+}
+
+// captureWritten keeps the test case written for the first capture of the request, or forgets the request when the
+// test case couldn't be written.
+func (ys *Yaml) captureWritten(key, name string, doc *NetworkTrafficDoc) {
+	ys.mutex.Lock()
+	defer ys.mutex.Unlock()
+	captured, ok := ys.captured[key]
+	if !ok {
+		return
+	}
+	if doc == nil {
+		delete(ys.captured, key)
+		return
+	}
+	captured.name, captured.doc = name, doc
+	if captured.count > 1 {
+		ys.writeCaptures(captured)
+	}
+}
+
+// writeCaptures writes the number of the captures of the request in the metadata of its test case.
+func (ys *Yaml) writeCaptures(captured *capturedRequest) {
+	httpSpec := spec.HttpSpec{}
+	if err := captured.doc.Spec.Decode(&httpSpec); err != nil {
+		ys.Logger.Error("failed to decode the testcase to count its duplicates", zap.String("testcase name", captured.name), zap.Error(err))
+		return
+	}
+	if httpSpec.Metadata == nil {
+		httpSpec.Metadata = map[string]string{}
+	}
+	httpSpec.Metadata["captures"] = strconv.Itoa(captured.count)
+	if err := captured.doc.Spec.Encode(httpSpec); err != nil {
+		ys.Logger.Error("failed to encode the testcase to count its duplicates", zap.String("testcase name", captured.name), zap.Error(err))
+		return
+	}
+	if err := WriteDocs(ys.TcsPath, captured.name, []*NetworkTrafficDoc{captured.doc}); err != nil {
+		ys.Logger.Error("failed to write the count of the duplicates of the testcase", zap.String("testcase name", captured.name), zap.Error(err))
+	}
+}
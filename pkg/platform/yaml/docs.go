@@ -0,0 +1,90 @@
+package yaml
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	yamlLib "gopkg.in/yaml.v3"
+)
+
+// ReadDocs returns the documents of the yaml file, like the mocks of a test set, or of the CBOR file when they are
+// stored in CBOR.
+func ReadDocs(path, name string) ([]*NetworkTrafficDoc, error) {
+	return read(path, name)
+}
+
+// WriteDocs replaces the documents of the yaml file, or of the CBOR file when they are stored in CBOR. The file is
+// written next to the previous one before being renamed, so that it is never left half written.
+func WriteDocs(path, name string, docs []*NetworkTrafficDoc) error {
+	return writeDocs(path, name, docs, docsFormat(path, name))
+}
+
+func writeDocs(path, name string, docs []*NetworkTrafficDoc, format string) error {
+	data := []byte{}
+	for i, doc := range docs {
+		if i > 0 && format == FormatYaml {
+			data = append(data, []byte("---\n")...)
+		}
+		encrypted, err := encryptDoc(doc)
+		if err != nil {
+			return fmt.Errorf("failed to encrypt the yaml document %s: %v", doc.Name, err)
+		}
+		var d []byte
+		if format == FormatCbor {
+			d, err = marshalCbor(encrypted)
+		} else {
+			d, err = yamlLib.Marshal(encrypted)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to marshal the yaml document %s: %v", doc.Name, err)
+		}
+		data = append(data, d...)
+	}
+	if err := os.MkdirAll(path, 0777); err != nil {
+		return fmt.Errorf("failed to create the directory %s: %v", path, err)
+	}
+	filePath := filepath.Join(path, name+".yaml")
+	if format == FormatCbor {
+		filePath = filepath.Join(path, name+cborExt)
+	}
+	tempPath := filePath + ".tmp"
+	if err := os.WriteFile(tempPath, data, os.ModePerm); err != nil {
+		return fmt.Errorf("failed to write the yaml file %s: %v", filePath, err)
+	}
+	if err := os.Rename(tempPath, filePath); err != nil {
+		os.Remove(tempPath)
+		return fmt.Errorf("failed to replace the yaml file %s: %v", filePath, err)
+	}
+	return nil
+}
+
+// TestcaseNames returns the names of the testcase files of the directory (test-1, test-2, ...) in their order.
+func TestcaseNames(path string) ([]string, error) {
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	indexes := map[string]int{}
+	names := []string{}
+	for _, entry := range entries {
+		name := strings.TrimSuffix(entry.Name(), ".yaml")
+		if entry.IsDir() || name == entry.Name() || !strings.HasPrefix(name, "test-") {
+			continue
+		}
+		index, err := strconv.Atoi(strings.TrimPrefix(name, "test-"))
+		if err != nil {
+			continue
+		}
+		indexes[name] = index
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool { return indexes[names[i]] < indexes[names[j]] })
+	return names, nil
+}
@@ -0,0 +1,148 @@
+package yaml
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+
+	yamlLib "gopkg.in/yaml.v3"
+)
+
+// the environment variables of the AES key encrypting the tests and mocks, either the key itself or the command
+// printing it, like the decryption of a data key by a KMS
+const (
+	EncryptionKeyEnv        = "KEPLOY_ENCRYPTION_KEY"
+	EncryptionKeyCommandEnv = "KEPLOY_ENCRYPTION_KEY_COMMAND"
+)
+
+// encryptedTag is the yaml tag of the specs encrypted.
+const encryptedTag = "!encrypted"
+
+// encryption holds the cipher of the key of the environment, loaded once.
+var encryption struct {
+	once sync.Once
+	aead cipher.AEAD
+	err  error
+}
+
+// encryptedPayload is the part of the document which is encrypted, its version, kind and name being left in clear so
+// that the files can still be listed.
+type encryptedPayload struct {
+	Spec yamlLib.Node `yaml:"spec"`
+	Curl string       `yaml:"curl,omitempty"`
+}
+
+// decodeKey decodes the base64 or hex AES key, of 16, 24 or 32 bytes.
+func decodeKey(encoded string) ([]byte, error) {
+	encoded = strings.TrimSpace(encoded)
+	key, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		key, err = hex.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("the encryption key is neither base64 nor hex encoded")
+		}
+	}
+	switch len(key) {
+	case 16, 24, 32:
+		return key, nil
+	}
+	return nil, fmt.Errorf("the encryption key has %d bytes instead of 16, 24 or 32", len(key))
+}
+
+// encryptionCipher returns the cipher of the key of the environment, or nil when no key is set.
+func encryptionCipher() (cipher.AEAD, error) {
+	encryption.once.Do(func() {
+		encoded := os.Getenv(EncryptionKeyEnv)
+		if command := os.Getenv(EncryptionKeyCommandEnv); encoded == "" && command != "" {
+			out, err := exec.Command("sh", "-c", command).Output()
+			if err != nil {
+				encryption.err = fmt.Errorf("failed to run the command of the encryption key: %v", err)
+				return
+			}
+			encoded = string(out)
+		}
+		if encoded == "" {
+			return
+		}
+		key, err := decodeKey(encoded)
+		if err != nil {
+			encryption.err = err
+			return
+		}
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			encryption.err = err
+			return
+		}
+		encryption.aead, encryption.err = cipher.NewGCM(block)
+	})
+	return encryption.aead, encryption.err
+}
+
+// additionalData binds the encrypted spec to the version, kind and name of its document.
+func additionalData(doc *NetworkTrafficDoc) []byte {
+	return []byte(string(doc.Version) + "/" + string(doc.Kind) + "/" + doc.Name)
+}
+
+// encryptDoc returns the document with its spec and curl encrypted, or the document itself when no key is set.
+func encryptDoc(doc *NetworkTrafficDoc) (*NetworkTrafficDoc, error) {
+	aead, err := encryptionCipher()
+	if err != nil || aead == nil || doc == nil {
+		return doc, err
+	}
+	plaintext, err := yamlLib.Marshal(encryptedPayload{Spec: doc.Spec, Curl: doc.Curl})
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	sealed := aead.Seal(nonce, nonce, plaintext, additionalData(doc))
+	encrypted := &NetworkTrafficDoc{
+		Version: doc.Version,
+		Kind:    doc.Kind,
+		Name:    doc.Name,
+		Spec: yamlLib.Node{
+			Kind:  yamlLib.ScalarNode,
+			Tag:   encryptedTag,
+			Value: base64.StdEncoding.EncodeToString(sealed),
+		},
+	}
+	return encrypted, nil
+}
+
+// decryptDoc decrypts the spec and curl of the document in place, when they are encrypted.
+func decryptDoc(doc *NetworkTrafficDoc) error {
+	if doc.Spec.Kind != yamlLib.ScalarNode || doc.Spec.Tag != encryptedTag {
+		return nil
+	}
+	aead, err := encryptionCipher()
+	if err != nil {
+		return err
+	}
+	if aead == nil {
+		return fmt.Errorf("%s is encrypted, set %s or %s to decrypt it", doc.Name, EncryptionKeyEnv, EncryptionKeyCommandEnv)
+	}
+	sealed, err := base64.StdEncoding.DecodeString(doc.Spec.Value)
+	if err != nil || len(sealed) < aead.NonceSize() {
+		return fmt.Errorf("the encrypted spec of %s is malformed", doc.Name)
+	}
+	plaintext, err := aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], additionalData(doc))
+	if err != nil {
+		return fmt.Errorf("failed to decrypt %s, check the encryption key: %v", doc.Name, err)
+	}
+	payload := encryptedPayload{}
+	if err := yamlLib.Unmarshal(plaintext, &payload); err != nil {
+		return fmt.Errorf("failed to decode the decrypted spec of %s: %v", doc.Name, err)
+	}
+	doc.Spec, doc.Curl = payload.Spec, payload.Curl
+	return nil
+}
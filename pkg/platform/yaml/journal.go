@@ -0,0 +1,209 @@
+package yaml
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"go.keploy.io/server/pkg/proxy/util"
+	"go.uber.org/zap"
+)
+
+// The mocks are appended to a journal next to their yaml file while they are recorded, each one being synced to the
+// disk as a whole document. The journal is moved into the yaml file when the recording ends, or when the mocks are
+// read after a recording which was killed, its last document being dropped when it was cut short.
+const (
+	journalExt = ".yaml.journal"
+	// journalDocStart and journalDocEnd are the markers of the yaml documents around each mock of the journal, the end
+	// marker telling that the mock was written whole
+	journalDocStart = "---\n"
+	journalDocEnd   = "...\n"
+)
+
+func journalPath(path, name string) string {
+	return filepath.Join(path, name+journalExt)
+}
+
+// appendJournal appends the document to the journal of the yaml file and syncs it to the disk.
+func (ys *Yaml) appendJournal(path, name string, doc *NetworkTrafficDoc) error {
+	d, err := ys.marshalDoc(doc, name)
+	if err != nil {
+		return err
+	}
+	data := make([]byte, 0, len(journalDocStart)+len(d)+len(journalDocEnd))
+	data = append(append(append(data, journalDocStart...), d...), journalDocEnd...)
+
+	ys.journalMutex.Lock()
+	defer ys.journalMutex.Unlock()
+	// the yaml file is created empty, along with its directory
+	if _, err := util.CreateYamlFile(path, name, ys.Logger); err != nil {
+		return err
+	}
+	journal, err := util.ValidatePath(journalPath(path, name))
+	if err != nil {
+		return err
+	}
+	file, err := os.OpenFile(journal, os.O_CREATE|os.O_WRONLY|os.O_APPEND, os.ModePerm)
+	if err != nil {
+		ys.Logger.Error("failed to open the journal of the mocks", zap.Error(err), zap.Any("yaml file name", name))
+		return err
+	}
+	defer file.Close()
+	// the document is written at once, so that a crash leaves it either whole or cut short at the end of the journal
+	if _, err := file.Write(data); err != nil {
+		ys.Logger.Error("failed to write the mock in the journal", zap.Error(err), zap.Any("yaml file name", name))
+		return err
+	}
+	if err := file.Sync(); err != nil {
+		ys.Logger.Error("failed to sync the journal of the mocks", zap.Error(err), zap.Any("yaml file name", name))
+		return err
+	}
+	if ys.journals == nil {
+		ys.journals = map[string]string{}
+	}
+	ys.journals[journal] = name
+	return nil
+}
+
+// FinalizeMocks moves the journals of the mocks recorded by the store into their yaml files.
+func (ys *Yaml) FinalizeMocks() {
+	ys.journalMutex.Lock()
+	defer ys.journalMutex.Unlock()
+	journals := make([]string, 0, len(ys.journals))
+	for journal := range ys.journals {
+		journals = append(journals, journal)
+	}
+	sort.Strings(journals)
+	for _, journal := range journals {
+		path, name := filepath.Dir(journal), ys.journals[journal]
+		if err := finalizeJournal(path, name, ys.Logger); err != nil {
+			ys.Logger.Error("failed to move the recorded mocks into their yaml file, they are kept in the journal", zap.Error(err), zap.String("journal", journal))
+			continue
+		}
+		delete(ys.journals, journal)
+		if ys.MockFormat == FormatCbor {
+			if _, err := ConvertDocs(path, name, FormatCbor); err != nil {
+				ys.Logger.Error("failed to convert the recorded mocks to cbor, they are kept in their yaml file", zap.Error(err), zap.String("path", path))
+			}
+		}
+	}
+}
+
+// recoverJournal moves the journal left by a recording which didn't end into its yaml file, before it is read.
+func (ys *Yaml) recoverJournal(path, name string) {
+	ys.journalMutex.Lock()
+	defer ys.journalMutex.Unlock()
+	journal := journalPath(path, name)
+	if _, recording := ys.journals[journal]; recording {
+		return
+	}
+	_, journalErr := os.Stat(journal)
+	_, tempErr := os.Stat(filepath.Join(path, name+".yaml.tmp"))
+	if journalErr != nil && tempErr != nil {
+		return
+	}
+	ys.Logger.Info("recovering the mocks of a recording which didn't end", zap.String("journal", journal))
+	if err := finalizeJournal(path, name, ys.Logger); err != nil {
+		ys.Logger.Error("failed to recover the mocks of the journal", zap.Error(err), zap.String("journal", journal))
+	}
+}
+
+// finalizeJournal appends the whole documents of the journal to the yaml file, which is replaced atomically with a
+// temporary file. The journal is removed before the temporary file is renamed, so that a crash in between leaves
+// either the journal or the temporary file holding the mocks, never both of them being moved.
+func finalizeJournal(path, name string, logger *zap.Logger) error {
+	journal, err := util.ValidatePath(journalPath(path, name))
+	if err != nil {
+		return err
+	}
+	yamlPath, err := util.ValidatePath(filepath.Join(path, name+".yaml"))
+	if err != nil {
+		return err
+	}
+	temp := yamlPath + ".tmp"
+	content, err := os.ReadFile(journal)
+	if os.IsNotExist(err) {
+		// the journal was removed, the temporary file holds all the mocks
+		if _, err := os.Stat(temp); err != nil {
+			return nil
+		}
+		return renameSynced(temp, yamlPath, path)
+	}
+	if err != nil {
+		return err
+	}
+	docs, dropped := journalDocs(content)
+	if dropped > 0 {
+		logger.Warn("dropping the end of the journal of the mocks, which was cut short", zap.String("journal", journal), zap.Int("bytes", dropped))
+	}
+
+	existing, err := os.ReadFile(yamlPath)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	var data bytes.Buffer
+	data.Write(existing)
+	for _, doc := range docs {
+		if data.Len() > 0 {
+			data.WriteString(journalDocStart)
+		}
+		data.Write(doc)
+	}
+
+	if err := writeSynced(temp, data.Bytes()); err != nil {
+		return fmt.Errorf("failed to write the mocks in %s: %v", temp, err)
+	}
+	if err := os.Remove(journal); err != nil {
+		return err
+	}
+	return renameSynced(temp, yamlPath, path)
+}
+
+// renameSynced renames the file and syncs its directory, so that the rename is durable.
+func renameSynced(from, to, dir string) error {
+	if err := os.Rename(from, to); err != nil {
+		return err
+	}
+	if d, err := os.Open(dir); err == nil {
+		_ = d.Sync()
+		d.Close()
+	}
+	return nil
+}
+
+// journalDocs returns the documents written whole in the journal, without their markers, and the number of bytes of
+// the last one when it was cut short.
+func journalDocs(content []byte) ([][]byte, int) {
+	var docs [][]byte
+	rest := content
+	for len(rest) > 0 {
+		if !bytes.HasPrefix(rest, []byte(journalDocStart)) {
+			return docs, len(rest)
+		}
+		end := bytes.Index(rest, []byte("\n"+journalDocEnd))
+		if end < 0 {
+			return docs, len(rest)
+		}
+		docs = append(docs, rest[len(journalDocStart):end+1])
+		rest = rest[end+1+len(journalDocEnd):]
+	}
+	return docs, 0
+}
+
+func writeSynced(path string, data []byte) error {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, os.ModePerm)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
@@ -0,0 +1,98 @@
+package yaml
+
+import (
+	"fmt"
+
+	"go.keploy.io/server/pkg/models"
+	"go.keploy.io/server/pkg/platform/yaml/spec"
+)
+
+// migration upgrades the documents of a version of the schema to the next one.
+type migration struct {
+	from    models.Version
+	to      models.Version
+	migrate func(doc *NetworkTrafficDoc) error
+}
+
+// migrations are the upgrades between the consecutive versions of models.Versions.
+var migrations = []migration{
+	{from: models.V1Beta1, to: models.V1Beta2, migrate: noiseAsMap},
+}
+
+// noiseAsMap writes the noise of the assertions of the http tests, which v1beta1 also allowed as a list of fields,
+// as a map of the fields to their ignored values.
+func noiseAsMap(doc *NetworkTrafficDoc) error {
+	if doc.Kind != models.HTTP {
+		return nil
+	}
+	httpSpec := spec.HttpSpec{}
+	if err := doc.Spec.Decode(&httpSpec); err != nil {
+		return err
+	}
+	fields, ok := httpSpec.Assertions["noise"].([]interface{})
+	if !ok {
+		return nil
+	}
+	noise := map[string][]string{}
+	for _, field := range fields {
+		noise[fmt.Sprint(field)] = []string{}
+	}
+	httpSpec.Assertions["noise"] = noise
+	return doc.Spec.Encode(httpSpec)
+}
+
+// versionIndex returns the index of the version in models.Versions, the documents without a version being v1beta1.
+func versionIndex(version models.Version) int {
+	if version == "" {
+		return 0
+	}
+	for i, v := range models.Versions {
+		if v == version {
+			return i
+		}
+	}
+	return -1
+}
+
+// migrateDoc upgrades the document to the current version of the schema, and tells whether it was upgraded. The
+// documents of the versions unknown to this keploy, like the ones of a newer keploy, can't be read.
+func migrateDoc(doc *NetworkTrafficDoc) (bool, error) {
+	index := versionIndex(doc.Version)
+	current := versionIndex(models.GetVersion())
+	if index == -1 || (current != -1 && index > current) {
+		return false, fmt.Errorf("%s has the version %s of the schema, unknown to this keploy which reads the versions up to %s, upgrade keploy to read it", doc.Name, doc.Version, models.GetVersion())
+	}
+	if index == current || current == -1 {
+		return false, nil
+	}
+	for _, m := range migrations[index:current] {
+		if err := m.migrate(doc); err != nil {
+			return false, fmt.Errorf("failed to migrate %s from %s to %s: %v", doc.Name, m.from, m.to, err)
+		}
+		doc.Version = m.to
+	}
+	return true, nil
+}
+
+// MigrateFile upgrades the documents of the yaml file to the current version of the schema in place, and returns the
+// number of the documents upgraded. The file is only rewritten when a document was upgraded and dryRun is false.
+func MigrateFile(path, name string, dryRun bool) (int, error) {
+	docs, err := readDocs(path, name)
+	if err != nil {
+		return 0, err
+	}
+	migrated := 0
+	for _, doc := range docs {
+		upgraded, err := migrateDoc(doc)
+		if err != nil {
+			return 0, err
+		}
+		if upgraded {
+			migrated++
+		}
+	}
+	if migrated == 0 || dryRun {
+		return migrated, nil
+	}
+	return migrated, WriteDocs(path, name, docs)
+}
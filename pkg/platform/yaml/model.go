@@ -28,6 +28,9 @@ func (nd *NetworkTrafficDoc) GetKind() string {
 }
 
 func EncodeTestcase(tc models.TestCase, logger *zap.Logger) (*NetworkTrafficDoc, error) {
+	if tc.Kind == models.GRPC_EXPORT {
+		return encodeGrpcTestcase(tc, logger)
+	}
 
 	header := pkg.ToHttpHeader(tc.HttpReq.Header)
 	curl := pkg.MakeCurlCommand(string(tc.HttpReq.Method), tc.HttpReq.URL, pkg.ToYamlHttpHeader(header), tc.HttpReq.Body)
@@ -63,13 +66,31 @@ func EncodeTestcase(tc models.TestCase, logger *zap.Logger) (*NetworkTrafficDoc,
 
 	switch tc.Kind {
 	case models.HTTP:
+		assertions := map[string]interface{}{
+			"noise": noise,
+		}
+		// the schema of the JSON bodies is stored so that the test case can be switched to the schema assertions
+		schema := tc.Schema
+		if schema == nil {
+			schema = pkg.InferSchema(tc.HttpResp.Body)
+		}
+		if schema != nil {
+			assertions["schema"] = schema
+		}
+		if tc.AssertionMode != "" {
+			assertions["mode"] = string(tc.AssertionMode)
+		}
+		var metadata map[string]string
+		if len(tc.Tags) > 0 {
+			metadata = map[string]string{"tags": strings.Join(tc.Tags, ", ")}
+		}
 		err := doc.Spec.Encode(spec.HttpSpec{
-			Request:  tc.HttpReq,
-			Response: tc.HttpResp,
-			Created:  tc.Created,
-			Assertions: map[string]interface{}{
-				"noise": noise,
-			},
+			Metadata:   metadata,
+			Request:    tc.HttpReq,
+			Response:   tc.HttpResp,
+			Created:    tc.Created,
+			Assertions: assertions,
+			Variables:  tc.Variables,
 		})
 		if err != nil {
 			logger.Error("failed to encode testcase into a yaml doc", zap.Error(err))
@@ -82,6 +103,25 @@ func EncodeTestcase(tc models.TestCase, logger *zap.Logger) (*NetworkTrafficDoc,
 	return doc, nil
 }
 
+// encodeGrpcTestcase returns the yaml doc of the gRPC testcase, which has neither a curl command nor the noisy fields
+// of an http response.
+func encodeGrpcTestcase(tc models.TestCase, logger *zap.Logger) (*NetworkTrafficDoc, error) {
+	doc := &NetworkTrafficDoc{
+		Version: tc.Version,
+		Kind:    tc.Kind,
+		Name:    tc.Name,
+	}
+	err := doc.Spec.Encode(spec.GrpcSpec{
+		GrpcReq:  tc.GrpcReq,
+		GrpcResp: tc.GrpcResp,
+	})
+	if err != nil {
+		logger.Error(Emoji+"failed to encode the gRPC testcase into a yaml doc", zap.Error(err))
+		return nil, err
+	}
+	return doc, nil
+}
+
 func EncodeMock(mock *models.Mock, logger *zap.Logger) (*NetworkTrafficDoc, error) {
 	yamlDoc := NetworkTrafficDoc{
 		Version: mock.Version,
@@ -173,6 +213,150 @@ func EncodeMock(mock *models.Mock, logger *zap.Logger) (*NetworkTrafficDoc, erro
 			logger.Error("failed to marshal postgres of external call into yaml", zap.Error(err))
 			return nil, err
 		}
+	case models.Redis:
+		redisSpec := spec.RedisSpec{
+			Metadata:         mock.Spec.Metadata,
+			RedisRequests:    mock.Spec.RedisRequests,
+			RedisResponses:   mock.Spec.RedisResponses,
+			ReqTimestampMock: mock.Spec.ReqTimestampMock,
+			ResTimestampMock: mock.Spec.ResTimestampMock,
+		}
+		err := yamlDoc.Spec.Encode(redisSpec)
+		if err != nil {
+			logger.Error("failed to marshal redis of external call into yaml", zap.Error(err))
+			return nil, err
+		}
+	case models.Kafka:
+		kafkaSpec := spec.KafkaSpec{
+			Metadata:         mock.Spec.Metadata,
+			KafkaRequests:    mock.Spec.KafkaRequests,
+			KafkaResponses:   mock.Spec.KafkaResponses,
+			ReqTimestampMock: mock.Spec.ReqTimestampMock,
+			ResTimestampMock: mock.Spec.ResTimestampMock,
+		}
+		err := yamlDoc.Spec.Encode(kafkaSpec)
+		if err != nil {
+			logger.Error("failed to marshal kafka of external call into yaml", zap.Error(err))
+			return nil, err
+		}
+	case models.AMQP:
+		amqpSpec := spec.AMQPSpec{
+			Metadata:         mock.Spec.Metadata,
+			AMQPRequests:     mock.Spec.AMQPRequests,
+			AMQPResponses:    mock.Spec.AMQPResponses,
+			ReqTimestampMock: mock.Spec.ReqTimestampMock,
+			ResTimestampMock: mock.Spec.ResTimestampMock,
+		}
+		err := yamlDoc.Spec.Encode(amqpSpec)
+		if err != nil {
+			logger.Error("failed to marshal amqp of external call into yaml", zap.Error(err))
+			return nil, err
+		}
+	case models.Cassandra:
+		cassandraSpec := spec.CassandraSpec{
+			Metadata:           mock.Spec.Metadata,
+			CassandraRequests:  mock.Spec.CassandraRequests,
+			CassandraResponses: mock.Spec.CassandraResponses,
+			ReqTimestampMock:   mock.Spec.ReqTimestampMock,
+			ResTimestampMock:   mock.Spec.ResTimestampMock,
+		}
+		err := yamlDoc.Spec.Encode(cassandraSpec)
+		if err != nil {
+			logger.Error("failed to marshal cassandra of external call into yaml", zap.Error(err))
+			return nil, err
+		}
+	case models.SMTP:
+		smtpSpec := spec.SMTPSpec{
+			Metadata:         mock.Spec.Metadata,
+			SMTPRequests:     mock.Spec.SMTPRequests,
+			SMTPResponses:    mock.Spec.SMTPResponses,
+			ReqTimestampMock: mock.Spec.ReqTimestampMock,
+			ResTimestampMock: mock.Spec.ResTimestampMock,
+		}
+		err := yamlDoc.Spec.Encode(smtpSpec)
+		if err != nil {
+			logger.Error("failed to marshal smtp of external call into yaml", zap.Error(err))
+			return nil, err
+		}
+	case models.LDAP:
+		ldapSpec := spec.LDAPSpec{
+			Metadata:         mock.Spec.Metadata,
+			LDAPRequests:     mock.Spec.LDAPRequests,
+			LDAPResponses:    mock.Spec.LDAPResponses,
+			ReqTimestampMock: mock.Spec.ReqTimestampMock,
+			ResTimestampMock: mock.Spec.ResTimestampMock,
+		}
+		err := yamlDoc.Spec.Encode(ldapSpec)
+		if err != nil {
+			logger.Error("failed to marshal ldap of external call into yaml", zap.Error(err))
+			return nil, err
+		}
+	case models.MQTT:
+		mqttSpec := spec.MQTTSpec{
+			Metadata:         mock.Spec.Metadata,
+			MQTTRequests:     mock.Spec.MQTTRequests,
+			MQTTResponses:    mock.Spec.MQTTResponses,
+			ReqTimestampMock: mock.Spec.ReqTimestampMock,
+			ResTimestampMock: mock.Spec.ResTimestampMock,
+		}
+		err := yamlDoc.Spec.Encode(mqttSpec)
+		if err != nil {
+			logger.Error("failed to marshal mqtt of external call into yaml", zap.Error(err))
+			return nil, err
+		}
+	case models.Memcached:
+		memcachedSpec := spec.MemcachedSpec{
+			Metadata:           mock.Spec.Metadata,
+			MemcachedRequests:  mock.Spec.MemcachedRequests,
+			MemcachedResponses: mock.Spec.MemcachedResponses,
+			ReqTimestampMock:   mock.Spec.ReqTimestampMock,
+			ResTimestampMock:   mock.Spec.ResTimestampMock,
+		}
+		err := yamlDoc.Spec.Encode(memcachedSpec)
+		if err != nil {
+			logger.Error("failed to marshal memcached of external call into yaml", zap.Error(err))
+			return nil, err
+		}
+	case models.NATS:
+		natsSpec := spec.NATSSpec{
+			Metadata:         mock.Spec.Metadata,
+			NATSRequests:     mock.Spec.NATSRequests,
+			NATSResponses:    mock.Spec.NATSResponses,
+			ReqTimestampMock: mock.Spec.ReqTimestampMock,
+			ResTimestampMock: mock.Spec.ResTimestampMock,
+		}
+		err := yamlDoc.Spec.Encode(natsSpec)
+		if err != nil {
+			logger.Error("failed to marshal nats of external call into yaml", zap.Error(err))
+			return nil, err
+		}
+	case models.WebSocket:
+		webSocketSpec := spec.WebSocketSpec{
+			Metadata:         mock.Spec.Metadata,
+			Request:          *mock.Spec.HttpReq,
+			Response:         *mock.Spec.HttpResp,
+			Messages:         mock.Spec.WebSocketMessages,
+			ReqTimestampMock: mock.Spec.ReqTimestampMock,
+			ResTimestampMock: mock.Spec.ResTimestampMock,
+		}
+		err := yamlDoc.Spec.Encode(webSocketSpec)
+		if err != nil {
+			logger.Error("failed to marshal websocket of external call into yaml", zap.Error(err))
+			return nil, err
+		}
+	case models.DNS:
+		dnsSpec := spec.DNSSpec{
+			Metadata:         mock.Spec.Metadata,
+			Request:          *mock.Spec.DNSReq,
+			Response:         *mock.Spec.DNSResp,
+			ReqTimestampMock: mock.Spec.ReqTimestampMock,
+			ResTimestampMock: mock.Spec.ResTimestampMock,
+		}
+		err := yamlDoc.Spec.Encode(dnsSpec)
+		if err != nil {
+			logger.Error("failed to marshal dns of external call into yaml", zap.Error(err))
+			return nil, err
+		}
 	case models.GRPC_EXPORT:
 		gRPCSpec := spec.GrpcSpec{
 			GrpcReq:          *mock.Spec.GRPCReq,
@@ -250,6 +434,12 @@ func Decode(yamlTestcase *NetworkTrafficDoc, logger *zap.Logger) (*models.TestCa
 		tc.Created = httpSpec.Created
 		tc.HttpReq = httpSpec.Request
 		tc.HttpResp = httpSpec.Response
+		tc.Variables = httpSpec.Variables
+		for _, tag := range strings.Split(httpSpec.Metadata["tags"], ",") {
+			if tag = strings.TrimSpace(tag); tag != "" {
+				tc.Tags = append(tc.Tags, tag)
+			}
+		}
 		tc.Noise = map[string][]string{}
 		switch reflect.ValueOf(httpSpec.Assertions["noise"]).Kind() {
 		case reflect.Map:
@@ -264,6 +454,20 @@ func Decode(yamlTestcase *NetworkTrafficDoc, logger *zap.Logger) (*models.TestCa
 				tc.Noise[v.(string)] = []string{}
 			}
 		}
+		if mode, ok := httpSpec.Assertions["mode"].(string); ok {
+			tc.AssertionMode = models.AssertionMode(mode)
+		}
+		if schema, ok := httpSpec.Assertions["schema"]; ok {
+			// the assertions are decoded as maps, the schema is decoded again into its struct
+			data, err := yamlLib.Marshal(schema)
+			if err == nil {
+				err = yamlLib.Unmarshal(data, &tc.Schema)
+			}
+			if err != nil {
+				logger.Error("failed to decode the schema of the testcase", zap.Error(err), zap.Any("testcase", tc.Name))
+				return nil, err
+			}
+		}
 	// unmarshal its mocks from yaml docs to go struct
 	case models.GRPC_EXPORT:
 		grpcSpec := spec.GrpcSpec{}
@@ -370,6 +574,161 @@ func decodeMocks(yamlMocks []*NetworkTrafficDoc, logger *zap.Logger) ([]*models.
 				ReqTimestampMock:  PostSpec.ReqTimestampMock,
 				ResTimestampMock:  PostSpec.ResTimestampMock,
 			}
+		case models.Redis:
+			redisSpec := spec.RedisSpec{}
+			err := m.Spec.Decode(&redisSpec)
+			if err != nil {
+				logger.Error("failed to unmarshal a yaml doc into redis mock", zap.Error(err), zap.Any("mock name", m.Name))
+				return nil, err
+			}
+			mock.Spec = models.MockSpec{
+				Metadata:         redisSpec.Metadata,
+				RedisRequests:    redisSpec.RedisRequests,
+				RedisResponses:   redisSpec.RedisResponses,
+				ReqTimestampMock: redisSpec.ReqTimestampMock,
+				ResTimestampMock: redisSpec.ResTimestampMock,
+			}
+		case models.Kafka:
+			kafkaSpec := spec.KafkaSpec{}
+			err := m.Spec.Decode(&kafkaSpec)
+			if err != nil {
+				logger.Error("failed to unmarshal a yaml doc into kafka mock", zap.Error(err), zap.Any("mock name", m.Name))
+				return nil, err
+			}
+			mock.Spec = models.MockSpec{
+				Metadata:         kafkaSpec.Metadata,
+				KafkaRequests:    kafkaSpec.KafkaRequests,
+				KafkaResponses:   kafkaSpec.KafkaResponses,
+				ReqTimestampMock: kafkaSpec.ReqTimestampMock,
+				ResTimestampMock: kafkaSpec.ResTimestampMock,
+			}
+		case models.AMQP:
+			amqpSpec := spec.AMQPSpec{}
+			err := m.Spec.Decode(&amqpSpec)
+			if err != nil {
+				logger.Error("failed to unmarshal a yaml doc into amqp mock", zap.Error(err), zap.Any("mock name", m.Name))
+				return nil, err
+			}
+			mock.Spec = models.MockSpec{
+				Metadata:         amqpSpec.Metadata,
+				AMQPRequests:     amqpSpec.AMQPRequests,
+				AMQPResponses:    amqpSpec.AMQPResponses,
+				ReqTimestampMock: amqpSpec.ReqTimestampMock,
+				ResTimestampMock: amqpSpec.ResTimestampMock,
+			}
+		case models.Cassandra:
+			cassandraSpec := spec.CassandraSpec{}
+			err := m.Spec.Decode(&cassandraSpec)
+			if err != nil {
+				logger.Error("failed to unmarshal a yaml doc into cassandra mock", zap.Error(err), zap.Any("mock name", m.Name))
+				return nil, err
+			}
+			mock.Spec = models.MockSpec{
+				Metadata:           cassandraSpec.Metadata,
+				CassandraRequests:  cassandraSpec.CassandraRequests,
+				CassandraResponses: cassandraSpec.CassandraResponses,
+				ReqTimestampMock:   cassandraSpec.ReqTimestampMock,
+				ResTimestampMock:   cassandraSpec.ResTimestampMock,
+			}
+		case models.SMTP:
+			smtpSpec := spec.SMTPSpec{}
+			err := m.Spec.Decode(&smtpSpec)
+			if err != nil {
+				logger.Error("failed to unmarshal a yaml doc into smtp mock", zap.Error(err), zap.Any("mock name", m.Name))
+				return nil, err
+			}
+			mock.Spec = models.MockSpec{
+				Metadata:         smtpSpec.Metadata,
+				SMTPRequests:     smtpSpec.SMTPRequests,
+				SMTPResponses:    smtpSpec.SMTPResponses,
+				ReqTimestampMock: smtpSpec.ReqTimestampMock,
+				ResTimestampMock: smtpSpec.ResTimestampMock,
+			}
+		case models.LDAP:
+			ldapSpec := spec.LDAPSpec{}
+			err := m.Spec.Decode(&ldapSpec)
+			if err != nil {
+				logger.Error("failed to unmarshal a yaml doc into ldap mock", zap.Error(err), zap.Any("mock name", m.Name))
+				return nil, err
+			}
+			mock.Spec = models.MockSpec{
+				Metadata:         ldapSpec.Metadata,
+				LDAPRequests:     ldapSpec.LDAPRequests,
+				LDAPResponses:    ldapSpec.LDAPResponses,
+				ReqTimestampMock: ldapSpec.ReqTimestampMock,
+				ResTimestampMock: ldapSpec.ResTimestampMock,
+			}
+		case models.MQTT:
+			mqttSpec := spec.MQTTSpec{}
+			err := m.Spec.Decode(&mqttSpec)
+			if err != nil {
+				logger.Error("failed to unmarshal a yaml doc into mqtt mock", zap.Error(err), zap.Any("mock name", m.Name))
+				return nil, err
+			}
+			mock.Spec = models.MockSpec{
+				Metadata:         mqttSpec.Metadata,
+				MQTTRequests:     mqttSpec.MQTTRequests,
+				MQTTResponses:    mqttSpec.MQTTResponses,
+				ReqTimestampMock: mqttSpec.ReqTimestampMock,
+				ResTimestampMock: mqttSpec.ResTimestampMock,
+			}
+		case models.Memcached:
+			memcachedSpec := spec.MemcachedSpec{}
+			err := m.Spec.Decode(&memcachedSpec)
+			if err != nil {
+				logger.Error("failed to unmarshal a yaml doc into memcached mock", zap.Error(err), zap.Any("mock name", m.Name))
+				return nil, err
+			}
+			mock.Spec = models.MockSpec{
+				Metadata:           memcachedSpec.Metadata,
+				MemcachedRequests:  memcachedSpec.MemcachedRequests,
+				MemcachedResponses: memcachedSpec.MemcachedResponses,
+				ReqTimestampMock:   memcachedSpec.ReqTimestampMock,
+				ResTimestampMock:   memcachedSpec.ResTimestampMock,
+			}
+		case models.NATS:
+			natsSpec := spec.NATSSpec{}
+			err := m.Spec.Decode(&natsSpec)
+			if err != nil {
+				logger.Error("failed to unmarshal a yaml doc into nats mock", zap.Error(err), zap.Any("mock name", m.Name))
+				return nil, err
+			}
+			mock.Spec = models.MockSpec{
+				Metadata:         natsSpec.Metadata,
+				NATSRequests:     natsSpec.NATSRequests,
+				NATSResponses:    natsSpec.NATSResponses,
+				ReqTimestampMock: natsSpec.ReqTimestampMock,
+				ResTimestampMock: natsSpec.ResTimestampMock,
+			}
+		case models.WebSocket:
+			webSocketSpec := spec.WebSocketSpec{}
+			err := m.Spec.Decode(&webSocketSpec)
+			if err != nil {
+				logger.Error("failed to unmarshal a yaml doc into websocket mock", zap.Error(err), zap.Any("mock name", m.Name))
+				return nil, err
+			}
+			mock.Spec = models.MockSpec{
+				Metadata:          webSocketSpec.Metadata,
+				HttpReq:           &webSocketSpec.Request,
+				HttpResp:          &webSocketSpec.Response,
+				WebSocketMessages: webSocketSpec.Messages,
+				ReqTimestampMock:  webSocketSpec.ReqTimestampMock,
+				ResTimestampMock:  webSocketSpec.ResTimestampMock,
+			}
+		case models.DNS:
+			dnsSpec := spec.DNSSpec{}
+			err := m.Spec.Decode(&dnsSpec)
+			if err != nil {
+				logger.Error("failed to unmarshal a yaml doc into dns mock", zap.Error(err), zap.Any("mock name", m.Name))
+				return nil, err
+			}
+			mock.Spec = models.MockSpec{
+				Metadata:         dnsSpec.Metadata,
+				DNSReq:           &dnsSpec.Request,
+				DNSResp:          &dnsSpec.Response,
+				ReqTimestampMock: dnsSpec.ReqTimestampMock,
+				ResTimestampMock: dnsSpec.ResTimestampMock,
+			}
 		case models.SQL:
 			mysqlSpec := spec.MySQLSpec{}
 			err := m.Spec.Decode(&mysqlSpec)
@@ -476,6 +835,22 @@ func decodeMySqlMessage(yamlSpec *spec.MySQLSpec, logger *zap.Logger) (*models.M
 				return nil, err
 			}
 			req.Message = requestMessage
+		case "AUTH_MORE_DATA":
+			requestMessage := &models.MySQLNextAuthPacket{}
+			err := v.Message.Decode(requestMessage)
+			if err != nil {
+				logger.Error(Emoji+"failed to unmarshal yml document into MySQLNextAuthPacket", zap.Error(err))
+				return nil, err
+			}
+			req.Message = requestMessage
+		case "ENCRYPT_PASSWORD":
+			requestMessage := &models.MySQLPasswordData{}
+			err := v.Message.Decode(requestMessage)
+			if err != nil {
+				logger.Error(Emoji+"failed to unmarshal yml document into MySQLPasswordData", zap.Error(err))
+				return nil, err
+			}
+			req.Message = requestMessage
 		case "COM_CHANGE_USER":
 			requestMessage := &models.MySQLComChangeUserPacket{}
 			err := v.Message.Decode(requestMessage)
@@ -484,6 +859,14 @@ func decodeMySqlMessage(yamlSpec *spec.MySQLSpec, logger *zap.Logger) (*models.M
 				return nil, err
 			}
 			req.Message = requestMessage
+		case "LOCAL_INFILE_CONTENT":
+			requestMessage := &models.MySQLLocalInfileContent{}
+			err := v.Message.Decode(requestMessage)
+			if err != nil {
+				logger.Error(Emoji+"failed to unmarshal yml document into MySQLLocalInfileContent", zap.Error(err))
+				return nil, err
+			}
+			req.Message = requestMessage
 		}
 		requests = append(requests, req)
 	}
@@ -545,6 +928,22 @@ func decodeMySqlMessage(yamlSpec *spec.MySQLSpec, logger *zap.Logger) (*models.M
 				return nil, err
 			}
 			resp.Message = responseMessage
+		case "MULTI_RESULT_SET":
+			responseMessage := &models.MySQLMultiResultSet{}
+			err := v.Message.Decode(responseMessage)
+			if err != nil {
+				logger.Error(Emoji+"failed to unmarshal yml document into MySQLMultiResultSet ", zap.Error(err))
+				return nil, err
+			}
+			resp.Message = responseMessage
+		case "LOCAL_INFILE_REQUEST":
+			responseMessage := &models.MySQLLocalInfileRequest{}
+			err := v.Message.Decode(responseMessage)
+			if err != nil {
+				logger.Error(Emoji+"failed to unmarshal yml document into MySQLLocalInfileRequest ", zap.Error(err))
+				return nil, err
+			}
+			resp.Message = responseMessage
 		case "MySQLErr":
 			responseMessage := &models.MySQLERRPacket{}
 			err := v.Message.Decode(responseMessage)
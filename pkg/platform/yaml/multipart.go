@@ -0,0 +1,208 @@
+package yaml
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"go.keploy.io/server/pkg"
+	"go.keploy.io/server/pkg/models"
+	"go.keploy.io/server/pkg/proxy/util"
+	"go.uber.org/zap"
+)
+
+// maxInlinePartSize is the size above which the content of a multipart part is stored in a file.
+const maxInlinePartSize = 16 * 1024
+
+// assetsDir is the directory, next to the yaml files, which stores the contents of the large parts.
+const assetsDir = "assets"
+
+// multipartBoundary returns the boundary of a multipart body from the Content-Type header of the request.
+func multipartBoundary(header map[string]string) (string, bool) {
+	mediaType, params, err := mime.ParseMediaType(pkg.ToHttpHeader(header).Get("Content-Type"))
+	if err != nil || !strings.HasPrefix(mediaType, "multipart/") || params["boundary"] == "" {
+		return "", false
+	}
+	return params["boundary"], true
+}
+
+// splitMultipart returns the request with its multipart body split into its parts, the large and binary contents
+// being written in the assets directory of dir. The request is returned as it is when its body isn't multipart or
+// can't be assembled again byte for byte from the parts, and when a content would be written in a file of an encrypted
+// test set, the body then staying in its encrypted document.
+func splitMultipart(dir string, req models.HttpReq, logger *zap.Logger) models.HttpReq {
+	boundary, ok := multipartBoundary(req.Header)
+	if !ok || req.Body == "" {
+		return req
+	}
+
+	var (
+		form      []models.FormData
+		contents  [][]byte
+		fileNames []string
+	)
+	reader := multipart.NewReader(strings.NewReader(req.Body), boundary)
+	for {
+		part, err := reader.NextRawPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			logger.Debug("failed to parse the multipart body, storing it as it is", zap.Error(err))
+			return req
+		}
+		content, err := io.ReadAll(part)
+		if err != nil {
+			logger.Debug("failed to read the part of the multipart body, storing it as it is", zap.Error(err))
+			return req
+		}
+		header := map[string]string{}
+		for key, values := range part.Header {
+			header[key] = strings.Join(values, ", ")
+		}
+		form = append(form, models.FormData{Key: part.FormName(), Header: header})
+		contents = append(contents, content)
+		fileNames = append(fileNames, part.FileName())
+	}
+	if len(form) == 0 || string(assembleMultipart(boundary, form, contents)) != req.Body {
+		// the preamble, the epilogue or the order of the headers of the parts would be lost
+		logger.Debug("the multipart body can't be rebuilt from its parts, storing it as it is")
+		return req
+	}
+
+	aead, err := encryptionCipher()
+	if err != nil {
+		logger.Debug("failed to read the encryption key, storing the multipart body as it is", zap.Error(err))
+		return req
+	}
+	for i := range form {
+		if utf8.Valid(contents[i]) && len(contents[i]) <= maxInlinePartSize {
+			form[i].Values = []string{string(contents[i])}
+			continue
+		}
+		if aead != nil {
+			// the files of the assets directory aren't encrypted
+			return req
+		}
+		path, err := writeAsset(dir, fileNames[i], contents[i])
+		if err != nil {
+			logger.Error("failed to store the content of the multipart part in a file", zap.Error(err), zap.String("key", form[i].Key))
+			return req
+		}
+		form[i].Paths = []string{path}
+	}
+	req.Form = form
+	req.Body = ""
+	return req
+}
+
+// joinMultipart rebuilds the multipart body of a request from its parts, with the boundary it was recorded with.
+func joinMultipart(dir string, req *models.HttpReq) error {
+	if len(req.Form) == 0 || req.Body != "" {
+		return nil
+	}
+	boundary, ok := multipartBoundary(req.Header)
+	if !ok {
+		return fmt.Errorf("the request has parts but no multipart boundary in its Content-Type header")
+	}
+	contents := make([][]byte, len(req.Form))
+	for i, part := range req.Form {
+		switch {
+		case len(part.Paths) > 0:
+			path, err := util.ValidatePath(part.Paths[0])
+			if err != nil {
+				return err
+			}
+			contents[i], err = os.ReadFile(filepath.Join(dir, path))
+			if err != nil {
+				return fmt.Errorf("failed to read the content of the part %q: %v", part.Key, err)
+			}
+		case len(part.Values) > 0:
+			contents[i] = []byte(part.Values[0])
+		}
+	}
+	req.Body = string(assembleMultipart(boundary, req.Form, contents))
+	return nil
+}
+
+// assembleMultipart writes the parts the way the usual clients do, the Content-Disposition and the Content-Type
+// headers of each part coming first.
+func assembleMultipart(boundary string, form []models.FormData, contents [][]byte) []byte {
+	var buf bytes.Buffer
+	for i, part := range form {
+		if i > 0 {
+			buf.WriteString("\r\n")
+		}
+		buf.WriteString("--" + boundary + "\r\n")
+		keys := make([]string, 0, len(part.Header))
+		for key := range part.Header {
+			keys = append(keys, key)
+		}
+		sort.Slice(keys, func(a, b int) bool {
+			return headerRank(keys[a]) < headerRank(keys[b]) || headerRank(keys[a]) == headerRank(keys[b]) && keys[a] < keys[b]
+		})
+		for _, key := range keys {
+			buf.WriteString(key + ": " + part.Header[key] + "\r\n")
+		}
+		buf.WriteString("\r\n")
+		buf.Write(contents[i])
+	}
+	buf.WriteString("\r\n--" + boundary + "--\r\n")
+	return buf.Bytes()
+}
+
+func headerRank(key string) int {
+	switch key {
+	case "Content-Disposition":
+		return 0
+	case "Content-Type":
+		return 1
+	}
+	return 2
+}
+
+// writeAsset writes the content of a part in the assets directory, under the hash of the content so that the same
+// upload is stored once. It returns the path of the file relative to dir.
+func writeAsset(dir, fileName string, content []byte) (string, error) {
+	sum := sha256.Sum256(content)
+	name := hex.EncodeToString(sum[:8])
+	if ext := filepath.Ext(fileName); len(ext) <= 10 && !strings.ContainsAny(ext, `/\`) {
+		name += ext
+	}
+	if err := os.MkdirAll(filepath.Join(dir, assetsDir), os.ModePerm); err != nil {
+		return "", err
+	}
+	path := filepath.Join(dir, assetsDir, name)
+	if _, err := os.Stat(path); err == nil {
+		return filepath.Join(assetsDir, name), nil
+	}
+	if err := os.WriteFile(path, content, 0644); err != nil {
+		return "", err
+	}
+	return filepath.Join(assetsDir, name), nil
+}
+
+// joinMockBodies rebuilds the multipart bodies of the http mocks read from the yaml file of dir, and reads the bodies
+// stored in files.
+func joinMockBodies(dir string, mocks []*models.Mock, logger *zap.Logger) {
+	for _, mock := range mocks {
+		if mock.Kind != models.HTTP || mock.Spec.HttpReq == nil {
+			continue
+		}
+		if err := loadBodies(dir, mock.Spec.HttpReq, mock.Spec.HttpResp, logger); err != nil {
+			logger.Error("failed to read the body of the mock from its file", zap.Error(err), zap.String("mock", mock.Name))
+		}
+		if err := joinMultipart(dir, mock.Spec.HttpReq); err != nil {
+			logger.Error("failed to rebuild the multipart body of the mock", zap.Error(err), zap.String("mock", mock.Name))
+		}
+	}
+}
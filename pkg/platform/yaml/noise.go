@@ -0,0 +1,49 @@
+package yaml
+
+import (
+	"fmt"
+
+	"go.keploy.io/server/pkg/models"
+	"go.keploy.io/server/pkg/platform/yaml/spec"
+)
+
+// AddNoise adds the fields, like body.data.id or header.Date, to the noise of the http testcase of the yaml file,
+// which is rewritten in place.
+func AddNoise(path, name string, fields []string) error {
+	docs, err := ReadDocs(path, name)
+	if err != nil {
+		return err
+	}
+	for _, doc := range docs {
+		if doc.Kind != models.HTTP {
+			continue
+		}
+		httpSpec := spec.HttpSpec{}
+		if err := doc.Spec.Decode(&httpSpec); err != nil {
+			return fmt.Errorf("failed to decode the testcase %s: %v", name, err)
+		}
+		if httpSpec.Assertions == nil {
+			httpSpec.Assertions = map[string]interface{}{}
+		}
+		noise := map[string][]string{}
+		if existing, ok := httpSpec.Assertions["noise"].(map[string]interface{}); ok {
+			for field, values := range existing {
+				noise[field] = []string{}
+				if values, ok := values.([]interface{}); ok {
+					for _, value := range values {
+						noise[field] = append(noise[field], fmt.Sprint(value))
+					}
+				}
+			}
+		}
+		for _, field := range fields {
+			// the field is noisy whatever its value
+			noise[field] = []string{}
+		}
+		httpSpec.Assertions["noise"] = noise
+		if err := doc.Spec.Encode(httpSpec); err != nil {
+			return fmt.Errorf("failed to encode the testcase %s: %v", name, err)
+		}
+	}
+	return WriteDocs(path, name, docs)
+}
@@ -0,0 +1,294 @@
+package yaml
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"go.keploy.io/server/pkg"
+	"go.keploy.io/server/pkg/models"
+	"go.keploy.io/server/pkg/platform/yaml/spec"
+	"go.uber.org/zap"
+	yamlLib "gopkg.in/yaml.v3"
+)
+
+// the built-in detectors of the sensitive values
+const (
+	DetectorEmail = "email"
+	DetectorCard  = "card"
+	DetectorJWT   = "jwt"
+)
+
+// RedactionKeyEnv is the environment variable of the key of the tokens of the redacted values.
+const RedactionKeyEnv = "KEPLOY_REDACTION_KEY"
+
+var detectorPatterns = map[string]*regexp.Regexp{
+	DetectorEmail: regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`),
+	DetectorCard:  regexp.MustCompile(`\b\d(?:[ -]?\d){12,18}\b`),
+	DetectorJWT:   regexp.MustCompile(`\beyJ[A-Za-z0-9_-]+\.eyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]*`),
+}
+
+// Redactor masks the sensitive values of the tests and mocks when they are written. The values are replaced with
+// tokens derived from them, so that a value masked in a test is masked the same in its mocks and the replay stays
+// consistent.
+type Redactor struct {
+	key       []byte
+	patterns  []*regexp.Regexp
+	headers   map[string]bool
+	fields    [][]string
+	detectors []string
+	logger    *zap.Logger
+}
+
+// NewRedactor returns the redactor of the configuration, or nil when it masks nothing. The key of the tokens is read
+// from KEPLOY_REDACTION_KEY, a random one being used for the session when it isn't set.
+func NewRedactor(config models.Redaction, logger *zap.Logger) (*Redactor, error) {
+	if len(config.Patterns) == 0 && len(config.Headers) == 0 && len(config.Fields) == 0 && len(config.Detectors) == 0 {
+		return nil, nil
+	}
+	r := &Redactor{
+		headers: map[string]bool{},
+		logger:  logger,
+	}
+	for _, pattern := range config.Patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid redaction pattern %q: %v", pattern, err)
+		}
+		r.patterns = append(r.patterns, re)
+	}
+	for _, header := range config.Headers {
+		r.headers[strings.ToLower(header)] = true
+	}
+	for _, field := range config.Fields {
+		path, err := parseFieldPath(field)
+		if err != nil {
+			return nil, err
+		}
+		r.fields = append(r.fields, path)
+	}
+	for _, detector := range config.Detectors {
+		if _, ok := detectorPatterns[detector]; !ok {
+			return nil, fmt.Errorf("unknown redaction detector %q, the detectors are %s, %s and %s", detector, DetectorEmail, DetectorCard, DetectorJWT)
+		}
+		r.detectors = append(r.detectors, detector)
+	}
+	if key := os.Getenv(RedactionKeyEnv); key != "" {
+		r.key = []byte(key)
+	} else {
+		r.key = make([]byte, 32)
+		if _, err := rand.Read(r.key); err != nil {
+			return nil, fmt.Errorf("failed to generate the redaction key: %v", err)
+		}
+		logger.Info("the redacted values are tokenized with a key of the session, set " + RedactionKeyEnv + " to tokenize them the same across the sessions")
+	}
+	return r, nil
+}
+
+// parseFieldPath parses the JSONPath of the fields of the JSON bodies, like $.user.password, $.items[*].card or
+// $..token, into its segments, * matching any field or item and ** any depth.
+func parseFieldPath(field string) ([]string, error) {
+	path := strings.TrimPrefix(strings.TrimSpace(field), "$")
+	path = strings.ReplaceAll(path, "..", ".**.")
+	path = strings.ReplaceAll(path, "[", ".")
+	path = strings.ReplaceAll(path, "]", "")
+	var segments []string
+	for _, segment := range strings.Split(path, ".") {
+		segment = strings.Trim(segment, `'"`)
+		if segment != "" {
+			segments = append(segments, segment)
+		}
+	}
+	if len(segments) == 0 {
+		return nil, fmt.Errorf("invalid redaction field %q", field)
+	}
+	return segments, nil
+}
+
+func (r *Redactor) hash(value string) string {
+	mac := hmac.New(sha256.New, r.key)
+	mac.Write([]byte(value))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// token returns the token replacing the value, the emails and the card numbers keeping their shape so that their
+// validation by the application still passes.
+func (r *Redactor) token(detector, value string) string {
+	h := r.hash(value)
+	switch detector {
+	case DetectorEmail:
+		return h[:10] + "@redacted.invalid"
+	case DetectorCard:
+		digits := []byte(value)
+		for i, c := range digits {
+			if c >= '0' && c <= '9' {
+				digits[i] = '0' + h[i%len(h)]%10
+			}
+		}
+		return string(digits)
+	}
+	return "REDACTED-" + h[:16]
+}
+
+// luhn tells whether the digits of the value pass the checksum of the card numbers.
+func luhn(value string) bool {
+	sum, double := 0, false
+	for i := len(value) - 1; i >= 0; i-- {
+		c := value[i]
+		if c < '0' || c > '9' {
+			continue
+		}
+		d := int(c - '0')
+		if double {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		double = !double
+	}
+	return sum%10 == 0
+}
+
+// redactString masks the values of the string matched by the detectors and the patterns.
+func (r *Redactor) redactString(s string) string {
+	for _, detector := range r.detectors {
+		s = detectorPatterns[detector].ReplaceAllStringFunc(s, func(match string) string {
+			if detector == DetectorCard && !luhn(match) {
+				return match
+			}
+			return r.token(detector, match)
+		})
+	}
+	for _, re := range r.patterns {
+		s = re.ReplaceAllStringFunc(s, func(match string) string {
+			return r.token("", match)
+		})
+	}
+	return s
+}
+
+// redactField masks the values at the path in the decoded JSON value.
+func (r *Redactor) redactField(value interface{}, path []string) (interface{}, bool) {
+	if len(path) == 0 {
+		text, ok := value.(string)
+		if !ok {
+			data, _ := json.Marshal(value)
+			text = string(data)
+		}
+		return r.token("", text), true
+	}
+	changed := false
+	// ** matches this level as well as the deeper ones
+	if path[0] == "**" {
+		value, changed = r.redactField(value, path[1:])
+	}
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, field := range v {
+			rest := path[1:]
+			switch {
+			case path[0] == "**":
+				rest = path
+			case path[0] != "*" && path[0] != key:
+				continue
+			}
+			if masked, ok := r.redactField(field, rest); ok {
+				v[key] = masked
+				changed = true
+			}
+		}
+	case []interface{}:
+		for i, item := range v {
+			rest := path[1:]
+			switch {
+			case path[0] == "**":
+				rest = path
+			case path[0] != "*" && path[0] != strconv.Itoa(i):
+				continue
+			}
+			if masked, ok := r.redactField(item, rest); ok {
+				v[i] = masked
+				changed = true
+			}
+		}
+	}
+	return value, changed
+}
+
+// redactBody masks the fields of the JSON body at the paths of the configuration. The other bodies are left as is.
+func (r *Redactor) redactBody(body string) string {
+	trimmed := strings.TrimSpace(body)
+	if len(r.fields) == 0 || (!strings.HasPrefix(trimmed, "{") && !strings.HasPrefix(trimmed, "[")) {
+		return body
+	}
+	var value interface{}
+	if err := json.Unmarshal([]byte(trimmed), &value); err != nil {
+		return body
+	}
+	changed := false
+	for _, path := range r.fields {
+		var masked bool
+		value, masked = r.redactField(value, path)
+		changed = changed || masked
+	}
+	if !changed {
+		return body
+	}
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(value); err != nil {
+		return body
+	}
+	return strings.TrimSuffix(buf.String(), "\n")
+}
+
+// redactNode masks the values of the yaml node and of its children.
+func (r *Redactor) redactNode(node *yamlLib.Node) {
+	switch node.Kind {
+	case yamlLib.DocumentNode, yamlLib.SequenceNode:
+		for _, child := range node.Content {
+			r.redactNode(child)
+		}
+	case yamlLib.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			// the values of the headers are masked as a whole
+			if r.headers[strings.ToLower(key.Value)] && value.Kind == yamlLib.ScalarNode {
+				value.Value = r.token("", value.Value)
+				value.Tag, value.Style = "!!str", 0
+				continue
+			}
+			r.redactNode(value)
+		}
+	case yamlLib.ScalarNode:
+		if node.Tag == "!!str" {
+			node.Value = r.redactString(r.redactBody(node.Value))
+		}
+	}
+}
+
+// Redact masks the sensitive values of the test or mock document, and the curl command of the tests.
+func (r *Redactor) Redact(doc *NetworkTrafficDoc) {
+	r.redactNode(&doc.Spec)
+	if doc.Curl == "" || doc.Kind != models.HTTP {
+		return
+	}
+	httpSpec := spec.HttpSpec{}
+	if err := doc.Spec.Decode(&httpSpec); err != nil {
+		r.logger.Error("failed to decode the testcase to redact its curl command", zap.String("testcase name", doc.Name), zap.Error(err))
+		doc.Curl = ""
+		return
+	}
+	doc.Curl = pkg.MakeCurlCommand(string(httpSpec.Request.Method), httpSpec.Request.URL, httpSpec.Request.Header, httpSpec.Request.Body)
+}
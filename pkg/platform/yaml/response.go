@@ -0,0 +1,31 @@
+package yaml
+
+import (
+	"fmt"
+
+	"go.keploy.io/server/pkg/models"
+	"go.keploy.io/server/pkg/platform/yaml/spec"
+)
+
+// UpdateResponse changes the expected response of the http testcase of the yaml file with update, like to replace it
+// with the actual response of a test run. The file is rewritten in place.
+func UpdateResponse(path, name string, update func(resp *models.HttpResp)) error {
+	docs, err := ReadDocs(path, name)
+	if err != nil {
+		return err
+	}
+	for _, doc := range docs {
+		if doc.Kind != models.HTTP {
+			continue
+		}
+		httpSpec := spec.HttpSpec{}
+		if err := doc.Spec.Decode(&httpSpec); err != nil {
+			return fmt.Errorf("failed to decode the testcase %s: %v", name, err)
+		}
+		update(&httpSpec.Response)
+		if err := doc.Spec.Encode(httpSpec); err != nil {
+			return fmt.Errorf("failed to encode the testcase %s: %v", name, err)
+		}
+	}
+	return WriteDocs(path, name, docs)
+}
@@ -0,0 +1,52 @@
+package yaml
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	yamlLib "gopkg.in/yaml.v3"
+)
+
+// seedFile holds the seed of the randomness of the application during the recording of a test set.
+type seedFile struct {
+	Seed uint64 `yaml:"seed"`
+}
+
+// NewSeed writes a new random seed in the seed.yaml file of the test set directory and returns it.
+func NewSeed(path string) (uint64, error) {
+	var b [8]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return 0, fmt.Errorf("failed to generate the seed: %v", err)
+	}
+	seed := binary.LittleEndian.Uint64(b[:])
+	data, err := yamlLib.Marshal(seedFile{Seed: seed})
+	if err != nil {
+		return 0, fmt.Errorf("failed to marshal the seed: %v", err)
+	}
+	if err := os.MkdirAll(path, 0777); err != nil {
+		return 0, fmt.Errorf("failed to create the directory %s: %v", path, err)
+	}
+	if err := os.WriteFile(filepath.Join(path, "seed.yaml"), data, os.ModePerm); err != nil {
+		return 0, fmt.Errorf("failed to write the seed of %s: %v", path, err)
+	}
+	return seed, nil
+}
+
+// ReadSeed returns the seed of the test set directory, and false when its application wasn't recorded with one.
+func ReadSeed(path string) (uint64, bool, error) {
+	data, err := os.ReadFile(filepath.Join(path, "seed.yaml"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, false, nil
+		}
+		return 0, false, err
+	}
+	var file seedFile
+	if err := yamlLib.Unmarshal(data, &file); err != nil {
+		return 0, false, fmt.Errorf("failed to decode the seed of %s: %v", path, err)
+	}
+	return file.Seed, true, nil
+}
@@ -0,0 +1,15 @@
+package spec
+
+import (
+	"time"
+
+	"go.keploy.io/server/pkg/models"
+)
+
+type AMQPSpec struct {
+	Metadata         map[string]string    `json:"metadata" yaml:"metadata"`
+	AMQPRequests     []models.AMQPCommand `json:"requests" yaml:"requests"`
+	AMQPResponses    []models.AMQPCommand `json:"responses" yaml:"responses"`
+	ReqTimestampMock time.Time            `json:"reqTimestampMock,omitempty" yaml:"reqTimestampMock,omitempty"`
+	ResTimestampMock time.Time            `json:"resTimestampMock,omitempty" yaml:"resTimestampMock,omitempty"`
+}
@@ -0,0 +1,15 @@
+package spec
+
+import (
+	"time"
+
+	"go.keploy.io/server/pkg/models"
+)
+
+type CassandraSpec struct {
+	Metadata           map[string]string          `json:"metadata" yaml:"metadata"`
+	CassandraRequests  []models.CassandraRequest  `json:"requests" yaml:"requests"`
+	CassandraResponses []models.CassandraResponse `json:"responses" yaml:"responses"`
+	ReqTimestampMock   time.Time                  `json:"reqTimestampMock,omitempty" yaml:"reqTimestampMock,omitempty"`
+	ResTimestampMock   time.Time                  `json:"resTimestampMock,omitempty" yaml:"resTimestampMock,omitempty"`
+}
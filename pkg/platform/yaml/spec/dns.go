@@ -0,0 +1,15 @@
+package spec
+
+import (
+	"time"
+
+	"go.keploy.io/server/pkg/models"
+)
+
+type DNSSpec struct {
+	Metadata         map[string]string  `json:"metadata" yaml:"metadata"`
+	Request          models.DNSRequest  `json:"request" yaml:"request"`
+	Response         models.DNSResponse `json:"response" yaml:"response"`
+	ReqTimestampMock time.Time          `json:"reqTimestampMock,omitempty" yaml:"reqTimestampMock,omitempty"`
+	ResTimestampMock time.Time          `json:"resTimestampMock,omitempty" yaml:"resTimestampMock,omitempty"`
+}
@@ -0,0 +1,15 @@
+package spec
+
+import (
+	"time"
+
+	"go.keploy.io/server/pkg/models"
+)
+
+type KafkaSpec struct {
+	Metadata         map[string]string      `json:"metadata" yaml:"metadata"`
+	KafkaRequests    []models.KafkaRequest  `json:"requests" yaml:"requests"`
+	KafkaResponses   []models.KafkaResponse `json:"responses" yaml:"responses"`
+	ReqTimestampMock time.Time              `json:"reqTimestampMock,omitempty" yaml:"reqTimestampMock,omitempty"`
+	ResTimestampMock time.Time              `json:"resTimestampMock,omitempty" yaml:"resTimestampMock,omitempty"`
+}
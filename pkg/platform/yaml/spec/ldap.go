@@ -0,0 +1,15 @@
+package spec
+
+import (
+	"time"
+
+	"go.keploy.io/server/pkg/models"
+)
+
+type LDAPSpec struct {
+	Metadata         map[string]string    `json:"metadata" yaml:"metadata"`
+	LDAPRequests     []models.LDAPMessage `json:"requests" yaml:"requests"`
+	LDAPResponses    []models.LDAPMessage `json:"responses" yaml:"responses"`
+	ReqTimestampMock time.Time            `json:"reqTimestampMock,omitempty" yaml:"reqTimestampMock,omitempty"`
+	ResTimestampMock time.Time            `json:"resTimestampMock,omitempty" yaml:"resTimestampMock,omitempty"`
+}
@@ -0,0 +1,15 @@
+package spec
+
+import (
+	"time"
+
+	"go.keploy.io/server/pkg/models"
+)
+
+type MemcachedSpec struct {
+	Metadata           map[string]string          `json:"metadata" yaml:"metadata"`
+	MemcachedRequests  []models.MemcachedRequest  `json:"requests" yaml:"requests"`
+	MemcachedResponses []models.MemcachedResponse `json:"responses" yaml:"responses"`
+	ReqTimestampMock   time.Time                  `json:"reqTimestampMock,omitempty" yaml:"reqTimestampMock,omitempty"`
+	ResTimestampMock   time.Time                  `json:"resTimestampMock,omitempty" yaml:"resTimestampMock,omitempty"`
+}
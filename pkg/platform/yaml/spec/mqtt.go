@@ -0,0 +1,15 @@
+package spec
+
+import (
+	"time"
+
+	"go.keploy.io/server/pkg/models"
+)
+
+type MQTTSpec struct {
+	Metadata         map[string]string   `json:"metadata" yaml:"metadata"`
+	MQTTRequests     []models.MQTTPacket `json:"requests" yaml:"requests"`
+	MQTTResponses    []models.MQTTPacket `json:"responses" yaml:"responses"`
+	ReqTimestampMock time.Time           `json:"reqTimestampMock,omitempty" yaml:"reqTimestampMock,omitempty"`
+	ResTimestampMock time.Time           `json:"resTimestampMock,omitempty" yaml:"resTimestampMock,omitempty"`
+}
@@ -0,0 +1,15 @@
+package spec
+
+import (
+	"time"
+
+	"go.keploy.io/server/pkg/models"
+)
+
+type NATSSpec struct {
+	Metadata         map[string]string    `json:"metadata" yaml:"metadata"`
+	NATSRequests     []models.NATSMessage `json:"requests" yaml:"requests"`
+	NATSResponses    []models.NATSMessage `json:"responses" yaml:"responses"`
+	ReqTimestampMock time.Time            `json:"reqTimestampMock,omitempty" yaml:"reqTimestampMock,omitempty"`
+	ResTimestampMock time.Time            `json:"resTimestampMock,omitempty" yaml:"resTimestampMock,omitempty"`
+}
@@ -0,0 +1,15 @@
+package spec
+
+import (
+	"time"
+
+	"go.keploy.io/server/pkg/models"
+)
+
+type RedisSpec struct {
+	Metadata         map[string]string     `json:"metadata" yaml:"metadata"`
+	RedisRequests    []models.RedisRequest `json:"requests" yaml:"requests"`
+	RedisResponses   []models.RedisValue   `json:"responses" yaml:"responses"`
+	ReqTimestampMock time.Time             `json:"reqTimestampMock,omitempty" yaml:"reqTimestampMock,omitempty"`
+	ResTimestampMock time.Time             `json:"resTimestampMock,omitempty" yaml:"resTimestampMock,omitempty"`
+}
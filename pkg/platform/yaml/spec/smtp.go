@@ -0,0 +1,15 @@
+package spec
+
+import (
+	"time"
+
+	"go.keploy.io/server/pkg/models"
+)
+
+type SMTPSpec struct {
+	Metadata         map[string]string     `json:"metadata" yaml:"metadata"`
+	SMTPRequests     []models.SMTPRequest  `json:"requests" yaml:"requests"`
+	SMTPResponses    []models.SMTPResponse `json:"responses" yaml:"responses"`
+	ReqTimestampMock time.Time             `json:"reqTimestampMock,omitempty" yaml:"reqTimestampMock,omitempty"`
+	ResTimestampMock time.Time             `json:"resTimestampMock,omitempty" yaml:"resTimestampMock,omitempty"`
+}
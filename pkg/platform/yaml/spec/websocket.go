@@ -0,0 +1,17 @@
+package spec
+
+import (
+	"time"
+
+	"go.keploy.io/server/pkg/models"
+)
+
+// WebSocketSpec is the opening handshake of a WebSocket connection along with the timeline of its messages.
+type WebSocketSpec struct {
+	Metadata         map[string]string         `json:"metadata" yaml:"metadata"`
+	Request          models.HttpReq            `json:"req" yaml:"req"`
+	Response         models.HttpResp           `json:"resp" yaml:"resp"`
+	Messages         []models.WebSocketMessage `json:"messages" yaml:"messages"`
+	ReqTimestampMock time.Time                 `json:"reqTimestampMock,omitempty" yaml:"reqTimestampMock,omitempty"`
+	ResTimestampMock time.Time                 `json:"resTimestampMock,omitempty" yaml:"resTimestampMock,omitempty"`
+}
@@ -75,11 +75,11 @@ func (fe *TestReport) Write(ctx context.Context, path string, doc platform.KindS
 		return fmt.Errorf("%s failed to read test report in yaml file.", Emoji)
 	}
 	if readDock.Name == "" {
-		lastIndex, err := findLastIndex(path, fe.Logger)
+		name, err := reserveReportName(path, fe.Logger)
 		if err != nil {
 			return err
 		}
-		readDock.Name = fmt.Sprintf("report-%v", lastIndex)
+		readDock.Name = name
 	}
 
 	_, err := util.CreateYamlFile(path, readDock.Name, fe.Logger)
@@ -100,3 +100,27 @@ func (fe *TestReport) Write(ctx context.Context, path string, doc platform.KindS
 	}
 	return nil
 }
+
+// reserveReportName creates the file of the next test report and returns its name. The file is created exclusively
+// since the keploy processes of a parallel run write their reports in the same directory.
+func reserveReportName(path string, logger *zap.Logger) (string, error) {
+	if err := os.MkdirAll(path, os.ModePerm); err != nil {
+		return "", fmt.Errorf("%s failed to create the test reports directory. error: %s", Emoji, err.Error())
+	}
+	for {
+		lastIndex, err := findLastIndex(path, logger)
+		if err != nil {
+			return "", err
+		}
+		name := fmt.Sprintf("report-%v", lastIndex)
+		file, err := os.OpenFile(filepath.Join(path, name+".yaml"), os.O_CREATE|os.O_EXCL|os.O_WRONLY, os.ModePerm)
+		if os.IsExist(err) {
+			continue
+		}
+		if err != nil {
+			return "", fmt.Errorf("%s failed to create the test report file. error: %s", Emoji, err.Error())
+		}
+		file.Close()
+		return name, nil
+	}
+}
@@ -15,6 +15,7 @@ import (
 	"sync"
 	"time"
 
+	"go.keploy.io/server/pkg/metrics"
 	"go.keploy.io/server/pkg/models"
 	"go.keploy.io/server/pkg/platform"
 	"go.keploy.io/server/pkg/platform/telemetry"
@@ -33,6 +34,20 @@ type Yaml struct {
 	Logger   *zap.Logger
 	tele     *telemetry.Telemetry
 	mutex    sync.RWMutex
+	// responses holds the values of the responses of the test cases recorded, looked for in the next requests
+	responses []recordedResponse
+	// Dedup skips the requests identical to one already recorded in the session, which only counts them
+	Dedup    bool
+	captured map[string]*capturedRequest
+	// Redactor masks the sensitive values of the tests and mocks written, when set
+	Redactor *Redactor
+	// BodyStorage stores the large http bodies of the tests and mocks written in files
+	BodyStorage models.BodyStorage
+	// journals are the journals of the mocks being recorded, by the name of their yaml file
+	journals     map[string]string
+	journalMutex sync.Mutex
+	// MockFormat is the format the recorded mocks are stored in once the recording ends, yaml or cbor
+	MockFormat string
 }
 
 func NewYamlStore(tcsPath string, mockPath string, tcsName string, mockName string, Logger *zap.Logger, tele *telemetry.Telemetry) *Yaml {
@@ -47,6 +62,24 @@ func NewYamlStore(tcsPath string, mockPath string, tcsName string, mockName stri
 	}
 }
 
+// marshalDoc returns the yaml of the document, masked and encrypted when configured.
+func (ys *Yaml) marshalDoc(doc *NetworkTrafficDoc, fileName string) ([]byte, error) {
+	if ys.Redactor != nil && doc != nil {
+		ys.Redactor.Redact(doc)
+	}
+	doc, err := encryptDoc(doc)
+	if err != nil {
+		ys.Logger.Error("failed to encrypt the recorded calls", zap.Error(err), zap.Any("yaml file name", fileName))
+		return nil, err
+	}
+	d, err := yamlLib.Marshal(&doc)
+	if err != nil {
+		ys.Logger.Error("failed to marshal the recorded calls into yaml", zap.Error(err), zap.Any("yaml file name", fileName))
+		return nil, err
+	}
+	return d, nil
+}
+
 // findLastIndex returns the index for the new yaml file by reading the yaml file names in the given path directory
 func findLastIndex(path string, Logger *zap.Logger) (int, error) {
 
@@ -109,9 +142,8 @@ func (ys *Yaml) Write(path, fileName string, docRead platform.KindSpecifier) err
 	if isFileEmpty {
 		data = []byte{}
 	}
-	d, err := yamlLib.Marshal(&doc)
+	d, err := ys.marshalDoc(doc, fileName)
 	if err != nil {
-		ys.Logger.Error("failed to marshal the recorded calls into yaml", zap.Error(err), zap.Any("yaml file name", fileName))
 		return err
 	}
 	data = append(data, d...)
@@ -177,6 +209,19 @@ func (ys *Yaml) WriteTestcase(tcRead platform.KindSpecifier, ctx context.Context
 		}
 	}
 
+	var writtenName string
+	var written *NetworkTrafficDoc
+	if !bypassTestCase && ys.Dedup && tc.Kind == models.HTTP {
+		dedupKey := requestKey(tc)
+		if ys.duplicate(dedupKey) {
+			ys.Logger.Debug("skipping the request identical to one already recorded", zap.Any("method", tc.HttpReq.Method), zap.String("url", tc.HttpReq.URL))
+			bypassTestCase = true
+		} else {
+			// the duplicates of the request are counted in the test case once it is written
+			defer func() { ys.captureWritten(dedupKey, writtenName, written) }()
+		}
+	}
+
 	if !bypassTestCase {
 		ys.tele.RecordedTestAndMocks()
 		ys.mutex.Lock()
@@ -187,6 +232,7 @@ func (ys *Yaml) WriteTestcase(tcRead platform.KindSpecifier, ctx context.Context
 			*testsTotal++
 		}
 		ys.mutex.Unlock()
+		metrics.RecordedTests.Inc()
 		var tcsName string
 		if ys.TcsName == "" {
 			if tc.Name == "" {
@@ -203,11 +249,22 @@ func (ys *Yaml) WriteTestcase(tcRead platform.KindSpecifier, ctx context.Context
 			tcsName = ys.TcsName
 		}
 
+		// the values taken from the responses of the previous test cases are replaced with variables
+		ys.templatize(tc, tcsName)
+
 		// encode the testcase and its mocks into yaml docs
 		yamlTc, err := EncodeTestcase(*tc, ys.Logger)
 		if err != nil {
 			return err
 		}
+		// the large bodies are stored in files, once their noisy fields are found
+		stored := *tc
+		if ys.storeBodies(ys.TcsPath, &stored.HttpReq, &stored.HttpResp) {
+			yamlTc, err = EncodeTestcase(stored, ys.Logger)
+			if err != nil {
+				return err
+			}
+		}
 
 		// write testcase yaml
 		yamlTc.Name = tcsName
@@ -216,6 +273,7 @@ func (ys *Yaml) WriteTestcase(tcRead platform.KindSpecifier, ctx context.Context
 			ys.Logger.Error("failed to write testcase yaml file", zap.Error(err))
 			return err
 		}
+		writtenName, written = tcsName, yamlTc
 		ys.Logger.Info("🟠 Keploy has captured test cases for the user's application.", zap.String("path", ys.TcsPath), zap.String("testcase name", tcsName))
 
 	}
@@ -269,6 +327,10 @@ func (ys *Yaml) ReadTestcase(path string, lastSeenId platform.KindSpecifier, opt
 		if err != nil {
 			return nil, err
 		}
+		if err := loadBodies(path, &tc.HttpReq, &tc.HttpResp, ys.Logger); err != nil {
+			ys.Logger.Error("failed to read the body of the testcase from its file", zap.Error(err), zap.String("testcase", tc.Name))
+			return nil, err
+		}
 		// Append the encoded testcase
 		tcs = append(tcs, tc)
 	}
@@ -283,7 +345,26 @@ func (ys *Yaml) ReadTestcase(path string, lastSeenId platform.KindSpecifier, opt
 	return tcsRead, nil
 }
 
+// read returns the documents of the yaml file, upgraded to the current version of the schema.
 func read(path, name string) ([]*NetworkTrafficDoc, error) {
+	docs, err := readDocs(path, name)
+	if err != nil {
+		return nil, err
+	}
+	for _, doc := range docs {
+		if _, err := migrateDoc(doc); err != nil {
+			return nil, err
+		}
+	}
+	return docs, nil
+}
+
+// readDocs returns the documents of the yaml file as they are written, or of the CBOR file when they are stored in
+// CBOR.
+func readDocs(path, name string) ([]*NetworkTrafficDoc, error) {
+	if docsFormat(path, name) == FormatCbor {
+		return readCborDocs(path, name)
+	}
 	file, err := os.OpenFile(filepath.Join(path, name+".yaml"), os.O_RDONLY, os.ModePerm)
 	if err != nil {
 		return nil, err
@@ -300,6 +381,9 @@ func read(path, name string) ([]*NetworkTrafficDoc, error) {
 		if err != nil {
 			return nil, fmt.Errorf("failed to decode the yaml file documents. error: %v", err.Error())
 		}
+		if err := decryptDoc(&doc); err != nil {
+			return nil, err
+		}
 		yamlDocs = append(yamlDocs, &doc)
 	}
 	return yamlDocs, nil
@@ -312,12 +396,45 @@ func (ys *Yaml) WriteMock(mockRead platform.KindSpecifier, ctx context.Context)
 		ys.Logger.Debug("failed to get mocksTotal from context")
 	}
 	(*mocksTotal)[string(mock.Kind)]++
+	metrics.RecordedMocks.Inc(string(mock.Kind))
 	if ctx.Value("cmd") == "mockrecord" {
 		ys.tele.RecordedMock(string(mock.Kind))
 	}
 	if ys.MockName != "" {
 		mock.Name = ys.MockName
 	}
+	// the address of the server of the connection, IPv4 or IPv6, tells apart the dependencies speaking the same protocol
+	if destination, ok := ctx.Value("destination").(string); ok && destination != "" {
+		if mock.Spec.Metadata == nil {
+			mock.Spec.Metadata = map[string]string{}
+		}
+		if _, ok := mock.Spec.Metadata["destination"]; !ok {
+			mock.Spec.Metadata["destination"] = destination
+		}
+	}
+	// the connection of the mock orders the mocks of the connection-sequence policy on replay
+	if connection, ok := ctx.Value(models.ConnectionMetadata).(string); ok && connection != "" {
+		if mock.Spec.Metadata == nil {
+			mock.Spec.Metadata = map[string]string{}
+		}
+		if _, ok := mock.Spec.Metadata[models.ConnectionMetadata]; !ok {
+			mock.Spec.Metadata[models.ConnectionMetadata] = connection
+		}
+	}
+
+	// the multipart uploads are stored as their parts, the large ones in files next to the mocks
+	if mock.Kind == models.HTTP && mock.Spec.HttpReq != nil {
+		split := *mock
+		req := splitMultipart(ys.MockPath, *mock.Spec.HttpReq, ys.Logger)
+		split.Spec.HttpReq = &req
+		if mock.Spec.HttpResp != nil {
+			resp := *mock.Spec.HttpResp
+			split.Spec.HttpResp = &resp
+		}
+		// the large bodies are stored in files next to the mocks
+		ys.storeBodies(ys.MockPath, split.Spec.HttpReq, split.Spec.HttpResp)
+		mock = &split
+	}
 
 	mockYaml, err := EncodeMock(mock, ys.Logger)
 	if err != nil {
@@ -328,7 +445,8 @@ func (ys *Yaml) WriteMock(mockRead platform.KindSpecifier, ctx context.Context)
 		mock.Name = "mocks"
 	}
 
-	err = ys.Write(ys.MockPath, mock.Name, mockYaml)
+	// the mocks are journaled as they are recorded, and moved into their yaml file once the recording ends
+	err = ys.appendJournal(ys.MockPath, mock.Name, mockYaml)
 	if err != nil {
 		return err
 	}
@@ -351,12 +469,13 @@ func (ys *Yaml) ReadTcsMocks(tcRead platform.KindSpecifier, path string) ([]plat
 		mockName = ys.MockName
 	}
 
-	mockPath, err := util.ValidatePath(path + "/" + mockName + ".yaml")
+	_, err := util.ValidatePath(path + "/" + mockName + ".yaml")
 	if err != nil {
 		return nil, err
 	}
+	ys.recoverJournal(path, mockName)
 
-	if _, err := os.Stat(mockPath); err == nil {
+	if docsExist(path, mockName) {
 
 		yamls, err := read(path, mockName)
 		if err != nil {
@@ -368,6 +487,7 @@ func (ys *Yaml) ReadTcsMocks(tcRead platform.KindSpecifier, path string) ([]plat
 			ys.Logger.Error("failed to decode the config mocks from yaml docs", zap.Error(err), zap.Any("session", filepath.Base(path)))
 			return nil, err
 		}
+		joinMockBodies(path, mocks, ys.Logger)
 
 		for _, mock := range mocks {
 			if mock.Spec.Metadata["type"] != "config" {
@@ -433,12 +553,13 @@ func (ys *Yaml) ReadConfigMocks(path string) ([]platform.KindSpecifier, error) {
 		mockName = ys.MockName
 	}
 
-	mockPath, err := util.ValidatePath(path + "/" + mockName + ".yaml")
+	_, err := util.ValidatePath(path + "/" + mockName + ".yaml")
 	if err != nil {
 		return nil, err
 	}
+	ys.recoverJournal(path, mockName)
 
-	if _, err := os.Stat(mockPath); err == nil {
+	if docsExist(path, mockName) {
 
 		yamls, err := read(path, mockName)
 		if err != nil {
@@ -450,6 +571,7 @@ func (ys *Yaml) ReadConfigMocks(path string) ([]platform.KindSpecifier, error) {
 			ys.Logger.Error("failed to decode the config mocks from yaml docs", zap.Error(err), zap.Any("session", filepath.Base(path)))
 			return nil, err
 		}
+		joinMockBodies(path, mocks, ys.Logger)
 
 		for _, mock := range mocks {
 			if mock.Spec.Metadata["type"] == "config" {
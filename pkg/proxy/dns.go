@@ -0,0 +1,130 @@
+package proxy
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/miekg/dns"
+	"go.keploy.io/server/pkg/models"
+	"go.uber.org/zap"
+)
+
+// resolvConf lists the DNS servers which the queries of the application are forwarded to during the recording.
+const resolvConf = "/etc/resolv.conf"
+
+// dnsKey identifies the queries answered by a DNS mock, the names being case insensitive.
+func dnsKey(name string, qtype uint16) string {
+	return strings.ToLower(dns.Fqdn(name)) + " " + dns.TypeToString[qtype]
+}
+
+// forwardDNS sends the query of the application to the DNS servers of the machine and returns their answer.
+func (ps *ProxySet) forwardDNS(r *dns.Msg) (*dns.Msg, error) {
+	config, err := dns.ClientConfigFromFile(resolvConf)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the DNS servers of %s: %v", resolvConf, err)
+	}
+	client := &dns.Client{Net: "udp", Timeout: ps.DnsServerTimeout}
+	var lastErr error
+	for _, server := range config.Servers {
+		resp, _, err := client.Exchange(r, net.JoinHostPort(server, config.Port))
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		if resp.Truncated {
+			// the answers too large for udp are fetched again over tcp
+			tcpClient := &dns.Client{Net: "tcp", Timeout: ps.DnsServerTimeout}
+			if tcpResp, _, err := tcpClient.Exchange(r, net.JoinHostPort(server, config.Port)); err == nil {
+				resp = tcpResp
+			}
+		}
+		return resp, nil
+	}
+	if lastErr == nil {
+		lastErr = fmt.Errorf("no DNS server in %s", resolvConf)
+	}
+	return nil, lastErr
+}
+
+// recordDNS answers the query of the application with the answer of the DNS servers of the machine, which is
+// recorded as a DNS mock the first time the name and type are queried in the session.
+func (ps *ProxySet) recordDNS(w dns.ResponseWriter, r *dns.Msg) {
+	requested := time.Now()
+	resp, err := ps.forwardDNS(r)
+	if err != nil {
+		ps.logger.Error("failed to resolve the dns query of the application", zap.Error(err))
+		resp = new(dns.Msg)
+		resp.SetRcode(r, dns.RcodeServerFailure)
+		if err := w.WriteMsg(resp); err != nil {
+			ps.logger.Error("failed to write dns info back to the client", zap.Error(err))
+		}
+		return
+	}
+	resp.Id = r.Id
+	if err := w.WriteMsg(resp); err != nil {
+		ps.logger.Error("failed to write dns info back to the client", zap.Error(err))
+	}
+	// the failures of the servers aren't answers of the dependencies, which resolve the name again
+	if resp.Rcode == dns.RcodeServerFailure || resp.Rcode == dns.RcodeRefused {
+		return
+	}
+	for _, question := range r.Question {
+		if _, recorded := ps.recordedDNS.LoadOrStore(dnsKey(question.Name, question.Qtype), true); recorded {
+			continue
+		}
+		var answers []string
+		for _, answer := range resp.Answer {
+			answers = append(answers, answer.String())
+		}
+		err := ps.hook.AppendMocks(&models.Mock{
+			Version: models.GetVersion(),
+			Name:    "mocks",
+			Kind:    models.DNS,
+			Spec: models.MockSpec{
+				// the resolutions are made whenever the application wants, not by the testcases
+				Metadata:         map[string]string{"type": "config"},
+				DNSReq:           &models.DNSRequest{Name: dns.Fqdn(question.Name), Type: dns.TypeToString[question.Qtype]},
+				DNSResp:          &models.DNSResponse{Rcode: dns.RcodeToString[resp.Rcode], Answers: answers},
+				ReqTimestampMock: requested,
+				ResTimestampMock: time.Now(),
+			},
+		}, ps.ctx)
+		if err != nil {
+			ps.logger.Error("failed to record the dns query of the application", zap.String("name", question.Name), zap.Error(err))
+		}
+	}
+}
+
+// mockedDNS returns the recorded answer of the question, and false when it wasn't recorded.
+func (ps *ProxySet) mockedDNS(question dns.Question) ([]dns.RR, int, bool) {
+	mocks := ps.hook.GetDNSMocks()
+	key := dnsKey(question.Name, question.Qtype)
+	for _, mock := range mocks {
+		if mock.Kind != models.DNS || mock.Spec.DNSReq == nil || mock.Spec.DNSResp == nil {
+			continue
+		}
+		qtype, ok := dns.StringToType[mock.Spec.DNSReq.Type]
+		if !ok || dnsKey(mock.Spec.DNSReq.Name, qtype) != key {
+			continue
+		}
+		rcode, ok := dns.StringToRcode[mock.Spec.DNSResp.Rcode]
+		if !ok {
+			rcode = dns.RcodeSuccess
+		}
+		var answers []dns.RR
+		for _, answer := range mock.Spec.DNSResp.Answers {
+			rr, err := dns.NewRR(answer)
+			if err != nil || rr == nil {
+				ps.logger.Warn("skipping the invalid record of the dns mock", zap.String("record", answer), zap.Error(err))
+				continue
+			}
+			// the answers are the ones of the query, whatever the case of its name
+			rr.Header().Name = question.Name
+			answers = append(answers, rr)
+		}
+		return answers, rcode, true
+	}
+	return nil, 0, false
+}
@@ -0,0 +1,326 @@
+package cassandraparser
+
+import (
+	"context"
+	"encoding/binary"
+	"io"
+	"net"
+	"strings"
+	"time"
+
+	"go.keploy.io/server/pkg"
+	"go.keploy.io/server/pkg/hooks"
+	"go.keploy.io/server/pkg/metrics"
+	"go.keploy.io/server/pkg/models"
+	"go.keploy.io/server/pkg/proxy/util"
+	"go.keploy.io/server/utils"
+	"go.uber.org/zap"
+)
+
+// bounds of the protocol versions accepted while detecting the protocol
+const (
+	minVersion = 3
+	maxVersion = 5
+	// the flags defined by the protocol v5
+	knownFlags = 0x1f
+)
+
+type CassandraParser struct {
+	logger *zap.Logger
+	hooks  *hooks.Hook
+}
+
+func NewCassandraParser(logger *zap.Logger, h *hooks.Hook) *CassandraParser {
+	return &CassandraParser{
+		logger: logger,
+		hooks:  h,
+	}
+}
+
+// OutgoingType determines if the outgoing network call is Cassandra by checking that the buffer starts with the
+// OPTIONS or STARTUP request which opens a connection of the CQL native protocol.
+func (c *CassandraParser) OutgoingType(buffer []byte) bool {
+	if len(buffer) < headerLength {
+		return false
+	}
+	version := buffer[0]
+	if version < minVersion || version > maxVersion || buffer[1]&^knownFlags != 0 {
+		return false
+	}
+	if buffer[4] != opOptions && buffer[4] != opStartup {
+		return false
+	}
+	length := binary.BigEndian.Uint32(buffer[5:9])
+	return uint64(headerLength)+uint64(length) <= uint64(len(buffer))
+}
+
+func (c *CassandraParser) ProcessOutgoing(requestBuffer []byte, clientConn, destConn net.Conn, ctx context.Context) {
+	switch models.GetMode() {
+	case models.MODE_RECORD:
+		encodeCassandraOutgoing(requestBuffer, clientConn, destConn, c.hooks, c.logger, ctx)
+	case models.MODE_TEST:
+		decodeCassandraOutgoing(requestBuffer, clientConn, destConn, c.hooks, c.logger)
+	default:
+		c.logger.Info("Invalid mode detected while intercepting outgoing cassandra call", zap.Any("mode", models.GetMode()))
+	}
+}
+
+type pendingRequest struct {
+	request   models.CassandraRequest
+	timestamp time.Time
+}
+
+// encodeCassandraOutgoing forwards the traffic between the client and the node and records a mock for every
+// request. The requests are multiplexed over the streams of the connection, so the responses are paired with the
+// requests through their stream id. The events pushed on the stream -1 aren't recorded.
+func encodeCassandraOutgoing(requestBuffer []byte, clientConn, destConn net.Conn, h *hooks.Hook, logger *zap.Logger, ctx context.Context) error {
+	_, err := destConn.Write(requestBuffer)
+	if err != nil {
+		logger.Error("failed to write request message to the destination server", zap.Error(err))
+		return err
+	}
+
+	clientBufferChannel := make(chan []byte)
+	destBufferChannel := make(chan []byte)
+	errChannel := make(chan error)
+	// read requests from client
+	go func() {
+		// Recover from panic and gracefully shutdown
+		defer h.Recover(pkg.GenerateRandomID())
+		defer utils.HandlePanic()
+		readBuffConn(clientConn, clientBufferChannel, errChannel, logger, h)
+	}()
+	// read responses from destination
+	go func() {
+		// Recover from panic and gracefully shutdown
+		defer h.Recover(pkg.GenerateRandomID())
+		defer utils.HandlePanic()
+		readBuffConn(destConn, destBufferChannel, errChannel, logger, h)
+	}()
+
+	var (
+		clientFramer = &framer{}
+		serverFramer = &framer{}
+		pending      = map[int16]pendingRequest{}
+		// the compressed connections are forwarded without being recorded
+		recording = true
+	)
+	recordRequests := func(buffer []byte) {
+		frames, err := clientFramer.feed(buffer)
+		if err != nil {
+			logger.Error("failed to split the cql requests, forwarding the rest of the connection", zap.Error(err))
+			recording = false
+			return
+		}
+		for _, f := range frames {
+			request, err := decodeRequest(f)
+			if err != nil {
+				logger.Error("failed to decode the cql request", zap.Error(err))
+				continue
+			}
+			if f.opcode == opStartup && request.Options["COMPRESSION"] != "" {
+				logger.Warn("compressed cql connections are forwarded without being recorded", zap.String("compression", request.Options["COMPRESSION"]))
+				recording = false
+				return
+			}
+			pending[f.stream] = pendingRequest{request: request, timestamp: time.Now()}
+		}
+	}
+	recordResponses := func(buffer []byte) {
+		frames, err := serverFramer.feed(buffer)
+		if err != nil {
+			logger.Error("failed to split the cql responses, forwarding the rest of the connection", zap.Error(err))
+			recording = false
+			return
+		}
+		for _, f := range frames {
+			if f.stream < 0 {
+				continue
+			}
+			p, ok := pending[f.stream]
+			if !ok {
+				logger.Debug("no cql request found for the response", zap.Int16("stream", f.stream))
+				continue
+			}
+			delete(pending, f.stream)
+			response, err := decodeResponse(f)
+			if err != nil {
+				logger.Error("failed to decode the cql response", zap.Error(err))
+				continue
+			}
+			if response.PreparedID != "" {
+				rememberPrepared(response.PreparedID, p.request.Query)
+			}
+			// the protocol v5 switches to the modern framing once the STARTUP is answered
+			if (f.opcode == opReady || f.opcode == opAuthenticate) && f.protocolVersion() >= 5 {
+				clientFramer.switchToModern()
+				serverFramer.switchToModern()
+			}
+			h.AppendMocks(&models.Mock{
+				Version: models.GetVersion(),
+				Name:    "mocks",
+				Kind:    models.Cassandra,
+				Spec: models.MockSpec{
+					CassandraRequests:  []models.CassandraRequest{p.request},
+					CassandraResponses: []models.CassandraResponse{response},
+					ReqTimestampMock:   p.timestamp,
+					ResTimestampMock:   time.Now(),
+				},
+			}, ctx)
+		}
+	}
+
+	recordRequests(requestBuffer)
+	for {
+		select {
+		case buffer := <-clientBufferChannel:
+			_, err := destConn.Write(buffer)
+			if err != nil {
+				logger.Error("failed to write request message to the destination server", zap.Error(err))
+				return err
+			}
+			if recording {
+				recordRequests(buffer)
+			}
+		case buffer := <-destBufferChannel:
+			_, err := clientConn.Write(buffer)
+			if err != nil {
+				logger.Error("failed to write response to the client", zap.Error(err))
+				return err
+			}
+			if recording {
+				recordResponses(buffer)
+			}
+		case err := <-errChannel:
+			return err
+		}
+	}
+}
+
+func readBuffConn(conn net.Conn, bufferChannel chan []byte, errChannel chan error, logger *zap.Logger, h *hooks.Hook) error {
+	for {
+		buffer, err := util.ReadBytes(conn)
+		if err != nil {
+			if !h.IsUsrAppTerminateInitiated() {
+				if err == io.EOF {
+					logger.Debug("EOF error received from client. Closing connection in cassandra !!")
+				} else if !strings.Contains(err.Error(), "use of closed network connection") {
+					logger.Error("failed to read the packet message in proxy for cassandra dependency", zap.Error(err))
+				}
+				errChannel <- err
+			}
+			return err
+		}
+		bufferChannel <- buffer
+	}
+}
+
+// decodeCassandraOutgoing replies to every request of the client with the response of its mock, carrying the
+// stream id of the request. The connections whose first request wasn't recorded are forwarded to the node, the
+// other requests which can't be matched get an ERROR response.
+func decodeCassandraOutgoing(requestBuffer []byte, clientConn, destConn net.Conn, h *hooks.Hook, logger *zap.Logger) error {
+	var (
+		clientFramer = &framer{}
+		buffer       = requestBuffer
+		// replied is set once a response has been mocked on the connection, received holds the bytes read until then
+		replied  bool
+		received = requestBuffer
+	)
+	write := func(encoded []byte) error {
+		if clientFramer.modern {
+			encoded = encodeSegments(encoded)
+		}
+		_, err := clientConn.Write(encoded)
+		if err != nil {
+			logger.Error("failed to write the cql response to the client application", zap.Error(err))
+		}
+		return err
+	}
+
+	for {
+		frames, err := clientFramer.feed(buffer)
+		if err != nil {
+			logger.Error("failed to split the cql requests", zap.Error(err))
+			return err
+		}
+
+		for _, f := range frames {
+			request, err := decodeRequest(f)
+			if err != nil {
+				logger.Error("failed to decode the cql request", zap.Error(err))
+				return err
+			}
+			mock, matched, err := match(h, request)
+			if err != nil {
+				logger.Error("failed to match the cql request with the mocks", zap.Error(err))
+				return err
+			}
+			if !matched {
+				if !replied {
+					logger.Debug("no mock found for the cassandra connection, forwarding it to the node")
+					return forward(received, clientConn, destConn, h)
+				}
+				metrics.MatchFailures.Inc("cassandra")
+				logger.Error("no mock matched the cql request", zap.String("opcode", request.Header.Opcode), zap.String("query", request.Query))
+				if err := write(encodeError(f, "keploy: no mock matched the "+request.Header.Opcode+" request")); err != nil {
+					return err
+				}
+				continue
+			}
+			replied = true
+
+			for _, response := range mock.Spec.CassandraResponses {
+				encoded, err := encodeResponse(response, f)
+				if err != nil {
+					logger.Error("failed to encode the cql response of the mock", zap.Error(err), zap.String("mock", mock.Name))
+					return err
+				}
+				if err := write(encoded); err != nil {
+					return err
+				}
+				if response.PreparedID != "" {
+					rememberPrepared(response.PreparedID, request.Query)
+				}
+				if (response.Header.Opcode == opcodeNames[opReady] || response.Header.Opcode == opcodeNames[opAuthenticate]) && f.protocolVersion() >= 5 {
+					clientFramer.switchToModern()
+				}
+			}
+		}
+
+		buffer, err = util.ReadBytes(clientConn)
+		if len(buffer) == 0 && err != nil {
+			if err == io.EOF {
+				logger.Debug("EOF error received from client. Closing connection in cassandra !!")
+				return nil
+			}
+			logger.Error("failed to read the request message in proxy for cassandra dependency", zap.Error(err))
+			return err
+		}
+		if !replied {
+			received = append(received, buffer...)
+		}
+	}
+}
+
+// forward passes the connection through to the node when it wasn't recorded.
+func forward(requestBuffer []byte, clientConn, destConn net.Conn, h *hooks.Hook) error {
+	if _, err := destConn.Write(requestBuffer); err != nil {
+		return err
+	}
+	errChannel := make(chan error, 2)
+	go func() {
+		// Recover from panic and gracefully shutdown
+		defer h.Recover(pkg.GenerateRandomID())
+		defer utils.HandlePanic()
+		_, err := io.Copy(destConn, clientConn)
+		errChannel <- err
+	}()
+	go func() {
+		// Recover from panic and gracefully shutdown
+		defer h.Recover(pkg.GenerateRandomID())
+		defer utils.HandlePanic()
+		_, err := io.Copy(clientConn, destConn)
+		errChannel <- err
+	}()
+	return <-errChannel
+}
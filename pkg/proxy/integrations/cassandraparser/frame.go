@@ -0,0 +1,170 @@
+package cassandraparser
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+)
+
+const (
+	headerLength = 9
+	// the direction bit of the version byte is set in the responses
+	responseDirection = 0x80
+	// the flag of the frames having a compressed body
+	flagCompression = 0x01
+	// the bodies are limited to 256MB by the protocol
+	maxBodyLength = 256 * 1024 * 1024
+
+	// the modern framing of the protocol v5 wraps the frames into segments of at most 128KB
+	segmentHeaderLength  = 6
+	segmentTrailerLength = 4
+	maxSegmentPayload    = 128*1024 - 1
+	selfContainedFlag    = 1 << 17
+
+	crc24Init       = 0x875060
+	crc24Polynomial = 0x1974f0b
+)
+
+// initialBytes are hashed before the payload of a segment to compute its CRC32.
+var initialBytes = []byte{0xfa, 0x2d, 0x55, 0xca}
+
+// frame is a complete frame of the protocol, the header is kept in raw.
+type frame struct {
+	version byte
+	flags   byte
+	stream  int16
+	opcode  byte
+	body    []byte
+}
+
+func (f frame) isResponse() bool {
+	return f.version&responseDirection != 0
+}
+
+func (f frame) protocolVersion() byte {
+	return f.version &^ responseDirection
+}
+
+// framer splits the bytes read in one direction of a connection into frames. Once the protocol v5 switches to the
+// modern framing, the frames are read from the payloads of the segments.
+type framer struct {
+	modern   bool
+	raw      []byte
+	payloads []byte
+}
+
+// feed returns the frames completed by the data.
+func (fr *framer) feed(data []byte) ([]frame, error) {
+	fr.raw = append(fr.raw, data...)
+	if !fr.modern {
+		frames, rest, err := splitFrames(fr.raw)
+		if err != nil {
+			return nil, err
+		}
+		fr.raw = rest
+		return frames, nil
+	}
+
+	for len(fr.raw) >= segmentHeaderLength {
+		header := uint32(fr.raw[0]) | uint32(fr.raw[1])<<8 | uint32(fr.raw[2])<<16
+		length := int(header & maxSegmentPayload)
+		if len(fr.raw) < segmentHeaderLength+length+segmentTrailerLength {
+			break
+		}
+		fr.payloads = append(fr.payloads, fr.raw[segmentHeaderLength:segmentHeaderLength+length]...)
+		fr.raw = fr.raw[segmentHeaderLength+length+segmentTrailerLength:]
+	}
+	frames, rest, err := splitFrames(fr.payloads)
+	if err != nil {
+		return nil, err
+	}
+	fr.payloads = rest
+	return frames, nil
+}
+
+// switchToModern makes the framer read segments, the bytes following the frame which ended the legacy framing
+// are already segments.
+func (fr *framer) switchToModern() {
+	fr.modern = true
+	fr.payloads = nil
+}
+
+func splitFrames(buffer []byte) ([]frame, []byte, error) {
+	var frames []frame
+	for len(buffer) >= headerLength {
+		length := int(binary.BigEndian.Uint32(buffer[5:9]))
+		if length > maxBodyLength {
+			return nil, nil, fmt.Errorf("frame body of %d bytes exceeds the limit of the protocol", length)
+		}
+		if len(buffer) < headerLength+length {
+			break
+		}
+		frames = append(frames, frame{
+			version: buffer[0],
+			flags:   buffer[1],
+			stream:  int16(binary.BigEndian.Uint16(buffer[2:4])),
+			opcode:  buffer[4],
+			body:    buffer[headerLength : headerLength+length],
+		})
+		buffer = buffer[headerLength+length:]
+	}
+	return frames, buffer, nil
+}
+
+func encodeFrame(f frame) []byte {
+	buf := make([]byte, headerLength, headerLength+len(f.body))
+	buf[0] = f.version
+	buf[1] = f.flags
+	binary.BigEndian.PutUint16(buf[2:4], uint16(f.stream))
+	buf[4] = f.opcode
+	binary.BigEndian.PutUint32(buf[5:9], uint32(len(f.body)))
+	return append(buf, f.body...)
+}
+
+// encodeSegments wraps an encoded frame into uncompressed segments. A frame fitting a segment is sent in a self
+// contained segment, a larger one is split into several segments.
+func encodeSegments(data []byte) []byte {
+	var segments []byte
+	selfContained := len(data) <= maxSegmentPayload
+	for len(data) > 0 {
+		payload := data
+		if len(payload) > maxSegmentPayload {
+			payload = payload[:maxSegmentPayload]
+		}
+		data = data[len(payload):]
+
+		header := uint32(len(payload))
+		if selfContained {
+			header |= selfContainedFlag
+		}
+		crc := crc24(header)
+		segments = append(segments, byte(header), byte(header>>8), byte(header>>16), byte(crc), byte(crc>>8), byte(crc>>16))
+		segments = append(segments, payload...)
+
+		checksum := crc32.NewIEEE()
+		checksum.Write(initialBytes)
+		checksum.Write(payload)
+		segments = binary.LittleEndian.AppendUint32(segments, checksum.Sum32())
+	}
+	return segments
+}
+
+// crc24 computes the CRC24 of the 3 bytes of a segment header, read in little endian order.
+func crc24(header uint32) uint32 {
+	crc := uint32(crc24Init)
+	for i := 0; i < 3; i++ {
+		crc ^= (header & 0xff) << 16
+		header >>= 8
+		for bit := 0; bit < 8; bit++ {
+			crc <<= 1
+			if crc&0x1000000 != 0 {
+				crc ^= crc24Polynomial
+			}
+		}
+	}
+	return crc & 0xffffff
+}
+
+// errCompressed is returned for the frames having a compressed body, which the parser doesn't decompress.
+var errCompressed = errors.New("compressed cql frames are not supported")
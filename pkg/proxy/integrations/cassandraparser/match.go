@@ -0,0 +1,139 @@
+package cassandraparser
+
+import (
+	"fmt"
+	"sync"
+
+	"go.keploy.io/server/pkg/hooks"
+	"go.keploy.io/server/pkg/models"
+)
+
+var (
+	preparedMutex sync.RWMutex
+	// preparedQueries maps the ids of the prepared statements to their query. The drivers execute a statement on
+	// any connection to the node which prepared it, so the ids are shared by the connections.
+	preparedQueries = map[string]string{}
+)
+
+func rememberPrepared(id, query string) {
+	preparedMutex.Lock()
+	defer preparedMutex.Unlock()
+	preparedQueries[id] = query
+}
+
+func preparedQuery(id string) string {
+	preparedMutex.RLock()
+	defer preparedMutex.RUnlock()
+	return preparedQueries[id]
+}
+
+// rememberMockedStatements registers the statements prepared in the mocks, for the EXECUTE requests of the
+// statements which the driver prepared in a previous test.
+func rememberMockedStatements(mocks []*models.Mock) {
+	for _, mock := range mocks {
+		if mock == nil || mock.Kind != models.Cassandra || len(mock.Spec.CassandraRequests) != 1 {
+			continue
+		}
+		request := mock.Spec.CassandraRequests[0]
+		if request.Header.Opcode != opcodeNames[opPrepare] {
+			continue
+		}
+		for _, response := range mock.Spec.CassandraResponses {
+			if response.PreparedID != "" {
+				rememberPrepared(response.PreparedID, request.Query)
+			}
+		}
+	}
+}
+
+// match finds the mock of the request and removes it from the mocks of the test. The mocks of the same opcode,
+// query (or queries of a batch) and paging state are candidates, the one having the same bound values is preferred,
+// then the one having the same body, then the first one recorded. The handshake and the authentication requests
+// are matched on their opcode.
+func match(h *hooks.Hook, request models.CassandraRequest) (*models.Mock, bool, error) {
+	for {
+		tcsMocks, err := h.GetTcsMocks()
+		if err != nil {
+			return nil, false, fmt.Errorf("error while getting tcs mocks %v", err)
+		}
+		rememberMockedStatements(tcsMocks)
+		if request.Header.Opcode == opcodeNames[opExecute] && request.Query == "" {
+			request.Query = preparedQuery(request.PreparedID)
+		}
+
+		var (
+			bestMatch *models.Mock
+			bestScore = -1
+		)
+		for _, mock := range tcsMocks {
+			if mock == nil || mock.Kind != models.Cassandra || len(mock.Spec.CassandraRequests) != 1 {
+				continue
+			}
+			recorded := mock.Spec.CassandraRequests[0]
+			if recorded.Header.Opcode != request.Header.Opcode || !sameStatement(recorded.CassandraStatement, request.CassandraStatement) ||
+				recorded.PagingState != request.PagingState || len(recorded.Batch) != len(request.Batch) {
+				continue
+			}
+			sameBatch := true
+			for i := range recorded.Batch {
+				if !sameStatement(recorded.Batch[i], request.Batch[i]) {
+					sameBatch = false
+					break
+				}
+			}
+			if !sameBatch {
+				continue
+			}
+
+			score := 0
+			if equalValues(recorded.Values, request.Values) {
+				score += 2
+				for i := range recorded.Batch {
+					if !equalValues(recorded.Batch[i].Values, request.Batch[i].Values) {
+						score -= 2
+						break
+					}
+				}
+			}
+			if recorded.Body == request.Body {
+				score++
+			}
+			if score > bestScore {
+				bestMatch, bestScore = mock, score
+			}
+		}
+		if bestMatch == nil {
+			return nil, false, nil
+		}
+
+		isDeleted, err := h.DeleteTcsMock(bestMatch)
+		if err != nil {
+			return nil, false, fmt.Errorf("error while deleting tcs mock: %v", err)
+		}
+		if !isDeleted {
+			continue
+		}
+		return bestMatch, true, nil
+	}
+}
+
+// sameStatement compares the queries of the statements, or their prepared ids when the query of one of them
+// couldn't be resolved.
+func sameStatement(a, b models.CassandraStatement) bool {
+	if a.Query != "" && b.Query != "" {
+		return a.Query == b.Query
+	}
+	return a.PreparedID == b.PreparedID
+}
+
+func equalValues(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,473 @@
+package cassandraparser
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+
+	"go.keploy.io/server/pkg/models"
+)
+
+const (
+	opError         = 0x00
+	opStartup       = 0x01
+	opReady         = 0x02
+	opAuthenticate  = 0x03
+	opOptions       = 0x05
+	opSupported     = 0x06
+	opQuery         = 0x07
+	opResult        = 0x08
+	opPrepare       = 0x09
+	opExecute       = 0x0a
+	opRegister      = 0x0b
+	opEvent         = 0x0c
+	opBatch         = 0x0d
+	opAuthChallenge = 0x0e
+	opAuthResponse  = 0x0f
+	opAuthSuccess   = 0x10
+)
+
+var opcodeNames = map[byte]string{
+	opError:         "ERROR",
+	opStartup:       "STARTUP",
+	opReady:         "READY",
+	opAuthenticate:  "AUTHENTICATE",
+	opOptions:       "OPTIONS",
+	opSupported:     "SUPPORTED",
+	opQuery:         "QUERY",
+	opResult:        "RESULT",
+	opPrepare:       "PREPARE",
+	opExecute:       "EXECUTE",
+	opRegister:      "REGISTER",
+	opEvent:         "EVENT",
+	opBatch:         "BATCH",
+	opAuthChallenge: "AUTH_CHALLENGE",
+	opAuthResponse:  "AUTH_RESPONSE",
+	opAuthSuccess:   "AUTH_SUCCESS",
+}
+
+func opcodeOf(name string) (byte, bool) {
+	for opcode, n := range opcodeNames {
+		if n == name {
+			return opcode, true
+		}
+	}
+	return 0, false
+}
+
+// flags of the query parameters
+const (
+	flagValues            = 0x01
+	flagPageSize          = 0x04
+	flagPagingState       = 0x08
+	flagSerialConsistency = 0x10
+	flagTimestamp         = 0x20
+	flagNamesForValues    = 0x40
+	flagKeyspace          = 0x80
+	flagNowInSeconds      = 0x100
+)
+
+// kinds of result
+var resultKinds = map[int32]string{
+	1: "Void",
+	2: "Rows",
+	3: "SetKeyspace",
+	4: "Prepared",
+	5: "SchemaChange",
+}
+
+// flags of the rows metadata
+const (
+	flagGlobalTableSpec = 0x01
+	flagHasMorePages    = 0x02
+	flagNoMetadata      = 0x04
+	flagMetadataChanged = 0x08
+)
+
+var typeNames = map[uint16]string{
+	0x01: "ascii",
+	0x02: "bigint",
+	0x03: "blob",
+	0x04: "boolean",
+	0x05: "counter",
+	0x06: "decimal",
+	0x07: "double",
+	0x08: "float",
+	0x09: "int",
+	0x0b: "timestamp",
+	0x0c: "uuid",
+	0x0d: "varchar",
+	0x0e: "varint",
+	0x0f: "timeuuid",
+	0x10: "inet",
+	0x11: "date",
+	0x12: "time",
+	0x13: "smallint",
+	0x14: "tinyint",
+	0x15: "duration",
+}
+
+func decodeHeader(f frame) models.CassandraHeader {
+	name, ok := opcodeNames[f.opcode]
+	if !ok {
+		name = fmt.Sprintf("0x%02x", f.opcode)
+	}
+	return models.CassandraHeader{
+		Version: f.version,
+		Flags:   f.flags,
+		Stream:  f.stream,
+		Opcode:  name,
+	}
+}
+
+// decodeRequest decodes the frame sent by the client. The query of an EXECUTE request is resolved from the
+// statements prepared so far.
+func decodeRequest(f frame) (models.CassandraRequest, error) {
+	request := models.CassandraRequest{
+		Header: decodeHeader(f),
+		Body:   base64.StdEncoding.EncodeToString(f.body),
+	}
+	if f.flags&flagCompression != 0 {
+		return request, errCompressed
+	}
+
+	version := f.protocolVersion()
+	r := &reader{buf: f.body}
+	switch f.opcode {
+	case opStartup:
+		request.Options = r.readStringMap()
+	case opQuery:
+		request.Query = normalizeQuery(r.readLongString())
+		decodeQueryParameters(r, version, &request)
+	case opPrepare:
+		request.Query = normalizeQuery(r.readLongString())
+	case opExecute:
+		id := r.readShortBytes()
+		if version >= 5 {
+			// result metadata id
+			r.readShortBytes()
+		}
+		request.PreparedID = hex.EncodeToString(id)
+		request.Query = preparedQuery(request.PreparedID)
+		decodeQueryParameters(r, version, &request)
+	case opBatch:
+		r.readByte()
+		count := int(r.readShort())
+		for i := 0; i < count && r.err == nil; i++ {
+			statement := models.CassandraStatement{}
+			if r.readByte() == 0 {
+				statement.Query = normalizeQuery(r.readLongString())
+			} else {
+				statement.PreparedID = hex.EncodeToString(r.readShortBytes())
+				statement.Query = preparedQuery(statement.PreparedID)
+			}
+			valueCount := int(r.readShort())
+			for j := 0; j < valueCount && r.err == nil; j++ {
+				statement.Values = append(statement.Values, r.readValue())
+			}
+			request.Batch = append(request.Batch, statement)
+		}
+	}
+	if r.err != nil {
+		return request, fmt.Errorf("failed to decode the %s request: %v", request.Header.Opcode, r.err)
+	}
+	return request, nil
+}
+
+func decodeQueryParameters(r *reader, version byte, request *models.CassandraRequest) {
+	// consistency
+	r.readShort()
+	var flags uint32
+	if version >= 5 {
+		flags = r.readInt()
+	} else {
+		flags = uint32(r.readByte())
+	}
+	if flags&flagValues != 0 {
+		count := int(r.readShort())
+		for i := 0; i < count && r.err == nil; i++ {
+			if flags&flagNamesForValues != 0 {
+				r.readString()
+			}
+			request.Values = append(request.Values, r.readValue())
+		}
+	}
+	if flags&flagPageSize != 0 {
+		request.PageSize = int32(r.readInt())
+	}
+	if flags&flagPagingState != 0 {
+		if state := r.readBytes(); state != nil {
+			request.PagingState = base64.StdEncoding.EncodeToString(state)
+		}
+	}
+	// the serial consistency, the default timestamp, the keyspace and the current time aren't matched
+}
+
+// decodeResponse decodes the frame sent by the server.
+func decodeResponse(f frame) (models.CassandraResponse, error) {
+	response := models.CassandraResponse{
+		Header: decodeHeader(f),
+		Body:   base64.StdEncoding.EncodeToString(f.body),
+	}
+	if f.flags&flagCompression != 0 {
+		return response, errCompressed
+	}
+
+	version := f.protocolVersion()
+	r := &reader{buf: f.body}
+	// the tracing id, the warnings and the custom payload come before the body of the message
+	if f.flags&0x02 != 0 {
+		r.next(16)
+	}
+	if f.flags&0x08 != 0 {
+		count := int(r.readShort())
+		for i := 0; i < count && r.err == nil; i++ {
+			r.readString()
+		}
+	}
+	if f.flags&0x04 != 0 {
+		count := int(r.readShort())
+		for i := 0; i < count && r.err == nil; i++ {
+			r.readString()
+			r.readBytes()
+		}
+	}
+
+	switch f.opcode {
+	case opError:
+		response.Error = &models.CassandraError{
+			Code:    int32(r.readInt()),
+			Message: r.readString(),
+		}
+	case opResult:
+		kind := int32(r.readInt())
+		response.Result = resultKinds[kind]
+		switch kind {
+		case 2:
+			decodeRowsMetadata(r, version, &response, false)
+			response.RowsCount = int32(r.readInt())
+		case 3:
+			response.Keyspace = r.readString()
+		case 4:
+			response.PreparedID = hex.EncodeToString(r.readShortBytes())
+			if version >= 5 {
+				// result metadata id
+				r.readShortBytes()
+			}
+			// the metadata of the bound variables
+			decodeRowsMetadata(r, version, &models.CassandraResponse{}, true)
+			decodeRowsMetadata(r, version, &response, false)
+		}
+	}
+	if r.err != nil {
+		return response, fmt.Errorf("failed to decode the %s response: %v", response.Header.Opcode, r.err)
+	}
+	return response, nil
+}
+
+// decodeRowsMetadata decodes the metadata of the rows, or the metadata of the bound variables of a prepared
+// statement which also carries the indexes of the partition key.
+func decodeRowsMetadata(r *reader, version byte, response *models.CassandraResponse, prepared bool) {
+	flags := r.readInt()
+	columnCount := int(r.readInt())
+	if prepared {
+		pkCount := int(r.readInt())
+		for i := 0; i < pkCount && r.err == nil; i++ {
+			r.readShort()
+		}
+	}
+	if !prepared && flags&flagHasMorePages != 0 {
+		if state := r.readBytes(); state != nil {
+			response.PagingState = base64.StdEncoding.EncodeToString(state)
+		}
+	}
+	if !prepared && flags&flagMetadataChanged != 0 && version >= 5 {
+		r.readShortBytes()
+	}
+	if !prepared && flags&flagNoMetadata != 0 {
+		return
+	}
+	var keyspace, table string
+	if flags&flagGlobalTableSpec != 0 {
+		keyspace = r.readString()
+		table = r.readString()
+	}
+	for i := 0; i < columnCount && r.err == nil; i++ {
+		column := models.CassandraColumn{Keyspace: keyspace, Table: table}
+		if flags&flagGlobalTableSpec == 0 {
+			column.Keyspace = r.readString()
+			column.Table = r.readString()
+		}
+		column.Name = r.readString()
+		column.Type = r.readOption()
+		response.Columns = append(response.Columns, column)
+	}
+}
+
+// encodeResponse returns the frame of the response of a mock for the request, the response carries the version and
+// the stream of the request.
+func encodeResponse(response models.CassandraResponse, request frame) ([]byte, error) {
+	opcode, ok := opcodeOf(response.Header.Opcode)
+	if !ok {
+		return nil, fmt.Errorf("unknown opcode %s in the response", response.Header.Opcode)
+	}
+	body, err := base64.StdEncoding.DecodeString(response.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode the body of the response: %v", err)
+	}
+	return encodeFrame(frame{
+		version: request.protocolVersion() | responseDirection,
+		flags:   response.Header.Flags &^ flagCompression,
+		stream:  request.stream,
+		opcode:  opcode,
+		body:    body,
+	}), nil
+}
+
+// encodeError returns an ERROR response for a request which couldn't be mocked.
+func encodeError(request frame, message string) []byte {
+	body := make([]byte, 6, 6+len(message))
+	// server error
+	binary.BigEndian.PutUint32(body[0:4], 0x0000)
+	binary.BigEndian.PutUint16(body[4:6], uint16(len(message)))
+	body = append(body, message...)
+	return encodeFrame(frame{
+		version: request.protocolVersion() | responseDirection,
+		stream:  request.stream,
+		opcode:  opError,
+		body:    body,
+	})
+}
+
+func normalizeQuery(query string) string {
+	return strings.Join(strings.Fields(query), " ")
+}
+
+// reader reads the notations of the protocol, the first error is kept and ends the reading.
+type reader struct {
+	buf []byte
+	off int
+	err error
+}
+
+func (r *reader) next(n int) []byte {
+	if r.err != nil {
+		return nil
+	}
+	if n < 0 || len(r.buf) < r.off+n {
+		r.err = errors.New("body too short")
+		return nil
+	}
+	b := r.buf[r.off : r.off+n]
+	r.off += n
+	return b
+}
+
+func (r *reader) readByte() byte {
+	b := r.next(1)
+	if b == nil {
+		return 0
+	}
+	return b[0]
+}
+
+func (r *reader) readShort() uint16 {
+	b := r.next(2)
+	if b == nil {
+		return 0
+	}
+	return binary.BigEndian.Uint16(b)
+}
+
+func (r *reader) readInt() uint32 {
+	b := r.next(4)
+	if b == nil {
+		return 0
+	}
+	return binary.BigEndian.Uint32(b)
+}
+
+func (r *reader) readString() string {
+	return string(r.next(int(r.readShort())))
+}
+
+func (r *reader) readLongString() string {
+	return string(r.next(int(int32(r.readInt()))))
+}
+
+func (r *reader) readShortBytes() []byte {
+	return r.next(int(r.readShort()))
+}
+
+// readBytes returns nil for a null value.
+func (r *reader) readBytes() []byte {
+	length := int32(r.readInt())
+	if length < 0 {
+		return nil
+	}
+	return r.next(int(length))
+}
+
+// readValue returns a bound value hex encoded, or "null" and "unset" for the values which aren't set.
+func (r *reader) readValue() string {
+	length := int32(r.readInt())
+	switch {
+	case length == -1:
+		return "null"
+	case length == -2:
+		return "unset"
+	case length < 0:
+		r.err = fmt.Errorf("invalid value length %d", length)
+		return ""
+	}
+	return hex.EncodeToString(r.next(int(length)))
+}
+
+func (r *reader) readStringMap() map[string]string {
+	count := int(r.readShort())
+	m := make(map[string]string, count)
+	for i := 0; i < count && r.err == nil; i++ {
+		key := r.readString()
+		m[key] = r.readString()
+	}
+	return m
+}
+
+// readOption returns the name of a column type.
+func (r *reader) readOption() string {
+	id := r.readShort()
+	switch id {
+	case 0x0000:
+		return r.readString()
+	case 0x20:
+		return "list<" + r.readOption() + ">"
+	case 0x21:
+		key := r.readOption()
+		return "map<" + key + ", " + r.readOption() + ">"
+	case 0x22:
+		return "set<" + r.readOption() + ">"
+	case 0x30:
+		keyspace := r.readString()
+		name := r.readString()
+		count := int(r.readShort())
+		for i := 0; i < count && r.err == nil; i++ {
+			r.readString()
+			r.readOption()
+		}
+		return keyspace + "." + name
+	case 0x31:
+		count := int(r.readShort())
+		elements := make([]string, 0, count)
+		for i := 0; i < count && r.err == nil; i++ {
+			elements = append(elements, r.readOption())
+		}
+		return "tuple<" + strings.Join(elements, ", ") + ">"
+	}
+	if name, ok := typeNames[id]; ok {
+		return name
+	}
+	return fmt.Sprintf("0x%04x", id)
+}
@@ -14,6 +14,7 @@ import (
 
 	"go.keploy.io/server/pkg"
 	"go.keploy.io/server/pkg/hooks"
+	"go.keploy.io/server/pkg/metrics"
 	"go.keploy.io/server/pkg/models"
 	"go.keploy.io/server/pkg/proxy/util"
 	"go.keploy.io/server/utils"
@@ -70,6 +71,7 @@ func decodeGenericOutgoing(requestBuffer []byte, clientConn, destConn net.Conn,
 		}
 
 		if !matched {
+			metrics.MatchFailures.Inc("generic")
 			// logger.Error("failed to match the dependency call from user application", zap.Any("request packets", len(genericRequests)))
 			clientConn.SetReadDeadline(time.Time{})
 			logger.Debug("the genericRequests are before pass through", zap.Any("length", len(genericRequests)))
@@ -25,23 +25,24 @@ func PostgresDecoder(encoded string) ([]byte, error) {
 }
 
 func fuzzymatch(requestBuffers [][]byte, h *hooks.Hook) (bool, []models.GenericPayload, error) {
-	for {
-		tcsMocks, err := h.GetTcsMocks()
-		if err != nil {
-			return false, nil, fmt.Errorf("error while getting tcs mocks %v", err)
+	// the messages are compared encoded like in the mocks
+	encoded := make([]string, len(requestBuffers))
+	for requestIndex, reqBuff := range requestBuffers {
+		encoded[requestIndex] = string(reqBuff)
+		if !IsAsciiPrintable(string(reqBuff)) {
+			encoded[requestIndex] = base64.StdEncoding.EncodeToString(reqBuff)
 		}
-		index := -1
-		for idx, mock := range tcsMocks {
-			if len(mock.Spec.GenericRequests) == len(requestBuffers) {
+	}
+	for {
+		var matchedMock *models.Mock
+		if len(encoded) > 0 {
+			// the mocks recorded for the same first message are looked up in the index
+			for _, mock := range h.GetTcsMocksFor(models.GENERIC, hooks.GenericRequestKey(encoded[0])) {
+				if len(mock.Spec.GenericRequests) != len(requestBuffers) {
+					continue
+				}
 				matched := true // Flag to track if all requests match
-
-				for requestIndex, reqBuff := range requestBuffers {
-
-					bufStr := string(reqBuff)
-					if !IsAsciiPrintable(string(reqBuff)) {
-						bufStr = base64.StdEncoding.EncodeToString(reqBuff)
-					}
-
+				for requestIndex, bufStr := range encoded {
 					// Compare the encoded data
 					if mock.Spec.GenericRequests[requestIndex].Message[0].Data != bufStr {
 						matched = false
@@ -49,20 +50,26 @@ func fuzzymatch(requestBuffers [][]byte, h *hooks.Hook) (bool, []models.GenericP
 					}
 				}
 				if matched {
-					index = idx
+					matchedMock = mock
 					break
 				}
 			}
 		}
 
-		if index == -1 {
-			index = findBinaryMatch(tcsMocks, requestBuffers, h)
+		if matchedMock == nil {
+			tcsMocks, err := h.GetTcsMocks()
+			if err != nil {
+				return false, nil, fmt.Errorf("error while getting tcs mocks %v", err)
+			}
+			if index := findBinaryMatch(tcsMocks, requestBuffers, h); index != -1 {
+				matchedMock = tcsMocks[index]
+			}
 		}
 
-		if index != -1 {
-			responseMock := make([]models.GenericPayload, len(tcsMocks[index].Spec.GenericResponses))
-			copy(responseMock, tcsMocks[index].Spec.GenericResponses)
-			isDeleted, err := h.DeleteTcsMock(tcsMocks[index])
+		if matchedMock != nil {
+			responseMock := make([]models.GenericPayload, len(matchedMock.Spec.GenericResponses))
+			copy(responseMock, matchedMock.Spec.GenericResponses)
+			isDeleted, err := h.DeleteTcsMock(matchedMock)
 			if err != nil {
 				return false, nil, fmt.Errorf("error while deleting tcsMock %v", err)
 			}
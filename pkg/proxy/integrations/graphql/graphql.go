@@ -0,0 +1,207 @@
+// Package graphql reads the GraphQL requests sent over http, as the JSON body of a POST or the query of a GET, so
+// that they are matched and reported on their operation rather than as opaque calls to the same endpoint. The
+// documents are compared on their tokens, whatever their whitespaces, commas and comments.
+package graphql
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/url"
+	"strings"
+)
+
+// Request is a GraphQL request.
+type Request struct {
+	// OperationName is the operation of the document to execute, empty when the document has a single one
+	OperationName string
+	Query         string
+	Variables     map[string]interface{}
+}
+
+// fields are the fields of the body of a GraphQL request, a JSON body with other fields not being one.
+var fields = map[string]bool{"query": true, "operationName": true, "variables": true, "extensions": true}
+
+// Parse returns the GraphQL request of the JSON body of a POST.
+func Parse(body []byte) (*Request, bool) {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(body, &raw); err != nil {
+		return nil, false
+	}
+	for field := range raw {
+		if !fields[field] {
+			return nil, false
+		}
+	}
+	req := &Request{}
+	if err := json.Unmarshal(raw["query"], &req.Query); err != nil || !isDocument(req.Query) {
+		return nil, false
+	}
+	if name, ok := raw["operationName"]; ok {
+		// a null operationName is left empty
+		_ = json.Unmarshal(name, &req.OperationName)
+	}
+	if variables, ok := raw["variables"]; ok {
+		_ = json.Unmarshal(variables, &req.Variables)
+	}
+	return req, true
+}
+
+// ParseQuery returns the GraphQL request of the query parameters of a GET, whose variables are JSON encoded.
+func ParseQuery(values url.Values) (*Request, bool) {
+	req := &Request{
+		Query:         values.Get("query"),
+		OperationName: values.Get("operationName"),
+	}
+	if !isDocument(req.Query) {
+		return nil, false
+	}
+	if variables := values.Get("variables"); variables != "" {
+		_ = json.Unmarshal([]byte(variables), &req.Variables)
+	}
+	return req, true
+}
+
+// Normalize returns the tokens of the document separated by single spaces, without its comments and its commas.
+func Normalize(document string) string {
+	return strings.Join(tokens(document), " ")
+}
+
+// Hash returns the hash of the normalised document, which identifies the documents differing only by their
+// formatting.
+func (r *Request) Hash() string {
+	sum := sha256.Sum256([]byte(Normalize(r.Query)))
+	return hex.EncodeToString(sum[:8])
+}
+
+// Operation returns the type and the name of the executed operation, like "query GetUser" or "mutation", the
+// anonymous queries written as a selection set being queries.
+func (r *Request) Operation() string {
+	tokens := tokens(r.Query)
+	depth := 0
+	for i, token := range tokens {
+		switch token {
+		case "{":
+			// a selection set at the top level is an anonymous query
+			if depth == 0 && (i == 0 || tokens[i-1] == "}") && r.OperationName == "" {
+				return "query"
+			}
+			depth++
+			continue
+		case "}":
+			depth--
+			continue
+		case "query", "mutation", "subscription":
+		default:
+			continue
+		}
+		// the keywords are only operation types at the start of a definition
+		if depth != 0 || (i > 0 && tokens[i-1] != "}") {
+			continue
+		}
+		name := ""
+		if i+1 < len(tokens) && isName(tokens[i+1]) {
+			name = tokens[i+1]
+		}
+		if r.OperationName != "" && name != r.OperationName {
+			continue
+		}
+		if name == "" {
+			return token
+		}
+		return token + " " + name
+	}
+	if r.OperationName != "" {
+		return r.OperationName
+	}
+	return "query"
+}
+
+// isDocument reports whether the text starts like a GraphQL document.
+func isDocument(text string) bool {
+	tokens := tokens(text)
+	if len(tokens) == 0 {
+		return false
+	}
+	switch tokens[0] {
+	case "{", "query", "mutation", "subscription", "fragment":
+		return true
+	}
+	return false
+}
+
+// tokens returns the lexical tokens of the document, the whitespaces, the commas and the comments being ignored.
+func tokens(document string) []string {
+	var tokens []string
+	for i := 0; i < len(document); {
+		c := document[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == ',':
+			i++
+		case c == '#':
+			for i < len(document) && document[i] != '\n' && document[i] != '\r' {
+				i++
+			}
+		case strings.HasPrefix(document[i:], `"""`):
+			n := 3
+			for i+n < len(document) {
+				if strings.HasPrefix(document[i+n:], `\"""`) {
+					n += 4
+				} else if strings.HasPrefix(document[i+n:], `"""`) {
+					n += 3
+					break
+				} else {
+					n++
+				}
+			}
+			tokens = append(tokens, document[i:i+n])
+			i += n
+		case c == '"':
+			n := 1
+			for i+n < len(document) && document[i+n] != '"' && document[i+n] != '\n' {
+				if document[i+n] == '\\' && i+n+1 < len(document) {
+					n++
+				}
+				n++
+			}
+			if i+n < len(document) && document[i+n] == '"' {
+				n++
+			}
+			tokens = append(tokens, document[i:i+n])
+			i += n
+		case strings.HasPrefix(document[i:], "..."):
+			tokens = append(tokens, "...")
+			i += 3
+		case isNameStart(c):
+			n := 1
+			for i+n < len(document) && (isNameStart(document[i+n]) || isDigit(document[i+n])) {
+				n++
+			}
+			tokens = append(tokens, document[i:i+n])
+			i += n
+		case c == '-' || isDigit(c):
+			n := 1
+			for i+n < len(document) && (isDigit(document[i+n]) || strings.IndexByte(".eE", document[i+n]) >= 0 || ((document[i+n] == '+' || document[i+n] == '-') && strings.IndexByte("eE", document[i+n-1]) >= 0)) {
+				n++
+			}
+			tokens = append(tokens, document[i:i+n])
+			i += n
+		default:
+			tokens = append(tokens, string(c))
+			i++
+		}
+	}
+	return tokens
+}
+
+func isNameStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isName(token string) bool {
+	return token != "" && isNameStart(token[0])
+}
@@ -9,6 +9,9 @@ import (
 	"golang.org/x/net/http2/hpack"
 
 	"go.keploy.io/server/pkg/hooks"
+	"go.keploy.io/server/pkg/metrics"
+	"go.keploy.io/server/pkg/models"
+	"go.keploy.io/server/pkg/proxy/integrations/httpparser"
 )
 
 type transcoder struct {
@@ -17,6 +20,8 @@ type transcoder struct {
 	logger  *zap.Logger
 	framer  *http2.Framer
 	decoder *hpack.Decoder
+	// block is the header block being received through CONTINUATION frames
+	block headerBlock
 }
 
 func NewTranscoder(framer *http2.Framer, logger *zap.Logger, h *hooks.Hook) *transcoder {
@@ -69,11 +74,39 @@ func (srv *transcoder) ProcessDataFrame(dataFrame *http2.DataFrame) error {
 			zap.Any("stream_id", id))
 		return http2.ConnectionError(http2.ErrCodeProtocol)
 	}
+	// Give back the flow control window taken by the frame, the client could block on large requests otherwise.
+	if length := dataFrame.Header().Length; length > 0 {
+		if err := srv.framer.WriteWindowUpdate(0, length); err != nil {
+			return err
+		}
+		if !dataFrame.StreamEnded() {
+			if err := srv.framer.WriteWindowUpdate(id, length); err != nil {
+				return err
+			}
+		}
+	}
+
+	if srv.sic.HttpStreams.Has(id) {
+		srv.sic.HttpStreams.AddRequestData(id, dataFrame.Data())
+		if !dataFrame.StreamEnded() {
+			return nil
+		}
+		return srv.sic.HttpStreams.Respond(id, srv.framer, srv.logger)
+	}
+
 	srv.sic.AddPayloadForRequest(id, dataFrame.Data())
 
-	if dataFrame.StreamEnded() {
-		defer srv.sic.ResetStream(dataFrame.StreamID)
+	// The client streaming RPCs send several messages, the response is only sent once the request is complete.
+	if !dataFrame.StreamEnded() {
+		return nil
 	}
+	return srv.respond(id)
+}
+
+// respond writes the mocked response of the request sent on the stream, its messages are written in separate
+// DATA frames to replay the server streaming RPCs.
+func (srv *transcoder) respond(id uint32) error {
+	defer srv.sic.ResetStream(id)
 
 	grpcReq := srv.sic.FetchRequestForStream(id)
 
@@ -83,6 +116,7 @@ func (srv *transcoder) ProcessDataFrame(dataFrame *http2.DataFrame) error {
 		return fmt.Errorf("failed match mocks: %v", err)
 	}
 	if mock == nil {
+		metrics.MatchFailures.Inc("grpc")
 		return fmt.Errorf("failed to mock the output for unrecorded outgoing grpc call")
 	}
 
@@ -129,17 +163,20 @@ func (srv *transcoder) ProcessDataFrame(dataFrame *http2.DataFrame) error {
 		return err
 	}
 
-	payload, err := CreatePayloadFromLengthPrefixedMessage(grpcMockResp.Body)
-	if err != nil {
-		srv.logger.Error("could not create grpc payload from mocks", zap.Error(err))
-		return err
-	}
+	messages := append([]models.GrpcLengthPrefixedMessage{grpcMockResp.Body}, grpcMockResp.Stream...)
+	for _, message := range messages {
+		payload, err := CreatePayloadFromLengthPrefixedMessage(message)
+		if err != nil {
+			srv.logger.Error("could not create grpc payload from mocks", zap.Error(err))
+			return err
+		}
 
-	// Write the DATA frame with the payload.
-	err = srv.framer.WriteData(id, false, payload)
-	if err != nil {
-		srv.logger.Error("could not write the data frame onto the client", zap.Error(err))
-		return err
+		// Write the DATA frame with the payload.
+		err = srv.framer.WriteData(id, false, payload)
+		if err != nil {
+			srv.logger.Error("could not write the data frame onto the client", zap.Error(err))
+			return err
+		}
 	}
 
 	// Reset the buffer and start with a new encoding.
@@ -231,13 +268,43 @@ func (srv *transcoder) ProcessHeadersFrame(headersFrame *http2.HeadersFrame) err
 		return http2.ConnectionError(http2.ErrCodeProtocol)
 	}
 
-	pseudoHeaders, ordinaryHeaders, err := ExtractHeaders(headersFrame, srv.decoder)
+	// The frame is reused by the next read, the fragment is copied.
+	srv.block = headerBlock{
+		streamID:  id,
+		endStream: headersFrame.StreamEnded(),
+		fragment:  append([]byte{}, headersFrame.HeaderBlockFragment()...),
+	}
+	if !headersFrame.HeadersEnded() {
+		return nil
+	}
+	return srv.processHeaderBlock()
+}
+
+// processHeaderBlock handles the complete header block of a request. The requests which aren't gRPC calls are
+// mocked as HTTP calls.
+func (srv *transcoder) processHeaderBlock() error {
+	id := srv.block.streamID
+	hf, err := srv.decoder.DecodeFull(srv.block.fragment)
 	if err != nil {
-		fmt.Errorf("could not extract headers from frame: %v", err)
+		return fmt.Errorf("could not extract headers from frame: %v", err)
+	}
+
+	if srv.sic.HttpStreams.Has(id) || !httpparser.IsGrpcRequest(hf) {
+		srv.sic.HttpStreams.AddRequestHeaders(id, hf)
+		if srv.block.endStream {
+			return srv.sic.HttpStreams.Respond(id, srv.framer, srv.logger)
+		}
+		return nil
 	}
 
+	pseudoHeaders, ordinaryHeaders := splitHeaders(hf)
 	srv.sic.AddHeadersForRequest(id, pseudoHeaders, true)
 	srv.sic.AddHeadersForRequest(id, ordinaryHeaders, false)
+
+	// A request ending with its headers carries no message.
+	if srv.block.endStream {
+		return srv.respond(id)
+	}
 	return nil
 }
 
@@ -248,12 +315,19 @@ func (srv *transcoder) ProcessPushPromise(pushPromiseFrame *http2.PushPromiseFra
 	return http2.ConnectionError(http2.ErrCodeProtocol)
 }
 
-func (srv *transcoder) ProcessContinuationFrame(ContinuationFrame *http2.ContinuationFrame) error {
-	// Continuation frame support is overkill currently because the headers won't exceed the frame size
-	// used by our mock server.
-	// However, if we really need this feature, we can implement it later.
-	srv.logger.Error("Continuation Frame received. This is unsupported currently")
-	return fmt.Errorf("continuation frame is unsupported in the current implementation")
+func (srv *transcoder) ProcessContinuationFrame(continuationFrame *http2.ContinuationFrame) error {
+	// The headers exceeding the frame size, like large cookies, are continued in CONTINUATION frames which
+	// must follow the HEADERS frame of their stream.
+	if continuationFrame.StreamID != srv.block.streamID {
+		srv.logger.Error("As per HTTP/2 spec, CONTINUATION frame must follow the HEADERS frame of its stream.",
+			zap.Any("stream_id", continuationFrame.StreamID))
+		return http2.ConnectionError(http2.ErrCodeProtocol)
+	}
+	srv.block.fragment = append(srv.block.fragment, continuationFrame.HeaderBlockFragment()...)
+	if !continuationFrame.HeadersEnded() {
+		return nil
+	}
+	return srv.processHeaderBlock()
 }
 
 func (srv *transcoder) ProcessGenericFrame(frame http2.Frame) error {
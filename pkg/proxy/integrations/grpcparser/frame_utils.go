@@ -76,6 +76,12 @@ func ExtractHeaders(frame *http2.HeadersFrame, decoder *hpack.Decoder) (pseudoHe
 		return nil, nil, fmt.Errorf("could not decode headers: %v", err)
 	}
 
+	pseudoHeaders, ordinaryHeaders = splitHeaders(hf)
+	return pseudoHeaders, ordinaryHeaders, nil
+}
+
+// splitHeaders separates the decoded pseudo headers from the ordinary ones.
+func splitHeaders(hf []hpack.HeaderField) (pseudoHeaders, ordinaryHeaders map[string]string) {
 	pseudoHeaders = make(map[string]string)
 	ordinaryHeaders = make(map[string]string)
 
@@ -87,5 +93,13 @@ func ExtractHeaders(frame *http2.HeadersFrame, decoder *hpack.Decoder) (pseudoHe
 		}
 	}
 
-	return pseudoHeaders, ordinaryHeaders, nil
+	return pseudoHeaders, ordinaryHeaders
+}
+
+// headerBlock is a header block being received, its fragments are sent in a HEADERS frame and the CONTINUATION
+// frames following it until one of them ends the headers.
+type headerBlock struct {
+	streamID  uint32
+	endStream bool
+	fragment  []byte
 }
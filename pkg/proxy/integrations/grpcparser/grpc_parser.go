@@ -16,6 +16,7 @@ import (
 	"go.keploy.io/server/pkg"
 	"go.keploy.io/server/pkg/hooks"
 	"go.keploy.io/server/pkg/models"
+	"go.keploy.io/server/pkg/proxy/integrations/httpparser"
 	"go.keploy.io/server/utils"
 )
 
@@ -24,7 +25,12 @@ type GrpcParser struct {
 	hooks  *hooks.Hook
 }
 
-func NewGrpcParser(logger *zap.Logger, h *hooks.Hook) *GrpcParser {
+// NewGrpcParser returns a parser decoding the protobuf messages through the given FileDescriptorSets. The messages
+// of the services missing from them are stored in the protoscope form.
+func NewGrpcParser(logger *zap.Logger, h *hooks.Hook, descriptorFiles []string) *GrpcParser {
+	if err := LoadProtoDescriptors(descriptorFiles); err != nil {
+		logger.Error("failed to load the protobuf descriptors, the grpc messages would be stored in the protoscope form", zap.Error(err))
+	}
 	return &GrpcParser{
 		logger: logger,
 		hooks:  h,
@@ -76,7 +82,7 @@ func encodeOutgoingGRPC(requestBuffer []byte, clientConn, destConn net.Conn, h *
 		defer h.Recover(pkg.GenerateRandomID())
 		defer utils.HandlePanic()
 		defer wg.Done()
-		err := TransferFrame(destConn, clientConn, streamInfoCollection, isReqFromClient, serverSideDecoder, ctx)
+		err := TransferFrame(destConn, clientConn, streamInfoCollection, isReqFromClient, serverSideDecoder, logger, ctx)
 		if err != nil {
 			// check for EOF error
 			if err == io.EOF {
@@ -95,7 +101,7 @@ func encodeOutgoingGRPC(requestBuffer []byte, clientConn, destConn net.Conn, h *
 		defer h.Recover(pkg.GenerateRandomID())
 		defer utils.HandlePanic()
 		defer wg.Done()
-		err := TransferFrame(clientConn, destConn, streamInfoCollection, !isReqFromClient, clientSideDecoder, ctx)
+		err := TransferFrame(clientConn, destConn, streamInfoCollection, !isReqFromClient, clientSideDecoder, logger, ctx)
 		if err != nil {
 			logger.Error("failed to transfer frame from server to client", zap.Error(err))
 		}
@@ -107,10 +113,57 @@ func encodeOutgoingGRPC(requestBuffer []byte, clientConn, destConn net.Conn, h *
 	wg.Wait()
 }
 
-// TransferFrame reads one frame from rhs and writes it to lhs.
-func TransferFrame(lhs net.Conn, rhs net.Conn, sic *StreamInfoCollection, isReqFromClient bool, decoder *hpack.Decoder, ctx context.Context) error {
+// TransferFrame reads one frame from rhs and writes it to lhs. The streams whose requests aren't gRPC calls are
+// recorded as HTTP mocks.
+func TransferFrame(lhs net.Conn, rhs net.Conn, sic *StreamInfoCollection, isReqFromClient bool, decoder *hpack.Decoder, logger *zap.Logger, ctx context.Context) error {
 	isRespFromServer := !isReqFromClient
 	framer := http2.NewFramer(lhs, rhs)
+	var block headerBlock
+
+	// handleHeaders decodes a complete header block, it has to be decoded once and in order to keep the dynamic
+	// table of the decoder in sync with the encoder of the peer.
+	handleHeaders := func() error {
+		hf, err := decoder.DecodeFull(block.fragment)
+		if err != nil {
+			return fmt.Errorf("could not extract headers from frame: %v", err)
+		}
+		streamID := block.streamID
+
+		if sic.HttpStreams.Has(streamID) || (isReqFromClient && !httpparser.IsGrpcRequest(hf)) {
+			if isReqFromClient {
+				sic.HttpStreams.AddRequestHeaders(streamID, hf)
+				return nil
+			}
+			sic.HttpStreams.AddResponseHeaders(streamID, hf)
+			if block.endStream {
+				sic.HttpStreams.Persist(streamID, logger, ctx)
+			}
+			return nil
+		}
+
+		pseudoHeaders, ordinaryHeaders := splitHeaders(hf)
+		if isReqFromClient {
+			sic.AddHeadersForRequest(streamID, pseudoHeaders, true)
+			sic.AddHeadersForRequest(streamID, ordinaryHeaders, false)
+		} else if isRespFromServer {
+			// If this is the last fragment of a stream from the server, it has to be a trailer.
+			isTrailer := false
+			if block.endStream {
+				isTrailer = true
+			}
+			sic.AddHeadersForResponse(streamID, pseudoHeaders, true, isTrailer)
+			sic.AddHeadersForResponse(streamID, ordinaryHeaders, false, isTrailer)
+		}
+
+		// The trailers frame has been received. The stream has been closed by the server.
+		// Capture the mock and clear the map, as the stream ID can be reused by client.
+		if isRespFromServer && block.endStream {
+			sic.PersistMockForStream(streamID, ctx)
+			sic.ResetStream(streamID)
+		}
+		return nil
+	}
+
 	for {
 		frame, err := framer.ReadFrame()
 		if err != nil {
@@ -157,29 +210,16 @@ func TransferFrame(lhs net.Conn, rhs net.Conn, sic *StreamInfoCollection, isReqF
 			if err != nil {
 				return fmt.Errorf("could not write headers frame: %v", err)
 			}
-			pseudoHeaders, ordinaryHeaders, err := ExtractHeaders(headersFrame, decoder)
-			if err != nil {
-				return fmt.Errorf("could not extract headers from frame: %v", err)
+			// The frame is reused by the next read, the fragment is copied.
+			block = headerBlock{
+				streamID:  streamID,
+				endStream: headersFrame.StreamEnded(),
+				fragment:  append([]byte{}, headersFrame.HeaderBlockFragment()...),
 			}
-
-			if isReqFromClient {
-				sic.AddHeadersForRequest(streamID, pseudoHeaders, true)
-				sic.AddHeadersForRequest(streamID, ordinaryHeaders, false)
-			} else if isRespFromServer {
-				// If this is the last fragment of a stream from the server, it has to be a trailer.
-				isTrailer := false
-				if headersFrame.StreamEnded() {
-					isTrailer = true
+			if headersFrame.HeadersEnded() {
+				if err := handleHeaders(); err != nil {
+					return err
 				}
-				sic.AddHeadersForResponse(streamID, pseudoHeaders, true, isTrailer)
-				sic.AddHeadersForResponse(streamID, ordinaryHeaders, false, isTrailer)
-			}
-
-			// The trailers frame has been received. The stream has been closed by the server.
-			// Capture the mock and clear the map, as the stream ID can be reused by client.
-			if isRespFromServer && headersFrame.StreamEnded() {
-				sic.PersistMockForStream(streamID, ctx)
-				sic.ResetStream(streamID)
 			}
 
 		case *http2.DataFrame:
@@ -188,7 +228,16 @@ func TransferFrame(lhs net.Conn, rhs net.Conn, sic *StreamInfoCollection, isReqF
 			if err != nil {
 				return fmt.Errorf("could not write data frame: %v", err)
 			}
-			if isReqFromClient {
+			if sic.HttpStreams.Has(dataFrame.StreamID) {
+				if isReqFromClient {
+					sic.HttpStreams.AddRequestData(dataFrame.StreamID, dataFrame.Data())
+				} else {
+					sic.HttpStreams.AddResponseData(dataFrame.StreamID, dataFrame.Data())
+					if dataFrame.StreamEnded() {
+						sic.HttpStreams.Persist(dataFrame.StreamID, logger, ctx)
+					}
+				}
+			} else if isReqFromClient {
 				// Capturing the request timestamp
 				sic.ReqTimestampMock = time.Now()
 
@@ -218,6 +267,15 @@ func TransferFrame(lhs net.Conn, rhs net.Conn, sic *StreamInfoCollection, isReqF
 			if err != nil {
 				return fmt.Errorf("could not write continuation frame: %v", err)
 			}
+			if continuationFrame.StreamID != block.streamID {
+				return fmt.Errorf("continuation frame of stream %d received during the headers of stream %d", continuationFrame.StreamID, block.streamID)
+			}
+			block.fragment = append(block.fragment, continuationFrame.HeaderBlockFragment()...)
+			if continuationFrame.HeadersEnded() {
+				if err := handleHeaders(); err != nil {
+					return err
+				}
+			}
 		case *http2.PriorityFrame:
 			priorityFrame := frame.(*http2.PriorityFrame)
 			err := framer.WritePriority(priorityFrame.StreamID, priorityFrame.PriorityParam)
@@ -230,6 +288,8 @@ func TransferFrame(lhs net.Conn, rhs net.Conn, sic *StreamInfoCollection, isReqF
 			if err != nil {
 				return fmt.Errorf("could not write reset stream frame: %v", err)
 			}
+			// The request was cancelled, a plain HTTP stream has no response to record.
+			sic.HttpStreams.Reset(rstStreamFrame.StreamID)
 		case *http2.GoAwayFrame:
 			goAwayFrame := frame.(*http2.GoAwayFrame)
 			err := framer.WriteGoAway(goAwayFrame.StreamID, goAwayFrame.ErrCode, goAwayFrame.DebugData())
@@ -1,15 +1,19 @@
 package grpcparser
 
 import (
+	"bytes"
 	"encoding/binary"
 	"fmt"
 
 	"github.com/protocolbuffers/protoscope"
+	"google.golang.org/protobuf/reflect/protoreflect"
 
 	"go.keploy.io/server/pkg/models"
 )
 
-func CreateLengthPrefixedMessageFromPayload(data []byte) models.GrpcLengthPrefixedMessage {
+// CreateLengthPrefixedMessageFromPayload decodes a length prefixed message. The message is stored in the JSON form
+// when its descriptor is known and it isn't compressed, in the protoscope form otherwise.
+func CreateLengthPrefixedMessageFromPayload(data []byte, descriptor protoreflect.MessageDescriptor) models.GrpcLengthPrefixedMessage {
 	msg := models.GrpcLengthPrefixedMessage{}
 
 	// If the body is not length prefixed, we return the default value.
@@ -23,20 +27,37 @@ func CreateLengthPrefixedMessageFromPayload(data []byte) models.GrpcLengthPrefix
 	// The next 4 bytes are message length.
 	msg.MessageLength = binary.BigEndian.Uint32(data[1:5])
 
-	// The payload could be empty. We only parse it if it is present.
-	if len(data) >= 5 {
-		// Use protoscope to decode the message.
-		msg.DecodedData = protoscope.Write(data[5:], protoscope.WriterOptions{})
+	if descriptor != nil && msg.CompressionFlag == 0 {
+		decoded, err := marshalJSON(data[5:], descriptor)
+		if err == nil {
+			msg.MessageType = string(descriptor.FullName())
+			msg.DecodedData = decoded
+			return msg
+		}
 	}
 
+	// Use protoscope to decode the message.
+	msg.DecodedData = protoscope.Write(data[5:], protoscope.WriterOptions{})
+
 	return msg
 }
 
 func CreatePayloadFromLengthPrefixedMessage(msg models.GrpcLengthPrefixedMessage) ([]byte, error) {
-	scanner := protoscope.NewScanner(msg.DecodedData)
-	encodedData, err := scanner.Exec()
-	if err != nil {
-		return nil, fmt.Errorf("could not encode grpc msg using protoscope: %v", err)
+	var (
+		encodedData []byte
+		err         error
+	)
+	if msg.MessageType != "" {
+		encodedData, err = unmarshalJSON(msg.DecodedData, msg.MessageType)
+		if err != nil {
+			return nil, fmt.Errorf("could not encode grpc msg from its JSON form: %v", err)
+		}
+	} else {
+		scanner := protoscope.NewScanner(msg.DecodedData)
+		encodedData, err = scanner.Exec()
+		if err != nil {
+			return nil, fmt.Errorf("could not encode grpc msg using protoscope: %v", err)
+		}
 	}
 
 	// Note that the encoded length is present in the msg, but it is also equal to the len of encodedData.
@@ -50,3 +71,37 @@ func CreatePayloadFromLengthPrefixedMessage(msg models.GrpcLengthPrefixedMessage
 
 	return payload, nil
 }
+
+// splitLengthPrefixedMessages splits the complete length prefixed messages from the data of a stream. The bytes
+// of a message spanning the next DATA frames are returned as the rest.
+func splitLengthPrefixedMessages(data []byte) (messages [][]byte, rest []byte) {
+	for len(data) >= 5 {
+		length := int(binary.BigEndian.Uint32(data[1:5]))
+		if len(data) < 5+length {
+			break
+		}
+		messages = append(messages, data[:5+length])
+		data = data[5+length:]
+	}
+	return messages, data
+}
+
+// sameMessage compares the messages through their decoded data, or through their wire encoding when one of
+// them was stored in a different form.
+func sameMessage(have, want models.GrpcLengthPrefixedMessage) bool {
+	if have.CompressionFlag != want.CompressionFlag {
+		return false
+	}
+	if have.MessageType == want.MessageType {
+		return have.DecodedData == want.DecodedData
+	}
+	havePayload, err := CreatePayloadFromLengthPrefixedMessage(have)
+	if err != nil {
+		return false
+	}
+	wantPayload, err := CreatePayloadFromLengthPrefixedMessage(want)
+	if err != nil {
+		return false
+	}
+	return bytes.Equal(havePayload, wantPayload)
+}
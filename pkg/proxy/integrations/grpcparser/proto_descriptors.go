@@ -0,0 +1,114 @@
+package grpcparser
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+
+	"go.keploy.io/server/pkg/proxy/integrations/protohttp"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+var (
+	protoFilesMutex sync.RWMutex
+	// protoFiles holds the descriptors of the services called by the application, it is nil when no descriptor
+	// set is supplied and the messages are then stored in the protoscope form.
+	protoFiles *protoregistry.Files
+)
+
+// LoadProtoDescriptors registers the FileDescriptorSets at the given paths, as generated by
+// `protoc --descriptor_set_out=<file> --include_imports`.
+func LoadProtoDescriptors(paths []string) error {
+	if len(paths) == 0 {
+		return nil
+	}
+	files, err := protohttp.LoadFiles(paths)
+	if err != nil {
+		return err
+	}
+	protoFilesMutex.Lock()
+	defer protoFilesMutex.Unlock()
+	protoFiles = files
+	return nil
+}
+
+// methodMessages returns the descriptors of the request and response messages of the method called on the
+// given :path, e.g. "/helloworld.Greeter/SayHello".
+func methodMessages(path string) (protoreflect.MessageDescriptor, protoreflect.MessageDescriptor) {
+	protoFilesMutex.RLock()
+	defer protoFilesMutex.RUnlock()
+	if protoFiles == nil {
+		return nil, nil
+	}
+	service, method, ok := strings.Cut(strings.TrimPrefix(path, "/"), "/")
+	if !ok {
+		return nil, nil
+	}
+	descriptor, err := protoFiles.FindDescriptorByName(protoreflect.FullName(service))
+	if err != nil {
+		return nil, nil
+	}
+	serviceDescriptor, ok := descriptor.(protoreflect.ServiceDescriptor)
+	if !ok {
+		return nil, nil
+	}
+	methodDescriptor := serviceDescriptor.Methods().ByName(protoreflect.Name(method))
+	if methodDescriptor == nil {
+		return nil, nil
+	}
+	return methodDescriptor.Input(), methodDescriptor.Output()
+}
+
+func findMessageDescriptor(name string) (protoreflect.MessageDescriptor, error) {
+	protoFilesMutex.RLock()
+	defer protoFilesMutex.RUnlock()
+	if protoFiles == nil {
+		return nil, fmt.Errorf("no descriptor set is loaded to encode the %s message", name)
+	}
+	descriptor, err := protoFiles.FindDescriptorByName(protoreflect.FullName(name))
+	if err != nil {
+		return nil, fmt.Errorf("could not find the %s message in the descriptor sets: %v", name, err)
+	}
+	messageDescriptor, ok := descriptor.(protoreflect.MessageDescriptor)
+	if !ok {
+		return nil, fmt.Errorf("%s is not a protobuf message", name)
+	}
+	return messageDescriptor, nil
+}
+
+// marshalJSON returns the indented JSON form of the wire encoded message. The output of protojson is
+// normalised since its whitespaces are randomised on purpose.
+func marshalJSON(data []byte, descriptor protoreflect.MessageDescriptor) (string, error) {
+	message := dynamicpb.NewMessage(descriptor)
+	if err := proto.Unmarshal(data, message); err != nil {
+		return "", err
+	}
+	encoded, err := protojson.Marshal(message)
+	if err != nil {
+		return "", err
+	}
+	var indented bytes.Buffer
+	if err := json.Indent(&indented, encoded, "", "  "); err != nil {
+		return "", err
+	}
+	return indented.String(), nil
+}
+
+// unmarshalJSON returns the wire encoding of a message stored in the JSON form.
+func unmarshalJSON(data string, name string) ([]byte, error) {
+	descriptor, err := findMessageDescriptor(name)
+	if err != nil {
+		return nil, err
+	}
+	message := dynamicpb.NewMessage(descriptor)
+	if err := protojson.Unmarshal([]byte(data), message); err != nil {
+		return nil, fmt.Errorf("could not decode the JSON form of the %s message: %v", name, err)
+	}
+	return proto.MarshalOptions{Deterministic: true}.Marshal(message)
+}
@@ -58,13 +58,11 @@ func FilterMocksBasedOnGrpcRequest(grpcReq models.GrpcReq, hook *hooks.Hook) (*m
 				continue
 			}
 
-			// Investigate the compression flag.
-			if have.Body.CompressionFlag != grpcReq.Body.CompressionFlag {
+			// Investigate the body and the messages streamed by the client.
+			if !sameMessage(have.Body, grpcReq.Body) {
 				continue
 			}
-
-			// Investigate the body.
-			if have.Body.DecodedData != grpcReq.Body.DecodedData {
+			if !sameStream(have.Stream, grpcReq.Stream) {
 				continue
 			}
 
@@ -86,3 +84,15 @@ func FilterMocksBasedOnGrpcRequest(grpcReq models.GrpcReq, hook *hooks.Hook) (*m
 		return nil, nil
 	}
 }
+
+func sameStream(have, want []models.GrpcLengthPrefixedMessage) bool {
+	if len(have) != len(want) {
+		return false
+	}
+	for i := range have {
+		if !sameMessage(have[i], want[i]) {
+			return false
+		}
+	}
+	return true
+}
@@ -7,26 +7,50 @@ import (
 
 	"go.keploy.io/server/pkg/hooks"
 	"go.keploy.io/server/pkg/models"
+	"go.keploy.io/server/pkg/proxy/integrations/httpparser"
 )
 
 // StreamInfoCollection is a thread-safe data structure to store all communications
 // that happen in a stream for grpc. This includes the headers and data frame for the
 // request and response.
 type StreamInfoCollection struct {
-	hook             *hooks.Hook
-	mutex            sync.Mutex
-	StreamInfo       map[uint32]models.GrpcStream
+	hook       *hooks.Hook
+	mutex      sync.Mutex
+	StreamInfo map[uint32]models.GrpcStream
+	// pending holds the data of the messages spanning several DATA frames
+	pending map[uint32]*pendingMessages
+	// HttpStreams holds the streams of the plain HTTP/2 requests, which are recorded and mocked as HTTP calls
+	HttpStreams      *httpparser.Http2Streams
 	ReqTimestampMock time.Time
 	ResTimestampMock time.Time
 }
 
 func NewStreamInfoCollection(h *hooks.Hook) *StreamInfoCollection {
 	return &StreamInfoCollection{
-		hook:       h,
-		StreamInfo: make(map[uint32]models.GrpcStream),
+		hook:        h,
+		StreamInfo:  make(map[uint32]models.GrpcStream),
+		pending:     make(map[uint32]*pendingMessages),
+		HttpStreams: httpparser.NewHttp2Streams(h, models.HttpConfig{}),
 	}
 }
 
+// pendingMessages is the partial data of a stream along with the number of messages decoded in each direction.
+type pendingMessages struct {
+	request       []byte
+	response      []byte
+	requestCount  int
+	responseCount int
+}
+
+func (sic *StreamInfoCollection) pendingFor(streamID uint32) *pendingMessages {
+	pending, ok := sic.pending[streamID]
+	if !ok {
+		pending = &pendingMessages{}
+		sic.pending[streamID] = pending
+	}
+	return pending
+}
+
 func (sic *StreamInfoCollection) InitialiseStream(streamID uint32) {
 	sic.mutex.Lock()
 	defer sic.mutex.Unlock()
@@ -85,7 +109,21 @@ func (sic *StreamInfoCollection) AddPayloadForRequest(streamID uint32, payload [
 	// We cannot modify non pointer values in nested entries in map.
 	// Create a copy and overwrite it.
 	info := sic.StreamInfo[streamID]
-	info.GrpcReq.Body = CreateLengthPrefixedMessageFromPayload(payload)
+	pending := sic.pendingFor(streamID)
+	messages, rest := splitLengthPrefixedMessages(append(pending.request, payload...))
+	pending.request = rest
+
+	descriptor, _ := methodMessages(info.GrpcReq.Headers.PseudoHeaders[KLabelForPath])
+	for _, message := range messages {
+		msg := CreateLengthPrefixedMessageFromPayload(message, descriptor)
+		// The first message is the body, the next ones are sent by the client streaming RPCs.
+		if pending.requestCount == 0 {
+			info.GrpcReq.Body = msg
+		} else {
+			info.GrpcReq.Stream = append(info.GrpcReq.Stream, msg)
+		}
+		pending.requestCount++
+	}
 	sic.StreamInfo[streamID] = info
 }
 
@@ -99,7 +137,21 @@ func (sic *StreamInfoCollection) AddPayloadForResponse(streamID uint32, payload
 	// We cannot modify non pointer values in nested entries in map.
 	// Create a copy and overwrite it.
 	info := sic.StreamInfo[streamID]
-	info.GrpcResp.Body = CreateLengthPrefixedMessageFromPayload(payload)
+	pending := sic.pendingFor(streamID)
+	messages, rest := splitLengthPrefixedMessages(append(pending.response, payload...))
+	pending.response = rest
+
+	_, descriptor := methodMessages(info.GrpcReq.Headers.PseudoHeaders[KLabelForPath])
+	for _, message := range messages {
+		msg := CreateLengthPrefixedMessageFromPayload(message, descriptor)
+		// The first message is the body, the next ones are sent by the server streaming RPCs.
+		if pending.responseCount == 0 {
+			info.GrpcResp.Body = msg
+		} else {
+			info.GrpcResp.Stream = append(info.GrpcResp.Stream, msg)
+		}
+		pending.responseCount++
+	}
 	sic.StreamInfo[streamID] = info
 }
 
@@ -134,4 +186,6 @@ func (sic *StreamInfoCollection) ResetStream(streamID uint32) {
 	defer sic.mutex.Unlock()
 
 	delete(sic.StreamInfo, streamID)
+	delete(sic.pending, streamID)
+	sic.HttpStreams.Reset(streamID)
 }
@@ -0,0 +1,141 @@
+package httpparser
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.keploy.io/server/pkg"
+	"go.keploy.io/server/pkg/models"
+)
+
+// chunkRead is a read of a chunked response from the server: the length of the response read so far, and the time
+// it was received at.
+type chunkRead struct {
+	end int
+	at  time.Time
+}
+
+// chunkSpan is a chunk of the body of a chunked response: the size of its data, and the offset in the response of
+// the end of the chunk.
+type chunkSpan struct {
+	size int
+	end  int
+}
+
+// parseChunks returns the chunks of the body of the raw chunked response, without the last empty one, and reports
+// whether the response is complete, its trailers included.
+func parseChunks(raw []byte) ([]chunkSpan, bool) {
+	headerEnd := bytes.Index(raw, []byte("\r\n\r\n"))
+	if headerEnd < 0 {
+		return nil, false
+	}
+	var spans []chunkSpan
+	pos := headerEnd + 4
+	for {
+		lineEnd := bytes.Index(raw[pos:], []byte("\r\n"))
+		if lineEnd < 0 {
+			return spans, false
+		}
+		line := string(raw[pos : pos+lineEnd])
+		// the chunk extensions follow the size
+		if i := strings.IndexByte(line, ';'); i >= 0 {
+			line = line[:i]
+		}
+		size, err := strconv.ParseInt(strings.TrimSpace(line), 16, 64)
+		if err != nil || size < 0 {
+			return spans, false
+		}
+		pos += lineEnd + 2
+		if size == 0 {
+			// the trailers end with an empty line
+			rest := raw[pos:]
+			return spans, bytes.HasPrefix(rest, []byte("\r\n")) || bytes.Contains(rest, []byte("\r\n\r\n"))
+		}
+		if int64(len(raw)-pos) < size+2 {
+			return spans, false
+		}
+		pos += int(size) + 2
+		spans = append(spans, chunkSpan{size: int(size), end: pos})
+	}
+}
+
+// recordedChunks returns the chunks of the raw chunked response along with the delays between them, a chunk being
+// received at the read which completed it. The first delay counts from the time the headers were received at.
+func recordedChunks(raw []byte, reads []chunkRead, start time.Time) []models.HttpChunk {
+	spans, _ := parseChunks(raw)
+	var (
+		chunks []models.HttpChunk
+		last   = start
+		r      = 0
+	)
+	for _, span := range spans {
+		for r < len(reads)-1 && reads[r].end < span.end {
+			r++
+		}
+		at := last
+		if r < len(reads) && reads[r].at.After(last) {
+			at = reads[r].at
+		}
+		chunks = append(chunks, models.HttpChunk{Size: span.size, Delay: int64(at.Sub(last))})
+		last = at
+	}
+	return chunks
+}
+
+// writeChunked sends the mocked response chunk by chunk, each one after its delay, followed by its trailers. The
+// chunks are cut from the body as it is sent, the last one taking the bytes left when the body was encoded again to
+// another length.
+func writeChunked(clientConn net.Conn, statusLine string, header http.Header, body []byte, resp *models.HttpResp, config models.StreamTiming) error {
+	var head bytes.Buffer
+	head.WriteString(statusLine)
+	fields := header.Clone()
+	for _, key := range []string{"Content-Length", "Transfer-Encoding", "Trailer"} {
+		delete(fields, key)
+	}
+	// the headers recorded with the header fidelity are sent in their order and case
+	if err := pkg.WriteOrderedHeader(&head, fields, resp.HeaderOrder); err != nil {
+		return err
+	}
+	trailers := make([]string, 0, len(resp.Trailer))
+	for name := range resp.Trailer {
+		trailers = append(trailers, name)
+	}
+	sort.Strings(trailers)
+	if len(trailers) > 0 {
+		fmt.Fprintf(&head, "Trailer: %s\r\n", strings.Join(trailers, ", "))
+	}
+	head.WriteString("Transfer-Encoding: chunked\r\n\r\n")
+	if _, err := clientConn.Write(head.Bytes()); err != nil {
+		return err
+	}
+
+	for i, chunk := range resp.Chunks {
+		time.Sleep(streamDelay(chunk.Delay, config))
+		size := chunk.Size
+		if i == len(resp.Chunks)-1 || size > len(body) {
+			size = len(body)
+		}
+		if size == 0 {
+			continue
+		}
+		if _, err := clientConn.Write([]byte(fmt.Sprintf("%x\r\n%s\r\n", size, body[:size]))); err != nil {
+			return err
+		}
+		body = body[size:]
+	}
+
+	var tail bytes.Buffer
+	tail.WriteString("0\r\n")
+	for _, name := range trailers {
+		fmt.Fprintf(&tail, "%s: %s\r\n", name, resp.Trailer[name])
+	}
+	tail.WriteString("\r\n")
+	_, err := clientConn.Write(tail.Bytes())
+	return err
+}
@@ -0,0 +1,190 @@
+package httpparser
+
+import (
+	"bytes"
+	"encoding/json"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"go.keploy.io/server/pkg/models"
+)
+
+const (
+	// placeholders of the values left out of the matching
+	ignoredValue   = "<ignored>"
+	timestampValue = "<timestamp>"
+)
+
+// scrollFields hold the ids of the scroll and point in time contexts, which the cluster generates for every run.
+var scrollFields = []string{"scroll_id", "_scroll_id"}
+
+// rangeOperators bound the range queries, their values are usually computed from the current time.
+var rangeOperators = map[string]bool{"gt": true, "gte": true, "lt": true, "lte": true, "from": true, "to": true}
+
+// timestampLayouts are the formats of the dates detected in the bodies.
+var timestampLayouts = []string{
+	time.RFC3339Nano,
+	time.RFC3339,
+	"2006-01-02T15:04:05.000Z0700",
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+}
+
+// isElasticsearchRequest reports whether the request is matched in the Elasticsearch mode, the requests to the
+// configured hosts or, when none is configured, the requests to the APIs of the cluster.
+func isElasticsearchRequest(req *http.Request, config models.ElasticsearchConfig) bool {
+	if !config.Enabled {
+		return false
+	}
+	if len(config.Hosts) == 0 {
+		return isElasticsearchPath(req.URL.Path)
+	}
+	host, _, err := net.SplitHostPort(req.Host)
+	if err != nil {
+		host = req.Host
+	}
+	for _, configured := range config.Hosts {
+		if configured == req.Host || configured == host {
+			return true
+		}
+	}
+	return false
+}
+
+// isElasticsearchPath reports whether the path calls an API of the cluster, whose names start with an underscore.
+func isElasticsearchPath(path string) bool {
+	for _, segment := range strings.Split(path, "/") {
+		if strings.HasPrefix(segment, "_") {
+			return true
+		}
+	}
+	return false
+}
+
+// normalizeElasticsearchPath replaces the scroll ids of the paths, as in "/_search/scroll/<scroll id>".
+func normalizeElasticsearchPath(path string) string {
+	segments := strings.Split(path, "/")
+	for i := 1; i < len(segments); i++ {
+		if segments[i-1] == "scroll" && i >= 2 && segments[i-2] == "_search" && segments[i] != "" {
+			segments[i] = ignoredValue
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+type elasticsearchMatcher struct {
+	fuzzyFields map[string]bool
+}
+
+func newElasticsearchMatcher(config models.ElasticsearchConfig) *elasticsearchMatcher {
+	fuzzyFields := map[string]bool{}
+	for _, field := range scrollFields {
+		fuzzyFields[field] = true
+	}
+	for _, field := range config.FuzzyFields {
+		fuzzyFields[field] = true
+	}
+	return &elasticsearchMatcher{fuzzyFields: fuzzyFields}
+}
+
+// match returns the first mock whose normalised body is the normalised body of the request.
+func (e *elasticsearchMatcher) match(mocks []*models.Mock, reqBody []byte, contentType string) (*models.Mock, bool) {
+	want, ok := e.normalizeBody(reqBody, contentType)
+	if !ok {
+		return nil, false
+	}
+	for _, mock := range mocks {
+		have, ok := e.normalizeBody([]byte(mock.Spec.HttpReq.Body), mock.Spec.HttpReq.Header["Content-Type"])
+		if ok && have == want {
+			return mock, true
+		}
+	}
+	return nil, false
+}
+
+// normalizeBody returns the canonical form of a JSON body or of the NDJSON bodies of the bulk and multi search
+// APIs: the keys are sorted, and the scroll ids, the fuzzy fields and the timestamps are replaced by placeholders.
+func (e *elasticsearchMatcher) normalizeBody(body []byte, contentType string) (string, bool) {
+	body = bytes.TrimSpace(body)
+	if len(body) == 0 {
+		return "", true
+	}
+	var lines [][]byte
+	if strings.Contains(contentType, "ndjson") || (bytes.Contains(body, []byte("\n")) && !isJSON(body)) {
+		lines = bytes.Split(body, []byte("\n"))
+	} else {
+		lines = [][]byte{body}
+	}
+
+	normalized := make([]string, 0, len(lines))
+	for _, line := range lines {
+		line = bytes.TrimSpace(line)
+		if len(line) == 0 {
+			continue
+		}
+		var value interface{}
+		decoder := json.NewDecoder(bytes.NewReader(line))
+		decoder.UseNumber()
+		if err := decoder.Decode(&value); err != nil {
+			return "", false
+		}
+		canonical, err := json.Marshal(e.normalizeValue("", value, false))
+		if err != nil {
+			return "", false
+		}
+		normalized = append(normalized, string(canonical))
+	}
+	return strings.Join(normalized, "\n"), true
+}
+
+// normalizeValue replaces the values to leave out of the matching, inRange is set for the bounds of a range query.
+func (e *elasticsearchMatcher) normalizeValue(key string, value interface{}, inRange bool) interface{} {
+	if e.fuzzyFields[key] {
+		return ignoredValue
+	}
+	switch v := value.(type) {
+	case map[string]interface{}:
+		normalized := make(map[string]interface{}, len(v))
+		for k, child := range v {
+			// the id of a point in time is generated like the scroll ids
+			if key == "pit" && k == "id" {
+				normalized[k] = ignoredValue
+				continue
+			}
+			normalized[k] = e.normalizeValue(k, child, key == "range" || inRange)
+		}
+		return normalized
+	case []interface{}:
+		normalized := make([]interface{}, len(v))
+		for i, child := range v {
+			normalized[i] = e.normalizeValue(key, child, inRange)
+		}
+		return normalized
+	case json.Number:
+		// epoch seconds and milliseconds, the shorter numbers bound the other fields
+		if inRange && rangeOperators[key] && len(strings.TrimLeft(v.String(), "-")) >= 10 {
+			return timestampValue
+		}
+	case string:
+		if isTimestamp(v) || (inRange && rangeOperators[key] && strings.HasPrefix(v, "now")) {
+			return timestampValue
+		}
+	}
+	return value
+}
+
+func isTimestamp(value string) bool {
+	// the dates are at least 10 characters long, skip the parsing of the shorter strings
+	if len(value) < 10 || value[0] < '0' || value[0] > '9' {
+		return false
+	}
+	for _, layout := range timestampLayouts {
+		if _, err := time.Parse(layout, value); err == nil {
+			return true
+		}
+	}
+	return false
+}
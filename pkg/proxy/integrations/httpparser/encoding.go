@@ -0,0 +1,180 @@
+package httpparser
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// supportedCodings are the content codings whose bodies are stored decoded in the mocks and encoded again on replay.
+// The other ones, like br which has no codec in the standard library, are kept as they were sent.
+var supportedCodings = map[string]bool{
+	"gzip":    true,
+	"x-gzip":  true,
+	"deflate": true,
+	"zstd":    true,
+}
+
+var errUnsupportedCoding = errors.New("unsupported content coding")
+
+// acceptEncodingHeader matches the Accept-Encoding header of a request, up to the end of its line.
+var acceptEncodingHeader = regexp.MustCompile(`(?im)^accept-encoding:[ \t]*([^\r\n]*)`)
+
+// contentCodings returns the codings listed by a Content-Encoding header in the order they were applied.
+func contentCodings(contentEncoding string) []string {
+	var codings []string
+	for _, coding := range strings.Split(contentEncoding, ",") {
+		coding = strings.ToLower(strings.TrimSpace(coding))
+		if coding != "" && coding != "identity" {
+			codings = append(codings, coding)
+		}
+	}
+	return codings
+}
+
+// decodeBody removes the content codings of a body. It returns errUnsupportedCoding, along with the body as it is,
+// when one of the codings isn't supported.
+func decodeBody(contentEncoding string, body []byte) ([]byte, error) {
+	codings := contentCodings(contentEncoding)
+	if len(body) == 0 || len(codings) == 0 {
+		return body, nil
+	}
+	for _, coding := range codings {
+		if !supportedCodings[coding] {
+			return body, errUnsupportedCoding
+		}
+	}
+	decoded := body
+	for i := len(codings) - 1; i >= 0; i-- {
+		var err error
+		decoded, err = decodeCoding(codings[i], decoded)
+		if err != nil {
+			return body, fmt.Errorf("failed to decode the %s body: %v", codings[i], err)
+		}
+	}
+	return decoded, nil
+}
+
+// encodeBody applies the content codings to a decoded body. The bodies of the unsupported codings were stored as
+// they were sent and are returned as they are.
+func encodeBody(contentEncoding string, body []byte) ([]byte, error) {
+	codings := contentCodings(contentEncoding)
+	if len(body) == 0 {
+		return body, nil
+	}
+	for _, coding := range codings {
+		if !supportedCodings[coding] {
+			return body, nil
+		}
+	}
+	for _, coding := range codings {
+		var err error
+		body, err = encodeCoding(coding, body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode the %s body: %v", coding, err)
+		}
+	}
+	return body, nil
+}
+
+func decodeCoding(coding string, body []byte) ([]byte, error) {
+	var reader io.ReadCloser
+	var err error
+	switch coding {
+	case "gzip", "x-gzip":
+		reader, err = gzip.NewReader(bytes.NewReader(body))
+	case "deflate":
+		// deflate is the zlib format, some servers send the raw deflate stream though
+		reader, err = zlib.NewReader(bytes.NewReader(body))
+		if err != nil {
+			reader, err = flate.NewReader(bytes.NewReader(body)), nil
+		}
+	case "zstd":
+		var decoder *zstd.Decoder
+		decoder, err = zstd.NewReader(bytes.NewReader(body))
+		if err == nil {
+			reader = decoder.IOReadCloser()
+		}
+	default:
+		return nil, errUnsupportedCoding
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	return io.ReadAll(reader)
+}
+
+func encodeCoding(coding string, body []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	var writer io.WriteCloser
+	var err error
+	switch coding {
+	case "gzip", "x-gzip":
+		writer = gzip.NewWriter(&buf)
+	case "deflate":
+		writer = zlib.NewWriter(&buf)
+	case "zstd":
+		writer, err = zstd.NewWriter(&buf)
+	default:
+		return nil, errUnsupportedCoding
+	}
+	if err != nil {
+		return nil, err
+	}
+	if _, err := writer.Write(body); err != nil {
+		return nil, err
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// withSupportedAcceptEncoding removes the unsupported codings from the Accept-Encoding header of a request, so that
+// the server answers with a body which can be stored decoded. The request is returned as it is when it doesn't
+// list any unsupported coding.
+func withSupportedAcceptEncoding(request []byte) []byte {
+	headerEnd := bytes.Index(request, []byte("\r\n\r\n"))
+	if headerEnd < 0 {
+		return request
+	}
+	match := acceptEncodingHeader.FindSubmatchIndex(request[:headerEnd])
+	if match == nil {
+		return request
+	}
+
+	var kept []string
+	removed := false
+	for _, coding := range strings.Split(string(request[match[2]:match[3]]), ",") {
+		name := strings.ToLower(strings.TrimSpace(strings.SplitN(coding, ";", 2)[0]))
+		if name == "" {
+			continue
+		}
+		if !supportedCodings[name] && name != "identity" && name != "*" {
+			removed = true
+			continue
+		}
+		kept = append(kept, strings.TrimSpace(coding))
+	}
+	if !removed {
+		return request
+	}
+	value := strings.Join(kept, ", ")
+	if value == "" {
+		value = "identity"
+	}
+
+	rewritten := make([]byte, 0, len(request))
+	rewritten = append(rewritten, request[:match[2]]...)
+	rewritten = append(rewritten, value...)
+	return append(rewritten, request[match[3]:]...)
+}
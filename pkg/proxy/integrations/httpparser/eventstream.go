@@ -0,0 +1,259 @@
+package httpparser
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.keploy.io/server/pkg"
+	"go.keploy.io/server/pkg/models"
+	"go.keploy.io/server/pkg/proxy/util"
+	"go.uber.org/zap"
+)
+
+// eventStreamContentType is the content type of the Server-Sent Events responses.
+const eventStreamContentType = "text/event-stream"
+
+// isEventStream reports whether the headers are the ones of a stream of Server-Sent Events. The compressed
+// streams are recorded as a single body.
+func isEventStream(header http.Header) bool {
+	return strings.HasPrefix(strings.ToLower(header.Get("Content-Type")), eventStreamContentType) && header.Get("Content-Encoding") == ""
+}
+
+// responseHeader parses the headers of the response starting the buffer, along with the offset of its body. It
+// returns false when the headers aren't complete.
+func responseHeader(resp []byte) (http.Header, int, bool) {
+	end := bytes.Index(resp, []byte("\r\n\r\n"))
+	statusEnd := bytes.Index(resp, []byte("\r\n"))
+	if end < 0 || statusEnd < 0 {
+		return nil, 0, false
+	}
+	header, err := textproto.NewReader(bufio.NewReader(bytes.NewReader(resp[statusEnd+2 : end+4]))).ReadMIMEHeader()
+	if err != nil {
+		return nil, 0, false
+	}
+	return http.Header(header), end + 4, true
+}
+
+// eventStreamParser splits the body of a text/event-stream response into its events while it is received, each
+// event being timed from its arrival.
+type eventStreamParser struct {
+	buffer []byte
+	last   time.Time
+	events []models.ServerSentEvent
+}
+
+func newEventStreamParser(start time.Time) *eventStreamParser {
+	return &eventStreamParser{last: start}
+}
+
+// feed parses the events completed by the data of the body received at the given time.
+func (p *eventStreamParser) feed(data []byte, now time.Time) {
+	// the lines end with LF or CRLF, a CR ending the buffer is normalised once its LF is received
+	p.buffer = bytes.ReplaceAll(append(p.buffer, data...), []byte("\r\n"), []byte("\n"))
+	for {
+		end := bytes.Index(p.buffer, []byte("\n\n"))
+		if end < 0 {
+			return
+		}
+		block := p.buffer[:end]
+		p.buffer = p.buffer[end+2:]
+		p.add(block, now)
+	}
+}
+
+// flush returns the events of the stream, along with the last one when the stream ended without the blank line
+// terminating it.
+func (p *eventStreamParser) flush(now time.Time) []models.ServerSentEvent {
+	p.add(p.buffer, now)
+	p.buffer = nil
+	return p.events
+}
+
+func (p *eventStreamParser) add(block []byte, now time.Time) {
+	block = bytes.Trim(block, "\n")
+	if len(block) == 0 {
+		return
+	}
+	event := models.ServerSentEvent{Delay: int64(now.Sub(p.last))}
+	p.last = now
+
+	var data, comments []string
+	for _, line := range strings.Split(string(block), "\n") {
+		if strings.HasPrefix(line, ":") {
+			comments = append(comments, strings.TrimPrefix(line[1:], " "))
+			continue
+		}
+		field, value := line, ""
+		if i := strings.IndexByte(line, ':'); i >= 0 {
+			field, value = line[:i], strings.TrimPrefix(line[i+1:], " ")
+		}
+		switch field {
+		case "data":
+			data = append(data, value)
+		case "event":
+			event.Event = value
+		case "id":
+			event.ID = value
+		case "retry":
+			event.Retry = value
+		}
+	}
+	event.Data = strings.Join(data, "\n")
+	event.Comment = strings.Join(comments, "\n")
+	p.events = append(p.events, event)
+}
+
+// encodeEvent returns the text of an event as it is sent in the stream.
+func encodeEvent(event models.ServerSentEvent) []byte {
+	var buf bytes.Buffer
+	if event.Comment != "" {
+		for _, line := range strings.Split(event.Comment, "\n") {
+			buf.WriteString(": " + line + "\n")
+		}
+	}
+	if event.ID != "" {
+		buf.WriteString("id: " + event.ID + "\n")
+	}
+	if event.Event != "" {
+		buf.WriteString("event: " + event.Event + "\n")
+	}
+	if event.Retry != "" {
+		buf.WriteString("retry: " + event.Retry + "\n")
+	}
+	if event.Data != "" {
+		for _, line := range strings.Split(event.Data, "\n") {
+			buf.WriteString("data: " + line + "\n")
+		}
+	}
+	buf.WriteString("\n")
+	return buf.Bytes()
+}
+
+// dechunk decodes the complete chunks starting the buffer. It returns their data, the bytes left and whether the
+// last chunk was received, the trailers being ignored.
+func dechunk(raw []byte) ([]byte, []byte, bool) {
+	var data []byte
+	for {
+		lineEnd := bytes.Index(raw, []byte("\r\n"))
+		if lineEnd < 0 {
+			return data, raw, false
+		}
+		sizeField := string(raw[:lineEnd])
+		if i := strings.IndexByte(sizeField, ';'); i >= 0 {
+			sizeField = sizeField[:i]
+		}
+		size, err := strconv.ParseInt(strings.TrimSpace(sizeField), 16, 64)
+		if err != nil {
+			// not a chunked body after all, the stream is ended
+			return data, nil, true
+		}
+		if size == 0 {
+			return data, nil, true
+		}
+		if int64(len(raw)) < int64(lineEnd)+2+size+2 {
+			return data, raw, false
+		}
+		start := lineEnd + 2
+		data = append(data, raw[start:start+int(size)]...)
+		raw = raw[start+int(size)+2:]
+	}
+}
+
+// readEventStream reads the rest of a text/event-stream response whose headers and first bytes are in finalResp,
+// forwarding it to the client as it is received, and returns its events.
+func readEventStream(finalResp *[]byte, clientConn, destConn net.Conn, logger *zap.Logger, start time.Time) []models.ServerSentEvent {
+	header, bodyStart, _ := responseHeader(*finalResp)
+	chunked := strings.EqualFold(header.Get("Transfer-Encoding"), "chunked")
+	contentLength := -1
+	if value := header.Get("Content-Length"); value != "" && !chunked {
+		if length, err := strconv.Atoi(value); err == nil {
+			contentLength = length
+		}
+	}
+
+	parser := newEventStreamParser(start)
+	pending := append([]byte{}, (*finalResp)[bodyStart:]...)
+	received := len(pending)
+	process := func(now time.Time) bool {
+		if chunked {
+			data, rest, last := dechunk(pending)
+			pending = rest
+			parser.feed(data, now)
+			return last
+		}
+		parser.feed(pending, now)
+		pending = nil
+		return contentLength >= 0 && received >= contentLength
+	}
+
+	done := process(start)
+	for !done {
+		resp, err := util.ReadBytes(destConn)
+		now := time.Now()
+		if len(resp) > 0 {
+			// write the response message to the user client
+			if _, err := clientConn.Write(resp); err != nil {
+				logger.Error("failed to write response message to the user client", zap.Error(err))
+				break
+			}
+			*finalResp = append(*finalResp, resp...)
+			pending = append(pending, resp...)
+			received += len(resp)
+			done = process(now)
+		}
+		if err != nil {
+			if err != io.EOF {
+				logger.Debug("failed to read the event stream from the destination server", zap.Error(err))
+			}
+			break
+		}
+	}
+	return parser.flush(time.Now())
+}
+
+// writeEventStream streams the events of the mocked response in chunks, each one after its delay.
+func writeEventStream(clientConn net.Conn, resp *models.HttpResp, config models.StreamTiming) error {
+	var head bytes.Buffer
+	fmt.Fprintf(&head, "HTTP/1.1 %d %s\r\n", resp.StatusCode, http.StatusText(resp.StatusCode))
+	for key, values := range pkg.ToHttpHeader(resp.Header) {
+		if key == "Content-Length" || key == "Transfer-Encoding" {
+			continue
+		}
+		for _, value := range values {
+			fmt.Fprintf(&head, "%s: %s\r\n", key, value)
+		}
+	}
+	head.WriteString("Transfer-Encoding: chunked\r\n\r\n")
+	if _, err := clientConn.Write(head.Bytes()); err != nil {
+		return err
+	}
+
+	for _, event := range resp.Events {
+		time.Sleep(streamDelay(event.Delay, config))
+		data := encodeEvent(event)
+		if _, err := clientConn.Write([]byte(fmt.Sprintf("%x\r\n%s\r\n", len(data), data))); err != nil {
+			return err
+		}
+	}
+	_, err := clientConn.Write([]byte("0\r\n\r\n"))
+	return err
+}
+
+// streamDelay returns the time to wait before sending a recorded message of a stream.
+func streamDelay(delay int64, config models.StreamTiming) time.Duration {
+	if config.Timing == "immediate" || delay <= 0 {
+		return 0
+	}
+	if config.Speed > 0 {
+		return time.Duration(float64(delay) / config.Speed)
+	}
+	return time.Duration(delay)
+}
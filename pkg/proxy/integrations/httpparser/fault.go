@@ -0,0 +1,34 @@
+package httpparser
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+
+	"go.keploy.io/server/pkg/models"
+)
+
+// writeFault replays the failure of the fault instead of the response of the mock. It returns whether the connection
+// can still be used, the reset and the truncated ones being closed.
+func writeFault(clientConn net.Conn, fault *models.Fault, response string, bodyLength int) (bool, error) {
+	switch fault.Type {
+	case models.FaultReset:
+		if tcpConn, ok := clientConn.(*net.TCPConn); ok {
+			// closing with a zero linger sends a RST instead of a FIN
+			tcpConn.SetLinger(0)
+		}
+		return false, clientConn.Close()
+	case models.FaultTruncate:
+		// the headers announce the whole body, only the first half of which is sent
+		_, err := clientConn.Write([]byte(response[:len(response)-bodyLength+bodyLength/2]))
+		if err != nil {
+			return false, err
+		}
+		return false, clientConn.Close()
+	default:
+		body := fmt.Sprintf("keploy: injected fault, status %d", fault.Status)
+		status := fmt.Sprintf("HTTP/1.1 %d %s\r\nContent-Type: text/plain\r\nContent-Length: %d\r\n\r\n%s", fault.Status, http.StatusText(fault.Status), len(body), body)
+		_, err := clientConn.Write([]byte(status))
+		return err == nil, err
+	}
+}
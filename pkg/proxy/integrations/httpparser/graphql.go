@@ -0,0 +1,45 @@
+package httpparser
+
+import (
+	"net/http"
+	"net/url"
+	"reflect"
+
+	"go.keploy.io/server/pkg/models"
+	"go.keploy.io/server/pkg/proxy/integrations/graphql"
+	"go.keploy.io/server/pkg/proxy/integrations/matcher"
+)
+
+// graphQLMetadata is the key of the metadata of the mocks holding the GraphQL operation of their request.
+const graphQLMetadata = "graphql"
+
+// graphQLRequest returns the GraphQL request sent in the query of a GET or in the body of the other methods.
+func graphQLRequest(method, rawURL string, body []byte) (*graphql.Request, bool) {
+	if method == http.MethodGet {
+		parsedURL, err := url.Parse(rawURL)
+		if err != nil {
+			return nil, false
+		}
+		return graphql.ParseQuery(parsedURL.Query())
+	}
+	return graphql.Parse(body)
+}
+
+// matchGraphQL returns the mock of the same GraphQL operation as the request: the same operation of a document with
+// the same tokens, whatever their formatting, called with the same variables. The variables ignored by the rule,
+// like body.variables.id, aren't compared.
+func matchGraphQL(mocks []*models.Mock, req *graphql.Request, rule matcher.Rule) (*models.Mock, bool) {
+	hash, operation := req.Hash(), req.Operation()
+	variables := rule.StripDocument(req.Variables, "body.variables.")
+	for _, mock := range mocks {
+		mockReq, ok := graphQLRequest(string(mock.Spec.HttpReq.Method), mock.Spec.HttpReq.URL, []byte(mock.Spec.HttpReq.Body))
+		if !ok || mockReq.Hash() != hash || mockReq.Operation() != operation {
+			continue
+		}
+		mockVariables := rule.StripDocument(mockReq.Variables, "body.variables.")
+		if (len(mockVariables) == 0 && len(variables) == 0) || reflect.DeepEqual(mockVariables, variables) {
+			return mock, true
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,418 @@
+package httpparser
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.keploy.io/server/pkg"
+	"go.keploy.io/server/pkg/hooks"
+	"go.keploy.io/server/pkg/models"
+	"go.uber.org/zap"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/hpack"
+)
+
+// grpcContentType starts the content type of the gRPC calls, the other HTTP/2 requests are recorded as HTTP mocks.
+const grpcContentType = "application/grpc"
+
+// http2MaxFrameSize is the default SETTINGS_MAX_FRAME_SIZE, which every HTTP/2 endpoint accepts.
+const http2MaxFrameSize = 16384
+
+// connectionHeaders are the HTTP/1 headers which are forbidden in HTTP/2 (RFC 9113, section 8.2.2).
+var connectionHeaders = map[string]bool{
+	"connection":        true,
+	"keep-alive":        true,
+	"proxy-connection":  true,
+	"transfer-encoding": true,
+	"upgrade":           true,
+}
+
+// Http2Streams holds the plain HTTP/2 streams of a connection, reassembled from their HEADERS, CONTINUATION and
+// DATA frames. The HTTP/2 connections are handled by the gRPC parser, as they start with the same preface, which
+// hands over the streams whose requests aren't gRPC calls.
+type Http2Streams struct {
+	hook    *hooks.Hook
+	config  models.HttpConfig
+	mutex   sync.Mutex
+	streams map[uint32]*http2Stream
+}
+
+// http2Stream is a request and its response exchanged on a stream.
+type http2Stream struct {
+	request          []hpack.HeaderField
+	requestBody      []byte
+	response         []hpack.HeaderField
+	responseBody     []byte
+	reqTimestampMock time.Time
+	resTimestampMock time.Time
+	// events parses the body of a text/event-stream response
+	events *eventStreamParser
+}
+
+func NewHttp2Streams(h *hooks.Hook, config models.HttpConfig) *Http2Streams {
+	return &Http2Streams{
+		hook:    h,
+		config:  config,
+		streams: make(map[uint32]*http2Stream),
+	}
+}
+
+// IsGrpcRequest reports whether the decoded headers of a request are the ones of a gRPC call.
+func IsGrpcRequest(fields []hpack.HeaderField) bool {
+	for _, field := range fields {
+		if field.Name == "content-type" && strings.HasPrefix(field.Value, grpcContentType) {
+			return true
+		}
+	}
+	return false
+}
+
+// Has reports whether the stream carries a plain HTTP request.
+func (s *Http2Streams) Has(streamID uint32) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	_, ok := s.streams[streamID]
+	return ok
+}
+
+// AddRequestHeaders starts the stream with the headers of its request. The trailers of the request are ignored.
+func (s *Http2Streams) AddRequestHeaders(streamID uint32, fields []hpack.HeaderField) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if _, ok := s.streams[streamID]; ok {
+		return
+	}
+	s.streams[streamID] = &http2Stream{
+		request:          fields,
+		reqTimestampMock: time.Now(),
+	}
+}
+
+func (s *Http2Streams) AddRequestData(streamID uint32, data []byte) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if stream, ok := s.streams[streamID]; ok {
+		stream.requestBody = append(stream.requestBody, data...)
+	}
+}
+
+// AddResponseHeaders adds the headers of the response to the stream. The informational (1xx) responses and the
+// trailers are skipped.
+func (s *Http2Streams) AddResponseHeaders(streamID uint32, fields []hpack.HeaderField) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	stream, ok := s.streams[streamID]
+	if !ok || stream.response != nil {
+		return
+	}
+	for _, field := range fields {
+		if field.Name == ":status" && strings.HasPrefix(field.Value, "1") {
+			return
+		}
+	}
+	stream.response = fields
+	stream.resTimestampMock = time.Now()
+
+	header := http.Header{}
+	for _, field := range fields {
+		if !field.IsPseudo() {
+			header.Add(field.Name, field.Value)
+		}
+	}
+	if isEventStream(header) {
+		stream.events = newEventStreamParser(stream.resTimestampMock)
+	}
+}
+
+func (s *Http2Streams) AddResponseData(streamID uint32, data []byte) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	stream, ok := s.streams[streamID]
+	if !ok {
+		return
+	}
+	if stream.events != nil {
+		stream.events.feed(data, time.Now())
+		return
+	}
+	stream.responseBody = append(stream.responseBody, data...)
+}
+
+func (s *Http2Streams) Reset(streamID uint32) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	delete(s.streams, streamID)
+}
+
+// take removes the stream once its request or its response is complete, the stream ID being reusable.
+func (s *Http2Streams) take(streamID uint32) (*http2Stream, bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	stream, ok := s.streams[streamID]
+	delete(s.streams, streamID)
+	return stream, ok
+}
+
+// Persist records the request and the response of a completed stream as an HTTP mock.
+func (s *Http2Streams) Persist(streamID uint32, logger *zap.Logger, ctx context.Context) {
+	stream, ok := s.take(streamID)
+	if !ok {
+		return
+	}
+	req, err := stream.httpRequest()
+	if err != nil {
+		logger.Error("failed to parse the http2 request", zap.Error(err), zap.Any("stream_id", streamID))
+		return
+	}
+	if isPassThroughHost(req.Host) {
+		return
+	}
+
+	statusCode, header := 0, http.Header{}
+	for _, field := range stream.response {
+		if field.Name == ":status" {
+			statusCode, err = strconv.Atoi(field.Value)
+			if err != nil {
+				logger.Error("failed to parse the status of the http2 response", zap.Error(err), zap.Any("stream_id", streamID))
+				return
+			}
+		} else if !field.IsPseudo() {
+			header.Add(field.Name, field.Value)
+		}
+	}
+	respBody := stream.responseBody
+	var events []models.ServerSentEvent
+	if stream.events != nil {
+		events = stream.events.flush(time.Now())
+	} else if respBody, err = decodeBody(header.Get("Content-Encoding"), respBody); err != nil && err != errUnsupportedCoding {
+		logger.Debug("failed to decode the http2 response body, storing it as it was sent", zap.Error(err), zap.Any("stream_id", streamID))
+	}
+	reqBody, err := decodeBody(req.Header.Get("Content-Encoding"), stream.requestBody)
+	if err != nil && err != errUnsupportedCoding {
+		logger.Debug("failed to decode the http2 request body, storing it as it was sent", zap.Error(err), zap.Any("stream_id", streamID))
+	}
+
+	meta := map[string]string{
+		"name":      "Http",
+		"type":      models.HttpClient,
+		"operation": req.Method,
+	}
+	s.hook.AppendMocks(&models.Mock{
+		Version: models.GetVersion(),
+		Name:    "mocks",
+		Kind:    models.HTTP,
+		Spec: models.MockSpec{
+			Metadata: meta,
+			HttpReq: &models.HttpReq{
+				Method:     models.Method(req.Method),
+				ProtoMajor: req.ProtoMajor,
+				ProtoMinor: req.ProtoMinor,
+				URL:        req.URL.String(),
+				Header:     pkg.ToYamlHttpHeader(req.Header),
+				Body:       string(reqBody),
+				URLParams:  pkg.UrlParams(req),
+				Host:       req.Host,
+			},
+			HttpResp: &models.HttpResp{
+				StatusCode: statusCode,
+				Header:     pkg.ToYamlHttpHeader(header),
+				Body:       string(respBody),
+				Events:     events,
+				ProtoMajor: req.ProtoMajor,
+				ProtoMinor: req.ProtoMinor,
+			},
+			Created:          time.Now().Unix(),
+			ReqTimestampMock: stream.reqTimestampMock,
+			ResTimestampMock: stream.resTimestampMock,
+		},
+	}, ctx)
+}
+
+// Respond writes the mocked response of the completed request of the stream. The stream is reset when no mock
+// matches, as a single stream of the connection can't be passed through to the server.
+func (s *Http2Streams) Respond(streamID uint32, framer *http2.Framer, logger *zap.Logger) error {
+	stream, ok := s.take(streamID)
+	if !ok {
+		return nil
+	}
+	req, err := stream.httpRequest()
+	if err != nil {
+		logger.Error("failed to parse the http2 request", zap.Error(err), zap.Any("stream_id", streamID))
+		return framer.WriteRSTStream(streamID, http2.ErrCodeProtocol)
+	}
+
+	reqBody, err := decodeBody(req.Header.Get("Content-Encoding"), stream.requestBody)
+	if err != nil && err != errUnsupportedCoding {
+		logger.Debug("failed to decode the http2 request body", zap.Error(err), zap.Any("stream_id", streamID))
+	}
+	isMatched, stub, err := match(req, reqBody, req.URL, isJSON(reqBody), s.hook, logger, nil, nil, stream.requestBody, s.hook.Recover, s.config)
+	if err != nil {
+		logger.Error("error while matching http mocks", zap.Error(err))
+	}
+	if !isMatched {
+		if !isPassThroughHost(req.Host) {
+			logger.Error("Didn't match any prexisting http mock", zap.Any("stream_id", streamID), zap.String("path", req.URL.Path))
+		}
+		return framer.WriteRSTStream(streamID, http2.ErrCodeInternal)
+	}
+	return writeHttp2Response(framer, streamID, stub.Spec.HttpResp, s.config.EventStream)
+}
+
+// httpRequest builds the request from the pseudo headers and the headers of the stream.
+func (stream *http2Stream) httpRequest() (*http.Request, error) {
+	var method, authority, path string
+	header := http.Header{}
+	for _, field := range stream.request {
+		switch field.Name {
+		case ":method":
+			method = field.Value
+		case ":authority":
+			authority = field.Value
+		case ":path":
+			path = field.Value
+		default:
+			if !field.IsPseudo() {
+				header.Add(field.Name, field.Value)
+			}
+		}
+	}
+	if method == "" || path == "" {
+		return nil, fmt.Errorf("the request has no :method or :path pseudo header")
+	}
+	reqURL, err := url.ParseRequestURI(path)
+	if err != nil {
+		return nil, fmt.Errorf("invalid :path %q: %v", path, err)
+	}
+	if authority == "" {
+		authority = header.Get("Host")
+	}
+	return &http.Request{
+		Method:        method,
+		URL:           reqURL,
+		Proto:         "HTTP/2.0",
+		ProtoMajor:    2,
+		ProtoMinor:    0,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(stream.requestBody)),
+		ContentLength: int64(len(stream.requestBody)),
+		Host:          authority,
+	}, nil
+}
+
+// writeHttp2Response writes the response of a mock on the stream, its headers being split into CONTINUATION
+// frames and its body into DATA frames of the default maximum frame size. The events of a stream are sent in
+// DATA frames after their recorded delay, the other streams of the connection waiting meanwhile.
+func writeHttp2Response(framer *http2.Framer, streamID uint32, resp *models.HttpResp, timing models.StreamTiming) error {
+	body := []byte(resp.Body)
+	streamed := len(resp.Events) > 0
+	header := pkg.ToHttpHeader(resp.Header)
+	body, err := encodeBody(header.Get("Content-Encoding"), body)
+	if err != nil {
+		return err
+	}
+
+	buf := new(bytes.Buffer)
+	encoder := hpack.NewEncoder(buf)
+	// The pseudo headers should be written before ordinary ones.
+	fields := []hpack.HeaderField{{Name: ":status", Value: strconv.Itoa(resp.StatusCode)}}
+	for key, values := range header {
+		name := strings.ToLower(key)
+		if connectionHeaders[name] {
+			continue
+		}
+		if name == "content-length" {
+			if streamed {
+				continue
+			}
+			values = []string{strconv.Itoa(len(body))}
+		}
+		for _, value := range values {
+			fields = append(fields, hpack.HeaderField{Name: name, Value: strings.TrimSpace(value)})
+		}
+	}
+	for _, field := range fields {
+		if err := encoder.WriteField(field); err != nil {
+			return fmt.Errorf("could not encode the header %q: %v", field.Name, err)
+		}
+	}
+
+	block := buf.Bytes()
+	first := block
+	if len(first) > http2MaxFrameSize {
+		first = block[:http2MaxFrameSize]
+	}
+	block = block[len(first):]
+	err = framer.WriteHeaders(http2.HeadersFrameParam{
+		StreamID:      streamID,
+		BlockFragment: first,
+		EndStream:     len(body) == 0 && !streamed,
+		EndHeaders:    len(block) == 0,
+	})
+	if err != nil {
+		return fmt.Errorf("could not write the headers frame: %v", err)
+	}
+	for len(block) > 0 {
+		fragment := block
+		if len(fragment) > http2MaxFrameSize {
+			fragment = block[:http2MaxFrameSize]
+		}
+		block = block[len(fragment):]
+		if err := framer.WriteContinuation(streamID, len(block) == 0, fragment); err != nil {
+			return fmt.Errorf("could not write the continuation frame: %v", err)
+		}
+	}
+
+	if streamed {
+		for _, event := range resp.Events {
+			time.Sleep(streamDelay(event.Delay, timing))
+			if err := writeHttp2Data(framer, streamID, encodeEvent(event), false); err != nil {
+				return err
+			}
+		}
+		return writeHttp2Data(framer, streamID, nil, true)
+	}
+	// the headers ended the stream of an empty body
+	if len(body) == 0 {
+		return nil
+	}
+	return writeHttp2Data(framer, streamID, body, true)
+}
+
+// writeHttp2Data writes the data in DATA frames of the default maximum frame size, the last one ending the stream
+// when endStream is set.
+func writeHttp2Data(framer *http2.Framer, streamID uint32, body []byte, endStream bool) error {
+	if len(body) == 0 && endStream {
+		if err := framer.WriteData(streamID, true, nil); err != nil {
+			return fmt.Errorf("could not write the data frame: %v", err)
+		}
+		return nil
+	}
+	for len(body) > 0 {
+		chunk := body
+		if len(chunk) > http2MaxFrameSize {
+			chunk = body[:http2MaxFrameSize]
+		}
+		body = body[len(chunk):]
+		if err := framer.WriteData(streamID, endStream && len(body) == 0, chunk); err != nil {
+			return fmt.Errorf("could not write the data frame: %v", err)
+		}
+	}
+	return nil
+}
+
+func isPassThroughHost(host string) bool {
+	for _, passThroughHost := range models.PassThroughHosts {
+		if host == passThroughHost {
+			return true
+		}
+	}
+	return false
+}
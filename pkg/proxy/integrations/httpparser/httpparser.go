@@ -3,7 +3,6 @@ package httpparser
 import (
 	"bufio"
 	"bytes"
-	"compress/gzip"
 	"context"
 	"encoding/json"
 	"fmt"
@@ -16,10 +15,11 @@ import (
 	"strings"
 	"time"
 
-	"github.com/cloudflare/cfssl/log"
 	"go.keploy.io/server/pkg"
 	"go.keploy.io/server/pkg/hooks"
+	"go.keploy.io/server/pkg/metrics"
 	"go.keploy.io/server/pkg/models"
+	"go.keploy.io/server/pkg/proxy/integrations/protohttp"
 	"go.keploy.io/server/pkg/proxy/util"
 	"go.uber.org/zap"
 )
@@ -27,6 +27,7 @@ import (
 type HttpParser struct {
 	logger *zap.Logger
 	hooks  *hooks.Hook
+	config models.HttpConfig
 }
 
 // ProcessOutgoing implements proxy.DepInterface.
@@ -40,17 +41,18 @@ func (http *HttpParser) ProcessOutgoing(request []byte, clientConn, destConn net
 		}
 
 	case models.MODE_TEST:
-		decodeOutgoingHttp(request, clientConn, destConn, http.hooks, http.logger)
+		decodeOutgoingHttp(request, clientConn, destConn, http.hooks, http.logger, http.config)
 	default:
 		http.logger.Info("Invalid mode detected while intercepting outgoing http call", zap.Any("mode", models.GetMode()))
 	}
 
 }
 
-func NewHttpParser(logger *zap.Logger, h *hooks.Hook) *HttpParser {
+func NewHttpParser(logger *zap.Logger, h *hooks.Hook, config models.HttpConfig) *HttpParser {
 	return &HttpParser{
 		logger: logger,
 		hooks:  h,
+		config: config,
 	}
 }
 
@@ -102,7 +104,7 @@ func ProcessOutgoingHttp(request []byte, clientConn, destConn net.Conn, h *hooks
 		}
 
 	case models.MODE_TEST:
-		decodeOutgoingHttp(request, clientConn, destConn, h, logger)
+		decodeOutgoingHttp(request, clientConn, destConn, h, logger, models.HttpConfig{})
 	default:
 		logger.Info("Invalid mode detected while intercepting outgoing http call", zap.Any("mode", models.GetMode()))
 	}
@@ -197,8 +199,9 @@ func contentLengthResponse(finalResp *[]byte, clientConn, destConn net.Conn, log
 	}
 }
 
-// Handled chunked responses when transfer-encoding is given.
-func chunkedResponse(finalResp *[]byte, clientConn, destConn net.Conn, logger *zap.Logger, transferEncodingHeader string) {
+// Handled chunked responses when transfer-encoding is given. The reads are recorded to tell when each chunk was
+// received.
+func chunkedResponse(finalResp *[]byte, clientConn, destConn net.Conn, logger *zap.Logger, transferEncodingHeader string, reads *[]chunkRead) {
 	if transferEncodingHeader == "chunked" {
 		for {
 			resp, err := util.ReadBytes(destConn)
@@ -212,13 +215,15 @@ func chunkedResponse(finalResp *[]byte, clientConn, destConn net.Conn, logger *z
 				}
 			}
 			*finalResp = append(*finalResp, resp...)
+			*reads = append(*reads, chunkRead{end: len(*finalResp), at: time.Now()})
 			// write the response message to the user client
 			_, err = clientConn.Write(resp)
 			if err != nil {
 				logger.Error("failed to write response message to the user client", zap.Error(err))
 				return
 			}
-			if string(resp) == "0\r\n\r\n" {
+			// the response ends with the last chunk and its trailers, which may come in any read
+			if _, complete := parseChunks(*finalResp); complete {
 				break
 			}
 		}
@@ -260,7 +265,7 @@ func handleChunkedRequests(finalReq *[]byte, clientConn, destConn net.Conn, logg
 	}
 }
 
-func handleChunkedResponses(finalResp *[]byte, clientConn, destConn net.Conn, logger *zap.Logger, resp []byte) {
+func handleChunkedResponses(finalResp *[]byte, clientConn, destConn net.Conn, logger *zap.Logger, resp []byte, reads *[]chunkRead) {
 	//Getting the content-length or the transfer-encoding header
 	var contentLengthHeader, transferEncodingHeader string
 	lines := strings.Split(string(resp), "\n")
@@ -287,33 +292,15 @@ func handleChunkedResponses(finalResp *[]byte, clientConn, destConn net.Conn, lo
 		}
 	} else if transferEncodingHeader != "" {
 		//check if the intial response is the complete response.
-		if strings.HasSuffix(string(*finalResp), "0\r\n\r\n") {
+		if _, complete := parseChunks(*finalResp); complete {
 			return
 		}
-		chunkedResponse(finalResp, clientConn, destConn, logger, transferEncodingHeader)
-	}
-}
-
-// Checks if the response is gzipped
-func checkIfGzipped(check io.ReadCloser) (bool, *bufio.Reader) {
-	bufReader := bufio.NewReader(check)
-	peekedBytes, err := bufReader.Peek(2)
-	if err != nil && err != io.EOF {
-		log.Debug("Error peeking:", err)
-		return false, nil
-	}
-	if len(peekedBytes) < 2 {
-		return false, nil
-	}
-	if peekedBytes[0] == 0x1f && peekedBytes[1] == 0x8b {
-		return true, bufReader
-	} else {
-		return false, nil
+		chunkedResponse(finalResp, clientConn, destConn, logger, transferEncodingHeader, reads)
 	}
 }
 
 // Decodes the mocks in test mode so that they can be sent to the user application.
-func decodeOutgoingHttp(requestBuffer []byte, clientConn, destConn net.Conn, h *hooks.Hook, logger *zap.Logger) {
+func decodeOutgoingHttp(requestBuffer []byte, clientConn, destConn net.Conn, h *hooks.Hook, logger *zap.Logger, config models.HttpConfig) {
 	//Matching algorithmm
 	//Get the mocks
 	for {
@@ -344,11 +331,26 @@ func decodeOutgoingHttp(requestBuffer []byte, clientConn, destConn net.Conn, h *
 			return
 		}
 
+		// The WebSocket connections are replayed from the timeline of their messages.
+		if isWebSocketUpgrade(req.Header) {
+			replayWebSocket(req, afterHeaders(requestBuffer), clientConn, h, logger, config.WebSocket)
+			return
+		}
+
 		reqBody, err := ioutil.ReadAll(req.Body)
 		if err != nil {
 			logger.Error("failed to read from request body", zap.Error(err))
 
 		}
+		// the recorded request bodies are decoded
+		reqBody, err = decodeBody(req.Header.Get("Content-Encoding"), reqBody)
+		if err != nil && err != errUnsupportedCoding {
+			logger.Debug("failed to decode the request body", zap.Error(err))
+		}
+		// the protobuf bodies are matched on their JSON form, which the mocks store
+		if decoded, _, ok := protohttp.DecodeBody(reqBody, req.Header.Get("Content-Type"), req.Method, req.URL.Path, false); ok {
+			reqBody = []byte(decoded)
+		}
 
 		//parse request url
 		reqURL, err := url.Parse(req.URL.String())
@@ -359,13 +361,14 @@ func decodeOutgoingHttp(requestBuffer []byte, clientConn, destConn net.Conn, h *
 		//check if req body is a json
 		isReqBodyJSON := isJSON(reqBody)
 
-		isMatched, stub, err := match(req, reqBody, reqURL, isReqBodyJSON, h, logger, clientConn, destConn, requestBuffer, h.Recover)
+		isMatched, stub, err := match(req, reqBody, reqURL, isReqBodyJSON, h, logger, clientConn, destConn, requestBuffer, h.Recover, config)
 
 		if err != nil {
 			logger.Error("error while matching http mocks", zap.Error(err))
 		}
 
 		if !isMatched {
+			metrics.MatchFailures.Inc("http")
 			passthroughHost := false
 			for _, host := range models.PassThroughHosts {
 				if req.Host == host {
@@ -379,6 +382,40 @@ func decodeOutgoingHttp(requestBuffer []byte, clientConn, destConn net.Conn, h *
 			return
 		}
 
+		// The mocks selected by the faults of the chaos mode are replayed as failures.
+		fault := h.Fault(stub)
+		if fault != nil && fault.Type != models.FaultTruncate {
+			logger.Info("replaying the mock as a failure", zap.String("mock", stub.Name), zap.String("fault", string(fault.Type)))
+			open, err := writeFault(clientConn, fault, "", 0)
+			if err != nil {
+				logger.Error("failed to replay the mock as a failure", zap.Error(err))
+			}
+			if !open {
+				return
+			}
+			requestBuffer, err = util.ReadBytes(clientConn)
+			if err != nil {
+				logger.Debug("failed to read the request buffer from the client", zap.Error(err))
+				break
+			}
+			continue
+		}
+
+		// The events of a stream are sent one by one, after their recorded delay.
+		if len(stub.Spec.HttpResp.Events) > 0 {
+			err = writeEventStream(clientConn, stub.Spec.HttpResp, config.EventStream)
+			if err != nil {
+				logger.Error("failed to stream the events of the mock to the user application", zap.Error(err))
+				return
+			}
+			requestBuffer, err = util.ReadBytes(clientConn)
+			if err != nil {
+				logger.Debug("failed to read the request buffer from the client", zap.Error(err))
+				break
+			}
+			continue
+		}
+
 		statusLine := fmt.Sprintf("HTTP/%d.%d %d %s\r\n", stub.Spec.HttpReq.ProtoMajor, stub.Spec.HttpReq.ProtoMinor, stub.Spec.HttpResp.StatusCode, http.StatusText(int(stub.Spec.HttpResp.StatusCode)))
 
 		body := stub.Spec.HttpResp.Body
@@ -388,41 +425,59 @@ func decodeOutgoingHttp(requestBuffer []byte, clientConn, destConn net.Conn, h *
 		// Fetching the response headers
 		header := pkg.ToHttpHeader(stub.Spec.HttpResp.Header)
 
-		//Check if the gzip encoding is present in the header
-		if header["Content-Encoding"] != nil && header["Content-Encoding"][0] == "gzip" {
-			var compressedBuffer bytes.Buffer
-			gw := gzip.NewWriter(&compressedBuffer)
-			_, err := gw.Write([]byte(body))
-			if err != nil {
-				logger.Error("failed to compress the response body", zap.Error(err))
-				return
-			}
-			err = gw.Close()
-			if err != nil {
-				logger.Error("failed to close the gzip writer", zap.Error(err))
-				return
-			}
-			logger.Debug("the length of the response body: " + strconv.Itoa(len(compressedBuffer.String())))
-			respBody = compressedBuffer.String()
-			// responseString = statusLine + headers + "\r\n" + compressedBuffer.String()
-		} else {
-			respBody = body
-			// responseString = statusLine + headers + "\r\n" + body
+		// the protobuf bodies are sent as they were recorded
+		if original, ok, err := protohttp.EncodeBody(body, stub.Spec.HttpResp.Binary, header.Get("Content-Type"), req.Method, req.URL.Path, true); err != nil {
+			logger.Error("failed to encode the protobuf response body", zap.Error(err))
+			return
+		} else if ok {
+			body = string(original)
+		}
+
+		// The body is stored decoded, it is sent with the recorded Content-Encoding.
+		encoded, err := encodeBody(header.Get("Content-Encoding"), []byte(body))
+		if err != nil {
+			logger.Error("failed to encode the response body", zap.Error(err))
+			return
 		}
+		respBody = string(encoded)
+		logger.Debug("the length of the response body: " + strconv.Itoa(len(respBody)))
 		var headers string
-		for key, values := range header {
-			if key == "Content-Length" {
-				values = []string{strconv.Itoa(len(respBody))}
+		if len(stub.Spec.HttpResp.HeaderOrder) > 0 {
+			// the headers are sent in the order and the case they were recorded in
+			ordered := header.Clone()
+			if _, ok := ordered["Content-Length"]; ok {
+				ordered["Content-Length"] = []string{strconv.Itoa(len(respBody))}
 			}
-			for _, value := range values {
-				headerLine := fmt.Sprintf("%s: %s\r\n", key, value)
-				headers += headerLine
+			var lines strings.Builder
+			_ = pkg.WriteOrderedHeader(&lines, ordered, stub.Spec.HttpResp.HeaderOrder)
+			headers = lines.String()
+		} else {
+			for key, values := range header {
+				if key == "Content-Length" {
+					values = []string{strconv.Itoa(len(respBody))}
+				}
+				for _, value := range values {
+					headerLine := fmt.Sprintf("%s: %s\r\n", key, value)
+					headers += headerLine
+				}
 			}
 		}
 		responseString = statusLine + headers + "\r\n" + "" + respBody
 
 		logger.Debug("the content-length header" + headers)
-		_, err = clientConn.Write([]byte(responseString))
+		if fault != nil {
+			logger.Info("replaying the mock as a failure", zap.String("mock", stub.Name), zap.String("fault", string(fault.Type)))
+			if _, err := writeFault(clientConn, fault, responseString, len(respBody)); err != nil {
+				logger.Error("failed to replay the mock as a failure", zap.Error(err))
+			}
+			return
+		}
+		// The chunks of a chunked response are sent one by one, after their recorded delay.
+		if config.Chunked.Enabled && len(stub.Spec.HttpResp.Chunks) > 0 {
+			err = writeChunked(clientConn, statusLine, header, encoded, stub.Spec.HttpResp, config.Chunked.StreamTiming)
+		} else {
+			_, err = clientConn.Write([]byte(responseString))
+		}
 		if err != nil {
 			logger.Error("failed to write the mock output to the user application", zap.Error(err))
 			return
@@ -446,8 +501,8 @@ func encodeOutgoingHttp(request []byte, clientConn, destConn net.Conn, logger *z
 	var finalReq []byte
 	var err error
 	defer destConn.Close()
-	//Writing the request to the server.
-	_, err = destConn.Write(request)
+	//Writing the request to the server, without the codings whose responses can't be stored decoded.
+	_, err = destConn.Write(withSupportedAcceptEncoding(request))
 	if err != nil {
 		logger.Error("failed to write request message to the destination server", zap.Error(err))
 		return err
@@ -523,7 +578,15 @@ func encodeOutgoingHttp(request []byte, clientConn, destConn net.Conn, logger *z
 		}
 		finalResp = append(finalResp, resp...)
 		logger.Debug("This is the initial response: " + string(resp))
-		handleChunkedResponses(&finalResp, clientConn, destConn, logger, resp)
+		// The events of a text/event-stream response are recorded along with the time they were received at.
+		var events []models.ServerSentEvent
+		// The reads of a chunked response are recorded to tell the delays between its chunks.
+		reads := []chunkRead{{end: len(finalResp), at: resTimestampcMock}}
+		if header, _, ok := responseHeader(finalResp); ok && isEventStream(header) {
+			events = readEventStream(&finalResp, clientConn, destConn, logger, resTimestampcMock)
+		} else {
+			handleChunkedResponses(&finalResp, clientConn, destConn, logger, resp, &reads)
+		}
 		logger.Debug("This is the final response: " + string(finalResp))
 
 		var req *http.Request
@@ -542,6 +605,10 @@ func encodeOutgoingHttp(request []byte, clientConn, destConn net.Conn, logger *z
 				logger.Error("failed to read the http request body", zap.Error(err))
 				return err
 			}
+			reqBody, err = decodeBody(req.Header.Get("Content-Encoding"), reqBody)
+			if err != nil && err != errUnsupportedCoding {
+				logger.Debug("failed to decode the http request body, storing it as it was sent", zap.Error(err))
+			}
 		}
 		// converts the response message buffer to http response
 		respParsed, err := http.ReadResponse(bufio.NewReader(bytes.NewReader(finalResp)), req)
@@ -549,40 +616,61 @@ func encodeOutgoingHttp(request []byte, clientConn, destConn net.Conn, logger *z
 			logger.Error("failed to parse the http response message", zap.Error(err))
 			return err
 		}
+		// The server switched to the WebSocket protocol, the connection now carries its frames.
+		if respParsed.StatusCode == http.StatusSwitchingProtocols && isWebSocketUpgrade(req.Header) {
+			recordWebSocket(req, respParsed, afterHeaders(finalReq), afterHeaders(finalResp), clientConn, destConn, h, logger, reqTimestampMock, ctx)
+			return nil
+		}
 		//Add the content length to the headers.
 		var respBody []byte
 		//Checking if the body of the response is empty or does not exist.
 
-		if respParsed.Body != nil { // Read
-			if respParsed.Header.Get("Content-Encoding") == "gzip" {
-				check := respParsed.Body
-				ok, reader := checkIfGzipped(check)
-				logger.Debug("The body is gzip or not" + strconv.FormatBool(ok))
-				logger.Debug("", zap.Any("isGzipped", ok))
-				if ok {
-					gzipReader, err := gzip.NewReader(reader)
-					if err != nil {
-						logger.Error("failed to create a gzip reader", zap.Error(err))
-						return err
-					}
-					respParsed.Body = gzipReader
-				}
-			}
+		if len(events) > 0 {
+			// the events are stored in place of the body, which may end abruptly when the stream is closed
+			respParsed.Header.Del("Content-Length")
+		} else if respParsed.Body != nil { // Read
 			respBody, err = io.ReadAll(respParsed.Body)
 			if err != nil {
 				logger.Error("failed to read the the http response body", zap.Error(err))
 				return err
 			}
+			// the body is stored decoded, it is encoded again with the recorded Content-Encoding on replay
+			respBody, err = decodeBody(respParsed.Header.Get("Content-Encoding"), respBody)
+			if err != nil && err != errUnsupportedCoding {
+				logger.Debug("failed to decode the http response body, storing it as it was sent", zap.Error(err))
+			}
 			logger.Debug("This is the response body: " + string(respBody))
 			//Add the content length to the headers.
 			respParsed.Header.Add("Content-Length", strconv.Itoa(len(respBody)))
 		}
+		// the chunks of a chunked response are recorded along with its trailers, which are read after the body
+		var (
+			chunks  []models.HttpChunk
+			trailer map[string]string
+		)
+		if len(respParsed.TransferEncoding) > 0 && respParsed.TransferEncoding[0] == "chunked" {
+			chunks = recordedChunks(finalResp, reads, resTimestampcMock)
+			if len(respParsed.Trailer) > 0 {
+				trailer = pkg.ToYamlHttpHeader(respParsed.Trailer)
+			}
+		}
 		// store the request and responses as mocks
 		meta := map[string]string{
 			"name":      "Http",
 			"type":      models.HttpClient,
 			"operation": req.Method,
 		}
+		if gql, ok := graphQLRequest(req.Method, req.URL.String(), reqBody); ok {
+			meta[graphQLMetadata] = gql.Operation()
+		}
+		// the protobuf bodies are stored in their JSON form, along with their bytes
+		storedReq, storedResp := &models.HttpReq{Body: string(reqBody)}, &models.HttpResp{Body: string(respBody)}
+		if decoded, original, ok := protohttp.DecodeBody(reqBody, req.Header.Get("Content-Type"), req.Method, req.URL.Path, false); ok {
+			storedReq.Body, storedReq.Binary = decoded, original
+		}
+		if decoded, original, ok := protohttp.DecodeBody(respBody, respParsed.Header.Get("Content-Type"), req.Method, req.URL.Path, true); ok {
+			storedResp.Body, storedResp.Binary = decoded, original
+		}
 		passthroughHost := false
 		for _, host := range models.PassThroughHosts {
 			if req.Host == host {
@@ -590,7 +678,7 @@ func encodeOutgoingHttp(request []byte, clientConn, destConn net.Conn, logger *z
 			}
 		}
 		if !passthroughHost {
-			h.AppendMocks(&models.Mock{
+			mock := &models.Mock{
 				Version: models.GetVersion(),
 				Name:    "mocks",
 				Kind:    models.HTTP,
@@ -602,20 +690,31 @@ func encodeOutgoingHttp(request []byte, clientConn, destConn net.Conn, logger *z
 						ProtoMinor: req.ProtoMinor,
 						URL:        req.URL.String(),
 						Header:     pkg.ToYamlHttpHeader(req.Header),
-						Body:       string(reqBody),
+						Body:       storedReq.Body,
+						Binary:     storedReq.Binary,
 						URLParams:  pkg.UrlParams(req),
 						Host:       req.Host,
 					},
 					HttpResp: &models.HttpResp{
 						StatusCode: respParsed.StatusCode,
 						Header:     pkg.ToYamlHttpHeader(respParsed.Header),
-						Body:       string(respBody),
+						Body:       storedResp.Body,
+						Binary:     storedResp.Binary,
+						Events:     events,
+						Chunks:     chunks,
+						Trailer:    trailer,
 					},
 					Created:          time.Now().Unix(),
 					ReqTimestampMock: reqTimestampMock,
 					ResTimestampMock: resTimestampcMock,
 				},
-			}, ctx)
+			}
+			if headerFidelity, _ := ctx.Value("headerFidelity").(bool); headerFidelity {
+				// the parsed messages lose the order and the case of their headers
+				mock.Spec.HttpReq.HeaderOrder = pkg.HeaderOrder(finalReq)
+				mock.Spec.HttpResp.HeaderOrder = pkg.HeaderOrder(finalResp)
+			}
+			h.AppendMocks(mock, ctx)
 		}
 		finalReq = []byte("")
 		finalResp = []byte("")
@@ -629,7 +728,7 @@ func encodeOutgoingHttp(request []byte, clientConn, destConn net.Conn, logger *z
 			break
 		}
 		// write the request message to the actual destination server
-		_, err = destConn.Write(finalReq)
+		_, err = destConn.Write(withSupportedAcceptEncoding(finalReq))
 		if err != nil {
 			logger.Error("failed to write request message to the destination server", zap.Error(err))
 			return err
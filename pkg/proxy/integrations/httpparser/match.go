@@ -1,76 +1,131 @@
 package httpparser
 
 import (
+	"encoding/json"
 	"fmt"
 	"net"
 	"net/http"
 	"net/url"
+	"reflect"
 	"unicode"
 
 	"github.com/agnivade/levenshtein"
 	"github.com/cloudflare/cfssl/log"
 	"go.keploy.io/server/pkg/hooks"
 	"go.keploy.io/server/pkg/models"
+	"go.keploy.io/server/pkg/proxy/integrations/matcher"
+	"go.keploy.io/server/pkg/proxy/integrations/protohttp"
 	"go.keploy.io/server/pkg/proxy/util"
 	"go.uber.org/zap"
 )
 
-func match(req *http.Request, reqBody []byte, reqURL *url.URL, isReqBodyJSON bool, h *hooks.Hook, logger *zap.Logger, clientConn, destConn net.Conn, requestBuffer []byte, recover func(id int)) (bool, *models.Mock, error) {
+func match(req *http.Request, reqBody []byte, reqURL *url.URL, isReqBodyJSON bool, h *hooks.Hook, logger *zap.Logger, clientConn, destConn net.Conn, requestBuffer []byte, recover func(id int), config models.HttpConfig) (bool, *models.Mock, error) {
+	// the requests to Elasticsearch are matched on their normalised path and body
+	var es *elasticsearchMatcher
+	if isElasticsearchRequest(req, config.Elasticsearch) {
+		es = newElasticsearchMatcher(config.Elasticsearch)
+	}
+	// the headers and the body fields ignored by the matcher of the http mocks aren't compared
+	rule := matcher.For(models.HTTP)
+	// the mocks are looked up by the method and the path of the request, the ones of Elasticsearch being compared on
+	// their normalised path
+	requestKey := hooks.HttpRequestKey(req.Method, reqURL.Path)
+	if es != nil {
+		requestKey = ""
+	}
 	for {
-		tcsMocks, err := h.GetTcsMocks()
-		if err != nil {
-			return false, nil, fmt.Errorf("error while getting tcs mocks %v", err)
-		}
-
 		var eligibleMock []*models.Mock
 
-		for _, mock := range tcsMocks {
-			if mock.Kind == models.HTTP {
-				isMockBodyJSON := isJSON([]byte(mock.Spec.HttpReq.Body))
+		for _, mock := range h.GetTcsMocksFor(models.HTTP, requestKey) {
+			isMockBodyJSON := isJSON([]byte(mock.Spec.HttpReq.Body))
 
-				//the body of mock and request aren't of same type
-				if isMockBodyJSON != isReqBodyJSON {
-					continue
-				}
+			//the body of mock and request aren't of same type
+			if isMockBodyJSON != isReqBodyJSON {
+				continue
+			}
 
-				//parse request body url
-				parsedURL, err := url.Parse(mock.Spec.HttpReq.URL)
-				if err != nil {
-					logger.Error("failed to parse mock url", zap.Error(err))
-					continue
-				}
+			//parse request body url
+			parsedURL, err := url.Parse(mock.Spec.HttpReq.URL)
+			if err != nil {
+				logger.Error("failed to parse mock url", zap.Error(err))
+				continue
+			}
 
-				//Check if the path matches
-				if parsedURL.Path != reqURL.Path {
-					//If it is not the same, continue
+			//Check if the path matches
+			if es != nil {
+				if normalizeElasticsearchPath(parsedURL.Path) != normalizeElasticsearchPath(reqURL.Path) {
 					continue
 				}
+			} else if parsedURL.Path != reqURL.Path {
+				//If it is not the same, continue
+				continue
+			}
 
-				//Check if the method matches
-				if mock.Spec.HttpReq.Method != models.Method(req.Method) {
-					//If it is not the same, continue
-					continue
-				}
+			//Check if the method matches
+			if mock.Spec.HttpReq.Method != models.Method(req.Method) {
+				//If it is not the same, continue
+				continue
+			}
 
-				// Check if the header keys match
-				if !mapsHaveSameKeys(mock.Spec.HttpReq.Header, req.Header) {
-					// Different headers, so not a match
-					continue
-				}
+			// Check if the header keys match
+			if !headersHaveSameKeys(mock.Spec.HttpReq.Header, req.Header, rule) {
+				// Different headers, so not a match
+				continue
+			}
 
-				if !mapsHaveSameKeys(mock.Spec.HttpReq.URLParams, req.URL.Query()) {
-					// Different query params, so not a match
-					continue
-				}
-				eligibleMock = append(eligibleMock, mock)
+			if !mapsHaveSameKeys(mock.Spec.HttpReq.URLParams, req.URL.Query()) {
+				// Different query params, so not a match
+				continue
 			}
+			eligibleMock = append(eligibleMock, mock)
 		}
 
 		if len(eligibleMock) == 0 {
-			return false, nil, nil
+			// fall back to the mock the most similar to the request, reporting why it doesn't match otherwise
+			best := bestScoredMock(h.GetTcsMocksFor(models.HTTP, ""), req, reqBody)
+			if best == nil {
+				return false, nil, nil
+			}
+			threshold := config.FuzzyMatch.Threshold
+			if threshold <= 0 {
+				threshold = defaultMatchThreshold
+			}
+			if !config.FuzzyMatch.Enabled || best.score < threshold {
+				logger.Warn("no http mock matches the request, the closest one differs", zap.Any("method", req.Method), zap.Any("url", reqURL.String()), zap.Any("mock", best.mock.Name), zap.Any("score", best.score), zap.Any("diff", best.diff))
+				return false, nil, nil
+			}
+			isDeleted, err := h.DeleteTcsMock(best.mock)
+			if err != nil {
+				return false, nil, fmt.Errorf("error while deleting tcs mocks: %v", err)
+			}
+			if !isDeleted {
+				continue
+			}
+			logger.Info("matched the http mock most similar to the request", zap.Any("url", reqURL.String()), zap.Any("mock", best.mock.Name), zap.Any("score", best.score), zap.Any("diff", best.diff))
+			return true, best.mock, nil
 		}
 
-		isMatched, bestMatch := Fuzzymatch(eligibleMock, requestBuffer, h)
+		var (
+			isMatched bool
+			bestMatch *models.Mock
+		)
+		if es != nil {
+			bestMatch, isMatched = es.match(eligibleMock, reqBody, req.Header.Get("Content-Type"))
+		}
+		if gql, ok := graphQLRequest(req.Method, reqURL.String(), reqBody); ok && !isMatched {
+			// the GraphQL requests are matched on their operation rather than on the text of their body
+			bestMatch, isMatched = matchGraphQL(eligibleMock, gql, rule)
+		}
+		if !isMatched && protohttp.Message(req.Header.Get("Content-Type"), req.Method, reqURL.Path, false) != "" {
+			// the protobuf requests are compared on their fields, whatever the order they were encoded in
+			bestMatch, isMatched = matchJSONBody(eligibleMock, reqBody, rule)
+		}
+		if !isMatched && isReqBodyJSON {
+			bestMatch, isMatched = matchIgnoringBodyFields(eligibleMock, reqBody, rule)
+		}
+		if !isMatched {
+			isMatched, bestMatch = Fuzzymatch(eligibleMock, requestBuffer, h)
+		}
 		if isMatched {
 			isDeleted, err := h.DeleteTcsMock(bestMatch)
 			if err != nil {
@@ -85,6 +140,56 @@ func match(req *http.Request, reqBody []byte, reqURL *url.URL, isReqBodyJSON boo
 
 }
 
+// headersHaveSameKeys reports whether the mock and the request have the same headers, besides the ones ignored by
+// the rule.
+func headersHaveSameKeys(mockHeader map[string]string, header http.Header, rule matcher.Rule) bool {
+	if len(rule.IgnoredFields("header.")) == 0 {
+		return mapsHaveSameKeys(mockHeader, header)
+	}
+	kept := map[string]string{}
+	for name, value := range mockHeader {
+		if !rule.Ignores("header." + name) {
+			kept[name] = value
+		}
+	}
+	keptHeader := http.Header{}
+	for name, values := range header {
+		if !rule.Ignores("header." + name) {
+			keptHeader[name] = values
+		}
+	}
+	return mapsHaveSameKeys(kept, keptHeader)
+}
+
+// matchIgnoringBodyFields returns the mock whose JSON body equals the one of the request, once the body fields
+// ignored by the rule are removed from both.
+func matchIgnoringBodyFields(mocks []*models.Mock, reqBody []byte, rule matcher.Rule) (*models.Mock, bool) {
+	if len(rule.IgnoredFields("body.")) == 0 {
+		return nil, false
+	}
+	return matchJSONBody(mocks, reqBody, rule)
+}
+
+// matchJSONBody returns the mock whose JSON body equals the one of the request, besides the body fields ignored by
+// the rule.
+func matchJSONBody(mocks []*models.Mock, reqBody []byte, rule matcher.Rule) (*models.Mock, bool) {
+	var body map[string]interface{}
+	if err := json.Unmarshal(reqBody, &body); err != nil {
+		return nil, false
+	}
+	body = rule.StripDocument(body, "body.")
+	for _, mock := range mocks {
+		var mockBody map[string]interface{}
+		if err := json.Unmarshal([]byte(mock.Spec.HttpReq.Body), &mockBody); err != nil {
+			continue
+		}
+		if reflect.DeepEqual(rule.StripDocument(mockBody, "body."), body) {
+			return mock, true
+		}
+	}
+	return nil, false
+}
+
 func findStringMatch(req string, mockString []string) int {
 	minDist := int(^uint(0) >> 1) // Initialize with max int value
 	bestMatch := -1
@@ -175,3 +280,50 @@ func Fuzzymatch(tcsMocks []*models.Mock, reqBuff []byte, h *hooks.Hook) (bool, *
 	}
 	return false, &models.Mock{}
 }
+
+// matchWebSocket finds the mock of the WebSocket connection opened by the handshake, on the same path, preferring
+// the same query and host. It returns nil when no mock matches.
+func matchWebSocket(req *http.Request, h *hooks.Hook) (*models.Mock, error) {
+	for {
+		tcsMocks, err := h.GetTcsMocks()
+		if err != nil {
+			return nil, fmt.Errorf("error while getting tcs mocks %v", err)
+		}
+
+		var (
+			best      *models.Mock
+			bestScore = -1
+		)
+		for _, mock := range tcsMocks {
+			if mock == nil || mock.Kind != models.WebSocket || mock.Spec.HttpReq == nil {
+				continue
+			}
+			parsedURL, err := url.Parse(mock.Spec.HttpReq.URL)
+			if err != nil || parsedURL.Path != req.URL.Path {
+				continue
+			}
+			score := 0
+			if parsedURL.RawQuery == req.URL.RawQuery {
+				score += 2
+			}
+			if mock.Spec.HttpReq.Host == req.Host {
+				score++
+			}
+			if score > bestScore {
+				best, bestScore = mock, score
+			}
+		}
+		if best == nil {
+			return nil, nil
+		}
+
+		isDeleted, err := h.DeleteTcsMock(best)
+		if err != nil {
+			return nil, fmt.Errorf("error while deleting tcs mocks: %v", err)
+		}
+		if !isDeleted {
+			continue
+		}
+		return best, nil
+	}
+}
@@ -0,0 +1,132 @@
+package httpparser
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+
+	"go.keploy.io/server/pkg/models"
+	"go.keploy.io/server/pkg/proxy/util"
+)
+
+// defaultMatchThreshold is the score a mock needs to be matched by the scored fallback when no threshold is set.
+const defaultMatchThreshold = 0.8
+
+// the weights of the similarities of the method, the path and the body in the score of a mock
+const (
+	methodWeight = 0.3
+	pathWeight   = 0.4
+	bodyWeight   = 0.3
+)
+
+// scoredMock is a mock along with its similarity to a request, between 0 and 1, and the differences between them.
+type scoredMock struct {
+	mock  *models.Mock
+	score float64
+	diff  []string
+}
+
+// bestScoredMock returns the mock the most similar to the request, or nil when there are no mocks.
+func bestScoredMock(mocks []*models.Mock, req *http.Request, reqBody []byte) *scoredMock {
+	var best *scoredMock
+	for _, mock := range mocks {
+		scored := scoreMock(mock, req, reqBody)
+		if best == nil || scored.score > best.score {
+			best = scored
+		}
+	}
+	return best
+}
+
+func scoreMock(mock *models.Mock, req *http.Request, reqBody []byte) *scoredMock {
+	scored := &scoredMock{mock: mock}
+
+	methodScore := 0.0
+	if mock.Spec.HttpReq.Method == models.Method(req.Method) {
+		methodScore = 1
+	} else {
+		scored.diff = append(scored.diff, fmt.Sprintf("method: the mock has %s, the request %s", mock.Spec.HttpReq.Method, req.Method))
+	}
+
+	mockURL, err := url.Parse(mock.Spec.HttpReq.URL)
+	if err != nil {
+		mockURL = &url.URL{}
+	}
+	pathScore := pathSimilarity(mockURL.Path, req.URL.Path)
+	if pathScore < 1 {
+		scored.diff = append(scored.diff, fmt.Sprintf("path: the mock has %s, the request %s", mockURL.Path, req.URL.Path))
+	}
+
+	bodyScore := bodySimilarity([]byte(mock.Spec.HttpReq.Body), reqBody)
+	if bodyScore < 1 {
+		scored.diff = append(scored.diff, fmt.Sprintf("body: %.0f%% similar", bodyScore*100))
+	}
+
+	// the headers and the query params are only reported
+	if missing, extra := keysDiff(mock.Spec.HttpReq.Header, req.Header); len(missing)+len(extra) > 0 {
+		scored.diff = append(scored.diff, fmt.Sprintf("headers: missing from the request %v, not in the mock %v", missing, extra))
+	}
+	if missing, extra := keysDiff(mock.Spec.HttpReq.URLParams, req.URL.Query()); len(missing)+len(extra) > 0 {
+		scored.diff = append(scored.diff, fmt.Sprintf("query params: missing from the request %v, not in the mock %v", missing, extra))
+	}
+
+	scored.score = methodWeight*methodScore + pathWeight*pathScore + bodyWeight*bodyScore
+	return scored
+}
+
+// pathSimilarity is the share of the segments of the longest path which are the same in the other one, at the
+// same position, so that the paths differing by an id stay similar.
+func pathSimilarity(a, b string) float64 {
+	if a == b {
+		return 1
+	}
+	segmentsA := strings.Split(strings.Trim(a, "/"), "/")
+	segmentsB := strings.Split(strings.Trim(b, "/"), "/")
+	longest := len(segmentsA)
+	if len(segmentsB) > longest {
+		longest = len(segmentsB)
+	}
+	same := 0
+	for i := 0; i < len(segmentsA) && i < len(segmentsB); i++ {
+		if segmentsA[i] == segmentsB[i] {
+			same++
+		}
+	}
+	return float64(same) / float64(longest)
+}
+
+// bodySimilarity is the Jaccard similarity of the shingles of the bodies.
+func bodySimilarity(a, b []byte) float64 {
+	if string(a) == string(b) {
+		return 1
+	}
+	if len(a) == 0 || len(b) == 0 {
+		return 0
+	}
+	length := len(a)
+	if len(b) < length {
+		length = len(b)
+	}
+	k := util.AdaptiveK(length, 3, 8, 5)
+	return util.JaccardSimilarity(util.CreateShingles(a, k), util.CreateShingles(b, k))
+}
+
+// keysDiff returns the keys of the mock which the request doesn't have, and the ones of the request which the mock
+// doesn't have.
+func keysDiff(mockKeys map[string]string, reqKeys map[string][]string) (missing, extra []string) {
+	for key := range mockKeys {
+		if _, ok := reqKeys[key]; !ok {
+			missing = append(missing, key)
+		}
+	}
+	for key := range reqKeys {
+		if _, ok := mockKeys[key]; !ok {
+			extra = append(extra, key)
+		}
+	}
+	sort.Strings(missing)
+	sort.Strings(extra)
+	return missing, extra
+}
@@ -0,0 +1,468 @@
+package httpparser
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"go.keploy.io/server/pkg"
+	"go.keploy.io/server/pkg/hooks"
+	"go.keploy.io/server/pkg/models"
+	"go.keploy.io/server/utils"
+	"go.uber.org/zap"
+)
+
+// webSocketGUID is appended to the key of the client to compute the accept key of the server (RFC 6455, section 4.2.2).
+const webSocketGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// maxWebSocketPayload bounds the size of the frames, a larger length being a corrupted frame.
+const maxWebSocketPayload = 64 << 20
+
+// The opcodes of the WebSocket frames (RFC 6455, section 5.2).
+const (
+	wsContinuation = 0x0
+	wsText         = 0x1
+	wsBinary       = 0x2
+	wsClose        = 0x8
+	wsPing         = 0x9
+	wsPong         = 0xa
+)
+
+var wsTypes = map[byte]string{
+	wsText:   "text",
+	wsBinary: "binary",
+	wsClose:  "close",
+	wsPing:   "ping",
+	wsPong:   "pong",
+}
+
+var wsOpcodes = map[string]byte{
+	"text":   wsText,
+	"binary": wsBinary,
+	"close":  wsClose,
+	"ping":   wsPing,
+	"pong":   wsPong,
+}
+
+// The senders of the messages of a timeline.
+const (
+	wsClient = "client"
+	wsServer = "server"
+)
+
+// wsFrame is a frame of the WebSocket protocol, its payload being unmasked. rsv1 is set on the messages compressed
+// through the permessage-deflate extension.
+type wsFrame struct {
+	fin     bool
+	rsv1    bool
+	opcode  byte
+	payload []byte
+}
+
+// isWebSocketUpgrade reports whether the request opens a WebSocket connection.
+func isWebSocketUpgrade(header http.Header) bool {
+	if !strings.EqualFold(header.Get("Upgrade"), "websocket") {
+		return false
+	}
+	for _, value := range header.Values("Connection") {
+		for _, token := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(token), "upgrade") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// webSocketAccept returns the accept key answering the key of the client.
+func webSocketAccept(key string) string {
+	sum := sha1.Sum([]byte(key + webSocketGUID))
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// afterHeaders returns the bytes following the headers of an HTTP message, which are the first frames of a
+// WebSocket connection when they are sent along with the handshake.
+func afterHeaders(message []byte) []byte {
+	end := bytes.Index(message, []byte("\r\n\r\n"))
+	if end < 0 {
+		return nil
+	}
+	return message[end+4:]
+}
+
+// readWebSocketFrame reads a frame, and returns it along with its bytes as they were sent.
+func readWebSocketFrame(r *bufio.Reader) (wsFrame, []byte, error) {
+	raw := make([]byte, 2)
+	if _, err := io.ReadFull(r, raw); err != nil {
+		return wsFrame{}, nil, err
+	}
+	frame := wsFrame{
+		fin:    raw[0]&0x80 != 0,
+		rsv1:   raw[0]&0x40 != 0,
+		opcode: raw[0] & 0x0f,
+	}
+	masked := raw[1]&0x80 != 0
+	length := uint64(raw[1] & 0x7f)
+
+	extended := 0
+	switch length {
+	case 126:
+		extended = 2
+	case 127:
+		extended = 8
+	}
+	if masked {
+		extended += 4
+	}
+	rest := make([]byte, extended)
+	if _, err := io.ReadFull(r, rest); err != nil {
+		return wsFrame{}, nil, err
+	}
+	raw = append(raw, rest...)
+	switch length {
+	case 126:
+		length = uint64(binary.BigEndian.Uint16(rest))
+		rest = rest[2:]
+	case 127:
+		length = binary.BigEndian.Uint64(rest)
+		rest = rest[8:]
+	}
+	if length > maxWebSocketPayload {
+		return wsFrame{}, nil, fmt.Errorf("websocket frame of %d bytes exceeds the maximum size", length)
+	}
+
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return wsFrame{}, nil, err
+	}
+	raw = append(raw, payload...)
+	// the frames of the client are masked with the key preceding the payload
+	if masked {
+		for i := range payload {
+			payload[i] ^= rest[i%4]
+		}
+	}
+	frame.payload = payload
+	return frame, raw, nil
+}
+
+// encodeWebSocketFrame encodes an unmasked frame, as the frames are sent by the server.
+func encodeWebSocketFrame(frame wsFrame) []byte {
+	first := frame.opcode
+	if frame.fin {
+		first |= 0x80
+	}
+	if frame.rsv1 {
+		first |= 0x40
+	}
+	buf := []byte{first}
+	switch length := len(frame.payload); {
+	case length < 126:
+		buf = append(buf, byte(length))
+	case length <= 0xffff:
+		buf = append(buf, 126)
+		buf = binary.BigEndian.AppendUint16(buf, uint16(length))
+	default:
+		buf = append(buf, 127)
+		buf = binary.BigEndian.AppendUint64(buf, uint64(length))
+	}
+	return append(buf, frame.payload...)
+}
+
+// wsAssembler joins the fragments of the messages sent in a direction.
+type wsAssembler struct {
+	opcode    byte
+	rsv1      bool
+	fragments []byte
+}
+
+// push returns the complete message once its last fragment is received. The control frames, which can't be
+// fragmented and can be sent between the fragments of a message, are returned as they are.
+func (a *wsAssembler) push(frame wsFrame) (wsFrame, bool) {
+	if frame.opcode >= wsClose {
+		return frame, true
+	}
+	if frame.opcode != wsContinuation {
+		a.opcode, a.rsv1, a.fragments = frame.opcode, frame.rsv1, nil
+	}
+	a.fragments = append(a.fragments, frame.payload...)
+	if !frame.fin {
+		return wsFrame{}, false
+	}
+	return wsFrame{fin: true, rsv1: a.rsv1, opcode: a.opcode, payload: a.fragments}, true
+}
+
+// webSocketTimeline is the ordered list of the messages exchanged on a connection.
+type webSocketTimeline struct {
+	mutex    sync.Mutex
+	messages []models.WebSocketMessage
+	last     time.Time
+}
+
+func (t *webSocketTimeline) add(sender string, frame wsFrame) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	now := time.Now()
+	message := models.WebSocketMessage{
+		Sender:     sender,
+		Type:       wsTypes[frame.opcode],
+		Compressed: frame.rsv1,
+		Delay:      int64(now.Sub(t.last)),
+	}
+	t.last = now
+	payload := frame.payload
+	if frame.opcode == wsClose && len(payload) >= 2 {
+		message.CloseCode = int(binary.BigEndian.Uint16(payload))
+		payload = payload[2:]
+	}
+	if len(payload) > 0 {
+		if frame.opcode != wsBinary && !frame.rsv1 && utf8.Valid(payload) {
+			message.Payload = &models.OutputBinary{Type: models.String, Data: string(payload)}
+		} else {
+			message.Payload = &models.OutputBinary{Type: "binary", Data: base64.StdEncoding.EncodeToString(payload)}
+		}
+	}
+	t.messages = append(t.messages, message)
+}
+
+// webSocketFrame returns the frame sending a recorded message.
+func webSocketFrame(message models.WebSocketMessage) (wsFrame, error) {
+	opcode, ok := wsOpcodes[message.Type]
+	if !ok {
+		return wsFrame{}, fmt.Errorf("unknown websocket message type %q", message.Type)
+	}
+	var payload []byte
+	if message.Payload != nil {
+		if message.Payload.Type == models.String {
+			payload = []byte(message.Payload.Data)
+		} else {
+			data, err := base64.StdEncoding.DecodeString(message.Payload.Data)
+			if err != nil {
+				return wsFrame{}, fmt.Errorf("failed to decode the websocket payload: %v", err)
+			}
+			payload = data
+		}
+	}
+	if opcode == wsClose && message.CloseCode != 0 {
+		payload = append(binary.BigEndian.AppendUint16(nil, uint16(message.CloseCode)), payload...)
+	}
+	return wsFrame{fin: true, rsv1: message.Compressed, opcode: opcode, payload: payload}, nil
+}
+
+// recordWebSocket forwards the frames of the WebSocket connection opened by the handshake, and records the
+// handshake along with the timeline of the messages once the connection is closed. The bytes following the
+// handshakes, which were already forwarded, are the first frames of each side.
+func recordWebSocket(req *http.Request, resp *http.Response, reqRest, respRest []byte, clientConn, destConn net.Conn, h *hooks.Hook, logger *zap.Logger, reqTimestampMock time.Time, ctx context.Context) {
+	// the deadlines set while reading the bodies of the previous requests don't apply to the connection
+	clientConn.SetReadDeadline(time.Time{})
+	destConn.SetReadDeadline(time.Time{})
+
+	timeline := &webSocketTimeline{last: time.Now()}
+	forward := func(src, dst net.Conn, rest []byte, sender string) {
+		// closing both connections unblocks the other direction
+		defer src.Close()
+		defer dst.Close()
+
+		reader := bufio.NewReader(io.MultiReader(bytes.NewReader(rest), src))
+		assembler := &wsAssembler{}
+		skip := len(rest)
+		for {
+			frame, raw, err := readWebSocketFrame(reader)
+			if err != nil {
+				if err != io.EOF && !errors.Is(err, net.ErrClosed) {
+					logger.Debug("failed to read the websocket frame", zap.Error(err), zap.String("sender", sender))
+				}
+				return
+			}
+			if skip < len(raw) {
+				if _, err := dst.Write(raw[skip:]); err != nil {
+					logger.Debug("failed to forward the websocket frame", zap.Error(err), zap.String("sender", sender))
+					return
+				}
+				skip = 0
+			} else {
+				skip -= len(raw)
+			}
+			if message, ok := assembler.push(frame); ok {
+				timeline.add(sender, message)
+			}
+		}
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		// Recover from panic and gracefully shutdown
+		defer h.Recover(pkg.GenerateRandomID())
+		defer utils.HandlePanic()
+		defer wg.Done()
+		forward(clientConn, destConn, reqRest, wsClient)
+	}()
+	go func() {
+		// Recover from panic and gracefully shutdown
+		defer h.Recover(pkg.GenerateRandomID())
+		defer utils.HandlePanic()
+		defer wg.Done()
+		forward(destConn, clientConn, respRest, wsServer)
+	}()
+	wg.Wait()
+
+	if isPassThroughHost(req.Host) {
+		return
+	}
+	meta := map[string]string{
+		"name":      "WebSocket",
+		"type":      models.HttpClient,
+		"operation": req.Method,
+	}
+	h.AppendMocks(&models.Mock{
+		Version: models.GetVersion(),
+		Name:    "mocks",
+		Kind:    models.WebSocket,
+		Spec: models.MockSpec{
+			Metadata: meta,
+			HttpReq: &models.HttpReq{
+				Method:     models.Method(req.Method),
+				ProtoMajor: req.ProtoMajor,
+				ProtoMinor: req.ProtoMinor,
+				URL:        req.URL.String(),
+				Header:     pkg.ToYamlHttpHeader(req.Header),
+				URLParams:  pkg.UrlParams(req),
+				Host:       req.Host,
+			},
+			HttpResp: &models.HttpResp{
+				StatusCode: resp.StatusCode,
+				Header:     pkg.ToYamlHttpHeader(resp.Header),
+				ProtoMajor: resp.ProtoMajor,
+				ProtoMinor: resp.ProtoMinor,
+			},
+			WebSocketMessages: timeline.messages,
+			Created:           time.Now().Unix(),
+			ReqTimestampMock:  reqTimestampMock,
+			ResTimestampMock:  timeline.last,
+		},
+	}, ctx)
+}
+
+// replayWebSocket answers the handshake with the recorded one and replays the timeline of the connection: the
+// messages of the server are sent after their recorded delay (depending on the config), once the messages of the
+// application preceding them are received.
+func replayWebSocket(req *http.Request, reqRest []byte, clientConn net.Conn, h *hooks.Hook, logger *zap.Logger, config models.StreamTiming) {
+	mock, err := matchWebSocket(req, h)
+	if err != nil {
+		logger.Error("error while matching websocket mocks", zap.Error(err))
+		return
+	}
+	if mock == nil {
+		if !isPassThroughHost(req.Host) {
+			logger.Error("Didn't match any prexisting websocket mock", zap.String("path", req.URL.Path))
+		}
+		return
+	}
+
+	// the accept key answers the key sent by the client in this run
+	header := pkg.ToHttpHeader(mock.Spec.HttpResp.Header)
+	header.Set("Sec-WebSocket-Accept", webSocketAccept(req.Header.Get("Sec-WebSocket-Key")))
+	var handshake bytes.Buffer
+	fmt.Fprintf(&handshake, "HTTP/1.1 %d %s\r\n", mock.Spec.HttpResp.StatusCode, http.StatusText(mock.Spec.HttpResp.StatusCode))
+	for key, values := range header {
+		fmt.Fprintf(&handshake, "%s: %s\r\n", key, strings.Join(values, ","))
+	}
+	handshake.WriteString("\r\n")
+	if _, err := clientConn.Write(handshake.Bytes()); err != nil {
+		logger.Error("failed to write the websocket handshake to the user application", zap.Error(err))
+		return
+	}
+
+	// the messages of the application are read in the background, so that its pings get answered while waiting
+	messages := make(chan wsFrame)
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		// Recover from panic and gracefully shutdown
+		defer h.Recover(pkg.GenerateRandomID())
+		defer utils.HandlePanic()
+		defer close(messages)
+		reader := bufio.NewReader(io.MultiReader(bytes.NewReader(reqRest), clientConn))
+		assembler := &wsAssembler{}
+		for {
+			frame, _, err := readWebSocketFrame(reader)
+			if err != nil {
+				return
+			}
+			if message, ok := assembler.push(frame); ok {
+				select {
+				case messages <- message:
+				case <-done:
+					return
+				}
+			}
+		}
+	}()
+
+	closeSent := false
+	write := func(frame wsFrame) bool {
+		if _, err := clientConn.Write(encodeWebSocketFrame(frame)); err != nil {
+			logger.Error("failed to write the websocket message to the user application", zap.Error(err))
+			return false
+		}
+		closeSent = closeSent || frame.opcode == wsClose
+		return true
+	}
+	// receive waits for a message of the application of the given type, or of any type when it is empty. The
+	// pings the timeline isn't waiting for are answered, and a close is echoed. It returns false once the
+	// application has closed the connection.
+	receive := func(messageType string) bool {
+		for message := range messages {
+			if messageType != "" && wsTypes[message.opcode] == messageType {
+				return true
+			}
+			switch message.opcode {
+			case wsPing:
+				if !write(wsFrame{fin: true, opcode: wsPong, payload: message.payload}) {
+					return false
+				}
+			case wsClose:
+				if !closeSent {
+					write(wsFrame{fin: true, opcode: wsClose, payload: message.payload})
+				}
+				return false
+			default:
+				logger.Debug("skipping the websocket message not found in the mock", zap.String("type", wsTypes[message.opcode]))
+			}
+		}
+		return false
+	}
+
+	for _, message := range mock.Spec.WebSocketMessages {
+		if message.Sender == wsClient {
+			if !receive(message.Type) {
+				return
+			}
+			continue
+		}
+		time.Sleep(streamDelay(message.Delay, config))
+		frame, err := webSocketFrame(message)
+		if err != nil {
+			logger.Error("failed to decode the websocket message of the mock", zap.Error(err))
+			return
+		}
+		if !write(frame) {
+			return
+		}
+	}
+	// the connection stays open until the application closes it
+	receive("")
+}
@@ -0,0 +1,115 @@
+package integrations
+
+import (
+	"context"
+	"net"
+	"sort"
+	"sync"
+
+	"go.keploy.io/server/pkg/hooks"
+	"go.keploy.io/server/pkg/logging"
+	"go.uber.org/zap"
+)
+
+// DefaultPriority is the priority of the parsers registered through Register.
+const DefaultPriority = 0
+
+// Matcher reports whether the initial buffer read from the application's connection belongs to the
+// protocol handled by a parser.
+type Matcher func(buffer []byte) bool
+
+// Parser records or mocks the outgoing calls of a dependency.
+type Parser interface {
+	ProcessOutgoing(buffer []byte, conn net.Conn, dst net.Conn, ctx context.Context)
+}
+
+// Initializer is implemented by the parsers which need the logger and the hooks of the proxy. Init is
+// called once for every registered parser when the proxy boots.
+type Initializer interface {
+	Init(logger *zap.Logger, h *hooks.Hook)
+}
+
+type integration struct {
+	name     string
+	priority int
+	// order keeps the parsers having the same priority in their registration order
+	order   int
+	matcher Matcher
+	parser  Parser
+}
+
+var (
+	mu         sync.RWMutex
+	registered = map[string]*integration{}
+	// pipeline holds the registered integrations sorted by descending priority
+	pipeline     []*integration
+	registration int
+)
+
+// Register adds a parser to the detection pipeline with the default priority. External packages can call it
+// from their init function (optionally behind a build tag) to support a new wire protocol.
+func Register(name string, matcher Matcher, parser Parser) {
+	RegisterWithPriority(name, DefaultPriority, matcher, parser)
+}
+
+// RegisterWithPriority adds a parser to the detection pipeline. Parsers with a higher priority are matched first,
+// the ones sharing a priority are matched in their registration order. Registering an existing name replaces
+// the previous parser.
+func RegisterWithPriority(name string, priority int, matcher Matcher, parser Parser) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	registration++
+	registered[name] = &integration{
+		name:     name,
+		priority: priority,
+		order:    registration,
+		matcher:  matcher,
+		parser:   parser,
+	}
+
+	pipeline = make([]*integration, 0, len(registered))
+	for _, i := range registered {
+		pipeline = append(pipeline, i)
+	}
+	sort.Slice(pipeline, func(a, b int) bool {
+		if pipeline[a].priority != pipeline[b].priority {
+			return pipeline[a].priority > pipeline[b].priority
+		}
+		return pipeline[a].order < pipeline[b].order
+	})
+}
+
+// Get returns the parser registered with the given name.
+func Get(name string) (Parser, bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+	i, ok := registered[name]
+	if !ok {
+		return nil, false
+	}
+	return i.parser, true
+}
+
+// Match returns the name and the parser of the first integration, in priority order, whose matcher accepts the buffer.
+func Match(buffer []byte) (string, Parser, bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+	for _, i := range pipeline {
+		if i.matcher != nil && i.matcher(buffer) {
+			return i.name, i.parser, true
+		}
+	}
+	return "", nil, false
+}
+
+// Init passes the logger and the hooks of the proxy to the registered parsers implementing Initializer.
+func Init(logger *zap.Logger, h *hooks.Hook) {
+	mu.RLock()
+	defer mu.RUnlock()
+	for _, i := range pipeline {
+		if initializer, ok := i.parser.(Initializer); ok {
+			initializer.Init(logger.Named(logging.Parser(i.name)).With(zap.String("integration", i.name)), h)
+		}
+	}
+}
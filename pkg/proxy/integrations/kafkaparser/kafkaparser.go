@@ -0,0 +1,282 @@
+package kafkaparser
+
+import (
+	"context"
+	"encoding/binary"
+	"io"
+	"net"
+	"strings"
+	"time"
+
+	"go.keploy.io/server/pkg"
+	"go.keploy.io/server/pkg/hooks"
+	"go.keploy.io/server/pkg/metrics"
+	"go.keploy.io/server/pkg/models"
+	"go.keploy.io/server/pkg/proxy/util"
+	"go.keploy.io/server/utils"
+	"go.uber.org/zap"
+)
+
+// bounds of the api keys and versions accepted while detecting the protocol
+const (
+	maxAPIKey     = 100
+	maxAPIVersion = 30
+)
+
+type KafkaParser struct {
+	logger *zap.Logger
+	hooks  *hooks.Hook
+}
+
+func NewKafkaParser(logger *zap.Logger, h *hooks.Hook) *KafkaParser {
+	return &KafkaParser{
+		logger: logger,
+		hooks:  h,
+	}
+}
+
+// OutgoingType determines if the outgoing network call is Kafka by checking that the buffer starts with a size
+// delimited request having a valid header.
+func (k *KafkaParser) OutgoingType(buffer []byte) bool {
+	if len(buffer) < sizeLength+requestHeaderLength+2 {
+		return false
+	}
+	size := int(int32(binary.BigEndian.Uint32(buffer)))
+	if size < requestHeaderLength+2 || sizeLength+size > len(buffer) {
+		return false
+	}
+	apiKey := int16(binary.BigEndian.Uint16(buffer[4:]))
+	apiVersion := int16(binary.BigEndian.Uint16(buffer[6:]))
+	if apiKey < 0 || apiKey > maxAPIKey || apiVersion < 0 || apiVersion > maxAPIVersion {
+		return false
+	}
+	clientIDLength := int(int16(binary.BigEndian.Uint16(buffer[12:])))
+	return clientIDLength >= -1 && 14+clientIDLength <= sizeLength+size
+}
+
+func (k *KafkaParser) ProcessOutgoing(requestBuffer []byte, clientConn, destConn net.Conn, ctx context.Context) {
+	switch models.GetMode() {
+	case models.MODE_RECORD:
+		encodeKafkaOutgoing(requestBuffer, clientConn, destConn, k.hooks, k.logger, ctx)
+	case models.MODE_TEST:
+		decodeKafkaOutgoing(requestBuffer, clientConn, destConn, k.hooks, k.logger)
+	default:
+		k.logger.Info("Invalid mode detected while intercepting outgoing kafka call", zap.Any("mode", models.GetMode()))
+	}
+}
+
+type pendingRequest struct {
+	request   models.KafkaRequest
+	timestamp time.Time
+}
+
+// encodeKafkaOutgoing forwards the traffic between the client and the broker and records a mock for every request.
+// The clients send several requests before reading the responses, so the responses are paired with the requests
+// through their correlation id.
+func encodeKafkaOutgoing(requestBuffer []byte, clientConn, destConn net.Conn, h *hooks.Hook, logger *zap.Logger, ctx context.Context) error {
+	_, err := destConn.Write(requestBuffer)
+	if err != nil {
+		logger.Error("failed to write request message to the destination server", zap.Error(err))
+		return err
+	}
+
+	clientBufferChannel := make(chan []byte)
+	destBufferChannel := make(chan []byte)
+	errChannel := make(chan error)
+	// read requests from client
+	go func() {
+		// Recover from panic and gracefully shutdown
+		defer h.Recover(pkg.GenerateRandomID())
+		defer utils.HandlePanic()
+		readBuffConn(clientConn, clientBufferChannel, errChannel, logger, h)
+	}()
+	// read responses from destination
+	go func() {
+		// Recover from panic and gracefully shutdown
+		defer h.Recover(pkg.GenerateRandomID())
+		defer utils.HandlePanic()
+		readBuffConn(destConn, destBufferChannel, errChannel, logger, h)
+	}()
+
+	var (
+		requests  = requestBuffer
+		responses []byte
+		pending   = map[int32]pendingRequest{}
+	)
+	appendMock := func(p pendingRequest, kafkaResponses []models.KafkaResponse) {
+		h.AppendMocks(&models.Mock{
+			Version: models.GetVersion(),
+			Name:    "mocks",
+			Kind:    models.Kafka,
+			Spec: models.MockSpec{
+				KafkaRequests:    []models.KafkaRequest{p.request},
+				KafkaResponses:   kafkaResponses,
+				ReqTimestampMock: p.timestamp,
+				ResTimestampMock: time.Now(),
+			},
+		}, ctx)
+	}
+	recordRequests := func() error {
+		messages, rest, err := splitMessages(requests)
+		if err != nil {
+			return err
+		}
+		requests = rest
+		for _, message := range messages {
+			request, err := decodeRequest(message)
+			if err != nil {
+				logger.Error("failed to decode the kafka request", zap.Error(err))
+				continue
+			}
+			p := pendingRequest{request: request, timestamp: time.Now()}
+			if request.Header.APIKey == produceKey && request.Acks == 0 {
+				// the broker doesn't respond to the produce requests which don't wait for any acknowledgement
+				appendMock(p, nil)
+				continue
+			}
+			pending[request.Header.CorrelationID] = p
+		}
+		return nil
+	}
+
+	if err := recordRequests(); err != nil {
+		logger.Error("failed to split the kafka requests", zap.Error(err))
+		return err
+	}
+	for {
+		select {
+		case buffer := <-clientBufferChannel:
+			_, err := destConn.Write(buffer)
+			if err != nil {
+				logger.Error("failed to write request message to the destination server", zap.Error(err))
+				return err
+			}
+			requests = append(requests, buffer...)
+			if err := recordRequests(); err != nil {
+				logger.Error("failed to split the kafka requests", zap.Error(err))
+				return err
+			}
+		case buffer := <-destBufferChannel:
+			_, err := clientConn.Write(buffer)
+			if err != nil {
+				logger.Error("failed to write response to the client", zap.Error(err))
+				return err
+			}
+			responses = append(responses, buffer...)
+			messages, rest, err := splitMessages(responses)
+			if err != nil {
+				logger.Error("failed to split the kafka responses", zap.Error(err))
+				return err
+			}
+			responses = rest
+			for _, message := range messages {
+				if len(message) < sizeLength+4 {
+					continue
+				}
+				correlationID := int32(binary.BigEndian.Uint32(message[sizeLength:]))
+				p, ok := pending[correlationID]
+				if !ok {
+					logger.Debug("no kafka request found for the response", zap.Int32("correlation id", correlationID))
+					continue
+				}
+				delete(pending, correlationID)
+				response, err := decodeResponse(message, p.request.Header)
+				if err != nil {
+					logger.Error("failed to decode the kafka response", zap.Error(err))
+					continue
+				}
+				appendMock(p, []models.KafkaResponse{response})
+			}
+		case err := <-errChannel:
+			return err
+		}
+	}
+}
+
+func readBuffConn(conn net.Conn, bufferChannel chan []byte, errChannel chan error, logger *zap.Logger, h *hooks.Hook) error {
+	for {
+		buffer, err := util.ReadBytes(conn)
+		if err != nil {
+			if !h.IsUsrAppTerminateInitiated() {
+				if err == io.EOF {
+					logger.Debug("EOF error received from client. Closing connection in kafka !!")
+				} else if !strings.Contains(err.Error(), "use of closed network connection") {
+					logger.Error("failed to read the packet message in proxy for kafka dependency", zap.Error(err))
+				}
+				errChannel <- err
+			}
+			return err
+		}
+		bufferChannel <- buffer
+	}
+}
+
+// decodeKafkaOutgoing replies to every request of the client with the response of its mock, carrying the
+// correlation id of the request so that the responses can be read in any order.
+func decodeKafkaOutgoing(requestBuffer []byte, clientConn, destConn net.Conn, h *hooks.Hook, logger *zap.Logger) error {
+	requests := requestBuffer
+	for {
+		messages, rest, err := splitMessages(requests)
+		if err != nil {
+			logger.Error("failed to split the kafka requests", zap.Error(err))
+			return err
+		}
+		if len(messages) == 0 {
+			buffer, err := util.ReadBytes(clientConn)
+			if len(buffer) == 0 && err != nil {
+				if err == io.EOF {
+					logger.Debug("EOF error received from client. Closing connection in kafka !!")
+					return nil
+				}
+				logger.Error("failed to read the request message in proxy for kafka dependency", zap.Error(err))
+				return err
+			}
+			requests = append(rest, buffer...)
+			continue
+		}
+		requests = rest
+
+		for _, message := range messages {
+			request, err := decodeRequest(message)
+			if err != nil {
+				logger.Error("failed to decode the kafka request", zap.Error(err))
+				return err
+			}
+			mock, matched, err := match(h, request)
+			if err != nil {
+				logger.Error("failed to match the kafka request with the mocks", zap.Error(err))
+				return err
+			}
+			if !matched {
+				metrics.MatchFailures.Inc("kafka")
+				logger.Debug("no mock matched the kafka request, passing it through", zap.String("api", request.API), zap.Int32("correlation id", request.Header.CorrelationID))
+				if request.Header.APIKey == produceKey && request.Acks == 0 && destConn != nil {
+					if _, err := destConn.Write(message); err != nil {
+						logger.Error("failed to write request message to the destination server", zap.Error(err))
+						return err
+					}
+					continue
+				}
+				_, err = util.Passthrough(clientConn, destConn, [][]byte{message}, h.Recover, logger)
+				if err != nil {
+					logger.Error("failed to match the dependency call from user application", zap.String("api", request.API))
+					return err
+				}
+				continue
+			}
+
+			for _, response := range mock.Spec.KafkaResponses {
+				encoded, err := encodeResponse(response, request.Header.CorrelationID)
+				if err != nil {
+					logger.Error("failed to encode the kafka response of the mock", zap.Error(err), zap.String("mock", mock.Name))
+					return err
+				}
+				_, err = clientConn.Write(encoded)
+				if err != nil {
+					logger.Error("failed to write the kafka response to the client application", zap.Error(err))
+					return err
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package kafkaparser
+
+import (
+	"fmt"
+
+	"go.keploy.io/server/pkg/hooks"
+	"go.keploy.io/server/pkg/models"
+)
+
+// match finds the mock of the request and removes it from the mocks of the test. The mocks of the same API,
+// version and topics are candidates, the one having the same body is preferred, then the one fetching the same
+// offsets, then the first one recorded.
+func match(h *hooks.Hook, request models.KafkaRequest) (*models.Mock, bool, error) {
+	for {
+		tcsMocks, err := h.GetTcsMocks()
+		if err != nil {
+			return nil, false, fmt.Errorf("error while getting tcs mocks %v", err)
+		}
+
+		var (
+			bestMatch *models.Mock
+			bestScore = -1
+		)
+		for _, mock := range tcsMocks {
+			if mock == nil || mock.Kind != models.Kafka || len(mock.Spec.KafkaRequests) != 1 {
+				continue
+			}
+			recorded := mock.Spec.KafkaRequests[0]
+			if recorded.Header.APIKey != request.Header.APIKey || recorded.Header.APIVersion != request.Header.APIVersion ||
+				!sameTopics(recorded.Topics, request.Topics) {
+				continue
+			}
+			score := 0
+			switch {
+			case recorded.Body == request.Body:
+				score = 2
+			case sameOffsets(recorded.Topics, request.Topics):
+				score = 1
+			}
+			if score > bestScore {
+				bestMatch, bestScore = mock, score
+			}
+		}
+		if bestMatch == nil {
+			return nil, false, nil
+		}
+
+		isDeleted, err := h.DeleteTcsMock(bestMatch)
+		if err != nil {
+			return nil, false, fmt.Errorf("error while deleting tcs mock: %v", err)
+		}
+		if !isDeleted {
+			continue
+		}
+		return bestMatch, true, nil
+	}
+}
+
+func sameTopics(a, b []models.KafkaTopic) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].Name != b[i].Name || len(a[i].Partitions) != len(b[i].Partitions) {
+			return false
+		}
+		for j := range a[i].Partitions {
+			if a[i].Partitions[j].Index != b[i].Partitions[j].Index {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// sameOffsets compares the fetch offsets of topics having the same partitions.
+func sameOffsets(a, b []models.KafkaTopic) bool {
+	for i := range a {
+		for j := range a[i].Partitions {
+			if a[i].Partitions[j].FetchOffset != b[i].Partitions[j].FetchOffset {
+				return false
+			}
+		}
+	}
+	return true
+}
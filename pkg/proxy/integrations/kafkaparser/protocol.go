@@ -0,0 +1,411 @@
+package kafkaparser
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+
+	"go.keploy.io/server/pkg/models"
+)
+
+// keys of the APIs whose requests are decoded
+const (
+	produceKey     int16 = 0
+	fetchKey       int16 = 1
+	metadataKey    int16 = 3
+	apiVersionsKey int16 = 18
+)
+
+var apiNames = map[int16]string{
+	produceKey:     "Produce",
+	fetchKey:       "Fetch",
+	2:              "ListOffsets",
+	metadataKey:    "Metadata",
+	8:              "OffsetCommit",
+	9:              "OffsetFetch",
+	10:             "FindCoordinator",
+	11:             "JoinGroup",
+	12:             "Heartbeat",
+	13:             "LeaveGroup",
+	14:             "SyncGroup",
+	15:             "DescribeGroups",
+	16:             "ListGroups",
+	17:             "SaslHandshake",
+	apiVersionsKey: "ApiVersions",
+	19:             "CreateTopics",
+	20:             "DeleteTopics",
+	22:             "InitProducerId",
+	36:             "SaslAuthenticate",
+}
+
+// firstFlexibleVersion is the first version of the decoded APIs using the compact encodings and the tagged fields.
+var firstFlexibleVersion = map[int16]int16{
+	produceKey:     9,
+	fetchKey:       12,
+	metadataKey:    9,
+	apiVersionsKey: 3,
+}
+
+const (
+	// size of the length prefixing every request and response
+	sizeLength = 4
+	// api key, api version and correlation id of the request header
+	requestHeaderLength = 8
+	// the brokers reject the requests bigger than socket.request.max.bytes, 100MB by default
+	maxMessageSize = 100 * 1024 * 1024
+)
+
+var errShortMessage = errors.New("kafka message too short")
+
+func isFlexible(apiKey, apiVersion int16) bool {
+	first, ok := firstFlexibleVersion[apiKey]
+	return ok && apiVersion >= first
+}
+
+func apiName(apiKey int16) string {
+	if name, ok := apiNames[apiKey]; ok {
+		return name
+	}
+	return fmt.Sprintf("Api%d", apiKey)
+}
+
+// splitMessages returns the complete size delimited messages of the buffer, along with the bytes left.
+func splitMessages(buffer []byte) ([][]byte, []byte, error) {
+	var messages [][]byte
+	for len(buffer) >= sizeLength {
+		size := int(int32(binary.BigEndian.Uint32(buffer)))
+		if size < 0 || size > maxMessageSize {
+			return nil, nil, fmt.Errorf("invalid size %d of the kafka message", size)
+		}
+		if len(buffer) < sizeLength+size {
+			break
+		}
+		messages = append(messages, buffer[:sizeLength+size])
+		buffer = buffer[sizeLength+size:]
+	}
+	return messages, buffer, nil
+}
+
+// decodeRequest decodes a size delimited request.
+func decodeRequest(message []byte) (models.KafkaRequest, error) {
+	if len(message) < sizeLength+requestHeaderLength+2 {
+		return models.KafkaRequest{}, errShortMessage
+	}
+	r := &reader{buffer: message[sizeLength:]}
+	header := models.KafkaRequestHeader{
+		APIKey:        r.int16(),
+		APIVersion:    r.int16(),
+		CorrelationID: r.int32(),
+	}
+	// the client id is never a compact string, even in the flexible versions
+	header.ClientID = r.nullableString()
+	r.flexible = isFlexible(header.APIKey, header.APIVersion)
+	r.skipTaggedFields()
+	if r.err != nil {
+		return models.KafkaRequest{}, fmt.Errorf("failed to decode the kafka request header: %v", r.err)
+	}
+
+	request := models.KafkaRequest{
+		Header: header,
+		API:    apiName(header.APIKey),
+		Body:   base64.StdEncoding.EncodeToString(r.buffer[r.offset:]),
+	}
+	body := &reader{buffer: r.buffer[r.offset:], flexible: r.flexible}
+	switch header.APIKey {
+	case produceKey:
+		request.Acks, request.Topics = decodeProduceRequest(body, header.APIVersion)
+	case fetchKey:
+		request.Topics = decodeFetchRequest(body, header.APIVersion)
+	case metadataKey:
+		request.Topics = decodeMetadataRequest(body, header.APIVersion)
+	}
+	if body.err != nil {
+		return models.KafkaRequest{}, fmt.Errorf("failed to decode the kafka %s request: %v", request.API, body.err)
+	}
+	return request, nil
+}
+
+func decodeProduceRequest(r *reader, version int16) (int16, []models.KafkaTopic) {
+	if version >= 3 {
+		// transactional id
+		r.flexibleNullableString()
+	}
+	acks := r.int16()
+	// timeout
+	r.int32()
+
+	topics := make([]models.KafkaTopic, r.arrayLength())
+	for i := range topics {
+		topics[i].Name = r.topic(version >= 13)
+		topics[i].Partitions = make([]models.KafkaPartition, r.arrayLength())
+		for j := range topics[i].Partitions {
+			topics[i].Partitions[j].Index = r.int32()
+			// records
+			r.flexibleBytes()
+			r.skipTaggedFields()
+		}
+		r.skipTaggedFields()
+	}
+	return acks, topics
+}
+
+func decodeFetchRequest(r *reader, version int16) []models.KafkaTopic {
+	if version < 15 {
+		// replica id
+		r.int32()
+	}
+	// max wait and min bytes
+	r.int32()
+	r.int32()
+	if version >= 3 {
+		// max bytes
+		r.int32()
+	}
+	if version >= 4 {
+		// isolation level
+		r.int8()
+	}
+	if version >= 7 {
+		// session id and epoch
+		r.int32()
+		r.int32()
+	}
+
+	topics := make([]models.KafkaTopic, r.arrayLength())
+	for i := range topics {
+		topics[i].Name = r.topic(version >= 13)
+		topics[i].Partitions = make([]models.KafkaPartition, r.arrayLength())
+		for j := range topics[i].Partitions {
+			partition := &topics[i].Partitions[j]
+			partition.Index = r.int32()
+			if version >= 9 {
+				// current leader epoch
+				r.int32()
+			}
+			partition.FetchOffset = r.int64()
+			if version >= 12 {
+				// last fetched epoch
+				r.int32()
+			}
+			if version >= 5 {
+				// log start offset
+				r.int64()
+			}
+			// partition max bytes
+			r.int32()
+			r.skipTaggedFields()
+		}
+		r.skipTaggedFields()
+	}
+	return topics
+}
+
+func decodeMetadataRequest(r *reader, version int16) []models.KafkaTopic {
+	// a null array requests all the topics
+	count := r.arrayLength()
+	topics := make([]models.KafkaTopic, 0, count)
+	for i := 0; i < count; i++ {
+		var name string
+		if version >= 10 {
+			id := r.uuid()
+			name = r.flexibleNullableString()
+			if name == "" {
+				name = id
+			}
+		} else {
+			name = r.flexibleNullableString()
+		}
+		r.skipTaggedFields()
+		topics = append(topics, models.KafkaTopic{Name: name})
+	}
+	return topics
+}
+
+// decodeResponse decodes a size delimited response to the given request.
+func decodeResponse(message []byte, request models.KafkaRequestHeader) (models.KafkaResponse, error) {
+	if len(message) < sizeLength+4 {
+		return models.KafkaResponse{}, errShortMessage
+	}
+	response := models.KafkaResponse{
+		CorrelationID: int32(binary.BigEndian.Uint32(message[sizeLength:])),
+		Body:          base64.StdEncoding.EncodeToString(message[sizeLength+4:]),
+	}
+	if request.APIKey == apiVersionsKey {
+		// the header of the ApiVersions responses never has tagged fields, so that the clients can read it
+		// whatever the version they asked for
+		r := &reader{buffer: message[sizeLength+4:], flexible: isFlexible(request.APIKey, request.APIVersion)}
+		// error code
+		r.int16()
+		versions := make([]models.KafkaAPIVersion, r.arrayLength())
+		for i := range versions {
+			versions[i] = models.KafkaAPIVersion{
+				APIKey:     r.int16(),
+				MinVersion: r.int16(),
+				MaxVersion: r.int16(),
+			}
+			r.skipTaggedFields()
+		}
+		if r.err == nil {
+			response.APIVersions = versions
+		}
+	}
+	return response, nil
+}
+
+// encodeResponse builds the size delimited response of a mock, answering the request with the given correlation id.
+func encodeResponse(response models.KafkaResponse, correlationID int32) ([]byte, error) {
+	body, err := base64.StdEncoding.DecodeString(response.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode the body of the kafka response: %v", err)
+	}
+	message := make([]byte, sizeLength+4, sizeLength+4+len(body))
+	binary.BigEndian.PutUint32(message, uint32(4+len(body)))
+	binary.BigEndian.PutUint32(message[sizeLength:], uint32(correlationID))
+	return append(message, body...), nil
+}
+
+// reader decodes the primitive types of the protocol. The first error is kept and the following reads return
+// zero values.
+type reader struct {
+	buffer   []byte
+	offset   int
+	flexible bool
+	err      error
+}
+
+func (r *reader) next(n int) []byte {
+	if r.err != nil {
+		return nil
+	}
+	if n < 0 || len(r.buffer) < r.offset+n {
+		r.err = errShortMessage
+		return nil
+	}
+	b := r.buffer[r.offset : r.offset+n]
+	r.offset += n
+	return b
+}
+
+func (r *reader) int8() int8 {
+	b := r.next(1)
+	if b == nil {
+		return 0
+	}
+	return int8(b[0])
+}
+
+func (r *reader) int16() int16 {
+	b := r.next(2)
+	if b == nil {
+		return 0
+	}
+	return int16(binary.BigEndian.Uint16(b))
+}
+
+func (r *reader) int32() int32 {
+	b := r.next(4)
+	if b == nil {
+		return 0
+	}
+	return int32(binary.BigEndian.Uint32(b))
+}
+
+func (r *reader) int64() int64 {
+	b := r.next(8)
+	if b == nil {
+		return 0
+	}
+	return int64(binary.BigEndian.Uint64(b))
+}
+
+func (r *reader) uvarint() uint64 {
+	if r.err != nil {
+		return 0
+	}
+	v, n := binary.Uvarint(r.buffer[r.offset:])
+	if n <= 0 {
+		r.err = errShortMessage
+		return 0
+	}
+	r.offset += n
+	return v
+}
+
+func (r *reader) uuid() string {
+	return hex.EncodeToString(r.next(16))
+}
+
+// nullableString reads a string prefixed by its int16 length, -1 for null.
+func (r *reader) nullableString() string {
+	length := r.int16()
+	if length < 0 {
+		return ""
+	}
+	return string(r.next(int(length)))
+}
+
+// flexibleNullableString reads a compact string in the flexible versions, and a string otherwise.
+func (r *reader) flexibleNullableString() string {
+	if !r.flexible {
+		return r.nullableString()
+	}
+	// the compact length is the length plus one, 0 for null
+	length := int(r.uvarint()) - 1
+	if length < 0 {
+		return ""
+	}
+	return string(r.next(length))
+}
+
+func (r *reader) flexibleBytes() []byte {
+	var length int
+	if r.flexible {
+		length = int(r.uvarint()) - 1
+	} else {
+		length = int(r.int32())
+	}
+	if length < 0 {
+		return nil
+	}
+	return r.next(length)
+}
+
+// arrayLength reads the length of an array, a null array is read as an empty one.
+func (r *reader) arrayLength() int {
+	var length int
+	if r.flexible {
+		length = int(r.uvarint()) - 1
+	} else {
+		length = int(r.int32())
+	}
+	if length < 0 || r.err != nil {
+		return 0
+	}
+	// every element takes at least a byte
+	if length > len(r.buffer)-r.offset {
+		r.err = errShortMessage
+		return 0
+	}
+	return length
+}
+
+func (r *reader) topic(byID bool) string {
+	if byID {
+		return r.uuid()
+	}
+	return r.flexibleNullableString()
+}
+
+func (r *reader) skipTaggedFields() {
+	if !r.flexible {
+		return
+	}
+	count := r.uvarint()
+	for i := uint64(0); i < count && r.err == nil; i++ {
+		// tag
+		r.uvarint()
+		r.next(int(r.uvarint()))
+	}
+}
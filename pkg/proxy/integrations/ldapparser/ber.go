@@ -0,0 +1,119 @@
+package ldapparser
+
+import (
+	"errors"
+	"fmt"
+)
+
+// universal tags of the BER elements used by LDAP
+const (
+	tagInteger     = 0x02
+	tagOctetString = 0x04
+	tagEnumerated  = 0x0a
+	tagSequence    = 0x30
+)
+
+// errIncomplete is returned when the buffer doesn't hold the whole element yet.
+var errIncomplete = errors.New("incomplete BER element")
+
+// element is a BER encoded element along with its encoding, LDAP only uses the tags fitting in a single byte.
+type element struct {
+	tag     byte
+	content []byte
+	raw     []byte
+}
+
+// readElement reads the element starting the buffer and returns the count of bytes it spans.
+func readElement(buffer []byte) (element, int, error) {
+	if len(buffer) < 2 {
+		return element{}, 0, errIncomplete
+	}
+	tag := buffer[0]
+	if tag&0x1f == 0x1f {
+		return element{}, 0, fmt.Errorf("unsupported multi-byte BER tag 0x%02x", tag)
+	}
+	length, offset := int(buffer[1]), 2
+	if length&0x80 != 0 {
+		// the long form gives the count of bytes holding the length, LDAP forbids the indefinite form
+		n := length & 0x7f
+		if n == 0 || n > 4 {
+			return element{}, 0, fmt.Errorf("unsupported BER length of %d bytes", n)
+		}
+		if len(buffer) < offset+n {
+			return element{}, 0, errIncomplete
+		}
+		length = 0
+		for _, b := range buffer[offset : offset+n] {
+			length = length<<8 | int(b)
+		}
+		offset += n
+	}
+	if len(buffer) < offset+length {
+		return element{}, 0, errIncomplete
+	}
+	return element{
+		tag:     tag,
+		content: buffer[offset : offset+length],
+		raw:     buffer[:offset+length],
+	}, offset + length, nil
+}
+
+// children splits the content of a constructed element into its elements.
+func (e element) children() ([]element, error) {
+	var (
+		children []element
+		content  = e.content
+	)
+	for len(content) > 0 {
+		child, n, err := readElement(content)
+		if err != nil {
+			if errors.Is(err, errIncomplete) {
+				return nil, fmt.Errorf("truncated element in 0x%02x", e.tag)
+			}
+			return nil, err
+		}
+		children = append(children, child)
+		content = content[n:]
+	}
+	return children, nil
+}
+
+// integer decodes the content of an INTEGER or ENUMERATED element, a big endian two's complement number.
+func (e element) integer() (int64, error) {
+	if len(e.content) == 0 || len(e.content) > 8 {
+		return 0, fmt.Errorf("invalid integer of %d bytes", len(e.content))
+	}
+	value := int64(int8(e.content[0]))
+	for _, b := range e.content[1:] {
+		value = value<<8 | int64(b)
+	}
+	return value, nil
+}
+
+func encodeElement(tag byte, content []byte) []byte {
+	length := len(content)
+	var header []byte
+	switch {
+	case length < 0x80:
+		header = []byte{tag, byte(length)}
+	case length <= 0xff:
+		header = []byte{tag, 0x81, byte(length)}
+	case length <= 0xffff:
+		header = []byte{tag, 0x82, byte(length >> 8), byte(length)}
+	case length <= 0xffffff:
+		header = []byte{tag, 0x83, byte(length >> 16), byte(length >> 8), byte(length)}
+	default:
+		header = []byte{tag, 0x84, byte(length >> 24), byte(length >> 16), byte(length >> 8), byte(length)}
+	}
+	return append(header, content...)
+}
+
+// encodeInteger encodes an INTEGER or ENUMERATED element with the fewest bytes.
+func encodeInteger(tag byte, value int64) []byte {
+	content := []byte{byte(value)}
+	for value > 0x7f || value < -0x80 {
+		value >>= 8
+		content = append([]byte{byte(value)}, content...)
+	}
+	return encodeElement(tag, content)
+}
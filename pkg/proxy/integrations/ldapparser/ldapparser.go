@@ -0,0 +1,312 @@
+package ldapparser
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"time"
+
+	"go.keploy.io/server/pkg"
+	"go.keploy.io/server/pkg/hooks"
+	"go.keploy.io/server/pkg/metrics"
+	"go.keploy.io/server/pkg/models"
+	"go.keploy.io/server/pkg/proxy/util"
+	"go.keploy.io/server/utils"
+	"go.uber.org/zap"
+)
+
+type LdapParser struct {
+	logger *zap.Logger
+	hooks  *hooks.Hook
+}
+
+func NewLdapParser(logger *zap.Logger, h *hooks.Hook) *LdapParser {
+	return &LdapParser{
+		logger: logger,
+		hooks:  h,
+	}
+}
+
+// OutgoingType determines if the outgoing network call is LDAP by checking that the buffer starts with an
+// LDAPMessage carrying a bind, search or extended request, the operations opening the connections of the clients.
+func (l *LdapParser) OutgoingType(buffer []byte) bool {
+	envelope, _, err := readElement(buffer)
+	if err != nil || envelope.tag != tagSequence {
+		return false
+	}
+	id, n, err := readElement(envelope.content)
+	if err != nil || id.tag != tagInteger || len(id.content) == 0 || len(id.content) > 4 {
+		return false
+	}
+	op, _, err := readElement(envelope.content[n:])
+	if err != nil {
+		return false
+	}
+	switch op.tag {
+	case opBindRequest, opSearchRequest, opExtendedRequest:
+		return true
+	default:
+		return false
+	}
+}
+
+func (l *LdapParser) ProcessOutgoing(requestBuffer []byte, clientConn, destConn net.Conn, ctx context.Context) {
+	switch models.GetMode() {
+	case models.MODE_RECORD:
+		encodeLdapOutgoing(requestBuffer, clientConn, destConn, l.hooks, l.logger, ctx)
+	case models.MODE_TEST:
+		decodeLdapOutgoing(requestBuffer, clientConn, destConn, l.hooks, l.logger)
+	default:
+		l.logger.Info("Invalid mode detected while intercepting outgoing ldap call", zap.Any("mode", models.GetMode()))
+	}
+}
+
+type pendingRequest struct {
+	request   models.LDAPMessage
+	responses []models.LDAPMessage
+	timestamp time.Time
+}
+
+// encodeLdapOutgoing forwards the traffic between the client and the server and records a mock for every request
+// along with its responses, which are paired through the message id since the requests may be outstanding at the
+// same time. The unbind and abandon requests have no response and aren't recorded, and the connection is forwarded
+// without being recorded once the client starts TLS on it.
+func encodeLdapOutgoing(requestBuffer []byte, clientConn, destConn net.Conn, h *hooks.Hook, logger *zap.Logger, ctx context.Context) error {
+	_, err := destConn.Write(requestBuffer)
+	if err != nil {
+		logger.Error("failed to write request message to the destination server", zap.Error(err))
+		return err
+	}
+
+	clientBufferChannel := make(chan []byte)
+	destBufferChannel := make(chan []byte)
+	errChannel := make(chan error)
+	// read requests from client
+	go func() {
+		// Recover from panic and gracefully shutdown
+		defer h.Recover(pkg.GenerateRandomID())
+		defer utils.HandlePanic()
+		readBuffConn(clientConn, clientBufferChannel, errChannel, logger, h)
+	}()
+	// read responses from destination
+	go func() {
+		// Recover from panic and gracefully shutdown
+		defer h.Recover(pkg.GenerateRandomID())
+		defer utils.HandlePanic()
+		readBuffConn(destConn, destBufferChannel, errChannel, logger, h)
+	}()
+
+	var (
+		requests  []byte
+		responses []byte
+		pending   = map[int64]*pendingRequest{}
+		recording = true
+	)
+	recordRequests := func(buffer []byte) {
+		requests = append(requests, buffer...)
+		messages, consumed, err := splitMessages(requests)
+		if err != nil {
+			logger.Error("failed to decode the ldap requests, forwarding the rest of the connection", zap.Error(err))
+			recording = false
+			return
+		}
+		requests = requests[consumed:]
+		for _, m := range messages {
+			switch {
+			case m.op.tag == opUnbindRequest || m.op.tag == opAbandonRequest:
+				continue
+			case m.op.tag == opExtendedRequest && m.decoded.Name == startTLSOID:
+				logger.Warn("the ldap connection is forwarded without being recorded after StartTLS")
+				recording = false
+				return
+			}
+			pending[m.id] = &pendingRequest{request: m.decoded, timestamp: time.Now()}
+		}
+	}
+	recordResponses := func(buffer []byte) {
+		responses = append(responses, buffer...)
+		messages, consumed, err := splitMessages(responses)
+		if err != nil {
+			logger.Error("failed to decode the ldap responses, forwarding the rest of the connection", zap.Error(err))
+			recording = false
+			return
+		}
+		responses = responses[consumed:]
+		for _, m := range messages {
+			p, ok := pending[m.id]
+			if !ok {
+				// e.g. the notice of disconnection, sent with the message id 0
+				logger.Debug("no ldap request found for the response", zap.Int64("message id", m.id), zap.String("operation", m.decoded.Operation))
+				continue
+			}
+			p.responses = append(p.responses, m.decoded)
+			if !m.isFinal() {
+				continue
+			}
+			delete(pending, m.id)
+			h.AppendMocks(&models.Mock{
+				Version: models.GetVersion(),
+				Name:    "mocks",
+				Kind:    models.LDAP,
+				Spec: models.MockSpec{
+					LDAPRequests:     []models.LDAPMessage{p.request},
+					LDAPResponses:    p.responses,
+					ReqTimestampMock: p.timestamp,
+					ResTimestampMock: time.Now(),
+				},
+			}, ctx)
+		}
+	}
+
+	recordRequests(requestBuffer)
+	for {
+		select {
+		case buffer := <-clientBufferChannel:
+			_, err := destConn.Write(buffer)
+			if err != nil {
+				logger.Error("failed to write request message to the destination server", zap.Error(err))
+				return err
+			}
+			if recording {
+				recordRequests(buffer)
+			}
+		case buffer := <-destBufferChannel:
+			_, err := clientConn.Write(buffer)
+			if err != nil {
+				logger.Error("failed to write response to the client", zap.Error(err))
+				return err
+			}
+			if recording {
+				recordResponses(buffer)
+			}
+		case err := <-errChannel:
+			return err
+		}
+	}
+}
+
+func readBuffConn(conn net.Conn, bufferChannel chan []byte, errChannel chan error, logger *zap.Logger, h *hooks.Hook) error {
+	for {
+		buffer, err := util.ReadBytes(conn)
+		if err != nil {
+			if !h.IsUsrAppTerminateInitiated() {
+				if err == io.EOF {
+					logger.Debug("EOF error received from client. Closing connection in ldap !!")
+				} else if !strings.Contains(err.Error(), "use of closed network connection") {
+					logger.Error("failed to read the packet message in proxy for ldap dependency", zap.Error(err))
+				}
+				errChannel <- err
+			}
+			return err
+		}
+		bufferChannel <- buffer
+	}
+}
+
+// decodeLdapOutgoing replies to every request of the client with the responses of its mock, carrying the message
+// id of the request. The connections whose first request wasn't recorded are forwarded to the server, the other
+// requests which can't be matched get a response with the result code other (80).
+func decodeLdapOutgoing(requestBuffer []byte, clientConn, destConn net.Conn, h *hooks.Hook, logger *zap.Logger) error {
+	var (
+		buffer = requestBuffer
+		// replied is set once a response has been mocked on the connection, received holds the bytes read until then
+		replied  bool
+		received = requestBuffer
+	)
+	for {
+		messages, consumed, err := splitMessages(buffer)
+		if err != nil {
+			logger.Error("failed to decode the ldap requests", zap.Error(err))
+			return err
+		}
+		buffer = buffer[consumed:]
+
+		for _, m := range messages {
+			switch m.op.tag {
+			case opUnbindRequest:
+				logger.Debug("unbind request received from client. Closing connection in ldap !!")
+				return nil
+			case opAbandonRequest:
+				continue
+			}
+
+			mock, err := match(h, m.decoded)
+			if err != nil {
+				logger.Error("failed to match the ldap request with the mocks", zap.Error(err))
+				return err
+			}
+			if mock == nil {
+				if !replied {
+					logger.Debug("no mock found for the ldap connection, forwarding it to the server")
+					return forward(received, clientConn, destConn, h)
+				}
+				metrics.MatchFailures.Inc("ldap")
+				logger.Error("no mock matched the ldap request", zap.String("operation", m.decoded.Operation), zap.String("dn", m.decoded.DN))
+				if op, ok := responseOf[m.op.tag]; ok {
+					_, err := clientConn.Write(encodeResult(m.id, op, resultOther, "keploy: no mock matched the "+m.decoded.Operation))
+					if err != nil {
+						logger.Error("failed to write the ldap response to the client application", zap.Error(err))
+						return err
+					}
+				}
+				continue
+			}
+			replied = true
+
+			for _, response := range mock.Spec.LDAPResponses {
+				encoded, err := encodeResponse(m.id, response)
+				if err != nil {
+					logger.Error("failed to encode the ldap response of the mock", zap.Error(err), zap.String("mock", mock.Name))
+					return err
+				}
+				_, err = clientConn.Write(encoded)
+				if err != nil {
+					logger.Error("failed to write the ldap response to the client application", zap.Error(err))
+					return err
+				}
+			}
+		}
+
+		read, err := util.ReadBytes(clientConn)
+		if len(read) == 0 && err != nil {
+			if err == io.EOF {
+				logger.Debug("EOF error received from client. Closing connection in ldap !!")
+				return nil
+			}
+			logger.Error("failed to read the request message in proxy for ldap dependency", zap.Error(err))
+			return err
+		}
+		buffer = append(buffer, read...)
+		if !replied {
+			received = append(received, read...)
+		}
+	}
+}
+
+// forward passes the connection through to the server when it wasn't recorded.
+func forward(requestBuffer []byte, clientConn, destConn net.Conn, h *hooks.Hook) error {
+	if destConn == nil {
+		return errors.New("failed to pass network traffic to the destination connection")
+	}
+	if _, err := destConn.Write(requestBuffer); err != nil {
+		return err
+	}
+	errChannel := make(chan error, 2)
+	go func() {
+		// Recover from panic and gracefully shutdown
+		defer h.Recover(pkg.GenerateRandomID())
+		defer utils.HandlePanic()
+		_, err := io.Copy(destConn, clientConn)
+		errChannel <- err
+	}()
+	go func() {
+		// Recover from panic and gracefully shutdown
+		defer h.Recover(pkg.GenerateRandomID())
+		defer utils.HandlePanic()
+		_, err := io.Copy(clientConn, destConn)
+		errChannel <- err
+	}()
+	return <-errChannel
+}
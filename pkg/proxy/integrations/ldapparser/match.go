@@ -0,0 +1,80 @@
+package ldapparser
+
+import (
+	"fmt"
+	"strings"
+
+	"go.keploy.io/server/pkg/hooks"
+	"go.keploy.io/server/pkg/models"
+)
+
+// match finds the mock recorded for the request and removes it from the mocks of the test. It returns nil when no
+// mock matches.
+func match(h *hooks.Hook, request models.LDAPMessage) (*models.Mock, error) {
+	for {
+		tcsMocks, err := h.GetTcsMocks()
+		if err != nil {
+			return nil, fmt.Errorf("error while getting tcs mocks %v", err)
+		}
+
+		var (
+			best      *models.Mock
+			bestScore = -1
+		)
+		for _, mock := range tcsMocks {
+			if mock == nil || mock.Kind != models.LDAP || len(mock.Spec.LDAPRequests) != 1 {
+				continue
+			}
+			score, ok := matchScore(mock.Spec.LDAPRequests[0], request)
+			if ok && score > bestScore {
+				best, bestScore = mock, score
+			}
+		}
+		if best == nil {
+			return nil, nil
+		}
+
+		isDeleted, err := h.DeleteTcsMock(best)
+		if err != nil {
+			return nil, fmt.Errorf("error while deleting tcs mock: %v", err)
+		}
+		if !isDeleted {
+			// another connection consumed the mock in the meantime
+			continue
+		}
+		return best, nil
+	}
+}
+
+// matchScore reports whether the recorded request answers the request, and how close they are. The operation, the
+// DN and the fields telling what is asked (the method of a bind, the scope and filter of a search, the name of an
+// extended operation) have to be the same. A mock having the same operation is preferred, then one requesting the
+// same attributes. The message ids depend on the order of the requests on the connection, they are left out.
+func matchScore(recorded, request models.LDAPMessage) (int, bool) {
+	if recorded.Operation != request.Operation || !strings.EqualFold(recorded.DN, request.DN) {
+		return 0, false
+	}
+	if recorded.AuthMethod != request.AuthMethod || recorded.Mechanism != request.Mechanism ||
+		recorded.Scope != request.Scope || recorded.Filter != request.Filter || recorded.Name != request.Name {
+		return 0, false
+	}
+	switch {
+	case recorded.Payload == request.Payload && recorded.Controls == request.Controls:
+		return 2, true
+	case equalAttributes(recorded.Attributes, request.Attributes):
+		return 1, true
+	}
+	return 0, true
+}
+
+func equalAttributes(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !strings.EqualFold(a[i], b[i]) {
+			return false
+		}
+	}
+	return true
+}
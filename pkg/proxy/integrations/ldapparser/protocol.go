@@ -0,0 +1,425 @@
+package ldapparser
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+
+	"go.keploy.io/server/pkg/models"
+)
+
+// tags of the protocol operations, in the application class
+const (
+	opBindRequest           = 0x60
+	opBindResponse          = 0x61
+	opUnbindRequest         = 0x42
+	opSearchRequest         = 0x63
+	opSearchResultEntry     = 0x64
+	opSearchResultDone      = 0x65
+	opSearchResultReference = 0x73
+	opModifyRequest         = 0x66
+	opModifyResponse        = 0x67
+	opAddRequest            = 0x68
+	opAddResponse           = 0x69
+	opDelRequest            = 0x4a
+	opDelResponse           = 0x6b
+	opModifyDNRequest       = 0x6c
+	opModifyDNResponse      = 0x6d
+	opCompareRequest        = 0x6e
+	opCompareResponse       = 0x6f
+	opAbandonRequest        = 0x50
+	opExtendedRequest       = 0x77
+	opExtendedResponse      = 0x78
+	opIntermediateResponse  = 0x79
+)
+
+// tag of the controls following the operation in a message
+const tagControls = 0xa0
+
+const (
+	startTLSOID = "1.3.6.1.4.1.1466.20037"
+	// resultOther is the result code sent for the requests which can't be matched
+	resultOther = 80
+)
+
+var operationNames = map[byte]string{
+	opBindRequest:           "BindRequest",
+	opBindResponse:          "BindResponse",
+	opUnbindRequest:         "UnbindRequest",
+	opSearchRequest:         "SearchRequest",
+	opSearchResultEntry:     "SearchResultEntry",
+	opSearchResultDone:      "SearchResultDone",
+	opSearchResultReference: "SearchResultReference",
+	opModifyRequest:         "ModifyRequest",
+	opModifyResponse:        "ModifyResponse",
+	opAddRequest:            "AddRequest",
+	opAddResponse:           "AddResponse",
+	opDelRequest:            "DelRequest",
+	opDelResponse:           "DelResponse",
+	opModifyDNRequest:       "ModifyDNRequest",
+	opModifyDNResponse:      "ModifyDNResponse",
+	opCompareRequest:        "CompareRequest",
+	opCompareResponse:       "CompareResponse",
+	opAbandonRequest:        "AbandonRequest",
+	opExtendedRequest:       "ExtendedRequest",
+	opExtendedResponse:      "ExtendedResponse",
+	opIntermediateResponse:  "IntermediateResponse",
+}
+
+// responseOf gives the operation ending the response to a request
+var responseOf = map[byte]byte{
+	opBindRequest:     opBindResponse,
+	opSearchRequest:   opSearchResultDone,
+	opModifyRequest:   opModifyResponse,
+	opAddRequest:      opAddResponse,
+	opDelRequest:      opDelResponse,
+	opModifyDNRequest: opModifyDNResponse,
+	opCompareRequest:  opCompareResponse,
+	opExtendedRequest: opExtendedResponse,
+}
+
+var scopes = []string{"baseObject", "singleLevel", "wholeSubtree", "subordinateSubtree"}
+
+// message is an LDAPMessage read from a connection.
+type message struct {
+	id      int64
+	op      element
+	decoded models.LDAPMessage
+}
+
+// isFinal reports whether the message ends the response to a request, the entries, references and intermediate
+// responses are followed by other messages.
+func (m message) isFinal() bool {
+	switch m.op.tag {
+	case opSearchResultEntry, opSearchResultReference, opIntermediateResponse:
+		return false
+	default:
+		return true
+	}
+}
+
+// splitMessages decodes the whole messages starting the buffer and returns the count of bytes they span.
+func splitMessages(buffer []byte) ([]message, int, error) {
+	var (
+		messages []message
+		consumed int
+	)
+	for consumed < len(buffer) {
+		envelope, n, err := readElement(buffer[consumed:])
+		if errors.Is(err, errIncomplete) {
+			break
+		}
+		if err != nil {
+			return nil, 0, err
+		}
+		m, err := decodeMessage(envelope)
+		if err != nil {
+			return nil, 0, err
+		}
+		messages = append(messages, m)
+		consumed += n
+	}
+	return messages, consumed, nil
+}
+
+func decodeMessage(envelope element) (message, error) {
+	if envelope.tag != tagSequence {
+		return message{}, fmt.Errorf("unexpected tag 0x%02x for an ldap message", envelope.tag)
+	}
+	parts, err := envelope.children()
+	if err != nil {
+		return message{}, err
+	}
+	if len(parts) < 2 || parts[0].tag != tagInteger {
+		return message{}, errors.New("ldap message without message id or operation")
+	}
+	id, err := parts[0].integer()
+	if err != nil {
+		return message{}, fmt.Errorf("invalid message id: %v", err)
+	}
+
+	m := message{
+		id: id,
+		op: parts[1],
+		decoded: models.LDAPMessage{
+			MessageID: id,
+			Operation: operationNames[parts[1].tag],
+		},
+	}
+	if m.decoded.Operation == "" {
+		m.decoded.Operation = fmt.Sprintf("0x%02x", parts[1].tag)
+	}
+	if len(parts) > 2 && parts[2].tag == tagControls {
+		m.decoded.Controls = base64.StdEncoding.EncodeToString(parts[2].raw)
+	}
+	if err := decodeOperation(&m.decoded, m.op); err != nil {
+		return message{}, fmt.Errorf("failed to decode the %s: %v", m.decoded.Operation, err)
+	}
+	// the bind requests hold the credentials, they are matched on their decoded fields
+	if m.op.tag != opBindRequest {
+		m.decoded.Payload = base64.StdEncoding.EncodeToString(m.op.raw)
+	}
+	return m, nil
+}
+
+// decodeOperation fills the fields of the message decoded from its operation.
+func decodeOperation(decoded *models.LDAPMessage, op element) error {
+	switch op.tag {
+	case opUnbindRequest, opAbandonRequest:
+		return nil
+	case opDelRequest:
+		decoded.DN = string(op.content)
+		return nil
+	}
+
+	fields, err := op.children()
+	if err != nil {
+		return err
+	}
+	switch op.tag {
+	case opBindRequest:
+		if len(fields) < 3 {
+			return errors.New("missing fields")
+		}
+		decoded.DN = string(fields[1].content)
+		switch fields[2].tag {
+		case 0x80:
+			decoded.AuthMethod = "simple"
+		case 0xa3:
+			decoded.AuthMethod = "sasl"
+			sasl, err := fields[2].children()
+			if err != nil {
+				return err
+			}
+			if len(sasl) > 0 {
+				decoded.Mechanism = string(sasl[0].content)
+			}
+		}
+	case opSearchRequest:
+		if len(fields) < 8 {
+			return errors.New("missing fields")
+		}
+		decoded.DN = string(fields[0].content)
+		scope, err := fields[1].integer()
+		if err != nil {
+			return err
+		}
+		if scope >= 0 && int(scope) < len(scopes) {
+			decoded.Scope = scopes[scope]
+		}
+		decoded.Filter, err = renderFilter(fields[6])
+		if err != nil {
+			return err
+		}
+		attributes, err := fields[7].children()
+		if err != nil {
+			return err
+		}
+		for _, attribute := range attributes {
+			decoded.Attributes = append(decoded.Attributes, string(attribute.content))
+		}
+	case opModifyRequest, opAddRequest, opModifyDNRequest, opCompareRequest:
+		if len(fields) > 0 {
+			decoded.DN = string(fields[0].content)
+		}
+	case opExtendedRequest, opIntermediateResponse:
+		for _, field := range fields {
+			if field.tag == 0x80 {
+				decoded.Name = string(field.content)
+			}
+		}
+	case opBindResponse, opSearchResultDone, opModifyResponse, opAddResponse, opDelResponse, opModifyDNResponse,
+		opCompareResponse, opExtendedResponse:
+		if len(fields) < 3 {
+			return errors.New("missing fields of the result")
+		}
+		code, err := fields[0].integer()
+		if err != nil {
+			return err
+		}
+		resultCode := int(code)
+		decoded.ResultCode = &resultCode
+		decoded.MatchedDN = string(fields[1].content)
+		decoded.DiagnosticMessage = string(fields[2].content)
+		for _, field := range fields[3:] {
+			if field.tag == 0x8a {
+				decoded.Name = string(field.content)
+			}
+		}
+	case opSearchResultEntry:
+		if len(fields) < 2 {
+			return errors.New("missing fields")
+		}
+		decoded.DN = string(fields[0].content)
+		attributes, err := fields[1].children()
+		if err != nil {
+			return err
+		}
+		for _, attribute := range attributes {
+			parts, err := attribute.children()
+			if err != nil {
+				return err
+			}
+			if len(parts) < 2 {
+				return errors.New("attribute without values")
+			}
+			values, err := parts[1].children()
+			if err != nil {
+				return err
+			}
+			decodedAttribute := models.LDAPAttribute{Type: string(parts[0].content), Values: []string{}}
+			for _, value := range values {
+				decodedAttribute.Values = append(decodedAttribute.Values, printableValue(value.content))
+			}
+			decoded.Entry = append(decoded.Entry, decodedAttribute)
+		}
+	}
+	return nil
+}
+
+// filterOperators gives the operator of the filters comparing an attribute with a value
+var filterOperators = map[byte]string{
+	0xa3: "=",
+	0xa5: ">=",
+	0xa6: "<=",
+	0xa8: "~=",
+}
+
+// renderFilter renders a search filter in its string representation (RFC 4515).
+func renderFilter(filter element) (string, error) {
+	switch filter.tag {
+	case 0x87:
+		return "(" + string(filter.content) + "=*)", nil
+	}
+
+	parts, err := filter.children()
+	if err != nil {
+		return "", err
+	}
+	switch filter.tag {
+	case 0xa0, 0xa1, 0xa2:
+		var b strings.Builder
+		b.WriteByte('(')
+		b.WriteByte("&|!"[filter.tag-0xa0])
+		for _, part := range parts {
+			rendered, err := renderFilter(part)
+			if err != nil {
+				return "", err
+			}
+			b.WriteString(rendered)
+		}
+		b.WriteByte(')')
+		return b.String(), nil
+	case 0xa3, 0xa5, 0xa6, 0xa8:
+		if len(parts) != 2 {
+			return "", errors.New("invalid attribute value assertion")
+		}
+		return "(" + string(parts[0].content) + filterOperators[filter.tag] + escapeFilterValue(parts[1].content) + ")", nil
+	case 0xa4:
+		if len(parts) != 2 {
+			return "", errors.New("invalid substrings filter")
+		}
+		substrings, err := parts[1].children()
+		if err != nil {
+			return "", err
+		}
+		var initial, final string
+		var middle []string
+		for _, substring := range substrings {
+			switch substring.tag {
+			case 0x80:
+				initial = escapeFilterValue(substring.content)
+			case 0x81:
+				middle = append(middle, escapeFilterValue(substring.content))
+			case 0x82:
+				final = escapeFilterValue(substring.content)
+			}
+		}
+		value := initial
+		for _, a := range middle {
+			value += "*" + a
+		}
+		return "(" + string(parts[0].content) + "=" + value + "*" + final + ")", nil
+	case 0xa9:
+		var rule, attribute, value string
+		var dnAttributes bool
+		for _, part := range parts {
+			switch part.tag {
+			case 0x81:
+				rule = string(part.content)
+			case 0x82:
+				attribute = string(part.content)
+			case 0x83:
+				value = escapeFilterValue(part.content)
+			case 0x84:
+				dnAttributes = len(part.content) > 0 && part.content[0] != 0
+			}
+		}
+		rendered := "(" + attribute
+		if dnAttributes {
+			rendered += ":dn"
+		}
+		if rule != "" {
+			rendered += ":" + rule
+		}
+		return rendered + ":=" + value + ")", nil
+	default:
+		return "", fmt.Errorf("unknown filter 0x%02x", filter.tag)
+	}
+}
+
+func escapeFilterValue(value []byte) string {
+	var b strings.Builder
+	for _, c := range value {
+		if c == '*' || c == '(' || c == ')' || c == '\\' || c < 0x20 || c >= 0x7f {
+			fmt.Fprintf(&b, "\\%02x", c)
+			continue
+		}
+		b.WriteByte(c)
+	}
+	return b.String()
+}
+
+func printableValue(value []byte) string {
+	if isAsciiPrintable(string(value)) {
+		return string(value)
+	}
+	return base64.StdEncoding.EncodeToString(value)
+}
+
+func isAsciiPrintable(s string) bool {
+	for _, r := range s {
+		if r > unicode.MaxASCII || !unicode.IsPrint(r) {
+			return false
+		}
+	}
+	return true
+}
+
+// encodeResponse encodes a recorded response with the message id of the request it answers.
+func encodeResponse(id int64, response models.LDAPMessage) ([]byte, error) {
+	op, err := base64.StdEncoding.DecodeString(response.Payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode the payload of the %s: %v", response.Operation, err)
+	}
+	controls, err := base64.StdEncoding.DecodeString(response.Controls)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode the controls of the %s: %v", response.Operation, err)
+	}
+	content := encodeInteger(tagInteger, id)
+	content = append(content, op...)
+	content = append(content, controls...)
+	return encodeElement(tagSequence, content), nil
+}
+
+// encodeResult encodes a response made of an LDAPResult, as sent for the requests which can't be matched.
+func encodeResult(id int64, op byte, code int64, diagnosticMessage string) []byte {
+	result := encodeInteger(tagEnumerated, code)
+	result = append(result, encodeElement(tagOctetString, nil)...)
+	result = append(result, encodeElement(tagOctetString, []byte(diagnosticMessage))...)
+	content := encodeInteger(tagInteger, id)
+	content = append(content, encodeElement(op, result)...)
+	return encodeElement(tagSequence, content)
+}
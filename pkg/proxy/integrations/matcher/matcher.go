@@ -0,0 +1,129 @@
+// Package matcher holds the rules tuning how the mocks of each integration are matched in test mode, set from the
+// matchers of the test config. The parsers look up the rule of the kind of their mocks rather than hard-coding which
+// fields of the requests are compared.
+package matcher
+
+import (
+	"strings"
+	"sync"
+
+	"go.keploy.io/server/pkg/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var (
+	mu    sync.RWMutex
+	rules = map[models.Kind]Rule{}
+)
+
+// Rule is the matching rule of the mocks of a kind. The zero rule matches the requests on all their fields, with the
+// default strategy of the parser.
+type Rule struct {
+	strategy string
+	fields   []string
+}
+
+// Configure sets the rules of the kinds, the last matcher of a kind winning.
+func Configure(matchers []models.MatcherRule) {
+	mu.Lock()
+	defer mu.Unlock()
+	rules = map[models.Kind]Rule{}
+	for _, m := range matchers {
+		rules[m.Kind] = Rule{strategy: m.Strategy, fields: m.IgnoreFields}
+	}
+}
+
+// For returns the rule of the mocks of the kind.
+func For(kind models.Kind) Rule {
+	mu.RLock()
+	defer mu.RUnlock()
+	return rules[kind]
+}
+
+// Strategy returns the strategy of the rule, the default one when it sets none.
+func (r Rule) Strategy(defaultStrategy string) string {
+	if r.strategy == "" {
+		return defaultStrategy
+	}
+	return r.strategy
+}
+
+// IgnoredFields returns the ignored fields of the rule starting with the prefix, e.g. "header.", without it. All of
+// them are returned when the prefix is empty.
+func (r Rule) IgnoredFields(prefix string) []string {
+	var fields []string
+	for _, field := range r.fields {
+		if strings.HasPrefix(field, prefix) {
+			fields = append(fields, strings.TrimPrefix(field, prefix))
+		}
+	}
+	return fields
+}
+
+// Ignores reports whether the field, e.g. "header.X-Request-Id", is ignored, the names being compared without case.
+func (r Rule) Ignores(field string) bool {
+	for _, ignored := range r.fields {
+		if strings.EqualFold(ignored, field) {
+			return true
+		}
+	}
+	return false
+}
+
+// StripDocument returns a copy of the document without the ignored fields starting with the prefix. The fields are
+// dotted paths into the nested documents, e.g. "lsid" or "filter.updatedAt".
+func (r Rule) StripDocument(document map[string]interface{}, prefix string) map[string]interface{} {
+	paths := r.IgnoredFields(prefix)
+	if len(paths) == 0 {
+		return document
+	}
+	stripped := make(map[string]interface{}, len(document))
+	for key, value := range document {
+		stripped[key] = value
+	}
+	for _, path := range paths {
+		keys := strings.Split(path, ".")
+		if len(keys) == 1 {
+			delete(stripped, keys[0])
+		} else if value, ok := stripped[keys[0]]; ok {
+			stripped[keys[0]] = removePath(value, keys[1:])
+		}
+	}
+	return stripped
+}
+
+// removePath returns the value without the field at the path, leaving the value it was copied from untouched. The
+// nested documents may be decoded as maps or as ordered bson documents.
+func removePath(value interface{}, path []string) interface{} {
+	switch document := value.(type) {
+	case map[string]interface{}:
+		copied := make(map[string]interface{}, len(document))
+		for key, nested := range document {
+			copied[key] = nested
+		}
+		if nested, ok := copied[path[0]]; ok {
+			if len(path) == 1 {
+				delete(copied, path[0])
+			} else {
+				copied[path[0]] = removePath(nested, path[1:])
+			}
+		}
+		return copied
+	case primitive.M:
+		return primitive.M(removePath(map[string]interface{}(document), path).(map[string]interface{}))
+	case primitive.D:
+		copied := make(primitive.D, 0, len(document))
+		for _, element := range document {
+			if element.Key == path[0] {
+				if len(path) == 1 {
+					continue
+				}
+				element.Value = removePath(element.Value, path[1:])
+			}
+			copied = append(copied, element)
+		}
+		return copied
+	default:
+		return value
+	}
+}
@@ -0,0 +1,317 @@
+package memcachedparser
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"go.keploy.io/server/pkg/models"
+)
+
+const (
+	magicRequest       = 0x80
+	magicResponse      = 0x81
+	binaryHeaderLength = 24
+)
+
+const (
+	opGet  = 0x00
+	opQuit = 0x07
+	opNoop = 0x0a
+	opGetk = 0x0c
+	opStat = 0x10
+	opGat  = 0x1d
+	opGatk = 0x23
+)
+
+const (
+	statusKeyNotFound   = 0x0001
+	statusInternalError = 0x0084
+)
+
+var opcodeNames = map[byte]string{
+	0x00: "get",
+	0x01: "set",
+	0x02: "add",
+	0x03: "replace",
+	0x04: "delete",
+	0x05: "increment",
+	0x06: "decrement",
+	0x07: "quit",
+	0x08: "flush",
+	0x09: "getq",
+	0x0a: "noop",
+	0x0b: "version",
+	0x0c: "getk",
+	0x0d: "getkq",
+	0x0e: "append",
+	0x0f: "prepend",
+	0x10: "stat",
+	0x11: "setq",
+	0x12: "addq",
+	0x13: "replaceq",
+	0x14: "deleteq",
+	0x15: "incrementq",
+	0x16: "decrementq",
+	0x17: "quitq",
+	0x18: "flushq",
+	0x19: "appendq",
+	0x1a: "prependq",
+	0x1b: "verbosity",
+	0x1c: "touch",
+	0x1d: "gat",
+	0x1e: "gatq",
+	0x20: "sasl_list_mechs",
+	0x21: "sasl_auth",
+	0x22: "sasl_step",
+	0x23: "gatk",
+	0x24: "gatkq",
+}
+
+var opcodes = func() map[string]byte {
+	codes := map[string]byte{}
+	for code, name := range opcodeNames {
+		codes[name] = code
+	}
+	return codes
+}()
+
+// quiet commands, the server only answers their failures (and the hits of the quiet gets)
+var quietOpcodes = map[byte]bool{
+	0x09: true,
+	0x0d: true,
+	0x11: true,
+	0x12: true,
+	0x13: true,
+	0x14: true,
+	0x15: true,
+	0x16: true,
+	0x17: true,
+	0x18: true,
+	0x19: true,
+	0x1a: true,
+	0x1e: true,
+	0x24: true,
+}
+
+// binaryPacket is a request or response packet of the binary protocol, the status field holds the vbucket id in
+// the requests.
+type binaryPacket struct {
+	magic    byte
+	opcode   byte
+	dataType byte
+	status   uint16
+	opaque   uint32
+	cas      uint64
+	extras   []byte
+	key      []byte
+	value    []byte
+}
+
+// isBinaryRequest reports whether the buffer starts with a whole request packet of the binary protocol.
+func isBinaryRequest(buffer []byte) bool {
+	if len(buffer) < binaryHeaderLength || buffer[0] != magicRequest {
+		return false
+	}
+	if _, ok := opcodeNames[buffer[1]]; !ok {
+		return false
+	}
+	keyLength := int(binary.BigEndian.Uint16(buffer[2:4]))
+	extrasLength := int(buffer[4])
+	bodyLength := int(binary.BigEndian.Uint32(buffer[8:12]))
+	return keyLength+extrasLength <= bodyLength && binaryHeaderLength+bodyLength <= len(buffer)
+}
+
+// splitBinaryPackets returns the whole packets starting the buffer along with the count of bytes they span.
+func splitBinaryPackets(buffer []byte) ([]binaryPacket, int, error) {
+	var (
+		packets  []binaryPacket
+		consumed int
+	)
+	for len(buffer)-consumed >= binaryHeaderLength {
+		header := buffer[consumed : consumed+binaryHeaderLength]
+		if header[0] != magicRequest && header[0] != magicResponse {
+			return nil, 0, fmt.Errorf("invalid magic 0x%02x of a memcached packet", header[0])
+		}
+		keyLength := int(binary.BigEndian.Uint16(header[2:4]))
+		extrasLength := int(header[4])
+		bodyLength := int(binary.BigEndian.Uint32(header[8:12]))
+		if keyLength+extrasLength > bodyLength {
+			return nil, 0, fmt.Errorf("memcached packet body of %d bytes too short for its key and extras", bodyLength)
+		}
+		if len(buffer)-consumed < binaryHeaderLength+bodyLength {
+			break
+		}
+		body := buffer[consumed+binaryHeaderLength : consumed+binaryHeaderLength+bodyLength]
+		packets = append(packets, binaryPacket{
+			magic:    header[0],
+			opcode:   header[1],
+			dataType: header[5],
+			status:   binary.BigEndian.Uint16(header[6:8]),
+			opaque:   binary.BigEndian.Uint32(header[12:16]),
+			cas:      binary.BigEndian.Uint64(header[16:24]),
+			extras:   body[:extrasLength],
+			key:      body[extrasLength : extrasLength+keyLength],
+			value:    body[extrasLength+keyLength:],
+		})
+		consumed += binaryHeaderLength + bodyLength
+	}
+	return packets, consumed, nil
+}
+
+func (p binaryPacket) encode() []byte {
+	bodyLength := len(p.extras) + len(p.key) + len(p.value)
+	encoded := make([]byte, binaryHeaderLength, binaryHeaderLength+bodyLength)
+	encoded[0] = p.magic
+	encoded[1] = p.opcode
+	binary.BigEndian.PutUint16(encoded[2:4], uint16(len(p.key)))
+	encoded[4] = byte(len(p.extras))
+	encoded[5] = p.dataType
+	binary.BigEndian.PutUint16(encoded[6:8], p.status)
+	binary.BigEndian.PutUint32(encoded[8:12], uint32(bodyLength))
+	binary.BigEndian.PutUint32(encoded[12:16], p.opaque)
+	binary.BigEndian.PutUint64(encoded[16:24], p.cas)
+	encoded = append(encoded, p.extras...)
+	encoded = append(encoded, p.key...)
+	return append(encoded, p.value...)
+}
+
+// isFinal reports whether the response ends the responses to its request, the stat command gets a packet per
+// statistic and an empty one at the end.
+func (p binaryPacket) isFinal() bool {
+	return p.opcode != opStat || p.status != 0 || (len(p.key) == 0 && len(p.value) == 0)
+}
+
+func opcodeName(opcode byte) string {
+	if name, ok := opcodeNames[opcode]; ok {
+		return name
+	}
+	return fmt.Sprintf("0x%02x", opcode)
+}
+
+func opcodeOf(name string) (byte, error) {
+	if opcode, ok := opcodes[name]; ok {
+		return opcode, nil
+	}
+	opcode, err := strconv.ParseUint(strings.TrimPrefix(name, "0x"), 16, 8)
+	if err != nil {
+		return 0, fmt.Errorf("unknown memcached opcode %q", name)
+	}
+	return byte(opcode), nil
+}
+
+func decodeBinaryRequest(p binaryPacket) models.MemcachedRequest {
+	request := models.MemcachedRequest{
+		Protocol: protocolBinary,
+		Command:  opcodeName(p.opcode),
+		Cas:      p.cas,
+	}
+	if len(p.key) > 0 {
+		request.Keys = []string{string(p.key)}
+	}
+	if len(p.extras) > 0 {
+		request.Extras = base64.StdEncoding.EncodeToString(p.extras)
+	}
+	if len(p.value) > 0 {
+		request.Value = encodeValue(p.value)
+	}
+	return request
+}
+
+func decodeBinaryResponse(p binaryPacket) models.MemcachedResponse {
+	response := models.MemcachedResponse{
+		Opcode: opcodeName(p.opcode),
+		Status: p.status,
+		Key:    string(p.key),
+		Cas:    p.cas,
+	}
+	if len(p.extras) > 0 {
+		response.Extras = base64.StdEncoding.EncodeToString(p.extras)
+	}
+	if len(p.value) > 0 {
+		response.Value = encodeValue(p.value)
+	}
+	return response
+}
+
+// encodeBinaryResponse encodes a recorded response with the opaque of the request it answers.
+func encodeBinaryResponse(response models.MemcachedResponse, opaque uint32) ([]byte, error) {
+	opcode, err := opcodeOf(response.Opcode)
+	if err != nil {
+		return nil, err
+	}
+	extras, err := base64.StdEncoding.DecodeString(response.Extras)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode the extras of the %s response: %v", response.Opcode, err)
+	}
+	var value []byte
+	if response.Value != nil {
+		value, err = decodeValue(response.Value)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return binaryPacket{
+		magic:  magicResponse,
+		opcode: opcode,
+		status: response.Status,
+		opaque: opaque,
+		cas:    response.Cas,
+		extras: extras,
+		key:    []byte(response.Key),
+		value:  value,
+	}.encode(), nil
+}
+
+// unmatchedBinaryResponse is sent for the commands which can't be matched: the gets miss, the noop succeeds and
+// the other commands get an internal error. The quiet commands aren't answered.
+func unmatchedBinaryResponse(request binaryPacket) []byte {
+	if quietOpcodes[request.opcode] {
+		return nil
+	}
+	response := binaryPacket{
+		magic:  magicResponse,
+		opcode: request.opcode,
+		opaque: request.opaque,
+	}
+	switch request.opcode {
+	case opNoop:
+	case opGet, opGetk, opGat, opGatk:
+		response.status = statusKeyNotFound
+		response.value = []byte("Not found")
+	default:
+		response.status = statusInternalError
+		response.value = []byte("no mock matched the " + opcodeName(request.opcode) + " command")
+	}
+	return response.encode()
+}
+
+func encodeValue(value []byte) *models.OutputBinary {
+	if isAsciiPrintable(string(value)) {
+		return &models.OutputBinary{Type: models.String, Data: string(value)}
+	}
+	return &models.OutputBinary{Type: "binary", Data: base64.StdEncoding.EncodeToString(value)}
+}
+
+func decodeValue(value *models.OutputBinary) ([]byte, error) {
+	if value.Type == models.String {
+		return []byte(value.Data), nil
+	}
+	data, err := base64.StdEncoding.DecodeString(value.Data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode the memcached value: %v", err)
+	}
+	return data, nil
+}
+
+func isAsciiPrintable(s string) bool {
+	for _, r := range s {
+		if r > unicode.MaxASCII || (!unicode.IsPrint(r) && r != '\r' && r != '\n' && r != '\t') {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,82 @@
+package memcachedparser
+
+import (
+	"fmt"
+
+	"go.keploy.io/server/pkg/hooks"
+	"go.keploy.io/server/pkg/models"
+)
+
+// match finds the mock recorded for the command and removes it from the mocks of the test. It returns nil when no
+// mock matches.
+func match(h *hooks.Hook, request models.MemcachedRequest) (*models.Mock, error) {
+	for {
+		tcsMocks, err := h.GetTcsMocks()
+		if err != nil {
+			return nil, fmt.Errorf("error while getting tcs mocks %v", err)
+		}
+
+		var (
+			best      *models.Mock
+			bestScore = -1
+		)
+		for _, mock := range tcsMocks {
+			if mock == nil || mock.Kind != models.Memcached || len(mock.Spec.MemcachedRequests) != 1 {
+				continue
+			}
+			score, ok := matchScore(mock.Spec.MemcachedRequests[0], request)
+			if ok && score > bestScore {
+				best, bestScore = mock, score
+			}
+		}
+		if best == nil {
+			return nil, nil
+		}
+
+		isDeleted, err := h.DeleteTcsMock(best)
+		if err != nil {
+			return nil, fmt.Errorf("error while deleting tcs mock: %v", err)
+		}
+		if !isDeleted {
+			// another connection consumed the mock in the meantime
+			continue
+		}
+		return best, nil
+	}
+}
+
+// matchScore reports whether the recorded command answers the command, and how close they are. The protocol, the
+// command and the keys have to be the same, then a mock storing the same value is preferred, then one having the
+// same arguments (flags, expiration time, delta).
+func matchScore(recorded, request models.MemcachedRequest) (int, bool) {
+	if recorded.Protocol != request.Protocol || recorded.Command != request.Command || !equalStrings(recorded.Keys, request.Keys) {
+		return 0, false
+	}
+	score := 0
+	if sameValue(recorded.Value, request.Value) {
+		score += 2
+	}
+	if equalStrings(recorded.Args, request.Args) && recorded.Extras == request.Extras {
+		score++
+	}
+	return score, true
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func sameValue(a, b *models.OutputBinary) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
@@ -0,0 +1,365 @@
+package memcachedparser
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"time"
+
+	"go.keploy.io/server/pkg"
+	"go.keploy.io/server/pkg/hooks"
+	"go.keploy.io/server/pkg/metrics"
+	"go.keploy.io/server/pkg/models"
+	"go.keploy.io/server/pkg/proxy/util"
+	"go.keploy.io/server/utils"
+	"go.uber.org/zap"
+)
+
+type MemcachedParser struct {
+	logger *zap.Logger
+	hooks  *hooks.Hook
+}
+
+func NewMemcachedParser(logger *zap.Logger, h *hooks.Hook) *MemcachedParser {
+	return &MemcachedParser{
+		logger: logger,
+		hooks:  h,
+	}
+}
+
+// OutgoingType determines if the outgoing network call is memcached by checking that the buffer starts with a
+// request packet of the binary protocol or a command line of the text protocol.
+func (m *MemcachedParser) OutgoingType(buffer []byte) bool {
+	return isBinaryRequest(buffer) || isTextCommand(buffer)
+}
+
+func (m *MemcachedParser) ProcessOutgoing(requestBuffer []byte, clientConn, destConn net.Conn, ctx context.Context) {
+	switch models.GetMode() {
+	case models.MODE_RECORD:
+		encodeMemcachedOutgoing(requestBuffer, clientConn, destConn, m.hooks, m.logger, ctx)
+	case models.MODE_TEST:
+		decodeMemcachedOutgoing(requestBuffer, clientConn, m.hooks, m.logger)
+	default:
+		m.logger.Info("Invalid mode detected while intercepting outgoing memcached call", zap.Any("mode", models.GetMode()))
+	}
+}
+
+type pendingRequest struct {
+	request   models.MemcachedRequest
+	responses []models.MemcachedResponse
+	timestamp time.Time
+	// opcode and opaque of a binary request, which its responses carry
+	opcode byte
+	opaque uint32
+}
+
+// encodeMemcachedOutgoing forwards the traffic between the client and the server and records a mock for every
+// command along with its response. The responses come in the order of the commands: the text commands sent with
+// noreply have no response, and the quiet binary commands are known to be answered by nothing once a response to a
+// later command comes. The connections using quiet meta commands are forwarded without being recorded.
+func encodeMemcachedOutgoing(requestBuffer []byte, clientConn, destConn net.Conn, h *hooks.Hook, logger *zap.Logger, ctx context.Context) error {
+	_, err := destConn.Write(requestBuffer)
+	if err != nil {
+		logger.Error("failed to write request message to the destination server", zap.Error(err))
+		return err
+	}
+
+	clientBufferChannel := make(chan []byte)
+	destBufferChannel := make(chan []byte)
+	errChannel := make(chan error)
+	// read requests from client
+	go func() {
+		// Recover from panic and gracefully shutdown
+		defer h.Recover(pkg.GenerateRandomID())
+		defer utils.HandlePanic()
+		readBuffConn(clientConn, clientBufferChannel, errChannel, logger, h)
+	}()
+	// read responses from destination
+	go func() {
+		// Recover from panic and gracefully shutdown
+		defer h.Recover(pkg.GenerateRandomID())
+		defer utils.HandlePanic()
+		readBuffConn(destConn, destBufferChannel, errChannel, logger, h)
+	}()
+
+	var (
+		binaryProtocol = isBinaryRequest(requestBuffer)
+		requests       []byte
+		responses      []byte
+		pending        []*pendingRequest
+		recording      = true
+	)
+	save := func(p *pendingRequest) {
+		h.AppendMocks(&models.Mock{
+			Version: models.GetVersion(),
+			Name:    "mocks",
+			Kind:    models.Memcached,
+			Spec: models.MockSpec{
+				MemcachedRequests:  []models.MemcachedRequest{p.request},
+				MemcachedResponses: p.responses,
+				ReqTimestampMock:   p.timestamp,
+				ResTimestampMock:   time.Now(),
+			},
+		}, ctx)
+	}
+	stopRecording := func(msg string, err error) {
+		logger.Error(msg+", forwarding the rest of the connection", zap.Error(err))
+		recording = false
+	}
+	recordRequests := func(buffer []byte) {
+		requests = append(requests, buffer...)
+		if binaryProtocol {
+			packets, consumed, err := splitBinaryPackets(requests)
+			if err != nil {
+				stopRecording("failed to split the memcached requests", err)
+				return
+			}
+			requests = requests[consumed:]
+			for _, p := range packets {
+				pending = append(pending, &pendingRequest{request: decodeBinaryRequest(p), timestamp: time.Now(), opcode: p.opcode, opaque: p.opaque})
+			}
+			return
+		}
+		for len(requests) > 0 {
+			request, consumed, err := readTextRequest(requests)
+			if errors.Is(err, errIncomplete) {
+				return
+			}
+			if err != nil {
+				stopRecording("failed to decode the memcached command", err)
+				return
+			}
+			requests = requests[consumed:]
+			if isQuietMeta(request) {
+				logger.Warn("the memcached connection is forwarded without being recorded after a quiet meta command", zap.String("command", request.Command))
+				recording = false
+				return
+			}
+			p := &pendingRequest{request: request, timestamp: time.Now()}
+			if !expectsReply(request) {
+				if request.Command != "quit" {
+					save(p)
+				}
+				continue
+			}
+			pending = append(pending, p)
+		}
+	}
+	recordResponses := func(buffer []byte) {
+		responses = append(responses, buffer...)
+		if binaryProtocol {
+			packets, consumed, err := splitBinaryPackets(responses)
+			if err != nil {
+				stopRecording("failed to split the memcached responses", err)
+				return
+			}
+			responses = responses[consumed:]
+			for _, p := range packets {
+				// the quiet commands sent before the one answered got no response
+				for len(pending) > 0 && (pending[0].opaque != p.opaque || pending[0].opcode != p.opcode) && quietOpcodes[pending[0].opcode] {
+					save(pending[0])
+					pending = pending[1:]
+				}
+				if len(pending) == 0 {
+					logger.Debug("no memcached request found for the response", zap.String("opcode", opcodeName(p.opcode)))
+					continue
+				}
+				pending[0].responses = append(pending[0].responses, decodeBinaryResponse(p))
+				if p.isFinal() {
+					save(pending[0])
+					pending = pending[1:]
+				}
+			}
+			return
+		}
+		for len(pending) > 0 && len(responses) > 0 {
+			decoded, consumed, err := readTextResponse(responses, pending[0].request.Command)
+			if errors.Is(err, errIncomplete) {
+				return
+			}
+			if err != nil {
+				stopRecording("failed to decode the memcached response", err)
+				return
+			}
+			responses = responses[consumed:]
+			pending[0].responses = decoded
+			save(pending[0])
+			pending = pending[1:]
+		}
+	}
+
+	recordRequests(requestBuffer)
+	for {
+		select {
+		case buffer := <-clientBufferChannel:
+			_, err := destConn.Write(buffer)
+			if err != nil {
+				logger.Error("failed to write request message to the destination server", zap.Error(err))
+				return err
+			}
+			if recording {
+				recordRequests(buffer)
+			}
+		case buffer := <-destBufferChannel:
+			_, err := clientConn.Write(buffer)
+			if err != nil {
+				logger.Error("failed to write response to the client", zap.Error(err))
+				return err
+			}
+			if recording {
+				recordResponses(buffer)
+			}
+		case err := <-errChannel:
+			return err
+		}
+	}
+}
+
+func readBuffConn(conn net.Conn, bufferChannel chan []byte, errChannel chan error, logger *zap.Logger, h *hooks.Hook) error {
+	for {
+		buffer, err := util.ReadBytes(conn)
+		if err != nil {
+			if !h.IsUsrAppTerminateInitiated() {
+				if err == io.EOF {
+					logger.Debug("EOF error received from client. Closing connection in memcached !!")
+				} else if !strings.Contains(err.Error(), "use of closed network connection") {
+					logger.Error("failed to read the packet message in proxy for memcached dependency", zap.Error(err))
+				}
+				errChannel <- err
+			}
+			return err
+		}
+		bufferChannel <- buffer
+	}
+}
+
+// decodeMemcachedOutgoing replies to the commands of the client with the responses of their mocks, the binary
+// responses carry the opaque of their request. The commands which can't be matched aren't passed through, a cache
+// being optional for most of the applications: the gets miss and the other commands fail.
+func decodeMemcachedOutgoing(requestBuffer []byte, clientConn net.Conn, h *hooks.Hook, logger *zap.Logger) error {
+	var (
+		buffer         = requestBuffer
+		binaryProtocol = isBinaryRequest(requestBuffer)
+	)
+	for {
+		var (
+			response []byte
+			quit     bool
+			err      error
+		)
+		if binaryProtocol {
+			response, quit, err = replyBinary(&buffer, h, logger)
+		} else {
+			response, quit, err = replyText(&buffer, h, logger)
+		}
+		if err != nil {
+			return err
+		}
+		if len(response) > 0 {
+			_, err = clientConn.Write(response)
+			if err != nil {
+				logger.Error("failed to write the memcached response to the client application", zap.Error(err))
+				return err
+			}
+		}
+		if quit {
+			logger.Debug("quit received from client. Closing connection in memcached !!")
+			return nil
+		}
+
+		read, err := util.ReadBytes(clientConn)
+		if len(read) == 0 && err != nil {
+			if err == io.EOF {
+				logger.Debug("EOF error received from client. Closing connection in memcached !!")
+				return nil
+			}
+			logger.Error("failed to read the request message in proxy for memcached dependency", zap.Error(err))
+			return err
+		}
+		buffer = append(buffer, read...)
+	}
+}
+
+// replyBinary builds the responses to the whole binary requests of the buffer, which it consumes. It reports
+// whether the client asked to close the connection.
+func replyBinary(buffer *[]byte, h *hooks.Hook, logger *zap.Logger) ([]byte, bool, error) {
+	packets, consumed, err := splitBinaryPackets(*buffer)
+	if err != nil {
+		logger.Error("failed to split the memcached requests", zap.Error(err))
+		return nil, false, err
+	}
+	*buffer = (*buffer)[consumed:]
+
+	var response []byte
+	for _, p := range packets {
+		request := decodeBinaryRequest(p)
+		mock, err := match(h, request)
+		if err != nil {
+			logger.Error("failed to match the memcached command with the mocks", zap.Error(err))
+			return nil, false, err
+		}
+		if mock == nil {
+			metrics.MatchFailures.Inc("memcached")
+			logger.Debug("no mock matched the memcached command", zap.String("command", request.Command), zap.Strings("keys", request.Keys))
+			response = append(response, unmatchedBinaryResponse(p)...)
+		} else {
+			for _, recorded := range mock.Spec.MemcachedResponses {
+				encoded, err := encodeBinaryResponse(recorded, p.opaque)
+				if err != nil {
+					logger.Error("failed to encode the memcached response of the mock", zap.Error(err), zap.String("mock", mock.Name))
+					return nil, false, err
+				}
+				response = append(response, encoded...)
+			}
+		}
+		if p.opcode == opQuit || p.opcode == opcodes["quitq"] {
+			return response, true, nil
+		}
+	}
+	return response, false, nil
+}
+
+// replyText builds the responses to the whole text commands of the buffer, which it consumes. It reports whether
+// the client asked to close the connection.
+func replyText(buffer *[]byte, h *hooks.Hook, logger *zap.Logger) ([]byte, bool, error) {
+	var response []byte
+	for len(*buffer) > 0 {
+		request, consumed, err := readTextRequest(*buffer)
+		if errors.Is(err, errIncomplete) {
+			break
+		}
+		if err != nil {
+			logger.Error("failed to decode the memcached command", zap.Error(err))
+			return nil, false, err
+		}
+		*buffer = (*buffer)[consumed:]
+		if request.Command == "quit" {
+			return response, true, nil
+		}
+
+		mock, err := match(h, request)
+		if err != nil {
+			logger.Error("failed to match the memcached command with the mocks", zap.Error(err))
+			return nil, false, err
+		}
+		responses := unmatchedTextResponse(request)
+		if mock != nil {
+			responses = mock.Spec.MemcachedResponses
+		} else {
+			metrics.MatchFailures.Inc("memcached")
+			logger.Debug("no mock matched the memcached command", zap.String("command", request.Command), zap.Strings("keys", request.Keys))
+		}
+		if !expectsReply(request) || (mock == nil && isQuietMeta(request)) {
+			continue
+		}
+		encoded, err := encodeTextResponses(responses)
+		if err != nil {
+			logger.Error("failed to encode the memcached response", zap.Error(err))
+			return nil, false, err
+		}
+		response = append(response, encoded...)
+	}
+	return response, false, nil
+}
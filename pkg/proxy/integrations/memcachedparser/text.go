@@ -0,0 +1,227 @@
+package memcachedparser
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"go.keploy.io/server/pkg/models"
+)
+
+const (
+	protocolText   = "text"
+	protocolBinary = "binary"
+)
+
+var errIncomplete = errors.New("incomplete memcached message")
+
+// commands of the text protocol, including the meta commands
+var textCommands = map[string]bool{
+	"get": true, "gets": true, "gat": true, "gats": true,
+	"set": true, "add": true, "replace": true, "append": true, "prepend": true, "cas": true,
+	"delete": true, "incr": true, "decr": true, "touch": true,
+	"stats": true, "version": true, "flush_all": true, "verbosity": true, "quit": true,
+	"mg": true, "ms": true, "md": true, "ma": true, "mn": true, "me": true,
+}
+
+// retrieval commands, whose response holds a VALUE line per key found and ends with END
+var retrievalCommands = map[string]bool{
+	"get":  true,
+	"gets": true,
+	"gat":  true,
+	"gats": true,
+}
+
+// isTextCommand reports whether the buffer starts with a whole command line of the text protocol.
+func isTextCommand(buffer []byte) bool {
+	end := bytes.Index(buffer, []byte("\r\n"))
+	if end <= 0 {
+		return false
+	}
+	tokens := strings.Fields(string(buffer[:end]))
+	return len(tokens) > 0 && textCommands[tokens[0]]
+}
+
+// readLine returns the line starting the buffer without its line ending, along with the count of bytes it spans.
+func readLine(buffer []byte) (string, int, error) {
+	end := bytes.IndexByte(buffer, '\n')
+	if end < 0 {
+		return "", 0, errIncomplete
+	}
+	return strings.TrimRight(string(buffer[:end]), "\r"), end + 1, nil
+}
+
+// readDataBlock reads the data block of the given length, followed by a line ending, starting the buffer.
+func readDataBlock(buffer []byte, length string) ([]byte, int, error) {
+	size, err := strconv.Atoi(length)
+	if err != nil || size < 0 {
+		return nil, 0, fmt.Errorf("invalid length %q of a data block", length)
+	}
+	if len(buffer) < size+2 {
+		return nil, 0, errIncomplete
+	}
+	return buffer[:size], size + 2, nil
+}
+
+// readTextRequest decodes the command starting the buffer, along with its data block for the storage commands.
+func readTextRequest(buffer []byte) (models.MemcachedRequest, int, error) {
+	line, consumed, err := readLine(buffer)
+	if err != nil {
+		return models.MemcachedRequest{}, 0, err
+	}
+	tokens := strings.Fields(line)
+	if len(tokens) == 0 {
+		return models.MemcachedRequest{}, 0, errors.New("empty memcached command")
+	}
+	request := models.MemcachedRequest{
+		Protocol: protocolText,
+		Command:  tokens[0],
+	}
+	args := tokens[1:]
+	// the token giving the length of the data block sent after the command line
+	dataLength := ""
+	switch request.Command {
+	case "get", "gets":
+		request.Keys = args
+	case "gat", "gats":
+		if len(args) < 2 {
+			return request, 0, fmt.Errorf("missing arguments of the %s command", request.Command)
+		}
+		request.Args, request.Keys = args[:1], args[1:]
+	case "set", "add", "replace", "append", "prepend", "cas":
+		if len(args) < 4 {
+			return request, 0, fmt.Errorf("missing arguments of the %s command", request.Command)
+		}
+		request.Keys, request.Args = args[:1], args[1:]
+		dataLength = args[3]
+	case "ms":
+		if len(args) < 2 {
+			return request, 0, errors.New("missing arguments of the ms command")
+		}
+		request.Keys, request.Args = args[:1], args[1:]
+		dataLength = args[1]
+	case "delete", "incr", "decr", "touch", "mg", "md", "ma", "me":
+		if len(args) < 1 {
+			return request, 0, fmt.Errorf("missing key of the %s command", request.Command)
+		}
+		request.Keys, request.Args = args[:1], args[1:]
+	default:
+		request.Args = args
+	}
+	if len(request.Args) == 0 {
+		request.Args = nil
+	}
+
+	if dataLength != "" {
+		data, n, err := readDataBlock(buffer[consumed:], dataLength)
+		if err != nil {
+			return request, 0, err
+		}
+		request.Value = encodeValue(data)
+		consumed += n
+	}
+	return request, consumed, nil
+}
+
+// expectsReply reports whether the server answers the command, the commands sent with noreply and quit have no
+// response.
+func expectsReply(request models.MemcachedRequest) bool {
+	if request.Command == "quit" {
+		return false
+	}
+	return len(request.Args) == 0 || request.Args[len(request.Args)-1] != "noreply"
+}
+
+// isQuietMeta reports whether a meta command is sent in the quiet mode, where only its failures are answered.
+func isQuietMeta(request models.MemcachedRequest) bool {
+	if !strings.HasPrefix(request.Command, "m") || len(request.Command) != 2 {
+		return false
+	}
+	for _, flag := range request.Args {
+		if flag == "q" {
+			return true
+		}
+	}
+	return false
+}
+
+// readTextResponse decodes the response to a command starting the buffer. The responses of the retrieval and
+// stats commands span several lines ending with END, the other ones are a single line, followed by a data block
+// for the VA line of the meta commands.
+func readTextResponse(buffer []byte, command string) ([]models.MemcachedResponse, int, error) {
+	var (
+		responses []models.MemcachedResponse
+		consumed  int
+	)
+	for {
+		line, n, err := readLine(buffer[consumed:])
+		if err != nil {
+			return nil, 0, err
+		}
+		next := consumed + n
+		response := models.MemcachedResponse{Line: line}
+		tokens := strings.Fields(line)
+
+		dataLength := ""
+		switch {
+		case len(tokens) >= 4 && tokens[0] == "VALUE":
+			dataLength = tokens[3]
+		case len(tokens) >= 2 && tokens[0] == "VA":
+			dataLength = tokens[1]
+		}
+		if dataLength != "" {
+			data, n, err := readDataBlock(buffer[next:], dataLength)
+			if err != nil {
+				return nil, 0, err
+			}
+			response.Value = encodeValue(data)
+			next += n
+		}
+		responses = append(responses, response)
+		consumed = next
+
+		if !retrievalCommands[command] && command != "stats" {
+			return responses, consumed, nil
+		}
+		if len(tokens) > 0 {
+			switch tokens[0] {
+			case "END", "ERROR", "CLIENT_ERROR", "SERVER_ERROR":
+				return responses, consumed, nil
+			}
+		}
+	}
+}
+
+func encodeTextResponses(responses []models.MemcachedResponse) ([]byte, error) {
+	var buf bytes.Buffer
+	for _, response := range responses {
+		buf.WriteString(response.Line)
+		buf.WriteString("\r\n")
+		if response.Value != nil {
+			data, err := decodeValue(response.Value)
+			if err != nil {
+				return nil, err
+			}
+			buf.Write(data)
+			buf.WriteString("\r\n")
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+// unmatchedTextResponse is sent for the commands which can't be matched: the retrievals miss and the other
+// commands get a server error.
+func unmatchedTextResponse(request models.MemcachedRequest) []models.MemcachedResponse {
+	switch {
+	case retrievalCommands[request.Command]:
+		return []models.MemcachedResponse{{Line: "END"}}
+	case request.Command == "mg":
+		return []models.MemcachedResponse{{Line: "EN"}}
+	case request.Command == "mn":
+		return []models.MemcachedResponse{{Line: "MN"}}
+	default:
+		return []models.MemcachedResponse{{Line: "SERVER_ERROR no mock matched the " + request.Command + " command"}}
+	}
+}
@@ -0,0 +1,126 @@
+package mongoparser
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/golang/snappy"
+	"github.com/klauspost/compress/zstd"
+	"go.mongodb.org/mongo-driver/x/mongo/driver/wiremessage"
+)
+
+// the OP_COMPRESSED body starts with the original opcode (4 bytes), the size of the uncompressed body (4 bytes)
+// and the id of the compressor (1 byte)
+const (
+	headerLength           = 16
+	compressedHeaderLength = 9
+)
+
+// compressorOf returns the compressor of a wire message wrapped in an OP_COMPRESSED. The drivers compress their
+// requests once a compressor is negotiated through the handshake, and expect the replies to be compressed alike.
+func compressorOf(wm []byte) (wiremessage.CompressorID, bool) {
+	if len(wm) < headerLength+compressedHeaderLength || wiremessage.OpCode(binary.LittleEndian.Uint32(wm[12:16])) != wiremessage.OpCompressed {
+		return 0, false
+	}
+	return wiremessage.CompressorID(wm[headerLength+8]), true
+}
+
+// decompressWireMessage unwraps an OP_COMPRESSED into the wire message it carries, keeping the request id and the
+// responseTo of the header.
+func decompressWireMessage(wm []byte) ([]byte, error) {
+	if len(wm) < headerLength+compressedHeaderLength {
+		return nil, errors.New("malformed OP_COMPRESSED: insufficient bytes")
+	}
+	body := wm[headerLength:]
+	originalOpcode := body[0:4]
+	uncompressedSize := int(int32(binary.LittleEndian.Uint32(body[4:8])))
+	compressor := wiremessage.CompressorID(body[8])
+	payload := body[compressedHeaderLength:]
+
+	var (
+		decompressed []byte
+		err          error
+	)
+	switch compressor {
+	case wiremessage.CompressorNoOp:
+		decompressed = payload
+	case wiremessage.CompressorSnappy:
+		decompressed, err = snappy.Decode(nil, payload)
+	case wiremessage.CompressorZLib:
+		var reader io.ReadCloser
+		reader, err = zlib.NewReader(bytes.NewReader(payload))
+		if err == nil {
+			defer reader.Close()
+			decompressed, err = io.ReadAll(reader)
+		}
+	case wiremessage.CompressorZstd:
+		var decoder *zstd.Decoder
+		decoder, err = zstd.NewReader(nil)
+		if err == nil {
+			defer decoder.Close()
+			decompressed, err = decoder.DecodeAll(payload, make([]byte, 0, uncompressedSize))
+		}
+	default:
+		return nil, fmt.Errorf("unknown compressor %d in OP_COMPRESSED", compressor)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress the OP_COMPRESSED with the compressor %d: %v", compressor, err)
+	}
+	if len(decompressed) != uncompressedSize {
+		return nil, fmt.Errorf("decompressed %d bytes instead of %d from the OP_COMPRESSED", len(decompressed), uncompressedSize)
+	}
+
+	message := make([]byte, 0, headerLength+len(decompressed))
+	message = binary.LittleEndian.AppendUint32(message, uint32(headerLength+len(decompressed)))
+	message = append(message, wm[4:12]...)
+	message = append(message, originalOpcode...)
+	return append(message, decompressed...), nil
+}
+
+// compressWireMessage wraps a wire message into an OP_COMPRESSED using the given compressor.
+func compressWireMessage(wm []byte, compressor wiremessage.CompressorID) ([]byte, error) {
+	if len(wm) < headerLength {
+		return nil, errors.New("malformed wire message: insufficient bytes")
+	}
+	body := wm[headerLength:]
+
+	var compressed []byte
+	switch compressor {
+	case wiremessage.CompressorNoOp:
+		compressed = body
+	case wiremessage.CompressorSnappy:
+		compressed = snappy.Encode(nil, body)
+	case wiremessage.CompressorZLib:
+		var buf bytes.Buffer
+		writer := zlib.NewWriter(&buf)
+		if _, err := writer.Write(body); err != nil {
+			return nil, err
+		}
+		if err := writer.Close(); err != nil {
+			return nil, err
+		}
+		compressed = buf.Bytes()
+	case wiremessage.CompressorZstd:
+		encoder, err := zstd.NewWriter(nil)
+		if err != nil {
+			return nil, err
+		}
+		defer encoder.Close()
+		compressed = encoder.EncodeAll(body, nil)
+	default:
+		return nil, fmt.Errorf("unknown compressor %d", compressor)
+	}
+
+	message := make([]byte, 0, headerLength+compressedHeaderLength+len(compressed))
+	message = binary.LittleEndian.AppendUint32(message, uint32(headerLength+compressedHeaderLength+len(compressed)))
+	message = append(message, wm[4:12]...)
+	message = binary.LittleEndian.AppendUint32(message, uint32(wiremessage.OpCompressed))
+	message = append(message, wm[12:16]...)
+	message = binary.LittleEndian.AppendUint32(message, uint32(len(body)))
+	message = append(message, byte(compressor))
+	return append(message, compressed...), nil
+}
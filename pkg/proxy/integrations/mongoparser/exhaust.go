@@ -0,0 +1,33 @@
+package mongoparser
+
+import (
+	"encoding/binary"
+
+	"go.keploy.io/server/pkg/models"
+)
+
+// splitWireMessages splits the buffer into the complete wire messages it starts with, and returns the bytes of
+// the message which isn't complete yet.
+func splitWireMessages(buffer []byte) ([][]byte, []byte) {
+	var messages [][]byte
+	for len(buffer) >= 4 {
+		length := int(binary.LittleEndian.Uint32(buffer[0:4]))
+		if length < 16 {
+			// malformed, decoding the message reports it
+			return append(messages, buffer), nil
+		}
+		if len(buffer) < length {
+			break
+		}
+		messages = append(messages, buffer[:length])
+		buffer = buffer[length:]
+	}
+	return messages, buffer
+}
+
+// isMoreToCome reports whether the moreToCome flag of an OP_MSG is set: the peer sends another message without
+// waiting for an answer, as the server does for the replies of a request allowing exhaust.
+func isMoreToCome(message interface{}) bool {
+	msg, ok := message.(*models.MongoOpMessage)
+	return ok && hasSecondSetBit(msg.FlagBits)
+}
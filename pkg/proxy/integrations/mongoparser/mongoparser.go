@@ -14,7 +14,9 @@ import (
 
 	"go.keploy.io/server/pkg"
 	"go.keploy.io/server/pkg/hooks"
+	"go.keploy.io/server/pkg/metrics"
 	"go.keploy.io/server/pkg/models"
+	"go.keploy.io/server/pkg/proxy/integrations/matcher"
 	"go.keploy.io/server/pkg/proxy/util"
 	"go.keploy.io/server/utils"
 	"go.mongodb.org/mongo-driver/bson"
@@ -215,6 +217,13 @@ func decodeOutgoingMongo(requestBuffer []byte, clientConn, destConn net.Conn, h
 				switch mongoResponse.Header.Opcode {
 				case wiremessage.OpReply:
 					replySpec := mongoResponse.Message.(*models.MongoOpReply)
+					if actualQuery, ok := mongoRequests[0].Message.(*models.MongoOpQuery); ok {
+						replySpec, err = handleSpeculativeAuthReply(actualQuery, configMocks[bestMatchIndex].Spec.MongoRequests[0].Message, replySpec, logger)
+						if err != nil {
+							logger.Error("failed to handle the speculative authentication of the hello", zap.Error(err), zap.Any("for request with id", responseTo))
+							return
+						}
+					}
 					replyMessage, err := encodeOpReply(replySpec, logger)
 					if err != nil {
 						logger.Error("failed to encode the recorded OpReply yaml", zap.Error(err), zap.Any("for request with id", responseTo))
@@ -271,6 +280,7 @@ func decodeOutgoingMongo(requestBuffer []byte, clientConn, destConn net.Conn, h
 			isMatched, matchedMock, err := match(h, mongoRequests, logger)
 
 			if !isMatched {
+				metrics.MatchFailures.Inc("mongo")
 				requestBuffer, err = util.Passthrough(clientConn, destConn, requestBuffers, h.Recover, logger)
 				if err != nil {
 					return
@@ -281,7 +291,12 @@ func decodeOutgoingMongo(requestBuffer []byte, clientConn, destConn net.Conn, h
 			responseTo := mongoRequests[0].Header.RequestID
 			logger.Debug("the mock matched with the current request", zap.Any("mock", matchedMock), zap.Any("responseTo", responseTo))
 
-			for _, resp := range matchedMock.Spec.MongoResponses {
+			for i, resp := range matchedMock.Spec.MongoResponses {
+				if i > 0 {
+					// the replies of an exhaust cursor are streamed at the pace they were recorded, as for the
+					// events of a change stream
+					time.Sleep(time.Duration(resp.ReadDelay))
+				}
 				respMessage := resp.Message.(*models.MongoOpMessage)
 				expectedRequestSections := []string{}
 				if len(matchedMock.Spec.MongoRequests) > 0 {
@@ -293,7 +308,16 @@ func decodeOutgoingMongo(requestBuffer []byte, clientConn, destConn net.Conn, h
 					return
 				}
 				requestId := wiremessage.NextRequestID()
-				_, err = clientConn.Write(message.Encode(responseTo, requestId))
+				response := message.Encode(responseTo, requestId)
+				// the reply is compressed like the request, as the driver negotiated a compressor
+				if compressor, ok := compressorOf(requestBuffer); ok {
+					response, err = compressWireMessage(response, compressor)
+					if err != nil {
+						logger.Error("failed to compress the recorded OpMsg response", zap.Error(err), zap.Any("for request with id", responseTo))
+						return
+					}
+				}
+				_, err = clientConn.Write(response)
 				if err != nil {
 					logger.Error("failed to write the health check opmsg to mongo client", zap.Error(err), zap.Any("for request with id", responseTo))
 					return
@@ -408,107 +432,90 @@ func encodeOutgoingMongo(requestBuffer []byte, clientConn, destConn net.Conn, h
 			}
 		}
 
-		// read reply message from the mongo server
-		// tmpStr := ""
+		// read the reply messages from the mongo server. The server streams several replies for a request allowing
+		// exhaust (exhaust cursors, awaitable hello), each one having the moreToCome flag set except the last.
 		reqTimestampMock := time.Now()
-		started := time.Now()
-		responseBuffer, err := util.ReadBytes(destConn)
-		logger.Debug("reading from the destination mongo server", zap.Any("", string(responseBuffer)))
-		// logStr += tmpStr
-		if err != nil {
-			if err == io.EOF {
-				logger.Debug("recieved response buffer is empty in record mode for mongo call")
-				destConn.Close()
-				return
-			}
-			logger.Error("failed to read reply from the mongo server", zap.Error(err), zap.String("mongo server address", destConn.RemoteAddr().String()))
-			return
-		}
-		readResponseDelay := time.Since(started)
-
-		// write the reply to mongo client
-		_, err = clientConn.Write(responseBuffer)
-		if err != nil {
-			logger.Error("failed to write the reply message to mongo client", zap.Error(err))
-			return
-		}
-
-		// logStr += fmt.Sprintln("after writting response to the client: ", time.Since(started), "current time is: ", time.Now())
-
-		_, responseHeader, mongoResponse, err := Decode(responseBuffer, logger)
-		if err != nil {
-			logger.Error("failed to decode the mongo wire message from the destination server", zap.Error(err))
-			return
+		var (
+			responseBuffer []byte
+			streamBuffer   []byte
+			lastResponse   interface{}
+			heartBeat      = isHeartBeat(opReq, *mongoRequests[0].Header, mongoRequests[0].Message, logger)
+			recorded       bool
+		)
+		record := func() {
+			recorded = true
+			go func(mongoResponses []models.MongoResponse) {
+				// Recover from panic and gracefully shutdown
+				defer h.Recover(pkg.GenerateRandomID())
+				defer utils.HandlePanic()
+				recordMessage(h, requestBuffer, responseBuffer, mongoRequests, mongoResponses, opReq, ctx, reqTimestampMock, logger)
+			}(mongoResponses)
 		}
-		mongoResponses = append(mongoResponses, models.MongoResponse{
-			Header:    &responseHeader,
-			Message:   mongoResponse,
-			ReadDelay: int64(readResponseDelay),
-		})
-		if val, ok := mongoResponse.(*models.MongoOpMessage); ok && hasSecondSetBit(val.FlagBits) {
-			for i := 0; ; i++ {
-				if i == 0 && isHeartBeat(opReq, *mongoRequests[0].Header, mongoRequests[0].Message, logger) {
-					go func() {
-						// Recover from panic and gracefully shutdown
-						defer h.Recover(pkg.GenerateRandomID())
-						defer utils.HandlePanic()
-						recordMessage(h, requestBuffer, responseBuffer, mongoRequests, mongoResponses, opReq, ctx, reqTimestampMock, logger)
-					}()
-				}
-				started = time.Now()
-				responseBuffer, err = util.ReadBytes(destConn)
-				// logStr += tmpStr
-				if err != nil {
-					if err == io.EOF {
-						logger.Debug("recieved response buffer is empty in record mode for mongo call")
-						destConn.Close()
-						return
-					}
-					logger.Error("failed to read reply from the mongo server", zap.Error(err), zap.String("mongo server address", destConn.RemoteAddr().String()))
-					return
+		for {
+			started := time.Now()
+			responseBuffer, err = util.ReadBytes(destConn)
+			logger.Debug("reading from the destination mongo server", zap.Any("", string(responseBuffer)))
+			if err != nil {
+				// the replies streamed until the connection closed are kept
+				if len(mongoResponses) > 0 && !recorded {
+					record()
 				}
-				logger.Debug(fmt.Sprintf("the response in the mongo parser before passing to client: %v", len(responseBuffer)))
-
-				readResponseDelay := time.Since(started)
-
-				// write the reply to mongo client
-				_, err = clientConn.Write(responseBuffer)
-				if err != nil {
-					// fmt.Println(logStr)
-					logger.Error("failed to write the reply message to mongo client", zap.Error(err))
+				if err == io.EOF {
+					logger.Debug("recieved response buffer is empty in record mode for mongo call")
+					destConn.Close()
 					return
 				}
-				logger.Debug(fmt.Sprintf("the response in the mongo parser after passing to client: %v", len(responseBuffer)))
+				logger.Error("failed to read reply from the mongo server", zap.Error(err), zap.String("mongo server address", destConn.RemoteAddr().String()))
+				return
+			}
+			readResponseDelay := time.Since(started)
 
-				// logStr += fmt.Sprintln("after writting response to the client: ", time.Since(started), "current time is: ", time.Now())
+			// write the reply to mongo client
+			_, err = clientConn.Write(responseBuffer)
+			if err != nil {
+				logger.Error("failed to write the reply message to mongo client", zap.Error(err))
+				return
+			}
 
-				if len(responseBuffer) == 0 {
-					logger.Debug("the response from the server is complete")
-					break
-				}
-				_, respHeader, mongoResp, err := Decode(responseBuffer, logger)
+			var messages [][]byte
+			streamBuffer = append(streamBuffer, responseBuffer...)
+			messages, streamBuffer = splitWireMessages(streamBuffer)
+			for i, message := range messages {
+				_, responseHeader, mongoResponse, err := Decode(message, logger)
 				if err != nil {
 					logger.Error("failed to decode the mongo wire message from the destination server", zap.Error(err))
 					return
 				}
-				if mongoRespVal, ok := mongoResp.(models.MongoOpMessage); ok && !hasSecondSetBit(mongoRespVal.FlagBits) {
-					logger.Debug("the response from the server is complete since the more_to_come flagbit is 0")
-					break
+				lastResponse = mongoResponse
+				// the awaitable hello streams the topology as long as the connection is open, only its first reply is kept
+				if recorded {
+					continue
+				}
+				delay := readResponseDelay
+				if i > 0 {
+					// the replies read in the same buffer were sent together
+					delay = 0
 				}
 				mongoResponses = append(mongoResponses, models.MongoResponse{
-					Header:    &respHeader,
-					Message:   mongoResp,
-					ReadDelay: int64(readResponseDelay),
+					Header:    &responseHeader,
+					Message:   mongoResponse,
+					ReadDelay: int64(delay),
 				})
 			}
+			if len(streamBuffer) > 0 || lastResponse == nil {
+				// the reply spans several reads
+				continue
+			}
+			if !isMoreToCome(lastResponse) {
+				break
+			}
+			if heartBeat && !recorded {
+				record()
+			}
+		}
+		if !recorded {
+			record()
 		}
-
-		go func() {
-			// Recover from panic and gracefully shutdown
-			defer h.Recover(pkg.GenerateRandomID())
-			defer utils.HandlePanic()
-			recordMessage(h, requestBuffer, responseBuffer, mongoRequests, mongoResponses, opReq, ctx, reqTimestampMock, logger)
-		}()
 		requestBuffer = []byte("read form client connection")
 
 	}
@@ -645,6 +652,8 @@ func compareOpMsgSection(expectedSection, actualSection string, logger *zap.Logg
 		if len(expectedMsgs) != len(actualMsgs) || expectedIdentifier != actualIdentifier {
 			return 0
 		}
+		// the fields ignored by the matcher of the mongo mocks, like the session ids, aren't compared
+		rule := matcher.For(models.Mongo)
 		score := 0.0
 		for i := range expectedMsgs {
 			expected := map[string]interface{}{}
@@ -659,7 +668,7 @@ func compareOpMsgSection(expectedSection, actualSection string, logger *zap.Logg
 				logger.Error("failed to unmarshal the section of incoming request to bson document", zap.Error(err))
 				return 0
 			}
-			score += calculateMatchingScore(expected, actual)
+			score += calculateMatchingScore(rule.StripDocument(expected, ""), rule.StripDocument(actual, ""))
 		}
 		logger.Debug("the matching score for sectionSequence", zap.Any("", score))
 		return score
@@ -696,6 +705,8 @@ func compareOpMsgSection(expectedSection, actualSection string, logger *zap.Logg
 			logger.Error("failed to unmarshal the section of incoming request to bson document", zap.Error(err))
 			return 0
 		}
+		rule := matcher.For(models.Mongo)
+		expected, actual = rule.StripDocument(expected, ""), rule.StripDocument(actual, "")
 		logger.Debug("the expected and actual msg in the single section.", zap.Any("expected", expected), zap.Any("actual", actual), zap.Any("score", calculateMatchingScore(expected, actual)))
 		return calculateMatchingScore(expected, actual)
 
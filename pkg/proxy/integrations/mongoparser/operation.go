@@ -61,6 +61,14 @@ func Decode(wm []byte, logger *zap.Logger) (Operation, models.MongoHeader, inter
 	if !ok || int(length) > wmLength {
 		return nil, messageHeader, &models.MongoOpMessage{}, errors.New("malformed wire message: insufficient bytes")
 	}
+	// the compressed messages are decoded as the message they carry
+	if opCode == wiremessage.OpCompressed {
+		decompressed, err := decompressWireMessage(wm[:length])
+		if err != nil {
+			return nil, messageHeader, &models.MongoOpMessage{}, err
+		}
+		return Decode(decompressed, logger)
+	}
 
 	var (
 		op       Operation
@@ -5,10 +5,9 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"strconv"
 	"strings"
 
-	"go.keploy.io/server/pkg/proxy/integrations/scram"
+	"go.keploy.io/server/pkg/models"
 	"go.uber.org/zap"
 )
 
@@ -37,10 +36,6 @@ func isScramAuthRequest(actualRequestSections []string, logger *zap.Logger) bool
 	return false
 }
 
-// authMessageMap stores the auth message from the saslStart request for the converstionIds. So, that
-// it can be used in the saslContinue request to generate the new server proof
-var authMessageMap map[string]string = map[string]string{}
-
 // handleScramAuth handles the SCRAM authentication requests by generating the
 // appropriate response string.
 //
@@ -83,6 +78,9 @@ func handleScramAuth(actualRequestSections, expectedRequestSections []string, re
 			if _, exists := actualMsg["payload"]; exists {
 				return handleSaslContinue(actualMsg, responseSection, logger)
 			}
+			// Check if the hello starts the SASL process speculatively
+		} else if _, exists := actualMsg["speculativeAuthenticate"]; exists {
+			return handleSpeculativeAuthSection(i, actualMsg, expectedRequestSections, responseSection, logger)
 		}
 	}
 	return "", false, nil
@@ -257,29 +255,18 @@ func handleSaslStart(i int, actualMsg map[string]interface{}, expectedRequestSec
 	}
 	logger.Debug(fmt.Sprint("the decoded payload of the repsonse for the saslstart: ", (string)(decodedResponsePayload)))
 
-	// Generate the first response for the saslStart request by
-	// replacing the old client nonce with new client nonce
-	newFirstAuthResponse, err := scram.GenerateServerFirstMessage(decodedExpectedReqPayload, decodedActualReqPayload, decodedResponsePayload, logger)
+	// Generate the first response for the saslStart request by replacing the old client nonce with new
+	// client nonce, the conversation is kept to generate the final response
+	mechanism, _ := actualMsg["mechanism"].(string)
+	newFirstAuthResponse, err := startScramConversation(mechanism, decodedExpectedReqPayload, decodedActualReqPayload, decodedResponsePayload, logger)
 	if err != nil {
+		logger.Error("failed to start the SCRAM conversation", zap.Error(err))
 		return "", false, err
 	}
 	logger.Debug("after replacing the new client nonce in auth response", zap.String("first response", newFirstAuthResponse))
 	// replace the payload with new first response auth
 	responseMsg["payload"].(map[string]interface{})["$binary"].(map[string]interface{})["base64"] = base64.StdEncoding.EncodeToString([]byte(newFirstAuthResponse))
 
-	// fetch the conversation id
-	conversationId, err := extractConversationId(responseMsg)
-	if err != nil {
-		logger.Error("failed to fetch the conversationId for the SCRAM auth from the recorded first response", zap.Error(err))
-		return "", false, err
-	}
-	logger.Debug("fetch the conversationId for the SCRAM authentication", zap.String("cid", conversationId))
-	// generate the auth message from the recieved first request and recorded first response
-	authMessage := scram.GenerateAuthMessage(string(decodedActualReqPayload), newFirstAuthResponse, logger)
-	// store the auth message in the global map for the conversationId
-	authMessageMap[conversationId] = authMessage
-	logger.Debug("genrate the new auth message for the recieved auth request", zap.String("msg", authMessage))
-
 	// marshal the new first response for the SCRAM authentication
 	newAuthResponse, err := json.Marshal(responseMsg)
 	if err != nil {
@@ -290,7 +277,7 @@ func handleSaslStart(i int, actualMsg map[string]interface{}, expectedRequestSec
 }
 
 // handleSaslContinue processes a SASL continuation message, updates the payload with
-// the new verifier, which is prepared by the new auth message.
+// the new verifier, which is computed from the messages of the conversation.
 //
 // Parameters:
 //   - actualMsg: The actual message map from the client.
@@ -302,9 +289,23 @@ func handleSaslStart(i int, actualMsg map[string]interface{}, expectedRequestSec
 //   - A boolean indicating if the processing was successful.
 //   - An error, if any, that occurred during processing.
 func handleSaslContinue(actualMsg map[string]interface{}, responseSection string, logger *zap.Logger) (string, bool, error) {
-	var responseMsg map[string]interface{}
+	actualReqPayload, err := extractAuthPayload(actualMsg)
+	if err != nil {
+		logger.Error("failed to fetch the payload from the recieved mongo request", zap.Error(err))
+		return "", false, err
+	}
+	decodedActualReqPayload, err := decodeBase64Str(actualReqPayload)
+	if err != nil {
+		logger.Error("Error decoding the recieved saslContinue payload base64 string:", zap.Error(err))
+		return "", false, err
+	}
+	// the empty exchange ending the conversation (without skipEmptyExchange) carries no proof
+	if !strings.Contains(string(decodedActualReqPayload), ",p=") {
+		return "", false, nil
+	}
 
-	err := json.Unmarshal([]byte(responseSection), &responseMsg)
+	var responseMsg map[string]interface{}
+	err = json.Unmarshal([]byte(responseSection), &responseMsg)
 	if err != nil {
 		logger.Error("failed to unmarshal string document of second auth response for SCRAM", zap.Error(err))
 		return "", false, err
@@ -316,74 +317,137 @@ func handleSaslContinue(actualMsg map[string]interface{}, responseSection string
 		logger.Error("failed to fetch the payload from the recorded mongo response", zap.Error(err))
 		return "", false, err
 	}
-	logger.Debug(fmt.Sprint("the payload of the recorded second response of SCRAM: ", responsePayload))
-
 	decodedResponsePayload, err := decodeBase64Str(responsePayload)
 	if err != nil {
 		logger.Error("Error decoding the recorded saslContinue response payload base64 string:", zap.Error(err))
 		return "", false, err
 	}
-	logger.Debug(fmt.Sprint("the decoded payload of the repsonse for the saslContinue: ", (string)(decodedResponsePayload)))
+	// a recorded authentication failure (e=...) is replayed as it is
+	if !strings.HasPrefix(string(decodedResponsePayload), "v=") {
+		return "", false, nil
+	}
 
-	fields := strings.Split(string(decodedResponsePayload), ",")
-	verifier, err := parseFieldBase64(fields[0], "v")
+	// Since, the server proof is the signature generated by the authMessage and salted password.
+	// So, need to return the new server proof according to the new authMessage which is different from the recorded.
+	serverFinal, err := finishScramConversation(string(decodedActualReqPayload), logger)
 	if err != nil {
-		logger.Error("failed to parse the verifier of final response message", zap.Error(err))
+		logger.Error("failed to get the new server proof", zap.Error(err))
 		return "", false, err
 	}
-	logger.Debug("the recorded verifier of the auth request", zap.Any("verifier/server-signature", string(verifier)))
 
-	// fetch the conversation id
-	conversationId, err := extractConversationId(responseMsg)
+	// update the payload of the mongo response for the authentication
+	responseMsg["payload"].(map[string]interface{})["$binary"].(map[string]interface{})["base64"] = base64.StdEncoding.EncodeToString([]byte(serverFinal))
+	byt, err := json.Marshal(responseMsg)
 	if err != nil {
-		logger.Error("failed to fetch the conversationId for the SCRAM auth from the recorded final response", zap.Error(err))
+		logger.Error("failed to marshal the updated string document of OpReply", zap.Error(err))
 		return "", false, err
 	}
-	logger.Debug("fetched conversationId for the SCRAM authentication", zap.String("cid", conversationId))
-
-	salt := ""
-	itr := 0
-	// get the authMessage from the saslStart conversation. Since, saslContinue have the same conversationId
-	authMsg := authMessageMap[conversationId]
-
-	// get the salt and iteration from the authMessage to generate salted password
-	fields = strings.Split(authMsg, ",")
-	for _, part := range fields {
-		if strings.HasPrefix(part, "s=") {
-			// Split based on "=" and get the value of "s"
-			saltByt, err := decodeBase64Str(strings.TrimPrefix(part, "s="))
-			if err != nil {
-				logger.Error("failed to convert the string into integer", zap.Error(err))
-				return "", false, err
-			}
-			salt = string(saltByt)
-		}
-		if strings.HasPrefix(part, "i=") {
-			// Split based on "=" and get the value of "i"
-			itr, err = strconv.Atoi(strings.Split(part, "=")[1])
-			if err != nil {
-				logger.Error("failed to convert the string into integer", zap.Error(err))
-				return "", false, err
-			}
-		}
+	return string(byt), true, nil
+}
+
+// handleSpeculativeAuthSection processes the speculative authentication of a hello sent with OP_MSG.
+func handleSpeculativeAuthSection(i int, actualMsg map[string]interface{}, expectedRequestSections []string, responseSection string, logger *zap.Logger) (string, bool, error) {
+	if len(expectedRequestSections) < i+1 {
+		err := errors.New("unrecorded message sections for the recieved hello")
+		logger.Error("failed to match the message section payload", zap.Error(err))
+		return "", false, err
 	}
-	// Since, the server proof is the signature generated by the authMessage and salted password.
-	// So, need to return the new server proof according to the new authMessage which is different from the recorded.
-	newVerifier, err := scram.GenerateServerFinalMessage(authMessageMap[conversationId], "SCRAM-SHA-1", password, salt, itr, logger)
+	expectedMsg, err := extractMsgFromSection(expectedRequestSections[i])
 	if err != nil {
-		logger.Error("failed to get the new server proof", zap.Error(err))
+		logger.Error("failed to extract the section of the recorded mongo request message", zap.Error(err))
+		return "", false, err
+	}
+	var responseMsg map[string]interface{}
+	err = json.Unmarshal([]byte(responseSection), &responseMsg)
+	if err != nil {
+		logger.Error("failed to unmarshal string document of the hello response", zap.Error(err))
 		return "", false, err
 	}
 
-	// update the payload of the mongo response for the authentication
-	responseMsg["payload"].(map[string]interface{})["$binary"].(map[string]interface{})["base64"] = base64.StdEncoding.EncodeToString([]byte("v=" + newVerifier))
+	ok, err := handleSpeculativeAuth(actualMsg, expectedMsg, responseMsg, logger)
+	if err != nil || !ok {
+		return "", false, err
+	}
 	byt, err := json.Marshal(responseMsg)
 	if err != nil {
-		logger.Error("failed to marshal the updated string document of OpReply", zap.Error(err))
+		logger.Error("failed to marshal the hello response with the new speculative authentication", zap.Error(err))
 		return "", false, err
 	}
-	responseSection = string(byt)
-	return responseSection, true, nil
+	return string(byt), true, nil
+}
+
+// handleSpeculativeAuthReply processes the speculative authentication of a legacy hello sent with OP_QUERY. The
+// recorded reply isn't modified, a copy is returned.
+func handleSpeculativeAuthReply(actualQuery *models.MongoOpQuery, expectedRequest interface{}, reply *models.MongoOpReply, logger *zap.Logger) (*models.MongoOpReply, error) {
+	expectedQuery, ok := expectedRequest.(*models.MongoOpQuery)
+	if !ok || len(reply.Documents) == 0 || !strings.Contains(actualQuery.Query, "speculativeAuthenticate") {
+		return reply, nil
+	}
+	msgs := make([]map[string]interface{}, 3)
+	for i, doc := range []string{actualQuery.Query, expectedQuery.Query, reply.Documents[0]} {
+		if err := json.Unmarshal([]byte(doc), &msgs[i]); err != nil {
+			logger.Error("failed to unmarshal the document of the legacy hello", zap.Error(err))
+			return nil, err
+		}
+	}
+	actualMsg, expectedMsg, responseMsg := msgs[0], msgs[1], msgs[2]
+
+	ok, err := handleSpeculativeAuth(actualMsg, expectedMsg, responseMsg, logger)
+	if err != nil || !ok {
+		return reply, err
+	}
+	byt, err := json.Marshal(responseMsg)
+	if err != nil {
+		logger.Error("failed to marshal the hello reply with the new speculative authentication", zap.Error(err))
+		return nil, err
+	}
+	updated := *reply
+	updated.Documents = append([]string{string(byt)}, reply.Documents[1:]...)
+	return &updated, nil
+}
+
+// handleSpeculativeAuth starts the SCRAM conversation carried by the speculativeAuthenticate field of a hello, the
+// saslStart of the client being embedded in the request and the first response of the server in the reply. The
+// server-first message of the reply is replaced in place. It reports whether the reply was updated.
+func handleSpeculativeAuth(actualMsg, expectedMsg, responseMsg map[string]interface{}, logger *zap.Logger) (bool, error) {
+	actualAuth, ok := actualMsg["speculativeAuthenticate"].(map[string]interface{})
+	if !ok {
+		return false, nil
+	}
+	mechanism, _ := actualAuth["mechanism"].(string)
+	if !strings.Contains(mechanism, "SCRAM") {
+		// the X.509 authentication has no conversation
+		return false, nil
+	}
+	expectedAuth, ok := expectedMsg["speculativeAuthenticate"].(map[string]interface{})
+	responseAuth, exists := responseMsg["speculativeAuthenticate"].(map[string]interface{})
+	if !ok || !exists {
+		// the reply has no speculative authentication, the client starts the conversation with saslStart
+		logger.Debug("the recorded hello reply has no speculative authentication")
+		return false, nil
+	}
+
+	payloads := make([][]byte, 3)
+	for i, auth := range []map[string]interface{}{actualAuth, expectedAuth, responseAuth} {
+		payload, err := extractAuthPayload(auth)
+		if err != nil {
+			logger.Error("failed to fetch the payload of the speculative authentication", zap.Error(err))
+			return false, err
+		}
+		payloads[i], err = decodeBase64Str(payload)
+		if err != nil {
+			logger.Error("Error decoding the payload base64 string of the speculative authentication:", zap.Error(err))
+			return false, err
+		}
+	}
+
+	serverFirst, err := startScramConversation(mechanism, payloads[1], payloads[0], payloads[2], logger)
+	if err != nil {
+		logger.Error("failed to start the speculative SCRAM conversation", zap.Error(err))
+		return false, err
+	}
+	responseAuth["payload"].(map[string]interface{})["$binary"].(map[string]interface{})["base64"] = base64.StdEncoding.EncodeToString([]byte(serverFirst))
+	return true, nil
 }
 
 func parseField(s, k string) (string, error) {
@@ -0,0 +1,115 @@
+package mongoparser
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+
+	"go.keploy.io/server/pkg/proxy/integrations/scram"
+	"go.uber.org/zap"
+)
+
+// scramConversation is a SCRAM conversation emulated in test mode. The client nonce changes on every run, so the
+// recorded messages of the server can't be replayed as they are: the server-first message is rebuilt with the new
+// nonce, and the server-final message is computed from the messages of the conversation, as the client checks the
+// signature of the server.
+type scramConversation struct {
+	mechanism       string
+	clientFirstBare string
+	serverFirst     string
+}
+
+var (
+	scramMu sync.Mutex
+	// scramConversations holds the conversations in progress by their nonce, which combines the nonces of the
+	// client and of the server and is repeated by the client in its final message. The conversations of
+	// concurrent connections are told apart this way, their conversationId being the same.
+	scramConversations = map[string]*scramConversation{}
+)
+
+// startScramConversation returns the server-first message answering the client-first message, built from the
+// recorded one, and keeps the conversation to answer the client-final message.
+func startScramConversation(mechanism string, recordedClientFirst, clientFirst, recordedServerFirst []byte, logger *zap.Logger) (string, error) {
+	if !strings.HasPrefix(mechanism, "SCRAM-SHA-") {
+		return "", fmt.Errorf("unsupported authentication mechanism %q", mechanism)
+	}
+	serverFirst, err := scram.GenerateServerFirstMessage(recordedClientFirst, clientFirst, recordedServerFirst, logger)
+	if err != nil {
+		return "", err
+	}
+	nonce, err := scramField(serverFirst, "r")
+	if err != nil {
+		return "", err
+	}
+	// the bare message follows the gs2 header, made of the channel binding flag and the authorization identity
+	header := strings.SplitN(string(clientFirst), ",", 3)
+	if len(header) != 3 {
+		return "", errors.New("malformed SCRAM client-first message")
+	}
+
+	scramMu.Lock()
+	defer scramMu.Unlock()
+	scramConversations[nonce] = &scramConversation{
+		mechanism:       mechanism,
+		clientFirstBare: header[2],
+		serverFirst:     serverFirst,
+	}
+	return serverFirst, nil
+}
+
+// finishScramConversation returns the server-final message answering the client-final message, which carries the
+// signature of the server computed with the password of the database.
+func finishScramConversation(clientFinal string, logger *zap.Logger) (string, error) {
+	nonce, err := scramField(clientFinal, "r")
+	if err != nil {
+		return "", err
+	}
+	scramMu.Lock()
+	conversation, ok := scramConversations[nonce]
+	delete(scramConversations, nonce)
+	scramMu.Unlock()
+	if !ok {
+		return "", errors.New("no SCRAM conversation was started for the client-final message")
+	}
+
+	proof := strings.LastIndex(clientFinal, ",p=")
+	if proof < 0 {
+		return "", errors.New("the SCRAM client-final message has no proof")
+	}
+	authMessage := conversation.clientFirstBare + "," + conversation.serverFirst + "," + clientFinal[:proof]
+
+	salt, err := scramField(conversation.serverFirst, "s")
+	if err != nil {
+		return "", err
+	}
+	decodedSalt, err := decodeBase64Str(salt)
+	if err != nil {
+		return "", fmt.Errorf("malformed salt in the SCRAM server-first message: %v", err)
+	}
+	iterations, err := scramField(conversation.serverFirst, "i")
+	if err != nil {
+		return "", err
+	}
+	itr, err := strconv.Atoi(iterations)
+	if err != nil {
+		return "", fmt.Errorf("malformed iteration count in the SCRAM server-first message: %v", err)
+	}
+
+	signature, err := scram.GenerateServerFinalMessage(authMessage, conversation.mechanism, password, string(decodedSalt), itr, logger)
+	if err != nil {
+		return "", err
+	}
+	return "v=" + signature, nil
+}
+
+// scramField returns the value of an attribute of a SCRAM message.
+func scramField(message, attribute string) (string, error) {
+	for _, field := range strings.Split(message, ",") {
+		if value, err := parseField(field, attribute); err == nil {
+			return value, nil
+		}
+	}
+	return "", fmt.Errorf("no '%s' attribute in the SCRAM message", attribute)
+}
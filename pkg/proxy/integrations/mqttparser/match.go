@@ -0,0 +1,133 @@
+package mqttparser
+
+import (
+	"fmt"
+
+	"go.keploy.io/server/pkg/hooks"
+	"go.keploy.io/server/pkg/models"
+)
+
+// match finds the mock recorded for the packet and removes it from the mocks of the test. It returns nil when no
+// mock matches.
+func match(h *hooks.Hook, request models.MQTTPacket) (*models.Mock, error) {
+	for {
+		tcsMocks, err := h.GetTcsMocks()
+		if err != nil {
+			return nil, fmt.Errorf("error while getting tcs mocks %v", err)
+		}
+
+		var (
+			best      *models.Mock
+			bestScore = -1
+		)
+		for _, mock := range tcsMocks {
+			if mock == nil || mock.Kind != models.MQTT || len(mock.Spec.MQTTRequests) != 1 {
+				continue
+			}
+			score, ok := matchScore(mock.Spec.MQTTRequests[0], request)
+			if ok && score > bestScore {
+				best, bestScore = mock, score
+			}
+		}
+		if best == nil {
+			return nil, nil
+		}
+
+		isDeleted, err := h.DeleteTcsMock(best)
+		if err != nil {
+			return nil, fmt.Errorf("error while deleting tcs mock: %v", err)
+		}
+		if !isDeleted {
+			// another connection consumed the mock in the meantime
+			continue
+		}
+		return best, nil
+	}
+}
+
+// matchScore reports whether the recorded packet answers the packet, and how close they are. A CONNECT is matched
+// on its protocol level, preferring the same client id then the same username since the clients often generate
+// their id. A PUBLISH needs the same topic and QoS, preferring the same payload, and a SUBSCRIBE or UNSUBSCRIBE the
+// same topic filters.
+func matchScore(recorded, request models.MQTTPacket) (int, bool) {
+	if recorded.Type != request.Type {
+		return 0, false
+	}
+	switch packetTypes[request.Type] {
+	case packetConnect:
+		if recorded.ProtocolLevel != request.ProtocolLevel {
+			return 0, false
+		}
+		score := 0
+		if recorded.ClientID == request.ClientID {
+			score += 2
+		}
+		if recorded.Username == request.Username {
+			score++
+		}
+		return score, true
+	case packetPublish:
+		if recorded.Topic != request.Topic || recorded.QoS != request.QoS {
+			return 0, false
+		}
+		if samePayload(recorded.Payload, request.Payload) {
+			return 1, true
+		}
+		return 0, true
+	case packetSubscribe, packetUnsubscribe:
+		if len(recorded.Subscriptions) != len(request.Subscriptions) {
+			return 0, false
+		}
+		for i := range recorded.Subscriptions {
+			if recorded.Subscriptions[i].Filter != request.Subscriptions[i].Filter {
+				return 0, false
+			}
+		}
+		return 0, true
+	}
+	return 0, false
+}
+
+func samePayload(a, b *models.OutputBinary) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+// matchDeliveries returns the messages recorded for the subscriptions, which are pushed by the broker once it
+// acknowledged them, and removes their mocks from the mocks of the test.
+func matchDeliveries(h *hooks.Hook, subscriptions []models.MQTTSubscription) ([]models.MQTTPacket, error) {
+	tcsMocks, err := h.GetTcsMocks()
+	if err != nil {
+		return nil, fmt.Errorf("error while getting tcs mocks %v", err)
+	}
+
+	var deliveries []models.MQTTPacket
+	for _, mock := range tcsMocks {
+		if mock == nil || mock.Kind != models.MQTT || len(mock.Spec.MQTTRequests) != 0 || len(mock.Spec.MQTTResponses) != 1 {
+			continue
+		}
+		delivery := mock.Spec.MQTTResponses[0]
+		if packetTypes[delivery.Type] != packetPublish || !subscribed(subscriptions, delivery.Topic) {
+			continue
+		}
+		isDeleted, err := h.DeleteTcsMock(mock)
+		if err != nil {
+			return nil, fmt.Errorf("error while deleting tcs mock: %v", err)
+		}
+		if isDeleted {
+			deliveries = append(deliveries, delivery)
+		}
+	}
+	return deliveries, nil
+}
+
+func subscribed(subscriptions []models.MQTTSubscription, topic string) bool {
+	for _, subscription := range subscriptions {
+		if topicMatches(subscription.Filter, topic) {
+			return true
+		}
+	}
+	return false
+}
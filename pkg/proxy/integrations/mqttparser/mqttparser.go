@@ -0,0 +1,420 @@
+package mqttparser
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"time"
+
+	"go.keploy.io/server/pkg"
+	"go.keploy.io/server/pkg/hooks"
+	"go.keploy.io/server/pkg/metrics"
+	"go.keploy.io/server/pkg/models"
+	"go.keploy.io/server/pkg/proxy/util"
+	"go.keploy.io/server/utils"
+	"go.uber.org/zap"
+)
+
+type MqttParser struct {
+	logger *zap.Logger
+	hooks  *hooks.Hook
+}
+
+func NewMqttParser(logger *zap.Logger, h *hooks.Hook) *MqttParser {
+	return &MqttParser{
+		logger: logger,
+		hooks:  h,
+	}
+}
+
+// OutgoingType determines if the outgoing network call is MQTT by checking that the buffer starts with the
+// CONNECT packet of the protocol 3.1.1 or 5.
+func (m *MqttParser) OutgoingType(buffer []byte) bool {
+	if len(buffer) < 2 || buffer[0] != packetConnect<<4 {
+		return false
+	}
+	_, n, err := readVarint(buffer[1:])
+	if err != nil {
+		return false
+	}
+	r := &reader{buf: buffer[1+n:]}
+	name := r.readString()
+	level := r.readByte()
+	return r.err == nil && name == "MQTT" && (level == version311 || level == version5)
+}
+
+func (m *MqttParser) ProcessOutgoing(requestBuffer []byte, clientConn, destConn net.Conn, ctx context.Context) {
+	switch models.GetMode() {
+	case models.MODE_RECORD:
+		encodeMqttOutgoing(requestBuffer, clientConn, destConn, m.hooks, m.logger, ctx)
+	case models.MODE_TEST:
+		decodeMqttOutgoing(requestBuffer, clientConn, destConn, m.hooks, m.logger)
+	default:
+		m.logger.Info("Invalid mode detected while intercepting outgoing mqtt call", zap.Any("mode", models.GetMode()))
+	}
+}
+
+type pendingRequest struct {
+	request   models.MQTTPacket
+	responses []models.MQTTPacket
+	timestamp time.Time
+}
+
+// encodeMqttOutgoing forwards the traffic between the client and the broker and records a mock for the CONNECT,
+// every PUBLISH, SUBSCRIBE and UNSUBSCRIBE along with the acknowledgement of the broker, paired through the packet
+// id. The messages delivered for the subscriptions are stored as mocks without request. The acknowledgements of the
+// deliveries, the pings and the DISCONNECT aren't recorded, the proxy answers them in test mode.
+func encodeMqttOutgoing(requestBuffer []byte, clientConn, destConn net.Conn, h *hooks.Hook, logger *zap.Logger, ctx context.Context) error {
+	_, err := destConn.Write(requestBuffer)
+	if err != nil {
+		logger.Error("failed to write request message to the destination server", zap.Error(err))
+		return err
+	}
+
+	clientBufferChannel := make(chan []byte)
+	destBufferChannel := make(chan []byte)
+	errChannel := make(chan error)
+	// read requests from client
+	go func() {
+		// Recover from panic and gracefully shutdown
+		defer h.Recover(pkg.GenerateRandomID())
+		defer utils.HandlePanic()
+		readBuffConn(clientConn, clientBufferChannel, errChannel, logger, h)
+	}()
+	// read responses from destination
+	go func() {
+		// Recover from panic and gracefully shutdown
+		defer h.Recover(pkg.GenerateRandomID())
+		defer utils.HandlePanic()
+		readBuffConn(destConn, destBufferChannel, errChannel, logger, h)
+	}()
+
+	var (
+		requests  []byte
+		responses []byte
+		version   byte = version311
+		connect   *pendingRequest
+		pending   = map[uint16]*pendingRequest{}
+		recording = true
+	)
+	save := func(p *pendingRequest) {
+		var requests []models.MQTTPacket
+		if p.request.Type != "" {
+			requests = []models.MQTTPacket{p.request}
+		}
+		h.AppendMocks(&models.Mock{
+			Version: models.GetVersion(),
+			Name:    "mocks",
+			Kind:    models.MQTT,
+			Spec: models.MockSpec{
+				MQTTRequests:     requests,
+				MQTTResponses:    p.responses,
+				ReqTimestampMock: p.timestamp,
+				ResTimestampMock: time.Now(),
+			},
+		}, ctx)
+	}
+	recordRequests := func(buffer []byte) {
+		requests = append(requests, buffer...)
+		packets, consumed, err := splitPackets(requests)
+		if err != nil {
+			logger.Error("failed to split the mqtt packets of the client, forwarding the rest of the connection", zap.Error(err))
+			recording = false
+			return
+		}
+		requests = requests[consumed:]
+		for _, p := range packets {
+			request, err := decodePacket(p, version)
+			if err != nil {
+				logger.Error("failed to decode the mqtt packet of the client, forwarding the rest of the connection", zap.Error(err))
+				recording = false
+				return
+			}
+			switch p.kind {
+			case packetConnect:
+				version = request.ProtocolLevel
+				connect = &pendingRequest{request: request, timestamp: time.Now()}
+			case packetPublish:
+				if request.QoS == 0 {
+					// nothing acknowledges the messages published with the QoS 0
+					save(&pendingRequest{request: request, timestamp: time.Now()})
+					continue
+				}
+				pending[request.PacketID] = &pendingRequest{request: request, timestamp: time.Now()}
+			case packetSubscribe, packetUnsubscribe:
+				pending[request.PacketID] = &pendingRequest{request: request, timestamp: time.Now()}
+			}
+		}
+	}
+	recordResponses := func(buffer []byte) {
+		responses = append(responses, buffer...)
+		packets, consumed, err := splitPackets(responses)
+		if err != nil {
+			logger.Error("failed to split the mqtt packets of the broker, forwarding the rest of the connection", zap.Error(err))
+			recording = false
+			return
+		}
+		responses = responses[consumed:]
+		for _, p := range packets {
+			response, err := decodePacket(p, version)
+			if err != nil {
+				logger.Error("failed to decode the mqtt packet of the broker, forwarding the rest of the connection", zap.Error(err))
+				recording = false
+				return
+			}
+			response.Body = base64.StdEncoding.EncodeToString(p.body)
+			switch p.kind {
+			case packetConnack:
+				if connect == nil {
+					logger.Debug("no mqtt CONNECT found for the CONNACK")
+					continue
+				}
+				connect.responses = append(connect.responses, response)
+				save(connect)
+				connect = nil
+			case packetPuback, packetPubrec, packetSuback, packetUnsuback:
+				acked, ok := pending[response.PacketID]
+				if !ok {
+					logger.Debug("no mqtt packet found for the acknowledgement", zap.String("type", response.Type), zap.Uint16("packet id", response.PacketID))
+					continue
+				}
+				delete(pending, response.PacketID)
+				acked.responses = append(acked.responses, response)
+				save(acked)
+			case packetPublish:
+				save(&pendingRequest{responses: []models.MQTTPacket{response}, timestamp: time.Now()})
+			}
+		}
+	}
+
+	recordRequests(requestBuffer)
+	for {
+		select {
+		case buffer := <-clientBufferChannel:
+			_, err := destConn.Write(buffer)
+			if err != nil {
+				logger.Error("failed to write request message to the destination server", zap.Error(err))
+				return err
+			}
+			if recording {
+				recordRequests(buffer)
+			}
+		case buffer := <-destBufferChannel:
+			_, err := clientConn.Write(buffer)
+			if err != nil {
+				logger.Error("failed to write response to the client", zap.Error(err))
+				return err
+			}
+			if recording {
+				recordResponses(buffer)
+			}
+		case err := <-errChannel:
+			return err
+		}
+	}
+}
+
+func readBuffConn(conn net.Conn, bufferChannel chan []byte, errChannel chan error, logger *zap.Logger, h *hooks.Hook) error {
+	for {
+		buffer, err := util.ReadBytes(conn)
+		if err != nil {
+			if !h.IsUsrAppTerminateInitiated() {
+				if err == io.EOF {
+					logger.Debug("EOF error received from client. Closing connection in mqtt !!")
+				} else if !strings.Contains(err.Error(), "use of closed network connection") {
+					logger.Error("failed to read the packet message in proxy for mqtt dependency", zap.Error(err))
+				}
+				errChannel <- err
+			}
+			return err
+		}
+		bufferChannel <- buffer
+	}
+}
+
+// decodeMqttOutgoing plays the broker: the CONNECT, PUBLISH, SUBSCRIBE and UNSUBSCRIBE packets get the
+// acknowledgements of their mocks, with the packet id of the request, and the messages recorded for the
+// subscriptions are delivered once they are acknowledged. The publications and subscriptions which can't be matched
+// are acknowledged by the proxy, as are the pings and the flows of the QoS 2. The connections whose CONNECT wasn't
+// recorded are forwarded to the broker.
+func decodeMqttOutgoing(requestBuffer []byte, clientConn, destConn net.Conn, h *hooks.Hook, logger *zap.Logger) error {
+	var (
+		buffer       = requestBuffer
+		version byte = version311
+	)
+	write := func(encoded []byte) error {
+		_, err := clientConn.Write(encoded)
+		if err != nil {
+			logger.Error("failed to write the mqtt packet to the client application", zap.Error(err))
+		}
+		return err
+	}
+	writeResponses := func(mock *models.Mock, packetID uint16) error {
+		for _, response := range mock.Spec.MQTTResponses {
+			encoded, err := encodeResponse(response, packetID)
+			if err != nil {
+				logger.Error("failed to encode the mqtt packet of the mock", zap.Error(err), zap.String("mock", mock.Name))
+				return err
+			}
+			if err := write(encoded); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	for {
+		packets, consumed, err := splitPackets(buffer)
+		if err != nil {
+			logger.Error("failed to split the mqtt packets of the client", zap.Error(err))
+			return err
+		}
+		received := buffer
+		buffer = buffer[consumed:]
+
+		for _, p := range packets {
+			request, err := decodePacket(p, version)
+			if err != nil {
+				logger.Error("failed to decode the mqtt packet of the client", zap.Error(err))
+				return err
+			}
+
+			switch p.kind {
+			case packetConnect:
+				version = request.ProtocolLevel
+				mock, err := match(h, request)
+				if err != nil {
+					logger.Error("failed to match the mqtt packet with the mocks", zap.Error(err))
+					return err
+				}
+				if mock == nil {
+					metrics.MatchFailures.Inc("mqtt")
+					logger.Debug("no mock found for the mqtt connection, forwarding it to the broker")
+					return forward(received, clientConn, destConn, h)
+				}
+				if err := writeResponses(mock, 0); err != nil {
+					return err
+				}
+			case packetPublish:
+				mock, err := match(h, request)
+				if err != nil {
+					logger.Error("failed to match the mqtt packet with the mocks", zap.Error(err))
+					return err
+				}
+				if mock == nil {
+					metrics.MatchFailures.Inc("mqtt")
+				}
+				switch {
+				case mock != nil:
+					err = writeResponses(mock, request.PacketID)
+				case request.QoS == 1:
+					err = write(encodeAck(packetPuback, request.PacketID))
+				case request.QoS == 2:
+					err = write(encodeAck(packetPubrec, request.PacketID))
+				}
+				if err != nil {
+					return err
+				}
+			case packetPubrel:
+				if err := write(encodeAck(packetPubcomp, request.PacketID)); err != nil {
+					return err
+				}
+			case packetPubrec:
+				// the client received a delivery with the QoS 2
+				if err := write(encodeAck(packetPubrel, request.PacketID)); err != nil {
+					return err
+				}
+			case packetPuback, packetPubcomp:
+				// the flows of the deliveries end with these
+			case packetSubscribe, packetUnsubscribe:
+				mock, err := match(h, request)
+				if err != nil {
+					logger.Error("failed to match the mqtt packet with the mocks", zap.Error(err))
+					return err
+				}
+				if mock == nil {
+					metrics.MatchFailures.Inc("mqtt")
+				}
+				switch {
+				case mock != nil:
+					err = writeResponses(mock, request.PacketID)
+				case p.kind == packetSubscribe:
+					logger.Debug("no mock matched the mqtt SUBSCRIBE, granting it", zap.Any("subscriptions", request.Subscriptions))
+					err = write(encodeSuback(request, version))
+				default:
+					err = write(encodeUnsuback(request, version))
+				}
+				if err != nil {
+					return err
+				}
+				if p.kind != packetSubscribe {
+					continue
+				}
+				deliveries, err := matchDeliveries(h, request.Subscriptions)
+				if err != nil {
+					logger.Error("failed to match the mqtt deliveries with the mocks", zap.Error(err))
+					return err
+				}
+				for _, delivery := range deliveries {
+					encoded, err := encodeResponse(delivery, delivery.PacketID)
+					if err != nil {
+						logger.Error("failed to encode the mqtt delivery of the mock", zap.Error(err))
+						return err
+					}
+					if err := write(encoded); err != nil {
+						return err
+					}
+				}
+			case packetPingreq:
+				if err := write(encodePacket(packetPingresp, 0, nil)); err != nil {
+					return err
+				}
+			case packetDisconnect:
+				logger.Debug("DISCONNECT received from client. Closing connection in mqtt !!")
+				return nil
+			default:
+				logger.Warn("mqtt packet not supported in test mode", zap.String("type", request.Type))
+			}
+		}
+
+		read, err := util.ReadBytes(clientConn)
+		if len(read) == 0 && err != nil {
+			if err == io.EOF {
+				logger.Debug("EOF error received from client. Closing connection in mqtt !!")
+				return nil
+			}
+			logger.Error("failed to read the request message in proxy for mqtt dependency", zap.Error(err))
+			return err
+		}
+		buffer = append(buffer, read...)
+	}
+}
+
+// forward passes the connection through to the broker when it wasn't recorded.
+func forward(requestBuffer []byte, clientConn, destConn net.Conn, h *hooks.Hook) error {
+	if destConn == nil {
+		return errors.New("failed to pass network traffic to the destination connection")
+	}
+	if _, err := destConn.Write(requestBuffer); err != nil {
+		return err
+	}
+	errChannel := make(chan error, 2)
+	go func() {
+		// Recover from panic and gracefully shutdown
+		defer h.Recover(pkg.GenerateRandomID())
+		defer utils.HandlePanic()
+		_, err := io.Copy(destConn, clientConn)
+		errChannel <- err
+	}()
+	go func() {
+		// Recover from panic and gracefully shutdown
+		defer h.Recover(pkg.GenerateRandomID())
+		defer utils.HandlePanic()
+		_, err := io.Copy(clientConn, destConn)
+		errChannel <- err
+	}()
+	return <-errChannel
+}
@@ -0,0 +1,381 @@
+package mqttparser
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+
+	"go.keploy.io/server/pkg/models"
+)
+
+// types of the control packets, in the high nibble of their first byte
+const (
+	packetConnect     = 1
+	packetConnack     = 2
+	packetPublish     = 3
+	packetPuback      = 4
+	packetPubrec      = 5
+	packetPubrel      = 6
+	packetPubcomp     = 7
+	packetSubscribe   = 8
+	packetSuback      = 9
+	packetUnsubscribe = 10
+	packetUnsuback    = 11
+	packetPingreq     = 12
+	packetPingresp    = 13
+	packetDisconnect  = 14
+	packetAuth        = 15
+)
+
+// protocol levels sent in the CONNECT packet
+const (
+	version311 = 4
+	version5   = 5
+)
+
+var packetNames = map[byte]string{
+	packetConnect:     "CONNECT",
+	packetConnack:     "CONNACK",
+	packetPublish:     "PUBLISH",
+	packetPuback:      "PUBACK",
+	packetPubrec:      "PUBREC",
+	packetPubrel:      "PUBREL",
+	packetPubcomp:     "PUBCOMP",
+	packetSubscribe:   "SUBSCRIBE",
+	packetSuback:      "SUBACK",
+	packetUnsubscribe: "UNSUBSCRIBE",
+	packetUnsuback:    "UNSUBACK",
+	packetPingreq:     "PINGREQ",
+	packetPingresp:    "PINGRESP",
+	packetDisconnect:  "DISCONNECT",
+	packetAuth:        "AUTH",
+}
+
+var packetTypes = func() map[string]byte {
+	types := map[string]byte{}
+	for kind, name := range packetNames {
+		types[name] = kind
+	}
+	return types
+}()
+
+var errIncomplete = errors.New("incomplete mqtt packet")
+
+// packet is a control packet, body holds its variable header and its payload.
+type packet struct {
+	kind  byte
+	flags byte
+	body  []byte
+}
+
+// splitPackets returns the whole packets starting the buffer along with the count of bytes they span.
+func splitPackets(buffer []byte) ([]packet, int, error) {
+	var (
+		packets  []packet
+		consumed int
+	)
+	for consumed < len(buffer) {
+		length, n, err := readVarint(buffer[consumed+1:])
+		if errors.Is(err, errIncomplete) {
+			break
+		}
+		if err != nil {
+			return nil, 0, err
+		}
+		start := consumed + 1 + n
+		if len(buffer) < start+length {
+			break
+		}
+		packets = append(packets, packet{
+			kind:  buffer[consumed] >> 4,
+			flags: buffer[consumed] & 0x0f,
+			body:  buffer[start : start+length],
+		})
+		consumed = start + length
+	}
+	return packets, consumed, nil
+}
+
+// readVarint decodes a variable byte integer, as used for the remaining length and the length of the properties.
+func readVarint(buffer []byte) (int, int, error) {
+	value, multiplier := 0, 1
+	for i := 0; i < 4; i++ {
+		if i >= len(buffer) {
+			return 0, 0, errIncomplete
+		}
+		value += int(buffer[i]&0x7f) * multiplier
+		if buffer[i]&0x80 == 0 {
+			return value, i + 1, nil
+		}
+		multiplier *= 128
+	}
+	return 0, 0, errors.New("malformed variable byte integer")
+}
+
+func encodeVarint(value int) []byte {
+	var encoded []byte
+	for {
+		b := byte(value % 128)
+		value /= 128
+		if value > 0 {
+			b |= 0x80
+		}
+		encoded = append(encoded, b)
+		if value == 0 {
+			return encoded
+		}
+	}
+}
+
+func encodePacket(kind, flags byte, body []byte) []byte {
+	encoded := []byte{kind<<4 | flags&0x0f}
+	encoded = append(encoded, encodeVarint(len(body))...)
+	return append(encoded, body...)
+}
+
+// decodePacket decodes the fields of a packet, the properties of the protocol v5 are skipped.
+func decodePacket(p packet, version byte) (models.MQTTPacket, error) {
+	decoded := models.MQTTPacket{
+		Type:  packetNames[p.kind],
+		Flags: p.flags,
+	}
+	r := &reader{buf: p.body}
+	switch p.kind {
+	case packetConnect:
+		r.readString()
+		decoded.ProtocolLevel = r.readByte()
+		flags := r.readByte()
+		r.readUint16()
+		if decoded.ProtocolLevel >= version5 {
+			r.skipProperties()
+		}
+		decoded.ClientID = r.readString()
+		// the will message and the password aren't kept
+		if flags&0x04 != 0 {
+			if decoded.ProtocolLevel >= version5 {
+				r.skipProperties()
+			}
+			r.readString()
+			r.readBinary()
+		}
+		if flags&0x80 != 0 {
+			decoded.Username = r.readString()
+		}
+	case packetConnack:
+		r.readByte()
+		decoded.ReasonCodes = []int{int(r.readByte())}
+	case packetPublish:
+		decoded.QoS = (p.flags >> 1) & 0x03
+		decoded.Retain = p.flags&0x01 != 0
+		decoded.Topic = r.readString()
+		if decoded.QoS > 0 {
+			decoded.PacketID = r.readUint16()
+		}
+		if version >= version5 {
+			r.skipProperties()
+		}
+		decoded.Payload = encodePayload(r.rest())
+	case packetPuback, packetPubrec, packetPubrel, packetPubcomp:
+		decoded.PacketID = r.readUint16()
+		if r.remaining() > 0 {
+			decoded.ReasonCodes = []int{int(r.readByte())}
+		}
+	case packetSubscribe, packetUnsubscribe:
+		decoded.PacketID = r.readUint16()
+		if version >= version5 {
+			r.skipProperties()
+		}
+		for r.err == nil && r.remaining() > 0 {
+			subscription := models.MQTTSubscription{Filter: r.readString()}
+			if p.kind == packetSubscribe {
+				subscription.QoS = r.readByte() & 0x03
+			}
+			decoded.Subscriptions = append(decoded.Subscriptions, subscription)
+		}
+	case packetSuback, packetUnsuback:
+		decoded.PacketID = r.readUint16()
+		if version >= version5 {
+			r.skipProperties()
+		}
+		for _, code := range r.rest() {
+			decoded.ReasonCodes = append(decoded.ReasonCodes, int(code))
+		}
+	}
+	if decoded.Type == "" {
+		return decoded, fmt.Errorf("unknown mqtt packet type %d", p.kind)
+	}
+	if r.err != nil {
+		return decoded, fmt.Errorf("failed to decode the %s packet: %v", decoded.Type, r.err)
+	}
+	return decoded, nil
+}
+
+// encodeResponse encodes a packet recorded from the broker. The acknowledgements are sent with the packet id of
+// the request they answer.
+func encodeResponse(response models.MQTTPacket, packetID uint16) ([]byte, error) {
+	kind, ok := packetTypes[response.Type]
+	if !ok {
+		return nil, fmt.Errorf("unknown mqtt packet type %q", response.Type)
+	}
+	body, err := base64.StdEncoding.DecodeString(response.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode the body of the %s packet: %v", response.Type, err)
+	}
+	switch kind {
+	case packetPuback, packetPubrec, packetPubrel, packetPubcomp, packetSuback, packetUnsuback:
+		if len(body) >= 2 {
+			binary.BigEndian.PutUint16(body[0:2], packetID)
+		}
+	}
+	return encodePacket(kind, response.Flags, body), nil
+}
+
+// encodeAck encodes a successful PUBACK, PUBREC, PUBREL or PUBCOMP, the reason code can be left out in v5 too.
+func encodeAck(kind byte, packetID uint16) []byte {
+	flags := byte(0)
+	if kind == packetPubrel {
+		flags = 0x02
+	}
+	body := make([]byte, 2)
+	binary.BigEndian.PutUint16(body, packetID)
+	return encodePacket(kind, flags, body)
+}
+
+// encodeSuback grants the subscriptions of a SUBSCRIBE with the QoS they request.
+func encodeSuback(request models.MQTTPacket, version byte) []byte {
+	body := make([]byte, 2)
+	binary.BigEndian.PutUint16(body, request.PacketID)
+	if version >= version5 {
+		body = append(body, 0)
+	}
+	for _, subscription := range request.Subscriptions {
+		body = append(body, subscription.QoS)
+	}
+	return encodePacket(packetSuback, 0, body)
+}
+
+// encodeUnsuback acknowledges an UNSUBSCRIBE, the protocol v5 adds a reason code for every topic filter.
+func encodeUnsuback(request models.MQTTPacket, version byte) []byte {
+	body := make([]byte, 2)
+	binary.BigEndian.PutUint16(body, request.PacketID)
+	if version >= version5 {
+		body = append(body, 0)
+		body = append(body, make([]byte, len(request.Subscriptions))...)
+	}
+	return encodePacket(packetUnsuback, 0, body)
+}
+
+func encodePayload(payload []byte) *models.OutputBinary {
+	if isAsciiPrintable(string(payload)) {
+		return &models.OutputBinary{Type: models.String, Data: string(payload)}
+	}
+	return &models.OutputBinary{Type: "binary", Data: base64.StdEncoding.EncodeToString(payload)}
+}
+
+func isAsciiPrintable(s string) bool {
+	for _, r := range s {
+		if r > unicode.MaxASCII || (!unicode.IsPrint(r) && r != '\r' && r != '\n' && r != '\t') {
+			return false
+		}
+	}
+	return true
+}
+
+// topicMatches reports whether a topic name matches a topic filter, where "+" matches a single level and "#" the
+// remaining ones. The shared subscriptions ($share/{group}/{filter}) match the topics of their filter.
+func topicMatches(filter, topic string) bool {
+	if strings.HasPrefix(filter, "$share/") {
+		parts := strings.SplitN(filter, "/", 3)
+		if len(parts) < 3 {
+			return false
+		}
+		filter = parts[2]
+	}
+	filterLevels, topicLevels := strings.Split(filter, "/"), strings.Split(topic, "/")
+	// the topics starting with $ aren't matched by a wildcard at the first level
+	if strings.HasPrefix(topic, "$") && (filterLevels[0] == "+" || filterLevels[0] == "#") {
+		return false
+	}
+	for i, level := range filterLevels {
+		if level == "#" {
+			return true
+		}
+		if i >= len(topicLevels) || (level != "+" && level != topicLevels[i]) {
+			return false
+		}
+	}
+	return len(filterLevels) == len(topicLevels)
+}
+
+// reader reads the fields of a packet, the first error is kept and ends the reading.
+type reader struct {
+	buf []byte
+	off int
+	err error
+}
+
+func (r *reader) next(n int) []byte {
+	if r.err != nil {
+		return nil
+	}
+	if len(r.buf) < r.off+n {
+		r.err = errors.New("packet too short")
+		return nil
+	}
+	b := r.buf[r.off : r.off+n]
+	r.off += n
+	return b
+}
+
+func (r *reader) remaining() int {
+	return len(r.buf) - r.off
+}
+
+func (r *reader) rest() []byte {
+	if r.err != nil {
+		return nil
+	}
+	b := r.buf[r.off:]
+	r.off = len(r.buf)
+	return b
+}
+
+func (r *reader) readByte() byte {
+	b := r.next(1)
+	if b == nil {
+		return 0
+	}
+	return b[0]
+}
+
+func (r *reader) readUint16() uint16 {
+	b := r.next(2)
+	if b == nil {
+		return 0
+	}
+	return binary.BigEndian.Uint16(b)
+}
+
+func (r *reader) readBinary() []byte {
+	return r.next(int(r.readUint16()))
+}
+
+func (r *reader) readString() string {
+	return string(r.readBinary())
+}
+
+func (r *reader) skipProperties() {
+	if r.err != nil {
+		return
+	}
+	length, n, err := readVarint(r.buf[r.off:])
+	if err != nil {
+		r.err = errors.New("invalid length of the properties")
+		return
+	}
+	r.off += n
+	r.next(length)
+}
@@ -2,6 +2,8 @@ package mysqlparser
 
 import (
 	"errors"
+
+	"go.keploy.io/server/pkg/models"
 )
 
 type NextAuthPacket struct {
@@ -24,3 +26,9 @@ func encodeAuthMoreData(packet *NextAuthPacket) ([]byte, error) {
 	}
 	return []byte{packet.PluginData}, nil
 }
+
+// isPublicKeyRequest checks whether the client packet asks the server for its RSA public key
+// during the caching_sha2_password full authentication.
+func isPublicKeyRequest(buffer []byte) bool {
+	return len(buffer) == 5 && buffer[4] == models.CachingSha2PasswordRequestPublicKey
+}
@@ -0,0 +1,475 @@
+package mysqlparser
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+
+	"go.keploy.io/server/pkg/models"
+)
+
+// unsignedFlag is set in the column definition flags of unsigned numeric columns
+const unsignedFlag = 0x20
+
+// preparedStatement keeps what the server told about a prepared statement. COM_STMT_EXECUTE neither carries the
+// number of parameters nor (unless they are re-bound) their types, so both are remembered from earlier packets.
+type preparedStatement struct {
+	numParams  uint16
+	paramTypes []BoundParameter
+}
+
+// statementTable holds the prepared statements of a connection. The statement ids are given by the server per
+// connection, each one starting at 1, so the connections of a pool each have their own table.
+type statementTable struct {
+	mutex      sync.Mutex
+	statements map[uint32]*preparedStatement
+}
+
+func newStatementTable() *statementTable {
+	return &statementTable{statements: map[uint32]*preparedStatement{}}
+}
+
+func (t *statementTable) register(statementID uint32, numParams uint16) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.statements[statementID] = &preparedStatement{numParams: numParams}
+}
+
+func (t *statementTable) forget(statementID uint32) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	delete(t.statements, statementID)
+}
+
+// paramTypesOf returns the number of parameters of a prepared statement along with the types bound last time.
+func (t *statementTable) paramTypesOf(statementID uint32) (uint16, []BoundParameter, bool) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	stmt, ok := t.statements[statementID]
+	if !ok {
+		return 0, nil, false
+	}
+	return stmt.numParams, stmt.paramTypes, true
+}
+
+func (t *statementTable) bindParamTypes(statementID uint32, params []BoundParameter) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if stmt, ok := t.statements[statementID]; ok {
+		stmt.paramTypes = append([]BoundParameter(nil), params...)
+	}
+}
+
+// isNullInBitmap reports whether the field at the given position is marked NULL. The bitmap of binary
+// resultset rows starts with an offset of 2 bits, the one of COM_STMT_EXECUTE has no offset.
+func isNullInBitmap(bitmap []byte, position, offset int) bool {
+	bytePos := (position + offset) / 8
+	bitPos := (position + offset) % 8
+	if bytePos >= len(bitmap) {
+		return false
+	}
+	return bitmap[bytePos]&(1<<uint(bitPos)) != 0
+}
+
+// binaryValueLength returns the number of bytes taken by a value of the given type in the binary protocol.
+func binaryValueLength(b []byte, fieldType models.FieldType) (int, error) {
+	switch fieldType {
+	case models.FieldTypeNULL:
+		return 0, nil
+	case models.FieldTypeTiny:
+		return 1, nil
+	case models.FieldTypeShort, models.FieldTypeYear:
+		return 2, nil
+	case models.FieldTypeLong, models.FieldTypeInt24, models.FieldTypeFloat:
+		return 4, nil
+	case models.FieldTypeLongLong, models.FieldTypeDouble:
+		return 8, nil
+	case models.FieldTypeDate, models.FieldTypeDateTime, models.FieldTypeTimestamp, models.FieldTypeTime:
+		if len(b) < 1 {
+			return 0, errors.New("data too short for the length of a temporal value")
+		}
+		return 1 + int(b[0]), nil
+	default:
+		if len(b) < 1 {
+			return 0, errors.New("data too short for a length encoded value")
+		}
+		length, _, n := readLengthEncodedInteger(b)
+		return n + int(length), nil
+	}
+}
+
+// readBinaryValue decodes a single value of the binary protocol into its textual form, so that it stays
+// readable in the mocks. It returns the value and the number of bytes consumed.
+func readBinaryValue(b []byte, fieldType models.FieldType, unsigned bool) (string, int, error) {
+	n, err := binaryValueLength(b, fieldType)
+	if err != nil {
+		return "", 0, err
+	}
+	if len(b) < n {
+		return "", 0, fmt.Errorf("data too short for a value of type %d", fieldType)
+	}
+
+	switch fieldType {
+	case models.FieldTypeNULL:
+		return "", 0, nil
+	case models.FieldTypeTiny:
+		if unsigned {
+			return strconv.FormatUint(uint64(b[0]), 10), n, nil
+		}
+		return strconv.FormatInt(int64(int8(b[0])), 10), n, nil
+	case models.FieldTypeShort, models.FieldTypeYear:
+		v := binary.LittleEndian.Uint16(b)
+		if unsigned || fieldType == models.FieldTypeYear {
+			return strconv.FormatUint(uint64(v), 10), n, nil
+		}
+		return strconv.FormatInt(int64(int16(v)), 10), n, nil
+	case models.FieldTypeLong, models.FieldTypeInt24:
+		v := binary.LittleEndian.Uint32(b)
+		if unsigned {
+			return strconv.FormatUint(uint64(v), 10), n, nil
+		}
+		return strconv.FormatInt(int64(int32(v)), 10), n, nil
+	case models.FieldTypeLongLong:
+		v := binary.LittleEndian.Uint64(b)
+		if unsigned {
+			return strconv.FormatUint(v, 10), n, nil
+		}
+		return strconv.FormatInt(int64(v), 10), n, nil
+	case models.FieldTypeFloat:
+		return strconv.FormatFloat(float64(math.Float32frombits(binary.LittleEndian.Uint32(b))), 'g', -1, 32), n, nil
+	case models.FieldTypeDouble:
+		return strconv.FormatFloat(math.Float64frombits(binary.LittleEndian.Uint64(b)), 'g', -1, 64), n, nil
+	case models.FieldTypeDate, models.FieldTypeDateTime, models.FieldTypeTimestamp:
+		return formatBinaryDateTime(b[1:n]), n, nil
+	case models.FieldTypeTime:
+		return formatBinaryTime(b[1:n]), n, nil
+	default:
+		length, _, m := readLengthEncodedInteger(b)
+		return string(b[m : m+int(length)]), n, nil
+	}
+}
+
+// writeBinaryValue is the inverse of readBinaryValue.
+func writeBinaryValue(buf *bytes.Buffer, value string, fieldType models.FieldType) error {
+	switch fieldType {
+	case models.FieldTypeNULL:
+		return nil
+	case models.FieldTypeTiny, models.FieldTypeShort, models.FieldTypeYear, models.FieldTypeLong, models.FieldTypeInt24, models.FieldTypeLongLong:
+		v, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			u, uerr := strconv.ParseUint(value, 10, 64)
+			if uerr != nil {
+				return fmt.Errorf("could not convert %q to an integer: %v", value, err)
+			}
+			v = int64(u)
+		}
+		integer := make([]byte, 8)
+		binary.LittleEndian.PutUint64(integer, uint64(v))
+		length, _ := binaryValueLength(nil, fieldType)
+		buf.Write(integer[:length])
+	case models.FieldTypeFloat:
+		v, err := strconv.ParseFloat(value, 32)
+		if err != nil {
+			return err
+		}
+		buf.Write(encodeFloat32(float32(v)))
+	case models.FieldTypeDouble:
+		v, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return err
+		}
+		buf.Write(encodeFloat64(v))
+	case models.FieldTypeDate, models.FieldTypeDateTime, models.FieldTypeTimestamp:
+		return writeBinaryDateTime(buf, value)
+	case models.FieldTypeTime:
+		return writeBinaryTime(buf, value)
+	default:
+		writeLengthEncodedString(buf, value)
+	}
+	return nil
+}
+
+func formatBinaryDateTime(b []byte) string {
+	var year, month, day, hour, minute, second int
+	var micro uint32
+	if len(b) >= 4 {
+		year = int(binary.LittleEndian.Uint16(b[0:2]))
+		month, day = int(b[2]), int(b[3])
+	}
+	if len(b) >= 7 {
+		hour, minute, second = int(b[4]), int(b[5]), int(b[6])
+	}
+	if len(b) >= 11 {
+		micro = binary.LittleEndian.Uint32(b[7:11])
+	}
+	switch {
+	case len(b) < 7:
+		return fmt.Sprintf("%04d-%02d-%02d", year, month, day)
+	case len(b) < 11:
+		return fmt.Sprintf("%04d-%02d-%02d %02d:%02d:%02d", year, month, day, hour, minute, second)
+	default:
+		return fmt.Sprintf("%04d-%02d-%02d %02d:%02d:%02d.%06d", year, month, day, hour, minute, second, micro)
+	}
+}
+
+func writeBinaryDateTime(buf *bytes.Buffer, value string) error {
+	var year, month, day, hour, minute, second, micro int
+	var err error
+	switch {
+	case len(value) <= len("0000-00-00"):
+		_, err = fmt.Sscanf(value, "%d-%d-%d", &year, &month, &day)
+	case strings.Contains(value, "."):
+		_, err = fmt.Sscanf(value, "%d-%d-%d %d:%d:%d.%d", &year, &month, &day, &hour, &minute, &second, &micro)
+	default:
+		_, err = fmt.Sscanf(value, "%d-%d-%d %d:%d:%d", &year, &month, &day, &hour, &minute, &second)
+	}
+	if err != nil {
+		return fmt.Errorf("could not parse the datetime value %q: %v", value, err)
+	}
+
+	date := make([]byte, 11)
+	binary.LittleEndian.PutUint16(date[0:2], uint16(year))
+	date[2], date[3] = byte(month), byte(day)
+	date[4], date[5], date[6] = byte(hour), byte(minute), byte(second)
+	binary.LittleEndian.PutUint32(date[7:11], uint32(micro))
+
+	length := 11
+	switch {
+	case strings.Contains(value, "."):
+	case len(value) > len("0000-00-00"):
+		length = 7
+	case year == 0 && month == 0 && day == 0:
+		length = 0
+	default:
+		length = 4
+	}
+	buf.WriteByte(byte(length))
+	buf.Write(date[:length])
+	return nil
+}
+
+func formatBinaryTime(b []byte) string {
+	if len(b) < 8 {
+		return "0 00:00:00"
+	}
+	sign := ""
+	if b[0] == 1 {
+		sign = "-"
+	}
+	days := binary.LittleEndian.Uint32(b[1:5])
+	if len(b) >= 12 {
+		return fmt.Sprintf("%s%d %02d:%02d:%02d.%06d", sign, days, b[5], b[6], b[7], binary.LittleEndian.Uint32(b[8:12]))
+	}
+	return fmt.Sprintf("%s%d %02d:%02d:%02d", sign, days, b[5], b[6], b[7])
+}
+
+func writeBinaryTime(buf *bytes.Buffer, value string) error {
+	var days, hour, minute, second, micro int
+	var err error
+	negative := strings.HasPrefix(value, "-")
+	value = strings.TrimPrefix(value, "-")
+	if strings.Contains(value, ".") {
+		_, err = fmt.Sscanf(value, "%d %d:%d:%d.%d", &days, &hour, &minute, &second, &micro)
+	} else {
+		_, err = fmt.Sscanf(value, "%d %d:%d:%d", &days, &hour, &minute, &second)
+	}
+	if err != nil {
+		return fmt.Errorf("could not parse the time value %q: %v", value, err)
+	}
+
+	t := make([]byte, 12)
+	if negative {
+		t[0] = 1
+	}
+	binary.LittleEndian.PutUint32(t[1:5], uint32(days))
+	t[5], t[6], t[7] = byte(hour), byte(minute), byte(second)
+	binary.LittleEndian.PutUint32(t[8:12], uint32(micro))
+
+	length := 8
+	if strings.Contains(value, ".") {
+		length = 12
+	} else if !negative && days == 0 && hour == 0 && minute == 0 && second == 0 {
+		length = 0
+	}
+	buf.WriteByte(byte(length))
+	buf.Write(t[:length])
+	return nil
+}
+
+// splitPackets splits a buffer holding several MySQL packets, each packet keeps its 4 bytes header.
+func splitPackets(b []byte) ([][]byte, error) {
+	var packets [][]byte
+	for len(b) > 0 {
+		if len(b) < 4 {
+			return nil, errors.New("data too short for a MySQL packet header")
+		}
+		length := int(readUint24(b[:3]))
+		if len(b) < 4+length {
+			return nil, fmt.Errorf("packet length %d exceeds the remaining %d bytes", length, len(b)-4)
+		}
+		packets = append(packets, b[:4+length])
+		b = b[4+length:]
+	}
+	return packets, nil
+}
+
+// parseBinaryResultSet parses the resultset sent for COM_STMT_EXECUTE, whose rows are encoded with the binary
+// protocol. The column count packet comes without its header, like in parseResultSet.
+func parseBinaryResultSet(b []byte) (*ResultSet, error) {
+	columnCount, _, n := readLengthEncodedInteger(b)
+	packets, err := splitPackets(b[n:])
+	if err != nil {
+		return nil, err
+	}
+	if uint64(len(packets)) < columnCount {
+		return nil, errors.New("data too short for the column definitions of the binary resultset")
+	}
+
+	resultSet := &ResultSet{
+		BinaryProtocol: true,
+	}
+	for _, packet := range packets[:columnCount] {
+		column, _, err := parseColumnDefinitionPacket(packet)
+		if err != nil {
+			return nil, err
+		}
+		resultSet.Columns = append(resultSet.Columns, column)
+	}
+	packets = packets[columnCount:]
+
+	// the EOF after the column definitions is absent when CLIENT_DEPRECATE_EOF is set
+	if len(packets) > 0 && len(packets[0]) > 4 && packets[0][4] == 0xfe && len(packets[0]) < 4+9 {
+		resultSet.EOFPresent = true
+		resultSet.EOFAfterColumns = packets[0]
+		packets = packets[1:]
+	}
+
+	for _, packet := range packets {
+		payload := packet[4:]
+		if len(payload) > 0 && payload[0] == 0xfe && len(payload) < 0xffffff {
+			// either an EOF or an OK packet closing the rows
+			resultSet.EOFPresentFinal = true
+			resultSet.OptionalEOFBytes = packet
+			break
+		}
+		row, err := parseBinaryRow(packet, resultSet.Columns)
+		if err != nil {
+			return nil, err
+		}
+		resultSet.Rows = append(resultSet.Rows, row)
+	}
+	return resultSet, nil
+}
+
+func parseBinaryRow(packet []byte, columns []*ColumnDefinition) (*Row, error) {
+	payload := packet[4:]
+	nullBitmapLength := (len(columns) + 7 + 2) / 8
+	if len(payload) < 1+nullBitmapLength || payload[0] != 0x00 {
+		return nil, errors.New("invalid binary resultset row")
+	}
+	nullBitmap := payload[1 : 1+nullBitmapLength]
+	b := payload[1+nullBitmapLength:]
+
+	row := &Row{
+		Header: RowHeader{
+			PacketLength: len(payload),
+			SequenceID:   packet[3],
+		},
+	}
+	for i, column := range columns {
+		colValue := RowColumnDefinition{
+			Type: models.FieldType(column.ColumnType),
+			Name: column.Name,
+		}
+		if isNullInBitmap(nullBitmap, i, 2) {
+			row.Columns = append(row.Columns, colValue)
+			continue
+		}
+		value, n, err := readBinaryValue(b, models.FieldType(column.ColumnType), column.Flags&unsignedFlag != 0)
+		if err != nil {
+			return nil, err
+		}
+		colValue.Value = value
+		row.Columns = append(row.Columns, colValue)
+		b = b[n:]
+	}
+	return row, nil
+}
+
+// withSequenceID returns a copy of the packet carrying the given sequence id.
+func withSequenceID(packet []byte, sequenceID byte) []byte {
+	if len(packet) < 4 {
+		return packet
+	}
+	patched := append([]byte(nil), packet...)
+	patched[3] = sequenceID
+	return patched
+}
+
+func encodeBinaryResultSet(resultSet *models.MySQLResultSet) ([]byte, error) {
+	buf := new(bytes.Buffer)
+	sequenceID := byte(1)
+
+	columnCount := new(bytes.Buffer)
+	count := uint64(len(resultSet.Columns))
+	writeLengthEncodedInteger(columnCount, &count)
+	writePacket(buf, columnCount.Bytes(), sequenceID)
+	sequenceID++
+
+	for _, column := range resultSet.Columns {
+		if err := encodeColumnDefinition(buf, column, &sequenceID); err != nil {
+			return nil, err
+		}
+	}
+
+	if resultSet.EOFPresent {
+		buf.Write(withSequenceID(resultSet.EOFAfterColumns, sequenceID))
+		sequenceID++
+	}
+
+	for _, row := range resultSet.Rows {
+		payload, err := encodeBinaryRow(row)
+		if err != nil {
+			return nil, err
+		}
+		writePacket(buf, payload, sequenceID)
+		sequenceID++
+	}
+
+	if resultSet.EOFPresentFinal {
+		buf.Write(withSequenceID(resultSet.OptionalEOFBytes, sequenceID))
+	}
+	return buf.Bytes(), nil
+}
+
+func encodeBinaryRow(row *models.Row) ([]byte, error) {
+	nullBitmap := make([]byte, (len(row.Columns)+7+2)/8)
+	values := new(bytes.Buffer)
+	for i, column := range row.Columns {
+		if column.Value == nil {
+			nullBitmap[(i+2)/8] |= 1 << uint((i+2)%8)
+			continue
+		}
+		if err := writeBinaryValue(values, fmt.Sprint(column.Value), column.Type); err != nil {
+			return nil, err
+		}
+	}
+
+	payload := []byte{0x00}
+	payload = append(payload, nullBitmap...)
+	return append(payload, values.Bytes()...), nil
+}
+
+func writePacket(buf *bytes.Buffer, payload []byte, sequenceID byte) {
+	length := len(payload)
+	buf.WriteByte(byte(length))
+	buf.WriteByte(byte(length >> 8))
+	buf.WriteByte(byte(length >> 16))
+	buf.WriteByte(sequenceID)
+	buf.Write(payload)
+}
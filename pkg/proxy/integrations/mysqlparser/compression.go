@@ -0,0 +1,176 @@
+package mysqlparser
+
+import (
+	"bytes"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/klauspost/compress/zstd"
+	"go.keploy.io/server/pkg/models"
+)
+
+// compressionAlgorithm is the compression negotiated during the handshake. Once the authentication is over,
+// the compressed protocol wraps the MySQL packets into frames having a 7 bytes header.
+type compressionAlgorithm int
+
+const (
+	noCompression compressionAlgorithm = iota
+	zlibCompression
+	zstdCompression
+)
+
+const (
+	compressedHeaderLength = 7
+	maxCompressedPayload   = 0xffffff
+	// payloads shorter than this are sent uncompressed, like the server does
+	minCompressLength = 50
+)
+
+func (c compressionAlgorithm) String() string {
+	switch c {
+	case zlibCompression:
+		return "zlib"
+	case zstdCompression:
+		return "zstd"
+	default:
+		return "none"
+	}
+}
+
+// compressionOf returns the compression requested by the client in its handshake response. The client only
+// sets the compression flags which the server advertised in the HandshakeV10 packet.
+func compressionOf(clientCapabilities uint32) compressionAlgorithm {
+	switch {
+	case clientCapabilities&CLIENT_ZSTD_COMPRESSION_ALGORITHM != 0:
+		return zstdCompression
+	case clientCapabilities&uint32(models.CLIENT_COMPRESS) != 0:
+		return zlibCompression
+	default:
+		return noCompression
+	}
+}
+
+// decompressPackets unwraps the frames of the compressed protocol and returns the plain MySQL packets they carry
+// along with the sequence id of the last frame, which the peer continues from.
+func decompressPackets(buffer []byte, algorithm compressionAlgorithm) ([]byte, byte, error) {
+	if algorithm == noCompression {
+		return buffer, 0, nil
+	}
+	var (
+		packets    []byte
+		sequenceID byte
+	)
+	for len(buffer) > 0 {
+		if len(buffer) < compressedHeaderLength {
+			return nil, 0, errors.New("data too short for a compressed packet header")
+		}
+		compressedLength := int(readUint24(buffer[0:3]))
+		sequenceID = buffer[3]
+		uncompressedLength := int(readUint24(buffer[4:7]))
+		if len(buffer) < compressedHeaderLength+compressedLength {
+			return nil, 0, fmt.Errorf("compressed packet length %d exceeds the remaining %d bytes", compressedLength, len(buffer)-compressedHeaderLength)
+		}
+		payload := buffer[compressedHeaderLength : compressedHeaderLength+compressedLength]
+		buffer = buffer[compressedHeaderLength+compressedLength:]
+
+		// an uncompressed length of 0 means that the payload was sent as it is
+		if uncompressedLength == 0 {
+			packets = append(packets, payload...)
+			continue
+		}
+		decompressed, err := decompress(payload, algorithm, uncompressedLength)
+		if err != nil {
+			return nil, 0, err
+		}
+		if len(decompressed) != uncompressedLength {
+			return nil, 0, fmt.Errorf("decompressed %d bytes instead of %d", len(decompressed), uncompressedLength)
+		}
+		packets = append(packets, decompressed...)
+	}
+	return packets, sequenceID, nil
+}
+
+// compressPackets wraps plain MySQL packets into frames of the compressed protocol starting with the given
+// sequence id.
+func compressPackets(packets []byte, algorithm compressionAlgorithm, sequenceID byte) ([]byte, error) {
+	if algorithm == noCompression {
+		return packets, nil
+	}
+	var frames []byte
+	for len(packets) > 0 {
+		chunk := packets
+		if len(chunk) > maxCompressedPayload {
+			chunk = chunk[:maxCompressedPayload]
+		}
+		packets = packets[len(chunk):]
+
+		payload := chunk
+		uncompressedLength := 0
+		if len(chunk) >= minCompressLength {
+			compressed, err := compress(chunk, algorithm)
+			if err != nil {
+				return nil, err
+			}
+			payload = compressed
+			uncompressedLength = len(chunk)
+		}
+
+		header := make([]byte, compressedHeaderLength)
+		putUint24(header[0:3], uint32(len(payload)))
+		header[3] = sequenceID
+		putUint24(header[4:7], uint32(uncompressedLength))
+		frames = append(frames, header...)
+		frames = append(frames, payload...)
+		sequenceID++
+	}
+	return frames, nil
+}
+
+func decompress(payload []byte, algorithm compressionAlgorithm, uncompressedLength int) ([]byte, error) {
+	switch algorithm {
+	case zstdCompression:
+		decoder, err := zstd.NewReader(nil)
+		if err != nil {
+			return nil, err
+		}
+		defer decoder.Close()
+		return decoder.DecodeAll(payload, make([]byte, 0, uncompressedLength))
+	default:
+		reader, err := zlib.NewReader(bytes.NewReader(payload))
+		if err != nil {
+			return nil, err
+		}
+		defer reader.Close()
+		return io.ReadAll(reader)
+	}
+}
+
+func compress(payload []byte, algorithm compressionAlgorithm) ([]byte, error) {
+	switch algorithm {
+	case zstdCompression:
+		encoder, err := zstd.NewWriter(nil)
+		if err != nil {
+			return nil, err
+		}
+		defer encoder.Close()
+		return encoder.EncodeAll(payload, nil), nil
+	default:
+		var buf bytes.Buffer
+		writer := zlib.NewWriter(&buf)
+		if _, err := writer.Write(payload); err != nil {
+			return nil, err
+		}
+		if err := writer.Close(); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
+}
+
+func putUint24(b []byte, v uint32) {
+	b[0] = byte(v)
+	b[1] = byte(v >> 8)
+	b[2] = byte(v >> 16)
+}
@@ -37,7 +37,7 @@ func encodeEncryptPassword(packet *PasswordData) ([]byte, error) {
 	}
 
 	// Convert PayloadLength from uint32 to 3-byte representation
-	lengthBytes := make([]byte, 3)
+	lengthBytes := make([]byte, 4)
 	binary.LittleEndian.PutUint32(lengthBytes, packet.PayloadLength)
 	lengthBytes = lengthBytes[:3]
 
@@ -1,8 +1,11 @@
 package mysqlparser
 
 import (
+	"bytes"
 	"encoding/binary"
 	"fmt"
+
+	"go.keploy.io/server/pkg/models"
 )
 
 type ERRPacket struct {
@@ -31,3 +34,24 @@ func decodeMySQLErr(data []byte) (*ERRPacket, error) {
 	packet.ErrorMessage = string(data[9:])
 	return packet, nil
 }
+
+func encodeMySQLErr(packet *models.MySQLERRPacket, header *models.MySQLPacketHeader) ([]byte, error) {
+	payload := new(bytes.Buffer)
+	payload.WriteByte(0xff)
+	if err := binary.Write(payload, binary.LittleEndian, packet.ErrorCode); err != nil {
+		return nil, err
+	}
+	payload.WriteByte('#')
+	payload.WriteString(packet.SQLState)
+	payload.WriteString(packet.ErrorMessage)
+
+	buf := new(bytes.Buffer)
+	packetLength := uint32(payload.Len())
+	buf.WriteByte(byte(packetLength))
+	buf.WriteByte(byte(packetLength >> 8))
+	buf.WriteByte(byte(packetLength >> 16))
+	buf.WriteByte(header.PacketNumber)
+	buf.Write(payload.Bytes())
+
+	return buf.Bytes(), nil
+}
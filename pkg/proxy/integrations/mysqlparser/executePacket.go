@@ -3,6 +3,8 @@ package mysqlparser
 import (
 	"encoding/binary"
 	"fmt"
+
+	"go.keploy.io/server/pkg/models"
 )
 
 type ComStmtExecute struct {
@@ -19,10 +21,10 @@ type BoundParameter struct {
 	Value    []byte `yaml:"value"`
 }
 
-func decodeComStmtExecute(packet []byte) (ComStmtExecute, error) {
-	// removed the print statement for cleanliness
-	if len(packet) < 14 { // the minimal size of the packet without parameters should be 14, not 13
-		return ComStmtExecute{}, fmt.Errorf("packet length less than 14 bytes")
+func decodeComStmtExecute(packet []byte, statements *statementTable) (ComStmtExecute, error) {
+	// command (1) + statement id (4) + flags (1) + iteration count (4)
+	if len(packet) < 10 {
+		return ComStmtExecute{}, fmt.Errorf("packet length less than 10 bytes")
 	}
 
 	stmtExecute := ComStmtExecute{}
@@ -30,27 +32,58 @@ func decodeComStmtExecute(packet []byte) (ComStmtExecute, error) {
 	stmtExecute.Flags = packet[5]
 	stmtExecute.IterationCount = binary.LittleEndian.Uint32(packet[6:10])
 
-	// the next bytes are reserved for the Null-Bitmap, Parameter Bound Flag and Bound Parameters if they exist
-	// if the length of the packet is greater than 14, then there are parameters
-	if len(packet) > 14 {
-		nullBitmapLength := int((stmtExecute.ParamCount + 7) / 8)
-
-		stmtExecute.NullBitmap = packet[10 : 10+nullBitmapLength]
-		stmtExecute.ParamCount = binary.LittleEndian.Uint16(packet[10+nullBitmapLength:])
-
-		// in case new parameters are bound, the new types and values are sent
-		if packet[10+nullBitmapLength] == 1 {
-			// read the types and values of the new parameters
-			stmtExecute.Parameters = make([]BoundParameter, stmtExecute.ParamCount)
-			for i := 0; i < int(stmtExecute.ParamCount); i++ {
-				index := 10 + nullBitmapLength + 1 + 2*i
-				if index+1 >= len(packet) {
-					return ComStmtExecute{}, fmt.Errorf("packet length less than expected while reading parameters")
-				}
-				stmtExecute.Parameters[i].Type = packet[index]
-				stmtExecute.Parameters[i].Unsigned = packet[index+1]
-			}
+	// the packet doesn't carry the number of parameters, it is known from the COM_STMT_PREPARE_OK response
+	paramCount, boundTypes, ok := statements.paramTypesOf(stmtExecute.StatementID)
+	if !ok || paramCount == 0 || len(packet) == 10 {
+		return stmtExecute, nil
+	}
+	stmtExecute.ParamCount = paramCount
+
+	offset := 10
+	nullBitmapLength := int((paramCount + 7) / 8)
+	if len(packet) < offset+nullBitmapLength+1 {
+		return ComStmtExecute{}, fmt.Errorf("packet length less than expected while reading the null bitmap")
+	}
+	stmtExecute.NullBitmap = packet[offset : offset+nullBitmapLength]
+	offset += nullBitmapLength
+
+	newParamsBound := packet[offset]
+	offset++
+
+	stmtExecute.Parameters = make([]BoundParameter, paramCount)
+	if newParamsBound == 1 {
+		// in case new parameters are bound, their types are sent before the values
+		if len(packet) < offset+2*int(paramCount) {
+			return ComStmtExecute{}, fmt.Errorf("packet length less than expected while reading parameter types")
+		}
+		for i := range stmtExecute.Parameters {
+			stmtExecute.Parameters[i].Type = packet[offset]
+			stmtExecute.Parameters[i].Unsigned = packet[offset+1]
+			offset += 2
+		}
+		statements.bindParamTypes(stmtExecute.StatementID, stmtExecute.Parameters)
+	} else if len(boundTypes) == int(paramCount) {
+		for i := range stmtExecute.Parameters {
+			stmtExecute.Parameters[i].Type = boundTypes[i].Type
+			stmtExecute.Parameters[i].Unsigned = boundTypes[i].Unsigned
+		}
+	} else {
+		return ComStmtExecute{}, fmt.Errorf("types of the parameters of statement %d are unknown", stmtExecute.StatementID)
+	}
+
+	for i := range stmtExecute.Parameters {
+		if isNullInBitmap(stmtExecute.NullBitmap, i, 0) {
+			continue
+		}
+		length, err := binaryValueLength(packet[offset:], models.FieldType(stmtExecute.Parameters[i].Type))
+		if err != nil {
+			return ComStmtExecute{}, err
+		}
+		if len(packet) < offset+length {
+			return ComStmtExecute{}, fmt.Errorf("packet length less than expected while reading parameter values")
 		}
+		stmtExecute.Parameters[i].Value = packet[offset : offset+length]
+		offset += length
 	}
 
 	return stmtExecute, nil
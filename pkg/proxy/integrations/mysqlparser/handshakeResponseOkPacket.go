@@ -69,9 +69,17 @@ func decodeHandshakeResponseOk(data []byte) (*HandshakeResponseOk, error) {
 	}, nil
 }
 
-func encodeHandshakeResponseOk(packet *models.MySQLHandshakeResponseOk) ([]byte, error) {
+func encodeHandshakeResponseOk(packet *models.MySQLHandshakeResponseOk, header *models.MySQLPacketHeader) ([]byte, error) {
 	var buf bytes.Buffer
 	var payload []byte
+	// older mocks do not carry the header, the server used to reply with sequence id 2 after the handshake response
+	sequenceID := byte(2)
+	if packet.PluginDetails.Type == "PublicKeyAuthentication" {
+		sequenceID = 4
+	}
+	if header != nil && header.PacketNumber != 0 {
+		sequenceID = header.PacketNumber
+	}
 	if packet.PluginDetails.Type == "PublicKeyAuthentication" {
 		publicKeydata := []byte(packet.RemainingBytes)
 
@@ -79,14 +87,14 @@ func encodeHandshakeResponseOk(packet *models.MySQLHandshakeResponseOk) ([]byte,
 		payloadLength := len(publicKeydata) + 1 // +1 for the MySQL protocol version byte
 
 		// Construct the MySQL packet header
-		header := make([]byte, 4)
-		header[0] = byte(payloadLength & 0xFF)         // Least significant byte
-		header[1] = byte((payloadLength >> 8) & 0xFF)  // Middle byte
-		header[2] = byte((payloadLength >> 16) & 0xFF) // Most significant byte
-		header[3] = 4                                  // Sequence ID
+		packetHeader := make([]byte, 4)
+		packetHeader[0] = byte(payloadLength & 0xFF)         // Least significant byte
+		packetHeader[1] = byte((payloadLength >> 8) & 0xFF)  // Middle byte
+		packetHeader[2] = byte((payloadLength >> 16) & 0xFF) // Most significant byte
+		packetHeader[3] = sequenceID                         // Sequence ID
 
 		// Append the MySQL protocol version byte and the public key data to the header
-		finalData := append(header, 0x01) // MySQL protocol version
+		finalData := append(packetHeader, 0x01) // MySQL protocol version
 		finalData = append(finalData, publicKeydata...)
 
 		buf.Write(finalData)
@@ -122,19 +130,22 @@ func encodeHandshakeResponseOk(packet *models.MySQLHandshakeResponseOk) ([]byte,
 			buf.WriteByte(authData)
 		}
 
+		// remaining bytes belong to the packets which followed in the same read and carry their own header
+		payloadLength := buf.Len()
+
 		// Write remaining bytes if available
 		if len(packet.RemainingBytes) > 0 {
 			buf.Write(packet.RemainingBytes)
 		}
 
 		// Create header
-		header := make([]byte, 4)
-		header[0] = 2 // sequence number
-		header[1] = 0
-		header[2] = 0
-		header[3] = 2
+		packetHeader := make([]byte, 4)
+		packetHeader[0] = byte(payloadLength)
+		packetHeader[1] = byte(payloadLength >> 8)
+		packetHeader[2] = byte(payloadLength >> 16)
+		packetHeader[3] = sequenceID
 		// Prepend header to the payload
-		payload = append(header, buf.Bytes()...)
+		payload = append(packetHeader, buf.Bytes()...)
 	}
 	return payload, nil
 }
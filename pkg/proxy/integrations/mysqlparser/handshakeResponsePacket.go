@@ -10,7 +10,7 @@ const (
 	CLIENT_PLUGIN_AUTH                = 0x00080000
 	CLIENT_CONNECT_WITH_DB            = 0x00000008
 	CLIENT_CONNECT_ATTRS              = 0x00100000
-	CLIENT_ZSTD_COMPRESSION_ALGORITHM = 0x00010000
+	CLIENT_ZSTD_COMPRESSION_ALGORITHM = 0x04000000
 )
 
 type HandshakeResponse struct {
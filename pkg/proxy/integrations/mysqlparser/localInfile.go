@@ -0,0 +1,164 @@
+package mysqlparser
+
+import (
+	"context"
+	"fmt"
+	"net"
+
+	"go.keploy.io/server/pkg/hooks"
+	"go.keploy.io/server/pkg/models"
+	"go.keploy.io/server/pkg/proxy/util"
+	"go.uber.org/zap"
+)
+
+// A LOAD DATA LOCAL INFILE query is answered by a LOCAL INFILE request (0xFB) carrying the name of the file, whose
+// content the client streams in packets ended by an empty one, before the server acknowledges it with an OK or an
+// ERR packet. The whole exchange is recorded as one mock of two requests, the query and the content of the file, and
+// of two responses, the LOCAL INFILE request and the acknowledgement.
+
+const localInfileHeader = 0xFB
+
+type LocalInfileRequest struct {
+	Filename string `yaml:"filename"`
+}
+
+type LocalInfileContent struct {
+	Content string `yaml:"content"`
+	Packets int    `yaml:"packets"`
+}
+
+// isLocalInfileRequest reports whether the response of a query is a LOCAL INFILE request.
+func isLocalInfileRequest(packets []byte) bool {
+	return len(packets) > 4 && packets[4] == localInfileHeader
+}
+
+func decodeLocalInfileRequest(data []byte) (*LocalInfileRequest, error) {
+	if len(data) < 1 || data[0] != localInfileHeader {
+		return nil, fmt.Errorf("invalid LOCAL INFILE request")
+	}
+	return &LocalInfileRequest{Filename: string(data[1:])}, nil
+}
+
+func encodeLocalInfileRequest(packet *models.MySQLLocalInfileRequest) ([]byte, error) {
+	return append([]byte{localInfileHeader}, packet.Filename...), nil
+}
+
+// localInfileEnded reports whether the packets end with the empty packet closing the content of the file.
+func localInfileEnded(packets []byte) bool {
+	for len(packets) >= 4 {
+		length := int(readUint24(packets[0:3]))
+		if len(packets) < 4+length {
+			return false
+		}
+		if length == 0 && len(packets) == 4 {
+			return true
+		}
+		packets = packets[4+length:]
+	}
+	return false
+}
+
+// readLocalInfileContent reads the packets of the file streamed by the client, following the ones already read,
+// until the empty one ending them. The buffers are forwarded to the server when there is one. It returns the
+// decompressed packets and the sequence id of the last compressed one.
+func readLocalInfileContent(packets []byte, sequenceID byte, clientConn, destConn net.Conn, compression compressionAlgorithm) ([]byte, byte, error) {
+	for !localInfileEnded(packets) {
+		buffer, err := util.ReadBytes(clientConn)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to read the LOCAL INFILE content from the mysql client: %v", err)
+		}
+		if destConn != nil {
+			if _, err := destConn.Write(buffer); err != nil {
+				return nil, 0, fmt.Errorf("failed to write the LOCAL INFILE content to the mysql server: %v", err)
+			}
+		}
+		next, nextSequenceID, err := decompressPackets(buffer, compression)
+		if err != nil {
+			return nil, 0, err
+		}
+		packets, sequenceID = append(packets, next...), nextSequenceID
+	}
+	return packets, sequenceID, nil
+}
+
+// decodeLocalInfileContent returns the content of the file streamed in the packets, with the header of the empty
+// packet ending them and the length of the whole content.
+func decodeLocalInfileContent(packets []byte) (*LocalInfileContent, MySQLPacketHeader, error) {
+	content := &LocalInfileContent{}
+	var data []byte
+	for len(packets) >= 4 {
+		length := int(readUint24(packets[0:3]))
+		if len(packets) < 4+length {
+			return nil, MySQLPacketHeader{}, fmt.Errorf("LOCAL INFILE packet length %d exceeds the remaining %d bytes", length, len(packets)-4)
+		}
+		if length == 0 {
+			content.Content = string(data)
+			return content, MySQLPacketHeader{PayloadLength: uint32(len(data)), SequenceID: packets[3]}, nil
+		}
+		data = append(data, packets[4:4+length]...)
+		content.Packets++
+		packets = packets[4+length:]
+	}
+	return nil, MySQLPacketHeader{}, fmt.Errorf("the LOCAL INFILE content isn't ended by an empty packet")
+}
+
+// recordLocalInfile forwards the file streamed by the client for the LOCAL INFILE request of the server, which was
+// sent to the client, and the acknowledgement of the server, and records the whole exchange.
+func recordLocalInfile(h *hooks.Hook, query models.MySQLRequest, responsePackets []byte, clientConn, destConn net.Conn, logger *zap.Logger, ctx context.Context, compression compressionAlgorithm, statements *statementTable) error {
+	responsePacket := bytesToMySQLPacket(responsePackets)
+	infileRequest, err := decodeLocalInfileRequest(responsePacket.Payload)
+	if err != nil {
+		return err
+	}
+	contentPackets, _, err := readLocalInfileContent(nil, 0, clientConn, destConn, compression)
+	if err != nil {
+		return err
+	}
+	content, contentHeader, err := decodeLocalInfileContent(contentPackets)
+	if err != nil {
+		return err
+	}
+
+	ack, err := util.ReadBytes(destConn)
+	if err != nil {
+		return fmt.Errorf("failed to read the LOCAL INFILE acknowledgement from the mysql server: %v", err)
+	}
+	if _, err := clientConn.Write(ack); err != nil {
+		return fmt.Errorf("failed to write the LOCAL INFILE acknowledgement to the mysql client: %v", err)
+	}
+	ackPackets, _, err := decompressPackets(ack, compression)
+	if err != nil {
+		return err
+	}
+	ackOperation, ackHeader, ackMessage, err := DecodeMySQLPacket(bytesToMySQLPacket(ackPackets), logger, destConn, statements)
+	if err != nil {
+		return err
+	}
+	logger.Debug("recorded the LOCAL INFILE content of the mysql client", zap.String("filename", infileRequest.Filename), zap.Int("bytes", len(content.Content)))
+
+	requests := []models.MySQLRequest{query, {
+		Header: &models.MySQLPacketHeader{
+			PacketLength: contentHeader.PayloadLength,
+			PacketNumber: contentHeader.SequenceID,
+			PacketType:   "LOCAL_INFILE_CONTENT",
+		},
+		Message: content,
+	}}
+	responses := []models.MySQLResponse{{
+		Header: &models.MySQLPacketHeader{
+			PacketLength: responsePacket.Header.PayloadLength,
+			PacketNumber: responsePacket.Header.SequenceID,
+			PacketType:   "LOCAL_INFILE_REQUEST",
+		},
+		Message: infileRequest,
+	}, {
+		Header: &models.MySQLPacketHeader{
+			PacketLength: ackHeader.PayloadLength,
+			PacketNumber: ackHeader.SequenceID,
+			PacketType:   ackOperation,
+		},
+		Message: ackMessage,
+	}}
+	recordMySQLMessage(h, requests, responses, query.Header.PacketType, ackOperation, "mocks", ctx)
+	return nil
+}
@@ -0,0 +1,189 @@
+package mysqlparser
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"go.keploy.io/server/pkg/models"
+)
+
+// serverMoreResultsExists is set in the status flags of the EOF/OK packet ending a result when another one follows,
+// as for stored procedure calls and multi statement queries.
+const serverMoreResultsExists = 0x0008
+
+// MultiResultSet holds a chain of results sent for a single command.
+type MultiResultSet struct {
+	Results []*Result `yaml:"results"`
+}
+
+// Result is one element of a MultiResultSet, only one of its fields is set.
+type Result struct {
+	ResultSet *ResultSet `yaml:"result_set,omitempty"`
+	OK        *OKPacket  `yaml:"ok,omitempty"`
+	Err       *ERRPacket `yaml:"err,omitempty"`
+}
+
+// splitResults splits a response into the raw results it is made of, each keeping the headers of its packets.
+// It reports whether the server announced more results which aren't part of the response yet.
+func splitResults(response []byte) ([][]byte, bool, error) {
+	packets, err := splitPackets(response)
+	if err != nil {
+		return nil, false, err
+	}
+
+	var results [][]byte
+	for len(packets) > 0 {
+		var (
+			consumed    int
+			statusFlags uint16
+		)
+		payload := packets[0][4:]
+		if len(payload) == 0 {
+			return nil, false, errors.New("empty packet in the response")
+		}
+		switch payload[0] {
+		case 0xff:
+			// an error ends the chain
+			consumed = 1
+		case 0x00:
+			consumed = 1
+			statusFlags = okStatusFlags(payload)
+		default:
+			consumed, statusFlags, err = resultSetPackets(packets)
+			if err != nil {
+				return nil, false, err
+			}
+		}
+
+		var result []byte
+		for _, packet := range packets[:consumed] {
+			result = append(result, packet...)
+		}
+		results = append(results, result)
+		packets = packets[consumed:]
+
+		if statusFlags&serverMoreResultsExists == 0 {
+			break
+		}
+		if len(packets) == 0 {
+			return results, true, nil
+		}
+	}
+	return results, false, nil
+}
+
+// resultSetPackets returns the number of packets of the resultset starting the given packets along with the
+// status flags of the packet terminating it.
+func resultSetPackets(packets [][]byte) (int, uint16, error) {
+	columnCount, _, _ := readLengthEncodedInteger(packets[0][4:])
+	consumed := 1 + int(columnCount)
+	if len(packets) < consumed {
+		return 0, 0, errors.New("response too short for the column definitions")
+	}
+	// the EOF after the column definitions is absent when CLIENT_DEPRECATE_EOF is set
+	if len(packets) > consumed && isEOFPacket(packets[consumed][4:]) {
+		consumed++
+	}
+	for ; consumed < len(packets); consumed++ {
+		payload := packets[consumed][4:]
+		if len(payload) > 0 && payload[0] == 0xff {
+			return consumed + 1, 0, nil
+		}
+		if len(payload) > 0 && payload[0] == 0xfe && len(payload) < 0xffffff {
+			if isEOFPacket(payload) {
+				return consumed + 1, binary.LittleEndian.Uint16(payload[3:5]), nil
+			}
+			// OK packet with the 0xfe header when CLIENT_DEPRECATE_EOF is set
+			return consumed + 1, okStatusFlags(payload), nil
+		}
+	}
+	return 0, 0, errors.New("response too short for the rows of the resultset")
+}
+
+func isEOFPacket(payload []byte) bool {
+	return len(payload) >= 5 && len(payload) < 9 && payload[0] == 0xfe
+}
+
+func okStatusFlags(payload []byte) uint16 {
+	offset := 1
+	if _, err := readLengthEncodedIntegerOff(payload, &offset); err != nil {
+		return 0
+	}
+	if _, err := readLengthEncodedIntegerOff(payload, &offset); err != nil {
+		return 0
+	}
+	if len(payload) < offset+2 {
+		return 0
+	}
+	return binary.LittleEndian.Uint16(payload[offset:])
+}
+
+// hasMoreResults reports whether the server announced results which haven't been read yet.
+func hasMoreResults(response []byte) bool {
+	_, pending, err := splitResults(response)
+	return err == nil && pending
+}
+
+func decodeMultiResultSet(results [][]byte, binaryProtocol bool) (*MultiResultSet, error) {
+	multiResultSet := &MultiResultSet{}
+	for _, result := range results {
+		payload := result[4:]
+		var (
+			decoded = &Result{}
+			err     error
+		)
+		switch payload[0] {
+		case 0x00:
+			decoded.OK, err = decodeMySQLOK(payload)
+		case 0xff:
+			decoded.Err, err = decodeMySQLErr(payload)
+		default:
+			if binaryProtocol {
+				decoded.ResultSet, err = parseBinaryResultSet(payload)
+			} else {
+				decoded.ResultSet, err = parseResultSet(payload)
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+		multiResultSet.Results = append(multiResultSet.Results, decoded)
+	}
+	return multiResultSet, nil
+}
+
+func encodeMultiResultSet(multiResultSet *models.MySQLMultiResultSet) ([]byte, error) {
+	buf := new(bytes.Buffer)
+	// the sequence ids continue across the results of the chain
+	sequenceID := byte(1)
+	for _, result := range multiResultSet.Results {
+		var (
+			data []byte
+			err  error
+		)
+		switch {
+		case result.ResultSet != nil:
+			data, err = encodeMySQLResultSet(result.ResultSet)
+		case result.OK != nil:
+			data, err = encodeMySQLOK(result.OK, &models.MySQLPacketHeader{})
+		case result.Err != nil:
+			data, err = encodeMySQLErr(result.Err, &models.MySQLPacketHeader{})
+		default:
+			return nil, errors.New("empty result in the multi resultset")
+		}
+		if err != nil {
+			return nil, err
+		}
+		packets, err := splitPackets(data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resequence the result: %v", err)
+		}
+		for _, packet := range packets {
+			buf.Write(withSequenceID(packet, sequenceID))
+			sequenceID++
+		}
+	}
+	return buf.Bytes(), nil
+}
@@ -1,6 +1,7 @@
 package mysqlparser
 
 import (
+	"bytes"
 	"context"
 	"encoding/binary"
 	"fmt"
@@ -9,22 +10,29 @@ import (
 	"time"
 
 	"go.keploy.io/server/pkg/hooks"
+	"go.keploy.io/server/pkg/metrics"
 	"go.keploy.io/server/pkg/models"
+	"go.keploy.io/server/pkg/proxy/integrations/matcher"
+	"go.keploy.io/server/pkg/proxy/integrations/sqlquery"
 	"go.keploy.io/server/pkg/proxy/util"
 	"go.uber.org/zap"
 )
 
 type MySqlParser struct {
-	logger *zap.Logger
-	hooks  *hooks.Hook
-	delay  uint64
+	logger     *zap.Logger
+	hooks      *hooks.Hook
+	delay      uint64
+	sqlConfig  models.SqlConfig
+	upgradeTLS TLSUpgrader
 }
 
-func NewMySqlParser(logger *zap.Logger, hooks *hooks.Hook, delay uint64) *MySqlParser {
+func NewMySqlParser(logger *zap.Logger, hooks *hooks.Hook, delay uint64, sqlConfig models.SqlConfig, upgradeTLS TLSUpgrader) *MySqlParser {
 	return &MySqlParser{
-		logger: logger,
-		hooks:  hooks,
-		delay:  delay,
+		logger:     logger,
+		hooks:      hooks,
+		delay:      delay,
+		sqlConfig:  sqlConfig,
+		upgradeTLS: upgradeTLS,
 	}
 }
 
@@ -36,9 +44,9 @@ func (sql *MySqlParser) ProcessOutgoing(requestBuffer []byte, clientConn, destCo
 	delay := sql.delay
 	switch models.GetMode() {
 	case models.MODE_RECORD:
-		encodeOutgoingMySql(requestBuffer, clientConn, destConn, sql.hooks, sql.logger, ctx)
+		encodeOutgoingMySql(requestBuffer, clientConn, destConn, sql.hooks, sql.logger, ctx, sql.upgradeTLS)
 	case models.MODE_TEST:
-		decodeOutgoingMySQL(requestBuffer, clientConn, destConn, sql.hooks, sql.logger, ctx, delay)
+		decodeOutgoingMySQL(requestBuffer, clientConn, destConn, sql.hooks, sql.logger, ctx, delay, sql.sqlConfig, sql.upgradeTLS)
 	default:
 	}
 }
@@ -56,11 +64,13 @@ var (
 	expectingHandshakeResponse = false
 )
 
-func encodeOutgoingMySql(requestBuffer []byte, clientConn, destConn net.Conn, h *hooks.Hook, logger *zap.Logger, ctx context.Context) {
+func encodeOutgoingMySql(requestBuffer []byte, clientConn, destConn net.Conn, h *hooks.Hook, logger *zap.Logger, ctx context.Context, upgradeTLS TLSUpgrader) {
 	var (
 		mysqlRequests  = []models.MySQLRequest{}
 		mysqlResponses = []models.MySQLResponse{}
 	)
+	// the prepared statements of the connection
+	statements := newStatementTable()
 	for {
 		lastCommand = 0x00 //resetting last command for new loop
 		data, source, err := ReadFirstBuffer(clientConn, destConn)
@@ -83,6 +93,23 @@ func encodeOutgoingMySql(requestBuffer []byte, clientConn, destConn net.Conn, h
 				logger.Error("failed to read handshake response from client", zap.Error(err))
 				return
 			}
+			if isSSLRequest(handshakeResponseFromClient) {
+				_, err = destConn.Write(handshakeResponseFromClient)
+				if err != nil {
+					logger.Error("failed to write SSL request to server", zap.Error(err))
+					return
+				}
+				clientConn, destConn, err = upgradeToTLS(clientConn, destConn, upgradeTLS, logger)
+				if err != nil {
+					return
+				}
+				// the handshake response follows over TLS
+				handshakeResponseFromClient, err = util.ReadBytes(clientConn)
+				if err != nil {
+					logger.Error("failed to read handshake response from client", zap.Error(err))
+					return
+				}
+			}
 			_, err = destConn.Write(handshakeResponseFromClient)
 			if err != nil {
 				logger.Error("failed to write handshake response to server", zap.Error(err))
@@ -100,7 +127,7 @@ func encodeOutgoingMySql(requestBuffer []byte, clientConn, destConn net.Conn, h
 				return
 			}
 			expectingHandshakeResponse = true
-			oprRequest, requestHeader, mysqlRequest, err := DecodeMySQLPacket(bytesToMySQLPacket(handshakeResponseFromClient), logger, destConn)
+			oprRequest, requestHeader, mysqlRequest, err := DecodeMySQLPacket(bytesToMySQLPacket(handshakeResponseFromClient), logger, destConn, statements)
 			if err != nil {
 				logger.Error("failed to decode MySQL packet from client", zap.Error(err))
 				return
@@ -114,7 +141,12 @@ func encodeOutgoingMySql(requestBuffer []byte, clientConn, destConn net.Conn, h
 				Message: mysqlRequest,
 			})
 			expectingHandshakeResponse = false
-			oprResponse1, responseHeader1, mysqlResp1, err := DecodeMySQLPacket(bytesToMySQLPacket(handshakeResponseBuffer), logger, destConn)
+			// the compressed protocol, if negotiated, starts once the authentication is over
+			compression := noCompression
+			if handshakeResponse, ok := mysqlRequest.(*HandshakeResponse); ok {
+				compression = compressionOf(handshakeResponse.CapabilityFlags)
+			}
+			oprResponse1, responseHeader1, mysqlResp1, err := DecodeMySQLPacket(bytesToMySQLPacket(handshakeResponseBuffer), logger, destConn, statements)
 			if err != nil {
 				logger.Error("failed to decode MySQL packet from destination", zap.Error(err))
 				return
@@ -127,7 +159,7 @@ func encodeOutgoingMySql(requestBuffer []byte, clientConn, destConn net.Conn, h
 				},
 				Message: mysqlResp1,
 			})
-			oprResponse2, responseHeader2, mysqlResp2, err := DecodeMySQLPacket(bytesToMySQLPacket(okPacket1), logger, destConn)
+			oprResponse2, responseHeader2, mysqlResp2, err := DecodeMySQLPacket(bytesToMySQLPacket(okPacket1), logger, destConn, statements)
 			if err != nil {
 				logger.Error("failed to decode MySQL packet from OK packet", zap.Error(err))
 				return
@@ -164,7 +196,7 @@ func encodeOutgoingMySql(requestBuffer []byte, clientConn, destConn net.Conn, h
 				}
 				expectingAuthSwitchResponse = true
 
-				oprRequestFinal, requestHeaderFinal, mysqlRequestFinal, err := DecodeMySQLPacket(bytesToMySQLPacket(authSwitchResponse), logger, destConn)
+				oprRequestFinal, requestHeaderFinal, mysqlRequestFinal, err := DecodeMySQLPacket(bytesToMySQLPacket(authSwitchResponse), logger, destConn, statements)
 				if err != nil {
 					logger.Error("failed to decode MySQL packet from client after full authentication", zap.Error(err))
 					return
@@ -180,7 +212,7 @@ func encodeOutgoingMySql(requestBuffer []byte, clientConn, destConn net.Conn, h
 				expectingAuthSwitchResponse = false
 
 				isPluginData = true
-				oprResponse, responseHeader, mysqlResp, err := DecodeMySQLPacket(bytesToMySQLPacket(ServerResponse), logger, destConn)
+				oprResponse, responseHeader, mysqlResp, err := DecodeMySQLPacket(bytesToMySQLPacket(ServerResponse), logger, destConn, statements)
 				isPluginData = false
 				if err != nil {
 					logger.Error("failed to decode MySQL packet from destination after full authentication", zap.Error(err))
@@ -200,107 +232,14 @@ func encodeOutgoingMySql(requestBuffer []byte, clientConn, destConn net.Conn, h
 					pluginType = handshakeResp.PluginDetails.Type
 				}
 				if pluginType == "cachingSha2PasswordPerformFullAuthentication" {
-
-					clientResponse, err := util.ReadBytes(clientConn)
-					if err != nil {
-						logger.Error("failed to read response from client", zap.Error(err))
-						return
-					}
-					_, err = destConn.Write(clientResponse)
-					if err != nil {
-						logger.Error("failed to write client's response to server", zap.Error(err))
-						return
-					}
-					finalServerResponse, err := util.ReadBytes(destConn)
-					if err != nil {
-						logger.Error("failed to read final response from server", zap.Error(err))
-						return
-					}
-					_, err = clientConn.Write(finalServerResponse)
-					if err != nil {
-						logger.Error("failed to write final response to client", zap.Error(err))
-						return
-					}
-					oprRequestFinal, requestHeaderFinal, mysqlRequestFinal, err := DecodeMySQLPacket(bytesToMySQLPacket(clientResponse), logger, destConn)
-					if err != nil {
-						logger.Error("failed to decode MySQL packet from client after full authentication", zap.Error(err))
-						return
-					}
-					mysqlRequests = append(mysqlRequests, models.MySQLRequest{
-						Header: &models.MySQLPacketHeader{
-							PacketLength: requestHeaderFinal.PayloadLength,
-							PacketNumber: requestHeaderFinal.SequenceID,
-							PacketType:   oprRequestFinal,
-						},
-						Message: mysqlRequestFinal,
-					})
-					isPluginData = true
-					oprResponseFinal, responseHeaderFinal, mysqlRespFinal, err := DecodeMySQLPacket(bytesToMySQLPacket(finalServerResponse), logger, destConn)
-					isPluginData = false
-					if err != nil {
-						logger.Error("failed to decode MySQL packet from destination after full authentication", zap.Error(err))
-						return
-					}
-					mysqlResponses = append(mysqlResponses, models.MySQLResponse{
-						Header: &models.MySQLPacketHeader{
-							PacketLength: responseHeaderFinal.PayloadLength,
-							PacketNumber: responseHeaderFinal.SequenceID,
-							PacketType:   oprResponseFinal,
-						},
-						Message: mysqlRespFinal,
-					})
-					clientResponse1, err := util.ReadBytes(clientConn)
-					if err != nil {
-						logger.Error("failed to read response from client", zap.Error(err))
-						return
-					}
-					_, err = destConn.Write(clientResponse1)
+					authRequests, authResponses, err := handleFullAuthentication(clientConn, destConn, logger, statements)
 					if err != nil {
-						logger.Error("failed to write client's response to server", zap.Error(err))
+						logger.Error("failed to complete the full authentication after auth switch", zap.Error(err))
 						return
 					}
-					finalServerResponse1, err := util.ReadBytes(destConn)
-					if err != nil {
-						logger.Error("failed to read final response from server", zap.Error(err))
-						return
-					}
-					_, err = clientConn.Write(finalServerResponse1)
-					if err != nil {
-						logger.Error("failed to write final response to client", zap.Error(err))
-						return
-					}
-					finalServerResponsetype1, finalServerResponseHeader1, mysqlRespfinalServerResponse, err := DecodeMySQLPacket(bytesToMySQLPacket(finalServerResponse1), logger, destConn)
-					if err != nil {
-						logger.Error("failed to decode MySQL packet from final server response", zap.Error(err))
-						return
-					}
-					mysqlResponses = append(mysqlResponses, models.MySQLResponse{
-						Header: &models.MySQLPacketHeader{
-							PacketLength: finalServerResponseHeader1.PayloadLength,
-							PacketNumber: finalServerResponseHeader1.SequenceID,
-							PacketType:   finalServerResponsetype1,
-						},
-						Message: mysqlRespfinalServerResponse,
-					})
-					oprRequestFinal1, requestHeaderFinal1, err := decodeEncryptPassword(clientResponse1)
-					if err != nil {
-						logger.Error("failed to decode MySQL packet from client after full authentication", zap.Error(err))
-						return
-					}
-					type DataMessage struct {
-						Data []byte
-					}
-					mysqlRequests = append(mysqlRequests, models.MySQLRequest{
-						Header: &models.MySQLPacketHeader{
-							PacketLength: requestHeaderFinal1.PayloadLength,
-							PacketNumber: requestHeaderFinal1.SequenceID,
-							PacketType:   oprRequestFinal1,
-						},
-						Message: DataMessage{
-							Data: requestHeaderFinal1.Payload,
-						},
-					})
-				} else {
+					mysqlRequests = append(mysqlRequests, authRequests...)
+					mysqlResponses = append(mysqlResponses, authResponses...)
+				} else if pluginType == "cachingSha2PasswordFastAuthSuccess" {
 					// time.Sleep(10 * time.Millisecond)
 					finalServerResponse, err := util.ReadBytes(destConn)
 					if err != nil {
@@ -312,7 +251,7 @@ func encodeOutgoingMySql(requestBuffer []byte, clientConn, destConn net.Conn, h
 						logger.Error("failed to write final response to client", zap.Error(err))
 						return
 					}
-					oprResponseFinal, responseHeaderFinal, mysqlRespFinal, err := DecodeMySQLPacket(bytesToMySQLPacket(finalServerResponse), logger, destConn)
+					oprResponseFinal, responseHeaderFinal, mysqlRespFinal, err := DecodeMySQLPacket(bytesToMySQLPacket(finalServerResponse), logger, destConn, statements)
 					isPluginData = false
 					if err != nil {
 						logger.Error("failed to decode MySQL packet from destination after full authentication", zap.Error(err))
@@ -336,118 +275,138 @@ func encodeOutgoingMySql(requestBuffer []byte, clientConn, destConn net.Conn, h
 				pluginType = handshakeResp.PluginDetails.Type
 			}
 			if pluginType == "cachingSha2PasswordPerformFullAuthentication" {
-
-				clientResponse, err := util.ReadBytes(clientConn)
-				if err != nil {
-					logger.Error("failed to read response from client", zap.Error(err))
-					return
-				}
-				_, err = destConn.Write(clientResponse)
-				if err != nil {
-					logger.Error("failed to write client's response to server", zap.Error(err))
-					return
-				}
-				finalServerResponse, err := util.ReadBytes(destConn)
-				if err != nil {
-					logger.Error("failed to read final response from server", zap.Error(err))
-					return
-				}
-				_, err = clientConn.Write(finalServerResponse)
-				if err != nil {
-					logger.Error("failed to write final response to client", zap.Error(err))
-					return
-				}
-				oprRequestFinal, requestHeaderFinal, mysqlRequestFinal, err := DecodeMySQLPacket(bytesToMySQLPacket(clientResponse), logger, destConn)
-				if err != nil {
-					logger.Error("failed to decode MySQL packet from client after full authentication", zap.Error(err))
-					return
-				}
-				mysqlRequests = append(mysqlRequests, models.MySQLRequest{
-					Header: &models.MySQLPacketHeader{
-						PacketLength: requestHeaderFinal.PayloadLength,
-						PacketNumber: requestHeaderFinal.SequenceID,
-						PacketType:   oprRequestFinal,
-					},
-					Message: mysqlRequestFinal,
-				})
-				isPluginData = true
-				oprResponseFinal, responseHeaderFinal, mysqlRespFinal, err := DecodeMySQLPacket(bytesToMySQLPacket(finalServerResponse), logger, destConn)
-				isPluginData = false
-				if err != nil {
-					logger.Error("failed to decode MySQL packet from destination after full authentication", zap.Error(err))
-					return
-				}
-				mysqlResponses = append(mysqlResponses, models.MySQLResponse{
-					Header: &models.MySQLPacketHeader{
-						PacketLength: responseHeaderFinal.PayloadLength,
-						PacketNumber: responseHeaderFinal.SequenceID,
-						PacketType:   oprResponseFinal,
-					},
-					Message: mysqlRespFinal,
-				})
-				clientResponse1, err := util.ReadBytes(clientConn)
-				if err != nil {
-					logger.Error("failed to read response from client", zap.Error(err))
-					return
-				}
-				_, err = destConn.Write(clientResponse1)
-				if err != nil {
-					logger.Error("failed to write client's response to server", zap.Error(err))
-					return
-				}
-				finalServerResponse1, err := util.ReadBytes(destConn)
-				if err != nil {
-					logger.Error("failed to read final response from server", zap.Error(err))
-					return
-				}
-				_, err = clientConn.Write(finalServerResponse1)
-				if err != nil {
-					logger.Error("failed to write final response to client", zap.Error(err))
-					return
-				}
-				finalServerResponsetype1, finalServerResponseHeader1, mysqlRespfinalServerResponse, err := DecodeMySQLPacket(bytesToMySQLPacket(finalServerResponse1), logger, destConn)
-				if err != nil {
-					logger.Error("failed to decode MySQL packet from final server response", zap.Error(err))
-					return
-				}
-				mysqlResponses = append(mysqlResponses, models.MySQLResponse{
-					Header: &models.MySQLPacketHeader{
-						PacketLength: finalServerResponseHeader1.PayloadLength,
-						PacketNumber: finalServerResponseHeader1.SequenceID,
-						PacketType:   finalServerResponsetype1,
-					},
-					Message: mysqlRespfinalServerResponse,
-				})
-				oprRequestFinal1, requestHeaderFinal1, err := decodeEncryptPassword(clientResponse1)
+				authRequests, authResponses, err := handleFullAuthentication(clientConn, destConn, logger, statements)
 				if err != nil {
-					logger.Error("failed to decode MySQL packet from client after full authentication", zap.Error(err))
+					logger.Error("failed to complete the full authentication", zap.Error(err))
 					return
 				}
-				type DataMessage struct {
-					Data []byte
-				}
-				mysqlRequests = append(mysqlRequests, models.MySQLRequest{
-					Header: &models.MySQLPacketHeader{
-						PacketLength: requestHeaderFinal1.PayloadLength,
-						PacketNumber: requestHeaderFinal1.SequenceID,
-						PacketType:   oprRequestFinal1,
-					},
-					Message: DataMessage{
-						Data: requestHeaderFinal1.Payload,
-					},
-				})
+				mysqlRequests = append(mysqlRequests, authRequests...)
+				mysqlResponses = append(mysqlResponses, authResponses...)
 			}
 			recordMySQLMessage(h, mysqlRequests, mysqlResponses, oprRequest, oprResponse2, "config", ctx)
 			mysqlRequests = []models.MySQLRequest{}
 			mysqlResponses = []models.MySQLResponse{}
-			handleClientQueries(h, nil, clientConn, destConn, logger, ctx)
+			handleClientQueries(h, nil, clientConn, destConn, logger, ctx, compression, statements)
 		} else if source == "client" {
-			handleClientQueries(h, nil, clientConn, destConn, logger, ctx)
+			handleClientQueries(h, nil, clientConn, destConn, logger, ctx, noCompression, statements)
 		}
 	}
 	return
 }
 
+// handleFullAuthentication proxies the caching_sha2_password full authentication exchange between the client
+// and the server. The client either asks for the server's RSA public key before sending the encrypted password
+// or, when it already has the key (or the connection is secure), sends the password straight away.
+func handleFullAuthentication(clientConn, destConn net.Conn, logger *zap.Logger, statements *statementTable) ([]models.MySQLRequest, []models.MySQLResponse, error) {
+	var (
+		mysqlRequests  = []models.MySQLRequest{}
+		mysqlResponses = []models.MySQLResponse{}
+	)
+	clientResponse, err := util.ReadBytes(clientConn)
+	if err != nil {
+		logger.Error("failed to read response from client", zap.Error(err))
+		return nil, nil, err
+	}
+	_, err = destConn.Write(clientResponse)
+	if err != nil {
+		logger.Error("failed to write client's response to server", zap.Error(err))
+		return nil, nil, err
+	}
+
+	if isPublicKeyRequest(clientResponse) {
+		oprRequest, requestHeader, mysqlRequest, err := DecodeMySQLPacket(bytesToMySQLPacket(clientResponse), logger, destConn, statements)
+		if err != nil {
+			logger.Error("failed to decode the public key request from client", zap.Error(err))
+			return nil, nil, err
+		}
+		mysqlRequests = append(mysqlRequests, models.MySQLRequest{
+			Header: &models.MySQLPacketHeader{
+				PacketLength: requestHeader.PayloadLength,
+				PacketNumber: requestHeader.SequenceID,
+				PacketType:   oprRequest,
+			},
+			Message: mysqlRequest,
+		})
+
+		publicKeyResponse, err := util.ReadBytes(destConn)
+		if err != nil {
+			logger.Error("failed to read the public key from server", zap.Error(err))
+			return nil, nil, err
+		}
+		_, err = clientConn.Write(publicKeyResponse)
+		if err != nil {
+			logger.Error("failed to write the public key to client", zap.Error(err))
+			return nil, nil, err
+		}
+		isPluginData = true
+		oprResponse, responseHeader, mysqlResp, err := DecodeMySQLPacket(bytesToMySQLPacket(publicKeyResponse), logger, destConn, statements)
+		isPluginData = false
+		if err != nil {
+			logger.Error("failed to decode the public key packet from server", zap.Error(err))
+			return nil, nil, err
+		}
+		mysqlResponses = append(mysqlResponses, models.MySQLResponse{
+			Header: &models.MySQLPacketHeader{
+				PacketLength: responseHeader.PayloadLength,
+				PacketNumber: responseHeader.SequenceID,
+				PacketType:   oprResponse,
+			},
+			Message: mysqlResp,
+		})
+
+		// the password encrypted with the public key follows
+		clientResponse, err = util.ReadBytes(clientConn)
+		if err != nil {
+			logger.Error("failed to read the encrypted password from client", zap.Error(err))
+			return nil, nil, err
+		}
+		_, err = destConn.Write(clientResponse)
+		if err != nil {
+			logger.Error("failed to write the encrypted password to server", zap.Error(err))
+			return nil, nil, err
+		}
+	}
+
+	oprPassword, passwordData, err := decodeEncryptPassword(clientResponse)
+	if err != nil {
+		logger.Error("failed to decode the password packet from client", zap.Error(err))
+		return nil, nil, err
+	}
+	mysqlRequests = append(mysqlRequests, models.MySQLRequest{
+		Header: &models.MySQLPacketHeader{
+			PacketLength: passwordData.PayloadLength,
+			PacketNumber: passwordData.SequenceID,
+			PacketType:   oprPassword,
+		},
+		Message: passwordData,
+	})
+
+	finalServerResponse, err := util.ReadBytes(destConn)
+	if err != nil {
+		logger.Error("failed to read final response from server", zap.Error(err))
+		return nil, nil, err
+	}
+	_, err = clientConn.Write(finalServerResponse)
+	if err != nil {
+		logger.Error("failed to write final response to client", zap.Error(err))
+		return nil, nil, err
+	}
+	oprResponseFinal, responseHeaderFinal, mysqlRespFinal, err := DecodeMySQLPacket(bytesToMySQLPacket(finalServerResponse), logger, destConn, statements)
+	if err != nil {
+		logger.Error("failed to decode MySQL packet from destination after full authentication", zap.Error(err))
+		return nil, nil, err
+	}
+	mysqlResponses = append(mysqlResponses, models.MySQLResponse{
+		Header: &models.MySQLPacketHeader{
+			PacketLength: responseHeaderFinal.PayloadLength,
+			PacketNumber: responseHeaderFinal.SequenceID,
+			PacketType:   oprResponseFinal,
+		},
+		Message: mysqlRespFinal,
+	})
+	return mysqlRequests, mysqlResponses, nil
+}
+
 var (
 	mockResponseRead = 0
 )
@@ -456,10 +415,18 @@ var (
 	expectingHandshakeResponseTest = false
 )
 
-func decodeOutgoingMySQL(requestBuffer []byte, clientConn, destConn net.Conn, h *hooks.Hook, logger *zap.Logger, ctx context.Context, delay uint64) {
+func decodeOutgoingMySQL(requestBuffer []byte, clientConn, destConn net.Conn, h *hooks.Hook, logger *zap.Logger, ctx context.Context, delay uint64, sqlConfig models.SqlConfig, upgradeTLS TLSUpgrader) {
 	firstLoop := true
 	doHandshakeAgain := true
 	prevRequest := ""
+	// set once the server has asked for the full caching_sha2_password authentication
+	expectingPassword := false
+	// set once the mocked server has asked for the file of a LOAD DATA LOCAL INFILE query
+	expectingInfile := false
+	// the compression asked by the client is applied once the authentication is over
+	compression, pendingCompression := noCompression, noCompression
+	// the prepared statements of the connection, registered from the mocked COM_STMT_PREPARE_OK responses
+	statements := newStatementTable()
 	var requestBuffers [][]byte
 	for {
 		configMocks, _ := h.GetConfigMocks()
@@ -528,10 +495,57 @@ func decodeOutgoingMySQL(requestBuffer []byte, clientConn, destConn net.Conn, h
 				return
 			}
 			if prevRequest == "MYSQLHANDSHAKE" {
+				if isSSLRequest(requestBuffer) {
+					// the mocked server advertised TLS, the handshake response follows over it
+					clientConn, _, err = upgradeToTLS(clientConn, nil, upgradeTLS, logger)
+					if err != nil {
+						return
+					}
+					requestBuffers = requestBuffers[:len(requestBuffers)-1]
+					continue
+				}
 				expectingHandshakeResponseTest = true
 			}
 
-			oprRequest, requestHeader, decodedRequest, err := DecodeMySQLPacket(bytesToMySQLPacket(requestBuffer), logger, destConn)
+			var (
+				oprRequest     string
+				requestHeader  MySQLPacketHeader
+				decodedRequest interface{}
+			)
+			requestPackets, requestSequenceID, err := decompressPackets(requestBuffer, compression)
+			if err != nil {
+				logger.Error("Failed to decompress MySQL packet", zap.Error(err), zap.String("compression", compression.String()))
+				return
+			}
+			if expectingInfile {
+				// the content of the file is matched as a whole, whatever the buffers it was streamed in
+				requestPackets, requestSequenceID, err = readLocalInfileContent(requestPackets, requestSequenceID, clientConn, nil, compression)
+				if err == nil {
+					oprRequest = "LOCAL_INFILE_CONTENT"
+					decodedRequest, requestHeader, err = decodeLocalInfileContent(requestPackets)
+				}
+				expectingInfile = false
+			} else if expectingPassword && !isPublicKeyRequest(requestPackets) {
+				// the (encrypted) password is opaque, it can't be decoded as a command packet
+				var passwordData *PasswordData
+				oprRequest, passwordData, err = decodeEncryptPassword(requestPackets)
+				if err == nil {
+					requestHeader = MySQLPacketHeader{
+						PayloadLength: passwordData.PayloadLength,
+						SequenceID:    passwordData.SequenceID,
+					}
+					decodedRequest = passwordData
+				}
+				expectingPassword = false
+			} else if command, ok := replicationCommand(requestPackets); ok {
+				err = refuseReplication(command, requestSequenceID, clientConn, compression, logger)
+				if err != nil {
+					logger.Error("Failed to refuse the replication command", zap.Error(err))
+				}
+				return
+			} else {
+				oprRequest, requestHeader, decodedRequest, err = DecodeMySQLPacket(bytesToMySQLPacket(requestPackets), logger, destConn, statements)
+			}
 			if err != nil {
 				logger.Error("Failed to decode MySQL packet", zap.Error(err))
 				return
@@ -539,6 +553,9 @@ func decodeOutgoingMySQL(requestBuffer []byte, clientConn, destConn net.Conn, h
 			if oprRequest == "COM_QUIT" {
 				return
 			}
+			if handshakeResponse, ok := decodedRequest.(*HandshakeResponse); ok {
+				pendingCompression = compressionOf(handshakeResponse.CapabilityFlags)
+			}
 			if expectingHandshakeResponseTest {
 				// configMocks = configMocks[1:]
 				// h.SetConfigMocks(configMocks)
@@ -561,8 +578,9 @@ func decodeOutgoingMySQL(requestBuffer []byte, clientConn, destConn net.Conn, h
 			if oprRequest == "COM_STMT_CLOSE" {
 				return
 			}
-			matchedResponse, matchedIndex, _, err := matchRequestWithMock(mysqlRequest, configMocks, tcsMocks, h)
+			matchedResponse, matchedIndex, _, err := matchRequestWithMock(mysqlRequest, configMocks, tcsMocks, h, sqlConfig)
 			if err != nil {
+				metrics.MatchFailures.Inc("mysql")
 				logger.Error("Failed to match request with mock", zap.Error(err))
 				return
 			}
@@ -576,12 +594,38 @@ func decodeOutgoingMySQL(requestBuffer []byte, clientConn, destConn net.Conn, h
 					logger.Error("Failed to encode response to binary", zap.Error(err))
 					return
 				}
+				// the server continues the sequence of the compressed request
+				responseBinary, err = compressPackets(responseBinary, compression, requestSequenceID+1)
+				if err != nil {
+					logger.Error("Failed to compress response", zap.Error(err), zap.String("compression", compression.String()))
+					return
+				}
 
 				_, err = clientConn.Write(responseBinary)
 				if err != nil {
 					logger.Error("Failed to write response to clientConn", zap.Error(err))
 					return
 				}
+				authenticated := false
+				switch resp := matchedResponse.Message.(type) {
+				case *models.MySQLHandshakeResponseOk:
+					switch resp.PluginDetails.Type {
+					case "cachingSha2PasswordPerformFullAuthentication", "PublicKeyAuthentication":
+						expectingPassword = true
+					case "cachingSha2PasswordFastAuthSuccess":
+						authenticated = true
+					}
+				case *models.MySQLOKPacket:
+					authenticated = true
+				case *models.MySQLStmtPrepareOk:
+					// the mocked statement id is the one the application will execute
+					statements.register(resp.StatementID, resp.NumParams)
+				case *models.MySQLLocalInfileRequest:
+					expectingInfile = true
+				}
+				if authenticated && pendingCompression != noCompression {
+					compression, pendingCompression = pendingCompression, noCompression
+				}
 
 			} else {
 				responseBuffer, err := util.Passthrough(clientConn, destConn, requestBuffers, h.Recover, logger)
@@ -598,7 +642,7 @@ func decodeOutgoingMySQL(requestBuffer []byte, clientConn, destConn net.Conn, h
 	}
 }
 
-func matchRequestWithMock(mysqlRequest models.MySQLRequest, configMocks, tcsMocks []*models.Mock, h *hooks.Hook) (*models.MySQLResponse, int, string, error) {
+func matchRequestWithMock(mysqlRequest models.MySQLRequest, configMocks, tcsMocks []*models.Mock, h *hooks.Hook, sqlConfig models.SqlConfig) (*models.MySQLResponse, int, string, error) {
 	allMocks := append([]*models.Mock(nil), configMocks...)
 	allMocks = append(allMocks, tcsMocks...)
 	var bestMatch *models.MySQLResponse
@@ -609,7 +653,7 @@ func matchRequestWithMock(mysqlRequest models.MySQLRequest, configMocks, tcsMock
 
 	for i, mock := range allMocks {
 		for j, mockReq := range mock.Spec.MySqlRequests {
-			matchCount := compareMySQLRequests(mysqlRequest, mockReq)
+			matchCount := compareMySQLRequests(mysqlRequest, mockReq, sqlConfig)
 			if matchCount > maxMatchCount {
 				maxMatchCount = matchCount
 				matchedIndex = i
@@ -647,20 +691,28 @@ func matchRequestWithMock(mysqlRequest models.MySQLRequest, configMocks, tcsMock
 		if realIndex < 0 || realIndex >= len(tcsMocks) {
 			return nil, -1, "", fmt.Errorf("index out of range in tcsMocks")
 		}
-		tcsMocks[realIndex].Spec.MySqlRequests = append(tcsMocks[realIndex].Spec.MySqlRequests[:matchedReqIndex], tcsMocks[realIndex].Spec.MySqlRequests[matchedReqIndex+1:]...)
-		tcsMocks[realIndex].Spec.MySqlResponses = append(tcsMocks[realIndex].Spec.MySqlResponses[:matchedReqIndex], tcsMocks[realIndex].Spec.MySqlResponses[matchedReqIndex+1:]...)
-
-		if len(tcsMocks[realIndex].Spec.MySqlResponses) == 0 {
-			tcsMocks = append(tcsMocks[:realIndex], tcsMocks[realIndex+1:]...)
+		mock := tcsMocks[realIndex]
+		// the repeatable mocks keep their requests, and the tcs mocks are updated in place since only the
+		// available ones are listed by the hooks
+		if mock.Policy != models.MockPolicyRepeatable {
+			mock.Spec.MySqlRequests = append(mock.Spec.MySqlRequests[:matchedReqIndex], mock.Spec.MySqlRequests[matchedReqIndex+1:]...)
+			mock.Spec.MySqlResponses = append(mock.Spec.MySqlResponses[:matchedReqIndex], mock.Spec.MySqlResponses[matchedReqIndex+1:]...)
+			if len(mock.Spec.MySqlResponses) == 0 {
+				if _, err := h.DeleteTcsMock(mock); err != nil {
+					return nil, -1, "", fmt.Errorf("error while deleting tcs mock: %v", err)
+				}
+			}
 		}
-		h.SetTcsMocks(tcsMocks)
+		h.MarkMockConsumed(mock)
 	}
 
 	return bestMatch, matchedIndex, mockType, nil
 }
 
-func compareMySQLRequests(req1, req2 models.MySQLRequest) int {
+func compareMySQLRequests(req1, req2 models.MySQLRequest, sqlConfig models.SqlConfig) int {
 	matchCount := 0
+	// in the fingerprint mode the queries differing by their literals only are preferred to the other ones
+	fingerprint := matcher.For(models.SQL).Strategy(sqlConfig.Matching) == sqlquery.MatchingFingerprint
 
 	// Compare Header fields
 	if req1.Header.PacketType == "MySQLQuery" && req2.Header.PacketType == "MySQLQuery" {
@@ -677,6 +729,49 @@ func compareMySQLRequests(req1, req2 models.MySQLRequest) int {
 		}
 		if packet.Query == packet3.Query {
 			matchCount += 5
+		} else if fingerprint && packet.Fingerprint == queryFingerprint(packet3.Fingerprint, packet3.Query) {
+			matchCount += 3
+		}
+	}
+	if req1.Header.PacketType == "COM_STMT_PREPARE" && req2.Header.PacketType == "COM_STMT_PREPARE" {
+		packet, ok := req1.Message.(*ComStmtPreparePacket)
+		if !ok {
+			return 0
+		}
+		packet2, ok := req2.Message.(*models.MySQLComStmtPreparePacket)
+		if !ok {
+			return 0
+		}
+		if packet.Query == packet2.Query {
+			matchCount += 5
+		} else if fingerprint && sqlquery.Fingerprint(packet.Query, sqlquery.MySQL) == sqlquery.Fingerprint(packet2.Query, sqlquery.MySQL) {
+			matchCount += 3
+		}
+	}
+	if req1.Header.PacketType == "COM_STMT_EXECUTE" && req2.Header.PacketType == "COM_STMT_EXECUTE" {
+		packet, ok := req1.Message.(ComStmtExecute)
+		if !ok {
+			return 0
+		}
+		packet2, ok := req2.Message.(*models.MySQLComStmtExecute)
+		if !ok {
+			return 0
+		}
+		if packet.StatementID == packet2.StatementID && boundParametersEqual(packet.Parameters, packet2.Parameters) {
+			matchCount += 5
+		}
+	}
+	if req1.Header.PacketType == "LOCAL_INFILE_CONTENT" && req2.Header.PacketType == "LOCAL_INFILE_CONTENT" {
+		packet, ok := req1.Message.(*LocalInfileContent)
+		if !ok {
+			return 0
+		}
+		packet2, ok := req2.Message.(*models.MySQLLocalInfileContent)
+		if !ok {
+			return 0
+		}
+		if packet.Content == packet2.Content {
+			matchCount += 5
 		}
 	}
 	if req1.Header.PacketLength == req2.Header.PacketLength {
@@ -690,6 +785,26 @@ func compareMySQLRequests(req1, req2 models.MySQLRequest) int {
 	}
 	return matchCount
 }
+
+// queryFingerprint returns the fingerprint of a recorded query, computed for the mocks recorded without it.
+func queryFingerprint(fingerprint, query string) string {
+	if fingerprint != "" {
+		return fingerprint
+	}
+	return sqlquery.Fingerprint(query, sqlquery.MySQL)
+}
+func boundParametersEqual(params []BoundParameter, mockParams []models.BoundParameter) bool {
+	if len(params) != len(mockParams) {
+		return false
+	}
+	for i := range params {
+		if params[i].Type != mockParams[i].Type || !bytes.Equal(params[i].Value, mockParams[i].Value) {
+			return false
+		}
+	}
+	return true
+}
+
 func ReadFirstBuffer(clientConn, destConn net.Conn) ([]byte, string, error) {
 	// Attempt to read from destConn first
 	n, err := util.ReadBytes(destConn)
@@ -710,7 +825,7 @@ func ReadFirstBuffer(clientConn, destConn net.Conn) ([]byte, string, error) {
 	// Return any other error from reading destConn
 	return nil, "", err
 }
-func handleClientQueries(h *hooks.Hook, initialBuffer []byte, clientConn, destConn net.Conn, logger *zap.Logger, ctx context.Context) ([]*models.Mock, error) {
+func handleClientQueries(h *hooks.Hook, initialBuffer []byte, clientConn, destConn net.Conn, logger *zap.Logger, ctx context.Context, compression compressionAlgorithm, statements *statementTable) ([]*models.Mock, error) {
 	firstIteration := true
 	var (
 		mysqlRequests  []models.MySQLRequest
@@ -734,7 +849,17 @@ func handleClientQueries(h *hooks.Hook, initialBuffer []byte, clientConn, destCo
 		if len(queryBuffer) == 0 {
 			break
 		}
-		operation, requestHeader, mysqlRequest, err := DecodeMySQLPacket(bytesToMySQLPacket(queryBuffer), logger, destConn)
+		// the mocks keep the decompressed packets, the raw bytes are forwarded as they are
+		queryPackets, _, err := decompressPackets(queryBuffer, compression)
+		if err != nil {
+			logger.Error("failed to decompress the query from the mysql client", zap.Error(err), zap.String("compression", compression.String()))
+			return nil, err
+		}
+		if command, ok := replicationCommand(queryPackets); ok {
+			// the binlog stream of a replica has no request to pair its events with
+			return nil, passThroughReplication(command, queryBuffer, clientConn, destConn, logger)
+		}
+		operation, requestHeader, mysqlRequest, err := DecodeMySQLPacket(bytesToMySQLPacket(queryPackets), logger, destConn, statements)
 		mysqlRequests = append([]models.MySQLRequest{}, models.MySQLRequest{
 			Header: &models.MySQLPacketHeader{
 				PacketLength: requestHeader.PayloadLength,
@@ -748,7 +873,7 @@ func handleClientQueries(h *hooks.Hook, initialBuffer []byte, clientConn, destCo
 			logger.Error("failed to write query to mysql server", zap.Error(err))
 			return nil, err
 		}
-		if res == 9 {
+		if res == 9 || len(queryPackets) == 9 {
 			return nil, nil
 		}
 		queryResponse, err := util.ReadBytes(destConn)
@@ -764,7 +889,40 @@ func handleClientQueries(h *hooks.Hook, initialBuffer []byte, clientConn, destCo
 		if len(queryResponse) == 0 {
 			break
 		}
-		responseOperation, responseHeader, mysqlResp, err := DecodeMySQLPacket(bytesToMySQLPacket(queryResponse), logger, destConn)
+		responsePackets, _, err := decompressPackets(queryResponse, compression)
+		if err != nil {
+			logger.Error("failed to decompress the response from the mysql server", zap.Error(err), zap.String("compression", compression.String()))
+			return nil, err
+		}
+		if operation == "MySQLQuery" && isLocalInfileRequest(responsePackets) {
+			// the client streams the file asked by the server before its acknowledgement
+			err = recordLocalInfile(h, mysqlRequests[0], responsePackets, clientConn, destConn, logger, ctx, compression, statements)
+			if err != nil {
+				logger.Error("failed to record the LOAD DATA LOCAL INFILE of the mysql client", zap.Error(err))
+				return nil, err
+			}
+			continue
+		}
+		// the remaining results of a chain may arrive separately
+		for hasMoreResults(responsePackets) {
+			nextResponse, err := util.ReadBytes(destConn)
+			if err != nil {
+				logger.Error("failed to read the remaining results from mysql server", zap.Error(err))
+				return nil, err
+			}
+			_, err = clientConn.Write(nextResponse)
+			if err != nil {
+				logger.Error("failed to write the remaining results to mysql client", zap.Error(err))
+				return nil, err
+			}
+			nextPackets, _, err := decompressPackets(nextResponse, compression)
+			if err != nil {
+				logger.Error("failed to decompress the response from the mysql server", zap.Error(err), zap.String("compression", compression.String()))
+				return nil, err
+			}
+			responsePackets = append(responsePackets, nextPackets...)
+		}
+		responseOperation, responseHeader, mysqlResp, err := DecodeMySQLPacket(bytesToMySQLPacket(responsePackets), logger, destConn, statements)
 		if err != nil {
 			logger.Error("Failed to decode the MySQL packet from the destination server", zap.Error(err))
 			continue
@@ -79,7 +79,7 @@ func encodeToBinary(packet interface{}, header *models.MySQLPacketHeader, operat
 		if !ok {
 			return nil, fmt.Errorf("invalid packet type for HandshakeResponse: expected *HandshakeResponse, got %T", packet)
 		}
-		data, err = encodeHandshakeResponseOk(p)
+		data, err = encodeHandshakeResponseOk(p, header)
 	case "AUTH_SWITCH_REQUEST":
 		p, ok := packet.(*models.AuthSwitchRequestPacket)
 		if !ok {
@@ -114,6 +114,26 @@ func encodeToBinary(packet interface{}, header *models.MySQLPacketHeader, operat
 		}
 		data, err = encodeMySQLResultSet(p)
 		bypassHeader = true
+	case "MULTI_RESULT_SET":
+		p, ok := packet.(*models.MySQLMultiResultSet)
+		if !ok {
+			return nil, fmt.Errorf("invalid packet for multi result set")
+		}
+		data, err = encodeMultiResultSet(p)
+		bypassHeader = true
+	case "LOCAL_INFILE_REQUEST":
+		p, ok := packet.(*models.MySQLLocalInfileRequest)
+		if !ok {
+			return nil, fmt.Errorf("invalid packet type for LOCAL_INFILE_REQUEST: expected *MySQLLocalInfileRequest, got %T", packet)
+		}
+		data, err = encodeLocalInfileRequest(p)
+	case "MySQLErr":
+		p, ok := packet.(*models.MySQLERRPacket)
+		if !ok {
+			return nil, fmt.Errorf("invalid packet type for MySQLErr: expected *MySQLERRPacket, got %T", packet)
+		}
+		data, err = encodeMySQLErr(p, header)
+		bypassHeader = true
 	default:
 		return nil, errors.New("unknown operation type")
 	}
@@ -131,7 +151,7 @@ func encodeToBinary(packet interface{}, header *models.MySQLPacketHeader, operat
 	}
 }
 
-func DecodeMySQLPacket(packet MySQLPacket, logger *zap.Logger, destConn net.Conn) (string, MySQLPacketHeader, interface{}, error) {
+func DecodeMySQLPacket(packet MySQLPacket, logger *zap.Logger, destConn net.Conn, statements *statementTable) (string, MySQLPacketHeader, interface{}, error) {
 	data := packet.Payload
 	header := packet.Header
 	var packetData interface{}
@@ -142,7 +162,17 @@ func DecodeMySQLPacket(packet MySQLPacket, logger *zap.Logger, destConn net.Conn
 		return "", MySQLPacketHeader{}, nil, fmt.Errorf("Invalid packet: Payload is empty")
 	}
 
+	// stored procedure calls and multi statement queries may answer with a chain of results
+	var results [][]byte
+	if lastCommand == 0x03 || lastCommand == 0x17 {
+		results = multipleResults(packet)
+	}
+
 	switch {
+	case len(results) > 1:
+		packetType = "MULTI_RESULT_SET"
+		packetData, err = decodeMultiResultSet(results, lastCommand == 0x17)
+		lastCommand = 0x00 // Reset the last command
 	case lastCommand == 0x03:
 		switch {
 		case data[0] == 0x00: // OK Packet
@@ -165,13 +195,28 @@ func DecodeMySQLPacket(packet MySQLPacket, logger *zap.Logger, destConn net.Conn
 			packetData = data
 			logger.Debug("unknown packet type after COM_QUERY", zap.Int("unknownPacketTypeInt", int(data[0])))
 		}
+	case lastCommand == 0x17:
+		switch {
+		case data[0] == 0x00: // OK Packet
+			packetType = "MySQLOK"
+			packetData, err = decodeMySQLOK(data)
+
+		case data[0] == 0xFF: // Error Packet
+			packetType = "MySQLErr"
+			packetData, err = decodeMySQLErr(data)
+
+		default: // Binary ResultSet Packet
+			packetType = "RESULT_SET_PACKET"
+			packetData, err = parseBinaryResultSet(data)
+		}
+		lastCommand = 0x00 // Reset the last command
 	case data[0] == 0x0e: // COM_PING
 		packetType = "COM_PING"
 		packetData, err = decodeComPing(data)
 		lastCommand = 0x0e
 	case data[0] == 0x17: // COM_STMT_EXECUTE
 		packetType = "COM_STMT_EXECUTE"
-		packetData, err = decodeComStmtExecute(data)
+		packetData, err = decodeComStmtExecute(data, statements)
 		lastCommand = 0x17
 	case data[0] == 0x1c: // COM_STMT_FETCH
 		packetType = "COM_STMT_FETCH"
@@ -189,7 +234,12 @@ func DecodeMySQLPacket(packet MySQLPacket, logger *zap.Logger, destConn net.Conn
 			lastCommand = 0x16
 		} else {
 			packetType = "COM_STMT_CLOSE"
-			packetData, err = decodeComStmtClose(data)
+			var closePacket *ComStmtClosePacket
+			closePacket, err = decodeComStmtClose(data)
+			if err == nil {
+				statements.forget(closePacket.StatementID)
+			}
+			packetData = closePacket
 			lastCommand = 0x19
 		}
 	case data[0] == 0x11: // COM_CHANGE_USER
@@ -214,7 +264,12 @@ func DecodeMySQLPacket(packet MySQLPacket, logger *zap.Logger, destConn net.Conn
 	case data[0] == 0x00: // MySQLOK or COM_STMT_PREPARE_OK
 		if lastCommand == 0x16 {
 			packetType = "COM_STMT_PREPARE_OK"
-			packetData, err = decodeComStmtPrepareOk(data)
+			var prepareOk *StmtPrepareOk
+			prepareOk, err = decodeComStmtPrepareOk(data)
+			if err == nil {
+				statements.register(prepareOk.StatementID, prepareOk.NumParams)
+			}
+			packetData = prepareOk
 		} else {
 			packetType = "MySQLOK"
 			packetData, err = decodeMySQLOK(data)
@@ -279,6 +334,19 @@ func DecodeMySQLPacket(packet MySQLPacket, logger *zap.Logger, destConn net.Conn
 	}
 	return packetType, header, packetData, nil
 }
+
+// multipleResults returns the raw results of a response made of more than one result, nil otherwise.
+func multipleResults(packet MySQLPacket) [][]byte {
+	header := make([]byte, 4)
+	putUint24(header, packet.Header.PayloadLength)
+	header[3] = packet.Header.SequenceID
+	results, _, err := splitResults(append(header, packet.Payload...))
+	if err != nil || len(results) < 2 {
+		return nil
+	}
+	return results
+}
+
 func isLengthEncodedInteger(b byte) bool {
 	// This is a simplified check. You may need a more robust check based on MySQL protocol.
 	return b != 0x00 && b != 0xFF
@@ -1,10 +1,16 @@
 package mysqlparser
 
-import "fmt"
+import (
+	"fmt"
+
+	"go.keploy.io/server/pkg/proxy/integrations/sqlquery"
+)
 
 type QueryPacket struct {
-	Command byte   `yaml:"command"`
-	Query   string `yaml:"query"`
+	Command     byte     `yaml:"command"`
+	Query       string   `yaml:"query"`
+	Parameters  []string `yaml:"parameters,omitempty"`
+	Fingerprint string   `yaml:"fingerprint,omitempty"`
 }
 
 func decodeMySQLQuery(data []byte) (*QueryPacket, error) {
@@ -15,6 +21,7 @@ func decodeMySQLQuery(data []byte) (*QueryPacket, error) {
 	packet := &QueryPacket{}
 	packet.Command = data[0]
 	packet.Query = string(data[1:])
+	packet.Fingerprint, packet.Parameters = sqlquery.Normalize(packet.Query, sqlquery.MySQL)
 
 	return packet, nil
 }
@@ -0,0 +1,78 @@
+package mysqlparser
+
+import (
+	"io"
+	"net"
+
+	"go.keploy.io/server/pkg/models"
+	"go.uber.org/zap"
+)
+
+// The replication clients, like the CDC tools (Debezium, canal), register as a replica with COM_REGISTER_SLAVE and
+// ask for the binlog events with COM_BINLOG_DUMP or COM_BINLOG_DUMP_GTID. The server then streams the events for as
+// long as the connection lives, without any request to pair them with. In record mode such a connection is passed
+// through to the server as it is, without being recorded. In test mode there is no server to stream the events, and
+// the command is refused with an ERR packet rather than left unanswered.
+
+const (
+	comBinlogDump     = 0x12
+	comRegisterSlave  = 0x15
+	comBinlogDumpGTID = 0x1e
+	// errMasterFatalReadingBinlog is the error code of the server when it can't stream the binlog
+	errMasterFatalReadingBinlog = 1236
+)
+
+var replicationCommands = map[byte]string{
+	comBinlogDump:     "COM_BINLOG_DUMP",
+	comRegisterSlave:  "COM_REGISTER_SLAVE",
+	comBinlogDumpGTID: "COM_BINLOG_DUMP_GTID",
+}
+
+// replicationCommand returns the name of the replication command of the packets, if they carry one.
+func replicationCommand(packets []byte) (string, bool) {
+	if len(packets) < 5 {
+		return "", false
+	}
+	command, ok := replicationCommands[packets[4]]
+	return command, ok
+}
+
+// passThroughReplication forwards the replication command to the server and pipes the connection in both directions
+// until either side closes it.
+func passThroughReplication(command string, queryBuffer []byte, clientConn, destConn net.Conn, logger *zap.Logger) error {
+	logger.Info("passing the mysql replication connection through without recording it", zap.String("command", command))
+	if _, err := destConn.Write(queryBuffer); err != nil {
+		logger.Error("failed to write the replication command to the mysql server", zap.Error(err))
+		return err
+	}
+	done := make(chan error, 2)
+	go func() {
+		_, err := io.Copy(destConn, clientConn)
+		done <- err
+	}()
+	go func() {
+		_, err := io.Copy(clientConn, destConn)
+		done <- err
+	}()
+	return <-done
+}
+
+// refuseReplication answers the replication command with an ERR packet, the binlog stream not being mocked.
+func refuseReplication(command string, sequenceID byte, clientConn net.Conn, compression compressionAlgorithm, logger *zap.Logger) error {
+	logger.Warn("refusing the mysql replication command, the binlog stream isn't replayed in test mode", zap.String("command", command))
+	errPacket, err := encodeMySQLErr(&models.MySQLERRPacket{
+		Header:       0xff,
+		ErrorCode:    errMasterFatalReadingBinlog,
+		SQLState:     "HY000",
+		ErrorMessage: "the binlog stream isn't available in the test mode of keploy",
+	}, &models.MySQLPacketHeader{PacketNumber: 1})
+	if err != nil {
+		return err
+	}
+	errPacket, err = compressPackets(errPacket, compression, sequenceID+1)
+	if err != nil {
+		return err
+	}
+	_, err = clientConn.Write(errPacket)
+	return err
+}
@@ -21,6 +21,7 @@ type ResultSet struct {
 	OptionalPadding     bool                `yaml:"optionalPadding"`
 	OptionalEOFBytes    []byte              `yaml:"optionalEOFBytes"`
 	EOFAfterColumns     []byte              `yaml:"eofAfterColumns"`
+	BinaryProtocol      bool                `yaml:"binaryProtocol"`
 }
 type Row struct {
 	Header  RowHeader             `yaml:"header"`
@@ -245,6 +246,9 @@ func parseRow(b []byte, columnDefinitions []*ColumnDefinition) (*Row, []byte, bo
 }
 
 func encodeMySQLResultSet(resultSet *models.MySQLResultSet) ([]byte, error) {
+	if resultSet.BinaryProtocol {
+		return encodeBinaryResultSet(resultSet)
+	}
 	buf := new(bytes.Buffer)
 	sequenceID := byte(1)
 	buf.Write([]byte{0x01, 0x00, 0x00, 0x01})
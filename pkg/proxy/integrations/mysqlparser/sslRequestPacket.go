@@ -0,0 +1,47 @@
+package mysqlparser
+
+import (
+	"crypto/tls"
+	"encoding/binary"
+	"net"
+
+	"go.keploy.io/server/pkg/models"
+	"go.uber.org/zap"
+)
+
+// TLSUpgrader terminates the TLS session started by the client on the given connection.
+type TLSUpgrader func(conn net.Conn) (net.Conn, error)
+
+// sslRequestLength is the payload length of the SSLRequest packet, the first 32 bytes of a handshake response.
+const sslRequestLength = 32
+
+// isSSLRequest checks whether the client asks to upgrade the connection to TLS before sending its handshake response.
+func isSSLRequest(buffer []byte) bool {
+	if len(buffer) != 4+sslRequestLength {
+		return false
+	}
+	capabilities := binary.LittleEndian.Uint32(buffer[4:8])
+	return capabilities&uint32(models.CLIENT_SSL) != 0
+}
+
+// upgradeToTLS switches both sides of the connection to TLS after the SSLRequest was forwarded to the server.
+// The client gets keploy's certificate, so the decrypted stream can be parsed, and the server is dialed as a client.
+func upgradeToTLS(clientConn, destConn net.Conn, upgradeTLS TLSUpgrader, logger *zap.Logger) (net.Conn, net.Conn, error) {
+	tlsClientConn, err := upgradeTLS(clientConn)
+	if err != nil {
+		logger.Error("failed to terminate the TLS session of the mysql client", zap.Error(err))
+		return nil, nil, err
+	}
+	if destConn == nil {
+		return tlsClientConn, nil, nil
+	}
+
+	// the certificate of the server was already trusted by the application, keploy only records the traffic
+	tlsDestConn := tls.Client(destConn, &tls.Config{InsecureSkipVerify: true})
+	err = tlsDestConn.Handshake()
+	if err != nil {
+		logger.Error("failed to complete the TLS handshake with the mysql server", zap.Error(err))
+		return nil, nil, err
+	}
+	return tlsClientConn, tlsDestConn, nil
+}
@@ -0,0 +1,123 @@
+package natsparser
+
+import (
+	"fmt"
+
+	"go.keploy.io/server/pkg/hooks"
+	"go.keploy.io/server/pkg/models"
+)
+
+// match finds the mock recorded for the operation and removes it from the mocks of the test. The INFO of the
+// server is matched when the operation is nil. It returns nil when no mock matches.
+func match(h *hooks.Hook, request *models.NATSMessage) (*models.Mock, error) {
+	for {
+		tcsMocks, err := h.GetTcsMocks()
+		if err != nil {
+			return nil, fmt.Errorf("error while getting tcs mocks %v", err)
+		}
+
+		var (
+			best      *models.Mock
+			bestScore = -1
+		)
+		for _, mock := range tcsMocks {
+			if mock == nil || mock.Kind != models.NATS {
+				continue
+			}
+			score, ok := matchScore(mock.Spec.NATSRequests, mock.Spec.NATSResponses, request)
+			if ok && score > bestScore {
+				best, bestScore = mock, score
+			}
+		}
+		if best == nil {
+			return nil, nil
+		}
+
+		isDeleted, err := h.DeleteTcsMock(best)
+		if err != nil {
+			return nil, fmt.Errorf("error while deleting tcs mock: %v", err)
+		}
+		if !isDeleted {
+			// another connection consumed the mock in the meantime
+			continue
+		}
+		return best, nil
+	}
+}
+
+// matchScore reports whether the recorded operation answers the operation, and how close they are. The subjects
+// have to be the same (the inboxes of the replies being all alike) along with the queue group of a subscription.
+// A publication having the same payload is preferred, then one having the same headers and reply subject.
+func matchScore(recorded, responses []models.NATSMessage, request *models.NATSMessage) (int, bool) {
+	if request == nil {
+		return 0, len(recorded) == 0 && len(responses) > 0 && responses[0].Op == "INFO"
+	}
+	if len(recorded) != 1 || recorded[0].Op != request.Op {
+		return 0, false
+	}
+	r := recorded[0]
+	score := 0
+	switch request.Op {
+	case "CONNECT":
+		if connectionName(r.Options) == connectionName(request.Options) {
+			score++
+		}
+	case "PUB", "HPUB":
+		if !sameSubject(r.Subject, request.Subject) {
+			return 0, false
+		}
+		if samePayload(r.Payload, request.Payload) {
+			score += 2
+		}
+		if r.Headers == request.Headers && (r.ReplyTo == "") == (request.ReplyTo == "") {
+			score++
+		}
+	case "SUB":
+		if !sameSubject(r.Subject, request.Subject) || r.Queue != request.Queue {
+			return 0, false
+		}
+	case "UNSUB":
+		if !sameSubject(r.Subject, request.Subject) {
+			return 0, false
+		}
+		if r.MaxMsgs == request.MaxMsgs {
+			score++
+		}
+	}
+	return score, true
+}
+
+func samePayload(a, b *models.OutputBinary) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+// matchDeliveries returns the messages recorded for the subjects matching the subject of a subscription, and
+// removes their mocks. The replies to the requests are sent along with them instead.
+func matchDeliveries(h *hooks.Hook, subject string) ([]models.NATSMessage, error) {
+	tcsMocks, err := h.GetTcsMocks()
+	if err != nil {
+		return nil, fmt.Errorf("error while getting tcs mocks %v", err)
+	}
+
+	var deliveries []models.NATSMessage
+	for _, mock := range tcsMocks {
+		if mock == nil || mock.Kind != models.NATS || len(mock.Spec.NATSRequests) != 0 || len(mock.Spec.NATSResponses) != 1 {
+			continue
+		}
+		delivery := mock.Spec.NATSResponses[0]
+		if (delivery.Op != "MSG" && delivery.Op != "HMSG") || isInbox(delivery.Subject) || !subjectMatches(subject, delivery.Subject) {
+			continue
+		}
+		isDeleted, err := h.DeleteTcsMock(mock)
+		if err != nil {
+			return nil, fmt.Errorf("error while deleting tcs mock: %v", err)
+		}
+		if isDeleted {
+			deliveries = append(deliveries, delivery)
+		}
+	}
+	return deliveries, nil
+}
@@ -0,0 +1,522 @@
+package natsparser
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"time"
+
+	"go.keploy.io/server/pkg"
+	"go.keploy.io/server/pkg/hooks"
+	"go.keploy.io/server/pkg/metrics"
+	"go.keploy.io/server/pkg/models"
+	"go.keploy.io/server/pkg/proxy/util"
+	"go.keploy.io/server/utils"
+	"go.uber.org/zap"
+)
+
+type NatsParser struct {
+	logger     *zap.Logger
+	hooks      *hooks.Hook
+	upgradeTLS TLSUpgrader
+}
+
+func NewNatsParser(logger *zap.Logger, h *hooks.Hook, upgradeTLS TLSUpgrader) *NatsParser {
+	return &NatsParser{
+		logger:     logger,
+		hooks:      h,
+		upgradeTLS: upgradeTLS,
+	}
+}
+
+// OutgoingType returns false since the server speaks first in NATS, the parser is chosen through the destination
+// port of the connection.
+func (n *NatsParser) OutgoingType(buffer []byte) bool {
+	return false
+}
+
+func (n *NatsParser) ProcessOutgoing(requestBuffer []byte, clientConn, destConn net.Conn, ctx context.Context) {
+	switch models.GetMode() {
+	case models.MODE_RECORD:
+		encodeNatsOutgoing(clientConn, destConn, n.hooks, n.logger, ctx, n.upgradeTLS)
+	case models.MODE_TEST:
+		decodeNatsOutgoing(clientConn, n.hooks, n.logger, n.upgradeTLS)
+	default:
+		n.logger.Info("Invalid mode detected while intercepting outgoing nats call", zap.Any("mode", models.GetMode()))
+	}
+}
+
+type pendingOp struct {
+	request   models.NATSMessage
+	responses []models.NATSMessage
+	timestamp time.Time
+	// awaitingAck is set until the +OK or -ERR of a verbose connection is received, and until the first PONG for
+	// the CONNECT of the other ones
+	awaitingAck bool
+	// awaitingReply is set until the reply to a request is received
+	awaitingReply bool
+	saved         bool
+}
+
+// encodeNatsOutgoing forwards the traffic between the client and the server and records a mock for the INFO of the
+// server and for every CONNECT, PUB, SUB and UNSUB along with its acknowledgement. The requests (the publications
+// having a reply subject) are stored along with their first reply, and the other messages delivered to the client
+// are stored as mocks without request. The pings aren't recorded.
+func encodeNatsOutgoing(clientConn, destConn net.Conn, h *hooks.Hook, logger *zap.Logger, ctx context.Context, upgradeTLS TLSUpgrader) error {
+	destReader := bufio.NewReader(destConn)
+	reqTimestampMock := time.Now()
+	info, err := readInfo(destReader)
+	if err != nil {
+		logger.Error("failed to read the INFO of the nats server", zap.Error(err))
+		return err
+	}
+	encoded, err := encodeOp(info)
+	if err != nil {
+		logger.Error("failed to encode the INFO of the nats server", zap.Error(err))
+		return err
+	}
+	_, err = clientConn.Write(encoded)
+	if err != nil {
+		logger.Error("failed to write the INFO of the nats server to the client", zap.Error(err))
+		return err
+	}
+	saveMock(h, ctx, nil, []models.NATSMessage{info}, reqTimestampMock)
+
+	clientConn, destConn, err = upgradeIfTLS(clientConn, bufio.NewReader(clientConn), destConn, destReader, upgradeTLS, logger)
+	if err != nil {
+		return err
+	}
+
+	clientBufferChannel := make(chan []byte)
+	destBufferChannel := make(chan []byte)
+	errChannel := make(chan error)
+	// read requests from client
+	go func() {
+		// Recover from panic and gracefully shutdown
+		defer h.Recover(pkg.GenerateRandomID())
+		defer utils.HandlePanic()
+		readBuffConn(clientConn, clientBufferChannel, errChannel, logger, h)
+	}()
+	// read responses from destination
+	go func() {
+		// Recover from panic and gracefully shutdown
+		defer h.Recover(pkg.GenerateRandomID())
+		defer utils.HandlePanic()
+		readBuffConn(destConn, destBufferChannel, errChannel, logger, h)
+	}()
+
+	var (
+		requests  []byte
+		responses []byte
+		verbose   bool
+		recording = true
+		// acks holds the operations waiting for their acknowledgement, in the order they were sent
+		acks []*pendingOp
+		// replies holds the requests waiting for their reply by reply subject
+		replies = map[string]*pendingOp{}
+		// subjects holds the subjects of the subscriptions by sid, to match the UNSUB
+		subjects = map[string]string{}
+	)
+	trySave := func(op *pendingOp) {
+		if op.saved || op.awaitingAck || op.awaitingReply {
+			return
+		}
+		op.saved = true
+		saveMock(h, ctx, []models.NATSMessage{op.request}, op.responses, op.timestamp)
+	}
+	flush := func() {
+		for _, op := range acks {
+			op.awaitingAck, op.awaitingReply = false, false
+			trySave(op)
+		}
+		for _, op := range replies {
+			op.awaitingAck, op.awaitingReply = false, false
+			trySave(op)
+		}
+	}
+	ack := func(response *models.NATSMessage) {
+		if len(acks) == 0 {
+			if response != nil {
+				logger.Debug("no nats operation found for the acknowledgement", zap.String("op", response.Op))
+			}
+			return
+		}
+		op := acks[0]
+		acks = acks[1:]
+		if response != nil {
+			op.responses = append(op.responses, *response)
+		}
+		op.awaitingAck = false
+		trySave(op)
+	}
+	stopRecording := func(msg string, err error) {
+		logger.Error(msg+", forwarding the rest of the connection", zap.Error(err))
+		flush()
+		recording = false
+	}
+	recordRequests := func(buffer []byte) {
+		requests = append(requests, buffer...)
+		for len(requests) > 0 {
+			request, consumed, err := readOp(requests)
+			if errors.Is(err, errIncomplete) {
+				return
+			}
+			if err != nil {
+				stopRecording("failed to decode the nats operation of the client", err)
+				return
+			}
+			requests = requests[consumed:]
+
+			op := &pendingOp{request: request, timestamp: time.Now(), awaitingAck: verbose}
+			switch request.Op {
+			case "PING", "PONG":
+				continue
+			case "CONNECT":
+				verbose = isVerbose(request.Options)
+				op.request.Options = redactOptions(request.Options)
+				op.awaitingAck = true
+			case "PUB", "HPUB":
+				if request.ReplyTo != "" {
+					op.awaitingReply = true
+					replies[request.ReplyTo] = op
+				}
+			case "SUB":
+				subjects[request.Sid] = request.Subject
+			case "UNSUB":
+				op.request.Subject = subjects[request.Sid]
+			default:
+				stopRecording("unexpected nats operation from the client", errors.New("unexpected "+request.Op))
+				return
+			}
+			if op.awaitingAck {
+				acks = append(acks, op)
+			}
+			trySave(op)
+		}
+	}
+	recordResponses := func(buffer []byte) {
+		responses = append(responses, buffer...)
+		for len(responses) > 0 {
+			response, consumed, err := readOp(responses)
+			if errors.Is(err, errIncomplete) {
+				return
+			}
+			if err != nil {
+				stopRecording("failed to decode the nats operation of the server", err)
+				return
+			}
+			responses = responses[consumed:]
+
+			switch response.Op {
+			case "+OK", "-ERR":
+				ack(&response)
+			case "PONG":
+				// the client sends a PING after CONNECT, its PONG tells that a quiet CONNECT was accepted
+				if !verbose && len(acks) > 0 && acks[0].request.Op == "CONNECT" {
+					ack(nil)
+				}
+			case "MSG", "HMSG":
+				if op, ok := replies[response.Subject]; ok {
+					delete(replies, response.Subject)
+					op.responses = append(op.responses, response)
+					op.awaitingReply = false
+					trySave(op)
+					continue
+				}
+				saveMock(h, ctx, nil, []models.NATSMessage{response}, time.Now())
+			default:
+				// the pings and the INFO updating the cluster topology aren't recorded
+			}
+		}
+	}
+
+	for {
+		select {
+		case buffer := <-clientBufferChannel:
+			_, err := destConn.Write(buffer)
+			if err != nil {
+				logger.Error("failed to write request message to the destination server", zap.Error(err))
+				return err
+			}
+			if recording {
+				recordRequests(buffer)
+			}
+		case buffer := <-destBufferChannel:
+			_, err := clientConn.Write(buffer)
+			if err != nil {
+				logger.Error("failed to write response to the client", zap.Error(err))
+				return err
+			}
+			if recording {
+				recordResponses(buffer)
+			}
+		case err := <-errChannel:
+			if recording {
+				// the requests which got no reply are recorded without it
+				flush()
+			}
+			return err
+		}
+	}
+}
+
+func saveMock(h *hooks.Hook, ctx context.Context, requests, responses []models.NATSMessage, reqTimestampMock time.Time) {
+	h.AppendMocks(&models.Mock{
+		Version: models.GetVersion(),
+		Name:    "mocks",
+		Kind:    models.NATS,
+		Spec: models.MockSpec{
+			NATSRequests:     requests,
+			NATSResponses:    responses,
+			ReqTimestampMock: reqTimestampMock,
+			ResTimestampMock: time.Now(),
+		},
+	}, ctx)
+}
+
+// readInfo reads the INFO sent by the server when the connection opens.
+func readInfo(r *bufio.Reader) (models.NATSMessage, error) {
+	line, err := r.ReadBytes('\n')
+	if err != nil {
+		return models.NATSMessage{}, err
+	}
+	info, _, err := readOp(line)
+	if err != nil {
+		return models.NATSMessage{}, err
+	}
+	if info.Op != "INFO" {
+		return models.NATSMessage{}, errors.New("the nats server sent " + info.Op + " instead of INFO")
+	}
+	return info, nil
+}
+
+// upgradeIfTLS upgrades the connections when the client starts a TLS handshake after the INFO of the server, which
+// it does when the server requires TLS or the client was configured to use it. The destination connection is nil
+// in test mode.
+func upgradeIfTLS(clientConn net.Conn, clientReader *bufio.Reader, destConn net.Conn, destReader *bufio.Reader, upgradeTLS TLSUpgrader, logger *zap.Logger) (net.Conn, net.Conn, error) {
+	clientConn = &bufferedConn{Conn: clientConn, r: clientReader}
+	if destConn != nil {
+		destConn = &bufferedConn{Conn: destConn, r: destReader}
+	}
+	first, err := clientReader.Peek(1)
+	if err != nil || first[0] != tlsHandshakeRecord {
+		// the errors are reported by the next read
+		return clientConn, destConn, nil
+	}
+
+	tlsClientConn, err := upgradeTLS(clientConn)
+	if err != nil {
+		logger.Error("failed to terminate the TLS session of the nats client", zap.Error(err))
+		return nil, nil, err
+	}
+	if destConn == nil {
+		return tlsClientConn, nil, nil
+	}
+	// the certificate of the server was already trusted by the application, keploy only records the traffic
+	tlsDestConn := tls.Client(destConn, &tls.Config{InsecureSkipVerify: true})
+	err = tlsDestConn.Handshake()
+	if err != nil {
+		logger.Error("failed to complete the TLS handshake with the nats server", zap.Error(err))
+		return nil, nil, err
+	}
+	return tlsClientConn, tlsDestConn, nil
+}
+
+func readBuffConn(conn net.Conn, bufferChannel chan []byte, errChannel chan error, logger *zap.Logger, h *hooks.Hook) error {
+	for {
+		buffer, err := util.ReadBytes(conn)
+		if err != nil {
+			if !h.IsUsrAppTerminateInitiated() {
+				if err == io.EOF {
+					logger.Debug("EOF error received from client. Closing connection in nats !!")
+				} else if !strings.Contains(err.Error(), "use of closed network connection") {
+					logger.Error("failed to read the packet message in proxy for nats dependency", zap.Error(err))
+				}
+				errChannel <- err
+			}
+			return err
+		}
+		bufferChannel <- buffer
+	}
+}
+
+// subscription is a subscription made by the client in test mode.
+type subscription struct {
+	sid     string
+	subject string
+}
+
+// decodeNatsOutgoing plays the server with the mocks: it sends the recorded INFO and replies to every operation with
+// the acknowledgement of its mock, or with +OK on verbose connections when no mock matches. The recorded replies to
+// the requests are delivered to the reply subject of the client, and the messages recorded for a subscription are
+// delivered after it. The pings of the client are answered by the proxy. The connection is closed when no INFO was
+// recorded.
+func decodeNatsOutgoing(clientConn net.Conn, h *hooks.Hook, logger *zap.Logger, upgradeTLS TLSUpgrader) error {
+	greeting, err := match(h, nil)
+	if err != nil {
+		logger.Error("failed to match the INFO of the nats server", zap.Error(err))
+		return err
+	}
+	if greeting == nil {
+		logger.Error("no nats INFO was recorded, closing the connection")
+		clientConn.Close()
+		return errors.New("no nats INFO was recorded")
+	}
+	encoded, err := encodeOp(greeting.Spec.NATSResponses[0])
+	if err != nil {
+		logger.Error("failed to encode the INFO of the nats server", zap.Error(err))
+		return err
+	}
+	_, err = clientConn.Write(encoded)
+	if err != nil {
+		logger.Error("failed to write the INFO of the nats server to the client application", zap.Error(err))
+		return err
+	}
+
+	clientConn, _, err = upgradeIfTLS(clientConn, bufio.NewReader(clientConn), nil, nil, upgradeTLS, logger)
+	if err != nil {
+		return err
+	}
+
+	var (
+		buffer        []byte
+		verbose       bool
+		subscriptions []subscription
+	)
+	// sidOf returns the sid of a subscription of the client receiving the messages sent to the subject
+	sidOf := func(subject string) (string, bool) {
+		for _, s := range subscriptions {
+			if subjectMatches(s.subject, subject) {
+				return s.sid, true
+			}
+		}
+		return "", false
+	}
+	// reply returns the response to an operation of the client, and whether the connection has to be closed
+	reply := func(request models.NATSMessage) ([]models.NATSMessage, bool, error) {
+		switch request.Op {
+		case "PING":
+			return []models.NATSMessage{{Op: "PONG"}}, false, nil
+		case "PONG":
+			return nil, false, nil
+		case "CONNECT":
+			verbose = isVerbose(request.Options)
+		case "SUB":
+			subscriptions = append(subscriptions, subscription{sid: request.Sid, subject: request.Subject})
+		case "UNSUB":
+			for i, s := range subscriptions {
+				if s.sid != request.Sid {
+					continue
+				}
+				request.Subject = s.subject
+				// the subscriptions ending after some messages are kept to deliver the reply of a request
+				if request.MaxMsgs == 0 {
+					subscriptions = append(subscriptions[:i], subscriptions[i+1:]...)
+				}
+				break
+			}
+		}
+
+		mock, err := match(h, &request)
+		if err != nil {
+			return nil, false, err
+		}
+		if mock == nil {
+			metrics.MatchFailures.Inc("nats")
+			logger.Warn("no mock matched the nats operation", zap.String("op", request.Op), zap.String("subject", request.Subject))
+			if verbose {
+				return []models.NATSMessage{{Op: "+OK"}}, false, nil
+			}
+			return nil, false, nil
+		}
+
+		var responses []models.NATSMessage
+		for _, response := range mock.Spec.NATSResponses {
+			switch response.Op {
+			case "MSG", "HMSG":
+				sid, ok := sidOf(request.ReplyTo)
+				if !ok {
+					logger.Debug("the client isn't subscribed to the reply subject of the request", zap.String("subject", request.ReplyTo))
+					continue
+				}
+				response.Subject, response.Sid = request.ReplyTo, sid
+			case "-ERR":
+				if request.Op == "CONNECT" {
+					// the server closes the connections which it refused
+					return append(responses, response), true, nil
+				}
+			}
+			responses = append(responses, response)
+		}
+		if request.Op == "SUB" {
+			deliveries, err := matchDeliveries(h, request.Subject)
+			if err != nil {
+				return nil, false, err
+			}
+			for _, delivery := range deliveries {
+				delivery.Sid = request.Sid
+				responses = append(responses, delivery)
+			}
+		}
+		return responses, false, nil
+	}
+
+	for {
+		read, err := util.ReadBytes(clientConn)
+		if len(read) == 0 && err != nil {
+			if err == io.EOF {
+				logger.Debug("EOF error received from client. Closing connection in nats !!")
+				return nil
+			}
+			logger.Error("failed to read the request message in proxy for nats dependency", zap.Error(err))
+			return err
+		}
+		buffer = append(buffer, read...)
+
+		var (
+			response  []byte
+			closeConn bool
+		)
+		for len(buffer) > 0 && !closeConn {
+			request, consumed, err := readOp(buffer)
+			if errors.Is(err, errIncomplete) {
+				break
+			}
+			if err != nil {
+				logger.Error("failed to decode the nats operation of the client", zap.Error(err))
+				return err
+			}
+			buffer = buffer[consumed:]
+
+			var replies []models.NATSMessage
+			replies, closeConn, err = reply(request)
+			if err != nil {
+				logger.Error("failed to match the nats operation with the mocks", zap.Error(err))
+				return err
+			}
+			for _, r := range replies {
+				encoded, err := encodeOp(r)
+				if err != nil {
+					logger.Error("failed to encode the nats response of the mock", zap.Error(err))
+					return err
+				}
+				response = append(response, encoded...)
+			}
+		}
+		if len(response) > 0 {
+			_, err = clientConn.Write(response)
+			if err != nil {
+				logger.Error("failed to write the nats response to the client application", zap.Error(err))
+				return err
+			}
+		}
+		if closeConn {
+			clientConn.Close()
+			return nil
+		}
+	}
+}
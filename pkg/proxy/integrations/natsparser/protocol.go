@@ -0,0 +1,282 @@
+package natsparser
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"go.keploy.io/server/pkg/models"
+)
+
+// errIncomplete is returned when the buffer ends before the operation starting it.
+var errIncomplete = errors.New("incomplete nats operation")
+
+// tlsHandshakeRecord starts the TLS handshake of a client upgrading the connection after the INFO of the server.
+const tlsHandshakeRecord = 0x16
+
+// inboxPrefix starts the subjects generated by the clients to receive the replies of their requests, they are
+// random and differ between the runs.
+const inboxPrefix = "_INBOX."
+
+// credentials are the fields of CONNECT which aren't kept in the mocks.
+var credentials = []string{"pass", "auth_token", "sig", "jwt"}
+
+// TLSUpgrader terminates the TLS session started by the client on the given connection.
+type TLSUpgrader func(conn net.Conn) (net.Conn, error)
+
+// bufferedConn reads a connection through the reader which peeked at its first bytes.
+type bufferedConn struct {
+	net.Conn
+	r *bufio.Reader
+}
+
+func (c *bufferedConn) Read(b []byte) (int, error) {
+	return c.r.Read(b)
+}
+
+// readOp decodes the operation starting the buffer, along with the count of bytes it spans.
+func readOp(buffer []byte) (models.NATSMessage, int, error) {
+	end := bytes.IndexByte(buffer, '\n')
+	if end < 0 {
+		return models.NATSMessage{}, 0, errIncomplete
+	}
+	line := strings.TrimRight(string(buffer[:end]), "\r")
+	consumed := end + 1
+
+	// the arguments are separated by spaces or tabs
+	op := strings.TrimSpace(line)
+	if i := strings.IndexAny(op, " \t"); i >= 0 {
+		op = op[:i]
+	}
+	rest := strings.TrimSpace(strings.TrimSpace(line)[len(op):])
+	args := strings.Fields(rest)
+	msg := models.NATSMessage{Op: strings.ToUpper(op)}
+
+	var headerLength, totalLength string
+	switch msg.Op {
+	case "INFO", "CONNECT":
+		msg.Options = rest
+		return msg, consumed, nil
+	case "PING", "PONG", "+OK":
+		return msg, consumed, nil
+	case "-ERR":
+		msg.Error = strings.Trim(rest, "'")
+		return msg, consumed, nil
+	case "PUB":
+		// PUB <subject> [reply-to] <#bytes>
+		if len(args) != 2 && len(args) != 3 {
+			return msg, 0, fmt.Errorf("malformed PUB: %q", line)
+		}
+		msg.Subject, msg.ReplyTo, totalLength = args[0], optional(args, 3, 1), args[len(args)-1]
+	case "HPUB":
+		// HPUB <subject> [reply-to] <#header bytes> <#total bytes>
+		if len(args) != 3 && len(args) != 4 {
+			return msg, 0, fmt.Errorf("malformed HPUB: %q", line)
+		}
+		msg.Subject, msg.ReplyTo = args[0], optional(args, 4, 1)
+		headerLength, totalLength = args[len(args)-2], args[len(args)-1]
+	case "MSG":
+		// MSG <subject> <sid> [reply-to] <#bytes>
+		if len(args) != 3 && len(args) != 4 {
+			return msg, 0, fmt.Errorf("malformed MSG: %q", line)
+		}
+		msg.Subject, msg.Sid, msg.ReplyTo, totalLength = args[0], args[1], optional(args, 4, 2), args[len(args)-1]
+	case "HMSG":
+		// HMSG <subject> <sid> [reply-to] <#header bytes> <#total bytes>
+		if len(args) != 4 && len(args) != 5 {
+			return msg, 0, fmt.Errorf("malformed HMSG: %q", line)
+		}
+		msg.Subject, msg.Sid, msg.ReplyTo = args[0], args[1], optional(args, 5, 2)
+		headerLength, totalLength = args[len(args)-2], args[len(args)-1]
+	case "SUB":
+		// SUB <subject> [queue group] <sid>
+		if len(args) != 2 && len(args) != 3 {
+			return msg, 0, fmt.Errorf("malformed SUB: %q", line)
+		}
+		msg.Subject, msg.Queue, msg.Sid = args[0], optional(args, 3, 1), args[len(args)-1]
+		return msg, consumed, nil
+	case "UNSUB":
+		// UNSUB <sid> [max_msgs]
+		if len(args) != 1 && len(args) != 2 {
+			return msg, 0, fmt.Errorf("malformed UNSUB: %q", line)
+		}
+		msg.Sid = args[0]
+		if len(args) == 2 {
+			maxMsgs, err := strconv.Atoi(args[1])
+			if err != nil {
+				return msg, 0, fmt.Errorf("malformed UNSUB: %q", line)
+			}
+			msg.MaxMsgs = maxMsgs
+		}
+		return msg, consumed, nil
+	default:
+		return msg, 0, fmt.Errorf("unknown nats operation %q", op)
+	}
+
+	// the operations carrying a message are followed by its headers and payload, ended by a CRLF
+	total, err := strconv.Atoi(totalLength)
+	if err != nil || total < 0 {
+		return msg, 0, fmt.Errorf("invalid message size in %q", line)
+	}
+	headers := 0
+	if headerLength != "" {
+		headers, err = strconv.Atoi(headerLength)
+		if err != nil || headers < 0 || headers > total {
+			return msg, 0, fmt.Errorf("invalid header size in %q", line)
+		}
+	}
+	if len(buffer) < consumed+total+2 {
+		return msg, 0, errIncomplete
+	}
+	data := buffer[consumed : consumed+total]
+	msg.Headers = string(data[:headers])
+	msg.Payload = encodePayload(data[headers:])
+	return msg, consumed + total + 2, nil
+}
+
+// optional returns the argument at index when the operation has its optional argument, that is count arguments.
+func optional(args []string, count, index int) string {
+	if len(args) != count {
+		return ""
+	}
+	return args[index]
+}
+
+// encodeOp encodes an operation as it is sent on the wire.
+func encodeOp(msg models.NATSMessage) ([]byte, error) {
+	var (
+		buf   bytes.Buffer
+		data  []byte
+		err   error
+		reply string
+	)
+	if msg.ReplyTo != "" {
+		reply = " " + msg.ReplyTo
+	}
+	switch msg.Op {
+	case "INFO", "CONNECT":
+		buf.WriteString(msg.Op + " " + msg.Options + "\r\n")
+	case "PING", "PONG", "+OK":
+		buf.WriteString(msg.Op + "\r\n")
+	case "-ERR":
+		buf.WriteString("-ERR '" + msg.Error + "'\r\n")
+	case "MSG", "HMSG", "PUB", "HPUB":
+		data, err = decodePayload(msg.Payload)
+		if err != nil {
+			return nil, err
+		}
+		target := msg.Subject
+		if msg.Op == "MSG" || msg.Op == "HMSG" {
+			target += " " + msg.Sid
+		}
+		target += reply
+		if msg.Op == "HMSG" || msg.Op == "HPUB" {
+			fmt.Fprintf(&buf, "%s %s %d %d\r\n", msg.Op, target, len(msg.Headers), len(msg.Headers)+len(data))
+			buf.WriteString(msg.Headers)
+		} else {
+			fmt.Fprintf(&buf, "%s %s %d\r\n", msg.Op, target, len(data))
+		}
+		buf.Write(data)
+		buf.WriteString("\r\n")
+	default:
+		return nil, fmt.Errorf("unknown nats operation %q", msg.Op)
+	}
+	return buf.Bytes(), nil
+}
+
+// redactOptions removes the credentials from the options of CONNECT.
+func redactOptions(options string) string {
+	fields := map[string]interface{}{}
+	if err := json.Unmarshal([]byte(options), &fields); err != nil {
+		return ""
+	}
+	for _, credential := range credentials {
+		delete(fields, credential)
+	}
+	redacted, err := json.Marshal(fields)
+	if err != nil {
+		return ""
+	}
+	return string(redacted)
+}
+
+// isVerbose reports whether the client asked the server to acknowledge every operation with +OK.
+func isVerbose(options string) bool {
+	fields := struct {
+		Verbose bool `json:"verbose"`
+	}{}
+	return json.Unmarshal([]byte(options), &fields) == nil && fields.Verbose
+}
+
+// connectionName returns the name given by the client to its connection.
+func connectionName(options string) string {
+	fields := struct {
+		Name string `json:"name"`
+	}{}
+	json.Unmarshal([]byte(options), &fields)
+	return fields.Name
+}
+
+// subjectMatches reports whether the subject matches the subject of a subscription, where * matches a token and >
+// the remaining tokens.
+func subjectMatches(pattern, subject string) bool {
+	patternTokens, subjectTokens := strings.Split(pattern, "."), strings.Split(subject, ".")
+	for i, token := range patternTokens {
+		if token == ">" {
+			return i < len(subjectTokens)
+		}
+		if i >= len(subjectTokens) || (token != "*" && token != subjectTokens[i]) {
+			return false
+		}
+	}
+	return len(patternTokens) == len(subjectTokens)
+}
+
+func isInbox(subject string) bool {
+	return strings.HasPrefix(subject, inboxPrefix)
+}
+
+// sameSubject reports whether two subjects are the same, the inboxes being all alike.
+func sameSubject(a, b string) bool {
+	return a == b || (isInbox(a) && isInbox(b))
+}
+
+func encodePayload(payload []byte) *models.OutputBinary {
+	if len(payload) == 0 {
+		return nil
+	}
+	if isAsciiPrintable(string(payload)) {
+		return &models.OutputBinary{Type: models.String, Data: string(payload)}
+	}
+	return &models.OutputBinary{Type: "binary", Data: base64.StdEncoding.EncodeToString(payload)}
+}
+
+func decodePayload(payload *models.OutputBinary) ([]byte, error) {
+	if payload == nil {
+		return nil, nil
+	}
+	if payload.Type == models.String {
+		return []byte(payload.Data), nil
+	}
+	data, err := base64.StdEncoding.DecodeString(payload.Data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode the nats payload: %v", err)
+	}
+	return data, nil
+}
+
+func isAsciiPrintable(s string) bool {
+	for _, r := range s {
+		if r > unicode.MaxASCII || (!unicode.IsPrint(r) && r != '\r' && r != '\n' && r != '\t') {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,36 @@
+package postgresparser
+
+import (
+	"bytes"
+	"encoding/binary"
+)
+
+// The COPY FROM STDIN of a query is answered by a CopyInResponse ('G'), after which the client streams its rows in
+// CopyData ('d') messages, without waiting for any response, until a CopyDone ('c') or a CopyFail ('f'). The rows are
+// split in buffers which differ from a run to the next, so the whole stream is recorded and matched as one request.
+
+// hasMessage reports whether the buffer holds a complete message of one of the types, the messages being read from
+// its start.
+func hasMessage(buffer []byte, types ...byte) bool {
+	for i := 0; i+5 <= len(buffer); {
+		length := int(binary.BigEndian.Uint32(buffer[i+1:]))
+		if length < 4 || i+1+length > len(buffer) {
+			return false
+		}
+		if bytes.IndexByte(types, buffer[i]) != -1 {
+			return true
+		}
+		i += 1 + length
+	}
+	return false
+}
+
+// isCopyIn reports whether the response starts the COPY FROM STDIN of the query.
+func isCopyIn(response []byte) bool {
+	return hasMessage(response, 'G')
+}
+
+// isCopyEnd reports whether the client ended the stream of the rows of the COPY FROM STDIN.
+func isCopyEnd(stream []byte) bool {
+	return hasMessage(stream, 'c', 'f')
+}
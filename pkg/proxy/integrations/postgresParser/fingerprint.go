@@ -0,0 +1,84 @@
+package postgresparser
+
+import (
+	"go.keploy.io/server/pkg/models"
+	"go.keploy.io/server/pkg/proxy/integrations/sqlquery"
+	"go.uber.org/zap"
+)
+
+// findFingerprintMatch returns the index of the mock whose requests carry the same messages as the request buffers,
+// their queries being compared on their fingerprint and the parameters of their Binds being ignored, or -1 when
+// there is none. It is the fallback of the exact matching in the fingerprint matching mode.
+func findFingerprintMatch(tcsMocks []*models.Mock, requestBuffers [][]byte, logger *zap.Logger) int {
+	requests := make([]*models.Backend, len(requestBuffers))
+	hasQuery := false
+	for i, buffer := range requestBuffers {
+		if isStartupPacket(buffer) || len(buffer) < 5 {
+			return -1
+		}
+		request, err := decodeBackendMessages(buffer, logger)
+		if err != nil {
+			logger.Debug("failed to decode the postgres request for matching", zap.Error(err))
+			return -1
+		}
+		requests[i] = request
+		hasQuery = hasQuery || request.Query.String != "" || len(request.Parses) > 0
+	}
+	if !hasQuery {
+		return -1
+	}
+
+	for idx, mock := range tcsMocks {
+		if mock == nil || len(mock.Spec.PostgresRequests) != len(requests) {
+			continue
+		}
+		matched := true
+		for i, request := range requests {
+			if !matchFingerprint(mock.Spec.PostgresRequests[i], *request) {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			logger.Debug("matched the postgres request with a mock on the fingerprint of its query", zap.String("mock", mock.Name))
+			return idx
+		}
+	}
+	return -1
+}
+
+func matchFingerprint(mockRequest, request models.Backend) bool {
+	if mockRequest.Payload != "" || len(mockRequest.PacketTypes) != len(request.PacketTypes) {
+		return false
+	}
+	for i := range request.PacketTypes {
+		if mockRequest.PacketTypes[i] != request.PacketTypes[i] {
+			return false
+		}
+	}
+	if len(mockRequest.Parses) != len(request.Parses) || len(mockRequest.Binds) != len(request.Binds) {
+		return false
+	}
+	if request.Query.String != "" && queryFingerprint(mockRequest) != request.QueryFingerprint {
+		return false
+	}
+	for i, parse := range request.Parses {
+		if sqlquery.Fingerprint(mockRequest.Parses[i].Query, sqlquery.Postgres) != sqlquery.Fingerprint(parse.Query, sqlquery.Postgres) {
+			return false
+		}
+	}
+	for i, bind := range request.Binds {
+		if len(mockRequest.Binds[i].Parameters) != len(bind.Parameters) {
+			return false
+		}
+	}
+	return true
+}
+
+// queryFingerprint returns the fingerprint of the simple query of a mock, computed for the mocks recorded without it.
+func queryFingerprint(mockRequest models.Backend) string {
+	if mockRequest.QueryFingerprint != "" {
+		return mockRequest.QueryFingerprint
+	}
+	return sqlquery.Fingerprint(mockRequest.Query.String, sqlquery.Postgres)
+}
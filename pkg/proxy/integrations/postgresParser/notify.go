@@ -0,0 +1,288 @@
+package postgresparser
+
+import (
+	"context"
+	"encoding/binary"
+	"net"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgproto3/v2"
+	"go.keploy.io/server/pkg/hooks"
+	"go.keploy.io/server/pkg/models"
+	"go.uber.org/zap"
+)
+
+// The NotificationResponse ('A') of a NOTIFY is sent by the server to the connections listening on its channel at
+// any time, rather than in response to one of their requests. Each notification is recorded as a mock without any
+// request, whose metadata holds its channel and the delay since the previous postgres exchange. In test mode a
+// connection which ran LISTEN is sent the notifications of its channels once the postgres mocks recorded before them
+// have been consumed, after their recorded delay.
+
+const (
+	notificationMetadata = "notification"
+	delayMetadata        = "delay"
+	// notifyPollInterval is the interval at which the listening connections look for the notifications due
+	notifyPollInterval = 20 * time.Millisecond
+)
+
+var (
+	exchangeMu sync.Mutex
+	// lastExchange is the time of the last postgres request or response recorded, on any connection
+	lastExchange time.Time
+)
+
+// recordExchange notes a postgres request or response, from which the delay of the next notification is measured.
+func recordExchange() {
+	exchangeMu.Lock()
+	lastExchange = time.Now()
+	exchangeMu.Unlock()
+}
+
+// notifications returns the NotificationResponses of the buffer when it holds nothing else.
+func notifications(buffer []byte) ([]pgproto3.NotificationResponse, bool) {
+	var responses []pgproto3.NotificationResponse
+	for i := 0; i < len(buffer); {
+		if i+5 > len(buffer) || buffer[i] != 'A' {
+			return nil, false
+		}
+		length := int(binary.BigEndian.Uint32(buffer[i+1:]))
+		if length < 4 || i+1+length > len(buffer) {
+			return nil, false
+		}
+		var response pgproto3.NotificationResponse
+		if err := response.Decode(buffer[i+5 : i+1+length]); err != nil {
+			return nil, false
+		}
+		responses = append(responses, response)
+		i += 1 + length
+	}
+	return responses, len(responses) > 0
+}
+
+// recordNotifications records each notification as a mock of its own.
+func recordNotifications(responses []pgproto3.NotificationResponse, h *hooks.Hook, ctx context.Context) {
+	exchangeMu.Lock()
+	now := time.Now()
+	var delay time.Duration
+	if !lastExchange.IsZero() {
+		delay = now.Sub(lastExchange)
+	}
+	lastExchange = now
+	exchangeMu.Unlock()
+
+	for _, response := range responses {
+		h.AppendMocks(&models.Mock{
+			Version: models.GetVersion(),
+			Name:    "mocks",
+			Kind:    models.Postgres,
+			Spec: models.MockSpec{
+				Metadata: map[string]string{
+					notificationMetadata: response.Channel,
+					delayMetadata:        delay.Round(time.Microsecond).String(),
+				},
+				PostgresResponses: []models.Frontend{{
+					PacketTypes:          []string{"A"},
+					Identfier:            "ServerResponse",
+					NotificationResponse: response,
+					MsgType:              'A',
+				}},
+				ReqTimestampMock: now,
+				ResTimestampMock: now,
+			},
+		}, ctx)
+		// the notifications of a buffer follow each other at once
+		delay = 0
+	}
+}
+
+// isNotificationMock reports whether the mock is a recorded notification, which has no request to match.
+func isNotificationMock(mock *models.Mock) bool {
+	_, ok := mock.Spec.Metadata[notificationMetadata]
+	return mock.Kind == models.Postgres && ok
+}
+
+// listenPattern matches the LISTEN and UNLISTEN statements with their channel.
+var listenPattern = regexp.MustCompile(`(?i)\b(un)?listen\s+("(?:[^"]|"")+"|[a-z_][a-z0-9_$]*|\*)`)
+
+// deliveredConfigNotifications holds the config notifications already sent, the config mocks not being consumed.
+var deliveredConfigNotifications sync.Map
+
+// notifier sends the recorded notifications to a connection in test mode.
+type notifier struct {
+	hooks      *hooks.Hook
+	logger     *zap.Logger
+	clientConn net.Conn
+	mu         sync.Mutex
+	// channels are the channels the connection listens on
+	channels map[string]bool
+	// due holds the time at which the notifications waiting to be sent are due
+	due  map[*models.Mock]time.Time
+	done chan struct{}
+}
+
+func newNotifier(clientConn net.Conn, h *hooks.Hook, logger *zap.Logger) *notifier {
+	return &notifier{
+		hooks:      h,
+		logger:     logger,
+		clientConn: clientConn,
+		channels:   map[string]bool{},
+		due:        map[*models.Mock]time.Time{},
+	}
+}
+
+// track updates the channels listened on by the connection from its requests, and starts sending the notifications
+// of the first one.
+func (n *notifier) track(requests [][]byte) {
+	for _, buffer := range requests {
+		if isStartupPacket(buffer) || len(buffer) < 5 {
+			continue
+		}
+		request, err := decodeBackendMessages(buffer, n.logger)
+		if err != nil {
+			continue
+		}
+		queries := []string{request.Query.String}
+		for _, parse := range request.Parses {
+			queries = append(queries, parse.Query)
+		}
+		for _, query := range queries {
+			for _, statement := range listenPattern.FindAllStringSubmatch(query, -1) {
+				n.listen(statement[2], statement[1] == "")
+			}
+		}
+	}
+}
+
+func (n *notifier) listen(channel string, listen bool) {
+	if strings.HasPrefix(channel, `"`) {
+		channel = strings.ReplaceAll(channel[1:len(channel)-1], `""`, `"`)
+	} else {
+		// the unquoted identifiers are folded to lower case by postgres
+		channel = strings.ToLower(channel)
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	switch {
+	case listen:
+		n.channels[channel] = true
+		if n.done == nil {
+			n.done = make(chan struct{})
+			go n.run(n.done)
+		}
+	case channel == "*":
+		n.channels = map[string]bool{}
+	default:
+		delete(n.channels, channel)
+	}
+}
+
+func (n *notifier) listening(channel string) bool {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.channels[channel]
+}
+
+// stop ends the sending of the notifications, once the connection is closed.
+func (n *notifier) stop() {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.done != nil {
+		close(n.done)
+		n.done = nil
+	}
+}
+
+func (n *notifier) run(done chan struct{}) {
+	ticker := time.NewTicker(notifyPollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+		}
+		if err := n.deliver(); err != nil {
+			n.logger.Debug("failed to send the recorded notifications to the postgres client", zap.Error(err))
+			return
+		}
+	}
+}
+
+// deliver sends the notifications which are due, in the order of their recording. A tcs notification is held back
+// while a postgres mock recorded before it hasn't been consumed.
+func (n *notifier) deliver() error {
+	tcsMocks, err := n.hooks.GetTcsMocks()
+	if err != nil {
+		return err
+	}
+	var pending time.Time
+	for _, mock := range tcsMocks {
+		if mock.Kind == models.Postgres && len(mock.Spec.PostgresRequests) > 0 {
+			pending = mock.Spec.ReqTimestampMock
+			break
+		}
+	}
+	for _, mock := range tcsMocks {
+		if !isNotificationMock(mock) || !n.listening(mock.Spec.Metadata[notificationMetadata]) {
+			continue
+		}
+		if (!pending.IsZero() && !mock.Spec.ReqTimestampMock.Before(pending)) || !n.isDue(mock) {
+			break
+		}
+		isDeleted, err := n.hooks.DeleteTcsMock(mock)
+		if err != nil {
+			return err
+		}
+		if isDeleted {
+			if err := n.send(mock); err != nil {
+				return err
+			}
+		}
+	}
+
+	configMocks, err := n.hooks.GetConfigMocks()
+	if err != nil {
+		return err
+	}
+	for _, mock := range configMocks {
+		if !isNotificationMock(mock) || !n.listening(mock.Spec.Metadata[notificationMetadata]) || !n.isDue(mock) {
+			continue
+		}
+		if _, delivered := deliveredConfigNotifications.LoadOrStore(mock, true); !delivered {
+			if err := n.send(mock); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// isDue reports whether the recorded delay of the notification elapsed since it could first be sent.
+func (n *notifier) isDue(mock *models.Mock) bool {
+	due, ok := n.due[mock]
+	if !ok {
+		delay, _ := time.ParseDuration(mock.Spec.Metadata[delayMetadata])
+		due = time.Now().Add(delay)
+		n.due[mock] = due
+	}
+	return !time.Now().Before(due)
+}
+
+func (n *notifier) send(mock *models.Mock) error {
+	delete(n.due, mock)
+	for _, response := range mock.Spec.PostgresResponses {
+		encoded, err := PostgresDecoderFrontend(response)
+		if err != nil {
+			return err
+		}
+		if _, err := n.clientConn.Write(encoded); err != nil {
+			return err
+		}
+	}
+	n.logger.Debug("sent the recorded notification to the postgres client", zap.String("channel", mock.Spec.Metadata[notificationMetadata]))
+	return nil
+}
@@ -1,6 +1,7 @@
 package postgresparser
 
 import (
+	"bytes"
 	"context"
 	"encoding/base64"
 	"encoding/binary"
@@ -19,6 +20,7 @@ import (
 	"go.keploy.io/server/pkg/proxy/util"
 
 	"go.keploy.io/server/pkg/hooks"
+	"go.keploy.io/server/pkg/metrics"
 	"go.keploy.io/server/pkg/models"
 
 	"go.keploy.io/server/utils"
@@ -30,12 +32,18 @@ var Emoji = "\U0001F430" + " Keploy:"
 type PostgresParser struct {
 	logger *zap.Logger
 	hooks  *hooks.Hook
+	// password authenticates the clients with SCRAM-SHA-256 in test mode, the emulation being off when it is empty
+	password string
+	// sqlConfig sets how the queries are matched with the mocks in test mode
+	sqlConfig models.SqlConfig
 }
 
-func NewPostgresParser(logger *zap.Logger, h *hooks.Hook) *PostgresParser {
+func NewPostgresParser(logger *zap.Logger, h *hooks.Hook, authPassword string, sqlConfig models.SqlConfig) *PostgresParser {
 	return &PostgresParser{
-		logger: logger,
-		hooks:  h,
+		logger:    logger,
+		hooks:     h,
+		password:  authPassword,
+		sqlConfig: sqlConfig,
 	}
 }
 
@@ -63,7 +71,7 @@ func (p *PostgresParser) ProcessOutgoing(requestBuffer []byte, clientConn, destC
 	case models.MODE_RECORD:
 		encodePostgresOutgoing(requestBuffer, clientConn, destConn, p.hooks, p.logger, ctx)
 	case models.MODE_TEST:
-		decodePostgresOutgoing(requestBuffer, clientConn, destConn, p.hooks, p.logger, ctx)
+		decodePostgresOutgoing(requestBuffer, clientConn, destConn, p.hooks, p.logger, ctx, p.password, p.sqlConfig)
 	default:
 		p.logger.Info("Invalid mode detected while intercepting outgoing http call", zap.Any("mode", models.GetMode()))
 	}
@@ -152,6 +160,10 @@ func encodePostgresOutgoing(requestBuffer []byte, clientConn, destConn net.Conn,
 	reqTimestampMock := time.Now()
 	var resTimestampMock time.Time
 
+	// the rows of a COPY FROM STDIN streamed by the client so far
+	var copyStream []byte
+	copyIn := false
+
 	for {
 
 		sigChan := make(chan os.Signal, 1)
@@ -185,6 +197,7 @@ func encodePostgresOutgoing(requestBuffer []byte, clientConn, destConn net.Conn,
 				logger.Error("failed to write request message to the destination server", zap.Error(err))
 				return err
 			}
+			recordExchange()
 
 			logger.Debug("the iteration for the pg request ends with no of pgReqs:" + strconv.Itoa(len(pgRequests)) + " and pgResps: " + strconv.Itoa(len(pgResponses)))
 			if !isPreviousChunkRequest && len(pgRequests) > 0 && len(pgResponses) > 0 {
@@ -203,83 +216,30 @@ func encodePostgresOutgoing(requestBuffer []byte, clientConn, destConn net.Conn,
 				pgResponses = []models.Frontend{}
 			}
 
+			if copyIn {
+				// the rows are recorded as a single request once the client ended their stream
+				copyStream = append(copyStream, buffer...)
+				if !isCopyEnd(copyStream) {
+					isPreviousChunkRequest = true
+					continue
+				}
+				buffer, copyStream, copyIn = copyStream, nil, false
+			}
+
 			bufStr := base64.StdEncoding.EncodeToString(buffer)
 			if bufStr != "" {
-
-				pg := NewBackend()
-				var msg pgproto3.FrontendMessage
-
-				if !isStartupPacket(buffer) && len(buffer) > 5 {
-					bufferCopy := buffer
-					for i := 0; i < len(bufferCopy); {
-						logger.Debug("Inside the if condition")
-						pg.BackendWrapper.MsgType = buffer[i]
-						pg.BackendWrapper.BodyLen = int(binary.BigEndian.Uint32(buffer[i+1:])) - 4
-						if len(buffer) < (i + pg.BackendWrapper.BodyLen + 5) {
-							logger.Error("failed to translate the postgres request message due to shorter network packet buffer")
-							continue
-						}
-						msg, err = pg.TranslateToReadableBackend(buffer[i:(i + pg.BackendWrapper.BodyLen + 5)])
-						if err != nil && buffer[i] != 112 {
-							logger.Error("failed to translate the request message to readable", zap.Error(err))
-						}
-						if pg.BackendWrapper.MsgType == 'p' {
-							pg.BackendWrapper.PasswordMessage = *msg.(*pgproto3.PasswordMessage)
+				// a lone Sync or Flush of the extended query protocol is only 5 bytes long
+				if !isStartupPacket(buffer) && len(buffer) >= 5 {
+					pg_mock, err := decodeBackendMessages(buffer, logger)
+					if err != nil {
+						logger.Error("failed to decode the postgres request message", zap.Error(err))
+						pg_mock = &models.Backend{
+							Identfier: "ClientRequest",
+							Length:    uint32(len(buffer)),
+							Payload:   bufStr,
 						}
-
-						if pg.BackendWrapper.MsgType == 'P' {
-							pg.BackendWrapper.Parse = *msg.(*pgproto3.Parse)
-							pg.BackendWrapper.Parses = append(pg.BackendWrapper.Parses, pg.BackendWrapper.Parse)
-						}
-
-						if pg.BackendWrapper.MsgType == 'B' {
-							pg.BackendWrapper.Bind = *msg.(*pgproto3.Bind)
-							pg.BackendWrapper.Binds = append(pg.BackendWrapper.Binds, pg.BackendWrapper.Bind)
-						}
-
-						if pg.BackendWrapper.MsgType == 'E' {
-							pg.BackendWrapper.Execute = *msg.(*pgproto3.Execute)
-							pg.BackendWrapper.Executes = append(pg.BackendWrapper.Executes, pg.BackendWrapper.Execute)
-						}
-
-						pg.BackendWrapper.PacketTypes = append(pg.BackendWrapper.PacketTypes, string(pg.BackendWrapper.MsgType))
-
-						i += (5 + pg.BackendWrapper.BodyLen)
-					}
-
-					pg_mock := &models.Backend{
-						PacketTypes: pg.BackendWrapper.PacketTypes,
-						Identfier:   "ClientRequest",
-						Length:      uint32(len(requestBuffer)),
-						// Payload:             bufStr,
-						Bind:                pg.BackendWrapper.Bind,
-						Binds:               pg.BackendWrapper.Binds,
-						PasswordMessage:     pg.BackendWrapper.PasswordMessage,
-						CancelRequest:       pg.BackendWrapper.CancelRequest,
-						Close:               pg.BackendWrapper.Close,
-						CopyData:            pg.BackendWrapper.CopyData,
-						CopyDone:            pg.BackendWrapper.CopyDone,
-						CopyFail:            pg.BackendWrapper.CopyFail,
-						Describe:            pg.BackendWrapper.Describe,
-						Execute:             pg.BackendWrapper.Execute,
-						Executes:            pg.BackendWrapper.Executes,
-						Flush:               pg.BackendWrapper.Flush,
-						FunctionCall:        pg.BackendWrapper.FunctionCall,
-						GssEncRequest:       pg.BackendWrapper.GssEncRequest,
-						Parse:               pg.BackendWrapper.Parse,
-						Parses:              pg.BackendWrapper.Parses,
-						Query:               pg.BackendWrapper.Query,
-						SSlRequest:          pg.BackendWrapper.SSlRequest,
-						StartupMessage:      pg.BackendWrapper.StartupMessage,
-						SASLInitialResponse: pg.BackendWrapper.SASLInitialResponse,
-						SASLResponse:        pg.BackendWrapper.SASLResponse,
-						Sync:                pg.BackendWrapper.Sync,
-						Terminate:           pg.BackendWrapper.Terminate,
-						MsgType:             pg.BackendWrapper.MsgType,
-						AuthType:            pg.BackendWrapper.AuthType,
 					}
 					pgRequests = append(pgRequests, *pg_mock)
-
 				}
 
 				if isStartupPacket(buffer) {
@@ -292,6 +252,17 @@ func encodePostgresOutgoing(requestBuffer []byte, clientConn, destConn net.Conn,
 			}
 			isPreviousChunkRequest = true
 		case buffer := <-destBufferChannel:
+			if responses, ok := notifications(buffer); ok {
+				// the notifications are sent by the server at any time, and recorded apart from the responses
+				_, err := clientConn.Write(buffer)
+				if err != nil {
+					logger.Error("failed to write the notification to the client", zap.Error(err))
+					return err
+				}
+				recordNotifications(responses, h, ctx)
+				continue
+			}
+			recordExchange()
 			if isPreviousChunkRequest {
 				// store the request timestamp
 				reqTimestampMock = time.Now()
@@ -303,6 +274,9 @@ func encodePostgresOutgoing(requestBuffer []byte, clientConn, destConn net.Conn,
 				logger.Error("failed to write response to the client", zap.Error(err))
 				return err
 			}
+			if isCopyIn(buffer) {
+				copyIn = true
+			}
 
 			bufStr := base64.StdEncoding.EncodeToString(buffer)
 
@@ -442,8 +416,20 @@ func ReadBuffConn(conn net.Conn, bufferChannel chan []byte, errChannel chan erro
 }
 
 // This is the decoding function for the postgres wiremessage
-func decodePostgresOutgoing(requestBuffer []byte, clientConn, destConn net.Conn, h *hooks.Hook, logger *zap.Logger, ctx context.Context) error {
+func decodePostgresOutgoing(requestBuffer []byte, clientConn, destConn net.Conn, h *hooks.Hook, logger *zap.Logger, ctx context.Context, password string, sqlConfig models.SqlConfig) error {
 	pgRequests := [][]byte{requestBuffer}
+	// scramAuth emulates the SCRAM authentication of the connection when a password is given
+	var scramAuth *scramServer
+	if password != "" {
+		scramAuth = newScramServer(password, h, logger)
+	}
+	// statements prepared on the connection, by name, to match the Binds of the extended query protocol
+	preparedStatements := map[string]string{}
+	// copyIn is set while the client streams the rows of a COPY FROM STDIN
+	copyIn := false
+	// notifier sends the recorded notifications of the channels listened on by the connection
+	notifier := newNotifier(clientConn, h, logger)
+	defer notifier.stop()
 
 	for {
 		// Since protocol packets have to be parsed for checking stream end,
@@ -476,12 +462,35 @@ func decodePostgresOutgoing(requestBuffer []byte, clientConn, destConn net.Conn,
 			continue
 		}
 
-		matched, pgResponses, err := matchingReadablePG(pgRequests, h)
+		if copyIn {
+			// the rows are matched as a single request once the client ended their stream, whatever their buffers
+			stream := bytes.Join(pgRequests, nil)
+			if !isCopyEnd(stream) {
+				continue
+			}
+			pgRequests = [][]byte{stream}
+			copyIn = false
+		}
+
+		if scramAuth != nil {
+			handled, err := scramAuth.handle(pgRequests, clientConn)
+			if err != nil {
+				return err
+			}
+			if handled {
+				pgRequests = [][]byte{}
+				continue
+			}
+		}
+
+		matched, pgResponses, err := matchingReadablePG(pgRequests, h, preparedStatements, scramAuth != nil, sqlConfig, logger)
 		if err != nil {
 			return fmt.Errorf("error while matching tcs mocks %v", err)
 		}
+		trackPreparedStatements(pgRequests, preparedStatements, logger)
 
 		if !matched {
+			metrics.MatchFailures.Inc("postgres")
 			_, err = util.Passthrough(clientConn, destConn, pgRequests, h.Recover, logger)
 
 			if err != nil {
@@ -505,7 +514,11 @@ func decodePostgresOutgoing(requestBuffer []byte, clientConn, destConn net.Conn,
 				logger.Error("failed to write request message to the client application", zap.Error(err))
 				return err
 			}
+			if isCopyIn(encoded) {
+				copyIn = true
+			}
 		}
+		notifier.track(pgRequests)
 		// update for the next dependency call
 		pgRequests = [][]byte{}
 	}
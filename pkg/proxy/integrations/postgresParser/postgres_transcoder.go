@@ -9,6 +9,7 @@ import (
 
 	"github.com/jackc/pgproto3/v2"
 	"go.keploy.io/server/pkg/models"
+	"go.keploy.io/server/pkg/proxy/integrations/sqlquery"
 	"go.uber.org/zap"
 )
 
@@ -50,6 +51,9 @@ func checkScram(encoded []byte, log *zap.Logger) bool {
 }
 
 func isStartupPacket(packet []byte) bool {
+	if len(packet) < 8 {
+		return false
+	}
 	protocolVersion := binary.BigEndian.Uint32(packet[4:8])
 	// printStartupPacketDetails(packet)
 	return protocolVersion == 196608 // 3.0 in PostgreSQL
@@ -374,3 +378,54 @@ func (b *BackendWrapper) DecodeStartupMessage(buf []byte) (pgproto3.FrontendMess
 		return nil, fmt.Errorf("unknown startup message code: %d", code)
 	}
 }
+
+// decodeBackendMessages translates the messages of a request sent after the startup into a readable mock request.
+// The extended query messages are collected in order, so that a pipeline of Parse/Bind/Describe/Execute/Sync can be
+// matched and encoded back from the mock.
+func decodeBackendMessages(buffer []byte, logger *zap.Logger) (*models.Backend, error) {
+	pg := NewBackend()
+	for i := 0; i < len(buffer); {
+		if len(buffer) < i+5 {
+			return nil, errors.New("incomplete header of the postgres request message")
+		}
+		pg.BackendWrapper.MsgType = buffer[i]
+		pg.BackendWrapper.BodyLen = int(binary.BigEndian.Uint32(buffer[i+1:])) - 4
+		if pg.BackendWrapper.BodyLen < 0 || len(buffer) < i+pg.BackendWrapper.BodyLen+5 {
+			return nil, errors.New("failed to translate the postgres request message due to shorter network packet buffer")
+		}
+		msg, err := pg.TranslateToReadableBackend(buffer[i:(i + pg.BackendWrapper.BodyLen + 5)])
+		if err != nil && buffer[i] != 'p' {
+			logger.Error("failed to translate the request message to readable", zap.Error(err))
+		}
+
+		switch pg.BackendWrapper.MsgType {
+		case 'p':
+			if password, ok := msg.(*pgproto3.PasswordMessage); ok {
+				pg.BackendWrapper.PasswordMessage = *password
+			}
+		case 'P':
+			pg.BackendWrapper.Parses = append(pg.BackendWrapper.Parses, pg.BackendWrapper.Parse)
+		case 'B':
+			pg.BackendWrapper.Binds = append(pg.BackendWrapper.Binds, pg.BackendWrapper.Bind)
+		case 'D':
+			pg.BackendWrapper.Describes = append(pg.BackendWrapper.Describes, pg.BackendWrapper.Describe)
+		case 'E':
+			pg.BackendWrapper.Executes = append(pg.BackendWrapper.Executes, pg.BackendWrapper.Execute)
+		case 'C':
+			pg.BackendWrapper.Closes = append(pg.BackendWrapper.Closes, pg.BackendWrapper.Close)
+		case 'd':
+			// the rows of a COPY FROM STDIN are kept as text, the binary ones being written in base64 by the yaml
+			pg.BackendWrapper.CopyDatas = append(pg.BackendWrapper.CopyDatas, string(pg.BackendWrapper.CopyData.Data))
+			pg.BackendWrapper.CopyData = pgproto3.CopyData{}
+		}
+
+		pg.BackendWrapper.PacketTypes = append(pg.BackendWrapper.PacketTypes, string(pg.BackendWrapper.MsgType))
+		i += (5 + pg.BackendWrapper.BodyLen)
+	}
+	if pg.BackendWrapper.Query.String != "" {
+		pg.BackendWrapper.QueryFingerprint, pg.BackendWrapper.QueryParameters = sqlquery.Normalize(pg.BackendWrapper.Query.String, sqlquery.Postgres)
+	}
+	pg.BackendWrapper.Identfier = "ClientRequest"
+	pg.BackendWrapper.Length = uint32(len(buffer))
+	return &pg.BackendWrapper, nil
+}
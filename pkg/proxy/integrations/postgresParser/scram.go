@@ -0,0 +1,324 @@
+package postgresparser
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/jackc/pgproto3/v2"
+	"go.keploy.io/server/pkg/hooks"
+	"go.keploy.io/server/pkg/models"
+	"go.keploy.io/server/pkg/proxy/integrations/scram"
+	"go.uber.org/zap"
+)
+
+// The SCRAM-SHA-256 authentication can't be replayed from the mocks, since the client generates a new nonce for each
+// connection and checks the signature of the server, computed from the whole conversation. In test mode the proxy
+// acts as the server: it answers the startup of the client with AuthenticationSASL, rebuilds the server-first message
+// around the nonce of the client, checks the proof of the client and signs the conversation with the password given
+// by --postgresPassword. The messages following the authentication, like the ParameterStatus, the BackendKeyData and
+// the ReadyForQuery, are replayed from the recorded conversation.
+
+const (
+	scramMechanism = "SCRAM-SHA-256"
+	// scramIterations is the iteration count of the server-first message, the default of postgres
+	scramIterations = 4096
+)
+
+// scramStep is the step of the conversation emulated on a connection.
+type scramStep int
+
+const (
+	// scramIdle waits for the startup message of the client
+	scramIdle scramStep = iota
+	// scramStarted waits for the SASLInitialResponse carrying the client-first message
+	scramStarted
+	// scramContinued waits for the SASLResponse carrying the client-final message
+	scramContinued
+	// scramDone is reached once the client is authenticated, the next requests being matched with the mocks
+	scramDone
+)
+
+// scramServer emulates the server side of the SCRAM-SHA-256 authentication of a connection.
+type scramServer struct {
+	hooks    *hooks.Hook
+	logger   *zap.Logger
+	password string
+	step     scramStep
+	// user is the user of the startup message, reported when its password is wrong
+	user            string
+	clientFirstBare string
+	serverFirst     string
+	salt            []byte
+}
+
+func newScramServer(password string, h *hooks.Hook, logger *zap.Logger) *scramServer {
+	return &scramServer{
+		hooks:    h,
+		logger:   logger,
+		password: password,
+	}
+}
+
+// handle answers the request when it belongs to the authentication, and reports whether it did. The startup
+// messages are only answered when a SCRAM authentication was recorded, the other methods being matched with the
+// mocks as usual.
+func (s *scramServer) handle(requests [][]byte, clientConn net.Conn) (bool, error) {
+	if s.step == scramDone || len(requests) != 1 {
+		return false, nil
+	}
+	request := requests[0]
+	switch s.step {
+	case scramIdle:
+		if !isStartupPacket(request) {
+			return false, nil
+		}
+		if _, ok := s.consumeRecorded(isScramStart); !ok {
+			return false, nil
+		}
+		var startup pgproto3.StartupMessage
+		if err := startup.Decode(request[4:]); err == nil {
+			s.user = startup.Parameters["user"]
+		}
+		s.step = scramStarted
+		s.logger.Debug("emulating the SCRAM authentication of the postgres connection", zap.String("user", s.user))
+		return true, s.write(clientConn, encodeMessages(&pgproto3.AuthenticationSASL{AuthMechanisms: []string{scramMechanism}}))
+	case scramStarted:
+		serverFirst, err := s.start(request)
+		if err != nil {
+			return true, err
+		}
+		s.consumeRecorded(isScramContinue)
+		s.step = scramContinued
+		return true, s.write(clientConn, encodeMessages(&pgproto3.AuthenticationSASLContinue{Data: []byte(serverFirst)}))
+	default:
+		serverFinal, err := s.finish(request)
+		if err != nil {
+			s.logger.Error("failed to authenticate the postgres client with the password of --postgresPassword", zap.String("user", s.user), zap.Error(err))
+			s.write(clientConn, encodeMessages(&pgproto3.ErrorResponse{
+				Severity: "FATAL",
+				Code:     "28P01",
+				Message:  fmt.Sprintf("password authentication failed for user %q", s.user),
+			}))
+			return true, err
+		}
+		s.step = scramDone
+		recorded, _ := s.consumeRecorded(isScramFinal)
+		buffer := encodeMessages(&pgproto3.AuthenticationSASLFinal{Data: []byte(serverFinal)}, &pgproto3.AuthenticationOk{})
+		return true, s.write(clientConn, append(buffer, afterAuthentication(recorded)...))
+	}
+}
+
+// start returns the server-first message answering the SASLInitialResponse, with a nonce extending the one of the
+// client and a new salt.
+func (s *scramServer) start(request []byte) (string, error) {
+	if len(request) < 5 || request[0] != 'p' {
+		return "", errors.New("expected a SASLInitialResponse from the postgres client")
+	}
+	var initial pgproto3.SASLInitialResponse
+	if err := initial.Decode(request[5:]); err != nil {
+		return "", fmt.Errorf("malformed SASLInitialResponse: %v", err)
+	}
+	if initial.AuthMechanism != scramMechanism {
+		return "", fmt.Errorf("unsupported authentication mechanism %q", initial.AuthMechanism)
+	}
+	// the bare message follows the gs2 header, made of the channel binding flag and the authorization identity
+	header := strings.SplitN(string(initial.Data), ",", 3)
+	if len(header) != 3 || header[0] == "p" {
+		return "", errors.New("malformed SCRAM client-first message, or channel binding requested")
+	}
+	clientNonce, err := scramAttribute(header[2], "r")
+	if err != nil {
+		return "", err
+	}
+	serverNonce, err := randomBase64(18)
+	if err != nil {
+		return "", err
+	}
+	s.salt = make([]byte, 16)
+	if _, err := rand.Read(s.salt); err != nil {
+		return "", err
+	}
+	s.clientFirstBare = header[2]
+	s.serverFirst = fmt.Sprintf("r=%s%s,s=%s,i=%d", clientNonce, serverNonce, base64.StdEncoding.EncodeToString(s.salt), scramIterations)
+	return s.serverFirst, nil
+}
+
+// finish checks the proof of the client-final message and returns the server-final message, which carries the
+// signature of the server.
+func (s *scramServer) finish(request []byte) (string, error) {
+	if len(request) < 5 || request[0] != 'p' {
+		return "", errors.New("expected a SASLResponse from the postgres client")
+	}
+	var response pgproto3.SASLResponse
+	if err := response.Decode(request[5:]); err != nil {
+		return "", fmt.Errorf("malformed SASLResponse: %v", err)
+	}
+	clientFinal := string(response.Data)
+	nonce, err := scramAttribute(clientFinal, "r")
+	if err != nil {
+		return "", err
+	}
+	if serverNonce, _ := scramAttribute(s.serverFirst, "r"); nonce != serverNonce {
+		return "", errors.New("the nonce of the SCRAM client-final message differs from the one of the server")
+	}
+	proofIndex := strings.LastIndex(clientFinal, ",p=")
+	if proofIndex < 0 {
+		return "", errors.New("the SCRAM client-final message has no proof")
+	}
+	authMessage := s.clientFirstBare + "," + s.serverFirst + "," + clientFinal[:proofIndex]
+
+	valid, err := scram.VerifyClientProof(clientFinal[proofIndex+len(",p="):], authMessage, scramMechanism, s.password, string(s.salt), scramIterations, s.logger)
+	if err != nil {
+		return "", err
+	}
+	if !valid {
+		return "", errors.New("the proof of the client doesn't match the password")
+	}
+	signature, err := scram.GenerateServerFinalMessage(authMessage, scramMechanism, s.password, string(s.salt), scramIterations, s.logger)
+	if err != nil {
+		return "", err
+	}
+	return "v=" + signature, nil
+}
+
+// consumeRecorded returns the recorded responses from the first one answering the step of the authentication,
+// consuming their mock when it is a tcs mock.
+func (s *scramServer) consumeRecorded(answers func(models.Frontend) bool) ([]models.Frontend, bool) {
+	tcsMocks, err := s.hooks.GetTcsMocks()
+	if err != nil {
+		s.logger.Debug("failed to get the tcs mocks for the postgres authentication", zap.Error(err))
+	}
+	for _, mock := range tcsMocks {
+		if responses := recordedAnswer(mock, answers); responses != nil {
+			if isDeleted, err := s.hooks.DeleteTcsMock(mock); err != nil || !isDeleted {
+				continue
+			}
+			return responses, true
+		}
+	}
+	configMocks, err := s.hooks.GetConfigMocks()
+	if err != nil {
+		s.logger.Debug("failed to get the config mocks for the postgres authentication", zap.Error(err))
+	}
+	for _, mock := range configMocks {
+		if responses := recordedAnswer(mock, answers); responses != nil {
+			return responses, true
+		}
+	}
+	return nil, false
+}
+
+func (s *scramServer) write(clientConn net.Conn, buffer []byte) error {
+	_, err := clientConn.Write(buffer)
+	if err != nil {
+		s.logger.Error("failed to write the authentication message to the postgres client", zap.Error(err))
+	}
+	return err
+}
+
+// recordedAnswer returns the responses of the postgres mock from the one answering the step of the authentication,
+// nil if none does.
+func recordedAnswer(mock *models.Mock, answers func(models.Frontend) bool) []models.Frontend {
+	if mock == nil || mock.Kind != models.Postgres {
+		return nil
+	}
+	for i, response := range mock.Spec.PostgresResponses {
+		if answers(response) {
+			return mock.Spec.PostgresResponses[i:]
+		}
+	}
+	return nil
+}
+
+// The steps of a recorded SCRAM conversation are told apart by their SASL messages rather than by their AuthType,
+// which is only kept for the last authentication message of a response.
+
+func isScramStart(response models.Frontend) bool {
+	return len(response.AuthenticationSASL.AuthMechanisms) > 0
+}
+
+func isScramContinue(response models.Frontend) bool {
+	return len(response.AuthenticationSASLContinue.Data) > 0
+}
+
+func isScramFinal(response models.Frontend) bool {
+	return len(response.AuthenticationSASLFinal.Data) > 0
+}
+
+// isScramMock reports whether the mock belongs to a recorded SCRAM conversation, which is answered by the
+// emulation instead of being matched.
+func isScramMock(mock *models.Mock) bool {
+	for _, response := range mock.Spec.PostgresResponses {
+		if isScramStart(response) || isScramContinue(response) || isScramFinal(response) {
+			return true
+		}
+	}
+	return false
+}
+
+// afterAuthentication returns the messages of the recorded responses following their authentication messages, like
+// the ParameterStatus, the BackendKeyData and the ReadyForQuery, or a default set of them when none was recorded.
+func afterAuthentication(recorded []models.Frontend) []byte {
+	var buffer []byte
+	for _, response := range recorded {
+		if len(response.PacketTypes) == 0 {
+			if encoded, err := PostgresDecoder(response.Payload); err == nil {
+				buffer = append(buffer, encoded...)
+			}
+			continue
+		}
+		packets := response.PacketTypes
+		response.PacketTypes = nil
+		for _, packet := range packets {
+			if packet != "R" {
+				response.PacketTypes = append(response.PacketTypes, packet)
+			}
+		}
+		if encoded, err := PostgresDecoderFrontend(response); err == nil {
+			buffer = append(buffer, encoded...)
+		}
+	}
+	if len(buffer) > 0 {
+		return buffer
+	}
+	return encodeMessages(
+		&pgproto3.ParameterStatus{Name: "client_encoding", Value: "UTF8"},
+		&pgproto3.ParameterStatus{Name: "DateStyle", Value: "ISO, MDY"},
+		&pgproto3.ParameterStatus{Name: "integer_datetimes", Value: "on"},
+		&pgproto3.ParameterStatus{Name: "server_encoding", Value: "UTF8"},
+		&pgproto3.ParameterStatus{Name: "standard_conforming_strings", Value: "on"},
+		&pgproto3.ParameterStatus{Name: "TimeZone", Value: "Etc/UTC"},
+		&pgproto3.BackendKeyData{},
+		&pgproto3.ReadyForQuery{TxStatus: 'I'},
+	)
+}
+
+func encodeMessages(messages ...pgproto3.BackendMessage) []byte {
+	var buffer []byte
+	for _, message := range messages {
+		buffer = message.Encode(buffer)
+	}
+	return buffer
+}
+
+// scramAttribute returns the value of an attribute of a SCRAM message.
+func scramAttribute(message, attribute string) (string, error) {
+	for _, field := range strings.Split(message, ",") {
+		if strings.HasPrefix(field, attribute+"=") {
+			return field[len(attribute)+1:], nil
+		}
+	}
+	return "", fmt.Errorf("no '%s' attribute in the SCRAM message", attribute)
+}
+
+func randomBase64(size int) (string, error) {
+	random := make([]byte, size)
+	if _, err := rand.Read(random); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(random), nil
+}
@@ -1,6 +1,7 @@
 package postgresparser
 
 import (
+	"bytes"
 	"encoding/base64"
 
 	"errors"
@@ -10,6 +11,8 @@ import (
 	"github.com/jackc/pgproto3/v2"
 	"go.keploy.io/server/pkg/hooks"
 	"go.keploy.io/server/pkg/models"
+	"go.keploy.io/server/pkg/proxy/integrations/matcher"
+	"go.keploy.io/server/pkg/proxy/integrations/sqlquery"
 	"go.keploy.io/server/pkg/proxy/util"
 	"go.uber.org/zap"
 )
@@ -196,7 +199,7 @@ func PostgresDecoderBackend(request models.Backend) ([]byte, error) {
 
 	var reqbuffer []byte
 	// list of packets available in the buffer
-	var b, e, p int = 0, 0, 0
+	var b, e, p, c, d, cd int = 0, 0, 0, 0, 0, 0
 	packets := request.PacketTypes
 	for _, packet := range packets {
 		// isme se encode ek ek
@@ -212,14 +215,24 @@ func PostgresDecoderBackend(request models.Backend) ([]byte, error) {
 			}
 			b++
 		case string('C'):
+			closeMsg := request.Close
+			if c < len(request.Closes) {
+				closeMsg = request.Closes[c]
+			}
+			c++
 			msg = &pgproto3.Close{
-				Object_Type: request.Close.Object_Type,
-				Name:        request.Close.Name,
+				Object_Type: closeMsg.Object_Type,
+				Name:        closeMsg.Name,
 			}
 		case string('D'):
+			describe := request.Describe
+			if d < len(request.Describes) {
+				describe = request.Describes[d]
+			}
+			d++
 			msg = &pgproto3.Describe{
-				ObjectType: request.Describe.ObjectType,
-				Name:       request.Describe.Name,
+				ObjectType: describe.ObjectType,
+				Name:       describe.Name,
 			}
 		case string('E'):
 			msg = &pgproto3.Execute{
@@ -228,26 +241,29 @@ func PostgresDecoderBackend(request models.Backend) ([]byte, error) {
 			}
 			e++
 		case string('F'):
-			// *msg.(*pgproto3.Flush) = request.Flush
-			msg = &pgproto3.Flush{}
-		case string('f'):
-			// *msg.(*pgproto3.FunctionCall) = request.FunctionCall
 			msg = &pgproto3.FunctionCall{
 				Function:         request.FunctionCall.Function,
 				Arguments:        request.FunctionCall.Arguments,
 				ArgFormatCodes:   request.FunctionCall.ArgFormatCodes,
 				ResultFormatCode: request.FunctionCall.ResultFormatCode,
 			}
+		case string('f'):
+			msg = &pgproto3.CopyFail{
+				Message: request.CopyFail.Message,
+			}
 		case string('d'):
+			data := request.CopyData.Data
+			if cd < len(request.CopyDatas) {
+				data = []byte(request.CopyDatas[cd])
+			}
+			cd++
 			msg = &pgproto3.CopyData{
-				Data: request.CopyData.Data,
+				Data: data,
 			}
 		case string('c'):
 			msg = &pgproto3.CopyDone{}
 		case string('H'):
-			msg = &pgproto3.CopyFail{
-				Message: request.CopyFail.Message,
-			}
+			msg = &pgproto3.Flush{}
 		case string('P'):
 			msg = &pgproto3.Parse{
 				Name:          request.Parses[p].Name,
@@ -391,7 +407,7 @@ func CheckValidEncode(tcsMocks []*models.Mock, h *hooks.Hook, log *zap.Logger) {
 	h.SetTcsMocks(tcsMocks)
 }
 
-func matchingReadablePG(requestBuffers [][]byte, h *hooks.Hook) (bool, []models.Frontend, error) {
+func matchingReadablePG(requestBuffers [][]byte, h *hooks.Hook, preparedStatements map[string]string, emulateScram bool, sqlConfig models.SqlConfig, logger *zap.Logger) (bool, []models.Frontend, error) {
 
 	for {
 
@@ -402,6 +418,29 @@ func matchingReadablePG(requestBuffers [][]byte, h *hooks.Hook) (bool, []models.
 		if err != nil {
 			return false, nil, fmt.Errorf("error while fetching tcs mocks %v", err)
 		}
+		if emulateScram {
+			// the recorded SCRAM conversations are answered by the emulation, never matched
+			var mocks []*models.Mock
+			for _, mock := range tcsMocks {
+				if mock != nil && !isScramMock(mock) {
+					mocks = append(mocks, mock)
+				}
+			}
+			tcsMocks = mocks
+		}
+
+		// the extended query requests are matched on their decoded messages first, since the names of the
+		// statements and portals generated by the drivers differ between the record and the test runs
+		if idx := findExtendedQueryMatch(tcsMocks, requestBuffers, preparedStatements, logger); idx != -1 {
+			isDeleted, err := h.DeleteTcsMock(tcsMocks[idx])
+			if err != nil {
+				return false, nil, fmt.Errorf("error while deleting tcs mock: %v", err)
+			}
+			if !isDeleted {
+				continue
+			}
+			return true, tcsMocks[idx].Spec.PostgresResponses, nil
+		}
 
 		for _, mock := range tcsMocks {
 			if mock == nil {
@@ -518,6 +557,198 @@ func matchingReadablePG(requestBuffers [][]byte, h *hooks.Hook) (bool, []models.
 			}
 		}
 
+		// the queries differing from the recorded ones by their literals only are matched on their fingerprint
+		if matcher.For(models.Postgres).Strategy(sqlConfig.Matching) == sqlquery.MatchingFingerprint {
+			if idx := findFingerprintMatch(tcsMocks, requestBuffers, logger); idx != -1 {
+				isDeleted, err := h.DeleteTcsMock(tcsMocks[idx])
+				if err != nil {
+					return false, nil, fmt.Errorf("error while deleting tcs mock: %v", err)
+				}
+				if !isDeleted {
+					continue
+				}
+				return true, tcsMocks[idx].Spec.PostgresResponses, nil
+			}
+		}
+
 		return false, nil, nil
 	}
 }
+
+// findExtendedQueryMatch returns the index of the mock whose requests carry the same extended query messages as
+// the request buffers, or -1 when there is none.
+func findExtendedQueryMatch(tcsMocks []*models.Mock, requestBuffers [][]byte, preparedStatements map[string]string, logger *zap.Logger) int {
+	requests := make([]*models.Backend, len(requestBuffers))
+	extended := false
+	for i, buffer := range requestBuffers {
+		if isStartupPacket(buffer) || len(buffer) < 5 {
+			return -1
+		}
+		request, err := decodeBackendMessages(buffer, logger)
+		if err != nil {
+			logger.Debug("failed to decode the postgres request for matching", zap.Error(err))
+			return -1
+		}
+		requests[i] = request
+		extended = extended || isExtendedQuery(request)
+	}
+	if !extended {
+		return -1
+	}
+
+	recordedStatements := recordedStatementQueries(tcsMocks)
+	for idx, mock := range tcsMocks {
+		if mock == nil || len(mock.Spec.PostgresRequests) != len(requests) {
+			continue
+		}
+		matched := true
+		for i, request := range requests {
+			if !matchExtendedQuery(mock.Spec.PostgresRequests[i], *request, recordedStatements, preparedStatements) {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return idx
+		}
+	}
+	return -1
+}
+
+func isExtendedQuery(request *models.Backend) bool {
+	return len(request.Parses) > 0 || len(request.Binds) > 0 || len(request.Describes) > 0 || len(request.Executes) > 0
+}
+
+// matchExtendedQuery compares the messages of a request with the recorded ones. A statement referenced by a Bind
+// is compared through its query text, looked up in the same request first and then in the statements prepared
+// earlier on the connection (or in any mock for the recorded request).
+func matchExtendedQuery(mockRequest, request models.Backend, recordedStatements, preparedStatements map[string]string) bool {
+	if mockRequest.Payload != "" || len(mockRequest.PacketTypes) != len(request.PacketTypes) {
+		return false
+	}
+	for i := range request.PacketTypes {
+		if mockRequest.PacketTypes[i] != request.PacketTypes[i] {
+			return false
+		}
+	}
+	if len(mockRequest.Parses) != len(request.Parses) || len(mockRequest.Binds) != len(request.Binds) ||
+		len(mockRequest.Executes) != len(request.Executes) || len(mockRequest.Describes) != len(request.Describes) {
+		return false
+	}
+
+	for i, parse := range request.Parses {
+		if mockRequest.Parses[i].Query != parse.Query || !equalOIDs(mockRequest.Parses[i].ParameterOIDs, parse.ParameterOIDs) {
+			return false
+		}
+	}
+	for i, bind := range request.Binds {
+		mockBind := mockRequest.Binds[i]
+		if !equalFormatCodes(mockBind.ParameterFormatCodes, bind.ParameterFormatCodes) ||
+			!equalFormatCodes(mockBind.ResultFormatCodes, bind.ResultFormatCodes) ||
+			len(mockBind.Parameters) != len(bind.Parameters) {
+			return false
+		}
+		for j := range bind.Parameters {
+			if !bytes.Equal(mockBind.Parameters[j], bind.Parameters[j]) {
+				return false
+			}
+		}
+		mockQuery, mockKnown := statementQuery(mockBind.PreparedStatement, mockRequest.Parses, recordedStatements)
+		query, known := statementQuery(bind.PreparedStatement, request.Parses, preparedStatements)
+		if mockKnown && known && mockQuery != query {
+			return false
+		}
+	}
+	for i, describe := range request.Describes {
+		if mockRequest.Describes[i].ObjectType != describe.ObjectType {
+			return false
+		}
+	}
+	for i, execute := range request.Executes {
+		if mockRequest.Executes[i].MaxRows != execute.MaxRows {
+			return false
+		}
+	}
+	return true
+}
+
+// statementQuery returns the query of the named statement, the last Parse of the request having the name wins.
+func statementQuery(name string, parses []pgproto3.Parse, statements map[string]string) (string, bool) {
+	for i := len(parses) - 1; i >= 0; i-- {
+		if parses[i].Name == name {
+			return parses[i].Query, true
+		}
+	}
+	query, ok := statements[name]
+	return query, ok && query != ""
+}
+
+// recordedStatementQueries maps the names of the statements prepared in the mocks to their query. A name prepared
+// with different queries (e.g. on different connections) is ambiguous and maps to an empty query.
+func recordedStatementQueries(tcsMocks []*models.Mock) map[string]string {
+	statements := map[string]string{}
+	for _, mock := range tcsMocks {
+		if mock == nil {
+			continue
+		}
+		for _, request := range mock.Spec.PostgresRequests {
+			for _, parse := range request.Parses {
+				if parse.Name == "" {
+					continue
+				}
+				if query, ok := statements[parse.Name]; ok && query != parse.Query {
+					statements[parse.Name] = ""
+					continue
+				}
+				statements[parse.Name] = parse.Query
+			}
+		}
+	}
+	return statements
+}
+
+// trackPreparedStatements records the statements prepared by the requests sent on a connection, so that the
+// following Binds can be matched through the query of their statement.
+func trackPreparedStatements(requestBuffers [][]byte, preparedStatements map[string]string, logger *zap.Logger) {
+	for _, buffer := range requestBuffers {
+		if isStartupPacket(buffer) || len(buffer) < 5 {
+			continue
+		}
+		request, err := decodeBackendMessages(buffer, logger)
+		if err != nil {
+			continue
+		}
+		for _, parse := range request.Parses {
+			preparedStatements[parse.Name] = parse.Query
+		}
+		for _, closeMsg := range request.Closes {
+			if closeMsg.Object_Type == 'S' {
+				delete(preparedStatements, closeMsg.Name)
+			}
+		}
+	}
+}
+
+func equalOIDs(a, b []uint32) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func equalFormatCodes(a, b []int16) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,269 @@
+// Package protohttp decodes the protobuf bodies of the http calls into their JSON form, through the descriptor sets
+// of the config, so that they are stored, diffed and denoised like the JSON bodies. The original bytes of a decoded
+// body are kept along with it (in the binary field of the request or the response) and are sent as they are on
+// replay, the bodies whose bytes were removed being encoded again from their JSON form.
+//
+// The message of a body is read from the proto or messageType parameter of its content type, e.g.
+// "application/x-protobuf; proto=users.v1.User", or from the protobuf routes of the config.
+package protohttp
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+
+	"go.keploy.io/server/pkg/models"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// contentTypes are the media types of the bodies which may be protobuf messages.
+var contentTypes = map[string]bool{
+	"application/x-protobuf":          true,
+	"application/protobuf":            true,
+	"application/x-google-protobuf":   true,
+	"application/vnd.google.protobuf": true,
+	"application/octet-stream":        true,
+}
+
+type route struct {
+	path     *regexp.Regexp
+	method   string
+	request  string
+	response string
+}
+
+var (
+	mu     sync.RWMutex
+	files  *protoregistry.Files
+	routes []route
+)
+
+// LoadFiles returns the descriptors of the FileDescriptorSets at the given paths, as generated by
+// `protoc --descriptor_set_out=<file> --include_imports`.
+func LoadFiles(paths []string) (*protoregistry.Files, error) {
+	fileSet := &descriptorpb.FileDescriptorSet{}
+	for _, path := range paths {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the descriptor set %s: %v", path, err)
+		}
+		set := &descriptorpb.FileDescriptorSet{}
+		if err := proto.Unmarshal(content, set); err != nil {
+			return nil, fmt.Errorf("failed to parse the descriptor set %s: %v", path, err)
+		}
+		fileSet.File = append(fileSet.File, set.File...)
+	}
+	fileSet.File = uniqueFiles(fileSet.File)
+
+	registry, err := protodesc.NewFiles(fileSet)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build the protobuf descriptors: %v", err)
+	}
+	return registry, nil
+}
+
+// uniqueFiles drops the files included by several descriptor sets, like the well known types.
+func uniqueFiles(files []*descriptorpb.FileDescriptorProto) []*descriptorpb.FileDescriptorProto {
+	seen := map[string]bool{}
+	unique := files[:0]
+	for _, file := range files {
+		if seen[file.GetName()] {
+			continue
+		}
+		seen[file.GetName()] = true
+		unique = append(unique, file)
+	}
+	return unique
+}
+
+// Configure loads the descriptor sets and the routes telling the messages of the bodies. The protobuf bodies are
+// left as they are when no descriptor set is given.
+func Configure(descriptorPaths []string, protobufRoutes []models.ProtobufRoute) error {
+	var (
+		registry *protoregistry.Files
+		compiled []route
+	)
+	if len(descriptorPaths) > 0 {
+		var err error
+		if registry, err = LoadFiles(descriptorPaths); err != nil {
+			return err
+		}
+	}
+	for _, r := range protobufRoutes {
+		path, err := regexp.Compile(r.Path)
+		if err != nil {
+			return fmt.Errorf("invalid path %q of a protobuf route: %v", r.Path, err)
+		}
+		compiled = append(compiled, route{path: path, method: r.Method, request: r.Request, response: r.Response})
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	files, routes = registry, compiled
+	return nil
+}
+
+// Message returns the full name of the message of the body of a call with the content type, or of its response. It
+// is empty when the body isn't a protobuf message, or when neither its content type nor the routes tell which one.
+func Message(contentType, method, path string, response bool) string {
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil || !contentTypes[mediaType] {
+		return ""
+	}
+	mu.RLock()
+	defer mu.RUnlock()
+	if files == nil {
+		return ""
+	}
+	for _, param := range []string{"proto", "messagetype"} {
+		if name := params[param]; name != "" {
+			return name
+		}
+	}
+	for _, r := range routes {
+		if (r.method != "" && !strings.EqualFold(r.method, method)) || !r.path.MatchString(path) {
+			continue
+		}
+		if response {
+			return r.response
+		}
+		return r.request
+	}
+	return ""
+}
+
+func findMessage(name string) (protoreflect.MessageDescriptor, error) {
+	mu.RLock()
+	defer mu.RUnlock()
+	if files == nil {
+		return nil, fmt.Errorf("no descriptor set is loaded to decode the %s message", name)
+	}
+	descriptor, err := files.FindDescriptorByName(protoreflect.FullName(name))
+	if err != nil {
+		return nil, fmt.Errorf("could not find the %s message in the descriptor sets: %v", name, err)
+	}
+	message, ok := descriptor.(protoreflect.MessageDescriptor)
+	if !ok {
+		return nil, fmt.Errorf("%s is not a protobuf message", name)
+	}
+	return message, nil
+}
+
+// Decode returns the indented JSON form of the wire encoded message. The output of protojson is normalised since its
+// whitespaces are randomised on purpose.
+func Decode(body []byte, name string) (string, error) {
+	descriptor, err := findMessage(name)
+	if err != nil {
+		return "", err
+	}
+	message := dynamicpb.NewMessage(descriptor)
+	if err := proto.Unmarshal(body, message); err != nil {
+		return "", fmt.Errorf("could not decode the body as a %s message: %v", name, err)
+	}
+	encoded, err := protojson.Marshal(message)
+	if err != nil {
+		return "", err
+	}
+	var indented bytes.Buffer
+	if err := json.Indent(&indented, encoded, "", "  "); err != nil {
+		return "", err
+	}
+	return indented.String(), nil
+}
+
+// Encode returns the wire encoding of the message stored in its JSON form.
+func Encode(body, name string) ([]byte, error) {
+	descriptor, err := findMessage(name)
+	if err != nil {
+		return nil, err
+	}
+	message := dynamicpb.NewMessage(descriptor)
+	if err := protojson.Unmarshal([]byte(body), message); err != nil {
+		return nil, fmt.Errorf("could not decode the JSON form of the %s message: %v", name, err)
+	}
+	return proto.MarshalOptions{Deterministic: true}.Marshal(message)
+}
+
+// DecodeBody returns the JSON form of the body, along with the base64 encoding of its bytes which is stored beside
+// it. It reports false when the body isn't a protobuf message known to the descriptor sets, the body being then
+// stored as it is.
+func DecodeBody(body []byte, contentType, method, path string, response bool) (string, string, bool) {
+	name := Message(contentType, method, path, response)
+	if name == "" || len(body) == 0 {
+		return "", "", false
+	}
+	decoded, err := Decode(body, name)
+	if err != nil {
+		return "", "", false
+	}
+	return decoded, base64.StdEncoding.EncodeToString(body), true
+}
+
+// EncodeBody returns the bytes of a body stored by DecodeBody: its original bytes, or the encoding of its JSON form
+// when they were removed. It reports false when the body was stored as it is.
+func EncodeBody(body, binary, contentType, method, path string, response bool) ([]byte, bool, error) {
+	if binary != "" {
+		original, err := base64.StdEncoding.DecodeString(binary)
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to decode the original bytes of the body: %v", err)
+		}
+		return original, true, nil
+	}
+	name := Message(contentType, method, path, response)
+	if name == "" || !json.Valid([]byte(body)) {
+		return nil, false, nil
+	}
+	encoded, err := Encode(body, name)
+	if err != nil {
+		return nil, false, err
+	}
+	return encoded, true, nil
+}
+
+// RequestBody returns the bytes of the body of the recorded request, the protobuf bodies being sent as they were
+// recorded.
+func RequestBody(req models.HttpReq) []byte {
+	body, ok, err := EncodeBody(req.Body, req.Binary, header(req.Header, "Content-Type"), string(req.Method), urlPath(req.URL), false)
+	if !ok || err != nil {
+		return []byte(req.Body)
+	}
+	return body
+}
+
+// ResponseBody returns the body of the response to the request in the form it is stored in, the JSON form of the
+// protobuf messages.
+func ResponseBody(body []byte, contentType string, req models.HttpReq) string {
+	if decoded, _, ok := DecodeBody(body, contentType, string(req.Method), urlPath(req.URL), true); ok {
+		return decoded
+	}
+	return string(body)
+}
+
+func header(headers map[string]string, name string) string {
+	for key, value := range headers {
+		if strings.EqualFold(key, name) {
+			return value
+		}
+	}
+	return ""
+}
+
+func urlPath(rawURL string) string {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+	return parsed.Path
+}
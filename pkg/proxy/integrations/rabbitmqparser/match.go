@@ -0,0 +1,87 @@
+package rabbitmqparser
+
+import (
+	"fmt"
+
+	"go.keploy.io/server/pkg/hooks"
+	"go.keploy.io/server/pkg/models"
+)
+
+// match finds the mock of the command and removes it from the mocks of the test. The mocks of the same method,
+// exchange, queue and routing key are candidates, the one having the same arguments is preferred, then the one
+// publishing the same body, then the first one recorded. The consumer tags are generated by the clients, so they
+// are left out. The arguments of the connection methods hold the credentials and the client properties, they are
+// only matched on their method.
+func match(h *hooks.Hook, command models.AMQPCommand) (*models.Mock, bool, error) {
+	for {
+		tcsMocks, err := h.GetTcsMocks()
+		if err != nil {
+			return nil, false, fmt.Errorf("error while getting tcs mocks %v", err)
+		}
+
+		var (
+			bestMatch *models.Mock
+			bestScore = -1
+		)
+		for _, mock := range tcsMocks {
+			if mock == nil || mock.Kind != models.AMQP || len(mock.Spec.AMQPRequests) != 1 {
+				continue
+			}
+			recorded := mock.Spec.AMQPRequests[0]
+			if recorded.Method != command.Method || recorded.Exchange != command.Exchange ||
+				recorded.Queue != command.Queue || recorded.RoutingKey != command.RoutingKey {
+				continue
+			}
+			score := 0
+			if recorded.Arguments == command.Arguments {
+				score += 2
+			}
+			if recorded.Content != nil && command.Content != nil && recorded.Content.Body == command.Content.Body {
+				score++
+			}
+			if score > bestScore {
+				bestMatch, bestScore = mock, score
+			}
+		}
+		if bestMatch == nil {
+			return nil, false, nil
+		}
+
+		isDeleted, err := h.DeleteTcsMock(bestMatch)
+		if err != nil {
+			return nil, false, fmt.Errorf("error while deleting tcs mock: %v", err)
+		}
+		if !isDeleted {
+			continue
+		}
+		return bestMatch, true, nil
+	}
+}
+
+// matchDeliveries returns the messages recorded for a consumer, in the order they were delivered, and removes
+// their mocks from the mocks of the test.
+func matchDeliveries(h *hooks.Hook, consumerTag string) ([]models.AMQPCommand, error) {
+	tcsMocks, err := h.GetTcsMocks()
+	if err != nil {
+		return nil, fmt.Errorf("error while getting tcs mocks %v", err)
+	}
+
+	var deliveries []models.AMQPCommand
+	for _, mock := range tcsMocks {
+		if mock == nil || mock.Kind != models.AMQP || len(mock.Spec.AMQPRequests) != 0 || len(mock.Spec.AMQPResponses) != 1 {
+			continue
+		}
+		delivery := mock.Spec.AMQPResponses[0]
+		if keyOf(delivery) != basicDeliver || delivery.ConsumerTag != consumerTag {
+			continue
+		}
+		isDeleted, err := h.DeleteTcsMock(mock)
+		if err != nil {
+			return nil, fmt.Errorf("error while deleting tcs mock: %v", err)
+		}
+		if isDeleted {
+			deliveries = append(deliveries, delivery)
+		}
+	}
+	return deliveries, nil
+}
@@ -0,0 +1,583 @@
+package rabbitmqparser
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"unicode"
+
+	"go.keploy.io/server/pkg/models"
+)
+
+const (
+	frameMethod    = 1
+	frameHeader    = 2
+	frameBody      = 3
+	frameHeartbeat = 8
+
+	frameEnd          = 0xce
+	frameHeaderLength = 7
+	// the frame max proposed by RabbitMQ, used until the client sends its connection.tune-ok
+	defaultFrameMax = 131072
+)
+
+// protocolHeader is sent by the client to open an AMQP 0.9.1 connection.
+var protocolHeader = []byte("AMQP\x00\x00\x09\x01")
+
+const protocolHeaderMethod = "protocol.header"
+
+// protocolHeaderCommand is stored as the request of the mock holding the connection.start of the broker.
+func protocolHeaderCommand() models.AMQPCommand {
+	return models.AMQPCommand{
+		Method:    protocolHeaderMethod,
+		Arguments: base64.StdEncoding.EncodeToString(protocolHeader),
+	}
+}
+
+type frame struct {
+	kind    byte
+	channel uint16
+	payload []byte
+}
+
+func methodKey(classID, methodID uint16) uint32 {
+	return uint32(classID)<<16 | uint32(methodID)
+}
+
+var (
+	connectionStart        = methodKey(10, 10)
+	connectionStartOk      = methodKey(10, 11)
+	connectionSecure       = methodKey(10, 20)
+	connectionSecureOk     = methodKey(10, 21)
+	connectionTune         = methodKey(10, 30)
+	connectionTuneOk       = methodKey(10, 31)
+	connectionOpen         = methodKey(10, 40)
+	connectionOpenOk       = methodKey(10, 41)
+	connectionClose        = methodKey(10, 50)
+	connectionCloseOk      = methodKey(10, 51)
+	connectionBlocked      = methodKey(10, 60)
+	connectionUnblocked    = methodKey(10, 61)
+	connectionUpdateSecret = methodKey(10, 70)
+	connectionUpdateOk     = methodKey(10, 71)
+	channelOpen            = methodKey(20, 10)
+	channelOpenOk          = methodKey(20, 11)
+	channelFlow            = methodKey(20, 20)
+	channelFlowOk          = methodKey(20, 21)
+	channelClose           = methodKey(20, 40)
+	channelCloseOk         = methodKey(20, 41)
+	exchangeDeclare        = methodKey(40, 10)
+	exchangeDeclareOk      = methodKey(40, 11)
+	exchangeDelete         = methodKey(40, 20)
+	exchangeDeleteOk       = methodKey(40, 21)
+	exchangeBind           = methodKey(40, 30)
+	exchangeBindOk         = methodKey(40, 31)
+	exchangeUnbind         = methodKey(40, 40)
+	exchangeUnbindOk       = methodKey(40, 51)
+	queueDeclare           = methodKey(50, 10)
+	queueDeclareOk         = methodKey(50, 11)
+	queueBind              = methodKey(50, 20)
+	queueBindOk            = methodKey(50, 21)
+	queuePurge             = methodKey(50, 30)
+	queuePurgeOk           = methodKey(50, 31)
+	queueDelete            = methodKey(50, 40)
+	queueDeleteOk          = methodKey(50, 41)
+	queueUnbind            = methodKey(50, 50)
+	queueUnbindOk          = methodKey(50, 51)
+	basicQos               = methodKey(60, 10)
+	basicQosOk             = methodKey(60, 11)
+	basicConsume           = methodKey(60, 20)
+	basicConsumeOk         = methodKey(60, 21)
+	basicCancel            = methodKey(60, 30)
+	basicCancelOk          = methodKey(60, 31)
+	basicPublish           = methodKey(60, 40)
+	basicReturn            = methodKey(60, 50)
+	basicDeliver           = methodKey(60, 60)
+	basicGet               = methodKey(60, 70)
+	basicGetOk             = methodKey(60, 71)
+	basicGetEmpty          = methodKey(60, 72)
+	basicAck               = methodKey(60, 80)
+	basicReject            = methodKey(60, 90)
+	basicRecoverAsync      = methodKey(60, 100)
+	basicRecover           = methodKey(60, 110)
+	basicRecoverOk         = methodKey(60, 111)
+	basicNack              = methodKey(60, 120)
+	confirmSelect          = methodKey(85, 10)
+	confirmSelectOk        = methodKey(85, 11)
+	txSelect               = methodKey(90, 10)
+	txSelectOk             = methodKey(90, 11)
+	txCommit               = methodKey(90, 20)
+	txCommitOk             = methodKey(90, 21)
+	txRollback             = methodKey(90, 30)
+	txRollbackOk           = methodKey(90, 31)
+)
+
+var methodNames = map[uint32]string{
+	connectionStart:        "connection.start",
+	connectionStartOk:      "connection.start-ok",
+	connectionSecure:       "connection.secure",
+	connectionSecureOk:     "connection.secure-ok",
+	connectionTune:         "connection.tune",
+	connectionTuneOk:       "connection.tune-ok",
+	connectionOpen:         "connection.open",
+	connectionOpenOk:       "connection.open-ok",
+	connectionClose:        "connection.close",
+	connectionCloseOk:      "connection.close-ok",
+	connectionBlocked:      "connection.blocked",
+	connectionUnblocked:    "connection.unblocked",
+	connectionUpdateSecret: "connection.update-secret",
+	connectionUpdateOk:     "connection.update-secret-ok",
+	channelOpen:            "channel.open",
+	channelOpenOk:          "channel.open-ok",
+	channelFlow:            "channel.flow",
+	channelFlowOk:          "channel.flow-ok",
+	channelClose:           "channel.close",
+	channelCloseOk:         "channel.close-ok",
+	exchangeDeclare:        "exchange.declare",
+	exchangeDeclareOk:      "exchange.declare-ok",
+	exchangeDelete:         "exchange.delete",
+	exchangeDeleteOk:       "exchange.delete-ok",
+	exchangeBind:           "exchange.bind",
+	exchangeBindOk:         "exchange.bind-ok",
+	exchangeUnbind:         "exchange.unbind",
+	exchangeUnbindOk:       "exchange.unbind-ok",
+	queueDeclare:           "queue.declare",
+	queueDeclareOk:         "queue.declare-ok",
+	queueBind:              "queue.bind",
+	queueBindOk:            "queue.bind-ok",
+	queuePurge:             "queue.purge",
+	queuePurgeOk:           "queue.purge-ok",
+	queueDelete:            "queue.delete",
+	queueDeleteOk:          "queue.delete-ok",
+	queueUnbind:            "queue.unbind",
+	queueUnbindOk:          "queue.unbind-ok",
+	basicQos:               "basic.qos",
+	basicQosOk:             "basic.qos-ok",
+	basicConsume:           "basic.consume",
+	basicConsumeOk:         "basic.consume-ok",
+	basicCancel:            "basic.cancel",
+	basicCancelOk:          "basic.cancel-ok",
+	basicPublish:           "basic.publish",
+	basicReturn:            "basic.return",
+	basicDeliver:           "basic.deliver",
+	basicGet:               "basic.get",
+	basicGetOk:             "basic.get-ok",
+	basicGetEmpty:          "basic.get-empty",
+	basicAck:               "basic.ack",
+	basicReject:            "basic.reject",
+	basicRecoverAsync:      "basic.recover-async",
+	basicRecover:           "basic.recover",
+	basicRecoverOk:         "basic.recover-ok",
+	basicNack:              "basic.nack",
+	confirmSelect:          "confirm.select",
+	confirmSelectOk:        "confirm.select-ok",
+	txSelect:               "tx.select",
+	txSelectOk:             "tx.select-ok",
+	txCommit:               "tx.commit",
+	txCommitOk:             "tx.commit-ok",
+	txRollback:             "tx.rollback",
+	txRollbackOk:           "tx.rollback-ok",
+}
+
+// replies holds the methods the broker replies with to the synchronous methods of the client. Any of them can
+// also be answered with a channel.close or a connection.close when it fails.
+var replies = map[uint32][]uint32{
+	methodKey(0, 0):        {connectionStart},
+	connectionStartOk:      {connectionSecure, connectionTune},
+	connectionSecureOk:     {connectionSecure, connectionTune},
+	connectionOpen:         {connectionOpenOk},
+	connectionClose:        {connectionCloseOk},
+	connectionUpdateSecret: {connectionUpdateOk},
+	channelOpen:            {channelOpenOk},
+	channelFlow:            {channelFlowOk},
+	channelClose:           {channelCloseOk},
+	exchangeDeclare:        {exchangeDeclareOk},
+	exchangeDelete:         {exchangeDeleteOk},
+	exchangeBind:           {exchangeBindOk},
+	exchangeUnbind:         {exchangeUnbindOk},
+	queueDeclare:           {queueDeclareOk},
+	queueBind:              {queueBindOk},
+	queuePurge:             {queuePurgeOk},
+	queueDelete:            {queueDeleteOk},
+	queueUnbind:            {queueUnbindOk},
+	basicQos:               {basicQosOk},
+	basicConsume:           {basicConsumeOk},
+	basicCancel:            {basicCancelOk},
+	basicGet:               {basicGetOk, basicGetEmpty},
+	basicRecover:           {basicRecoverOk},
+	confirmSelect:          {confirmSelectOk},
+	txSelect:               {txSelectOk},
+	txCommit:               {txCommitOk},
+	txRollback:             {txRollbackOk},
+}
+
+// contentMethods are followed by a content header and body frames.
+var contentMethods = map[uint32]bool{
+	basicPublish: true,
+	basicReturn:  true,
+	basicDeliver: true,
+	basicGetOk:   true,
+}
+
+func keyOf(command models.AMQPCommand) uint32 {
+	return methodKey(command.ClassID, command.MethodID)
+}
+
+// expectsReply reports whether the broker replies to the command. The synchronous methods sent with the no-wait
+// bit set don't get a reply either, they are told apart from the next command of the channel.
+func expectsReply(command models.AMQPCommand) bool {
+	_, ok := replies[keyOf(command)]
+	return ok
+}
+
+// isReply reports whether the response ends the synchronous command.
+func isReply(request, response models.AMQPCommand) bool {
+	key := keyOf(response)
+	if key == channelClose || key == connectionClose {
+		return keyOf(request) != key
+	}
+	for _, reply := range replies[keyOf(request)] {
+		if reply == key {
+			return true
+		}
+	}
+	return false
+}
+
+// splitFrames splits the complete frames from the buffer, the bytes of an incomplete frame are returned as the rest.
+func splitFrames(buffer []byte) ([]frame, []byte, error) {
+	var frames []frame
+	for len(buffer) >= frameHeaderLength {
+		size := int(binary.BigEndian.Uint32(buffer[3:7]))
+		if len(buffer) < frameHeaderLength+size+1 {
+			break
+		}
+		if buffer[frameHeaderLength+size] != frameEnd {
+			return nil, nil, fmt.Errorf("invalid frame end 0x%x for a frame of type %d", buffer[frameHeaderLength+size], buffer[0])
+		}
+		frames = append(frames, frame{
+			kind:    buffer[0],
+			channel: binary.BigEndian.Uint16(buffer[1:3]),
+			payload: buffer[frameHeaderLength : frameHeaderLength+size],
+		})
+		buffer = buffer[frameHeaderLength+size+1:]
+	}
+	return frames, buffer, nil
+}
+
+func encodeFrame(kind byte, channel uint16, payload []byte) []byte {
+	buf := make([]byte, frameHeaderLength, frameHeaderLength+len(payload)+1)
+	buf[0] = kind
+	binary.BigEndian.PutUint16(buf[1:3], channel)
+	binary.BigEndian.PutUint32(buf[3:7], uint32(len(payload)))
+	buf = append(buf, payload...)
+	return append(buf, frameEnd)
+}
+
+type partialCommand struct {
+	command    models.AMQPCommand
+	properties []byte
+	bodySize   uint64
+	body       []byte
+	headerRead bool
+}
+
+// assembler builds the commands from the frames sent in one direction of a connection. The content bearing methods
+// are only complete once their header and body frames have been read.
+type assembler struct {
+	partial map[uint16]*partialCommand
+}
+
+func newAssembler() *assembler {
+	return &assembler{partial: map[uint16]*partialCommand{}}
+}
+
+// add returns the command completed by the frame, if any.
+func (a *assembler) add(f frame) (*models.AMQPCommand, error) {
+	switch f.kind {
+	case frameMethod:
+		command, err := decodeMethod(f)
+		if err != nil {
+			return nil, err
+		}
+		if !contentMethods[keyOf(command)] {
+			return &command, nil
+		}
+		a.partial[f.channel] = &partialCommand{command: command}
+		return nil, nil
+	case frameHeader:
+		p, ok := a.partial[f.channel]
+		if !ok || p.headerRead {
+			return nil, fmt.Errorf("unexpected content header on channel %d", f.channel)
+		}
+		if len(f.payload) < 14 {
+			return nil, errors.New("content header too short")
+		}
+		p.bodySize = binary.BigEndian.Uint64(f.payload[4:12])
+		p.properties = f.payload[12:]
+		p.headerRead = true
+	case frameBody:
+		p, ok := a.partial[f.channel]
+		if !ok || !p.headerRead {
+			return nil, fmt.Errorf("unexpected content body on channel %d", f.channel)
+		}
+		p.body = append(p.body, f.payload...)
+	default:
+		return nil, nil
+	}
+
+	p := a.partial[f.channel]
+	if !p.headerRead || uint64(len(p.body)) < p.bodySize {
+		return nil, nil
+	}
+	delete(a.partial, f.channel)
+	command := p.command
+	command.Content = &models.AMQPContent{
+		Properties: base64.StdEncoding.EncodeToString(p.properties),
+		Body:       encodeBody(p.body),
+	}
+	return &command, nil
+}
+
+func decodeMethod(f frame) (models.AMQPCommand, error) {
+	if len(f.payload) < 4 {
+		return models.AMQPCommand{}, errors.New("method frame too short")
+	}
+	command := models.AMQPCommand{
+		Channel:   f.channel,
+		ClassID:   binary.BigEndian.Uint16(f.payload[0:2]),
+		MethodID:  binary.BigEndian.Uint16(f.payload[2:4]),
+		Arguments: base64.StdEncoding.EncodeToString(f.payload[4:]),
+	}
+	key := keyOf(command)
+	command.Method = methodNames[key]
+	if command.Method == "" {
+		command.Method = fmt.Sprintf("%d.%d", command.ClassID, command.MethodID)
+	}
+
+	r := &reader{buf: f.payload[4:]}
+	switch key {
+	case exchangeDeclare, exchangeDelete:
+		r.short()
+		command.Exchange = r.shortstr()
+	case exchangeBind, exchangeUnbind:
+		r.short()
+		command.Exchange = r.shortstr()
+		r.shortstr()
+		command.RoutingKey = r.shortstr()
+	case queueDeclare, queuePurge, queueDelete, basicGet:
+		r.short()
+		command.Queue = r.shortstr()
+	case queueDeclareOk:
+		command.Queue = r.shortstr()
+	case queueBind, queueUnbind:
+		r.short()
+		command.Queue = r.shortstr()
+		command.Exchange = r.shortstr()
+		command.RoutingKey = r.shortstr()
+	case basicConsume:
+		r.short()
+		command.Queue = r.shortstr()
+		command.ConsumerTag = r.shortstr()
+	case basicConsumeOk, basicCancel, basicCancelOk:
+		command.ConsumerTag = r.shortstr()
+	case basicPublish:
+		r.short()
+		command.Exchange = r.shortstr()
+		command.RoutingKey = r.shortstr()
+	case basicReturn:
+		r.short()
+		r.shortstr()
+		command.Exchange = r.shortstr()
+		command.RoutingKey = r.shortstr()
+	case basicDeliver:
+		command.ConsumerTag = r.shortstr()
+		command.DeliveryTag = r.longlong()
+		r.octet()
+		command.Exchange = r.shortstr()
+		command.RoutingKey = r.shortstr()
+	case basicGetOk:
+		command.DeliveryTag = r.longlong()
+		r.octet()
+		command.Exchange = r.shortstr()
+		command.RoutingKey = r.shortstr()
+	case basicAck, basicReject, basicNack:
+		command.DeliveryTag = r.longlong()
+	}
+	if r.err != nil {
+		return models.AMQPCommand{}, fmt.Errorf("failed to decode the arguments of %s: %v", command.Method, r.err)
+	}
+	return command, nil
+}
+
+// encodeCommand returns the frames of the command on the given channel, the body is split into frames fitting
+// the negotiated frame max.
+func encodeCommand(command models.AMQPCommand, channel uint16, frameMax uint32) ([]byte, error) {
+	arguments, err := base64.StdEncoding.DecodeString(command.Arguments)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode the arguments of %s: %v", command.Method, err)
+	}
+	payload := make([]byte, 4, 4+len(arguments))
+	binary.BigEndian.PutUint16(payload[0:2], command.ClassID)
+	binary.BigEndian.PutUint16(payload[2:4], command.MethodID)
+	payload = append(payload, arguments...)
+
+	buf := new(bytes.Buffer)
+	buf.Write(encodeFrame(frameMethod, channel, payload))
+	if command.Content == nil {
+		return buf.Bytes(), nil
+	}
+
+	properties, err := base64.StdEncoding.DecodeString(command.Content.Properties)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode the properties of %s: %v", command.Method, err)
+	}
+	body, err := decodeBody(command.Content.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode the body of %s: %v", command.Method, err)
+	}
+	header := make([]byte, 12, 12+len(properties))
+	binary.BigEndian.PutUint16(header[0:2], command.ClassID)
+	binary.BigEndian.PutUint64(header[4:12], uint64(len(body)))
+	header = append(header, properties...)
+	buf.Write(encodeFrame(frameHeader, channel, header))
+
+	// the frame max includes the header and the end of the frames
+	maxBody := len(body)
+	if frameMax > frameHeaderLength+1 {
+		maxBody = int(frameMax) - frameHeaderLength - 1
+	}
+	for len(body) > 0 {
+		chunk := body
+		if len(chunk) > maxBody {
+			chunk = chunk[:maxBody]
+		}
+		buf.Write(encodeFrame(frameBody, channel, chunk))
+		body = body[len(chunk):]
+	}
+	return buf.Bytes(), nil
+}
+
+// frameMaxOf returns the frame max accepted by the client in its connection.tune-ok.
+func frameMaxOf(command models.AMQPCommand) (uint32, error) {
+	arguments, err := base64.StdEncoding.DecodeString(command.Arguments)
+	if err != nil {
+		return 0, err
+	}
+	r := &reader{buf: arguments}
+	r.short()
+	frameMax := r.long()
+	return frameMax, r.err
+}
+
+func encodeHeartbeat() []byte {
+	return encodeFrame(frameHeartbeat, 0, nil)
+}
+
+// encodeAck returns the basic.ack confirming a message published on a channel in confirm mode.
+func encodeAck(channel uint16, deliveryTag uint64) []byte {
+	payload := make([]byte, 13)
+	binary.BigEndian.PutUint16(payload[0:2], 60)
+	binary.BigEndian.PutUint16(payload[2:4], 80)
+	binary.BigEndian.PutUint64(payload[4:12], deliveryTag)
+	return encodeFrame(frameMethod, channel, payload)
+}
+
+// withConsumerTag replaces the consumer tag starting the arguments of the basic.consume-ok, basic.cancel-ok and
+// basic.deliver methods.
+func withConsumerTag(command models.AMQPCommand, tag string) (models.AMQPCommand, error) {
+	arguments, err := base64.StdEncoding.DecodeString(command.Arguments)
+	if err != nil {
+		return command, err
+	}
+	if len(arguments) < 1 || len(arguments) < 1+int(arguments[0]) || len(tag) > 255 {
+		return command, errors.New("invalid consumer tag")
+	}
+	replaced := append([]byte{byte(len(tag))}, tag...)
+	replaced = append(replaced, arguments[1+int(arguments[0]):]...)
+	command.Arguments = base64.StdEncoding.EncodeToString(replaced)
+	command.ConsumerTag = tag
+	return command, nil
+}
+
+func encodeBody(body []byte) models.OutputBinary {
+	if isAsciiPrintable(string(body)) {
+		return models.OutputBinary{Type: models.String, Data: string(body)}
+	}
+	return models.OutputBinary{Type: "binary", Data: base64.StdEncoding.EncodeToString(body)}
+}
+
+func decodeBody(body models.OutputBinary) ([]byte, error) {
+	if body.Type == "binary" {
+		return base64.StdEncoding.DecodeString(body.Data)
+	}
+	return []byte(body.Data), nil
+}
+
+// checks if s is ascii and printable, aka doesn't include tab, backspace, etc.
+func isAsciiPrintable(s string) bool {
+	for _, r := range s {
+		if r > unicode.MaxASCII || (!unicode.IsPrint(r) && r != '\r' && r != '\n') {
+			return false
+		}
+	}
+	return true
+}
+
+// reader reads the fields of the method arguments, the first error is kept and ends the reading.
+type reader struct {
+	buf []byte
+	off int
+	err error
+}
+
+func (r *reader) next(n int) []byte {
+	if r.err != nil {
+		return nil
+	}
+	if len(r.buf) < r.off+n {
+		r.err = errors.New("arguments too short")
+		return nil
+	}
+	b := r.buf[r.off : r.off+n]
+	r.off += n
+	return b
+}
+
+func (r *reader) octet() byte {
+	b := r.next(1)
+	if b == nil {
+		return 0
+	}
+	return b[0]
+}
+
+func (r *reader) short() uint16 {
+	b := r.next(2)
+	if b == nil {
+		return 0
+	}
+	return binary.BigEndian.Uint16(b)
+}
+
+func (r *reader) long() uint32 {
+	b := r.next(4)
+	if b == nil {
+		return 0
+	}
+	return binary.BigEndian.Uint32(b)
+}
+
+func (r *reader) longlong() uint64 {
+	b := r.next(8)
+	if b == nil {
+		return 0
+	}
+	return binary.BigEndian.Uint64(b)
+}
+
+func (r *reader) shortstr() string {
+	length := int(r.octet())
+	return string(r.next(length))
+}
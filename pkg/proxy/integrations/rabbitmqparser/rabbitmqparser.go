@@ -0,0 +1,414 @@
+package rabbitmqparser
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"time"
+
+	"go.keploy.io/server/pkg"
+	"go.keploy.io/server/pkg/hooks"
+	"go.keploy.io/server/pkg/metrics"
+	"go.keploy.io/server/pkg/models"
+	"go.keploy.io/server/pkg/proxy/util"
+	"go.keploy.io/server/utils"
+	"go.uber.org/zap"
+)
+
+var errNoMock = errors.New("no amqp mock matched the synchronous command of the client")
+
+type RabbitMQParser struct {
+	logger *zap.Logger
+	hooks  *hooks.Hook
+}
+
+func NewRabbitMQParser(logger *zap.Logger, h *hooks.Hook) *RabbitMQParser {
+	return &RabbitMQParser{
+		logger: logger,
+		hooks:  h,
+	}
+}
+
+// OutgoingType determines if the outgoing network call is AMQP 0.9.1 by checking the protocol header sent by the
+// client to open the connection.
+func (r *RabbitMQParser) OutgoingType(buffer []byte) bool {
+	return bytes.HasPrefix(buffer, protocolHeader)
+}
+
+func (r *RabbitMQParser) ProcessOutgoing(requestBuffer []byte, clientConn, destConn net.Conn, ctx context.Context) {
+	switch models.GetMode() {
+	case models.MODE_RECORD:
+		encodeRabbitMQOutgoing(requestBuffer, clientConn, destConn, r.hooks, r.logger, ctx)
+	case models.MODE_TEST:
+		decodeRabbitMQOutgoing(requestBuffer, clientConn, destConn, r.hooks, r.logger)
+	default:
+		r.logger.Info("Invalid mode detected while intercepting outgoing amqp call", zap.Any("mode", models.GetMode()))
+	}
+}
+
+type pendingCommand struct {
+	request   models.AMQPCommand
+	responses []models.AMQPCommand
+	timestamp time.Time
+}
+
+// encodeRabbitMQOutgoing forwards the traffic between the client and the broker and records a mock for every
+// command of the client along with the replies of the broker on the same channel. The messages delivered to the
+// consumers are pushed by the broker at any time, each of them is recorded in a mock without request.
+func encodeRabbitMQOutgoing(requestBuffer []byte, clientConn, destConn net.Conn, h *hooks.Hook, logger *zap.Logger, ctx context.Context) error {
+	_, err := destConn.Write(requestBuffer)
+	if err != nil {
+		logger.Error("failed to write request message to the destination server", zap.Error(err))
+		return err
+	}
+
+	clientBufferChannel := make(chan []byte)
+	destBufferChannel := make(chan []byte)
+	errChannel := make(chan error)
+	// read requests from client
+	go func() {
+		// Recover from panic and gracefully shutdown
+		defer h.Recover(pkg.GenerateRandomID())
+		defer utils.HandlePanic()
+		readBuffConn(clientConn, clientBufferChannel, errChannel, logger, h)
+	}()
+	// read responses from destination
+	go func() {
+		// Recover from panic and gracefully shutdown
+		defer h.Recover(pkg.GenerateRandomID())
+		defer utils.HandlePanic()
+		readBuffConn(destConn, destBufferChannel, errChannel, logger, h)
+	}()
+
+	var (
+		requests        = requestBuffer[len(protocolHeader):]
+		responses       []byte
+		clientAssembler = newAssembler()
+		serverAssembler = newAssembler()
+		// pending holds the synchronous command of each channel waiting for the reply of the broker
+		pending = map[uint16]*pendingCommand{
+			0: {request: protocolHeaderCommand(), timestamp: time.Now()},
+		}
+	)
+	appendMock := func(requests, responses []models.AMQPCommand, reqTimestamp time.Time) {
+		h.AppendMocks(&models.Mock{
+			Version: models.GetVersion(),
+			Name:    "mocks",
+			Kind:    models.AMQP,
+			Spec: models.MockSpec{
+				AMQPRequests:     requests,
+				AMQPResponses:    responses,
+				ReqTimestampMock: reqTimestamp,
+				ResTimestampMock: time.Now(),
+			},
+		}, ctx)
+	}
+	flush := func(channel uint16) {
+		if p, ok := pending[channel]; ok {
+			appendMock([]models.AMQPCommand{p.request}, p.responses, p.timestamp)
+			delete(pending, channel)
+		}
+	}
+	// persist the commands still waiting for a reply when the connection ends
+	defer func() {
+		for channel := range pending {
+			flush(channel)
+		}
+	}()
+
+	for {
+		select {
+		case buffer := <-clientBufferChannel:
+			_, err := destConn.Write(buffer)
+			if err != nil {
+				logger.Error("failed to write request message to the destination server", zap.Error(err))
+				return err
+			}
+			frames, rest, err := splitFrames(append(requests, buffer...))
+			if err != nil {
+				logger.Error("failed to split the amqp frames of the client", zap.Error(err))
+				return err
+			}
+			requests = rest
+			for _, f := range frames {
+				if f.kind == frameHeartbeat {
+					continue
+				}
+				command, err := clientAssembler.add(f)
+				if err != nil {
+					logger.Error("failed to decode the amqp frame of the client", zap.Error(err))
+					return err
+				}
+				if command == nil {
+					continue
+				}
+				// a synchronous command sent with the no-wait bit doesn't get a reply, it ends with the next command
+				flush(command.Channel)
+				if !expectsReply(*command) {
+					appendMock([]models.AMQPCommand{*command}, nil, time.Now())
+					continue
+				}
+				pending[command.Channel] = &pendingCommand{request: *command, timestamp: time.Now()}
+			}
+		case buffer := <-destBufferChannel:
+			_, err := clientConn.Write(buffer)
+			if err != nil {
+				logger.Error("failed to write response to the client", zap.Error(err))
+				return err
+			}
+			frames, rest, err := splitFrames(append(responses, buffer...))
+			if err != nil {
+				logger.Error("failed to split the amqp frames of the broker", zap.Error(err))
+				return err
+			}
+			responses = rest
+			for _, f := range frames {
+				if f.kind == frameHeartbeat {
+					continue
+				}
+				command, err := serverAssembler.add(f)
+				if err != nil {
+					logger.Error("failed to decode the amqp frame of the broker", zap.Error(err))
+					return err
+				}
+				if command == nil {
+					continue
+				}
+				p, ok := pending[command.Channel]
+				if keyOf(*command) == basicDeliver || !ok {
+					// the deliveries, the confirms and the other methods pushed by the broker
+					appendMock(nil, []models.AMQPCommand{*command}, time.Now())
+					continue
+				}
+				p.responses = append(p.responses, *command)
+				if isReply(p.request, *command) {
+					flush(command.Channel)
+				}
+			}
+		case err := <-errChannel:
+			return err
+		}
+	}
+}
+
+func readBuffConn(conn net.Conn, bufferChannel chan []byte, errChannel chan error, logger *zap.Logger, h *hooks.Hook) error {
+	for {
+		buffer, err := util.ReadBytes(conn)
+		if err != nil {
+			if !h.IsUsrAppTerminateInitiated() {
+				if err == io.EOF {
+					logger.Debug("EOF error received from client. Closing connection in amqp !!")
+				} else if !strings.Contains(err.Error(), "use of closed network connection") {
+					logger.Error("failed to read the packet message in proxy for amqp dependency", zap.Error(err))
+				}
+				errChannel <- err
+			}
+			return err
+		}
+		bufferChannel <- buffer
+	}
+}
+
+// decodeRabbitMQOutgoing plays the broker for the client. Every command is answered with the replies of its mock
+// on the channel of the command, the consumers get the messages recorded for them right after their
+// basic.consume-ok and the messages published in confirm mode are acknowledged by the proxy.
+func decodeRabbitMQOutgoing(requestBuffer []byte, clientConn, destConn net.Conn, h *hooks.Hook, logger *zap.Logger) error {
+	mock, matched, err := match(h, protocolHeaderCommand())
+	if err != nil {
+		logger.Error("failed to match the amqp connection with the mocks", zap.Error(err))
+		return err
+	}
+	if !matched {
+		logger.Debug("no mock found for the amqp connection, forwarding it to the broker")
+		return forward(requestBuffer, clientConn, destConn, h)
+	}
+
+	var (
+		requests  = requestBuffer[len(protocolHeader):]
+		assembler = newAssembler()
+		frameMax  = uint32(defaultFrameMax)
+		// confirms holds the number of messages published on the channels in confirm mode
+		confirms = map[uint16]uint64{}
+	)
+	write := func(command models.AMQPCommand, channel uint16) error {
+		encoded, err := encodeCommand(command, channel, frameMax)
+		if err != nil {
+			logger.Error("failed to encode the amqp command of the mock", zap.Error(err), zap.String("method", command.Method))
+			return err
+		}
+		_, err = clientConn.Write(encoded)
+		if err != nil {
+			logger.Error("failed to write the amqp command to the client application", zap.Error(err))
+			return err
+		}
+		return nil
+	}
+	for _, response := range mock.Spec.AMQPResponses {
+		if err := write(response, 0); err != nil {
+			return err
+		}
+	}
+
+	for {
+		frames, rest, err := splitFrames(requests)
+		if err != nil {
+			logger.Error("failed to split the amqp frames of the client", zap.Error(err))
+			return err
+		}
+		if len(frames) == 0 {
+			buffer, err := util.ReadBytes(clientConn)
+			if len(buffer) == 0 && err != nil {
+				if err == io.EOF {
+					logger.Debug("EOF error received from client. Closing connection in amqp !!")
+					return nil
+				}
+				logger.Error("failed to read the request message in proxy for amqp dependency", zap.Error(err))
+				return err
+			}
+			requests = append(rest, buffer...)
+			continue
+		}
+		requests = rest
+
+		for _, f := range frames {
+			if f.kind == frameHeartbeat {
+				if _, err := clientConn.Write(encodeHeartbeat()); err != nil {
+					logger.Error("failed to write the heartbeat to the client application", zap.Error(err))
+					return err
+				}
+				continue
+			}
+			command, err := assembler.add(f)
+			if err != nil {
+				logger.Error("failed to decode the amqp frame of the client", zap.Error(err))
+				return err
+			}
+			if command == nil {
+				continue
+			}
+
+			key := keyOf(*command)
+			if key == connectionTuneOk {
+				if negotiated, err := frameMaxOf(*command); err == nil && negotiated != 0 {
+					frameMax = negotiated
+				}
+			}
+
+			mock, matched, err := match(h, *command)
+			if err != nil {
+				logger.Error("failed to match the amqp command with the mocks", zap.Error(err))
+				return err
+			}
+			if !matched {
+				switch {
+				case key == connectionClose || key == channelClose:
+					// the client is closing anyway, reply as the broker would
+					err = write(models.AMQPCommand{Method: methodNames[key+1], ClassID: command.ClassID, MethodID: command.MethodID + 1}, command.Channel)
+					if err != nil {
+						return err
+					}
+				case expectsReply(*command):
+					metrics.MatchFailures.Inc("rabbitmq")
+					logger.Error("no mock matched the amqp command", zap.String("method", command.Method), zap.String("exchange", command.Exchange),
+						zap.String("queue", command.Queue), zap.String("routing key", command.RoutingKey))
+					return errNoMock
+				default:
+					logger.Debug("no mock matched the amqp command", zap.String("method", command.Method))
+				}
+			} else {
+				for _, response := range mock.Spec.AMQPResponses {
+					responseKey := keyOf(response)
+					if (responseKey == basicConsumeOk || responseKey == basicCancelOk) && command.ConsumerTag != "" {
+						response, err = withConsumerTag(response, command.ConsumerTag)
+						if err != nil {
+							logger.Error("failed to set the consumer tag of the amqp response", zap.Error(err))
+							return err
+						}
+					}
+					if err := write(response, command.Channel); err != nil {
+						return err
+					}
+
+					switch responseKey {
+					case basicConsumeOk:
+						if err := deliver(h, response.ConsumerTag, mockConsumerTag(mock), command.Channel, write); err != nil {
+							logger.Error("failed to deliver the recorded messages to the consumer", zap.Error(err))
+							return err
+						}
+					case confirmSelectOk:
+						confirms[command.Channel] = 0
+					}
+				}
+				if key == confirmSelect && len(mock.Spec.AMQPResponses) == 0 {
+					// confirm mode selected with the no-wait bit
+					confirms[command.Channel] = 0
+				}
+			}
+
+			if count, ok := confirms[command.Channel]; ok && key == basicPublish {
+				confirms[command.Channel] = count + 1
+				if _, err := clientConn.Write(encodeAck(command.Channel, count+1)); err != nil {
+					logger.Error("failed to write the publisher confirm to the client application", zap.Error(err))
+					return err
+				}
+			}
+		}
+	}
+}
+
+// mockConsumerTag returns the consumer tag returned by the broker in the basic.consume-ok of the mock, which the
+// recorded deliveries carry.
+func mockConsumerTag(mock *models.Mock) string {
+	for _, response := range mock.Spec.AMQPResponses {
+		if keyOf(response) == basicConsumeOk {
+			return response.ConsumerTag
+		}
+	}
+	return ""
+}
+
+// deliver pushes the messages recorded for the consumer, with the consumer tag used by the client.
+func deliver(h *hooks.Hook, consumerTag, recordedTag string, channel uint16, write func(models.AMQPCommand, uint16) error) error {
+	deliveries, err := matchDeliveries(h, recordedTag)
+	if err != nil {
+		return err
+	}
+	for _, delivery := range deliveries {
+		if consumerTag != recordedTag {
+			delivery, err = withConsumerTag(delivery, consumerTag)
+			if err != nil {
+				return err
+			}
+		}
+		if err := write(delivery, channel); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// forward passes the connection through to the broker when it wasn't recorded.
+func forward(requestBuffer []byte, clientConn, destConn net.Conn, h *hooks.Hook) error {
+	if _, err := destConn.Write(requestBuffer); err != nil {
+		return err
+	}
+	errChannel := make(chan error, 2)
+	go func() {
+		// Recover from panic and gracefully shutdown
+		defer h.Recover(pkg.GenerateRandomID())
+		defer utils.HandlePanic()
+		_, err := io.Copy(destConn, clientConn)
+		errChannel <- err
+	}()
+	go func() {
+		// Recover from panic and gracefully shutdown
+		defer h.Recover(pkg.GenerateRandomID())
+		defer utils.HandlePanic()
+		_, err := io.Copy(clientConn, destConn)
+		errChannel <- err
+	}()
+	return <-errChannel
+}
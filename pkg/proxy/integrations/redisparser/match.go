@@ -0,0 +1,101 @@
+package redisparser
+
+import (
+	"fmt"
+	"strings"
+
+	"go.keploy.io/server/pkg/hooks"
+	"go.keploy.io/server/pkg/models"
+)
+
+// commands whose first argument isn't a key, they are matched on their name alone when no mock has the same
+// arguments (e.g. the password of AUTH may differ between the record and the test runs)
+var keylessCommands = map[string]bool{
+	"AUTH":   true,
+	"HELLO":  true,
+	"PING":   true,
+	"ECHO":   true,
+	"SELECT": true,
+	"CLIENT": true,
+	"INFO":   true,
+	"QUIT":   true,
+	"RESET":  true,
+}
+
+// commandKey returns the key a command operates on, which is its first argument for most of the commands.
+func commandKey(command models.RedisRequest) string {
+	if keylessCommands[strings.ToUpper(command.Command)] || len(command.Args) == 0 {
+		return ""
+	}
+	return command.Args[0]
+}
+
+// matchCommands finds a distinct mock for every command and removes them from the mocks of the test. It reports
+// false when any of the commands can't be matched.
+func matchCommands(h *hooks.Hook, commands []models.RedisRequest) ([]*models.Mock, bool, error) {
+	for {
+		tcsMocks, err := h.GetTcsMocks()
+		if err != nil {
+			return nil, false, fmt.Errorf("error while getting tcs mocks %v", err)
+		}
+
+		used := map[*models.Mock]bool{}
+		matched := make([]*models.Mock, 0, len(commands))
+		for _, command := range commands {
+			mock := findMock(tcsMocks, command, used)
+			if mock == nil {
+				return nil, false, nil
+			}
+			used[mock] = true
+			matched = append(matched, mock)
+		}
+
+		deleted := true
+		for _, mock := range matched {
+			isDeleted, err := h.DeleteTcsMock(mock)
+			if err != nil {
+				return nil, false, fmt.Errorf("error while deleting tcs mock: %v", err)
+			}
+			deleted = deleted && isDeleted
+		}
+		if !deleted {
+			// another connection consumed one of the mocks in the meantime
+			continue
+		}
+		return matched, true, nil
+	}
+}
+
+// findMock returns the first mock having the same command and arguments, or else the first one having the same
+// command and key.
+func findMock(tcsMocks []*models.Mock, command models.RedisRequest, used map[*models.Mock]bool) *models.Mock {
+	var fallback *models.Mock
+	for _, mock := range tcsMocks {
+		if mock == nil || mock.Kind != models.Redis || used[mock] || len(mock.Spec.RedisRequests) != 1 {
+			continue
+		}
+		recorded := mock.Spec.RedisRequests[0]
+		if !strings.EqualFold(recorded.Command, command.Command) || commandKey(recorded) != commandKey(command) {
+			continue
+		}
+		if equalArgs(recorded.Args, command.Args) {
+			return mock
+		}
+		if fallback == nil {
+			fallback = mock
+		}
+	}
+	return fallback
+}
+
+func equalArgs(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,250 @@
+package redisparser
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.keploy.io/server/pkg"
+	"go.keploy.io/server/pkg/hooks"
+	"go.keploy.io/server/pkg/metrics"
+	"go.keploy.io/server/pkg/models"
+	"go.keploy.io/server/pkg/proxy/util"
+	"go.keploy.io/server/utils"
+	"go.uber.org/zap"
+)
+
+// commands after which the server pushes messages without being asked, the rest of the connection is forwarded
+// without being recorded
+var streamingCommands = map[string]bool{
+	"SUBSCRIBE":  true,
+	"PSUBSCRIBE": true,
+	"SSUBSCRIBE": true,
+	"MONITOR":    true,
+}
+
+type RedisParser struct {
+	logger *zap.Logger
+	hooks  *hooks.Hook
+}
+
+func NewRedisParser(logger *zap.Logger, h *hooks.Hook) *RedisParser {
+	return &RedisParser{
+		logger: logger,
+		hooks:  h,
+	}
+}
+
+// OutgoingType determines if the outgoing network call is Redis, the clients send their commands as RESP arrays
+// of bulk strings (e.g. "*1\r\n$4\r\nPING\r\n").
+func (r *RedisParser) OutgoingType(buffer []byte) bool {
+	if len(buffer) < 4 || buffer[0] != '*' {
+		return false
+	}
+	i := bytes.Index(buffer, crlf)
+	if i < 2 || len(buffer) <= i+len(crlf) || buffer[i+len(crlf)] != '$' {
+		return false
+	}
+	_, err := strconv.Atoi(string(buffer[1:i]))
+	return err == nil
+}
+
+func (r *RedisParser) ProcessOutgoing(requestBuffer []byte, clientConn, destConn net.Conn, ctx context.Context) {
+	switch models.GetMode() {
+	case models.MODE_RECORD:
+		encodeRedisOutgoing(requestBuffer, clientConn, destConn, r.hooks, r.logger, ctx)
+	case models.MODE_TEST:
+		decodeRedisOutgoing(requestBuffer, clientConn, destConn, r.hooks, r.logger)
+	default:
+		r.logger.Info("Invalid mode detected while intercepting outgoing redis call", zap.Any("mode", models.GetMode()))
+	}
+}
+
+// encodeRedisOutgoing forwards the commands of the client to the server and records a mock for every command
+// along with its reply. The replies of the pipelined commands are read in order.
+func encodeRedisOutgoing(requestBuffer []byte, clientConn, destConn net.Conn, h *hooks.Hook, logger *zap.Logger, ctx context.Context) error {
+	var (
+		requests  = requestBuffer
+		responses []byte
+	)
+	for {
+		commands, consumed, err := decodeCommands(requests)
+		if err != nil {
+			logger.Error("failed to decode the redis command from the client", zap.Error(err))
+			return err
+		}
+		if len(commands) == 0 {
+			buffer, err := util.ReadBytes(clientConn)
+			if len(buffer) == 0 && err != nil {
+				if err == io.EOF {
+					logger.Debug("EOF error received from client. Closing connection in redis !!")
+					return nil
+				}
+				logger.Error("failed to read the request message in proxy for redis dependency", zap.Error(err))
+				return err
+			}
+			requests = append(requests, buffer...)
+			continue
+		}
+
+		reqTimestampMock := time.Now()
+		_, err = destConn.Write(requests[:consumed])
+		if err != nil {
+			logger.Error("failed to write request message to the destination server", zap.Error(err))
+			return err
+		}
+		requests = requests[consumed:]
+
+		// the commands pipelined before a streaming command are recorded, the connection being forwarded after it
+		streaming := ""
+		for i, command := range commands {
+			if streamingCommands[strings.ToUpper(command.Command)] {
+				streaming = command.Command
+				commands = commands[:i]
+				break
+			}
+		}
+
+		replies := make([][]models.RedisValue, len(commands))
+		for i := range commands {
+			for {
+				value, n, err := decodeValue(responses)
+				if errors.Is(err, errIncomplete) {
+					buffer, err := util.ReadBytes(destConn)
+					if len(buffer) == 0 && err != nil {
+						logger.Error("failed to read the response message in proxy for redis dependency", zap.Error(err))
+						return err
+					}
+					// forward the reply as soon as it is read, the client may be waiting for a part of the pipeline
+					if _, err := clientConn.Write(buffer); err != nil {
+						logger.Error("failed to write response to the client", zap.Error(err))
+						return err
+					}
+					responses = append(responses, buffer...)
+					continue
+				}
+				if err != nil {
+					logger.Error("failed to decode the redis reply from the server", zap.Error(err))
+					return err
+				}
+				responses = responses[n:]
+				replies[i] = append(replies[i], value)
+				if !isOutOfBand(value) {
+					break
+				}
+			}
+		}
+		resTimestampMock := time.Now()
+
+		for i, command := range commands {
+			h.AppendMocks(&models.Mock{
+				Version: models.GetVersion(),
+				Name:    "mocks",
+				Kind:    models.Redis,
+				Spec: models.MockSpec{
+					RedisRequests:    []models.RedisRequest{command},
+					RedisResponses:   replies[i],
+					ReqTimestampMock: reqTimestampMock,
+					ResTimestampMock: resTimestampMock,
+				},
+			}, ctx)
+		}
+
+		if streaming != "" {
+			logger.Warn("the redis connection is forwarded without being recorded after a streaming command", zap.String("command", streaming))
+			if len(requests) > 0 {
+				if _, err := destConn.Write(requests); err != nil {
+					logger.Error("failed to write request message to the destination server", zap.Error(err))
+					return err
+				}
+			}
+			// the replies read past the recorded commands were already written to the client
+			return forward(clientConn, destConn, h)
+		}
+	}
+}
+
+// forward copies the traffic of the connection in both directions until one of the peers closes it.
+func forward(clientConn, destConn net.Conn, h *hooks.Hook) error {
+	errChannel := make(chan error, 2)
+	go func() {
+		// Recover from panic and gracefully shutdown
+		defer h.Recover(pkg.GenerateRandomID())
+		defer utils.HandlePanic()
+		_, err := io.Copy(destConn, clientConn)
+		errChannel <- err
+	}()
+	go func() {
+		// Recover from panic and gracefully shutdown
+		defer h.Recover(pkg.GenerateRandomID())
+		defer utils.HandlePanic()
+		_, err := io.Copy(clientConn, destConn)
+		errChannel <- err
+	}()
+	return <-errChannel
+}
+
+// decodeRedisOutgoing replies to the commands of the client with the matched mocks. The commands which can't be
+// matched are passed through to the server.
+func decodeRedisOutgoing(requestBuffer []byte, clientConn, destConn net.Conn, h *hooks.Hook, logger *zap.Logger) error {
+	requests := requestBuffer
+	for {
+		commands, consumed, err := decodeCommands(requests)
+		if err != nil {
+			logger.Error("failed to decode the redis command from the client", zap.Error(err))
+			return err
+		}
+		if len(commands) == 0 {
+			buffer, err := util.ReadBytes(clientConn)
+			if len(buffer) == 0 && err != nil {
+				if err == io.EOF {
+					logger.Debug("EOF error received from client. Closing connection in redis !!")
+					return nil
+				}
+				logger.Error("failed to read the request message in proxy for redis dependency", zap.Error(err))
+				return err
+			}
+			requests = append(requests, buffer...)
+			continue
+		}
+		pipeline := requests[:consumed]
+		requests = requests[consumed:]
+
+		mocks, matched, err := matchCommands(h, commands)
+		if err != nil {
+			logger.Error("failed to match the redis commands with the mocks", zap.Error(err))
+			return err
+		}
+		if !matched {
+			metrics.MatchFailures.Inc("redis")
+			logger.Debug("no mock matched the redis commands, passing them through", zap.Any("commands", commands))
+			_, err = util.Passthrough(clientConn, destConn, [][]byte{pipeline}, h.Recover, logger)
+			if err != nil {
+				logger.Error("failed to match the dependency call from user application", zap.Any("commands", commands))
+				return err
+			}
+			continue
+		}
+
+		var response []byte
+		for _, mock := range mocks {
+			for _, value := range mock.Spec.RedisResponses {
+				response, err = encodeValue(response, value)
+				if err != nil {
+					logger.Error("failed to encode the redis reply of the mock", zap.Error(err), zap.String("mock", mock.Name))
+					return err
+				}
+			}
+		}
+		_, err = clientConn.Write(response)
+		if err != nil {
+			logger.Error("failed to write the redis reply to the client application", zap.Error(err))
+			return err
+		}
+	}
+}
@@ -0,0 +1,216 @@
+package redisparser
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"go.keploy.io/server/pkg/models"
+)
+
+// errIncomplete is returned while decoding a buffer which doesn't hold the whole value yet.
+var errIncomplete = errors.New("incomplete RESP value")
+
+var crlf = []byte("\r\n")
+
+// names of the RESP2/RESP3 types stored in the mocks
+var typeNames = map[byte]string{
+	'+': "simple_string",
+	'-': "error",
+	':': "integer",
+	'$': "bulk_string",
+	'*': "array",
+	'_': "null",
+	'#': "boolean",
+	',': "double",
+	'(': "big_number",
+	'!': "bulk_error",
+	'=': "verbatim_string",
+	'%': "map",
+	'~': "set",
+	'|': "attribute",
+	'>': "push",
+}
+
+var typePrefixes = func() map[string]byte {
+	prefixes := make(map[string]byte, len(typeNames))
+	for prefix, name := range typeNames {
+		prefixes[name] = prefix
+	}
+	return prefixes
+}()
+
+func readLine(buffer []byte) (string, int, error) {
+	i := bytes.Index(buffer, crlf)
+	if i < 0 {
+		return "", 0, errIncomplete
+	}
+	return string(buffer[:i]), i + len(crlf), nil
+}
+
+// decodeValue decodes the RESP value starting the buffer and returns it along with the number of bytes it takes.
+func decodeValue(buffer []byte) (models.RedisValue, int, error) {
+	if len(buffer) == 0 {
+		return models.RedisValue{}, 0, errIncomplete
+	}
+	prefix := buffer[0]
+	name, ok := typeNames[prefix]
+	if !ok {
+		return models.RedisValue{}, 0, fmt.Errorf("unknown RESP type %q", prefix)
+	}
+	line, n, err := readLine(buffer[1:])
+	if err != nil {
+		return models.RedisValue{}, 0, err
+	}
+	n++
+
+	value := models.RedisValue{Type: name}
+	switch prefix {
+	case '_':
+		value.Null = true
+	case '$', '!', '=':
+		length, err := strconv.Atoi(line)
+		if err != nil {
+			return models.RedisValue{}, 0, fmt.Errorf("invalid length of the %s: %v", name, err)
+		}
+		// the null bulk string of RESP2
+		if length < 0 {
+			value.Null = true
+			break
+		}
+		if len(buffer) < n+length+len(crlf) {
+			return models.RedisValue{}, 0, errIncomplete
+		}
+		value.Value = string(buffer[n : n+length])
+		n += length + len(crlf)
+	case '*', '%', '~', '|', '>':
+		count, err := strconv.Atoi(line)
+		if err != nil {
+			return models.RedisValue{}, 0, fmt.Errorf("invalid length of the %s: %v", name, err)
+		}
+		// the null array of RESP2
+		if count < 0 {
+			value.Null = true
+			break
+		}
+		if prefix == '%' || prefix == '|' {
+			count *= 2
+		}
+		value.Elements = make([]models.RedisValue, 0, count)
+		for i := 0; i < count; i++ {
+			element, m, err := decodeValue(buffer[n:])
+			if err != nil {
+				return models.RedisValue{}, 0, err
+			}
+			value.Elements = append(value.Elements, element)
+			n += m
+		}
+	default:
+		value.Value = line
+	}
+	return value, n, nil
+}
+
+// encodeValue appends the RESP encoding of the value to the buffer.
+func encodeValue(buffer []byte, value models.RedisValue) ([]byte, error) {
+	prefix, ok := typePrefixes[value.Type]
+	if !ok {
+		return nil, fmt.Errorf("unknown RESP type %q", value.Type)
+	}
+	buffer = append(buffer, prefix)
+	switch prefix {
+	case '_':
+	case '$', '!', '=':
+		if value.Null {
+			buffer = append(buffer, "-1"...)
+			break
+		}
+		buffer = strconv.AppendInt(buffer, int64(len(value.Value)), 10)
+		buffer = append(buffer, crlf...)
+		buffer = append(buffer, value.Value...)
+	case '*', '%', '~', '|', '>':
+		if value.Null {
+			buffer = append(buffer, "-1"...)
+			break
+		}
+		count := len(value.Elements)
+		if prefix == '%' || prefix == '|' {
+			count /= 2
+		}
+		buffer = strconv.AppendInt(buffer, int64(count), 10)
+		buffer = append(buffer, crlf...)
+		for _, element := range value.Elements {
+			var err error
+			buffer, err = encodeValue(buffer, element)
+			if err != nil {
+				return nil, err
+			}
+		}
+		return buffer, nil
+	default:
+		buffer = append(buffer, value.Value...)
+	}
+	return append(buffer, crlf...), nil
+}
+
+// isOutOfBand reports whether the value is sent by the server besides the reply of a command.
+func isOutOfBand(value models.RedisValue) bool {
+	return value.Type == typeNames['>'] || value.Type == typeNames['|']
+}
+
+// decodeCommands decodes the complete commands of the buffer and returns them along with the number of bytes
+// they take. The clients send the commands as arrays of bulk strings, the inline commands are supported as well.
+func decodeCommands(buffer []byte) ([]models.RedisRequest, int, error) {
+	var (
+		commands []models.RedisRequest
+		consumed int
+	)
+	for consumed < len(buffer) {
+		command, n, err := decodeCommand(buffer[consumed:])
+		if errors.Is(err, errIncomplete) {
+			break
+		}
+		if err != nil {
+			return nil, 0, err
+		}
+		consumed += n
+		// empty inline commands are ignored by the server
+		if command.Command == "" {
+			continue
+		}
+		commands = append(commands, command)
+	}
+	return commands, consumed, nil
+}
+
+func decodeCommand(buffer []byte) (models.RedisRequest, int, error) {
+	if buffer[0] != '*' {
+		line, n, err := readLine(buffer)
+		if err != nil {
+			return models.RedisRequest{}, 0, err
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			return models.RedisRequest{}, n, nil
+		}
+		return models.RedisRequest{Command: fields[0], Args: fields[1:]}, n, nil
+	}
+
+	value, n, err := decodeValue(buffer)
+	if err != nil {
+		return models.RedisRequest{}, 0, err
+	}
+	if len(value.Elements) == 0 {
+		return models.RedisRequest{}, n, nil
+	}
+	arguments := make([]string, len(value.Elements))
+	for i, element := range value.Elements {
+		if element.Type != typeNames['$'] {
+			return models.RedisRequest{}, 0, fmt.Errorf("unexpected %s in the command", element.Type)
+		}
+		arguments[i] = element.Value
+	}
+	return models.RedisRequest{Command: arguments[0], Args: arguments[1:]}, n, nil
+}
@@ -1,6 +1,7 @@
 package scram
 
 import (
+	"crypto/hmac"
 	"encoding/base64"
 	"errors"
 	"fmt"
@@ -15,43 +16,11 @@ import (
 // GenerateServerFinalMessage generates the server's final message (i.e., the server proof)
 // for SCRAM authentication, using a default password and given authentication message, mechanism, salt, iteration count.
 func GenerateServerFinalMessage(authMessage, mechanism, password, salt string, itr int, logger *zap.Logger) (string, error) {
-	var (
-		// Declare a variable to hold the hash generation function based on the chosen mechanism.
-		hashGen scram.HashGeneratorFcn
-		// normalised password is used in the salted password
-		passwordDigest string
-	)
-
-	username, err := extractUsername(authMessage)
+	hashGen, saltedPassword, err := saltPassword(authMessage, mechanism, password, salt, itr, logger)
 	if err != nil {
 		return "", err
 	}
 
-	// Switch based on the provided mechanism to determine the hash function to be used.
-	switch mechanism {
-	case "SCRAM-SHA-1":
-		hashGen = scram.SHA1
-		passwordDigest = mongoPasswordDigest(username, password)
-	case "SCRAM-SHA-256":
-		hashGen = scram.SHA256
-		passwordDigest, err = stringprep.SASLprep.Prepare(password)
-		if err != nil {
-			return "", fmt.Errorf("error SASLprepping password for SCRAM-SHA-256 with password: %s. error: %v", password, err.Error())
-		}
-	default:
-		// If the mechanism isn't supported, return an error.
-		return "", errors.New("unsupported authentication mechanism by keploy")
-	}
-
-	// Get the hash function instance based on the determined generator.
-	h := hashGen()
-
-	// Compute the salted password using the PBKDF2 function with the provided salt and iteration count.
-	// It uses the given password. This is the key derivation step.
-	logger.Debug("the input for generating the salted password", zap.Any("normalised password", passwordDigest), zap.Any("salt", salt), zap.Any("iteration", itr), zap.Any("hash size", h.Size()), zap.Any("mechanism", mechanism))
-	saltedPassword := pbkdf2.Key([]byte(passwordDigest), []byte(salt), itr, h.Size(), hashGen)
-	logger.Debug("after generating the salted password", zap.Any("salted password", saltedPassword))
-
 	// Compute the server key using HMAC with the derived salted password and the string "Server Key".
 	serverKey := computeHMAC(hashGen, saltedPassword, []byte("Server Key"))
 	logger.Debug("generating the server using the salted password", zap.Any("server key", serverKey))
@@ -126,3 +95,72 @@ func GenerateAuthMessage(firstRequest, firstResponse string, logger *zap.Logger)
 
 	return authMsg
 }
+
+// VerifyClientProof reports whether the proof of the client-final message was computed from the password, which the
+// server checks before answering with its own signature.
+func VerifyClientProof(proof, authMessage, mechanism, password, salt string, itr int, logger *zap.Logger) (bool, error) {
+	clientProof, err := base64.StdEncoding.DecodeString(proof)
+	if err != nil {
+		return false, fmt.Errorf("malformed client proof: %v", err)
+	}
+	hashGen, saltedPassword, err := saltPassword(authMessage, mechanism, password, salt, itr, logger)
+	if err != nil {
+		return false, err
+	}
+
+	// The proof is the client key masked by the client signature, which is computed from the stored key.
+	clientKey := computeHMAC(hashGen, saltedPassword, []byte("Client Key"))
+	h := hashGen()
+	h.Write(clientKey)
+	clientSignature := computeHMAC(hashGen, h.Sum(nil), []byte(authMessage))
+	if len(clientProof) != len(clientSignature) {
+		return false, nil
+	}
+	expected := make([]byte, len(clientKey))
+	for i := range clientKey {
+		expected[i] = clientKey[i] ^ clientSignature[i]
+	}
+	return hmac.Equal(expected, clientProof), nil
+}
+
+// saltPassword returns the hash function of the mechanism and the password salted with the PBKDF2 key derivation,
+// from which the keys of the client and of the server are computed.
+func saltPassword(authMessage, mechanism, password, salt string, itr int, logger *zap.Logger) (scram.HashGeneratorFcn, []byte, error) {
+	var (
+		// Declare a variable to hold the hash generation function based on the chosen mechanism.
+		hashGen scram.HashGeneratorFcn
+		// normalised password is used in the salted password
+		passwordDigest string
+	)
+
+	username, err := extractUsername(authMessage)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Switch based on the provided mechanism to determine the hash function to be used.
+	switch mechanism {
+	case "SCRAM-SHA-1":
+		hashGen = scram.SHA1
+		passwordDigest = mongoPasswordDigest(username, password)
+	case "SCRAM-SHA-256":
+		hashGen = scram.SHA256
+		passwordDigest, err = stringprep.SASLprep.Prepare(password)
+		if err != nil {
+			return nil, nil, fmt.Errorf("error SASLprepping password for SCRAM-SHA-256 with password: %s. error: %v", password, err.Error())
+		}
+	default:
+		// If the mechanism isn't supported, return an error.
+		return nil, nil, errors.New("unsupported authentication mechanism by keploy")
+	}
+
+	// Get the hash function instance based on the determined generator.
+	h := hashGen()
+
+	// Compute the salted password using the PBKDF2 function with the provided salt and iteration count.
+	// It uses the given password. This is the key derivation step.
+	logger.Debug("the input for generating the salted password", zap.Any("normalised password", passwordDigest), zap.Any("salt", salt), zap.Any("iteration", itr), zap.Any("hash size", h.Size()), zap.Any("mechanism", mechanism))
+	saltedPassword := pbkdf2.Key([]byte(passwordDigest), []byte(salt), itr, h.Size(), hashGen)
+	logger.Debug("after generating the salted password", zap.Any("salted password", saltedPassword))
+	return hashGen, saltedPassword, nil
+}
@@ -0,0 +1,96 @@
+package smtpparser
+
+import (
+	"fmt"
+	"strings"
+
+	"go.keploy.io/server/pkg/hooks"
+	"go.keploy.io/server/pkg/models"
+)
+
+// matchMock finds the mock recorded for the command and removes it from the mocks of the test. The greeting of the
+// server is matched when the command is nil. It returns nil when no mock matches.
+func matchMock(h *hooks.Hook, command *models.SMTPRequest) (*models.Mock, error) {
+	for {
+		tcsMocks, err := h.GetTcsMocks()
+		if err != nil {
+			return nil, fmt.Errorf("error while getting tcs mocks %v", err)
+		}
+
+		var (
+			best      *models.Mock
+			bestScore = -1
+		)
+		for _, mock := range tcsMocks {
+			if mock == nil || mock.Kind != models.SMTP {
+				continue
+			}
+			score, ok := matchScore(mock.Spec.SMTPRequests, command)
+			if ok && score > bestScore {
+				best, bestScore = mock, score
+			}
+		}
+		if best == nil {
+			return nil, nil
+		}
+
+		isDeleted, err := h.DeleteTcsMock(best)
+		if err != nil {
+			return nil, fmt.Errorf("error while deleting tcs mock: %v", err)
+		}
+		if !isDeleted {
+			// another connection consumed the mock in the meantime
+			continue
+		}
+		return best, nil
+	}
+}
+
+// matchScore reports whether the recorded requests answer the command, and how close they are. The verbs have to be
+// the same, then a mock having the same arguments is preferred, then one for the same address (the SIZE parameter
+// of MAIL depends on the mail). The arguments of AUTH and the mail itself differ between the runs.
+func matchScore(recorded []models.SMTPRequest, command *models.SMTPRequest) (int, bool) {
+	if command == nil {
+		return 0, len(recorded) == 0
+	}
+	if len(recorded) == 0 || recorded[0].Command != command.Command {
+		return 0, false
+	}
+	switch {
+	case recorded[0].Args == command.Args && sameMessage(recorded[0].Message, command.Message):
+		return 3, true
+	case recorded[0].Args == command.Args:
+		return 2, true
+	case strings.EqualFold(firstField(recorded[0].Args), firstField(command.Args)):
+		return 1, true
+	}
+	return 0, true
+}
+
+func sameMessage(a, b *models.OutputBinary) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+func firstField(args string) string {
+	fields := strings.Fields(args)
+	if len(fields) == 0 {
+		return ""
+	}
+	return fields[0]
+}
+
+// unmatchedReply is sent for the commands which can't be matched. The ones which don't depend on the state of the
+// session are accepted, the rest get a transient failure.
+func unmatchedReply(command string) models.SMTPResponse {
+	switch command {
+	case "QUIT":
+		return models.SMTPResponse{Code: 221, Lines: []string{"2.0.0 Bye"}}
+	case "NOOP", "RSET":
+		return models.SMTPResponse{Code: 250, Lines: []string{"2.0.0 OK"}}
+	default:
+		return models.SMTPResponse{Code: 451, Lines: []string{"4.3.0 No mock recorded for " + command}}
+	}
+}
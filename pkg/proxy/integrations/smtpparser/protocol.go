@@ -0,0 +1,169 @@
+package smtpparser
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/tls"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"go.keploy.io/server/pkg/models"
+	"go.uber.org/zap"
+)
+
+// reply codes changing the course of an exchange
+const (
+	codeServiceReady   = 220
+	codeAuthContinue   = 334
+	codeStartMailInput = 354
+)
+
+// TLSUpgrader terminates the TLS session started by the client on the given connection.
+type TLSUpgrader func(conn net.Conn) (net.Conn, error)
+
+// readLine reads a line sent by a peer, along with its line ending.
+func readLine(r *bufio.Reader) ([]byte, error) {
+	return r.ReadBytes('\n')
+}
+
+func trimLine(line []byte) string {
+	return strings.TrimRight(string(line), "\r\n")
+}
+
+// parseCommand splits a command line into its verb, in upper case, and its arguments.
+func parseCommand(line []byte) models.SMTPRequest {
+	text := trimLine(line)
+	verb, args, _ := strings.Cut(text, " ")
+	return models.SMTPRequest{
+		Command: strings.ToUpper(verb),
+		Args:    args,
+	}
+}
+
+// readReply reads a reply of the server, which spans several lines when all but the last one have a hyphen after
+// the code (e.g. the reply to EHLO). The raw bytes are returned to be forwarded as they are.
+func readReply(r *bufio.Reader) (models.SMTPResponse, []byte, error) {
+	var (
+		reply models.SMTPResponse
+		raw   []byte
+	)
+	for {
+		line, err := readLine(r)
+		if err != nil {
+			return reply, raw, err
+		}
+		raw = append(raw, line...)
+		text := trimLine(line)
+		if len(text) < 3 {
+			return reply, raw, fmt.Errorf("malformed smtp reply line %q", text)
+		}
+		code, err := strconv.Atoi(text[:3])
+		if err != nil {
+			return reply, raw, fmt.Errorf("malformed smtp reply code in %q", text)
+		}
+		reply.Code = code
+		if len(text) > 4 {
+			reply.Lines = append(reply.Lines, text[4:])
+		} else {
+			reply.Lines = append(reply.Lines, "")
+		}
+		if len(text) == 3 || text[3] != '-' {
+			return reply, raw, nil
+		}
+	}
+}
+
+func encodeReply(reply models.SMTPResponse) []byte {
+	lines := reply.Lines
+	if len(lines) == 0 {
+		lines = []string{""}
+	}
+	var buf bytes.Buffer
+	for i, line := range lines {
+		separator := ' '
+		if i < len(lines)-1 {
+			separator = '-'
+		}
+		fmt.Fprintf(&buf, "%03d%c%s\r\n", reply.Code, separator, line)
+	}
+	return buf.Bytes()
+}
+
+// readMessage reads the mail sent after the 354 reply to DATA up to the line holding a single dot. It returns the
+// raw bytes, to be forwarded as they are, and the mail without the dot-stuffing and the terminating line.
+func readMessage(r *bufio.Reader) ([]byte, []byte, error) {
+	var raw, message []byte
+	for {
+		line, err := readLine(r)
+		raw = append(raw, line...)
+		if err != nil {
+			return raw, message, err
+		}
+		if text := trimLine(line); text == "." {
+			return raw, message, nil
+		}
+		if line[0] == '.' {
+			line = line[1:]
+		}
+		message = append(message, line...)
+	}
+}
+
+// readChunk reads the chunk following a BDAT command, whose first argument is the size of the chunk.
+func readChunk(r *bufio.Reader, command models.SMTPRequest) ([]byte, error) {
+	fields := strings.Fields(command.Args)
+	if len(fields) == 0 {
+		return nil, errors.New("BDAT command without a chunk size")
+	}
+	size, err := strconv.Atoi(fields[0])
+	if err != nil || size < 0 {
+		return nil, fmt.Errorf("invalid BDAT chunk size %q", fields[0])
+	}
+	chunk := make([]byte, size)
+	_, err = io.ReadFull(r, chunk)
+	return chunk, err
+}
+
+func encodeMessage(message []byte) *models.OutputBinary {
+	if isAsciiPrintable(string(message)) {
+		return &models.OutputBinary{Type: models.String, Data: string(message)}
+	}
+	return &models.OutputBinary{Type: "binary", Data: base64.StdEncoding.EncodeToString(message)}
+}
+
+func isAsciiPrintable(s string) bool {
+	for _, r := range s {
+		if r > unicode.MaxASCII || (!unicode.IsPrint(r) && r != '\r' && r != '\n' && r != '\t') {
+			return false
+		}
+	}
+	return true
+}
+
+// upgradeToTLS switches both sides of the connection to TLS once the server accepted STARTTLS. The client gets
+// keploy's certificate, so the decrypted stream can be parsed, and the server is dialed as a client.
+func upgradeToTLS(clientConn, destConn net.Conn, upgradeTLS TLSUpgrader, logger *zap.Logger) (net.Conn, net.Conn, error) {
+	tlsClientConn, err := upgradeTLS(clientConn)
+	if err != nil {
+		logger.Error("failed to terminate the TLS session of the smtp client", zap.Error(err))
+		return nil, nil, err
+	}
+	if destConn == nil {
+		return tlsClientConn, nil, nil
+	}
+
+	// the certificate of the server was already trusted by the application, keploy only records the traffic
+	tlsDestConn := tls.Client(destConn, &tls.Config{InsecureSkipVerify: true})
+	err = tlsDestConn.Handshake()
+	if err != nil {
+		logger.Error("failed to complete the TLS handshake with the smtp server", zap.Error(err))
+		return nil, nil, err
+	}
+	return tlsClientConn, tlsDestConn, nil
+}
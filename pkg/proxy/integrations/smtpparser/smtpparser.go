@@ -0,0 +1,267 @@
+package smtpparser
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"time"
+
+	"go.keploy.io/server/pkg/hooks"
+	"go.keploy.io/server/pkg/metrics"
+	"go.keploy.io/server/pkg/models"
+	"go.uber.org/zap"
+)
+
+type SmtpParser struct {
+	logger     *zap.Logger
+	hooks      *hooks.Hook
+	upgradeTLS TLSUpgrader
+}
+
+func NewSmtpParser(logger *zap.Logger, h *hooks.Hook, upgradeTLS TLSUpgrader) *SmtpParser {
+	return &SmtpParser{
+		logger:     logger,
+		hooks:      h,
+		upgradeTLS: upgradeTLS,
+	}
+}
+
+// OutgoingType returns false since the server speaks first in SMTP, the parser is chosen through the destination
+// port of the connection.
+func (s *SmtpParser) OutgoingType(buffer []byte) bool {
+	return false
+}
+
+func (s *SmtpParser) ProcessOutgoing(requestBuffer []byte, clientConn, destConn net.Conn, ctx context.Context) {
+	switch models.GetMode() {
+	case models.MODE_RECORD:
+		encodeSmtpOutgoing(clientConn, destConn, s.hooks, s.logger, ctx, s.upgradeTLS)
+	case models.MODE_TEST:
+		decodeSmtpOutgoing(clientConn, s.hooks, s.logger, s.upgradeTLS)
+	default:
+		s.logger.Info("Invalid mode detected while intercepting outgoing smtp call", zap.Any("mode", models.GetMode()))
+	}
+}
+
+// encodeSmtpOutgoing forwards the session between the client and the server and records a mock for the greeting
+// of the server and for every command along with its replies. The lines answering the challenges of AUTH and the
+// mail sent after DATA or with BDAT are stored with their command.
+func encodeSmtpOutgoing(clientConn, destConn net.Conn, h *hooks.Hook, logger *zap.Logger, ctx context.Context, upgradeTLS TLSUpgrader) error {
+	clientReader, destReader := bufio.NewReader(clientConn), bufio.NewReader(destConn)
+
+	reqTimestampMock := time.Now()
+	greeting, raw, err := readReply(destReader)
+	if err != nil {
+		logger.Error("failed to read the greeting of the smtp server", zap.Error(err))
+		return err
+	}
+	_, err = clientConn.Write(raw)
+	if err != nil {
+		logger.Error("failed to write the greeting of the smtp server to the client", zap.Error(err))
+		return err
+	}
+	saveMock(h, ctx, nil, []models.SMTPResponse{greeting}, reqTimestampMock)
+
+	for {
+		line, err := readLine(clientReader)
+		if err != nil {
+			if err == io.EOF {
+				logger.Debug("EOF error received from client. Closing connection in smtp !!")
+				return nil
+			}
+			logger.Error("failed to read the smtp command from the client", zap.Error(err))
+			return err
+		}
+		reqTimestampMock := time.Now()
+		_, err = destConn.Write(line)
+		if err != nil {
+			logger.Error("failed to write request message to the destination server", zap.Error(err))
+			return err
+		}
+		command := parseCommand(line)
+		requests := []models.SMTPRequest{command}
+
+		if command.Command == "BDAT" {
+			chunk, err := readChunk(clientReader, command)
+			if err != nil {
+				logger.Error("failed to read the BDAT chunk from the client", zap.Error(err))
+				return err
+			}
+			_, err = destConn.Write(chunk)
+			if err != nil {
+				logger.Error("failed to write request message to the destination server", zap.Error(err))
+				return err
+			}
+			requests[0].Message = encodeMessage(chunk)
+		}
+
+		var responses []models.SMTPResponse
+		for {
+			reply, raw, err := readReply(destReader)
+			if err != nil {
+				logger.Error("failed to read the smtp reply from the server", zap.Error(err), zap.String("command", command.Command))
+				return err
+			}
+			_, err = clientConn.Write(raw)
+			if err != nil {
+				logger.Error("failed to write the smtp reply to the client", zap.Error(err))
+				return err
+			}
+			responses = append(responses, reply)
+
+			var input []byte
+			switch {
+			case reply.Code == codeAuthContinue:
+				input, err = readLine(clientReader)
+				requests = append(requests, models.SMTPRequest{Args: trimLine(input)})
+			case reply.Code == codeStartMailInput && command.Command == "DATA":
+				var message []byte
+				input, message, err = readMessage(clientReader)
+				requests[0].Message = encodeMessage(message)
+			default:
+				// the command is answered
+			}
+			if err != nil {
+				logger.Error("failed to read the smtp input from the client", zap.Error(err), zap.String("command", command.Command))
+				return err
+			}
+			if input == nil {
+				break
+			}
+			_, err = destConn.Write(input)
+			if err != nil {
+				logger.Error("failed to write request message to the destination server", zap.Error(err))
+				return err
+			}
+		}
+		saveMock(h, ctx, requests, responses, reqTimestampMock)
+
+		switch {
+		case command.Command == "STARTTLS" && responses[0].Code == codeServiceReady:
+			clientConn, destConn, err = upgradeToTLS(clientConn, destConn, upgradeTLS, logger)
+			if err != nil {
+				return err
+			}
+			clientReader, destReader = bufio.NewReader(clientConn), bufio.NewReader(destConn)
+		case command.Command == "QUIT":
+			return nil
+		}
+	}
+}
+
+func saveMock(h *hooks.Hook, ctx context.Context, requests []models.SMTPRequest, responses []models.SMTPResponse, reqTimestampMock time.Time) {
+	h.AppendMocks(&models.Mock{
+		Version: models.GetVersion(),
+		Name:    "mocks",
+		Kind:    models.SMTP,
+		Spec: models.MockSpec{
+			SMTPRequests:     requests,
+			SMTPResponses:    responses,
+			ReqTimestampMock: reqTimestampMock,
+			ResTimestampMock: time.Now(),
+		},
+	}, ctx)
+}
+
+// decodeSmtpOutgoing plays the server with the mocks: it sends the recorded greeting and replies to every command
+// with the replies of its mock. The connection is closed when no greeting was recorded.
+func decodeSmtpOutgoing(clientConn net.Conn, h *hooks.Hook, logger *zap.Logger, upgradeTLS TLSUpgrader) error {
+	greeting, err := matchMock(h, nil)
+	if err != nil {
+		logger.Error("failed to match the greeting of the smtp server", zap.Error(err))
+		return err
+	}
+	if greeting == nil {
+		logger.Error("no smtp greeting was recorded, closing the connection")
+		clientConn.Close()
+		return errors.New("no smtp greeting was recorded")
+	}
+	if err := writeReplies(clientConn, greeting.Spec.SMTPResponses); err != nil {
+		logger.Error("failed to write the smtp greeting to the client application", zap.Error(err))
+		return err
+	}
+
+	clientReader := bufio.NewReader(clientConn)
+	for {
+		line, err := readLine(clientReader)
+		if err != nil {
+			if err == io.EOF {
+				logger.Debug("EOF error received from client. Closing connection in smtp !!")
+				return nil
+			}
+			logger.Error("failed to read the smtp command from the client", zap.Error(err))
+			return err
+		}
+		command := parseCommand(line)
+		if command.Command == "BDAT" {
+			chunk, err := readChunk(clientReader, command)
+			if err != nil {
+				logger.Error("failed to read the BDAT chunk from the client", zap.Error(err))
+				return err
+			}
+			command.Message = encodeMessage(chunk)
+		}
+
+		mock, err := matchMock(h, &command)
+		if err != nil {
+			logger.Error("failed to match the smtp command with the mocks", zap.Error(err))
+			return err
+		}
+		if mock == nil {
+			metrics.MatchFailures.Inc("smtp")
+			logger.Warn("no mock matched the smtp command", zap.String("command", command.Command))
+			if err := writeReplies(clientConn, []models.SMTPResponse{unmatchedReply(command.Command)}); err != nil {
+				logger.Error("failed to write the smtp reply to the client application", zap.Error(err))
+				return err
+			}
+			if command.Command == "QUIT" {
+				return nil
+			}
+			continue
+		}
+
+		responses := mock.Spec.SMTPResponses
+		for i, reply := range responses {
+			if err := writeReplies(clientConn, []models.SMTPResponse{reply}); err != nil {
+				logger.Error("failed to write the smtp reply to the client application", zap.Error(err))
+				return err
+			}
+			if i == len(responses)-1 {
+				break
+			}
+			// the input of the client is read before the next reply, its content isn't needed to reply
+			switch reply.Code {
+			case codeAuthContinue:
+				_, err = readLine(clientReader)
+			case codeStartMailInput:
+				_, _, err = readMessage(clientReader)
+			}
+			if err != nil {
+				logger.Error("failed to read the smtp input from the client", zap.Error(err), zap.String("command", command.Command))
+				return err
+			}
+		}
+
+		switch {
+		case command.Command == "STARTTLS" && len(responses) > 0 && responses[0].Code == codeServiceReady:
+			clientConn, _, err = upgradeToTLS(clientConn, nil, upgradeTLS, logger)
+			if err != nil {
+				return err
+			}
+			clientReader = bufio.NewReader(clientConn)
+		case command.Command == "QUIT":
+			return nil
+		}
+	}
+}
+
+func writeReplies(conn net.Conn, replies []models.SMTPResponse) error {
+	for _, reply := range replies {
+		if _, err := conn.Write(encodeReply(reply)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
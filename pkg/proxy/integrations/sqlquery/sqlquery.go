@@ -0,0 +1,202 @@
+// Package sqlquery normalizes the SQL queries of the MySQL and Postgres mocks. The literals of a query are extracted
+// into a list of parameters, and the query is reduced to a readable fingerprint with a "?" in place of each literal,
+// so that the queries differing only by their values (ids, timestamps, ...) can be matched with each other.
+package sqlquery
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Dialect is the SQL dialect of a query, which sets how its strings and comments are written.
+type Dialect int
+
+const (
+	// MySQL strings escape their quotes with backslashes, and "#" starts a comment
+	MySQL Dialect = iota
+	// Postgres strings only escape their quotes by doubling them, unless prefixed by E, and may be dollar-quoted
+	Postgres
+)
+
+// MatchingFingerprint is the matching mode of the SQL mocks which matches the queries without an exact match on
+// their fingerprint.
+const MatchingFingerprint = "fingerprint"
+
+// listPattern matches the lists of literals, like the values of an IN, which are collapsed whatever their length.
+var listPattern = regexp.MustCompile(`\(\s*\?(\s*,\s*\?)+\s*\)`)
+
+// Normalize returns the fingerprint of the query and its literals, in order. The fingerprint drops the comments,
+// collapses the whitespaces and the lists of literals, and replaces the strings and the numbers by "?". The
+// identifiers, the keywords and the placeholders of the prepared statements are kept as they are.
+func Normalize(query string, dialect Dialect) (string, []string) {
+	var (
+		fingerprint strings.Builder
+		parameters  []string
+		space       bool
+	)
+	write := func(token string) {
+		if space && fingerprint.Len() > 0 {
+			fingerprint.WriteByte(' ')
+		}
+		space = false
+		fingerprint.WriteString(token)
+	}
+	literal := func(value string) {
+		parameters = append(parameters, value)
+		write("?")
+	}
+
+	for i := 0; i < len(query); {
+		c := query[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f':
+			space = true
+			i++
+		case strings.HasPrefix(query[i:], "--") || (c == '#' && dialect == MySQL):
+			end := strings.IndexByte(query[i:], '\n')
+			if end < 0 {
+				end = len(query) - i
+			}
+			space = true
+			i += end
+		case strings.HasPrefix(query[i:], "/*"):
+			end := strings.Index(query[i+2:], "*/")
+			if end < 0 {
+				end = len(query) - i - 4
+			}
+			space = true
+			i += end + 4
+		case c == '\'':
+			value, n := quoted(query[i:], dialect == MySQL)
+			literal(value)
+			i += n
+		case c == '"' || c == '`':
+			// the quoted identifiers are kept
+			_, n := quoted(query[i:], false)
+			write(query[i : i+n])
+			i += n
+		case c == '$' && dialect == Postgres:
+			if value, n, ok := dollarQuoted(query[i:]); ok {
+				literal(value)
+				i += n
+				break
+			}
+			// a placeholder, like $1
+			n := 1
+			for i+n < len(query) && isDigit(query[i+n]) {
+				n++
+			}
+			write(query[i : i+n])
+			i += n
+		case isDigit(c) || (c == '.' && i+1 < len(query) && isDigit(query[i+1])):
+			n := number(query[i:])
+			literal(query[i : i+n])
+			i += n
+		case isIdentifier(c):
+			n := 1
+			for i+n < len(query) && (isIdentifier(query[i+n]) || isDigit(query[i+n])) {
+				n++
+			}
+			word := query[i : i+n]
+			if (word == "E" || word == "e") && dialect == Postgres && i+n < len(query) && query[i+n] == '\'' {
+				// an escape string, whose quotes may be escaped with backslashes
+				value, m := quoted(query[i+n:], true)
+				literal(value)
+				i += n + m
+				break
+			}
+			write(word)
+			i += n
+		default:
+			write(string(c))
+			i++
+		}
+	}
+	return listPattern.ReplaceAllString(fingerprint.String(), "(...)"), parameters
+}
+
+// Fingerprint returns the fingerprint of the query, see Normalize.
+func Fingerprint(query string, dialect Dialect) string {
+	fingerprint, _ := Normalize(query, dialect)
+	return fingerprint
+}
+
+// quoted returns the content of the quoted string or identifier starting the query, with its doubled quotes
+// unescaped, and its length with the quotes.
+func quoted(query string, backslashEscapes bool) (string, int) {
+	quote := query[0]
+	var value strings.Builder
+	for i := 1; i < len(query); i++ {
+		switch {
+		case backslashEscapes && query[i] == '\\' && i+1 < len(query):
+			value.WriteByte(query[i])
+			value.WriteByte(query[i+1])
+			i++
+		case query[i] == quote && i+1 < len(query) && query[i+1] == quote:
+			value.WriteByte(quote)
+			i++
+		case query[i] == quote:
+			return value.String(), i + 1
+		default:
+			value.WriteByte(query[i])
+		}
+	}
+	// an unterminated string runs to the end of the query
+	return value.String(), len(query)
+}
+
+// dollarQuoted returns the content of the dollar-quoted string ($$...$$ or $tag$...$tag$) starting the query, and
+// its length with the quotes.
+func dollarQuoted(query string) (string, int, bool) {
+	end := strings.IndexByte(query[1:], '$')
+	if end < 0 {
+		return "", 0, false
+	}
+	tag := query[:end+2]
+	for i := 1; i < len(tag)-1; i++ {
+		if !isIdentifier(tag[i]) && (i == 1 || !isDigit(tag[i])) {
+			return "", 0, false
+		}
+	}
+	closing := strings.Index(query[len(tag):], tag)
+	if closing < 0 {
+		return "", 0, false
+	}
+	return query[len(tag) : len(tag)+closing], len(tag)*2 + closing, true
+}
+
+// number returns the length of the number starting the query, in decimal, scientific or hexadecimal notation.
+func number(query string) int {
+	if len(query) > 2 && query[0] == '0' && (query[1] == 'x' || query[1] == 'X') {
+		n := 2
+		for n < len(query) && strings.IndexByte("0123456789abcdefABCDEF", query[n]) >= 0 {
+			n++
+		}
+		return n
+	}
+	n := 0
+	for n < len(query) && (isDigit(query[n]) || query[n] == '.') {
+		n++
+	}
+	if n < len(query) && (query[n] == 'e' || query[n] == 'E') {
+		m := n + 1
+		if m < len(query) && (query[m] == '+' || query[m] == '-') {
+			m++
+		}
+		if m < len(query) && isDigit(query[m]) {
+			for m < len(query) && isDigit(query[m]) {
+				m++
+			}
+			n = m
+		}
+	}
+	return n
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isIdentifier(c byte) bool {
+	return c == '_' || c == '$' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || c >= 0x80
+}
@@ -1,7 +1,29 @@
 package proxy
 
+import "go.keploy.io/server/pkg/models"
+
 // Option provides a means to initiate the proxy based on user input.
 type Option struct {
 	Port          uint32
 	MongoPassword string
+	// PostgresPassword authenticates the postgres clients with SCRAM-SHA-256 in test mode
+	PostgresPassword string
+	// ProtoDescriptors are the paths of the FileDescriptorSets used to decode the protobuf messages of the gRPC calls
+	ProtoDescriptors []string
+	// ProtobufRoutes tell the messages of the protobuf bodies of the http calls decoded through the ProtoDescriptors
+	ProtobufRoutes []models.ProtobufRoute
+	// Http tunes the matching of the HTTP mocks
+	Http models.HttpConfig
+	// Sql tunes the matching of the MySQL and Postgres mocks
+	Sql models.SqlConfig
+	// Matchers tune the matching of the mocks of each kind
+	Matchers []models.MatcherRule
+	// PassThrough selects the outgoing calls forwarded untouched to their server
+	PassThrough []models.PassThroughRule
+	// UnixSockets are the unix sockets of the dependencies of the application captured by the proxy
+	UnixSockets []models.UnixSocket
+	// MaxBuffer bounds the bytes of the calls passed through read before they are written, 0 for no bound
+	MaxBuffer int
+	// MaxMessage bounds the bytes of the messages read whole by the parsers, 0 for util.DefaultMaxMessage
+	MaxMessage int
 }
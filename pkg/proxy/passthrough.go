@@ -0,0 +1,195 @@
+package proxy
+
+import (
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/miekg/dns"
+	"go.keploy.io/server/pkg/hooks/structs"
+	"go.keploy.io/server/pkg/models"
+	"go.keploy.io/server/pkg/proxy/util"
+	"go.uber.org/zap"
+)
+
+// passThroughResolveTTL is how long the resolved IPs of the hosts of the pass through rules are kept.
+const passThroughResolveTTL = time.Minute
+
+// passThrough selects the outgoing calls which are forwarded untouched to their server.
+type passThrough struct {
+	rules    []passThroughRule
+	logger   *zap.Logger
+	mutex    sync.Mutex
+	resolved map[string]resolvedHost
+}
+
+type passThroughRule struct {
+	host     string
+	network  *net.IPNet
+	port     uint32
+	protocol string
+}
+
+type resolvedHost struct {
+	ips []net.IP
+	at  time.Time
+}
+
+// newPassThrough compiles the pass through rules, skipping the invalid ones.
+func newPassThrough(rules []models.PassThroughRule, logger *zap.Logger) *passThrough {
+	p := &passThrough{
+		logger:   logger,
+		resolved: map[string]resolvedHost{},
+	}
+	for _, rule := range rules {
+		if rule.Host == "" && rule.CIDR == "" && rule.Port == 0 && rule.Protocol == "" {
+			logger.Warn("skipping the empty pass through rule")
+			continue
+		}
+		compiled := passThroughRule{host: rule.Host, port: rule.Port, protocol: strings.ToLower(rule.Protocol)}
+		if rule.CIDR != "" {
+			_, network, err := net.ParseCIDR(rule.CIDR)
+			if err != nil {
+				logger.Warn("skipping the pass through rule with an invalid CIDR", zap.Any("cidr", rule.CIDR), zap.Error(err))
+				continue
+			}
+			compiled.network = network
+		}
+		p.rules = append(p.rules, compiled)
+	}
+	return p
+}
+
+// matches reports whether the calls to the server are passed through. The protocol is empty when it isn't known
+// yet, in which case the rules selecting a protocol don't match.
+func (p *passThrough) matches(ip net.IP, port uint32, protocol string) bool {
+	if p == nil {
+		return false
+	}
+	for _, rule := range p.rules {
+		if rule.port != 0 && rule.port != port {
+			continue
+		}
+		if rule.protocol != "" && rule.protocol != strings.ToLower(protocol) {
+			continue
+		}
+		if rule.network != nil && !rule.network.Contains(ip) {
+			continue
+		}
+		if rule.host != "" && !p.hostHasIP(rule.host, ip) {
+			continue
+		}
+		return true
+	}
+	return false
+}
+
+// hasProtocolRules reports whether some rules select the calls on their protocol.
+func (p *passThrough) hasProtocolRules() bool {
+	if p == nil {
+		return false
+	}
+	for _, rule := range p.rules {
+		if rule.protocol != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// hostHasIP reports whether the host resolves to the IP, the resolved IPs being cached for a while.
+func (p *passThrough) hostHasIP(host string, ip net.IP) bool {
+	if hostIP := net.ParseIP(host); hostIP != nil {
+		return hostIP.Equal(ip)
+	}
+	p.mutex.Lock()
+	resolved, ok := p.resolved[host]
+	p.mutex.Unlock()
+	if !ok || time.Since(resolved.at) > passThroughResolveTTL {
+		ips, err := net.LookupIP(host)
+		if err != nil {
+			p.logger.Debug("failed to resolve the host of the pass through rule", zap.Any("host", host), zap.Error(err))
+		}
+		resolved = resolvedHost{ips: ips, at: time.Now()}
+		p.mutex.Lock()
+		p.resolved[host] = resolved
+		p.mutex.Unlock()
+	}
+	for _, resolvedIP := range resolved.ips {
+		if resolvedIP.Equal(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// hasHost reports whether a rule selects the calls to the host, like the names of the DNS queries.
+func (p *passThrough) hasHost(host string) bool {
+	if p == nil {
+		return false
+	}
+	host = strings.TrimSuffix(host, ".")
+	for _, rule := range p.rules {
+		if rule.host != "" && strings.EqualFold(rule.host, host) {
+			return true
+		}
+	}
+	return false
+}
+
+// SetPassThrough replaces the pass through rules and ports of the proxy, like on the reload of the config file. They
+// select the connections accepted next, the ones in progress keeping their course.
+func (ps *ProxySet) SetPassThrough(rules []models.PassThroughRule, ports []uint) {
+	passThrough := newPassThrough(rules, ps.logger)
+	ps.passThroughMutex.Lock()
+	defer ps.passThroughMutex.Unlock()
+	ps.passThrough, ps.PassThroughPorts = passThrough, ports
+}
+
+// passThroughRules returns the pass through rules and ports of the proxy.
+func (ps *ProxySet) passThroughRules() (*passThrough, []uint) {
+	ps.passThroughMutex.RLock()
+	defer ps.passThroughMutex.RUnlock()
+	return ps.passThrough, ps.PassThroughPorts
+}
+
+// passThroughConnection forwards the bytes of the connection to its destination server and back.
+func (ps *ProxySet) passThroughConnection(conn net.Conn, destInfo *structs.DestInfo) {
+	address := destinationAddress(destInfo)
+	logger := ps.logger.With(zap.Any("Client IP Address", conn.RemoteAddr().String()), zap.Any("Destination IP Address", address))
+	logger.Debug("passing through the outgoing call")
+	dst, err := net.Dial("tcp", address)
+	if err != nil {
+		logger.Error("failed to dial the connection to the destination server passed through", zap.Error(err))
+		return
+	}
+	if err := ps.callNext(nil, conn, dst, logger); err != nil {
+		logger.Error("failed to pass through the outgoing call", zap.Error(err))
+	}
+}
+
+func destinationIP(destInfo *structs.DestInfo) net.IP {
+	if destInfo.IpVersion == 6 {
+		return net.ParseIP(util.ToIPv6AddressStr(destInfo.DestIp6))
+	}
+	return net.ParseIP(util.ToIP4AddressStr(destInfo.DestIp4))
+}
+
+// destinationAddress returns the host:port address of the destination of the connection, [ip]:port for IPv6. The
+// IPv4-mapped IPv6 addresses (::ffff:a.b.c.d) of the dual-stack sockets are written as their IPv4 address.
+func destinationAddress(destInfo *structs.DestInfo) string {
+	return net.JoinHostPort(destinationIP(destInfo).String(), strconv.Itoa(int(destInfo.DestPort)))
+}
+
+// answersOfType returns the DNS answers of the type of the query.
+func answersOfType(answers []dns.RR, qtype uint16) []dns.RR {
+	var filtered []dns.RR
+	for _, answer := range answers {
+		if answer.Header().Rrtype == qtype {
+			filtered = append(filtered, answer)
+		}
+	}
+	return filtered
+}
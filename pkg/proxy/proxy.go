@@ -8,6 +8,7 @@ import (
 	"crypto/tls"
 	"crypto/x509"
 	"embed"
+	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
@@ -22,8 +23,21 @@ import (
 	"sync/atomic"
 
 	"go.keploy.io/server/pkg"
+	"go.keploy.io/server/pkg/logging"
+	"go.keploy.io/server/pkg/metrics"
+	"go.keploy.io/server/pkg/proxy/integrations/cassandraparser"
 	"go.keploy.io/server/pkg/proxy/integrations/grpcparser"
+	"go.keploy.io/server/pkg/proxy/integrations/kafkaparser"
+	"go.keploy.io/server/pkg/proxy/integrations/ldapparser"
+	"go.keploy.io/server/pkg/proxy/integrations/matcher"
+	"go.keploy.io/server/pkg/proxy/integrations/memcachedparser"
+	"go.keploy.io/server/pkg/proxy/integrations/mqttparser"
+	"go.keploy.io/server/pkg/proxy/integrations/natsparser"
 	postgresparser "go.keploy.io/server/pkg/proxy/integrations/postgresParser"
+	"go.keploy.io/server/pkg/proxy/integrations/protohttp"
+	"go.keploy.io/server/pkg/proxy/integrations/rabbitmqparser"
+	"go.keploy.io/server/pkg/proxy/integrations/redisparser"
+	"go.keploy.io/server/pkg/proxy/integrations/smtpparser"
 	"go.keploy.io/server/utils"
 
 	"github.com/cloudflare/cfssl/csr"
@@ -35,9 +49,12 @@ import (
 
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/miekg/dns"
 	"go.keploy.io/server/pkg/hooks"
+	"go.keploy.io/server/pkg/hooks/structs"
 	"go.keploy.io/server/pkg/models"
+	"go.keploy.io/server/pkg/proxy/integrations"
 	genericparser "go.keploy.io/server/pkg/proxy/integrations/genericParser"
 	"go.keploy.io/server/pkg/proxy/integrations/httpparser"
 	"go.keploy.io/server/pkg/proxy/integrations/mongoparser"
@@ -60,7 +77,30 @@ type DependencyHandler interface {
 	ProcessOutgoing(buffer []byte, conn net.Conn, dst net.Conn, ctx context.Context)
 }
 
-var ParsersMap = make(map[string]DependencyHandler)
+// priorities of the built-in parsers in the detection pipeline, the stricter matchers go first
+const (
+	grpcPriority      = 40
+	amqpPriority      = 35
+	httpPriority      = 30
+	mqttPriority      = 25
+	postgresPriority  = 20
+	redisPriority     = 15
+	ldapPriority      = 14
+	cassandraPriority = 13
+	kafkaPriority     = 12
+	mongoPriority     = 10
+	memcachedPriority = 8
+)
+
+// serverFirstPorts maps the destination ports of the protocols where the server speaks first to their parser, the
+// application sends nothing before the greeting of the server so these can't be detected from the first buffer
+var serverFirstPorts = map[uint32]string{
+	3306: "mysql",
+	25:   "smtp",
+	587:  "smtp",
+	2525: "smtp",
+	4222: "nats",
+}
 
 type ProxySet struct {
 	IP4               uint32
@@ -77,6 +117,19 @@ type ProxySet struct {
 	dockerAppCmd      bool
 	PassThroughPorts  []uint
 	MongoPassword     string // password to mock the mongo connection and pass the authentication requests
+	passThrough       *passThrough
+	// ctx is the context of the session, counting the recorded mocks
+	ctx context.Context
+	// recordedDNS holds the names and types of the DNS queries recorded in the session
+	recordedDNS sync.Map
+	// unixListeners listen for the connections of the application to its unix sockets
+	unixListeners []net.Listener
+	// userspace is set when the eBPF hooks aren't loaded, the application sending its calls through the proxy
+	userspace bool
+	// passThroughMutex guards the pass through rules and ports, which SetPassThrough replaces while recording
+	passThroughMutex sync.RWMutex
+	// maxBuffer bounds the bytes of the calls passed through read before they are written, 0 for no bound
+	maxBuffer int
 }
 
 type CustomConn struct {
@@ -305,18 +358,48 @@ func containsJava(input string) bool {
 	return strings.Contains(inputLower, searchTermLower)
 }
 
+// Register adds a dependency parser detected through its OutgoingType method to the integrations registry.
 func Register(parserName string, parser DependencyHandler) {
-	ParsersMap[parserName] = parser
+	integrations.Register(parserName, parser.OutgoingType, parser)
+}
+
+func registerWithPriority(parserName string, priority int, parser DependencyHandler) {
+	integrations.RegisterWithPriority(parserName, priority, parser.OutgoingType, parser)
 }
 
 // BootProxy starts proxy server on the idle local port, Default:16789
 func BootProxy(logger *zap.Logger, opt Option, appCmd, appContainer string, pid uint32, lang string, passThroughPorts []uint, h *hooks.Hook, ctx context.Context, delay uint64) *ProxySet {
+	logger = logger.Named(logging.Proxy)
+	// the parsers look up the matching rule of their mocks
+	matcher.Configure(opt.Matchers)
+	// the protobuf bodies of the http calls are decoded into their JSON form
+	if err := protohttp.Configure(opt.ProtoDescriptors, opt.ProtobufRoutes); err != nil {
+		logger.Error("failed to load the descriptors of the protobuf http bodies, storing them as they are", zap.Error(err))
+	}
 	//Register all the parsers in the map.
-	Register("grpc", grpcparser.NewGrpcParser(logger, h))
-	Register("postgres", postgresparser.NewPostgresParser(logger, h))
-	Register("mongo", mongoparser.NewMongoParser(logger, h, opt.MongoPassword))
-	Register("http", httpparser.NewHttpParser(logger, h))
-	Register("mysql", mysqlparser.NewMySqlParser(logger, h, delay))
+	registerWithPriority("grpc", grpcPriority, grpcparser.NewGrpcParser(logger.Named(logging.Parser("grpc")), h, opt.ProtoDescriptors))
+	registerWithPriority("rabbitmq", amqpPriority, rabbitmqparser.NewRabbitMQParser(logger.Named(logging.Parser("rabbitmq")), h))
+	registerWithPriority("mqtt", mqttPriority, mqttparser.NewMqttParser(logger.Named(logging.Parser("mqtt")), h))
+	registerWithPriority("postgres", postgresPriority, postgresparser.NewPostgresParser(logger.Named(logging.Parser("postgres")), h, opt.PostgresPassword, opt.Sql))
+	registerWithPriority("redis", redisPriority, redisparser.NewRedisParser(logger.Named(logging.Parser("redis")), h))
+	registerWithPriority("ldap", ldapPriority, ldapparser.NewLdapParser(logger.Named(logging.Parser("ldap")), h))
+	registerWithPriority("cassandra", cassandraPriority, cassandraparser.NewCassandraParser(logger.Named(logging.Parser("cassandra")), h))
+	registerWithPriority("kafka", kafkaPriority, kafkaparser.NewKafkaParser(logger.Named(logging.Parser("kafka")), h))
+	registerWithPriority("mongo", mongoPriority, mongoparser.NewMongoParser(logger.Named(logging.Parser("mongo")), h, opt.MongoPassword))
+	registerWithPriority("memcached", memcachedPriority, memcachedparser.NewMemcachedParser(logger.Named(logging.Parser("memcached")), h))
+	registerWithPriority("http", httpPriority, httpparser.NewHttpParser(logger.Named(logging.Parser("http")), h, opt.Http))
+	// mysql, smtp and nats are detected through the destination port, see handleConnection
+	Register("mysql", mysqlparser.NewMySqlParser(logger.Named(logging.Parser("mysql")), h, delay, opt.Sql, func(conn net.Conn) (net.Conn, error) {
+		return handleTLSConnection(conn, logger)
+	}))
+	Register("smtp", smtpparser.NewSmtpParser(logger.Named(logging.Parser("smtp")), h, func(conn net.Conn) (net.Conn, error) {
+		return handleTLSConnection(conn, logger)
+	}))
+	Register("nats", natsparser.NewNatsParser(logger.Named(logging.Parser("nats")), h, func(conn net.Conn) (net.Conn, error) {
+		return handleTLSConnection(conn, logger)
+	}))
+	// parsers registered by external packages get the logger and hooks of the proxy
+	integrations.Init(logger, h)
 	// assign default values if not provided
 	caPaths, err := getCaPaths()
 	if err != nil {
@@ -327,6 +410,11 @@ func BootProxy(logger *zap.Logger, opt Option, appCmd, appContainer string, pid
 		caPath := filepath.Join(path, "ca.crt")
 
 		fs, err := os.Create(caPath)
+		if err != nil && h.IsUserspace() && os.IsPermission(err) {
+			// the unprivileged capture modes only trust the CA through the variables set below
+			logger.Warn("failed to add the ca certificate to the trust store without the privileges", zap.Any("root store path", path))
+			continue
+		}
 		if err != nil {
 			logger.Error("failed to create path for ca certificate", zap.Error(err), zap.Any("root store path", path))
 			return nil
@@ -420,8 +508,14 @@ func BootProxy(logger *zap.Logger, opt Option, appCmd, appContainer string, pid
 		PassThroughPorts:  passThroughPorts,
 		hook:              h,
 		MongoPassword:     opt.MongoPassword,
+		passThrough:       newPassThrough(opt.PassThrough, logger),
+		ctx:               ctx,
+		userspace:         h.IsUserspace(),
+		maxBuffer:         opt.MaxBuffer,
 	}
 
+	util.SetMaxMessage(opt.MaxMessage)
+
 	//setting the proxy port field in hook
 	proxySet.hook.SetProxyPort(opt.Port)
 
@@ -431,10 +525,13 @@ func BootProxy(logger *zap.Logger, opt Option, appCmd, appContainer string, pid
 			defer utils.HandlePanic()
 			proxySet.startProxy(ctx)
 		}()
-		// Resolve DNS queries only in case of test mode.
-		if models.GetMode() == models.MODE_TEST {
-			proxySet.logger.Debug("Running Dns Server in Test mode...")
-			proxySet.logger.Info("Keploy has hijacked the DNS resolution mechanism, your application may misbehave in keploy test mode if you have provided wrong domain name in your application code.")
+		proxySet.startUnixSockets(opt.UnixSockets)
+		// Resolve DNS queries in record mode, recording them, and in test mode.
+		if models.GetMode() == models.MODE_TEST || models.GetMode() == models.MODE_RECORD {
+			proxySet.logger.Debug("Running Dns Server...", zap.Any("mode", models.GetMode()))
+			if models.GetMode() == models.MODE_TEST {
+				proxySet.logger.Info("Keploy has hijacked the DNS resolution mechanism, your application may misbehave in keploy test mode if you have provided wrong domain name in your application code.")
+			}
 			go func() {
 				defer h.Recover(pkg.GenerateRandomID())
 				defer utils.HandlePanic()
@@ -498,11 +595,17 @@ func certForClient(clientHello *tls.ClientHelloInfo) (*tls.Certificate, error) {
 
 	cfsslLog.Level = cfsslLog.LevelError
 
+	serverName := clientHello.ServerName
+	if serverName == "" {
+		// clients connecting through an ip address, like most database drivers, don't send SNI
+		serverName = "localhost"
+	}
+
 	serverReq := &csr.CertificateRequest{
 		//Make the name accordng to the ip of the request
-		CN: clientHello.ServerName,
+		CN: serverName,
 		Hosts: []string{
-			clientHello.ServerName,
+			serverName,
 		},
 		KeyRequest: csr.NewKeyRequest(),
 	}
@@ -549,7 +652,13 @@ func (ps *ProxySet) startProxy(ctx context.Context) {
 	proxyAddress6 := util.ToIPv6AddressStr(ps.IP6)
 	ps.logger.Debug("", zap.Any("ProxyAddress6", proxyAddress6))
 
-	listener, err := net.Listen("tcp", fmt.Sprintf(":%v", port))
+	// the userspace proxy relays the calls to the destination read from the request, so it only listens on the
+	// loopback, where the application reaches it, instead of relaying the calls of the whole network
+	address := fmt.Sprintf(":%v", port)
+	if ps.userspace {
+		address = fmt.Sprintf("127.0.0.1:%v", port)
+	}
+	listener, err := net.Listen("tcp", address)
 	if err != nil {
 		ps.logger.Error(fmt.Sprintf("failed to start proxy on port:%v", port), zap.Error(err))
 		return
@@ -582,6 +691,10 @@ func (ps *ProxySet) startProxy(ctx context.Context) {
 		go func() {
 			defer ps.hook.Recover(pkg.GenerateRandomID())
 			defer utils.HandlePanic()
+			if ps.userspace {
+				ps.handleProxyRequest(conn, port, ctx)
+				return
+			}
 			ps.handleConnection(conn, port, ctx)
 		}()
 	}
@@ -625,13 +738,28 @@ func generateCacheKey(name string, qtype uint16) string {
 func (ps *ProxySet) ServeDNS(w dns.ResponseWriter, r *dns.Msg) {
 
 	ps.logger.Debug("", zap.Any("Source socket info", w.RemoteAddr().String()))
+	if models.GetMode() == models.MODE_RECORD {
+		ps.recordDNS(w, r)
+		return
+	}
 	msg := new(dns.Msg)
 	msg.SetReply(r)
 	msg.Authoritative = true
 	ps.logger.Debug("Got some Dns queries")
+	passThrough, _ := ps.passThroughRules()
 	for _, question := range r.Question {
 		ps.logger.Debug("", zap.Any("Record Type", question.Qtype), zap.Any("Received Query", question.Name))
 
+		// the recorded answers are replayed, the names which don't resolve anymore included
+		if !passThrough.hasHost(question.Name) {
+			if answers, rcode, ok := ps.mockedDNS(question); ok {
+				ps.logger.Debug("answering the dns query with its mock", zap.Any("query", question.Name), zap.Any("answers", answers))
+				msg.Rcode = rcode
+				msg.Answer = append(msg.Answer, answers...)
+				continue
+			}
+		}
+
 		key := generateCacheKey(question.Name, question.Qtype)
 
 		// Check if the answer is cached
@@ -643,6 +771,11 @@ func (ps *ProxySet) ServeDNS(w dns.ResponseWriter, r *dns.Msg) {
 			// If not found in cache, resolve the DNS query
 			// answers = resolveDNSQuery(question.Name, ps.logger, ps.DnsServerTimeout)
 
+			// the hosts passed through are resolved for real so that their calls reach their servers
+			if passThrough.hasHost(question.Name) {
+				answers = answersOfType(resolveDNSQuery(question.Name, ps.logger, ps.DnsServerTimeout), question.Qtype)
+			}
+
 			if answers == nil || len(answers) == 0 {
 				// If the resolution failed, return a default A record with Proxy IP
 				if question.Qtype == dns.TypeA {
@@ -735,18 +868,83 @@ func isTLSHandshake(data []byte) bool {
 	return data[0] == 0x16 && data[1] == 0x03 && (data[2] == 0x00 || data[2] == 0x01 || data[2] == 0x02 || data[2] == 0x03)
 }
 
-func (ps *ProxySet) handleTLSConnection(conn net.Conn) (net.Conn, error) {
+// handleTLSConnection terminates the TLS session of a client calling its destination. In record mode, the session
+// with the destination server is started during the handshake of the client, offering it the application protocols
+// (h2, http/1.1) offered by the client, and is returned along with the one of the client, so that both negotiate the
+// same protocol.
+func (ps *ProxySet) handleTLSConnection(conn net.Conn, destInfo *structs.DestInfo) (net.Conn, net.Conn, error) {
+	var upstream *tls.Conn
+	tlsConn, err := handleTLSConnectionWithProtos(conn, ps.logger, func(clientHello *tls.ClientHelloInfo) []string {
+		var protos []string
+		protos, upstream = dialUpstream(clientHello, destinationAddress(destInfo), ps.logger)
+		return protos
+	})
+	if err != nil {
+		if upstream != nil {
+			upstream.Close()
+		}
+		return nil, nil, err
+	}
+	if upstream == nil {
+		return tlsConn, nil, nil
+	}
+	return tlsConn, upstream, nil
+}
+
+// handleTLSConnection terminates the TLS session started by the client using a certificate signed by keploy's CA.
+func handleTLSConnection(conn net.Conn, logger *zap.Logger) (net.Conn, error) {
+	return handleTLSConnectionWithProtos(conn, logger, nil)
+}
+
+// alpnProtos are the application protocols the proxy negotiates, the HTTP/2 streams being recorded by the grpc
+// parser and the HTTP/1 requests by the http parser.
+var alpnProtos = map[string]bool{"h2": true, "http/1.1": true}
+
+// dialUpstream returns the application protocols to negotiate with the client and, in record mode, the session with
+// the destination server at the captured address, started with the server name of the client: the client is offered
+// the protocol the server chose among the ones it offered, falling back to HTTP/1 when the server doesn't speak
+// HTTP/2. There is no server in test mode, the mocks being replayed over both protocols. Without a server name, the
+// session is left to be started once the client's one is.
+func dialUpstream(clientHello *tls.ClientHelloInfo, address string, logger *zap.Logger) ([]string, *tls.Conn) {
+	var offered []string
+	for _, proto := range clientHello.SupportedProtos {
+		if alpnProtos[proto] {
+			offered = append(offered, proto)
+		}
+	}
+	if models.GetMode() == models.MODE_TEST || clientHello.ServerName == "" {
+		return offered, nil
+	}
+
+	dialer := &net.Dialer{Timeout: 5 * time.Second}
+	upstream, err := tls.DialWithDialer(dialer, "tcp", address, &tls.Config{
+		ServerName: clientHello.ServerName,
+		NextProtos: offered,
+	})
+	if err != nil {
+		logger.Debug("failed to start the TLS session with the destination server", zap.String("server address", address), zap.Error(err))
+		return nil, nil
+	}
+	if negotiated := upstream.ConnectionState().NegotiatedProtocol; negotiated != "" {
+		return []string{negotiated}, upstream
+	}
+	return nil, upstream
+}
+
+// handleTLSConnectionWithProtos terminates the TLS session of the client, negotiating the application protocols
+// returned by nextProtos for its hello.
+func handleTLSConnectionWithProtos(conn net.Conn, logger *zap.Logger, nextProtos func(clientHello *tls.ClientHelloInfo) []string) (net.Conn, error) {
 	//Load the CA certificate and private key
 
 	var err error
 	caPrivKey, err = helpers.ParsePrivateKeyPEM(caPKey)
 	if err != nil {
-		ps.logger.Error(Emoji+"Failed to parse CA private key: ", zap.Error(err))
+		logger.Error(Emoji+"Failed to parse CA private key: ", zap.Error(err))
 		return nil, err
 	}
 	caCertParsed, err = helpers.ParseCertificatePEM(caCrt)
 	if err != nil {
-		ps.logger.Error(Emoji+"Failed to parse CA certificate: ", zap.Error(err))
+		logger.Error(Emoji+"Failed to parse CA certificate: ", zap.Error(err))
 		return nil, err
 	}
 
@@ -754,6 +952,14 @@ func (ps *ProxySet) handleTLSConnection(conn net.Conn) (net.Conn, error) {
 	config := &tls.Config{
 		GetCertificate: certForClient,
 	}
+	if nextProtos != nil {
+		config.GetConfigForClient = func(clientHello *tls.ClientHelloInfo) (*tls.Config, error) {
+			return &tls.Config{
+				GetCertificate: certForClient,
+				NextProtos:     nextProtos(clientHello),
+			}, nil
+		}
+	}
 
 	// Wrap the TCP connection with TLS
 	tlsConn := tls.Server(conn, config)
@@ -761,7 +967,7 @@ func (ps *ProxySet) handleTLSConnection(conn net.Conn) (net.Conn, error) {
 	err = tlsConn.Handshake()
 
 	if err != nil {
-		ps.logger.Error(Emoji+"failed to complete TLS handshake with the client with error: ", zap.Error(err))
+		logger.Error(Emoji+"failed to complete TLS handshake with the client with error: ", zap.Error(err))
 		return nil, err
 	}
 	// Use the tlsConn for further communication
@@ -790,6 +996,7 @@ func (ps *ProxySet) handleConnection(conn net.Conn, port uint32, ctx context.Con
 		ps.logger.Debug("Inside handleConnection: Got source port == proxy port", zap.Int("Source port", sourcePort), zap.Int("Proxy port", int(ps.Port)))
 		return
 	}
+	metrics.ProxyConnections.Inc()
 
 	destInfo, err := ps.hook.GetDestinationInfo(uint16(sourcePort))
 	if err != nil {
@@ -805,25 +1012,44 @@ func (ps *ProxySet) handleConnection(conn net.Conn, port uint32, ctx context.Con
 
 	// releases the occupied source port when done fetching the destination info
 	ps.hook.CleanProxyEntry(uint16(sourcePort))
-	//checking for the destination port of mysql
-	if destInfo.DestPort == 3306 {
+	ps.handleOutgoing(conn, destInfo, port, ctx, start)
+}
+
+// handleOutgoing records or mocks the outgoing call of the connection to its destination, through the parser of its
+// protocol.
+func (ps *ProxySet) handleOutgoing(conn net.Conn, destInfo *structs.DestInfo, port uint32, ctx context.Context, start time.Time) {
+	var err error
+	// the mocks of the connection hold the address of its destination, IPv4 or IPv6
+	ctx = context.WithValue(ctx, "destination", destinationAddress(destInfo))
+	// the mocks of the connection hold its id, for the connection-sequence policy
+	ctx = context.WithValue(ctx, models.ConnectionMetadata, uuid.NewString())
+
+	// the calls selected by the pass through rules are forwarded untouched, the TLS ones without being decrypted
+	passThrough, passThroughPorts := ps.passThroughRules()
+	if passThrough.matches(destinationIP(destInfo), destInfo.DestPort, serverFirstPorts[destInfo.DestPort]) {
+		ps.passThroughConnection(conn, destInfo)
+		conn.Close()
+		return
+	}
+	//checking for the destination port of the protocols where the server speaks first
+	if parserName, ok := serverFirstPorts[destInfo.DestPort]; ok {
 		var dst net.Conn
-		var actualAddress = ""
-		if destInfo.IpVersion == 4 {
-			actualAddress = fmt.Sprintf("%v:%v", util.ToIP4AddressStr(destInfo.DestIp4), destInfo.DestPort)
-		} else if destInfo.IpVersion == 6 {
-			actualAddress = fmt.Sprintf("[%v]:%v", util.ToIPv6AddressStr(destInfo.DestIp6), destInfo.DestPort)
-		}
+		var actualAddress = destinationAddress(destInfo)
 		if models.GetMode() != models.MODE_TEST {
 			dst, err = net.Dial("tcp", actualAddress)
 			if err != nil {
 				ps.logger.Error(Emoji+"failed to dial the connection to destination server", zap.Error(err), zap.Any("proxy port", port), zap.Any("server address", actualAddress))
 				conn.Close()
 				return
-				// }
 			}
 		}
-		ParsersMap["mysql"].ProcessOutgoing([]byte{}, conn, dst, ctx)
+		parser, ok := integrations.Get(parserName)
+		if !ok {
+			ps.logger.Error("the parser is not registered", zap.String("parser", parserName))
+			conn.Close()
+			return
+		}
+		parser.ProcessOutgoing([]byte{}, conn, dst, ctx)
 
 	} else {
 		clientConnId := getNextID()
@@ -841,6 +1067,8 @@ func (ps *ProxySet) handleConnection(conn net.Conn, port uint32, ctx context.Con
 		}
 
 		isTLS := isTLSHandshake(testBuffer)
+		// the session with the destination server started during the TLS handshake of the client, if any
+		var upstream net.Conn
 		multiReader := io.MultiReader(reader, conn)
 		conn = &CustomConn{
 			Conn:   conn,
@@ -848,15 +1076,24 @@ func (ps *ProxySet) handleConnection(conn net.Conn, port uint32, ctx context.Con
 			logger: ps.logger,
 		}
 		if isTLS {
-			conn, err = ps.handleTLSConnection(conn)
+			conn, upstream, err = ps.handleTLSConnection(conn, destInfo)
 			if err != nil {
 				ps.logger.Error("failed to handle TLS connection", zap.Error(err))
 				return
 			}
+			if upstream != nil {
+				// the session with the server ends with the call, even when the call ends before it's used
+				defer upstream.Close()
+			}
 		}
 		// attempt to read the conn until buffer is either filled or connection is closed
 		var buffer []byte
 		buffer, err = util.ReadBytes(conn)
+		// the calls starting with a message larger than the max message size are passed through once dialed
+		tooLarge := errors.Is(err, util.ErrMessageTooLarge)
+		if tooLarge {
+			err = nil
+		}
 		if err != nil && err != io.EOF {
 			ps.logger.Error("failed to read the request message in proxy", zap.Error(err), zap.Any("proxy port", port))
 			return
@@ -876,17 +1113,15 @@ func (ps *ProxySet) handleConnection(conn net.Conn, port uint32, ctx context.Con
 
 		// dst stores the connection with actual destination for the outgoing network call
 		var dst net.Conn
-		var actualAddress = ""
-		if destInfo.IpVersion == 4 {
-			actualAddress = fmt.Sprintf("%v:%v", util.ToIP4AddressStr(destInfo.DestIp4), destInfo.DestPort)
-		} else if destInfo.IpVersion == 6 {
-			actualAddress = fmt.Sprintf("[%v]:%v", util.ToIPv6AddressStr(destInfo.DestIp6), destInfo.DestPort)
-		}
+		var actualAddress = destinationAddress(destInfo)
 
 		//Dialing for tls connection
 		destConnId := getNextID()
 		logger := ps.logger.With(zap.Any("Client IP Address", conn.RemoteAddr().String()), zap.Any("Client ConnectionID", clientConnId), zap.Any("Destination IP Address", actualAddress), zap.Any("Destination ConnectionID", destConnId))
-		if isTLS {
+		if upstream != nil {
+			logger.Debug("", zap.Any("isTLS", isTLS))
+			dst = upstream
+		} else if isTLS {
 			logger.Debug("", zap.Any("isTLS", isTLS))
 			config := &tls.Config{
 				InsecureSkipVerify: false,
@@ -907,26 +1142,47 @@ func (ps *ProxySet) handleConnection(conn net.Conn, port uint32, ctx context.Con
 			}
 		}
 
-		for _, port := range ps.PassThroughPorts {
+		if tooLarge {
+			logger.Warn("passing through the outgoing call, its first message being larger than the max message size", zap.Int("size", len(buffer)))
+			if dst == nil {
+				logger.Error("failed to pass through the outgoing call, the destination server isn't reachable", zap.Any("server address", actualAddress))
+			} else if err := ps.callNext(buffer, conn, dst, logger); err != nil {
+				logger.Error("failed to pass through the outgoing call", zap.Error(err))
+			}
+			conn.Close()
+			return
+		}
+
+		for _, port := range passThroughPorts {
 			if port == uint(destInfo.DestPort) {
 				err = ps.callNext(buffer, conn, dst, logger)
 				if err != nil {
 					logger.Error("failed to pass through the outgoing call", zap.Error(err), zap.Any("for port", port))
 					return
 				}
+				conn.Close()
+				return
 			}
 		}
-		genericCheck := true
-		//Checking for all the parsers.
-		for _, parser := range ParsersMap {
-			if parser.OutgoingType(buffer) {
-				parser.ProcessOutgoing(buffer, conn, dst, ctx)
-				genericCheck = false
-			}
+		//Checking for the parsers in the priority order of the detection pipeline.
+		parserName, parser, ok := integrations.Match(buffer)
+		protocol := parserName
+		if !ok {
+			protocol = "generic"
 		}
-		if genericCheck {
+		if passThrough.hasProtocolRules() && passThrough.matches(destinationIP(destInfo), destInfo.DestPort, protocol) {
+			logger.Debug("passing through the outgoing call selected by its protocol", zap.String("protocol", protocol))
+			if dst == nil {
+				logger.Error("failed to pass through the outgoing call, the destination server isn't reachable", zap.Any("server address", actualAddress))
+			} else if err := ps.callNext(buffer, conn, dst, logger); err != nil {
+				logger.Error("failed to pass through the outgoing call", zap.Error(err), zap.String("protocol", protocol))
+			}
+		} else if ok {
+			logger.Debug("the external dependency is handled by a registered parser", zap.String("parser", parserName))
+			parser.ProcessOutgoing(buffer, conn, dst, ctx)
+		} else {
 			logger.Debug("The external dependency is not supported. Hence using generic parser")
-			genericparser.ProcessGeneric(buffer, conn, dst, ps.hook, logger, ctx)
+			genericparser.ProcessGeneric(buffer, conn, dst, ps.hook, logger.Named(logging.Parser("generic")), ctx)
 		}
 	}
 
@@ -942,10 +1198,6 @@ func (ps *ProxySet) callNext(requestBuffer []byte, clientConn, destConn net.Conn
 
 	defer destConn.Close()
 
-	// channels for writing messages from proxy to destination or client
-	destinationWriteChannel := make(chan []byte)
-	clientWriteChannel := make(chan []byte)
-
 	if requestBuffer != nil {
 		_, err := destConn.Write(requestBuffer)
 		if err != nil {
@@ -954,50 +1206,27 @@ func (ps *ProxySet) callNext(requestBuffer []byte, clientConn, destConn net.Conn
 		}
 	}
 
-	for {
-		// go routine to read from client
-		go func() {
-			defer ps.hook.Recover(pkg.GenerateRandomID())
-			defer utils.HandlePanic()
-			buffer, err := util.ReadBytes(clientConn)
-			if err != nil {
-				logger.Error("failed to read the request from client in proxy", zap.Error(err), zap.Any("Client Addr", clientConn.RemoteAddr().String()))
-				return
-			}
-			destinationWriteChannel <- buffer
-		}()
-
-		// go routine to read from destination
-		go func() {
-			defer ps.hook.Recover(pkg.GenerateRandomID())
-			defer utils.HandlePanic()
-			buffer, err := util.ReadBytes(destConn)
-			if err != nil {
-				logger.Error("failed to read the response from destination in proxy", zap.Error(err), zap.Any("Destination Addr", destConn.RemoteAddr().String()))
-				return
-			}
-
-			clientWriteChannel <- buffer
-		}()
-
-		select {
-		case requestBuffer := <-destinationWriteChannel:
-			// Write the request message to the actual destination server
-			_, err := destConn.Write(requestBuffer)
-			if err != nil {
-				logger.Error("failed to write request message to the destination server", zap.Error(err), zap.Any("Destination Addr", destConn.RemoteAddr().String()))
-				return err
-			}
-		case responseBuffer := <-clientWriteChannel:
-			// Write the response message to the client
-			_, err := clientConn.Write(responseBuffer)
-			if err != nil {
-				logger.Error("failed to write response to the client", zap.Error(err), zap.Any("Client Addr", clientConn.RemoteAddr().String()))
-				return err
-			}
-		}
+	// the bytes are copied each way through a pooled buffer, bounded by the max buffer, or spliced by the kernel
+	// between the TCP connections, until one of the sides closes its connection
+	errChannel := make(chan error, 2)
+	forward := func(dst, src net.Conn) {
+		defer ps.hook.Recover(pkg.GenerateRandomID())
+		defer utils.HandlePanic()
+		buf := util.GetBuffer()
+		defer util.PutBuffer(buf)
+		n, err := util.Forward(dst, src, *buf, ps.maxBuffer)
+		metrics.ProxyBytes.Add(float64(n))
+		errChannel <- err
 	}
+	go forward(destConn, clientConn)
+	go forward(clientConn, destConn)
 
+	err := <-errChannel
+	if err != nil && !errors.Is(err, net.ErrClosed) && !errors.Is(err, io.EOF) {
+		logger.Error("failed to forward the messages between the client and the destination server", zap.Error(err), zap.Any("Client Addr", clientConn.RemoteAddr().String()), zap.Any("Destination Addr", destConn.RemoteAddr().String()))
+		return err
+	}
+	return nil
 }
 
 func (ps *ProxySet) StopProxyServer() {
@@ -1013,6 +1242,7 @@ func (ps *ProxySet) StopProxyServer() {
 			ps.logger.Error("failed to stop proxy server", zap.Error(err))
 		}
 	}
+	ps.stopUnixSockets()
 
 	// stop dns server only in case of test mode.
 	if ps.DnsServer != nil {
@@ -1022,5 +1252,8 @@ func (ps *ProxySet) StopProxyServer() {
 		}
 		ps.logger.Info("Dns server stopped")
 	}
+	if flow := util.GetFlowMetrics(); flow.BufferLimitHits > 0 {
+		ps.logger.Info("the calls passed through were held back at the max buffer of the proxy", zap.Uint64("bufferLimitHits", flow.BufferLimitHits), zap.Int("maxBuffer", ps.maxBuffer), zap.Int64("peakBufferedBytes", flow.PeakBufferedBytes))
+	}
 	ps.logger.Info("proxy stopped...")
 }
@@ -0,0 +1,50 @@
+package proxy
+
+import (
+	"bytes"
+	"io"
+	"net"
+	"strconv"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+// BenchmarkCallNext passes a call through the proxy, the destination answering the request before closing its
+// connection. The bytes are forwarded through the pooled buffers, so the allocations of a call don't grow with its
+// size, whereas each message was read whole in a new buffer before.
+func BenchmarkCallNext(b *testing.B) {
+	request := bytes.Repeat([]byte("q"), 512)
+	for _, maxBuffer := range []int{0, 4096} {
+		for _, size := range []int{1024, 64 * 1024, 1024 * 1024} {
+			response := bytes.Repeat([]byte("r"), size)
+			b.Run("maxBuffer="+strconv.Itoa(maxBuffer)+"/"+strconv.Itoa(size), func(b *testing.B) {
+				ps := &ProxySet{logger: zap.NewNop(), maxBuffer: maxBuffer}
+				b.ReportAllocs()
+				b.SetBytes(int64(len(request) + size))
+				for i := 0; i < b.N; i++ {
+					app, clientConn := net.Pipe()
+					destConn, server := net.Pipe()
+					go func() {
+						io.ReadFull(server, make([]byte, len(request)))
+						server.Write(response)
+						server.Close()
+					}()
+					received := make(chan int64)
+					go func() {
+						n, _ := io.Copy(io.Discard, app)
+						received <- n
+					}()
+					if err := ps.callNext(request, clientConn, destConn, ps.logger); err != nil {
+						b.Fatal(err)
+					}
+					app.Close()
+					if n := <-received; n != int64(size) {
+						b.Fatalf("received %d bytes of %d", n, size)
+					}
+					clientConn.Close()
+				}
+			})
+		}
+	}
+}
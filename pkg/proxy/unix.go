@@ -0,0 +1,151 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"strings"
+
+	"github.com/google/uuid"
+	"go.keploy.io/server/pkg"
+	"go.keploy.io/server/pkg/hooks/preload"
+	"go.keploy.io/server/pkg/models"
+	"go.keploy.io/server/pkg/proxy/integrations"
+	genericparser "go.keploy.io/server/pkg/proxy/integrations/genericParser"
+	"go.keploy.io/server/pkg/proxy/util"
+	"go.keploy.io/server/utils"
+	"go.uber.org/zap"
+)
+
+// startUnixSockets listens on the sockets which the library of preload.Unix redirects the connections of the
+// application to the unix sockets, one per socket.
+func (ps *ProxySet) startUnixSockets(sockets []models.UnixSocket) {
+	if len(sockets) == 0 {
+		return
+	}
+	if err := preload.MakeUnixSocketsDir(); err != nil {
+		ps.logger.Error("failed to create the directory of the unix sockets of the proxy", zap.Error(err), zap.String("dir", preload.UnixSocketsDir()))
+		return
+	}
+	for _, socket := range sockets {
+		redirect := preload.UnixSocketRedirect(socket.Path)
+		os.Remove(redirect)
+		listener, err := net.Listen("unix", redirect)
+		if err != nil {
+			ps.logger.Error("failed to listen for the unix socket", zap.Error(err), zap.String("socket", socket.Path))
+			continue
+		}
+		// the application is run as the user who invoked sudo
+		os.Chmod(redirect, 0777)
+		ps.unixListeners = append(ps.unixListeners, listener)
+		ps.logger.Debug("capturing the unix socket", zap.String("socket", socket.Path), zap.String("redirect", redirect))
+		go func(socket models.UnixSocket) {
+			defer ps.hook.Recover(pkg.GenerateRandomID())
+			defer utils.HandlePanic()
+			for {
+				conn, err := listener.Accept()
+				if err != nil {
+					if !strings.Contains(err.Error(), "use of closed network connection") {
+						ps.logger.Error("failed to accept the connection to the unix socket", zap.Error(err), zap.String("socket", socket.Path))
+					}
+					return
+				}
+				go func() {
+					defer ps.hook.Recover(pkg.GenerateRandomID())
+					defer utils.HandlePanic()
+					ps.handleUnixConnection(conn, socket)
+				}()
+			}
+		}(socket)
+	}
+}
+
+// handleUnixConnection passes the connection of the application to the unix socket through the parser of its
+// protocol, the destination of its mocks being the path of the socket prefixed with "unix:".
+func (ps *ProxySet) handleUnixConnection(conn net.Conn, socket models.UnixSocket) {
+	defer conn.Close()
+	ctx := context.WithValue(ps.ctx, "destination", "unix:"+socket.Path)
+	ctx = context.WithValue(ctx, models.ConnectionMetadata, uuid.NewString())
+	logger := ps.logger.With(zap.String("socket", socket.Path))
+
+	var dst net.Conn
+	if models.GetMode() != models.MODE_TEST {
+		var err error
+		dst, err = net.Dial("unix", socket.Path)
+		if err != nil {
+			logger.Error("failed to dial the unix socket", zap.Error(err))
+			return
+		}
+	}
+
+	protocol := strings.ToLower(socket.Protocol)
+	if protocol != "" && protocol != "generic" {
+		parser, ok := integrations.Get(protocol)
+		if !ok {
+			logger.Error("the parser is not registered", zap.String("parser", protocol))
+			return
+		}
+		if isServerFirst(protocol) {
+			parser.ProcessOutgoing([]byte{}, conn, dst, ctx)
+			return
+		}
+	}
+
+	buffer, err := util.ReadBytes(conn)
+	if errors.Is(err, util.ErrMessageTooLarge) {
+		logger.Warn("passing through the call, its first message being larger than the max message size", zap.Int("size", len(buffer)))
+		if dst == nil {
+			logger.Error("failed to pass through the call, the unix socket isn't reachable")
+		} else if err := ps.callNext(buffer, conn, dst, logger); err != nil {
+			logger.Error("failed to pass through the call", zap.Error(err))
+		}
+		return
+	}
+	if err != nil && err != io.EOF {
+		logger.Error("failed to read the request message in proxy", zap.Error(err))
+		return
+	}
+	if len(buffer) == 0 {
+		logger.Debug("received EOF, closing connection")
+		return
+	}
+	if protocol == "generic" {
+		genericparser.ProcessGeneric(buffer, conn, dst, ps.hook, logger, ctx)
+		return
+	}
+	if protocol != "" {
+		parser, _ := integrations.Get(protocol)
+		parser.ProcessOutgoing(buffer, conn, dst, ctx)
+		return
+	}
+	if parserName, parser, ok := integrations.Match(buffer); ok {
+		logger.Debug("the external dependency is handled by a registered parser", zap.String("parser", parserName))
+		parser.ProcessOutgoing(buffer, conn, dst, ctx)
+		return
+	}
+	logger.Debug("The external dependency is not supported. Hence using generic parser")
+	genericparser.ProcessGeneric(buffer, conn, dst, ps.hook, logger, ctx)
+}
+
+// isServerFirst reports whether the server speaks first in the protocol of the parser.
+func isServerFirst(parserName string) bool {
+	for _, name := range serverFirstPorts {
+		if name == parserName {
+			return true
+		}
+	}
+	return false
+}
+
+// stopUnixSockets closes the listeners of the unix sockets, which removes their files, and their directory.
+func (ps *ProxySet) stopUnixSockets() {
+	if len(ps.unixListeners) == 0 {
+		return
+	}
+	for _, listener := range ps.unixListeners {
+		listener.Close()
+	}
+	os.Remove(preload.UnixSocketsDir())
+}
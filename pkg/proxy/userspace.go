@@ -0,0 +1,230 @@
+package proxy
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.keploy.io/server/pkg/hooks"
+	"go.keploy.io/server/pkg/hooks/structs"
+	"go.keploy.io/server/pkg/models"
+	"go.keploy.io/server/pkg/proxy/util"
+	"go.uber.org/zap"
+)
+
+// the SOCKS5 protocol, RFC 1928
+const (
+	socks5Version     = 0x05
+	socks5NoAuth      = 0x00
+	socks5Connect     = 0x01
+	socks5IPv4        = 0x01
+	socks5Domain      = 0x03
+	socks5IPv6        = 0x04
+	socks5Succeeded   = 0x00
+	socks5Unsupported = 0x07
+)
+
+// handleProxyRequest handles the connection of the application to the proxy in the userspace capture, where the
+// eBPF hooks don't redirect its calls: the application sends them through the proxy, set as its SOCKS5 and HTTP
+// proxy, which reads their destination from the request of the proxy protocol.
+func (ps *ProxySet) handleProxyRequest(conn net.Conn, port uint32, ctx context.Context) {
+	start := time.Now()
+	reader := bufio.NewReader(conn)
+	first, err := reader.Peek(1)
+	if err != nil {
+		ps.logger.Debug("received EOF, closing connection", zap.Error(err))
+		conn.Close()
+		return
+	}
+
+	var host string
+	var destPort uint32
+	if first[0] == socks5Version {
+		host, destPort, err = ps.readSocks5Request(reader, conn)
+	} else {
+		host, destPort, err = ps.readHttpProxyRequest(reader, conn)
+	}
+	if err != nil {
+		ps.logger.Error("failed to read the destination of the call sent through the proxy", zap.Error(err))
+		conn.Close()
+		return
+	}
+
+	destInfo, err := ps.resolveDestination(host, destPort)
+	if err != nil {
+		ps.logger.Error("failed to resolve the destination of the call sent through the proxy", zap.String("host", host), zap.Error(err))
+		conn.Close()
+		return
+	}
+	ps.logger.Debug("the call is sent through the proxy", zap.String("host", host), zap.Uint32("port", destPort))
+	conn = &CustomConn{
+		Conn:   conn,
+		r:      io.MultiReader(reader, conn),
+		logger: ps.logger,
+	}
+	ps.handleOutgoing(conn, destInfo, port, ctx, start)
+}
+
+// readSocks5Request negotiates the SOCKS5 connection without authentication and returns the destination of its
+// CONNECT request.
+func (ps *ProxySet) readSocks5Request(reader *bufio.Reader, conn net.Conn) (string, uint32, error) {
+	header := make([]byte, 2)
+	if _, err := io.ReadFull(reader, header); err != nil {
+		return "", 0, err
+	}
+	if _, err := io.ReadFull(reader, make([]byte, header[1])); err != nil {
+		return "", 0, err
+	}
+	if _, err := conn.Write([]byte{socks5Version, socks5NoAuth}); err != nil {
+		return "", 0, err
+	}
+
+	request := make([]byte, 4)
+	if _, err := io.ReadFull(reader, request); err != nil {
+		return "", 0, err
+	}
+	var host string
+	switch request[3] {
+	case socks5IPv4, socks5IPv6:
+		ip := make([]byte, net.IPv4len)
+		if request[3] == socks5IPv6 {
+			ip = make([]byte, net.IPv6len)
+		}
+		if _, err := io.ReadFull(reader, ip); err != nil {
+			return "", 0, err
+		}
+		host = net.IP(ip).String()
+	case socks5Domain:
+		length, err := reader.ReadByte()
+		if err != nil {
+			return "", 0, err
+		}
+		name := make([]byte, length)
+		if _, err := io.ReadFull(reader, name); err != nil {
+			return "", 0, err
+		}
+		host = string(name)
+	default:
+		conn.Write(socks5Reply(socks5Unsupported))
+		return "", 0, fmt.Errorf("unsupported SOCKS5 address type %d", request[3])
+	}
+	portBytes := make([]byte, 2)
+	if _, err := io.ReadFull(reader, portBytes); err != nil {
+		return "", 0, err
+	}
+	if request[1] != socks5Connect {
+		conn.Write(socks5Reply(socks5Unsupported))
+		return "", 0, fmt.Errorf("unsupported SOCKS5 command %d", request[1])
+	}
+	if _, err := conn.Write(socks5Reply(socks5Succeeded)); err != nil {
+		return "", 0, err
+	}
+	return host, uint32(binary.BigEndian.Uint16(portBytes)), nil
+}
+
+// socks5Reply returns the reply to the SOCKS5 request, bound to no address.
+func socks5Reply(status byte) []byte {
+	return []byte{socks5Version, status, 0x00, socks5IPv4, 0, 0, 0, 0, 0, 0}
+}
+
+// readHttpProxyRequest returns the destination of the request sent to the HTTP proxy. The CONNECT request opens a
+// tunnel, usually carrying TLS, and is answered by the proxy. The other requests, which hold the absolute URL of the
+// server, are left in the reader to be forwarded as they are, the servers accepting the absolute URLs.
+func (ps *ProxySet) readHttpProxyRequest(reader *bufio.Reader, conn net.Conn) (string, uint32, error) {
+	line, err := peekLine(reader)
+	if err != nil {
+		return "", 0, err
+	}
+	fields := strings.Fields(string(line))
+	if len(fields) != 3 {
+		return "", 0, fmt.Errorf("invalid request line %q sent to the HTTP proxy", strings.TrimSpace(string(line)))
+	}
+	method, target := fields[0], fields[1]
+
+	if method == http.MethodConnect {
+		if _, err := http.ReadRequest(reader); err != nil {
+			return "", 0, err
+		}
+		host, port, err := splitHostPort(target, "443")
+		if err != nil {
+			return "", 0, err
+		}
+		if _, err := conn.Write([]byte("HTTP/1.1 200 Connection established\r\n\r\n")); err != nil {
+			return "", 0, err
+		}
+		return host, port, nil
+	}
+	u, err := url.Parse(target)
+	if err != nil || u.Host == "" {
+		return "", 0, fmt.Errorf("the request sent to the HTTP proxy doesn't hold the absolute URL of the server: %q", target)
+	}
+	defaultPort := "80"
+	if u.Scheme == "https" {
+		defaultPort = "443"
+	}
+	return splitHostPort(u.Host, defaultPort)
+}
+
+// peekLine returns the first line of the reader, left in it.
+func peekLine(reader *bufio.Reader) ([]byte, error) {
+	for size := 1; ; size = reader.Buffered() + 1 {
+		buf, err := reader.Peek(size)
+		if i := bytes.Index(buf, []byte("\r\n")); i >= 0 {
+			return buf[:i+2], nil
+		}
+		if err != nil {
+			if err == bufio.ErrBufferFull {
+				return nil, errors.New("the request line sent to the HTTP proxy is too long")
+			}
+			return nil, err
+		}
+	}
+}
+
+// splitHostPort splits the host:port address, the port being the default one when it's missing.
+func splitHostPort(address, defaultPort string) (string, uint32, error) {
+	host, portStr, err := net.SplitHostPort(address)
+	if err != nil {
+		host, portStr = address, defaultPort
+	}
+	port, err := strconv.ParseUint(portStr, 10, 16)
+	if err != nil {
+		return "", 0, fmt.Errorf("invalid port in the address %q: %v", address, err)
+	}
+	return host, uint32(port), nil
+}
+
+// resolveDestination returns the destination of the call to the host, which is resolved. In test mode the servers
+// don't have to be reachable, so the hosts which can't be resolved get an unspecified address.
+func (ps *ProxySet) resolveDestination(host string, port uint32) (*structs.DestInfo, error) {
+	ip := net.ParseIP(host)
+	if ip == nil {
+		ips, err := net.LookupIP(host)
+		switch {
+		case err == nil && len(ips) > 0:
+			ip = ips[0]
+		case models.GetMode() == models.MODE_TEST:
+			ip = net.IPv4zero
+		default:
+			return nil, err
+		}
+	}
+	if ip4, ok := util.ConvertToIPV4(ip); ok {
+		return &structs.DestInfo{IpVersion: 4, DestIp4: ip4, DestPort: port}, nil
+	}
+	ip6, err := hooks.ConvertIPv6ToUint32(ip.String())
+	if err != nil {
+		return nil, err
+	}
+	return &structs.DestInfo{IpVersion: 6, DestIp6: ip6, DestPort: port}, nil
+}
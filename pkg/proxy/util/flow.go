@@ -0,0 +1,114 @@
+package util
+
+import (
+	"errors"
+	"io"
+	"sync/atomic"
+
+	"go.keploy.io/server/pkg/metrics"
+)
+
+// The calls passed through are forwarded through a buffer of maxBuffer bytes at most, so that a client (or a server)
+// sending faster than the other side of the proxy reads isn't buffered without bound: each part read is written to
+// the other side before the next one is read, and the sender is held back by TCP until it is. The messages read by
+// the parsers are read whole, since they are recorded and matched as a whole, up to maxMessage bytes.
+var (
+	bufferLimitHits   uint64
+	bufferedBytes     int64
+	peakBufferedBytes int64
+	maxMessage        int64 = DefaultMaxMessage
+)
+
+// DefaultMaxMessage is the max size of the messages read by the parsers when none is configured.
+const DefaultMaxMessage = 64 * 1024 * 1024
+
+// ErrMessageTooLarge is returned by ReadBytes when the message is larger than the max message size, the bytes read
+// so far being returned with it, so that the call can be passed through instead of being recorded or mocked.
+var ErrMessageTooLarge = errors.New("the message is larger than the max message size of the proxy")
+
+// SetMaxMessage sets the max size of the messages read by the parsers, DefaultMaxMessage when n is 0, and no bound
+// when it is negative.
+func SetMaxMessage(n int) {
+	if n == 0 {
+		n = DefaultMaxMessage
+	}
+	atomic.StoreInt64(&maxMessage, int64(n))
+}
+
+// FlowMetrics are the counters of the flow control of the proxy.
+type FlowMetrics struct {
+	// BufferLimitHits is the number of the reads of the forwarded calls which filled the max buffer, the sender
+	// being held back until they were written
+	BufferLimitHits uint64 `json:"bufferLimitHits"`
+	// BufferedBytes is the number of the bytes read and not yet handed over, and PeakBufferedBytes its highest value
+	BufferedBytes     int64 `json:"bufferedBytes"`
+	PeakBufferedBytes int64 `json:"peakBufferedBytes"`
+}
+
+func init() {
+	metrics.Default.NewCounterFunc("keploy_proxy_buffer_limit_hits_total", "Reads of the calls forwarded by the proxy which filled maxBuffer.", func() float64 {
+		return float64(atomic.LoadUint64(&bufferLimitHits))
+	})
+	metrics.Default.NewGaugeFunc("keploy_proxy_buffered_bytes", "Bytes read by the proxy and not yet handed over.", func() float64 {
+		return float64(atomic.LoadInt64(&bufferedBytes))
+	})
+}
+
+// GetFlowMetrics returns the counters of the flow control of the proxy.
+func GetFlowMetrics() FlowMetrics {
+	return FlowMetrics{
+		BufferLimitHits:   atomic.LoadUint64(&bufferLimitHits),
+		BufferedBytes:     atomic.LoadInt64(&bufferedBytes),
+		PeakBufferedBytes: atomic.LoadInt64(&peakBufferedBytes),
+	}
+}
+
+// holdBytes adds the bytes read to the buffered ones, or removes them when n is negative.
+func holdBytes(n int) {
+	held := atomic.AddInt64(&bufferedBytes, int64(n))
+	for {
+		peak := atomic.LoadInt64(&peakBufferedBytes)
+		if held <= peak || atomic.CompareAndSwapInt64(&peakBufferedBytes, peak, held) {
+			return
+		}
+	}
+}
+
+// Forward copies src to dst through buf until src ends, returning the number of the bytes copied. With a positive
+// maxBuffer, at most maxBuffer bytes are read before they are written, buf being resized to it, the reads filling
+// the bound being counted; otherwise the copy is left to io.CopyBuffer, which lets the kernel splice the bytes between TCP connections.
+func Forward(dst io.Writer, src io.Reader, buf []byte, maxBuffer int) (int64, error) {
+	if maxBuffer <= 0 {
+		return io.CopyBuffer(dst, src, buf)
+	}
+	if maxBuffer < len(buf) {
+		buf = buf[:maxBuffer]
+	} else if maxBuffer > len(buf) {
+		buf = make([]byte, maxBuffer)
+	}
+	var written int64
+	for {
+		n, err := src.Read(buf)
+		if n > 0 {
+			if n == len(buf) {
+				atomic.AddUint64(&bufferLimitHits, 1)
+			}
+			holdBytes(n)
+			w, writeErr := dst.Write(buf[:n])
+			holdBytes(-n)
+			written += int64(w)
+			if writeErr != nil {
+				return written, writeErr
+			}
+			if w != n {
+				return written, io.ErrShortWrite
+			}
+		}
+		if err == io.EOF {
+			return written, nil
+		}
+		if err != nil {
+			return written, err
+		}
+	}
+}
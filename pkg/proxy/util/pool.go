@@ -0,0 +1,30 @@
+package util
+
+import (
+	"sync"
+)
+
+// BufferSize is the size of the pooled buffers the proxy reads the connections with.
+const BufferSize = 32 * 1024
+
+var bufferPool = sync.Pool{
+	New: func() interface{} {
+		buf := make([]byte, BufferSize)
+		return &buf
+	},
+}
+
+// GetBuffer returns a buffer of BufferSize bytes from the pool, to be put back with PutBuffer once its bytes are no
+// longer used.
+func GetBuffer() *[]byte {
+	return bufferPool.Get().(*[]byte)
+}
+
+// PutBuffer puts the buffer back in the pool.
+func PutBuffer(buf *[]byte) {
+	if buf == nil || cap(*buf) < BufferSize {
+		return
+	}
+	*buf = (*buf)[:BufferSize]
+	bufferPool.Put(buf)
+}
@@ -19,8 +19,10 @@ import (
 	"net"
 	"strconv"
 	"strings"
+	"sync/atomic"
 
 	"go.keploy.io/server/pkg"
+	"go.keploy.io/server/pkg/metrics"
 	"go.keploy.io/server/pkg/models"
 	"go.keploy.io/server/utils"
 )
@@ -192,19 +194,37 @@ func PeekBytes(reader *bufio.Reader) ([]byte, error) {
 }
 
 // ReadBytes function is utilized to read the complete message from the reader until the end of the file (EOF).
-// It returns the content as a byte array.
+// It returns the content as a byte array. The message is read in a pooled buffer, the byte array returned being the
+// only allocation of the messages read at once.
+//
+// The message ends with the first read which doesn't fill the buffer, the one returning the last of the bytes
+// received so far. A message whose size is a multiple of BufferSize, filling the buffer with its last read, waits for
+// the next read, which returns the following message or the end of the connection. A message growing past the max
+// message size (SetMaxMessage) ends with ErrMessageTooLarge.
 func ReadBytes(reader io.Reader) ([]byte, error) {
 	var buffer []byte
 	const maxEmptyReads = 5
 	emptyReads := 0
 
+	pooled := GetBuffer()
+	defer PutBuffer(pooled)
+	buf := *pooled
+	// the bytes held are released once the message is handed over
+	defer func() { holdBytes(-len(buffer)) }()
 	for {
-		buf := make([]byte, 1024)
 		n, err := reader.Read(buf)
 
 		if n > 0 {
+			if buffer == nil {
+				buffer = make([]byte, 0, n)
+			}
 			buffer = append(buffer, buf[:n]...)
+			holdBytes(n)
+			metrics.ProxyBytes.Add(float64(n))
 			emptyReads = 0 // reset the counter because we got some data
+			if limit := atomic.LoadInt64(&maxMessage); limit > 0 && int64(len(buffer)) > limit {
+				return buffer, ErrMessageTooLarge
+			}
 		}
 
 		if err != nil {
@@ -0,0 +1,55 @@
+package util
+
+import (
+	"bytes"
+	"io"
+	"strconv"
+	"testing"
+)
+
+// readBytesUnpooled reads the message as ReadBytes did before the buffers were pooled, allocating a buffer of 1 KiB
+// for each read.
+func readBytesUnpooled(reader io.Reader) ([]byte, error) {
+	var buffer []byte
+	for {
+		buf := make([]byte, 1024)
+		n, err := reader.Read(buf)
+		buffer = append(buffer, buf[:n]...)
+		if err != nil {
+			return buffer, err
+		}
+		if n < len(buf) {
+			return buffer, nil
+		}
+	}
+}
+
+// BenchmarkReadBytes compares the allocations of the messages read with the pooled buffers and with the buffers of
+// 1 KiB allocated for each read. The size of the message isn't a multiple of 1 KiB, so that neither waits for a read
+// after its end.
+func BenchmarkReadBytes(b *testing.B) {
+	for _, size := range []int{100, 10000, 30000} {
+		message := bytes.Repeat([]byte("k"), size)
+		for _, impl := range []struct {
+			name string
+			read func(io.Reader) ([]byte, error)
+		}{
+			{"pooled", ReadBytes},
+			{"unpooled", readBytesUnpooled},
+		} {
+			read := impl.read
+			b.Run(impl.name+"/"+strconv.Itoa(size), func(b *testing.B) {
+				reader := bytes.NewReader(message)
+				b.ReportAllocs()
+				b.SetBytes(int64(size))
+				for i := 0; i < b.N; i++ {
+					reader.Reset(message)
+					got, err := read(reader)
+					if err != nil || len(got) != size {
+						b.Fatalf("read %d bytes of %d: %v", len(got), size, err)
+					}
+				}
+			})
+		}
+	}
+}
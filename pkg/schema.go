@@ -0,0 +1,165 @@
+package pkg
+
+import (
+	"encoding/json"
+	"sort"
+	"strconv"
+	"strings"
+
+	"go.keploy.io/server/pkg/models"
+)
+
+// InferSchema returns the schema of a JSON body, every property of its objects being required. It returns nil when
+// the body isn't JSON.
+func InferSchema(body string) *models.JSONSchema {
+	value, ok := decodeJson(body)
+	if !ok {
+		return nil
+	}
+	return inferSchema(value)
+}
+
+// ValidateSchema returns the values of a JSON body which don't match the schema.
+func ValidateSchema(schema *models.JSONSchema, body string) []models.SchemaError {
+	value, ok := decodeJson(body)
+	if !ok {
+		return []models.SchemaError{{Path: "body", Expected: "a JSON body", Actual: "a body which isn't JSON"}}
+	}
+	var errs []models.SchemaError
+	validateSchema(schema, value, "body", &errs)
+	sort.Slice(errs, func(i, j int) bool { return errs[i].Path < errs[j].Path })
+	return errs
+}
+
+func decodeJson(body string) (interface{}, bool) {
+	decoder := json.NewDecoder(strings.NewReader(body))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return nil, false
+	}
+	return value, true
+}
+
+func inferSchema(value interface{}) *models.JSONSchema {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		schema := &models.JSONSchema{Type: "object", Properties: map[string]*models.JSONSchema{}}
+		for key, child := range v {
+			schema.Properties[key] = inferSchema(child)
+			schema.Required = append(schema.Required, key)
+		}
+		sort.Strings(schema.Required)
+		return schema
+	case []interface{}:
+		schema := &models.JSONSchema{Type: "array"}
+		for i, item := range v {
+			if i == 0 {
+				schema.Items = inferSchema(item)
+			} else {
+				schema.Items = MergeSchemas(schema.Items, inferSchema(item))
+			}
+		}
+		return schema
+	case string:
+		return &models.JSONSchema{Type: "string"}
+	case json.Number:
+		return &models.JSONSchema{Type: "number"}
+	case bool:
+		return &models.JSONSchema{Type: "boolean"}
+	}
+	// the type of the null values isn't known
+	return &models.JSONSchema{}
+}
+
+// MergeSchemas returns the schema accepting the values of both, like the items of an array. The properties of the
+// objects are required when both require them.
+func MergeSchemas(a, b *models.JSONSchema) *models.JSONSchema {
+	if a.Type != b.Type {
+		return &models.JSONSchema{}
+	}
+	switch a.Type {
+	case "object":
+		merged := &models.JSONSchema{Type: "object", Properties: map[string]*models.JSONSchema{}}
+		for key, schema := range a.Properties {
+			merged.Properties[key] = schema
+		}
+		for key, schema := range b.Properties {
+			if existing, ok := merged.Properties[key]; ok {
+				merged.Properties[key] = MergeSchemas(existing, schema)
+			} else {
+				merged.Properties[key] = schema
+			}
+		}
+		for _, key := range a.Required {
+			for _, other := range b.Required {
+				if key == other {
+					merged.Required = append(merged.Required, key)
+					break
+				}
+			}
+		}
+		return merged
+	case "array":
+		if a.Items == nil || b.Items == nil {
+			items := a.Items
+			if items == nil {
+				items = b.Items
+			}
+			return &models.JSONSchema{Type: "array", Items: items}
+		}
+		return &models.JSONSchema{Type: "array", Items: MergeSchemas(a.Items, b.Items)}
+	}
+	return a
+}
+
+func validateSchema(schema *models.JSONSchema, value interface{}, path string, errs *[]models.SchemaError) {
+	if schema == nil || schema.Type == "" {
+		return
+	}
+	actual := jsonType(value)
+	if actual != schema.Type && !(schema.Type == "integer" && actual == "number" && isInteger(value)) {
+		*errs = append(*errs, models.SchemaError{Path: path, Expected: schema.Type, Actual: actual})
+		return
+	}
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for _, key := range schema.Required {
+			if _, ok := v[key]; !ok {
+				*errs = append(*errs, models.SchemaError{Path: path + "." + key, Expected: "required field", Actual: "missing"})
+			}
+		}
+		for key, child := range v {
+			validateSchema(schema.Properties[key], child, path+"."+key, errs)
+		}
+	case []interface{}:
+		for i, item := range v {
+			validateSchema(schema.Items, item, path+"."+strconv.Itoa(i), errs)
+		}
+	}
+}
+
+func jsonType(value interface{}) string {
+	switch value.(type) {
+	case map[string]interface{}:
+		return "object"
+	case []interface{}:
+		return "array"
+	case string:
+		return "string"
+	case json.Number:
+		return "number"
+	case bool:
+		return "boolean"
+	}
+	return "null"
+}
+
+func isInteger(value interface{}) bool {
+	number, ok := value.(json.Number)
+	if !ok {
+		return false
+	}
+	_, err := number.Int64()
+	return err == nil
+}
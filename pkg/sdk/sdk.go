@@ -0,0 +1,266 @@
+// Package sdk records and replays the calls of the go tests to their dependencies from the tests themselves, like the
+// testcontainers: keploy mockRecord or mockTest is started for the test binary, attached to its process, and stopped
+// when the test ends.
+//
+//	import keploy "go.keploy.io/server/pkg/sdk"
+//
+//	func TestOrders(t *testing.T) {
+//		keploy.Start(t, keploy.WithTestSet("orders"))
+//		// the calls to the database and the other services are recorded, or answered with the recorded mocks
+//	}
+package sdk
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// The modes of a session.
+const (
+	// ModeRecord records the calls of the test to its dependencies as the mocks of the test set
+	ModeRecord = "record"
+	// ModeTest answers the calls of the test with the recorded mocks of the test set
+	ModeTest = "test"
+)
+
+// ModeEnv is the environment variable setting the mode of the sessions started without WithMode. Without it, the
+// test sets are recorded when they have no mocks yet and replayed otherwise.
+const ModeEnv = "KEPLOY_MODE"
+
+// BinaryEnv is the environment variable setting the path of the keploy binary, keploy of the PATH by default.
+const BinaryEnv = "KEPLOY_BIN"
+
+// DefaultProxyPort is the port of the proxy of keploy when none is given.
+const DefaultProxyPort = 16789
+
+// config is the configuration of a session, set by the options.
+type config struct {
+	testSet      string
+	mode         string
+	path         string
+	binary       string
+	proxyPort    uint32
+	sudo         bool
+	startTimeout time.Duration
+	stopTimeout  time.Duration
+}
+
+// Option configures a session.
+type Option func(*config)
+
+// WithTestSet sets the name of the test set of the mocks, the name of the test by default.
+func WithTestSet(name string) Option {
+	return func(c *config) {
+		c.testSet = name
+	}
+}
+
+// WithMode sets the mode of the session, ModeRecord or ModeTest.
+func WithMode(mode string) Option {
+	return func(c *config) {
+		c.mode = mode
+	}
+}
+
+// WithPath sets the directory of the mocks, whose stubs directory holds the test sets, the keploy directory of the
+// working directory of the test by default.
+func WithPath(path string) Option {
+	return func(c *config) {
+		c.path = path
+	}
+}
+
+// WithBinary sets the path of the keploy binary.
+func WithBinary(path string) Option {
+	return func(c *config) {
+		c.binary = path
+	}
+}
+
+// WithProxyPort sets the port of the proxy of keploy, DefaultProxyPort by default.
+func WithProxyPort(port uint32) Option {
+	return func(c *config) {
+		c.proxyPort = port
+	}
+}
+
+// WithSudo runs keploy with sudo, which loading its eBPF hooks needs when the tests don't run as root.
+func WithSudo() Option {
+	return func(c *config) {
+		c.sudo = true
+	}
+}
+
+// WithStartTimeout sets the time keploy is given to be ready, 30 seconds by default.
+func WithStartTimeout(timeout time.Duration) Option {
+	return func(c *config) {
+		c.startTimeout = timeout
+	}
+}
+
+// Session is keploy attached to the test binary, recording or replaying the mocks of a test set.
+type Session struct {
+	// TestSet is the name of the test set of the mocks
+	TestSet string
+	// Mode of the session, ModeRecord or ModeTest
+	Mode string
+	// MocksPath is the path of the file of the mocks of the test set
+	MocksPath string
+
+	cmd         *exec.Cmd
+	done        chan error
+	stopTimeout time.Duration
+}
+
+// Start starts the session of the test, stopped when the test and its subtests end. The test fails when keploy can't
+// be started.
+func Start(t testing.TB, opts ...Option) *Session {
+	t.Helper()
+	opts = append([]Option{WithTestSet(t.Name())}, opts...)
+	s, err := Run(opts...)
+	if err != nil {
+		t.Fatalf("failed to start keploy: %v", err)
+	}
+	t.Cleanup(func() {
+		if err := s.Stop(); err != nil {
+			t.Errorf("failed to stop keploy: %v", err)
+		}
+	})
+	return s
+}
+
+// testSetChars are the characters kept in the names of the test sets, the others being replaced with _.
+var testSetChars = regexp.MustCompile(`[^a-zA-Z0-9_.-]`)
+
+// Run starts a session, which has to be stopped, like in the TestMain of the package. It returns once the proxy of
+// keploy is listening, its hooks being attached to the process of the tests.
+func Run(opts ...Option) (*Session, error) {
+	c := &config{
+		testSet:      "mocks",
+		proxyPort:    DefaultProxyPort,
+		startTimeout: 30 * time.Second,
+		stopTimeout:  30 * time.Second,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	c.testSet = testSetChars.ReplaceAllString(c.testSet, "_")
+
+	if c.path == "" {
+		wd, err := os.Getwd()
+		if err != nil {
+			return nil, err
+		}
+		c.path = filepath.Join(wd, "keploy")
+	}
+	path, err := filepath.Abs(c.path)
+	if err != nil {
+		return nil, err
+	}
+	mocksPath := filepath.Join(path, "stubs", c.testSet+".yaml")
+
+	if c.mode == "" {
+		c.mode = os.Getenv(ModeEnv)
+	}
+	if c.mode == "" {
+		c.mode = ModeTest
+		if _, err := os.Stat(mocksPath); errors.Is(err, os.ErrNotExist) {
+			c.mode = ModeRecord
+		}
+	}
+	command := "mockTest"
+	switch c.mode {
+	case ModeRecord:
+		command = "mockRecord"
+	case ModeTest:
+		if _, err := os.Stat(mocksPath); err != nil {
+			return nil, fmt.Errorf("the mocks of the test set %s can't be replayed: %v", c.testSet, err)
+		}
+	default:
+		return nil, fmt.Errorf("unknown mode %q, the modes are %s and %s", c.mode, ModeRecord, ModeTest)
+	}
+
+	if c.binary == "" {
+		c.binary = os.Getenv(BinaryEnv)
+	}
+	if c.binary == "" {
+		c.binary = "keploy"
+	}
+	args := []string{command, "--pid", strconv.Itoa(os.Getpid()), "--path", path, "--mockName", c.testSet, "--proxyport", strconv.FormatUint(uint64(c.proxyPort), 10)}
+	name := c.binary
+	if c.sudo {
+		name, args = "sudo", append([]string{"-E", c.binary}, args...)
+	}
+	cmd := exec.Command(name, args...)
+	cmd.Stdout = os.Stderr
+	cmd.Stderr = os.Stderr
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to run %s: %v", strings.Join(cmd.Args, " "), err)
+	}
+
+	s := &Session{
+		TestSet:     c.testSet,
+		Mode:        c.mode,
+		MocksPath:   mocksPath,
+		cmd:         cmd,
+		done:        make(chan error, 1),
+		stopTimeout: c.stopTimeout,
+	}
+	go func() {
+		s.done <- cmd.Wait()
+	}()
+	if err := s.waitReady(c.proxyPort, c.startTimeout); err != nil {
+		s.Stop()
+		return nil, err
+	}
+	return s, nil
+}
+
+// waitReady waits for the proxy of keploy to listen, which it does once its hooks are loaded.
+func (s *Session) waitReady(port uint32, timeout time.Duration) error {
+	address := net.JoinHostPort("127.0.0.1", strconv.FormatUint(uint64(port), 10))
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		select {
+		case err := <-s.done:
+			s.done <- err
+			return fmt.Errorf("keploy exited before being ready: %v", err)
+		default:
+		}
+		if conn, err := net.DialTimeout("tcp", address, time.Second); err == nil {
+			conn.Close()
+			return nil
+		}
+		time.Sleep(200 * time.Millisecond)
+	}
+	return fmt.Errorf("the proxy of keploy isn't listening on %s after %v", address, timeout)
+}
+
+// Stop stops keploy, which writes the recorded mocks, killing it when it doesn't exit in time.
+func (s *Session) Stop() error {
+	if err := s.cmd.Process.Signal(os.Interrupt); err != nil && !errors.Is(err, os.ErrProcessDone) {
+		return err
+	}
+	select {
+	case err := <-s.done:
+		s.done <- err
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && !exitErr.Exited() {
+			// interrupted
+			return nil
+		}
+		return err
+	case <-time.After(s.stopTimeout):
+		s.cmd.Process.Kill()
+		return fmt.Errorf("keploy didn't exit %v after being interrupted", s.stopTimeout)
+	}
+}
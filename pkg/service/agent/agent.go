@@ -0,0 +1,232 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"syscall"
+	"time"
+
+	"go.keploy.io/server/pkg/metrics"
+	"go.keploy.io/server/pkg/platform/kube"
+	"go.uber.org/zap"
+)
+
+// stopTimeout is the time given to the keploy processes to write their recordings and reports once interrupted.
+const stopTimeout = 30 * time.Second
+
+type agent struct {
+	logger *zap.Logger
+	// lastTest is the application tested last, which isn't tested again until it or its mode change
+	lastTest target
+	metrics  *agentMetrics
+}
+
+func NewAgent(logger *zap.Logger) Agent {
+	return &agent{
+		logger:  logger,
+		metrics: newAgentMetrics(),
+	}
+}
+
+// target is the application of a pod recorded or tested by the agent.
+type target struct {
+	pod       string
+	uid       string
+	container string
+	pid       uint32
+	mode      string
+}
+
+// session is the keploy record or test process run by the agent for its target.
+type session struct {
+	target  target
+	cmd     *exec.Cmd
+	done    chan error
+	started time.Time
+}
+
+func (a *agent) Run(options Options) error {
+	client, err := kube.NewInClusterClient()
+	if err != nil {
+		a.logger.Error("failed to connect to the API server of the cluster", zap.Error(err))
+		return err
+	}
+	if options.Storage == nil {
+		a.logger.Warn("no storage is configured, the test sets are only kept in the keploy directory of the agent", zap.String("path", filepath.Join(options.Path, "keploy")))
+	}
+	if options.MetricsAddr != "" {
+		metrics.Serve(options.MetricsAddr, a.metrics.handler(a.logger), a.logger)
+	}
+
+	stopper := make(chan os.Signal, 1)
+	signal.Notify(stopper, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(stopper)
+	ticker := time.NewTicker(options.PollInterval)
+	defer ticker.Stop()
+
+	var current *session
+	for {
+		next, err := a.findTarget(client, options)
+		if err != nil {
+			// the running session is kept when the pods can't be read
+			a.logger.Warn("failed to read the pods of the selector", zap.String("selector", options.Selector), zap.Error(err))
+		} else {
+			current = a.reconcile(current, next, options)
+		}
+
+		var done chan error
+		if current != nil {
+			done = current.done
+		}
+		select {
+		case <-stopper:
+			if current != nil {
+				a.stop(current, options)
+			}
+			return nil
+		case err := <-done:
+			if err != nil {
+				a.logger.Warn(fmt.Sprintf("keploy %s of the pod stopped", current.target.mode), zap.String("pod", current.target.pod), zap.Error(err))
+			}
+			a.finish(current, options)
+			current = nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// findTarget returns the application of the first pod of the selector, by name, whose process is found, nil when
+// there is none.
+func (a *agent) findTarget(client *kube.Client, options Options) (*target, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	pods, err := client.ListPods(ctx, options.Namespace, options.Selector, options.Node)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(pods, func(i, j int) bool { return pods[i].Name < pods[j].Name })
+	for _, pod := range pods {
+		if pod.Phase != "Running" {
+			continue
+		}
+		pid, container, err := containerProcess(pod, options.Container)
+		if err != nil {
+			a.logger.Debug("skipping the pod", zap.String("pod", pod.Name), zap.Error(err))
+			continue
+		}
+		mode, ok := pod.Annotations[ModeAnnotation]
+		if !ok {
+			mode = options.Mode
+		}
+		if mode != ModeRecord && mode != ModeTest && mode != ModeOff {
+			a.logger.Warn(fmt.Sprintf("unknown mode in the %s annotation of the pod, leaving it alone", ModeAnnotation), zap.String("pod", pod.Name), zap.String("mode", mode))
+			mode = ModeOff
+		}
+		return &target{pod: pod.Name, uid: pod.UID, container: container, pid: pid, mode: mode}, nil
+	}
+	return nil, nil
+}
+
+// reconcile stops the session when its target is gone, restarted or its mode changed, and when its recording lasted
+// the session duration, then starts the one of the next target. The test of a target is only run once.
+func (a *agent) reconcile(current *session, next *target, options Options) *session {
+	if current != nil {
+		switch {
+		case next == nil || *next != current.target:
+			a.logger.Info(fmt.Sprintf("stopping keploy %s, its application changed", current.target.mode), zap.String("pod", current.target.pod))
+			a.stop(current, options)
+			current = nil
+		case current.target.mode == ModeRecord && options.SessionDuration > 0 && time.Since(current.started) >= options.SessionDuration:
+			a.logger.Info("starting a new test set, the recording lasted the session duration", zap.String("pod", current.target.pod), zap.Duration("duration", options.SessionDuration))
+			a.stop(current, options)
+			current = nil
+		default:
+			return current
+		}
+	}
+	if next == nil || next.mode == ModeOff || (next.mode == ModeTest && *next == a.lastTest) {
+		return nil
+	}
+	started, err := a.start(*next, options)
+	if err != nil {
+		a.logger.Error(fmt.Sprintf("failed to start keploy %s", next.mode), zap.String("pod", next.pod), zap.Error(err))
+		return nil
+	}
+	return started
+}
+
+// start runs keploy record or test attached to the process of the target.
+func (a *agent) start(t target, options Options) (*session, error) {
+	executable, err := os.Executable()
+	if err != nil {
+		return nil, err
+	}
+	args := []string{t.mode, "--pid", strconv.FormatUint(uint64(t.pid), 10), "--path", options.Path, "--config-path", options.ConfigPath}
+	args = append(args, options.Args...)
+	// the keploy process serves its metrics on a local port, read by the ones of the agent
+	var metricsAddr string
+	if options.MetricsAddr != "" {
+		if metricsAddr, err = freeLocalAddr(); err != nil {
+			a.logger.Warn("failed to find a free port for the metrics of the session, serving the ones of the agent alone", zap.Error(err))
+		} else {
+			args = append(args, "--metrics-addr", metricsAddr)
+		}
+	}
+	cmd := exec.Command(executable, args...)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	a.logger.Info(fmt.Sprintf("started keploy %s", t.mode), zap.String("pod", t.pod), zap.String("container", t.container), zap.Uint32("pid", t.pid))
+	a.metrics.sessions.Inc(t.mode)
+	a.metrics.setSession(metricsAddr)
+
+	s := &session{target: t, cmd: cmd, done: make(chan error, 1), started: time.Now()}
+	go func() {
+		s.done <- cmd.Wait()
+	}()
+	return s, nil
+}
+
+// stop interrupts the keploy process of the session, killing it when it doesn't exit in time, and finishes it.
+func (a *agent) stop(s *session, options Options) {
+	if err := s.cmd.Process.Signal(os.Interrupt); err != nil && !errors.Is(err, os.ErrProcessDone) {
+		a.logger.Error("failed to interrupt keploy", zap.Error(err))
+	}
+	select {
+	case <-s.done:
+	case <-time.After(stopTimeout):
+		a.logger.Warn(fmt.Sprintf("keploy %s didn't exit in time, killing it", s.target.mode), zap.String("pod", s.target.pod))
+		s.cmd.Process.Kill()
+		<-s.done
+	}
+	a.finish(s, options)
+}
+
+// finish pushes the test sets to the storage after a recording, and remembers the target after a test.
+func (a *agent) finish(s *session, options Options) {
+	a.metrics.setSession("")
+	switch s.target.mode {
+	case ModeRecord:
+		if options.Storage == nil {
+			return
+		}
+		if err := options.Storage.Push(filepath.Join(options.Path, "keploy"), nil); err != nil {
+			a.logger.Error("failed to push the recorded test sets to the storage", zap.Error(err))
+			a.metrics.pushes.Inc("failure")
+			return
+		}
+		a.metrics.pushes.Inc("success")
+		a.logger.Info("pushed the recorded test sets to the storage", zap.String("pod", s.target.pod))
+	case ModeTest:
+		a.lastTest = s.target
+	}
+}
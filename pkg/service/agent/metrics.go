@@ -0,0 +1,94 @@
+package agent
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"go.keploy.io/server/pkg/metrics"
+	"go.uber.org/zap"
+)
+
+// scrapeTimeout bounds the read of the metrics of the keploy process of the session.
+const scrapeTimeout = 5 * time.Second
+
+// agentMetrics are the metrics of the agent itself, written before the ones of the keploy process of its session,
+// which serves the metrics of the recording or of the test run on a local port.
+type agentMetrics struct {
+	registry *metrics.Registry
+	sessions *metrics.Counter
+	pushes   *metrics.Counter
+	mutex    sync.Mutex
+	// session is the address of the metrics of the keploy process of the running session, empty when there is none
+	session string
+}
+
+func newAgentMetrics() *agentMetrics {
+	m := &agentMetrics{registry: metrics.NewRegistry()}
+	m.sessions = m.registry.NewCounter("keploy_agent_sessions_total", "Keploy record and test processes started by the agent, by mode.", "mode")
+	m.pushes = m.registry.NewCounter("keploy_agent_pushes_total", "Pushes of the recorded test sets to the storage, by result.", "result")
+	m.registry.NewGaugeFunc("keploy_agent_session_active", "Whether a keploy record or test process of the agent is running.", func() float64 {
+		if m.sessionAddr() == "" {
+			return 0
+		}
+		return 1
+	})
+	return m
+}
+
+func (m *agentMetrics) setSession(address string) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.session = address
+}
+
+func (m *agentMetrics) sessionAddr() string {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return m.session
+}
+
+// handler writes the metrics of the agent followed by the ones of the keploy process of the running session. The
+// counters of the session start again from zero with each new session, which Prometheus reads as a counter reset.
+func (m *agentMetrics) handler(logger *zap.Logger) http.Handler {
+	client := &http.Client{Timeout: scrapeTimeout}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", metrics.ContentType)
+		if err := m.registry.Write(w); err != nil {
+			logger.Debug("failed to write the metrics of the agent", zap.Error(err))
+			return
+		}
+		address := m.sessionAddr()
+		if address == "" {
+			return
+		}
+		resp, err := client.Get(fmt.Sprintf("http://%s/metrics", address))
+		if err != nil {
+			// the keploy process may not serve its metrics yet
+			logger.Debug("failed to read the metrics of the session", zap.String("address", address), zap.Error(err))
+			return
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			logger.Debug("failed to read the metrics of the session", zap.String("address", address), zap.Int("status", resp.StatusCode))
+			return
+		}
+		if _, err := io.Copy(w, resp.Body); err != nil {
+			logger.Debug("failed to write the metrics of the session", zap.Error(err))
+		}
+	})
+}
+
+// freeLocalAddr returns a local address whose port is free, for the metrics of the keploy process of a session.
+func freeLocalAddr() (string, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return "", err
+	}
+	defer listener.Close()
+	return net.JoinHostPort("127.0.0.1", strconv.Itoa(listener.Addr().(*net.TCPAddr).Port)), nil
+}
@@ -0,0 +1,64 @@
+package agent
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"go.keploy.io/server/pkg/platform/kube"
+)
+
+// containerProcess returns the pid of the main process of the container of the pod running the application, the one
+// named or the first one whose process is found. The processes are the ones of the pid namespace of keploy: the ones
+// of its own pod with shareProcessNamespace, or the ones of the node with hostPID. They are told apart by the id of
+// their container in their cgroup, the container of keploy itself being skipped.
+func containerProcess(pod kube.Pod, container string) (uint32, string, error) {
+	self, err := os.ReadFile("/proc/self/cgroup")
+	if err != nil {
+		return 0, "", err
+	}
+	for _, c := range pod.Containers {
+		if container != "" && c.Name != container {
+			continue
+		}
+		if container == "" && strings.Contains(string(self), c.ID) {
+			continue
+		}
+		pid, err := oldestProcess(c.ID)
+		if err != nil {
+			return 0, "", err
+		}
+		if pid != 0 {
+			return pid, c.Name, nil
+		}
+	}
+	if container != "" {
+		return 0, "", fmt.Errorf("no process of the container %s of the pod %s is visible, share the process namespace of the pod or the one of the node with keploy", container, pod.Name)
+	}
+	return 0, "", fmt.Errorf("no process of the pod %s is visible, share the process namespace of the pod or the one of the node with keploy", pod.Name)
+}
+
+// oldestProcess returns the lowest pid of the processes whose cgroup holds the container id, 0 when there is none.
+func oldestProcess(containerID string) (uint32, error) {
+	entries, err := os.ReadDir("/proc")
+	if err != nil {
+		return 0, err
+	}
+	var oldest uint64
+	for _, entry := range entries {
+		pid, err := strconv.ParseUint(entry.Name(), 10, 32)
+		if err != nil {
+			continue
+		}
+		// the processes may exit while they are read
+		cgroup, err := os.ReadFile("/proc/" + entry.Name() + "/cgroup")
+		if err != nil || !strings.Contains(string(cgroup), containerID) {
+			continue
+		}
+		if oldest == 0 || pid < oldest {
+			oldest = pid
+		}
+	}
+	return uint32(oldest), nil
+}
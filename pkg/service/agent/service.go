@@ -0,0 +1,55 @@
+package agent
+
+import (
+	"time"
+
+	"go.keploy.io/server/pkg/service/storage"
+)
+
+type Agent interface {
+	// Run watches the pods of the selector and records or tests the application of the first one whose process is
+	// found, as the mode annotation of its pod says, until keploy is interrupted.
+	Run(options Options) error
+}
+
+// ModeAnnotation is the annotation of the pods setting the mode of the agent for their application.
+const ModeAnnotation = "keploy.io/mode"
+
+// The modes of the agent.
+const (
+	// ModeRecord records the calls of the application, pushing the test sets to the storage
+	ModeRecord = "record"
+	// ModeTest tests the application with the test sets, pulled from the storage when they are missing
+	ModeTest = "test"
+	// ModeOff leaves the application alone
+	ModeOff = "off"
+)
+
+// Options of the agent.
+type Options struct {
+	// Path of the directory holding the keploy directory of the test sets
+	Path string
+	// ConfigPath is the directory of the config file passed to the keploy processes
+	ConfigPath string
+	// Namespace of the pods, all of them when it's empty
+	Namespace string
+	// Selector is the label selector of the pods
+	Selector string
+	// Node the pods are scheduled on, any of them when it's empty
+	Node string
+	// Container of the pods running the application, the first one whose process is found when it's empty
+	Container string
+	// Mode of the pods without the mode annotation
+	Mode string
+	// PollInterval is the interval between the reads of the pods
+	PollInterval time.Duration
+	// SessionDuration is the duration after which the recording is pushed and a new test set started, never when
+	// it's zero
+	SessionDuration time.Duration
+	// Args are the flags added to the keploy record and test processes
+	Args []string
+	// Storage the recorded test sets are pushed to, nil when none is configured
+	Storage storage.Storage
+	// MetricsAddr is the address the metrics of the agent and of its sessions are served on, none when it's empty
+	MetricsAddr string
+}
@@ -0,0 +1,163 @@
+package contract
+
+import (
+	"fmt"
+	"mime"
+	"net/url"
+	"path/filepath"
+	"strings"
+
+	"go.keploy.io/server/pkg"
+	"go.keploy.io/server/pkg/models"
+	"go.keploy.io/server/pkg/platform/yaml"
+	"go.uber.org/zap"
+)
+
+type contract struct {
+	logger *zap.Logger
+}
+
+func NewContract(logger *zap.Logger) Contract {
+	return &contract{
+		logger: logger,
+	}
+}
+
+func (c *contract) Verify(path string, testSets []string, options VerifyOptions) (*Report, error) {
+	provider, err := url.Parse(options.ProviderURL)
+	if err != nil || provider.Scheme == "" || provider.Host == "" {
+		return nil, fmt.Errorf("invalid provider url %q, expected one like http://localhost:8080", options.ProviderURL)
+	}
+	if len(testSets) == 0 {
+		testSets, err = yaml.ReadSessionIndices(path, c.logger)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list the test sets of %s: %v", path, err)
+		}
+	}
+	hosts := map[string]bool{}
+	for _, host := range options.Hosts {
+		hosts[strings.ToLower(host)] = true
+	}
+
+	report := &Report{Provider: options.ProviderURL, Interactions: []Interaction{}}
+	for _, testSet := range testSets {
+		dir := filepath.Join(path, testSet)
+		ys := yaml.NewYamlStore(filepath.Join(dir, "tests"), dir, "", "", c.logger, nil)
+		mocks, err := ys.ReadTcsMocks(nil, dir)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the mocks of the test set %s: %v", testSet, err)
+		}
+		// the same request made several times by the consumer is a single interaction
+		seen := map[string]bool{}
+		for _, m := range mocks {
+			mock, ok := m.(*models.Mock)
+			if !ok || mock.Kind != models.HTTP || mock.Spec.HttpReq == nil || mock.Spec.HttpResp == nil {
+				continue
+			}
+			recorded, err := url.Parse(mock.Spec.HttpReq.URL)
+			if err != nil {
+				c.logger.Warn("skipping the mock with an invalid url", zap.String("mock", mock.Name), zap.Error(err))
+				continue
+			}
+			// the urls of the requests sent by the consumer are mostly only their path, their host being in the header
+			host := recorded.Host
+			if host == "" {
+				host = mock.Spec.HttpReq.Host
+			}
+			if len(hosts) > 0 && !hosts[strings.ToLower(host)] && !hosts[strings.ToLower(strings.Split(host, ":")[0])] {
+				continue
+			}
+			key := string(mock.Spec.HttpReq.Method) + " " + recorded.RequestURI() + " " + mock.Spec.HttpReq.Body
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+
+			interaction := c.verify(testSet, mock, recorded, provider, options.ApiTimeout)
+			if interaction.Status == StatusPassed {
+				report.Passed++
+			} else {
+				report.Failed++
+			}
+			report.Interactions = append(report.Interactions, interaction)
+		}
+	}
+	return report, nil
+}
+
+// verify replays the request of the mock against the provider.
+func (c *contract) verify(testSet string, mock *models.Mock, recorded, provider *url.URL, apiTimeout uint64) Interaction {
+	interaction := Interaction{
+		TestSet: testSet,
+		Mock:    mock.Name,
+		Method:  string(mock.Spec.HttpReq.Method),
+		Path:    recorded.RequestURI(),
+		Status:  StatusPassed,
+	}
+	target := *recorded
+	target.Scheme, target.Host = provider.Scheme, provider.Host
+	target.Path = strings.TrimSuffix(provider.Path, "/") + recorded.Path
+	if recorded.RawPath != "" {
+		target.RawPath = strings.TrimSuffix(provider.EscapedPath(), "/") + recorded.RawPath
+	}
+
+	req := *mock.Spec.HttpReq
+	req.URL = target.String()
+	header := map[string]string{}
+	for name, value := range req.Header {
+		// the host of the provider is set by the client, and the body is read again by the provider
+		if !strings.EqualFold(name, "Host") && !strings.EqualFold(name, "Content-Length") {
+			header[name] = value
+		}
+	}
+	req.Header = header
+	tc := models.TestCase{Name: mock.Name, Kind: models.HTTP, HttpReq: req}
+	if req.ProtoMajor == 0 {
+		tc.HttpReq.ProtoMajor, tc.HttpReq.ProtoMinor = 1, 1
+	}
+	actual, err := pkg.SimulateHttp(tc, testSet, c.logger, apiTimeout)
+	if err != nil {
+		interaction.Status = StatusFailed
+		interaction.Errors = []string{fmt.Sprintf("failed to send the request to the provider: %v", err)}
+		return interaction
+	}
+	interaction.Errors = compatible(mock.Spec.HttpResp, actual)
+	if len(interaction.Errors) > 0 {
+		interaction.Status = StatusFailed
+	}
+	return interaction
+}
+
+// compatible returns why the actual response can't be handled by the consumer expecting the recorded one: a
+// different status or media type, or a JSON body missing the fields of the recorded one or with other types. The
+// provider is free to add fields and to change their values.
+func compatible(recorded, actual *models.HttpResp) []string {
+	var errs []string
+	if recorded.StatusCode != actual.StatusCode {
+		errs = append(errs, fmt.Sprintf("status code: expected %d, actual %d", recorded.StatusCode, actual.StatusCode))
+	}
+	expectedType, actualType := mediaType(recorded.Header), mediaType(actual.Header)
+	if expectedType != "" && expectedType != actualType {
+		errs = append(errs, fmt.Sprintf("content type: expected %s, actual %s", expectedType, actualType))
+	}
+	if schema := pkg.InferSchema(recorded.Body); schema != nil {
+		for _, schemaErr := range pkg.ValidateSchema(schema, actual.Body) {
+			errs = append(errs, fmt.Sprintf("%s: expected %s, actual %s", schemaErr.Path, schemaErr.Expected, schemaErr.Actual))
+		}
+	}
+	return errs
+}
+
+// mediaType returns the media type of the content type header, without its parameters.
+func mediaType(header map[string]string) string {
+	for name, value := range header {
+		if strings.EqualFold(name, "Content-Type") {
+			media, _, err := mime.ParseMediaType(value)
+			if err != nil {
+				return strings.ToLower(strings.TrimSpace(value))
+			}
+			return media
+		}
+	}
+	return ""
+}
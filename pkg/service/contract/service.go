@@ -0,0 +1,42 @@
+package contract
+
+type Contract interface {
+	// Verify replays the requests of the http mocks recorded by the consumer in its test sets, all of them when none
+	// is given, against the provider and checks that its responses are compatible with the recorded ones.
+	Verify(path string, testSets []string, options VerifyOptions) (*Report, error)
+}
+
+// VerifyOptions are the options of the verification of a provider.
+type VerifyOptions struct {
+	// ProviderURL is the base url of the provider, like http://localhost:8080, which replaces the scheme and the host
+	// of the recorded requests
+	ProviderURL string
+	// Hosts are the hosts of the recorded requests sent to the provider, like orders:8080, all of them by default
+	Hosts []string
+	// ApiTimeout is the timeout of the requests in seconds
+	ApiTimeout uint64
+}
+
+// Report is the result of the verification of a provider.
+type Report struct {
+	Provider     string        `json:"provider" yaml:"provider"`
+	Passed       int           `json:"passed" yaml:"passed"`
+	Failed       int           `json:"failed" yaml:"failed"`
+	Interactions []Interaction `json:"interactions" yaml:"interactions"`
+}
+
+// Interaction is the result of the replay of a recorded request, Errors being why the response of the provider is
+// incompatible with the recorded one.
+type Interaction struct {
+	TestSet string   `json:"test_set" yaml:"test_set"`
+	Mock    string   `json:"mock" yaml:"mock"`
+	Method  string   `json:"method" yaml:"method"`
+	Path    string   `json:"path" yaml:"path"`
+	Status  string   `json:"status" yaml:"status"`
+	Errors  []string `json:"errors,omitempty" yaml:"errors,omitempty"`
+}
+
+const (
+	StatusPassed = "PASSED"
+	StatusFailed = "FAILED"
+)
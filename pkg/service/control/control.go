@@ -0,0 +1,447 @@
+package control
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"go.keploy.io/server/pkg/models"
+	"go.keploy.io/server/pkg/platform/yaml"
+	"go.keploy.io/server/pkg/service/testset"
+	"go.uber.org/zap"
+)
+
+// stopTimeout is the time given to keploy to write its recordings and reports once interrupted.
+const stopTimeout = 30 * time.Second
+
+// errBusy is returned when a run is asked while another one is running.
+var errBusy = errors.New("keploy is already recording or testing, stop it first")
+
+type controller struct {
+	logger   *zap.Logger
+	testSets testset.TestSets
+	options  Options
+
+	mutex sync.Mutex
+	// current is the running keploy record or test, nil when keploy is idle
+	current *run
+	last    *RunResult
+}
+
+// run is a keploy record or test process started by the API.
+type run struct {
+	mode    string
+	request RunRequest
+	cmd     *exec.Cmd
+	done    chan struct{}
+	started time.Time
+	// before are the test sets which existed before the recording
+	before map[string]bool
+}
+
+func NewController(logger *zap.Logger) Controller {
+	return &controller{
+		logger:   logger,
+		testSets: testset.NewTestSets(logger),
+	}
+}
+
+func (c *controller) Serve(options Options) error {
+	if options.Token == "" {
+		// keploy record and test run the commands of the requests as root, so the API never serves unauthenticated
+		token, err := writeToken(options.TokenFile)
+		if err != nil {
+			c.logger.Error("failed to write the token of the control API", zap.String("tokenFile", options.TokenFile), zap.Error(err))
+			return err
+		}
+		options.Token = token
+		c.logger.Info("the requests of the control API must carry the token of the file as their bearer token", zap.String("tokenFile", options.TokenFile))
+	}
+	c.options = options
+	mux := http.NewServeMux()
+	mux.HandleFunc("/status", c.method(http.MethodGet, c.handleStatus))
+	mux.HandleFunc("/record/start", c.method(http.MethodPost, c.handleStart(models.MODE_RECORD)))
+	mux.HandleFunc("/record/stop", c.method(http.MethodPost, c.handleStop(models.MODE_RECORD)))
+	mux.HandleFunc("/record/switch", c.method(http.MethodPost, c.handleSwitch))
+	mux.HandleFunc("/test/start", c.method(http.MethodPost, c.handleStart(models.MODE_TEST)))
+	mux.HandleFunc("/test/stop", c.method(http.MethodPost, c.handleStop(models.MODE_TEST)))
+	mux.HandleFunc("/testsets", c.method(http.MethodGet, c.handleTestSets))
+	mux.HandleFunc("/testsets/", c.method(http.MethodGet, c.handleMocks))
+
+	listener, err := net.Listen("tcp", options.Address)
+	if err != nil {
+		c.logger.Error("failed to listen for the requests of the control API", zap.String("address", options.Address), zap.Error(err))
+		return err
+	}
+	server := &http.Server{Handler: c.authenticate(mux)}
+	c.logger.Info("the control API is listening", zap.String("address", listener.Addr().String()))
+
+	stopper := make(chan os.Signal, 1)
+	signal.Notify(stopper, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(stopper)
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- server.Serve(listener)
+	}()
+
+	select {
+	case <-stopper:
+	case err := <-serveErr:
+		c.logger.Error("the control API stopped", zap.Error(err))
+		return err
+	}
+	server.Close()
+	c.mutex.Lock()
+	current := c.current
+	c.mutex.Unlock()
+	if current != nil {
+		c.stop(current)
+	}
+	return nil
+}
+
+// authenticate rejects the requests without the bearer token of the options, and the ones whose host is neither the
+// address of the API nor a loopback one, which the web pages rebinding their domain to the API would send.
+func (c *controller) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !c.allowedHost(r.Host) {
+			writeError(w, http.StatusForbidden, fmt.Errorf("the host %s isn't allowed", r.Host))
+			return
+		}
+		token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if subtle.ConstantTimeCompare([]byte(token), []byte(c.options.Token)) != 1 {
+			writeError(w, http.StatusUnauthorized, errors.New("invalid or missing bearer token"))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// allowedHost tells whether the host of a request is the one of the address of the API, or a loopback one.
+func (c *controller) allowedHost(host string) bool {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	host = strings.Trim(host, "[]")
+	if strings.EqualFold(host, "localhost") {
+		return true
+	}
+	if ip := net.ParseIP(host); ip != nil && ip.IsLoopback() {
+		return true
+	}
+	address, _, err := net.SplitHostPort(c.options.Address)
+	if err != nil || address == "" {
+		return false
+	}
+	if ip := net.ParseIP(address); ip != nil && ip.IsUnspecified() {
+		return false
+	}
+	return strings.EqualFold(host, address)
+}
+
+// writeToken writes a new random token to the file, readable by the user who invoked sudo alone.
+func writeToken(path string) (string, error) {
+	if path == "" {
+		return "", errors.New("neither KEPLOY_CONTROL_TOKEN nor the file of the token is given")
+	}
+	secret := make([]byte, 16)
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+	token := hex.EncodeToString(secret)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return "", err
+	}
+	// the file is created anew, so that no other user can have it link elsewhere or keep it open
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return "", err
+	}
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	if _, err := file.WriteString(token + "\n"); err != nil {
+		return "", err
+	}
+	if uid, err := strconv.Atoi(os.Getenv("SUDO_UID")); err == nil {
+		gid, _ := strconv.Atoi(os.Getenv("SUDO_GID"))
+		file.Chown(uid, gid)
+	}
+	return token, nil
+}
+
+// method rejects the requests of the other methods.
+func (c *controller) method(method string, handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != method {
+			w.Header().Set("Allow", method)
+			writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("%s isn't allowed on %s", r.Method, r.URL.Path))
+			return
+		}
+		handler(w, r)
+	}
+}
+
+func (c *controller) handleStatus(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, c.status())
+}
+
+func (c *controller) handleStart(mode models.Mode) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// the web pages can only send JSON to the API after a preflight request, which it doesn't answer
+		if mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type")); err != nil || mediaType != "application/json" {
+			writeError(w, http.StatusUnsupportedMediaType, errors.New("the request must be sent as application/json"))
+			return
+		}
+		var request RunRequest
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid request: %v", err))
+			return
+		}
+		if request.Command == "" {
+			writeError(w, http.StatusBadRequest, errors.New("the command of the application is missing"))
+			return
+		}
+		if err := c.start(string(mode), request); err != nil {
+			status := http.StatusInternalServerError
+			if err == errBusy {
+				status = http.StatusConflict
+			}
+			writeError(w, status, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, c.status())
+	}
+}
+
+func (c *controller) handleStop(mode models.Mode) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		c.mutex.Lock()
+		current := c.current
+		c.mutex.Unlock()
+		if current == nil || current.mode != string(mode) {
+			writeError(w, http.StatusConflict, fmt.Errorf("keploy %s isn't running", mode))
+			return
+		}
+		c.stop(current)
+		writeJSON(w, http.StatusOK, c.status())
+	}
+}
+
+// handleSwitch stops the recording and starts a new one, recording the next calls in a new test set.
+func (c *controller) handleSwitch(w http.ResponseWriter, r *http.Request) {
+	c.mutex.Lock()
+	current := c.current
+	c.mutex.Unlock()
+	if current == nil || current.mode != string(models.MODE_RECORD) {
+		writeError(w, http.StatusConflict, errors.New("keploy record isn't running"))
+		return
+	}
+	c.stop(current)
+	if err := c.start(current.mode, current.request); err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, c.status())
+}
+
+func (c *controller) handleTestSets(w http.ResponseWriter, r *http.Request) {
+	infos, err := c.testSets.List(c.keployPath())
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, infos)
+}
+
+// handleMocks lists the mocks of the test set of /testsets/<test-set>/mocks.
+func (c *controller) handleMocks(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/testsets/"), "/")
+	if len(parts) != 2 || parts[1] != "mocks" || parts[0] == "" || parts[0] != filepath.Base(parts[0]) {
+		writeError(w, http.StatusNotFound, fmt.Errorf("no such resource %s", r.URL.Path))
+		return
+	}
+	dir := filepath.Join(c.keployPath(), parts[0])
+	if _, err := os.Stat(dir); err != nil {
+		writeError(w, http.StatusNotFound, fmt.Errorf("no test set %s", parts[0]))
+		return
+	}
+	docs, err := yaml.ReadDocs(dir, "mocks")
+	if err != nil && !os.IsNotExist(err) {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	mocks := []MockInfo{}
+	for _, doc := range docs {
+		mocks = append(mocks, MockInfo{Name: doc.Name, Kind: string(doc.Kind)})
+	}
+	writeJSON(w, http.StatusOK, mocks)
+}
+
+func (c *controller) keployPath() string {
+	return filepath.Join(c.options.Path, "keploy")
+}
+
+// start runs keploy record or test for the request.
+func (c *controller) start(mode string, request RunRequest) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.current != nil {
+		return errBusy
+	}
+	executable, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	args := []string{mode, "-c", request.Command, "--path", c.options.Path, "--config-path", c.options.ConfigPath}
+	if request.ContainerName != "" {
+		args = append(args, "--containerName", request.ContainerName)
+	}
+	if request.Delay != 0 {
+		args = append(args, "--delay", strconv.FormatUint(request.Delay, 10))
+	}
+	if len(request.TestSets) != 0 {
+		args = append(args, "--testsets", strings.Join(request.TestSets, ","))
+	}
+	before := map[string]bool{}
+	testSets, err := yaml.ReadSessionIndices(c.keployPath(), c.logger)
+	if err == nil {
+		for _, testSet := range testSets {
+			before[testSet] = true
+		}
+	}
+
+	cmd := exec.Command(executable, append(args, request.Args...)...)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	c.logger.Info(fmt.Sprintf("started keploy %s", mode), zap.String("command", request.Command))
+	current := &run{mode: mode, request: request, cmd: cmd, done: make(chan struct{}), started: time.Now(), before: before}
+	c.current = current
+	go c.wait(current)
+	return nil
+}
+
+// wait waits for the keploy process of the run to exit, keeping its result as the last one.
+func (c *controller) wait(current *run) {
+	err := current.cmd.Wait()
+	result := &RunResult{Mode: current.mode, StartedAt: current.started, EndedAt: time.Now()}
+	var exitErr *exec.ExitError
+	// keploy exits with the interrupt which stopped it
+	if err != nil && !(errors.As(err, &exitErr) && !exitErr.Exited()) {
+		result.Error = err.Error()
+	}
+	if current.mode == string(models.MODE_RECORD) {
+		for _, testSet := range c.recordedTestSets(current) {
+			result.TestSets = append(result.TestSets, TestSetResult{Name: testSet})
+		}
+	} else {
+		result.TestSets = c.testResults(current.request.TestSets)
+	}
+
+	c.mutex.Lock()
+	c.last = result
+	if c.current == current {
+		c.current = nil
+	}
+	c.mutex.Unlock()
+	close(current.done)
+	c.logger.Info(fmt.Sprintf("keploy %s stopped", current.mode), zap.String("error", result.Error))
+}
+
+// stop interrupts the keploy process of the run, killing it when it doesn't exit in time.
+func (c *controller) stop(current *run) {
+	if err := current.cmd.Process.Signal(os.Interrupt); err != nil && !errors.Is(err, os.ErrProcessDone) {
+		c.logger.Error("failed to interrupt keploy", zap.Error(err))
+	}
+	select {
+	case <-current.done:
+	case <-time.After(stopTimeout):
+		c.logger.Warn(fmt.Sprintf("keploy %s didn't exit in time, killing it", current.mode))
+		current.cmd.Process.Kill()
+		<-current.done
+	}
+}
+
+// recordedTestSets returns the test sets created since the start of the recording.
+func (c *controller) recordedTestSets(current *run) []string {
+	testSets, err := yaml.ReadSessionIndices(c.keployPath(), c.logger)
+	if err != nil {
+		return nil
+	}
+	var recorded []string
+	for _, testSet := range testSets {
+		if !current.before[testSet] {
+			recorded = append(recorded, testSet)
+		}
+	}
+	return recorded
+}
+
+// testResults returns the status of the last report of the test sets, of all of them when none is given.
+func (c *controller) testResults(testSets []string) []TestSetResult {
+	infos, err := c.testSets.List(c.keployPath())
+	if err != nil {
+		c.logger.Error("failed to read the reports of the test sets", zap.Error(err))
+		return nil
+	}
+	selected := map[string]bool{}
+	for _, testSet := range testSets {
+		selected[testSet] = true
+	}
+	var results []TestSetResult
+	for _, info := range infos {
+		if len(selected) == 0 || selected[info.Name] {
+			results = append(results, TestSetResult{Name: info.Name, Status: info.LastRun})
+		}
+	}
+	return results
+}
+
+func (c *controller) status() Status {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	status := Status{State: StateIdle, LastRun: c.last}
+	if c.current == nil {
+		return status
+	}
+	status.State = StateTesting
+	if c.current.mode == string(models.MODE_RECORD) {
+		status.State = StateRecording
+		if recorded := c.recordedTestSets(c.current); len(recorded) > 0 {
+			status.TestSet = recorded[len(recorded)-1]
+		}
+	}
+	status.Pid = c.current.cmd.Process.Pid
+	started := c.current.started
+	status.StartedAt = &started
+	return status
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
@@ -0,0 +1,79 @@
+package control
+
+import "time"
+
+type Controller interface {
+	// Serve answers the requests of the control API on the address of the options until keploy is interrupted,
+	// running keploy record and test as they ask.
+	Serve(options Options) error
+}
+
+// Options of the control API.
+type Options struct {
+	// Address the API listens on, like 127.0.0.1:16791
+	Address string
+	// Path of the directory holding the keploy directory of the test sets
+	Path string
+	// ConfigPath is the directory of the config file passed to keploy record and test
+	ConfigPath string
+	// Token the requests must carry as their bearer token, a new one being written to TokenFile when it's empty
+	Token string
+	// TokenFile is the file the token generated when none is given is written to, readable by its user alone
+	TokenFile string
+}
+
+// The states of keploy.
+const (
+	StateIdle      = "idle"
+	StateRecording = "recording"
+	StateTesting   = "testing"
+)
+
+// RunRequest is the request starting keploy record or test.
+type RunRequest struct {
+	// Command starting the application
+	Command string `json:"command"`
+	// ContainerName of the application, when its command starts it in docker
+	ContainerName string `json:"containerName,omitempty"`
+	// Delay is the time the application takes to start, in seconds
+	Delay uint64 `json:"delay,omitempty"`
+	// TestSets are the test sets run by keploy test, all of them when it's empty
+	TestSets []string `json:"testSets,omitempty"`
+	// Args are the other flags of keploy record or test
+	Args []string `json:"args,omitempty"`
+}
+
+// Status is the state of keploy, along with the result of its last run.
+type Status struct {
+	State     string     `json:"state"`
+	Pid       int        `json:"pid,omitempty"`
+	StartedAt *time.Time `json:"startedAt,omitempty"`
+	// TestSet is the test set being recorded
+	TestSet string     `json:"testSet,omitempty"`
+	LastRun *RunResult `json:"lastRun,omitempty"`
+}
+
+// RunResult is the result of a run of keploy record or test.
+type RunResult struct {
+	// Mode is record or test
+	Mode      string    `json:"mode"`
+	StartedAt time.Time `json:"startedAt"`
+	EndedAt   time.Time `json:"endedAt"`
+	// Error of keploy when it failed, like the failure of tests
+	Error string `json:"error,omitempty"`
+	// TestSets are the test sets recorded, or the ones run along with the status of their report
+	TestSets []TestSetResult `json:"testSets,omitempty"`
+}
+
+// TestSetResult is a test set recorded or run.
+type TestSetResult struct {
+	Name string `json:"name"`
+	// Status of the last report of the test set, for the runs of keploy test
+	Status string `json:"status,omitempty"`
+}
+
+// MockInfo describes a mock of a test set.
+type MockInfo struct {
+	Name string `json:"name"`
+	Kind string `json:"kind"`
+}
@@ -0,0 +1,216 @@
+package coverage
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+// the languages of the applications whose coverage is collected
+const (
+	LanguageGo     = "go"
+	LanguageNode   = "node"
+	LanguagePython = "python"
+	LanguageJava   = "java"
+)
+
+func (c *coverage) Collector(language string) (Collector, error) {
+	if IsGo(language) {
+		return &goCollector{coverage: c}, nil
+	}
+	switch language {
+	case LanguageNode, "javascript", "typescript":
+		return &nodeCollector{logger: c.logger}, nil
+	case LanguagePython:
+		return &pythonCollector{logger: c.logger}, nil
+	case LanguageJava:
+		jacocoHome := os.Getenv("JACOCO_HOME")
+		if jacocoHome == "" {
+			return nil, fmt.Errorf("set JACOCO_HOME to the directory of the JaCoCo distribution to collect the coverage of java applications")
+		}
+		agent := filepath.Join(jacocoHome, "lib", "jacocoagent.jar")
+		if _, err := os.Stat(agent); err != nil {
+			return nil, fmt.Errorf("failed to find the JaCoCo agent: %v", err)
+		}
+		return &javaCollector{
+			logger:      c.logger,
+			agent:       agent,
+			cli:         filepath.Join(jacocoHome, "lib", "jacococli.jar"),
+			toolOptions: os.Getenv("JAVA_TOOL_OPTIONS"),
+		}, nil
+	default:
+		return nil, fmt.Errorf("no coverage collector for the %s applications, the languages are %s, %s, %s and %s", language, LanguageGo, LanguageNode, LanguagePython, LanguageJava)
+	}
+}
+
+// goCollector collects the coverage of the go binaries built with -cover through GOCOVERDIR.
+type goCollector struct {
+	coverage *coverage
+}
+
+func (g *goCollector) Start(appCmd, dir string) (string, error) {
+	os.Setenv("GOCOVERDIR", dir)
+	return appCmd, nil
+}
+
+func (g *goCollector) Flush(dir string) error {
+	return g.coverage.GoProfile(dir, dir+".out")
+}
+
+// nodeCollector runs the node applications under nyc, which instruments their code with istanbul.
+type nodeCollector struct {
+	logger *zap.Logger
+}
+
+func (n *nodeCollector) Start(appCmd, dir string) (string, error) {
+	return fmt.Sprintf("npx nyc --silent --temp-dir %s %s", filepath.Join(dir, ".nyc_output"), appCmd), nil
+}
+
+func (n *nodeCollector) Flush(dir string) error {
+	cmd := exec.Command("npx", "nyc", "report", "--temp-dir", filepath.Join(dir, ".nyc_output"), "--report-dir", dir, "--reporter=lcov", "--reporter=text-summary")
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("failed to report the coverage of %s: %v: %s", dir, err, string(out))
+	}
+	n.logger.Info("coverage of the test set", zap.String("testSet", filepath.Base(dir)), zap.String("report", filepath.Join(dir, "lcov.info")))
+	os.Stdout.Write(out)
+	return nil
+}
+
+// pythonCollector runs the python applications under coverage.py.
+type pythonCollector struct {
+	logger *zap.Logger
+}
+
+// rcFile writes the coverage.py configuration of the test set, which makes the application save its coverage data in
+// the directory when it is stopped by keploy.
+func (p *pythonCollector) rcFile(dir string) (string, error) {
+	rcFile := filepath.Join(dir, ".coveragerc")
+	conf := fmt.Sprintf("[run]\nparallel = True\nsigterm = True\ndata_file = %s\n", filepath.Join(dir, ".coverage"))
+	if err := os.WriteFile(rcFile, []byte(conf), 0644); err != nil {
+		return "", err
+	}
+	return rcFile, nil
+}
+
+func (p *pythonCollector) Start(appCmd, dir string) (string, error) {
+	rcFile, err := p.rcFile(dir)
+	if err != nil {
+		return "", fmt.Errorf("failed to write the coverage.py configuration: %v", err)
+	}
+	fields := strings.Fields(appCmd)
+	if len(fields) == 0 {
+		return appCmd, nil
+	}
+	// coverage.py runs the python scripts and the modules, like flask or gunicorn, itself
+	args := fields[1:]
+	if !strings.HasPrefix(filepath.Base(fields[0]), "python") {
+		args = append([]string{"-m", fields[0]}, args...)
+	}
+	return fmt.Sprintf("coverage run --rcfile=%s %s", rcFile, strings.Join(args, " ")), nil
+}
+
+func (p *pythonCollector) Flush(dir string) error {
+	rcFile := filepath.Join(dir, ".coveragerc")
+	for _, args := range [][]string{
+		{"combine", "--rcfile=" + rcFile},
+		{"xml", "--rcfile=" + rcFile, "-o", filepath.Join(dir, "coverage.xml")},
+	} {
+		if out, err := exec.Command("coverage", args...).CombinedOutput(); err != nil {
+			return fmt.Errorf("failed to %s the coverage of %s: %v: %s", args[0], dir, err, string(out))
+		}
+	}
+	out, err := exec.Command("coverage", "report", "--rcfile="+rcFile).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("failed to report the coverage of %s: %v: %s", dir, err, string(out))
+	}
+	p.logger.Info("coverage of the test set", zap.String("testSet", filepath.Base(dir)), zap.String("report", filepath.Join(dir, "coverage.xml")))
+	os.Stdout.Write(out)
+	return nil
+}
+
+// javaCollector attaches the JaCoCo agent to the JVMs started for the test set, which write its coverage data when they
+// are stopped.
+type javaCollector struct {
+	logger *zap.Logger
+	agent  string
+	cli    string
+	// toolOptions are the JAVA_TOOL_OPTIONS set by the user, which the agent is added to
+	toolOptions string
+}
+
+// classDirs are the directories of the compiled classes of the maven and gradle builds.
+var classDirs = []string{"target/classes", "build/classes/java/main", "build/classes/kotlin/main"}
+
+func (j *javaCollector) Start(appCmd, dir string) (string, error) {
+	agent := fmt.Sprintf("-javaagent:%s=destfile=%s,output=file", j.agent, filepath.Join(dir, "jacoco.exec"))
+	os.Setenv("JAVA_TOOL_OPTIONS", strings.TrimSpace(j.toolOptions+" "+agent))
+	return appCmd, nil
+}
+
+func (j *javaCollector) Flush(dir string) error {
+	args := []string{"-jar", j.cli, "report", filepath.Join(dir, "jacoco.exec")}
+	for _, classDir := range classDirs {
+		if _, err := os.Stat(classDir); err == nil {
+			args = append(args, "--classfiles", classDir)
+		}
+	}
+	csvReport := filepath.Join(dir, "jacoco.csv")
+	args = append(args, "--xml", filepath.Join(dir, "jacoco.xml"), "--csv", csvReport)
+	if out, err := exec.Command("java", args...).CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to report the coverage of %s: %v: %s", dir, err, string(out))
+	}
+	lines, err := jacocoLines(csvReport)
+	if err != nil {
+		return err
+	}
+	j.logger.Info("coverage of the test set", zap.String("testSet", filepath.Base(dir)), zap.String("lines", fmt.Sprintf("%.1f%%", lines.Percent())), zap.String("report", filepath.Join(dir, "jacoco.xml")))
+	return nil
+}
+
+// jacocoLines sums the lines of the classes of the csv report of JaCoCo.
+func jacocoLines(csvReport string) (FileCoverage, error) {
+	lines := FileCoverage{Name: "total"}
+	file, err := os.Open(csvReport)
+	if err != nil {
+		return lines, err
+	}
+	defer file.Close()
+	records, err := csv.NewReader(file).ReadAll()
+	if err != nil {
+		return lines, fmt.Errorf("failed to read the JaCoCo report: %v", err)
+	}
+	if len(records) == 0 {
+		return lines, nil
+	}
+	missedCol, coveredCol := -1, -1
+	for i, column := range records[0] {
+		switch column {
+		case "LINE_MISSED":
+			missedCol = i
+		case "LINE_COVERED":
+			coveredCol = i
+		}
+	}
+	if missedCol == -1 || coveredCol == -1 {
+		return lines, fmt.Errorf("the JaCoCo report has no line counters")
+	}
+	for _, record := range records[1:] {
+		missed, _ := strconv.Atoi(record[missedCol])
+		covered, _ := strconv.Atoi(record[coveredCol])
+		lines.Statements += missed + covered
+		lines.Covered += covered
+	}
+	return lines, nil
+}
+
+// IsGo tells whether the language is the one of the go binaries, whose coverage profiles are merged.
+func IsGo(language string) bool {
+	return language == "" || language == LanguageGo || language == "golang"
+}
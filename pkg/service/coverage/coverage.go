@@ -0,0 +1,74 @@
+package coverage
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	"go.keploy.io/server/pkg/models"
+	"go.uber.org/zap"
+)
+
+type coverage struct {
+	logger *zap.Logger
+}
+
+func NewCoverage(logger *zap.Logger) Coverage {
+	return &coverage{
+		logger: logger,
+	}
+}
+
+func (c *coverage) Merge(profiles []string, output string) (*Profile, error) {
+	if len(profiles) == 0 {
+		return nil, fmt.Errorf("no coverage profile to merge")
+	}
+	var merged *Profile
+	for _, file := range profiles {
+		profile, err := ReadProfile(file)
+		if err != nil {
+			return nil, err
+		}
+		if merged == nil {
+			merged = profile
+			continue
+		}
+		if merged.Mode != profile.Mode {
+			c.logger.Warn("merging coverage profiles of different modes, the merged profile only tells the covered statements", zap.String("mode", merged.Mode), zap.String("other mode", profile.Mode), zap.String("profile", file))
+		}
+		merged.Merge(profile)
+	}
+	if output != "" {
+		if err := os.MkdirAll(filepath.Dir(output), os.ModePerm); err != nil {
+			return nil, err
+		}
+		if err := merged.Write(output); err != nil {
+			return nil, fmt.Errorf("failed to write the merged coverage profile: %v", err)
+		}
+		c.logger.Info("merged the coverage profiles", zap.Strings("profiles", profiles), zap.String("output", output))
+	}
+	return merged, nil
+}
+
+func (c *coverage) Report(profile *Profile, threshold float64) error {
+	for _, pkg := range profile.Packages() {
+		c.logger.Info("coverage", zap.String("package", pkg.Name), zap.String("statements", fmt.Sprintf("%.1f%%", pkg.Percent())))
+	}
+	total := profile.Total()
+	line := fmt.Sprintf("total coverage: %.1f%% of %d statements", total.Percent(), total.Statements)
+	if threshold > 0 && total.Percent() < threshold {
+		c.logger.Error(models.HighlightFailingString(line), zap.String("threshold", fmt.Sprintf("%.1f%%", threshold)))
+		return fmt.Errorf("the total coverage %.1f%% is below the threshold %.1f%%", total.Percent(), threshold)
+	}
+	c.logger.Info(models.HighlightPassingString(line))
+	return nil
+}
+
+func (c *coverage) GoProfile(coverDir, output string) error {
+	cmd := exec.Command("go", "tool", "covdata", "textfmt", "-i="+coverDir, "-o="+output)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to convert the coverage data of %s: %v: %s", coverDir, err, string(out))
+	}
+	return nil
+}
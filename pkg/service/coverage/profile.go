@@ -0,0 +1,189 @@
+package coverage
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Profile is a go coverage profile, like the ones of go test -coverprofile and go tool covdata textfmt.
+type Profile struct {
+	// Mode is set, count or atomic
+	Mode   string
+	blocks map[string]*block
+}
+
+// block is a block of statements of a file, keyed by its position like file.go:12.3,15.2.
+type block struct {
+	file       string
+	position   string
+	statements int
+	count      int
+}
+
+// FileCoverage is the coverage of a file or a package.
+type FileCoverage struct {
+	Name       string
+	Statements int
+	Covered    int
+}
+
+// Percent returns the percentage of the statements covered.
+func (f FileCoverage) Percent() float64 {
+	if f.Statements == 0 {
+		return 0
+	}
+	return float64(f.Covered) * 100 / float64(f.Statements)
+}
+
+func NewProfile(mode string) *Profile {
+	return &Profile{Mode: mode, blocks: map[string]*block{}}
+}
+
+// ReadProfile reads a go coverage profile.
+func ReadProfile(file string) (*Profile, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var profile *Profile
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		if profile == nil {
+			mode, ok := strings.CutPrefix(text, "mode: ")
+			if !ok {
+				return nil, fmt.Errorf("%s isn't a go coverage profile, it doesn't start with its mode", file)
+			}
+			profile = NewProfile(strings.TrimSpace(mode))
+			continue
+		}
+		b, err := parseBlock(text)
+		if err != nil {
+			return nil, fmt.Errorf("invalid line %d of %s: %v", line, file, err)
+		}
+		profile.add(b)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if profile == nil {
+		return nil, fmt.Errorf("%s is an empty coverage profile", file)
+	}
+	return profile, nil
+}
+
+// parseBlock parses a line like go.keploy.io/server/pkg/util.go:12.3,15.2 4 1.
+func parseBlock(line string) (*block, error) {
+	fields := strings.Fields(line)
+	if len(fields) != 3 {
+		return nil, fmt.Errorf("expected file:start,end statements count")
+	}
+	colon := strings.LastIndex(fields[0], ":")
+	if colon < 0 {
+		return nil, fmt.Errorf("the block %q has no position", fields[0])
+	}
+	statements, err := strconv.Atoi(fields[1])
+	if err != nil {
+		return nil, fmt.Errorf("invalid number of statements %q", fields[1])
+	}
+	count, err := strconv.Atoi(fields[2])
+	if err != nil {
+		return nil, fmt.Errorf("invalid count %q", fields[2])
+	}
+	return &block{file: fields[0][:colon], position: fields[0], statements: statements, count: count}, nil
+}
+
+// add adds the count of a block, the blocks being covered or not in set mode.
+func (p *Profile) add(b *block) {
+	if existing, ok := p.blocks[b.position]; ok {
+		existing.count += b.count
+	} else {
+		copied := *b
+		p.blocks[b.position] = &copied
+	}
+	if p.Mode == "set" && p.blocks[b.position].count > 1 {
+		p.blocks[b.position].count = 1
+	}
+}
+
+// Merge adds the counts of the blocks of another profile. The profiles of different modes are merged in set mode.
+func (p *Profile) Merge(other *Profile) {
+	if p.Mode != other.Mode {
+		p.Mode = "set"
+		for _, b := range p.blocks {
+			if b.count > 1 {
+				b.count = 1
+			}
+		}
+	}
+	for _, b := range other.blocks {
+		p.add(b)
+	}
+}
+
+// Write writes the profile in the format of go test -coverprofile, readable by go tool cover.
+func (p *Profile) Write(file string) error {
+	var sb strings.Builder
+	sb.WriteString("mode: " + p.Mode + "\n")
+	for _, b := range p.sortedBlocks() {
+		fmt.Fprintf(&sb, "%s %d %d\n", b.position, b.statements, b.count)
+	}
+	return os.WriteFile(file, []byte(sb.String()), os.ModePerm)
+}
+
+// Total returns the coverage of all the statements of the profile.
+func (p *Profile) Total() FileCoverage {
+	total := FileCoverage{Name: "total"}
+	for _, b := range p.blocks {
+		total.Statements += b.statements
+		if b.count > 0 {
+			total.Covered += b.statements
+		}
+	}
+	return total
+}
+
+// Packages returns the coverage of the packages of the profile, sorted by name.
+func (p *Profile) Packages() []FileCoverage {
+	packages := map[string]*FileCoverage{}
+	for _, b := range p.blocks {
+		name := path.Dir(b.file)
+		pkg, ok := packages[name]
+		if !ok {
+			pkg = &FileCoverage{Name: name}
+			packages[name] = pkg
+		}
+		pkg.Statements += b.statements
+		if b.count > 0 {
+			pkg.Covered += b.statements
+		}
+	}
+	var result []FileCoverage
+	for _, pkg := range packages {
+		result = append(result, *pkg)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+	return result
+}
+
+func (p *Profile) sortedBlocks() []*block {
+	blocks := make([]*block, 0, len(p.blocks))
+	for _, b := range p.blocks {
+		blocks = append(blocks, b)
+	}
+	sort.Slice(blocks, func(i, j int) bool { return blocks[i].position < blocks[j].position })
+	return blocks
+}
@@ -0,0 +1,23 @@
+package coverage
+
+type Coverage interface {
+	// Merge merges the go coverage profiles, writes the merged one to the output file when given and returns it.
+	Merge(profiles []string, output string) (*Profile, error)
+	// Report logs the coverage of the packages of the profile and its total, and returns an error when the total is
+	// below the threshold percentage.
+	Report(profile *Profile, threshold float64) error
+	// GoProfile converts the coverage data written by the go binaries in the directory (GOCOVERDIR) into a profile.
+	GoProfile(coverDir, output string) error
+	// Collector returns the collector of the coverage of the applications of the language.
+	Collector(language string) (Collector, error)
+}
+
+// Collector collects the coverage of the application run by keploy for each test set.
+type Collector interface {
+	// Start returns the command running the application so that it writes its coverage data in the directory of the
+	// test set, and sets the environment it needs.
+	Start(appCmd, dir string) (string, error)
+	// Flush converts the coverage data written in the directory of the test set, once the application is stopped,
+	// into the coverage report of the test set.
+	Flush(dir string) error
+}
@@ -0,0 +1,91 @@
+package export
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"go.keploy.io/server/pkg/models"
+	"go.keploy.io/server/pkg/platform/yaml"
+	"go.keploy.io/server/pkg/platform/yaml/spec"
+	"go.uber.org/zap"
+)
+
+type exporter struct {
+	logger *zap.Logger
+}
+
+func NewExporter(logger *zap.Logger) Exporter {
+	return &exporter{
+		logger: logger,
+	}
+}
+
+// readTests returns the http tests of the test sets, all of them when none is given.
+func (e *exporter) readTests(path string, testSets []string) ([]*models.TestCase, error) {
+	if len(testSets) == 0 {
+		var err error
+		testSets, err = yaml.ReadSessionIndices(path, e.logger)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list the test sets of %s: %v", path, err)
+		}
+	}
+	var tests []*models.TestCase
+	for _, testSet := range testSets {
+		ys := yaml.NewYamlStore(filepath.Join(path, testSet, "tests"), filepath.Join(path, testSet), "", "", e.logger, nil)
+		tcs, err := ys.ReadTestcase("", nil, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the tests of the test set %s: %v", testSet, err)
+		}
+		for _, tc := range tcs {
+			if test, ok := tc.(*models.TestCase); ok && test.Kind == models.HTTP {
+				tests = append(tests, test)
+			}
+		}
+	}
+	return tests, nil
+}
+
+// httpMock is a recorded http call of the application to one of its dependencies.
+type httpMock struct {
+	name string
+	spec spec.HttpSpec
+}
+
+// readMocks returns the http mocks of the test sets, all of them when none is given. The mocks of the other protocols
+// are skipped.
+func (e *exporter) readMocks(path string, testSets []string) ([]httpMock, error) {
+	if len(testSets) == 0 {
+		var err error
+		testSets, err = yaml.ReadSessionIndices(path, e.logger)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list the test sets of %s: %v", path, err)
+		}
+	}
+	var mocks []httpMock
+	skipped := 0
+	for _, testSet := range testSets {
+		docs, err := yaml.ReadDocs(filepath.Join(path, testSet), "mocks")
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, fmt.Errorf("failed to read the mocks of the test set %s: %v", testSet, err)
+		}
+		for _, doc := range docs {
+			if doc.Kind != models.HTTP {
+				skipped++
+				continue
+			}
+			httpSpec := spec.HttpSpec{}
+			if err := doc.Spec.Decode(&httpSpec); err != nil {
+				return nil, fmt.Errorf("failed to decode the mock %s of the test set %s: %v", doc.Name, testSet, err)
+			}
+			mocks = append(mocks, httpMock{name: testSet + "/" + doc.Name, spec: httpSpec})
+		}
+	}
+	if skipped > 0 {
+		e.logger.Info("the mocks of the other protocols than http aren't exported", zap.Int("skipped", skipped))
+	}
+	return mocks, nil
+}
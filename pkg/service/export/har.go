@@ -0,0 +1,110 @@
+package export
+
+import (
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"time"
+
+	"go.keploy.io/server/pkg/models"
+)
+
+func (e *exporter) HAR(path string, testSets []string) (*models.HAR, error) {
+	tests, err := e.readTests(path, testSets)
+	if err != nil {
+		return nil, err
+	}
+	har := &models.HAR{
+		Log: models.HARLog{
+			Version: "1.2",
+			Creator: models.HARCreator{Name: "keploy", Version: string(models.GetVersion())},
+			Entries: []models.HAREntry{},
+		},
+	}
+	for _, tc := range tests {
+		har.Log.Entries = append(har.Log.Entries, harEntry(tc))
+	}
+	return har, nil
+}
+
+// harEntry returns the entry of the test, its request and its expected response.
+func harEntry(tc *models.TestCase) models.HAREntry {
+	req, resp := tc.HttpReq, tc.HttpResp
+	entry := models.HAREntry{
+		StartedDateTime: req.Timestamp.UTC().Format(time.RFC3339Nano),
+		Comment:         tc.Name,
+		Request: models.HARRequest{
+			Method:      string(req.Method),
+			URL:         req.URL,
+			HTTPVersion: harVersion(req.ProtoMajor, req.ProtoMinor),
+			Cookies:     []models.HARNameValue{},
+			Headers:     harHeaders(req.Header),
+			QueryString: []models.HARNameValue{},
+			HeadersSize: -1,
+			BodySize:    len(req.Body),
+		},
+		Response: models.HARResponse{
+			Status:      resp.StatusCode,
+			StatusText:  resp.StatusMessage,
+			HTTPVersion: harVersion(resp.ProtoMajor, resp.ProtoMinor),
+			Cookies:     []models.HARNameValue{},
+			Headers:     harHeaders(resp.Header),
+			Content: models.HARContent{
+				Size:     len(resp.Body),
+				MimeType: headerValue(resp.Header, "Content-Type"),
+				Text:     resp.Body,
+			},
+			HeadersSize: -1,
+			BodySize:    len(resp.Body),
+		},
+	}
+	if entry.Response.StatusText == "" {
+		entry.Response.StatusText = http.StatusText(resp.StatusCode)
+	}
+	if u, err := url.Parse(req.URL); err == nil {
+		query := u.Query()
+		names := make([]string, 0, len(query))
+		for name := range query {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			for _, value := range query[name] {
+				entry.Request.QueryString = append(entry.Request.QueryString, models.HARNameValue{Name: name, Value: value})
+			}
+		}
+	}
+	if req.Body != "" {
+		entry.Request.PostData = &models.HARPostData{
+			MimeType: headerValue(req.Header, "Content-Type"),
+			Text:     req.Body,
+		}
+	}
+	if !req.Timestamp.IsZero() && resp.Timestamp.After(req.Timestamp) {
+		entry.Time = float64(resp.Timestamp.Sub(req.Timestamp)) / float64(time.Millisecond)
+		entry.Timings.Wait = entry.Time
+	}
+	return entry
+}
+
+// harHeaders returns the headers sorted by their names.
+func harHeaders(header map[string]string) []models.HARNameValue {
+	headers := make([]models.HARNameValue, 0, len(header))
+	for name, value := range header {
+		headers = append(headers, models.HARNameValue{Name: name, Value: value})
+	}
+	sort.Slice(headers, func(i, j int) bool { return headers[i].Name < headers[j].Name })
+	return headers
+}
+
+// harVersion returns the http version of the har, like HTTP/1.1.
+func harVersion(major, minor int) string {
+	if major == 0 {
+		major, minor = 1, 1
+	}
+	if major >= 2 {
+		return "HTTP/" + strconv.Itoa(major)
+	}
+	return "HTTP/" + strconv.Itoa(major) + "." + strconv.Itoa(minor)
+}
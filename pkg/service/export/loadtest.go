@@ -0,0 +1,232 @@
+package export
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.keploy.io/server/pkg/models"
+	"go.keploy.io/server/pkg/platform/yaml"
+)
+
+// LoadTestOptions tunes the load-testing scripts generated from the tests.
+type LoadTestOptions struct {
+	// BaseURL replaces the scheme and the host of the recorded urls, like http://staging:8080, when set
+	BaseURL string
+	// ThinkTime waits before each request for the time the client took to send it after the previous response
+	ThinkTime bool
+}
+
+// skippedLoadHeaders are the headers of the recorded requests which the load-testing tools set themselves.
+var skippedLoadHeaders = map[string]bool{
+	"Content-Length":    true,
+	"Host":              true,
+	"Transfer-Encoding": true,
+	"Connection":        true,
+}
+
+// loadRequest is a recorded request sent by the load-testing scripts.
+type loadRequest struct {
+	name      string
+	method    string
+	url       string
+	header    map[string]string
+	body      string
+	status    int
+	thinkTime time.Duration
+}
+
+// readLoadRequests returns the requests of the http tests of the test sets, all of them when none is given, in the
+// order of their recording. The think time of the first request of each test set is 0.
+func (e *exporter) readLoadRequests(path string, testSets []string, options LoadTestOptions) ([]loadRequest, error) {
+	if len(testSets) == 0 {
+		var err error
+		testSets, err = yaml.ReadSessionIndices(path, e.logger)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list the test sets of %s: %v", path, err)
+		}
+	}
+	var base *url.URL
+	if options.BaseURL != "" {
+		var err error
+		base, err = url.Parse(options.BaseURL)
+		if err != nil || base.Scheme == "" || base.Host == "" {
+			return nil, fmt.Errorf("invalid base url %q, like http://localhost:8080", options.BaseURL)
+		}
+	}
+	var requests []loadRequest
+	for _, testSet := range testSets {
+		tests, err := e.readTests(path, []string{testSet})
+		if err != nil {
+			return nil, err
+		}
+		sort.SliceStable(tests, func(i, j int) bool { return tests[i].HttpReq.Timestamp.Before(tests[j].HttpReq.Timestamp) })
+		var previous *models.TestCase
+		for _, tc := range tests {
+			req := loadRequest{
+				name:   testSet + "/" + tc.Name,
+				method: string(tc.HttpReq.Method),
+				url:    tc.HttpReq.URL,
+				header: map[string]string{},
+				body:   tc.HttpReq.Body,
+				status: tc.HttpResp.StatusCode,
+			}
+			if base != nil {
+				if u, err := url.Parse(tc.HttpReq.URL); err == nil {
+					u.Scheme, u.Host = base.Scheme, base.Host
+					u.Path = strings.TrimSuffix(base.Path, "/") + u.Path
+					req.url = u.String()
+				}
+			}
+			for name, value := range tc.HttpReq.Header {
+				if !skippedLoadHeaders[http.CanonicalHeaderKey(name)] {
+					req.header[name] = value
+				}
+			}
+			if options.ThinkTime && previous != nil && tc.HttpReq.Timestamp.After(previous.HttpResp.Timestamp) {
+				req.thinkTime = tc.HttpReq.Timestamp.Sub(previous.HttpResp.Timestamp)
+			}
+			previous = tc
+			requests = append(requests, req)
+		}
+	}
+	if len(requests) == 0 {
+		return nil, fmt.Errorf("no http test to export in %s", path)
+	}
+	return requests, nil
+}
+
+func (e *exporter) K6(path string, testSets []string, options LoadTestOptions) (string, error) {
+	requests, err := e.readLoadRequests(path, testSets, options)
+	if err != nil {
+		return "", err
+	}
+	var script strings.Builder
+	script.WriteString("// generated by keploy export k6 from the recorded tests\n")
+	script.WriteString("import http from 'k6/http';\n")
+	script.WriteString("import { check, sleep } from 'k6';\n\n")
+	script.WriteString("export const options = {\n  vus: 1,\n  iterations: 1,\n};\n\n")
+	script.WriteString("export default function () {\n  let res;\n")
+	for _, req := range requests {
+		fmt.Fprintf(&script, "\n  // %s\n", req.name)
+		if req.thinkTime > 0 {
+			fmt.Fprintf(&script, "  sleep(%s);\n", seconds(req.thinkTime))
+		}
+		fmt.Fprintf(&script, "  res = http.request(%s, %s, %s, { headers: %s, tags: { name: %s } });\n", quote(req.method), quote(req.url), bodyLiteral(req.body, "null"), headersLiteral(req.header), quote(req.name))
+		fmt.Fprintf(&script, "  check(res, { %s: (r) => r.status === %d });\n", quote(fmt.Sprintf("%s status is %d", req.name, req.status)), req.status)
+	}
+	script.WriteString("}\n")
+	return script.String(), nil
+}
+
+// vegetaTarget is a target of the JSON format of vegeta attack, one per line.
+type vegetaTarget struct {
+	Method string              `json:"method"`
+	URL    string              `json:"url"`
+	Body   string              `json:"body,omitempty"`
+	Header map[string][]string `json:"header,omitempty"`
+}
+
+// Vegeta returns the targets of vegeta attack -format=json, which sends them at its own rate: the think times are
+// ignored.
+func (e *exporter) Vegeta(path string, testSets []string, options LoadTestOptions) (string, error) {
+	requests, err := e.readLoadRequests(path, testSets, options)
+	if err != nil {
+		return "", err
+	}
+	var targets strings.Builder
+	for _, req := range requests {
+		target := vegetaTarget{Method: req.method, URL: req.url}
+		if req.body != "" {
+			target.Body = base64.StdEncoding.EncodeToString([]byte(req.body))
+		}
+		for name, value := range req.header {
+			if target.Header == nil {
+				target.Header = map[string][]string{}
+			}
+			target.Header[name] = []string{value}
+		}
+		line, err := json.Marshal(target)
+		if err != nil {
+			return "", err
+		}
+		targets.Write(line)
+		targets.WriteString("\n")
+	}
+	return targets.String(), nil
+}
+
+func (e *exporter) Locust(path string, testSets []string, options LoadTestOptions) (string, error) {
+	requests, err := e.readLoadRequests(path, testSets, options)
+	if err != nil {
+		return "", err
+	}
+	var script strings.Builder
+	script.WriteString("# generated by keploy export locust from the recorded tests\n")
+	script.WriteString("import time\n\n")
+	script.WriteString("from locust import HttpUser, task\n\n\n")
+	script.WriteString("class RecordedUser(HttpUser):\n")
+	script.WriteString("    # the requests are sent to their recorded urls, the host being the one of the first of them\n")
+	fmt.Fprintf(&script, "    host = %s\n\n", quote(hostOf(requests[0].url)))
+	script.WriteString("    @task\n")
+	script.WriteString("    def replay(self):\n")
+	for _, req := range requests {
+		fmt.Fprintf(&script, "        # %s\n", req.name)
+		if req.thinkTime > 0 {
+			fmt.Fprintf(&script, "        time.sleep(%s)\n", seconds(req.thinkTime))
+		}
+		fmt.Fprintf(&script, "        with self.client.request(%s, %s, data=%s, headers=%s, name=%s, catch_response=True) as res:\n", quote(req.method), quote(req.url), bodyLiteral(req.body, "None"), headersLiteral(req.header), quote(req.name))
+		fmt.Fprintf(&script, "            if res.status_code != %d:\n", req.status)
+		fmt.Fprintf(&script, "                res.failure(\"expected the status %d, got %%d\" %% res.status_code)\n", req.status)
+		script.WriteString("            else:\n")
+		script.WriteString("                res.success()\n")
+	}
+	return script.String(), nil
+}
+
+// quote returns the string literal of the value, valid in javascript and in python.
+func quote(value string) string {
+	literal, _ := json.Marshal(value)
+	return string(literal)
+}
+
+// bodyLiteral returns the string literal of the body, or the literal of the language for none.
+func bodyLiteral(body, none string) string {
+	if body == "" {
+		return none
+	}
+	return quote(body)
+}
+
+// headersLiteral returns the object literal of the headers sorted by their names, valid in javascript and in python.
+func headersLiteral(header map[string]string) string {
+	names := make([]string, 0, len(header))
+	for name := range header {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	fields := make([]string, 0, len(names))
+	for _, name := range names {
+		fields = append(fields, quote(name)+": "+quote(header[name]))
+	}
+	return "{" + strings.Join(fields, ", ") + "}"
+}
+
+// seconds returns the duration in seconds, with the precision of the milliseconds.
+func seconds(d time.Duration) string {
+	return strconv.FormatFloat(d.Round(time.Millisecond).Seconds(), 'f', -1, 64)
+}
+
+func hostOf(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	return u.Scheme + "://" + u.Host
+}
@@ -0,0 +1,175 @@
+package export
+
+import (
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// mockoonMigration is the last migration of the Mockoon data format which the environments are written in.
+const mockoonMigration = 28
+
+// MockoonEnvironment is the environment imported by Mockoon, from its desktop application or its cli.
+type MockoonEnvironment struct {
+	UUID           string            `json:"uuid"`
+	LastMigration  int               `json:"lastMigration"`
+	Name           string            `json:"name"`
+	EndpointPrefix string            `json:"endpointPrefix"`
+	Latency        int               `json:"latency"`
+	Port           int               `json:"port"`
+	Hostname       string            `json:"hostname"`
+	Folders        []interface{}     `json:"folders"`
+	Routes         []*MockoonRoute   `json:"routes"`
+	RootChildren   []MockoonChild    `json:"rootChildren"`
+	ProxyMode      bool              `json:"proxyMode"`
+	ProxyHost      string            `json:"proxyHost"`
+	Cors           bool              `json:"cors"`
+	Headers        []MockoonHeader   `json:"headers"`
+	ProxyReqHeader []MockoonHeader   `json:"proxyReqHeaders"`
+	ProxyResHeader []MockoonHeader   `json:"proxyResHeaders"`
+	Data           []interface{}     `json:"data"`
+	TLSOptions     MockoonTLSOptions `json:"tlsOptions"`
+	Callbacks      []interface{}     `json:"callbacks"`
+}
+
+// MockoonRoute answers the calls of a method and path, with the first of its responses whose rules match them or
+// with the default one.
+type MockoonRoute struct {
+	UUID          string             `json:"uuid"`
+	Type          string             `json:"type"`
+	Documentation string             `json:"documentation"`
+	Method        string             `json:"method"`
+	Endpoint      string             `json:"endpoint"`
+	Responses     []*MockoonResponse `json:"responses"`
+	ResponseMode  interface{}        `json:"responseMode"`
+}
+
+type MockoonResponse struct {
+	UUID              string          `json:"uuid"`
+	Label             string          `json:"label"`
+	StatusCode        int             `json:"statusCode"`
+	Headers           []MockoonHeader `json:"headers"`
+	Body              string          `json:"body"`
+	BodyType          string          `json:"bodyType"`
+	Latency           int             `json:"latency"`
+	Rules             []MockoonRule   `json:"rules"`
+	RulesOperator     string          `json:"rulesOperator"`
+	DisableTemplating bool            `json:"disableTemplating"`
+	FallbackTo404     bool            `json:"fallbackTo404"`
+	Default           bool            `json:"default"`
+}
+
+// MockoonRule matches the value of the target of the calls, like the query parameter of the modifier.
+type MockoonRule struct {
+	Target   string `json:"target"`
+	Modifier string `json:"modifier"`
+	Value    string `json:"value"`
+	Invert   bool   `json:"invert"`
+	Operator string `json:"operator"`
+}
+
+type MockoonHeader struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+type MockoonChild struct {
+	Type string `json:"type"`
+	UUID string `json:"uuid"`
+}
+
+type MockoonTLSOptions struct {
+	Enabled bool   `json:"enabled"`
+	Type    string `json:"type"`
+}
+
+func (e *exporter) Mockoon(path string, testSets []string, name string, port int) (*MockoonEnvironment, error) {
+	mocks, err := e.readMocks(path, testSets)
+	if err != nil {
+		return nil, err
+	}
+	env := &MockoonEnvironment{
+		UUID:           uuid.NewString(),
+		LastMigration:  mockoonMigration,
+		Name:           name,
+		Port:           port,
+		Folders:        []interface{}{},
+		Routes:         []*MockoonRoute{},
+		RootChildren:   []MockoonChild{},
+		Headers:        []MockoonHeader{},
+		ProxyReqHeader: []MockoonHeader{},
+		ProxyResHeader: []MockoonHeader{},
+		Data:           []interface{}{},
+		TLSOptions:     MockoonTLSOptions{Type: "CERT"},
+		Callbacks:      []interface{}{},
+	}
+	routes := map[string]*MockoonRoute{}
+	for _, mock := range mocks {
+		mockURL, err := url.Parse(mock.spec.Request.URL)
+		if err != nil {
+			e.logger.Warn("skipping the mock whose url can't be parsed", zap.String("mock", mock.name), zap.Error(err))
+			continue
+		}
+		method := strings.ToLower(string(mock.spec.Request.Method))
+		endpoint := strings.TrimPrefix(mockURL.Path, "/")
+		key := method + " " + endpoint
+		route, ok := routes[key]
+		if !ok {
+			route = &MockoonRoute{
+				UUID:      uuid.NewString(),
+				Type:      "http",
+				Method:    method,
+				Endpoint:  endpoint,
+				Responses: []*MockoonResponse{},
+			}
+			routes[key] = route
+			env.Routes = append(env.Routes, route)
+			env.RootChildren = append(env.RootChildren, MockoonChild{Type: "route", UUID: route.UUID})
+		}
+		route.Responses = append(route.Responses, mockoonResponse(mock, mockURL))
+	}
+	for _, route := range env.Routes {
+		// the first recorded response answers the calls which no rule matches
+		route.Responses[0].Default = true
+	}
+	return env, nil
+}
+
+// mockoonResponse returns the recorded response of the mock, its rules matching the query parameters and the body of
+// its request.
+func mockoonResponse(mock httpMock, mockURL *url.URL) *MockoonResponse {
+	req, resp := mock.spec.Request, mock.spec.Response
+	response := &MockoonResponse{
+		UUID:          uuid.NewString(),
+		Label:         mock.name,
+		StatusCode:    resp.StatusCode,
+		Headers:       []MockoonHeader{},
+		Body:          resp.Body,
+		BodyType:      "INLINE",
+		Rules:         []MockoonRule{},
+		RulesOperator: "AND",
+		// the recorded bodies are served as they are, even when they look like templates
+		DisableTemplating: true,
+	}
+	for _, name := range stubHeaderNames(resp.Header) {
+		response.Headers = append(response.Headers, MockoonHeader{Key: name, Value: resp.Header[name]})
+	}
+	query := mockURL.Query()
+	names := make([]string, 0, len(query))
+	for name := range query {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		for _, value := range query[name] {
+			response.Rules = append(response.Rules, MockoonRule{Target: "query", Modifier: name, Value: value, Operator: "equals"})
+		}
+	}
+	if req.Body != "" {
+		response.Rules = append(response.Rules, MockoonRule{Target: "body", Value: req.Body, Operator: "equals"})
+	}
+	return response
+}
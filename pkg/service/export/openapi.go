@@ -0,0 +1,247 @@
+package export
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"go.keploy.io/server/pkg"
+	"go.keploy.io/server/pkg/models"
+)
+
+// OpenAPI is the OpenAPI 3 document synthesized from the tests.
+type OpenAPI struct {
+	OpenAPI string                           `json:"openapi" yaml:"openapi"`
+	Info    Info                             `json:"info" yaml:"info"`
+	Servers []Server                         `json:"servers,omitempty" yaml:"servers,omitempty"`
+	Paths   map[string]map[string]*Operation `json:"paths" yaml:"paths"`
+}
+
+type Info struct {
+	Title   string `json:"title" yaml:"title"`
+	Version string `json:"version" yaml:"version"`
+}
+
+type Server struct {
+	URL string `json:"url" yaml:"url"`
+}
+
+type Operation struct {
+	OperationID string               `json:"operationId" yaml:"operationId"`
+	Parameters  []*Parameter         `json:"parameters,omitempty" yaml:"parameters,omitempty"`
+	RequestBody *RequestBody         `json:"requestBody,omitempty" yaml:"requestBody,omitempty"`
+	Responses   map[string]*Response `json:"responses" yaml:"responses"`
+	// samples is the number of tests of the operation, telling the query parameters present in all of them
+	samples int
+}
+
+type Parameter struct {
+	Name     string             `json:"name" yaml:"name"`
+	In       string             `json:"in" yaml:"in"`
+	Required bool               `json:"required" yaml:"required"`
+	Schema   *models.JSONSchema `json:"schema" yaml:"schema"`
+	Example  string             `json:"example,omitempty" yaml:"example,omitempty"`
+	// seen is the number of tests of the operation with the parameter
+	seen int
+}
+
+type RequestBody struct {
+	Content map[string]*MediaType `json:"content" yaml:"content"`
+}
+
+type Response struct {
+	Description string                `json:"description" yaml:"description"`
+	Content     map[string]*MediaType `json:"content,omitempty" yaml:"content,omitempty"`
+}
+
+type MediaType struct {
+	Schema  *models.JSONSchema `json:"schema,omitempty" yaml:"schema,omitempty"`
+	Example interface{}        `json:"example,omitempty" yaml:"example,omitempty"`
+}
+
+// pathParam matches the segments of the paths which are identifiers, like numbers, uuids and hashes, rather than names.
+var pathParam = regexp.MustCompile(`^(\d+|[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}|[0-9a-fA-F]{16,})$`)
+
+// nonAlphanumeric matches the separators of the words of the operation ids.
+var nonAlphanumeric = regexp.MustCompile(`[^A-Za-z0-9]+`)
+
+// templatePath replaces the identifiers of the path with parameters named after the segment before them, like
+// /users/42/orders/7 becoming /users/{userId}/orders/{orderId}, and returns the parameters along with their values.
+func templatePath(path string) (string, []*Parameter) {
+	segments := strings.Split(path, "/")
+	var params []*Parameter
+	used := map[string]int{}
+	for i, segment := range segments {
+		if segment == "" || !pathParam.MatchString(segment) {
+			continue
+		}
+		name := "id"
+		if i > 0 && segments[i-1] != "" && !strings.HasPrefix(segments[i-1], "{") {
+			name = strings.TrimSuffix(segments[i-1], "s") + "Id"
+		}
+		used[name]++
+		if used[name] > 1 {
+			name += strconv.Itoa(used[name])
+		}
+		schema := &models.JSONSchema{Type: "string"}
+		if _, err := strconv.ParseInt(segment, 10, 64); err == nil {
+			schema.Type = "integer"
+		}
+		params = append(params, &Parameter{Name: name, In: "path", Required: true, Schema: schema, Example: segment})
+		segments[i] = "{" + name + "}"
+	}
+	return strings.Join(segments, "/"), params
+}
+
+// mediaType returns the media type of the content type header, without its parameters.
+func mediaType(contentType string) string {
+	media := strings.TrimSpace(strings.SplitN(contentType, ";", 2)[0])
+	if media == "" {
+		return "application/octet-stream"
+	}
+	return strings.ToLower(media)
+}
+
+// headerValue returns the value of the header, whose names are matched regardless of their case.
+func headerValue(header map[string]string, name string) string {
+	for key, value := range header {
+		if strings.EqualFold(key, name) {
+			return value
+		}
+	}
+	return ""
+}
+
+// addContent adds the body to the content of the media type, the schemas of the JSON bodies being merged.
+func addContent(content map[string]*MediaType, contentType, body string) {
+	if body == "" {
+		return
+	}
+	media := mediaType(contentType)
+	existing, ok := content[media]
+	schema := pkg.InferSchema(body)
+	if !ok {
+		existing = &MediaType{Schema: schema}
+		var example interface{}
+		if schema != nil && json.Unmarshal([]byte(body), &example) == nil {
+			existing.Example = example
+		}
+		content[media] = existing
+		return
+	}
+	if existing.Schema != nil && schema != nil {
+		existing.Schema = pkg.MergeSchemas(existing.Schema, schema)
+	}
+}
+
+// operationID returns the id of the operation, like getUsersUserId for GET /users/{userId}.
+func operationID(method, path string) string {
+	id := strings.ToLower(method)
+	for _, part := range nonAlphanumeric.Split(path, -1) {
+		if part != "" {
+			id += strings.ToUpper(part[:1]) + part[1:]
+		}
+	}
+	return id
+}
+
+func (e *exporter) OpenAPI(path string, testSets []string, title string) (*OpenAPI, error) {
+	tests, err := e.readTests(path, testSets)
+	if err != nil {
+		return nil, err
+	}
+	if len(tests) == 0 {
+		return nil, fmt.Errorf("no http test to export")
+	}
+	doc := &OpenAPI{
+		OpenAPI: "3.0.3",
+		Info:    Info{Title: title, Version: "1.0.0"},
+		Paths:   map[string]map[string]*Operation{},
+	}
+	servers := map[string]bool{}
+	for _, tc := range tests {
+		u, err := url.Parse(tc.HttpReq.URL)
+		if err != nil {
+			continue
+		}
+		if u.Host != "" && !servers[u.Scheme+"://"+u.Host] {
+			servers[u.Scheme+"://"+u.Host] = true
+			doc.Servers = append(doc.Servers, Server{URL: u.Scheme + "://" + u.Host})
+		}
+		path, pathParams := templatePath(u.Path)
+		method := strings.ToLower(string(tc.HttpReq.Method))
+		if doc.Paths[path] == nil {
+			doc.Paths[path] = map[string]*Operation{}
+		}
+		op, ok := doc.Paths[path][method]
+		if !ok {
+			op = &Operation{
+				OperationID: operationID(method, path),
+				Parameters:  pathParams,
+				Responses:   map[string]*Response{},
+			}
+			doc.Paths[path][method] = op
+		}
+		op.samples++
+
+		for name, values := range u.Query() {
+			var param *Parameter
+			for _, p := range op.Parameters {
+				if p.In == "query" && p.Name == name {
+					param = p
+				}
+			}
+			if param == nil {
+				param = &Parameter{Name: name, In: "query", Schema: &models.JSONSchema{Type: "string"}}
+				if len(values) > 0 {
+					param.Example = values[0]
+				}
+				op.Parameters = append(op.Parameters, param)
+			}
+			param.seen++
+		}
+
+		if tc.HttpReq.Body != "" {
+			if op.RequestBody == nil {
+				op.RequestBody = &RequestBody{Content: map[string]*MediaType{}}
+			}
+			addContent(op.RequestBody.Content, headerValue(tc.HttpReq.Header, "Content-Type"), tc.HttpReq.Body)
+		}
+
+		status := strconv.Itoa(tc.HttpResp.StatusCode)
+		resp, ok := op.Responses[status]
+		if !ok {
+			resp = &Response{Description: statusDescription(tc.HttpResp.StatusCode), Content: map[string]*MediaType{}}
+			op.Responses[status] = resp
+		}
+		addContent(resp.Content, headerValue(tc.HttpResp.Header, "Content-Type"), tc.HttpResp.Body)
+	}
+
+	for _, operations := range doc.Paths {
+		for _, op := range operations {
+			for _, param := range op.Parameters {
+				if param.In == "query" {
+					param.Required = param.seen == op.samples
+				}
+			}
+			sort.SliceStable(op.Parameters, func(i, j int) bool {
+				return op.Parameters[i].In == "path" && op.Parameters[j].In != "path"
+			})
+		}
+	}
+	sort.Slice(doc.Servers, func(i, j int) bool { return doc.Servers[i].URL < doc.Servers[j].URL })
+	return doc, nil
+}
+
+// statusDescription returns the description of the response of the status code, required by OpenAPI.
+func statusDescription(code int) string {
+	if text := http.StatusText(code); text != "" {
+		return text
+	}
+	return "status " + strconv.Itoa(code)
+}
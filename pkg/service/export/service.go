@@ -0,0 +1,21 @@
+package export
+
+import "go.keploy.io/server/pkg/models"
+
+type Exporter interface {
+	// OpenAPI synthesizes the OpenAPI 3 document of the API exercised by the tests of the test sets, all of them when
+	// none is given.
+	OpenAPI(path string, testSets []string, title string) (*OpenAPI, error)
+	// HAR returns the HTTP Archive of the requests of the tests of the test sets along with their expected responses.
+	HAR(path string, testSets []string) (*models.HAR, error)
+	// WireMock converts the http mocks of the test sets into the stub mappings of WireMock.
+	WireMock(path string, testSets []string) (*WireMockStubs, error)
+	// Mockoon converts the http mocks of the test sets into a Mockoon environment served on the port.
+	Mockoon(path string, testSets []string, name string, port int) (*MockoonEnvironment, error)
+	// K6 returns the k6 script sending the requests of the tests of the test sets in the order of their recording.
+	K6(path string, testSets []string, options LoadTestOptions) (string, error)
+	// Vegeta returns the targets of vegeta attack sending the requests of the tests of the test sets.
+	Vegeta(path string, testSets []string, options LoadTestOptions) (string, error)
+	// Locust returns the locustfile sending the requests of the tests of the test sets in the order of their recording.
+	Locust(path string, testSets []string, options LoadTestOptions) (string, error)
+}
@@ -0,0 +1,129 @@
+package export
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"sort"
+
+	"go.uber.org/zap"
+)
+
+// skippedStubHeaders are the headers of the recorded responses which aren't exported, the bodies being exported
+// decoded and in one piece.
+var skippedStubHeaders = map[string]bool{
+	"Content-Length":    true,
+	"Content-Encoding":  true,
+	"Transfer-Encoding": true,
+}
+
+// WireMockStubs is the document of the stub mappings, imported by WireMock from its mappings directory or its
+// /__admin/mappings/import endpoint.
+type WireMockStubs struct {
+	Mappings []WireMockMapping `json:"mappings"`
+}
+
+type WireMockMapping struct {
+	Name     string           `json:"name,omitempty"`
+	Request  WireMockRequest  `json:"request"`
+	Response WireMockResponse `json:"response"`
+}
+
+type WireMockRequest struct {
+	Method          string                     `json:"method"`
+	URLPath         string                     `json:"urlPath"`
+	QueryParameters map[string]WireMockPattern `json:"queryParameters,omitempty"`
+	BodyPatterns    []WireMockPattern          `json:"bodyPatterns,omitempty"`
+}
+
+// WireMockPattern matches a value of the request, one of its fields being set.
+type WireMockPattern struct {
+	EqualTo     string            `json:"equalTo,omitempty"`
+	EqualToJSON json.RawMessage   `json:"equalToJson,omitempty"`
+	HasExactly  []WireMockPattern `json:"hasExactly,omitempty"`
+}
+
+type WireMockResponse struct {
+	Status  int               `json:"status"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Body    string            `json:"body,omitempty"`
+}
+
+func (e *exporter) WireMock(path string, testSets []string) (*WireMockStubs, error) {
+	mocks, err := e.readMocks(path, testSets)
+	if err != nil {
+		return nil, err
+	}
+	stubs := &WireMockStubs{Mappings: []WireMockMapping{}}
+	for _, mock := range mocks {
+		mockURL, err := url.Parse(mock.spec.Request.URL)
+		if err != nil {
+			e.logger.Warn("skipping the mock whose url can't be parsed", zap.String("mock", mock.name), zap.Error(err))
+			continue
+		}
+		stubs.Mappings = append(stubs.Mappings, wireMockMapping(mock, mockURL))
+	}
+	return stubs, nil
+}
+
+// wireMockMapping returns the mapping answering the request of the mock, with the same path, query parameters and
+// body, with its recorded response.
+func wireMockMapping(mock httpMock, mockURL *url.URL) WireMockMapping {
+	req, resp := mock.spec.Request, mock.spec.Response
+	mapping := WireMockMapping{
+		Name: mock.name,
+		Request: WireMockRequest{
+			Method:  string(req.Method),
+			URLPath: mockURL.Path,
+		},
+		Response: WireMockResponse{
+			Status: resp.StatusCode,
+			Body:   resp.Body,
+		},
+	}
+	if mapping.Request.URLPath == "" {
+		mapping.Request.URLPath = "/"
+	}
+	query := mockURL.Query()
+	if len(query) > 0 {
+		mapping.Request.QueryParameters = map[string]WireMockPattern{}
+		for name, values := range query {
+			if len(values) == 1 {
+				mapping.Request.QueryParameters[name] = WireMockPattern{EqualTo: values[0]}
+				continue
+			}
+			pattern := WireMockPattern{}
+			for _, value := range values {
+				pattern.HasExactly = append(pattern.HasExactly, WireMockPattern{EqualTo: value})
+			}
+			mapping.Request.QueryParameters[name] = pattern
+		}
+	}
+	if req.Body != "" {
+		if json.Valid([]byte(req.Body)) {
+			mapping.Request.BodyPatterns = []WireMockPattern{{EqualToJSON: json.RawMessage(req.Body)}}
+		} else {
+			mapping.Request.BodyPatterns = []WireMockPattern{{EqualTo: req.Body}}
+		}
+	}
+	for _, name := range stubHeaderNames(resp.Header) {
+		if mapping.Response.Headers == nil {
+			mapping.Response.Headers = map[string]string{}
+		}
+		mapping.Response.Headers[name] = resp.Header[name]
+	}
+	return mapping
+}
+
+// stubHeaderNames returns the sorted names of the headers of the recorded response which are exported.
+func stubHeaderNames(header map[string]string) []string {
+	names := make([]string, 0, len(header))
+	for name := range header {
+		if skippedStubHeaders[http.CanonicalHeaderKey(name)] {
+			continue
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -34,9 +34,117 @@ record:
   delay: 5
   buildDelay: 30s
   passThroughPorts: []
+  denoisePasses: 0
+  # skips the requests identical to one already recorded in the session
+  dedup: false
+  # feeds the application the random bytes generated from a seed saved with the test set, so that the ids, nonces and
+  # tokens it generates are generated again during keploy test --deterministic-random
+  deterministicRandom: false
+  # records the order and the case of the headers of the tests and the mocks, which are replayed with them byte for
+  # byte, for the clients and the signatures depending on them
+  headerFidelity: false
+  # stores the http bodies of the tests and mocks larger than maxInlineSize bytes (0 for no limit) in files of the
+  # assets directory next to their yaml file, gzipped with compress, with their sha256 in the yaml
+  bodyStorage:
+    maxInlineSize: 0
+    compress: false
+  # the format the recorded mocks are stored in: yaml, or cbor (mocks.cbor), a binary form of the same documents which
+  # loads quicker for the recordings of hundreds of MB. keploy mocks convert converts the test sets between them.
+  mockFormat: yaml
+  # the bytes of the calls passed through the proxy reads before writing them to the other side of the connection, the
+  # faster side being held back until the slower one reads them, so that a slow dependency doesn't make keploy buffer
+  # the calls of the application without bound. The messages recorded or mocked are read whole. 0 for no bound.
+  maxBuffer: 0
+  # the bytes of the messages the proxy reads whole to record or mock them. The calls whose first message is larger
+  # are passed through, and the ones sending a larger message later on are ended. 0 for 64 MiB, -1 for no bound.
+  maxMessage: 0
+  # the fields, like header.Date or body.updatedAt, written as noise on every recorded testcase
+  noise: []
+  # applies the changes of noise, passThrough, passThroughPorts and filters made to this file to the recording in
+  # progress, without restarting it. The other settings are read when the recording starts.
+  watchConfig: false
+  # unix sockets of the dependencies captured like the TCP ones, the protocol being needed for the ones where the
+  # server speaks first.
+  # example:
+  #   unixSockets:
+  #     - path: /var/run/mysqld/mysqld.sock
+  #       protocol: mysql
+  unixSockets: []
+  # when the eBPF hooks can't be loaded, the calls to the application sent to the ingress port are recorded and
+  # forwarded to the application on appPort. The calls to the other ports of appPorts are sent to the ingress ports
+  # following port. The testcases of an application recorded on several ports are tagged with their port, e.g. port-8080.
+  ingress:
+    port: 0
+    appPort: 0
+    appPorts: []
+  # how the calls of the application are captured: auto (the eBPF hooks, or the userspace proxy when they can't be
+  # loaded), ebpf, or without any privilege proxy (HTTP_PROXY and ALL_PROXY) and preload (LD_PRELOAD connect()).
+  captureMode: ""
+  # runs the application as a service of a docker compose file instead of the command: the whole stack is brought up
+  # and the calls of the service are recorded, the other services being its dependencies.
+  compose:
+    file: ""
+    service: ""
+  # the descriptor sets (protoc --descriptor_set_out --include_imports) decoding the protobuf messages of the gRPC calls
+  # and of the http bodies, which are stored in their JSON form. The message of an http body is read from the proto
+  # parameter of its content type, or from the first route matching the path of the call.
+  # example:
+  #   protobufRoutes:
+  #     - path: "^/v1/users/[^/]+$"
+  #       method: GET
+  #       response: users.v1.User
+  protoDescriptors: []
+  protobufRoutes: []
+  # masks the sensitive values of the recorded tests and mocks with tokens derived from them.
+  # example:
+  #   redact:
+  #     headers: ["Authorization", "Cookie"]
+  #     fields: ["$.user.password", "$..ssn"]
+  #     patterns: ["sk_live_[0-9a-zA-Z]+"]
+  #     detectors: ["email", "card", "jwt"]
+  redact:
+    patterns: []
+    headers: []
+    fields: []
+    detectors: []
+  # outgoing calls forwarded untouched to their server, each rule selecting the calls matching all of its fields,
+  # e.g. - host: telemetry.example.com
+  #      - cidr: 10.0.0.0/8
+  #        port: 443
+  #      - protocol: http
+  #        port: 9000
+  passThrough: []
   filters:
     ReqHeader: []
     urlMethods: {}
+    # only the inbound calls matching one of the include rules, and none of the exclude ones, are recorded.
+    # example:
+    #   include:
+    #     - path: "^/api/"
+    #       methods: ["GET", "POST"]
+    #       ports: [8080]
+    #   exclude:
+    #     - path: "^/(health|metrics)$"
+    #     - headers:
+    #         User-Agent: "kube-probe.*"
+    include: []
+    exclude: []
+    # bounds the calls recorded for each route (method and path, the ids in the path aside) on busy environments:
+    # rate is the probability of a call to be recorded, perMinute and maxPerRoute the number of calls of a route
+    # recorded each minute and in the session, 0 meaning unbounded. The rules override them for the calls they match.
+    # example:
+    #   rate: 0.1
+    #   perMinute: 5
+    #   maxPerRoute: 20
+    #   rules:
+    #     - path: "^/api/search"
+    #       methods: ["GET"]
+    #       maxPerRoute: 3
+    sampling:
+      rate: 0
+      perMinute: 0
+      maxPerRoute: 0
+      rules: []
 test:
   path: ""
   # mandatory
@@ -46,6 +154,51 @@ test:
   networkName: ""
   # example: "test-set-1": ["test-1", "test-2", "test-3"]
   tests: 
+  # only the tests having one of the tags, set as "tags: smoke, auth" in the metadata of their yaml, are run
+  tags: []
+  # keploy test --watch runs the tests again when the sources or the binary of the application change, rebuilding it
+  # with buildCmd first, e.g. paths: ["./src"] and buildCmd: "go build -o app ."
+  watch:
+    paths: []
+    buildCmd: ""
+  # the application observes the recorded time of the tests: libfaketime (faketime package, or libPath) is preloaded in
+  # the native and docker run applications, and the replayed requests carry the recorded time in the header
+  timeFreezing:
+    enabled: false
+    libPath: ""
+    header: "Keploy-Time"
+  # feeds the application the random bytes generated from the seed of the recording of the test sets recorded with
+  # deterministicRandom
+  deterministicRandom: false
+  # the responses of the mocks are delayed by the latency recorded for their calls, multiplied by simulateLatency,
+  # e.g. 1 for the recorded latency and 0.5 for half of it. 0 answers at once.
+  simulateLatency: 0
+  # keploy test --chaos replays the mocks selected by the faults as failures, to test the resilience of the
+  # application. The first fault selecting a mock sets its failure: "reset" (the connection is reset), "status" (an
+  # error response, 503 by default), "truncate" (the connection is closed in the middle of the body) apply to the http
+  # mocks, "latency" (the response is delayed) to the mocks of any kind. probability is the chance the failure
+  # happens, 1 by default, e.g.
+  # - path: "^/payments"
+  #   type: status
+  #   status: 502
+  #   probability: 0.5
+  # - kind: Postgres
+  #   type: latency
+  #   latency: 2s
+  chaos:
+    enabled: false
+    faults: []
+  # unix sockets of the dependencies mocked like the TCP ones, see the record section.
+  unixSockets: []
+  # how the calls of the application are captured, see the record section.
+  captureMode: ""
+  # the service of the docker compose file is brought up alone, the services it depends on being mocked.
+  compose:
+    file: ""
+    service: ""
+  # the descriptor sets and the routes of the protobuf bodies, see the record section.
+  protoDescriptors: []
+  protobufRoutes: []
   # to use globalNoise, please follow the guide at the end of this file.
   globalNoise:
     global:
@@ -55,8 +208,69 @@ test:
   buildDelay: 30s
   apiTimeout: 5
   passThroughPorts: []
+  # outgoing calls forwarded untouched to their server, like in the record section
+  passThrough: []
   withCoverage: false
   coverageReportPath: ""
+  # go coverage profiles of the unit tests (go test -coverprofile) merged with the coverage of the test run
+  unitCoverage: []
+  language: ""
+  # number of test sets run concurrently, each one by a keploy process with its own proxy and application container
+  # (docker run applications only)
+  parallel: 0
+  # formats the test reports are written in besides yaml: json and junit (JUnit XML) next to them, and html (side by
+  # side comparison of the responses) in keploy/reports
+  reportFormats: []
+  http:
+    # match the Elasticsearch/OpenSearch requests on their normalised path and body
+    elasticsearch:
+      enabled: false
+      hosts: []
+      fuzzyFields: []
+    # replay the messages of the WebSocket servers and the events of the text/event-stream responses with their
+    # recorded delays ("recorded") or without ("immediate"), speed dividing the delays
+    webSocket:
+      timing: recorded
+      speed: 1
+    eventStream:
+      timing: recorded
+      speed: 1
+    # replay the chunked responses chunk by chunk, with their recorded delays and their trailers, rather than as a
+    # single body
+    chunked:
+      enabled: false
+      timing: recorded
+      speed: 1
+    # match the requests which no mock matches exactly to the mock with the most similar method, path and body,
+    # if its score (between 0 and 1) reaches the threshold
+    fuzzyMatch:
+      enabled: false
+      threshold: 0.8
+  sql:
+    # "exact" to match the MySQL/Postgres queries on their text only, or "fingerprint" to match the queries which no
+    # mock matches exactly on their text with their literals replaced by "?", whatever the values of the literals
+    matching: exact
+  # matching rules of the mocks of a kind (Mongo, Http, SQL for MySQL, Postgres): the strategy ("exact", or
+  # "fingerprint" for the queries) and the fields of the requests whose values are ignored, e.g.
+  # - kind: Mongo
+  #   ignoreFields: ["lsid", "txnNumber", "$clusterTime"]
+  # - kind: Http
+  #   ignoreFields: ["header.X-Request-Id", "body.timestamp"]
+  # - kind: SQL
+  #   strategy: fingerprint
+  matchers: []
+  # how the mocks are consumed on replay: "any-order" (once, whatever the order), "strict-sequence" (once, after
+  # the mocks of the same kind recorded before), "connection-sequence" (once, after the mocks recorded before on the
+  # same connection) or "repeatable" (matched any number of times, e.g. by retries)
+  mockMatching:
+    default: any-order
+    # the first rule selecting a mock sets its policy, e.g.
+    # - kind: Redis
+    #   policy: repeatable
+    # - testSet: "test-set-[0-9]+"
+    #   name: "mock-1[0-9]"
+    #   policy: strict-sequence
+    rules: []
   #
   # Example on using globalNoise
   # globalNoise: 
@@ -65,10 +279,15 @@ test:
   #         # to ignore some values for a field, 
   #         # pass regex patterns to the corresponding array value
   #         "url": ["https?://\S+", "http://\S+"],
+  #         # the fields can also be selected through JSONPath expressions
+  #         "$.data[*].updated_at": [],
+  #         "$..request_id": [],
   #      }
   #      header: {
   #         # to ignore the entire field, pass an empty array
   #         "Date: [],
+  #         # the names are matched regardless of their case, and can be regexes
+  #         "^x-request-.*": [],
   #       }
   #     # to ignore fields or the corresponding values for a specific test-set,
   #     # pass the test-set-name as a key to the "test-sets" object and
@@ -83,6 +302,15 @@ test:
   #           # we can also pass the exact value to ignore for a field
   #           "User-Agent": ["PostmanRuntime/7.34.0"]
   #         }
+# the storage the test sets are pushed to (keploy storage push) and pulled from, the test sets missing from the
+# keploy directory being pulled by keploy test. type is fs, s3, minio or gcs, the credentials being read from
+# AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY (the HMAC keys for gcs).
+storage:
+  type: ""
+  bucket: ""
+  prefix: ""
+  endpoint: ""
+  region: ""
 `
 
 func (g *generatorConfig) GenerateConfig(filePath string) {
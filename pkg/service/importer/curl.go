@@ -0,0 +1,287 @@
+package importer
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"go.keploy.io/server/pkg/models"
+	"go.uber.org/zap"
+)
+
+func (i *importer) Curl(path, file, testSet string) (string, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return "", fmt.Errorf("failed to read the curl file %s: %v", file, err)
+	}
+	var tcs []*models.TestCase
+	for index, command := range splitCurlCommands(string(data)) {
+		args, err := shellWords(command)
+		if err != nil {
+			i.logger.Warn("skipping the curl command", zap.Int("command", index+1), zap.Error(err))
+			continue
+		}
+		req, err := parseCurl(args, filepath.Dir(file))
+		if err != nil {
+			i.logger.Warn("skipping the curl command", zap.Int("command", index+1), zap.Error(err))
+			continue
+		}
+		tcs = append(tcs, newTestCase(*req, nil, 200))
+	}
+	return i.write(path, testSet, tcs)
+}
+
+// splitCurlCommands returns the curl commands of the file, each one starting on a line beginning with curl and
+// going on over the lines ending with a backslash. The other lines, like the comments, are skipped.
+func splitCurlCommands(data string) []string {
+	var commands []string
+	var current strings.Builder
+	continued := false
+	for _, line := range strings.Split(strings.ReplaceAll(data, "\r\n", "\n"), "\n") {
+		trimmed := strings.TrimSpace(line)
+		if !continued {
+			if !strings.HasPrefix(trimmed, "curl ") {
+				continue
+			}
+			current.Reset()
+		}
+		continued = strings.HasSuffix(trimmed, `\`)
+		current.WriteString(strings.TrimSuffix(trimmed, `\`))
+		current.WriteString(" ")
+		if !continued {
+			commands = append(commands, current.String())
+		}
+	}
+	if continued {
+		commands = append(commands, current.String())
+	}
+	return commands
+}
+
+// shellWords splits the command into its arguments as a POSIX shell does, with the single, double and $'...' quotes.
+func shellWords(command string) ([]string, error) {
+	var words []string
+	var word strings.Builder
+	inWord := false
+	for i := 0; i < len(command); i++ {
+		c := command[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n':
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+		case c == '\'':
+			end := strings.IndexByte(command[i+1:], '\'')
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated single quote")
+			}
+			word.WriteString(command[i+1 : i+1+end])
+			i += end + 1
+			inWord = true
+		case c == '$' && i+1 < len(command) && command[i+1] == '\'':
+			i += 2
+			for ; i < len(command) && command[i] != '\''; i++ {
+				if command[i] == '\\' && i+1 < len(command) {
+					i++
+					switch command[i] {
+					case 'n':
+						word.WriteByte('\n')
+					case 't':
+						word.WriteByte('\t')
+					case 'r':
+						word.WriteByte('\r')
+					default:
+						word.WriteByte(command[i])
+					}
+					continue
+				}
+				word.WriteByte(command[i])
+			}
+			if i >= len(command) {
+				return nil, fmt.Errorf("unterminated $' quote")
+			}
+			inWord = true
+		case c == '"':
+			i++
+			for ; i < len(command) && command[i] != '"'; i++ {
+				if command[i] == '\\' && i+1 < len(command) && strings.IndexByte("\"\\$`", command[i+1]) >= 0 {
+					i++
+				}
+				word.WriteByte(command[i])
+			}
+			if i >= len(command) {
+				return nil, fmt.Errorf("unterminated double quote")
+			}
+			inWord = true
+		case c == '\\' && i+1 < len(command):
+			i++
+			word.WriteByte(command[i])
+			inWord = true
+		default:
+			word.WriteByte(c)
+			inWord = true
+		}
+	}
+	if inWord {
+		words = append(words, word.String())
+	}
+	return words, nil
+}
+
+// parseCurl returns the request of the arguments of a curl command, the files of its @file data being relative to dir.
+func parseCurl(args []string, dir string) (*models.HttpReq, error) {
+	if len(args) == 0 || args[0] != "curl" {
+		return nil, fmt.Errorf("not a curl command")
+	}
+	req := &models.HttpReq{Header: map[string]string{}}
+	var (
+		rawURL  string
+		method  string
+		data    []string
+		get     bool
+		headers []string
+	)
+	readData := func(value string, raw bool) (string, error) {
+		if raw || !strings.HasPrefix(value, "@") {
+			return value, nil
+		}
+		file := value[1:]
+		if !filepath.IsAbs(file) {
+			file = filepath.Join(dir, file)
+		}
+		content, err := os.ReadFile(file)
+		if err != nil {
+			return "", fmt.Errorf("failed to read the data file %s: %v", value[1:], err)
+		}
+		return string(content), nil
+	}
+	for i := 1; i < len(args); i++ {
+		arg := args[i]
+		// the value of the option, given as the next argument or after = for the long options
+		value := func() (string, error) {
+			if i+1 >= len(args) {
+				return "", fmt.Errorf("the option %s has no value", arg)
+			}
+			i++
+			return args[i], nil
+		}
+		if strings.HasPrefix(arg, "--") && strings.Contains(arg, "=") {
+			parts := strings.SplitN(arg, "=", 2)
+			arg = parts[0]
+			args = append(args[:i+1], append([]string{parts[1]}, args[i+1:]...)...)
+		}
+		var err error
+		var v string
+		switch arg {
+		case "-X", "--request":
+			method, err = value()
+		case "-H", "--header":
+			v, err = value()
+			headers = append(headers, v)
+		case "-d", "--data", "--data-ascii", "--data-binary", "--data-raw":
+			if v, err = value(); err == nil {
+				v, err = readData(v, arg == "--data-raw")
+				data = append(data, v)
+			}
+		case "--data-urlencode":
+			if v, err = value(); err == nil {
+				if name, content, ok := strings.Cut(v, "="); ok {
+					v = url.QueryEscape(name) + "=" + url.QueryEscape(content)
+				} else {
+					v = url.QueryEscape(v)
+				}
+				data = append(data, v)
+			}
+		case "--json":
+			if v, err = value(); err == nil {
+				v, err = readData(v, false)
+				data = append(data, v)
+				headers = append(headers, "Content-Type: application/json", "Accept: application/json")
+			}
+		case "-u", "--user":
+			if v, err = value(); err == nil {
+				req.Header["Authorization"] = "Basic " + base64.StdEncoding.EncodeToString([]byte(v))
+			}
+		case "-A", "--user-agent":
+			if v, err = value(); err == nil {
+				req.Header["User-Agent"] = v
+			}
+		case "-b", "--cookie":
+			if v, err = value(); err == nil {
+				req.Header["Cookie"] = v
+			}
+		case "-e", "--referer":
+			if v, err = value(); err == nil {
+				req.Header["Referer"] = v
+			}
+		case "--url":
+			rawURL, err = value()
+		case "-G", "--get":
+			get = true
+		case "-I", "--head":
+			method = "HEAD"
+		case "-F", "--form":
+			return nil, fmt.Errorf("the multipart forms of curl aren't supported")
+		case "-o", "--output", "-m", "--max-time", "--connect-timeout", "-w", "--write-out", "-x", "--proxy", "--retry", "-c", "--cookie-jar", "--cacert", "--cert", "--key", "-E":
+			// the options with a value which doesn't change the request
+			_, err = value()
+		default:
+			if !strings.HasPrefix(arg, "-") && rawURL == "" {
+				rawURL = arg
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	if rawURL == "" {
+		return nil, fmt.Errorf("the curl command has no url")
+	}
+	if !strings.Contains(rawURL, "://") {
+		rawURL = "http://" + rawURL
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse the url %s: %v", rawURL, err)
+	}
+	for _, header := range headers {
+		name, value, ok := strings.Cut(header, ":")
+		if !ok {
+			continue
+		}
+		req.Header[strings.TrimSpace(name)] = strings.TrimSpace(value)
+	}
+
+	body := strings.Join(data, "&")
+	switch {
+	case get && len(data) > 0:
+		// the data is sent as the query of the url
+		if u.RawQuery != "" {
+			u.RawQuery += "&"
+		}
+		u.RawQuery += body
+		body = ""
+		if method == "" {
+			method = "GET"
+		}
+	case len(data) > 0:
+		if method == "" {
+			method = "POST"
+		}
+		if headerValue(req.Header, "Content-Type") == "" {
+			req.Header["Content-Type"] = "application/x-www-form-urlencoded"
+		}
+	case method == "":
+		method = "GET"
+	}
+	req.Method = models.Method(strings.ToUpper(method))
+	req.URL = u.String()
+	req.Host = u.Host
+	req.Body = body
+	return req, nil
+}
@@ -0,0 +1,198 @@
+package importer
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.keploy.io/server/pkg/models"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// maxSampleDepth bounds the nesting of the sample messages, the recursive messages being left empty below it.
+const maxSampleDepth = 3
+
+func (i *importer) GRPC(path, address, testSet string, useTLS bool) (string, error) {
+	client := newReflectionClient(address, useTLS)
+	services, err := client.listServices()
+	if err != nil {
+		return "", err
+	}
+	var generated []string
+	for _, service := range services {
+		if !strings.HasPrefix(service, "grpc.reflection.") {
+			generated = append(generated, service)
+		}
+	}
+	if len(generated) == 0 {
+		return "", fmt.Errorf("the server at %s has no service other than the reflection one", address)
+	}
+	files, err := client.files(generated)
+	if err != nil {
+		return "", err
+	}
+
+	scheme := "https"
+	if !useTLS {
+		scheme = "http"
+	}
+	var tcs []*models.TestCase
+	for _, service := range generated {
+		descriptor, err := files.FindDescriptorByName(protoreflect.FullName(service))
+		if err != nil {
+			i.logger.Warn("skipping the service missing from the descriptors of the server", zap.String("service", service), zap.Error(err))
+			continue
+		}
+		serviceDescriptor, ok := descriptor.(protoreflect.ServiceDescriptor)
+		if !ok {
+			continue
+		}
+		methods := serviceDescriptor.Methods()
+		for m := 0; m < methods.Len(); m++ {
+			tc, err := newGrpcTestCase(methods.Get(m), address, scheme)
+			if err != nil {
+				i.logger.Warn("skipping the method", zap.String("method", string(methods.Get(m).FullName())), zap.Error(err))
+				continue
+			}
+			tcs = append(tcs, tc)
+		}
+	}
+	return i.write(path, testSet, tcs)
+}
+
+// newGrpcTestCase returns the skeleton test of the method, whose request is a sample of its input message. The test
+// expects the call to succeed, the expected response being left to be filled in.
+func newGrpcTestCase(method protoreflect.MethodDescriptor, address, scheme string) (*models.TestCase, error) {
+	request := sampleMessage(method.Input(), 0)
+	encoded, err := proto.Marshal(request)
+	if err != nil {
+		return nil, err
+	}
+	body, err := protojson.Marshal(request)
+	if err != nil {
+		return nil, err
+	}
+	// protojson randomises its whitespaces on purpose
+	var indented bytes.Buffer
+	if err := json.Indent(&indented, body, "", "  "); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	return &models.TestCase{
+		Version: models.GetVersion(),
+		Kind:    models.GRPC_EXPORT,
+		Created: now.Unix(),
+		GrpcReq: models.GrpcReq{
+			Headers: models.GrpcHeaders{
+				PseudoHeaders: map[string]string{
+					":method":    "POST",
+					":path":      fmt.Sprintf("/%s/%s", method.Parent().FullName(), method.Name()),
+					":scheme":    scheme,
+					":authority": address,
+				},
+				OrdinaryHeaders: map[string]string{
+					"content-type": "application/grpc",
+					"te":           "trailers",
+				},
+			},
+			Body: models.GrpcLengthPrefixedMessage{
+				MessageLength: uint32(len(encoded)),
+				MessageType:   string(method.Input().FullName()),
+				DecodedData:   indented.String(),
+			},
+		},
+		GrpcResp: models.GrpcResp{
+			Headers: models.GrpcHeaders{
+				PseudoHeaders:   map[string]string{":status": "200"},
+				OrdinaryHeaders: map[string]string{"content-type": "application/grpc"},
+			},
+			Body: models.GrpcLengthPrefixedMessage{
+				MessageType: string(method.Output().FullName()),
+			},
+			Trailers: models.GrpcHeaders{
+				PseudoHeaders:   map[string]string{},
+				OrdinaryHeaders: map[string]string{"grpc-status": "0"},
+			},
+		},
+		Noise: map[string][]string{},
+	}, nil
+}
+
+// sampleMessage returns the message with a sample value in each of its fields: the name of the field for the
+// strings and the bytes, 1 for the numbers, true, the first value of the enum other than the default one, and one
+// element in the lists and the maps. Only the first field of a oneof is set, and the well known types are left
+// empty.
+func sampleMessage(descriptor protoreflect.MessageDescriptor, depth int) *dynamicpb.Message {
+	message := dynamicpb.NewMessage(descriptor)
+	if depth >= maxSampleDepth {
+		return message
+	}
+	fields := descriptor.Fields()
+	for f := 0; f < fields.Len(); f++ {
+		field := fields.Get(f)
+		if oneof := field.ContainingOneof(); oneof != nil && oneof.Fields().Get(0) != field {
+			continue
+		}
+		switch {
+		case field.IsMap():
+			key, ok := sampleValue(field.MapKey(), depth)
+			value, valueOk := sampleValue(field.MapValue(), depth)
+			if ok && valueOk {
+				message.Mutable(field).Map().Set(key.MapKey(), value)
+			}
+		case field.IsList():
+			if value, ok := sampleValue(field, depth); ok {
+				message.Mutable(field).List().Append(value)
+			}
+		default:
+			if value, ok := sampleValue(field, depth); ok {
+				message.Set(field, value)
+			}
+		}
+	}
+	return message
+}
+
+// sampleValue returns the sample value of a single element of the field.
+func sampleValue(field protoreflect.FieldDescriptor, depth int) (protoreflect.Value, bool) {
+	switch field.Kind() {
+	case protoreflect.BoolKind:
+		return protoreflect.ValueOfBool(true), true
+	case protoreflect.EnumKind:
+		values := field.Enum().Values()
+		if values.Len() > 1 {
+			return protoreflect.ValueOfEnum(values.Get(1).Number()), true
+		}
+		return protoreflect.ValueOfEnum(values.Get(0).Number()), true
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+		return protoreflect.ValueOfInt32(1), true
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		return protoreflect.ValueOfInt64(1), true
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		return protoreflect.ValueOfUint32(1), true
+	case protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		return protoreflect.ValueOfUint64(1), true
+	case protoreflect.FloatKind:
+		return protoreflect.ValueOfFloat32(1.5), true
+	case protoreflect.DoubleKind:
+		return protoreflect.ValueOfFloat64(1.5), true
+	case protoreflect.StringKind:
+		return protoreflect.ValueOfString(string(field.Name())), true
+	case protoreflect.BytesKind:
+		return protoreflect.ValueOfBytes([]byte(field.Name())), true
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		// the JSON forms of the well known types, like google.protobuf.Value, don't allow all their empty values
+		if strings.HasPrefix(string(field.Message().FullName()), "google.protobuf.") {
+			return protoreflect.Value{}, false
+		}
+		return protoreflect.ValueOfMessage(sampleMessage(field.Message(), depth+1)), true
+	}
+	return protoreflect.Value{}, false
+}
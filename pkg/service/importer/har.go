@@ -0,0 +1,147 @@
+package importer
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/textproto"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"go.keploy.io/server/pkg/models"
+	"go.uber.org/zap"
+)
+
+func (i *importer) HAR(path, file, testSet, urlFilter string) (string, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return "", fmt.Errorf("failed to read the har file %s: %v", file, err)
+	}
+	var har models.HAR
+	if err := json.Unmarshal(data, &har); err != nil {
+		return "", fmt.Errorf("failed to parse the har file %s: %v", file, err)
+	}
+	var filter *regexp.Regexp
+	if urlFilter != "" {
+		filter, err = regexp.Compile(urlFilter)
+		if err != nil {
+			return "", fmt.Errorf("invalid url filter %q: %v", urlFilter, err)
+		}
+	}
+
+	var tcs []*models.TestCase
+	for index, entry := range har.Log.Entries {
+		if !strings.HasPrefix(entry.Request.URL, "http://") && !strings.HasPrefix(entry.Request.URL, "https://") {
+			continue
+		}
+		if filter != nil && !filter.MatchString(entry.Request.URL) {
+			continue
+		}
+		if entry.Response.Status == 0 {
+			// the request failed, or was blocked, without a response
+			i.logger.Debug("skipping the har entry without response", zap.String("url", entry.Request.URL))
+			continue
+		}
+		tc, err := harTestCase(entry)
+		if err != nil {
+			i.logger.Warn("skipping the har entry", zap.Int("entry", index+1), zap.String("url", entry.Request.URL), zap.Error(err))
+			continue
+		}
+		tcs = append(tcs, tc)
+	}
+	return i.write(path, testSet, tcs)
+}
+
+// harTestCase returns the test of the entry, its response being the expected one.
+func harTestCase(entry models.HAREntry) (*models.TestCase, error) {
+	u, err := url.Parse(entry.Request.URL)
+	if err != nil {
+		return nil, err
+	}
+	req := models.HttpReq{
+		Method: models.Method(strings.ToUpper(entry.Request.Method)),
+		URL:    u.String(),
+		Host:   u.Host,
+		Header: harHeaders(entry.Request.Headers),
+	}
+	req.ProtoMajor, req.ProtoMinor = harProto(entry.Request.HTTPVersion)
+	if entry.Request.PostData != nil {
+		req.Body = entry.Request.PostData.Text
+		if req.Body == "" && len(entry.Request.PostData.Params) > 0 {
+			form := url.Values{}
+			for _, param := range entry.Request.PostData.Params {
+				form.Add(param.Name, param.Value)
+			}
+			req.Body = form.Encode()
+		}
+		if headerValue(req.Header, "Content-Type") == "" && entry.Request.PostData.MimeType != "" {
+			req.Header["Content-Type"] = entry.Request.PostData.MimeType
+		}
+	}
+
+	resp := &models.HttpResp{
+		StatusCode:    entry.Response.Status,
+		StatusMessage: entry.Response.StatusText,
+		Header:        harHeaders(entry.Response.Headers),
+		Body:          entry.Response.Content.Text,
+	}
+	resp.ProtoMajor, resp.ProtoMinor = harProto(entry.Response.HTTPVersion)
+	binary := false
+	if entry.Response.Content.Encoding == "base64" {
+		body, err := base64.StdEncoding.DecodeString(entry.Response.Content.Text)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode the body of the response: %v", err)
+		}
+		resp.Body = string(body)
+		binary = !utf8.Valid(body)
+	}
+	// the body is already decoded by the browsers, the replayed response is compared once decoded as well
+	delete(resp.Header, "Content-Encoding")
+	delete(resp.Header, "Content-Length")
+
+	tc := newTestCase(req, resp, 0)
+	if binary {
+		tc.HttpResp.Body = ""
+		tc.Noise["body"] = []string{}
+	}
+	if started, err := time.Parse(time.RFC3339Nano, entry.StartedDateTime); err == nil {
+		tc.HttpReq.Timestamp = started
+		tc.HttpResp.Timestamp = started.Add(time.Duration(entry.Time * float64(time.Millisecond)))
+		tc.Created = started.Unix()
+	}
+	return tc, nil
+}
+
+// harHeaders returns the headers, the repeated ones being joined and the pseudo headers of HTTP/2 dropped.
+func harHeaders(headers []models.HARNameValue) map[string]string {
+	result := map[string]string{}
+	for _, header := range headers {
+		if strings.HasPrefix(header.Name, ":") {
+			continue
+		}
+		// the names of the headers of HTTP/2 are lowercase in the har files
+		name := textproto.CanonicalMIMEHeaderKey(header.Name)
+		if existing, ok := result[name]; ok {
+			result[name] = existing + ", " + header.Value
+			continue
+		}
+		result[name] = header.Value
+	}
+	return result
+}
+
+// harProto returns the major and minor version of the http version of the har, like HTTP/1.1 or h2.
+func harProto(version string) (int, int) {
+	switch strings.ToLower(version) {
+	case "http/1.0":
+		return 1, 0
+	case "http/2", "http/2.0", "h2":
+		return 2, 0
+	default:
+		return 1, 1
+	}
+}
@@ -0,0 +1,93 @@
+package importer
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.keploy.io/server/pkg/models"
+	"go.keploy.io/server/pkg/platform/yaml"
+	"go.uber.org/zap"
+)
+
+const testsDir = "tests"
+
+type importer struct {
+	logger *zap.Logger
+}
+
+func NewImporter(logger *zap.Logger) Importer {
+	return &importer{
+		logger: logger,
+	}
+}
+
+// newTestCase returns the http test of the request. The imported requests mostly come without their response, in
+// which case the test expects the status of the response and ignores its headers and body.
+func newTestCase(req models.HttpReq, resp *models.HttpResp, status int) *models.TestCase {
+	now := time.Now()
+	if req.ProtoMajor == 0 {
+		req.ProtoMajor, req.ProtoMinor = 1, 1
+	}
+	req.Timestamp = now
+	tc := &models.TestCase{
+		Version: models.GetVersion(),
+		Kind:    models.HTTP,
+		Created: now.Unix(),
+		HttpReq: req,
+		Noise:   map[string][]string{},
+	}
+	if resp != nil {
+		tc.HttpResp = *resp
+	} else {
+		tc.HttpResp = models.HttpResp{StatusCode: status, Header: map[string]string{}}
+		tc.Noise["body"] = []string{}
+		tc.Noise["header"] = []string{}
+	}
+	if tc.HttpResp.ProtoMajor == 0 {
+		tc.HttpResp.ProtoMajor, tc.HttpResp.ProtoMinor = 1, 1
+	}
+	tc.HttpResp.Timestamp = now
+	return tc
+}
+
+// write writes the tests into the test set, after its existing tests when it already exists.
+func (i *importer) write(path, testSet string, tcs []*models.TestCase) (string, error) {
+	if len(tcs) == 0 {
+		return "", fmt.Errorf("no request to import")
+	}
+	if testSet == "" {
+		var err error
+		testSet, err = yaml.NewSessionIndex(path, i.logger)
+		if err != nil {
+			return "", err
+		}
+	} else if strings.ContainsAny(testSet, `/\`) || testSet == "." || testSet == ".." {
+		return "", fmt.Errorf("invalid test set name %q", testSet)
+	}
+	dir := filepath.Join(path, testSet, testsDir)
+	names, err := yaml.TestcaseNames(dir)
+	if err != nil {
+		return "", err
+	}
+	index := 0
+	if len(names) > 0 {
+		index, _ = strconv.Atoi(strings.TrimPrefix(names[len(names)-1], "test-"))
+	}
+	for _, tc := range tcs {
+		index++
+		tc.Name = fmt.Sprintf("test-%d", index)
+		doc, err := yaml.EncodeTestcase(*tc, i.logger)
+		if err != nil {
+			return "", err
+		}
+		doc.Name = tc.Name
+		if err := yaml.WriteDocs(dir, tc.Name, []*yaml.NetworkTrafficDoc{doc}); err != nil {
+			return "", err
+		}
+	}
+	i.logger.Info("imported the requests", zap.String("testSet", testSet), zap.Int("testcases", len(tcs)))
+	return testSet, nil
+}
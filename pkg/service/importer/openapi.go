@@ -0,0 +1,469 @@
+package importer
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/textproto"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"go.keploy.io/server/pkg/models"
+	"go.uber.org/zap"
+	yamlLib "gopkg.in/yaml.v3"
+)
+
+// openAPIMethods are the methods of the operations of a path item, in the order their tests are generated.
+var openAPIMethods = []string{"get", "put", "post", "patch", "delete", "head", "options", "trace"}
+
+// maxRefDepth bounds the chains of references, guarding against the references to themselves.
+const maxRefDepth = 16
+
+// openAPIConverter converts the operations of an OpenAPI 3 document, decoded as plain maps so that its references
+// can be followed wherever they are.
+type openAPIConverter struct {
+	doc    map[string]interface{}
+	logger *zap.Logger
+}
+
+func (i *importer) OpenAPI(path, file, baseURL, testSet string) (string, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return "", fmt.Errorf("failed to read the openapi document %s: %v", file, err)
+	}
+	// the JSON documents are parsed as yaml as well
+	var raw interface{}
+	if err := yamlLib.Unmarshal(data, &raw); err != nil {
+		return "", fmt.Errorf("failed to parse the openapi document %s: %v", file, err)
+	}
+	doc, ok := stringKeys(raw).(map[string]interface{})
+	if !ok {
+		return "", fmt.Errorf("%s isn't an openapi document", file)
+	}
+	if _, ok := doc["swagger"]; ok {
+		return "", fmt.Errorf("%s is a Swagger 2.0 document, only the OpenAPI 3 ones are supported", file)
+	}
+	if version, _ := doc["openapi"].(string); !strings.HasPrefix(version, "3.") {
+		return "", fmt.Errorf("%s isn't an OpenAPI 3 document", file)
+	}
+
+	c := &openAPIConverter{doc: doc, logger: i.logger}
+	if baseURL == "" {
+		baseURL, err = c.serverURL()
+		if err != nil {
+			return "", err
+		}
+	}
+	paths := object(doc["paths"])
+	var names []string
+	for name := range paths {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var tcs []*models.TestCase
+	for _, name := range names {
+		item := object(c.resolve(paths[name]))
+		for _, method := range openAPIMethods {
+			operation := object(item[method])
+			if operation == nil {
+				continue
+			}
+			tc, err := c.convert(baseURL, name, method, item, operation)
+			if err != nil {
+				i.logger.Warn("skipping the operation", zap.String("method", strings.ToUpper(method)), zap.String("path", name), zap.Error(err))
+				continue
+			}
+			tcs = append(tcs, tc)
+		}
+	}
+	return i.write(path, testSet, tcs)
+}
+
+// serverURL returns the url of the first server of the document, its variables being replaced with their default.
+func (c *openAPIConverter) serverURL() (string, error) {
+	servers, _ := c.doc["servers"].([]interface{})
+	if len(servers) > 0 {
+		server := object(servers[0])
+		serverURL, _ := server["url"].(string)
+		for name, variable := range object(server["variables"]) {
+			serverURL = strings.ReplaceAll(serverURL, "{"+name+"}", fmt.Sprint(object(variable)["default"]))
+		}
+		if u, err := url.Parse(serverURL); err == nil && u.Scheme != "" && u.Host != "" {
+			return serverURL, nil
+		}
+	}
+	return "", fmt.Errorf("the openapi document has no absolute server url, the url of the application has to be given with --url")
+}
+
+// convert returns the test of the operation. Its request holds the required parameters and the ones with an
+// example, and a body when the operation takes one. The test expects the success status of the operation.
+func (c *openAPIConverter) convert(baseURL, path, method string, item, operation map[string]interface{}) (*models.TestCase, error) {
+	req := models.HttpReq{
+		Method: models.Method(strings.ToUpper(method)),
+		Header: map[string]string{},
+	}
+	query := url.Values{}
+	var cookies []string
+	for _, param := range c.parameters(item, operation) {
+		name, _ := param["name"].(string)
+		in, _ := param["in"].(string)
+		required, _ := param["required"].(bool)
+		value, explicit := c.parameterValue(param)
+		if !required && !explicit && in != "path" {
+			continue
+		}
+		switch in {
+		case "path":
+			path = strings.ReplaceAll(path, "{"+name+"}", url.PathEscape(sampleString(value)))
+		case "query":
+			if values, ok := value.([]interface{}); ok {
+				for _, v := range values {
+					query.Add(name, sampleString(v))
+				}
+			} else {
+				query.Add(name, sampleString(value))
+			}
+		case "header":
+			req.Header[textproto.CanonicalMIMEHeaderKey(name)] = sampleString(value)
+		case "cookie":
+			cookies = append(cookies, name+"="+sampleString(value))
+		}
+	}
+	if len(cookies) > 0 {
+		req.Header["Cookie"] = strings.Join(cookies, "; ")
+	}
+
+	u, err := url.Parse(strings.TrimSuffix(baseURL, "/") + path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse the url of the operation: %v", err)
+	}
+	u.RawQuery = query.Encode()
+	req.URL = u.String()
+	req.Host = u.Host
+	if body := object(c.resolve(operation["requestBody"])); body != nil {
+		if err := c.convertBody(body, &req); err != nil {
+			return nil, err
+		}
+	}
+	return newTestCase(req, nil, successStatus(object(operation["responses"]))), nil
+}
+
+// parameters returns the parameters of the operation along with the ones of its path item it doesn't override.
+func (c *openAPIConverter) parameters(item, operation map[string]interface{}) []map[string]interface{} {
+	var params []map[string]interface{}
+	index := map[string]int{}
+	for _, list := range []interface{}{item["parameters"], operation["parameters"]} {
+		values, _ := list.([]interface{})
+		for _, value := range values {
+			param := object(c.resolve(value))
+			if param == nil {
+				continue
+			}
+			key := fmt.Sprint(param["in"], ":", param["name"])
+			if i, ok := index[key]; ok {
+				params[i] = param
+				continue
+			}
+			index[key] = len(params)
+			params = append(params, param)
+		}
+	}
+	return params
+}
+
+// parameterValue returns the value of the parameter, and whether it is an example given by the document rather than
+// a sample of its schema.
+func (c *openAPIConverter) parameterValue(param map[string]interface{}) (interface{}, bool) {
+	if value, ok := c.example(param); ok {
+		return value, true
+	}
+	if mediaTypes := sortedKeys(object(param["content"])); len(mediaTypes) > 0 {
+		// the parameters with a content have a single media type
+		media := object(object(param["content"])[mediaTypes[0]])
+		if value, ok := c.example(media); ok {
+			return value, true
+		}
+		return c.sample(media["schema"], 0), false
+	}
+	return c.sample(param["schema"], 0), false
+}
+
+// convertBody sets the body of the request from the media type of the request body, JSON ones first, along with its
+// content type.
+func (c *openAPIConverter) convertBody(body map[string]interface{}, req *models.HttpReq) error {
+	content := object(body["content"])
+	mediaTypes := sortedKeys(content)
+	sort.SliceStable(mediaTypes, func(i, j int) bool {
+		return strings.Contains(mediaTypes[i], "json") && !strings.Contains(mediaTypes[j], "json")
+	})
+	for _, mediaType := range mediaTypes {
+		media := object(content[mediaType])
+		value, ok := c.example(media)
+		if !ok {
+			value = c.sample(media["schema"], 0)
+		}
+		switch {
+		case strings.Contains(mediaType, "json"):
+			if text, ok := value.(string); ok && json.Valid([]byte(text)) {
+				req.Body = text
+			} else {
+				encoded, err := json.Marshal(value)
+				if err != nil {
+					return fmt.Errorf("failed to encode the body of the request: %v", err)
+				}
+				req.Body = string(encoded)
+			}
+		case mediaType == "application/x-www-form-urlencoded":
+			form := url.Values{}
+			for name, field := range object(value) {
+				form.Set(name, sampleString(field))
+			}
+			req.Body = form.Encode()
+		case strings.HasPrefix(mediaType, "text/"):
+			req.Body = sampleString(value)
+		default:
+			continue
+		}
+		req.Header["Content-Type"] = mediaType
+		return nil
+	}
+	if required, _ := body["required"].(bool); required {
+		return fmt.Errorf("none of the media types of the request body, %s, is generated", strings.Join(mediaTypes, ", "))
+	}
+	return nil
+}
+
+// example returns the example of the parameter or the media type, the first one of its examples otherwise.
+func (c *openAPIConverter) example(value map[string]interface{}) (interface{}, bool) {
+	if example, ok := value["example"]; ok {
+		return example, true
+	}
+	examples := object(value["examples"])
+	for _, name := range sortedKeys(examples) {
+		if example, ok := object(c.resolve(examples[name]))["value"]; ok {
+			return example, true
+		}
+	}
+	return nil, false
+}
+
+// sample returns a value of the schema: its example, default or first enum value, a sample of the first schema of a
+// oneOf or an anyOf, the merged samples of the schemas of an allOf, or a value of its type.
+func (c *openAPIConverter) sample(value interface{}, depth int) interface{} {
+	schema := object(c.resolve(value))
+	if schema == nil {
+		return nil
+	}
+	for _, key := range []string{"example", "default", "const"} {
+		if example, ok := schema[key]; ok {
+			return example
+		}
+	}
+	for _, key := range []string{"enum", "examples"} {
+		if values, ok := schema[key].([]interface{}); ok && len(values) > 0 {
+			return values[0]
+		}
+	}
+	if schemas, ok := schema["allOf"].([]interface{}); ok {
+		merged := map[string]interface{}{}
+		for _, s := range schemas {
+			sample := c.sample(s, depth)
+			fields, ok := sample.(map[string]interface{})
+			if !ok {
+				return sample
+			}
+			for name, field := range fields {
+				merged[name] = field
+			}
+		}
+		return merged
+	}
+	for _, key := range []string{"oneOf", "anyOf"} {
+		if schemas, ok := schema[key].([]interface{}); ok && len(schemas) > 0 {
+			return c.sample(schemas[0], depth)
+		}
+	}
+
+	switch schemaType(schema) {
+	case "object":
+		fields := map[string]interface{}{}
+		if depth >= maxSampleDepth {
+			return fields
+		}
+		properties := object(schema["properties"])
+		for _, name := range sortedKeys(properties) {
+			// the read-only properties are only sent in the responses
+			if readOnly, _ := object(c.resolve(properties[name]))["readOnly"].(bool); readOnly {
+				continue
+			}
+			if field := c.sample(properties[name], depth+1); field != nil {
+				fields[name] = field
+			}
+		}
+		if additional := object(schema["additionalProperties"]); len(properties) == 0 && additional != nil {
+			if field := c.sample(additional, depth+1); field != nil {
+				fields["key"] = field
+			}
+		}
+		return fields
+	case "array":
+		if depth >= maxSampleDepth {
+			return []interface{}{}
+		}
+		if item := c.sample(schema["items"], depth+1); item != nil {
+			return []interface{}{item}
+		}
+		return []interface{}{}
+	case "string":
+		switch schema["format"] {
+		case "date-time":
+			return "2024-01-01T00:00:00Z"
+		case "date":
+			return "2024-01-01"
+		case "email":
+			return "user@example.com"
+		case "uuid":
+			return "00000000-0000-4000-8000-000000000000"
+		case "uri", "url":
+			return "https://example.com"
+		case "byte":
+			return "a2VwbG95"
+		}
+		return "string"
+	case "integer":
+		if minimum, ok := schema["minimum"]; ok {
+			return minimum
+		}
+		return 1
+	case "number":
+		if minimum, ok := schema["minimum"]; ok {
+			return minimum
+		}
+		return 1.5
+	case "boolean":
+		return true
+	}
+	return nil
+}
+
+// resolve follows the local references, like "#/components/schemas/User". The other ones are left as they are.
+func (c *openAPIConverter) resolve(value interface{}) interface{} {
+	for depth := 0; depth < maxRefDepth; depth++ {
+		ref, ok := object(value)["$ref"].(string)
+		if !ok || !strings.HasPrefix(ref, "#/") {
+			return value
+		}
+		var target interface{} = c.doc
+		for _, token := range strings.Split(ref[2:], "/") {
+			token = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+			target = object(target)[token]
+		}
+		if target == nil {
+			c.logger.Warn("the reference of the openapi document isn't found", zap.String("ref", ref))
+			return nil
+		}
+		value = target
+	}
+	return value
+}
+
+// schemaType returns the type of the schema, the first one other than null when it lists several of them.
+func schemaType(schema map[string]interface{}) string {
+	switch typ := schema["type"].(type) {
+	case string:
+		return typ
+	case []interface{}:
+		for _, t := range typ {
+			if t != "null" {
+				return fmt.Sprint(t)
+			}
+		}
+	}
+	if _, ok := schema["properties"]; ok {
+		return "object"
+	}
+	if _, ok := schema["items"]; ok {
+		return "array"
+	}
+	return ""
+}
+
+// successStatus returns the lowest 2XX status of the responses of the operation, 200 when it has none.
+func successStatus(responses map[string]interface{}) int {
+	status := 0
+	for code := range responses {
+		if strings.EqualFold(code, "2XX") {
+			code = "200"
+		}
+		if value, err := strconv.Atoi(code); err == nil && value >= 200 && value < 300 && (status == 0 || value < status) {
+			status = value
+		}
+	}
+	if status == 0 {
+		return 200
+	}
+	return status
+}
+
+// sampleString returns the text form of a value of a parameter, the elements of the lists being joined with commas.
+func sampleString(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case []interface{}:
+		elements := make([]string, len(v))
+		for i, element := range v {
+			elements[i] = sampleString(element)
+		}
+		return strings.Join(elements, ",")
+	case map[string]interface{}:
+		encoded, _ := json.Marshal(v)
+		return string(encoded)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// stringKeys converts the maps decoded by yaml with keys other than strings, like the status codes of the responses,
+// into maps with string keys.
+func stringKeys(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[interface{}]interface{}:
+		converted := make(map[string]interface{}, len(v))
+		for key, nested := range v {
+			converted[fmt.Sprint(key)] = stringKeys(nested)
+		}
+		return converted
+	case map[string]interface{}:
+		for key, nested := range v {
+			v[key] = stringKeys(nested)
+		}
+		return v
+	case []interface{}:
+		for i, nested := range v {
+			v[i] = stringKeys(nested)
+		}
+		return v
+	default:
+		return value
+	}
+}
+
+func object(value interface{}) map[string]interface{} {
+	m, _ := value.(map[string]interface{})
+	return m
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,441 @@
+package importer
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"mime/multipart"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"go.keploy.io/server/pkg/models"
+	"go.uber.org/zap"
+)
+
+// postmanCollection is a Postman collection of the v2.0 and v2.1 formats.
+type postmanCollection struct {
+	Info     postmanInfo       `json:"info"`
+	Item     []postmanItem     `json:"item"`
+	Variable []postmanKeyValue `json:"variable"`
+	Auth     *postmanAuth      `json:"auth"`
+}
+
+type postmanInfo struct {
+	Name string `json:"name"`
+}
+
+// postmanItem is either a folder of items or a request along with its saved responses.
+type postmanItem struct {
+	Name     string            `json:"name"`
+	Item     []postmanItem     `json:"item"`
+	Request  json.RawMessage   `json:"request"`
+	Response []postmanResponse `json:"response"`
+	Event    []postmanEvent    `json:"event"`
+	Auth     *postmanAuth      `json:"auth"`
+}
+
+type postmanRequest struct {
+	Method string            `json:"method"`
+	Header []postmanKeyValue `json:"header"`
+	URL    json.RawMessage   `json:"url"`
+	Body   *postmanBody      `json:"body"`
+	Auth   *postmanAuth      `json:"auth"`
+}
+
+type postmanURL struct {
+	Raw string `json:"raw"`
+}
+
+type postmanBody struct {
+	Mode       string            `json:"mode"`
+	Raw        string            `json:"raw"`
+	URLEncoded []postmanKeyValue `json:"urlencoded"`
+	FormData   []postmanKeyValue `json:"formdata"`
+	GraphQL    *struct {
+		Query     string `json:"query"`
+		Variables string `json:"variables"`
+	} `json:"graphql"`
+	Options struct {
+		Raw struct {
+			Language string `json:"language"`
+		} `json:"raw"`
+	} `json:"options"`
+}
+
+type postmanKeyValue struct {
+	Key      string      `json:"key"`
+	Value    interface{} `json:"value"`
+	Type     string      `json:"type"`
+	Src      interface{} `json:"src"`
+	Disabled bool        `json:"disabled"`
+	// Enabled is set in the values of the environments, rather than Disabled
+	Enabled *bool `json:"enabled"`
+}
+
+type postmanAuth struct {
+	Type   string            `json:"type"`
+	Bearer []postmanKeyValue `json:"bearer"`
+	Basic  []postmanKeyValue `json:"basic"`
+	APIKey []postmanKeyValue `json:"apikey"`
+}
+
+type postmanResponse struct {
+	Code   int               `json:"code"`
+	Status string            `json:"status"`
+	Header []postmanKeyValue `json:"header"`
+	Body   string            `json:"body"`
+}
+
+type postmanEvent struct {
+	Listen string `json:"listen"`
+	Script struct {
+		Exec json.RawMessage `json:"exec"`
+	} `json:"script"`
+}
+
+type postmanEnvironment struct {
+	Values []postmanKeyValue `json:"values"`
+}
+
+var (
+	// postmanVariable matches the {{name}} variables of Postman, the {{.name}} variables of keploy aren't matched
+	postmanVariable = regexp.MustCompile(`{{\s*([^.{}\s][^{}]*?)\s*}}`)
+	// postmanStatus matches the status code asserted by the test scripts of the requests
+	postmanStatus = regexp.MustCompile(`(?:to\.have\.status\(\s*|response\.code\)\.to\.(?:eql|equal)\(\s*|responseCode\.code\s*===?\s*)(\d{3})`)
+)
+
+func (kv postmanKeyValue) value() string {
+	switch v := kv.Value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+func (kv postmanKeyValue) enabled() bool {
+	return !kv.Disabled && (kv.Enabled == nil || *kv.Enabled)
+}
+
+// authValue returns the value of the key in the parameters of the auth.
+func authValue(params []postmanKeyValue, key string) string {
+	for _, param := range params {
+		if param.Key == key {
+			return param.value()
+		}
+	}
+	return ""
+}
+
+func (i *importer) Postman(path, file, env, testSet string) (string, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return "", fmt.Errorf("failed to read the postman collection %s: %v", file, err)
+	}
+	var collection postmanCollection
+	if err := json.Unmarshal(data, &collection); err != nil {
+		return "", fmt.Errorf("failed to parse the postman collection %s: %v", file, err)
+	}
+
+	// the variables of the environment take precedence over the ones of the collection, as in Postman
+	variables := map[string]string{}
+	for _, variable := range collection.Variable {
+		if variable.enabled() {
+			variables[variable.Key] = variable.value()
+		}
+	}
+	if env != "" {
+		data, err := os.ReadFile(env)
+		if err != nil {
+			return "", fmt.Errorf("failed to read the postman environment %s: %v", env, err)
+		}
+		var environment postmanEnvironment
+		if err := json.Unmarshal(data, &environment); err != nil {
+			return "", fmt.Errorf("failed to parse the postman environment %s: %v", env, err)
+		}
+		for _, variable := range environment.Values {
+			if variable.enabled() {
+				variables[variable.Key] = variable.value()
+			}
+		}
+	}
+
+	p := &postmanConverter{
+		logger:    i.logger,
+		variables: variables,
+		dir:       filepath.Dir(file),
+	}
+	tcs := p.convert(collection.Item, collection.Auth, "")
+	return i.write(path, testSet, tcs)
+}
+
+type postmanConverter struct {
+	logger    *zap.Logger
+	variables map[string]string
+	// dir is the directory of the collection, the files of the form data being relative to it
+	dir string
+}
+
+// resolve replaces the variables of the value with their values, the unknown ones being left as they are.
+func (p *postmanConverter) resolve(value string) string {
+	// the values of the variables may reference other variables
+	for depth := 0; depth < 5 && postmanVariable.MatchString(value); depth++ {
+		resolved := postmanVariable.ReplaceAllStringFunc(value, func(match string) string {
+			name := postmanVariable.FindStringSubmatch(match)[1]
+			if v, ok := p.variables[name]; ok {
+				return v
+			}
+			return match
+		})
+		if resolved == value {
+			break
+		}
+		value = resolved
+	}
+	return value
+}
+
+// convert returns the tests of the requests of the items, in their order, the auth being inherited by the items.
+func (p *postmanConverter) convert(items []postmanItem, auth *postmanAuth, folder string) []*models.TestCase {
+	var tcs []*models.TestCase
+	for _, item := range items {
+		itemAuth := auth
+		if item.Auth != nil {
+			itemAuth = item.Auth
+		}
+		name := strings.TrimPrefix(folder+"/"+item.Name, "/")
+		if len(item.Request) == 0 {
+			tcs = append(tcs, p.convert(item.Item, itemAuth, name)...)
+			continue
+		}
+		tc, err := p.convertItem(item, itemAuth)
+		if err != nil {
+			p.logger.Warn("skipping the request of the postman collection", zap.String("request", name), zap.Error(err))
+			continue
+		}
+		if unresolved := postmanVariable.FindAllString(tc.HttpReq.URL+tc.HttpReq.Body+fmt.Sprint(tc.HttpReq.Header), -1); len(unresolved) > 0 {
+			p.logger.Warn("the request of the postman collection has variables without value, pass the environment with --env", zap.String("request", name), zap.Strings("variables", unresolved))
+		}
+		tcs = append(tcs, tc)
+	}
+	return tcs
+}
+
+func (p *postmanConverter) convertItem(item postmanItem, auth *postmanAuth) (*models.TestCase, error) {
+	var request postmanRequest
+	var rawURL string
+	if err := json.Unmarshal(item.Request, &rawURL); err == nil {
+		// the request is only its url
+		request.Method = "GET"
+	} else if err := json.Unmarshal(item.Request, &request); err != nil {
+		return nil, err
+	} else if err := json.Unmarshal(request.URL, &rawURL); err != nil {
+		var u postmanURL
+		if err := json.Unmarshal(request.URL, &u); err != nil {
+			return nil, fmt.Errorf("failed to parse the url: %v", err)
+		}
+		rawURL = u.Raw
+	}
+	rawURL = p.resolve(rawURL)
+	if rawURL == "" {
+		return nil, fmt.Errorf("the request has no url")
+	}
+	if !strings.Contains(rawURL, "://") {
+		rawURL = "http://" + rawURL
+	}
+	if request.Method == "" {
+		request.Method = "GET"
+	}
+	if request.Auth != nil {
+		auth = request.Auth
+	}
+
+	req := models.HttpReq{
+		Method: models.Method(strings.ToUpper(request.Method)),
+		Header: map[string]string{},
+	}
+	for _, header := range request.Header {
+		if header.enabled() {
+			req.Header[p.resolve(header.Key)] = p.resolve(header.value())
+		}
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse the url %s: %v", rawURL, err)
+	}
+	if auth != nil {
+		switch auth.Type {
+		case "bearer":
+			req.Header["Authorization"] = "Bearer " + p.resolve(authValue(auth.Bearer, "token"))
+		case "basic":
+			credentials := p.resolve(authValue(auth.Basic, "username")) + ":" + p.resolve(authValue(auth.Basic, "password"))
+			req.Header["Authorization"] = "Basic " + base64.StdEncoding.EncodeToString([]byte(credentials))
+		case "apikey":
+			key, value := p.resolve(authValue(auth.APIKey, "key")), p.resolve(authValue(auth.APIKey, "value"))
+			if authValue(auth.APIKey, "in") == "query" {
+				query := u.Query()
+				query.Set(key, value)
+				u.RawQuery = query.Encode()
+			} else {
+				req.Header[key] = value
+			}
+		case "", "noauth", "inherit":
+		default:
+			p.logger.Warn("the auth of the postman request isn't supported, the request is imported without it", zap.String("request", item.Name), zap.String("auth", auth.Type))
+		}
+	}
+	req.URL = u.String()
+	req.Host = u.Host
+	if err := p.convertBody(request.Body, &req); err != nil {
+		return nil, err
+	}
+
+	// the first saved response is the expected one, otherwise the status asserted by the tests of the request
+	if len(item.Response) > 0 {
+		saved := item.Response[0]
+		resp := &models.HttpResp{
+			StatusCode:    saved.Code,
+			StatusMessage: saved.Status,
+			Header:        map[string]string{},
+			Body:          saved.Body,
+		}
+		for _, header := range saved.Header {
+			if header.enabled() {
+				resp.Header[header.Key] = header.value()
+			}
+		}
+		if resp.StatusCode == 0 {
+			resp.StatusCode = 200
+		}
+		return newTestCase(req, resp, 0), nil
+	}
+	return newTestCase(req, nil, expectedStatus(item.Event)), nil
+}
+
+// convertBody sets the body of the request, along with its content type when it isn't set.
+func (p *postmanConverter) convertBody(body *postmanBody, req *models.HttpReq) error {
+	if body == nil {
+		return nil
+	}
+	contentType := ""
+	switch body.Mode {
+	case "raw":
+		req.Body = p.resolve(body.Raw)
+		switch body.Options.Raw.Language {
+		case "json":
+			contentType = "application/json"
+		case "xml":
+			contentType = "application/xml"
+		case "html":
+			contentType = "text/html"
+		default:
+			contentType = "text/plain"
+		}
+	case "urlencoded":
+		form := url.Values{}
+		for _, field := range body.URLEncoded {
+			if field.enabled() {
+				form.Add(p.resolve(field.Key), p.resolve(field.value()))
+			}
+		}
+		req.Body = form.Encode()
+		contentType = "application/x-www-form-urlencoded"
+	case "formdata":
+		var buf bytes.Buffer
+		writer := multipart.NewWriter(&buf)
+		for _, field := range body.FormData {
+			if !field.enabled() {
+				continue
+			}
+			if field.Type != "file" {
+				if err := writer.WriteField(p.resolve(field.Key), p.resolve(field.value())); err != nil {
+					return err
+				}
+				continue
+			}
+			src, _ := field.Src.(string)
+			if src == "" {
+				p.logger.Warn("the file of the form field has no path, the field is skipped", zap.String("field", field.Key))
+				continue
+			}
+			if !filepath.IsAbs(src) {
+				src = filepath.Join(p.dir, src)
+			}
+			content, err := os.ReadFile(src)
+			if err != nil {
+				return fmt.Errorf("failed to read the file of the form field %s: %v", field.Key, err)
+			}
+			part, err := writer.CreateFormFile(p.resolve(field.Key), filepath.Base(src))
+			if err != nil {
+				return err
+			}
+			if _, err := part.Write(content); err != nil {
+				return err
+			}
+		}
+		if err := writer.Close(); err != nil {
+			return err
+		}
+		req.Body = buf.String()
+		contentType = writer.FormDataContentType()
+	case "graphql":
+		if body.GraphQL == nil {
+			return nil
+		}
+		payload := map[string]interface{}{"query": p.resolve(body.GraphQL.Query)}
+		if vars := strings.TrimSpace(p.resolve(body.GraphQL.Variables)); vars != "" {
+			payload["variables"] = json.RawMessage(vars)
+		}
+		data, err := json.Marshal(payload)
+		if err != nil {
+			return fmt.Errorf("failed to encode the graphql body: %v", err)
+		}
+		req.Body = string(data)
+		contentType = "application/json"
+	default:
+		return nil
+	}
+	if contentType != "" && headerValue(req.Header, "Content-Type") == "" {
+		req.Header["Content-Type"] = contentType
+	}
+	return nil
+}
+
+// expectedStatus returns the status code asserted by the test scripts of the request, 200 by default.
+func expectedStatus(events []postmanEvent) int {
+	for _, event := range events {
+		if event.Listen != "test" {
+			continue
+		}
+		var lines []string
+		var line string
+		if err := json.Unmarshal(event.Script.Exec, &lines); err != nil {
+			if err := json.Unmarshal(event.Script.Exec, &line); err != nil {
+				continue
+			}
+			lines = []string{line}
+		}
+		if match := postmanStatus.FindStringSubmatch(strings.Join(lines, "\n")); match != nil {
+			status, _ := strconv.Atoi(match[1])
+			return status
+		}
+	}
+	return 200
+}
+
+// headerValue returns the value of the header, whose names are matched regardless of their case.
+func headerValue(header map[string]string, name string) string {
+	for key, value := range header {
+		if strings.EqualFold(key, name) {
+			return value
+		}
+	}
+	return ""
+}
@@ -0,0 +1,246 @@
+package importer
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"golang.org/x/net/http2"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// The reflection service is called without the grpc library: its ServerReflectionInfo method is a bidirectional
+// stream, but each request sent on a stream of its own gets its response before the server closes the stream. The
+// messages are encoded by hand, the v1 and v1alpha versions of the service having the same ones.
+
+// reflectionServices are the names of the reflection service, the v1alpha one being tried when the server doesn't
+// implement the v1 one.
+var reflectionServices = []string{"grpc.reflection.v1.ServerReflection", "grpc.reflection.v1alpha.ServerReflection"}
+
+const (
+	// the fields of ServerReflectionRequest
+	reflectionFileByFilename       protowire.Number = 3
+	reflectionFileContainingSymbol protowire.Number = 4
+	reflectionListServices         protowire.Number = 7
+	// the fields of ServerReflectionResponse
+	reflectionFileDescriptorResponse protowire.Number = 4
+	reflectionListServicesResponse   protowire.Number = 6
+	reflectionErrorResponse          protowire.Number = 7
+
+	// grpcUnimplemented is the status of the calls to a service the server doesn't implement
+	grpcUnimplemented = "12"
+	reflectionTimeout = 10 * time.Second
+)
+
+// reflectionClient calls the reflection service of the gRPC server at the address.
+type reflectionClient struct {
+	client  *http.Client
+	baseURL string
+	service string
+}
+
+func newReflectionClient(address string, useTLS bool) *reflectionClient {
+	transport := &http2.Transport{}
+	scheme := "https"
+	if !useTLS {
+		// the plaintext servers speak HTTP/2 without the upgrade from HTTP/1.1
+		scheme = "http"
+		transport.AllowHTTP = true
+		transport.DialTLS = func(network, addr string, cfg *tls.Config) (net.Conn, error) {
+			return net.DialTimeout(network, addr, reflectionTimeout)
+		}
+	}
+	return &reflectionClient{
+		client:  &http.Client{Transport: transport, Timeout: reflectionTimeout},
+		baseURL: scheme + "://" + address,
+		service: reflectionServices[0],
+	}
+}
+
+// listServices returns the full names of the services of the server.
+func (c *reflectionClient) listServices() ([]string, error) {
+	response, err := c.call(protowire.AppendString(protowire.AppendTag(nil, reflectionListServices, protowire.BytesType), ""))
+	if err != nil {
+		return nil, err
+	}
+	list, ok := response[reflectionListServicesResponse]
+	if !ok {
+		return nil, fmt.Errorf("the server didn't list its services")
+	}
+	var services []string
+	for _, list := range list {
+		fields, err := bytesFields(list)
+		if err != nil {
+			return nil, err
+		}
+		// ListServiceResponse.service, whose ServiceResponse.name is its first field
+		for _, service := range fields[1] {
+			serviceFields, err := bytesFields(service)
+			if err != nil {
+				return nil, err
+			}
+			for _, name := range serviceFields[1] {
+				services = append(services, string(name))
+			}
+		}
+	}
+	return services, nil
+}
+
+// files returns the descriptors of the files defining the services along with the files they import.
+func (c *reflectionClient) files(services []string) (*protoregistry.Files, error) {
+	files := map[string]*descriptorpb.FileDescriptorProto{}
+	var add func(field protowire.Number, value string) error
+	add = func(field protowire.Number, value string) error {
+		response, err := c.call(protowire.AppendString(protowire.AppendTag(nil, field, protowire.BytesType), value))
+		if err != nil {
+			return err
+		}
+		var missing []string
+		for _, descriptors := range response[reflectionFileDescriptorResponse] {
+			fields, err := bytesFields(descriptors)
+			if err != nil {
+				return err
+			}
+			// FileDescriptorResponse.file_descriptor_proto
+			for _, encoded := range fields[1] {
+				file := &descriptorpb.FileDescriptorProto{}
+				if err := proto.Unmarshal(encoded, file); err != nil {
+					return fmt.Errorf("failed to decode the descriptor of a file of %s: %v", value, err)
+				}
+				files[file.GetName()] = file
+				missing = append(missing, file.GetDependency()...)
+			}
+		}
+		// the servers usually send the files imported along with the file asked for, the others are asked for
+		for _, dependency := range missing {
+			if _, ok := files[dependency]; !ok {
+				if err := add(reflectionFileByFilename, dependency); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+	for _, service := range services {
+		if err := add(reflectionFileContainingSymbol, service); err != nil {
+			return nil, err
+		}
+	}
+
+	set := &descriptorpb.FileDescriptorSet{}
+	for _, file := range files {
+		set.File = append(set.File, file)
+	}
+	registry, err := protodesc.NewFiles(set)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build the descriptors of the services: %v", err)
+	}
+	return registry, nil
+}
+
+// call sends the ServerReflectionRequest and returns the length-delimited fields of its response.
+func (c *reflectionClient) call(request []byte) (map[protowire.Number][][]byte, error) {
+	message, status, err := c.post(request)
+	if err == nil && status == grpcUnimplemented && c.service == reflectionServices[0] {
+		c.service = reflectionServices[1]
+		message, status, err = c.post(request)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if status != "0" {
+		return nil, fmt.Errorf("the reflection service of the server failed with the grpc status %s", status)
+	}
+	response, err := bytesFields(message)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode the response of the reflection service: %v", err)
+	}
+	if errors, ok := response[reflectionErrorResponse]; ok {
+		fields, _ := bytesFields(errors[0])
+		return nil, fmt.Errorf("the reflection service of the server failed: %s", firstString(fields[2]))
+	}
+	return response, nil
+}
+
+// post sends the message on a stream of its own and returns the first message of the response with its status.
+func (c *reflectionClient) post(message []byte) ([]byte, string, error) {
+	body := make([]byte, 5, 5+len(message))
+	binary.BigEndian.PutUint32(body[1:], uint32(len(message)))
+	body = append(body, message...)
+
+	req, err := http.NewRequest(http.MethodPost, c.baseURL+"/"+c.service+"/ServerReflectionInfo", bytes.NewReader(body))
+	if err != nil {
+		return nil, "", err
+	}
+	req.Header.Set("Content-Type", "application/grpc")
+	req.Header.Set("TE", "trailers")
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to call the reflection service of the server: %v", err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read the response of the reflection service: %v", err)
+	}
+	// the failures without any message come with their status in the headers rather than the trailers
+	status := resp.Trailer.Get("Grpc-Status")
+	if status == "" {
+		status = resp.Header.Get("Grpc-Status")
+	}
+	if status == "" && resp.StatusCode != http.StatusOK {
+		return nil, "", fmt.Errorf("the server answered the reflection call with the http status %d", resp.StatusCode)
+	}
+	if status != "0" && status != "" {
+		return nil, status, nil
+	}
+	if len(data) < 5 || data[0] != 0 || int(binary.BigEndian.Uint32(data[1:5])) > len(data)-5 {
+		return nil, "", fmt.Errorf("the response of the reflection service isn't an uncompressed grpc message")
+	}
+	return data[5 : 5+binary.BigEndian.Uint32(data[1:5])], "0", nil
+}
+
+// bytesFields returns the values of the length-delimited fields of the wire encoded message, by field number.
+func bytesFields(message []byte) (map[protowire.Number][][]byte, error) {
+	fields := map[protowire.Number][][]byte{}
+	for len(message) > 0 {
+		number, typ, n := protowire.ConsumeTag(message)
+		if n < 0 {
+			return nil, protowire.ParseError(n)
+		}
+		message = message[n:]
+		if typ == protowire.BytesType {
+			value, n := protowire.ConsumeBytes(message)
+			if n < 0 {
+				return nil, protowire.ParseError(n)
+			}
+			fields[number] = append(fields[number], value)
+			message = message[n:]
+			continue
+		}
+		n = protowire.ConsumeFieldValue(number, typ, message)
+		if n < 0 {
+			return nil, protowire.ParseError(n)
+		}
+		message = message[n:]
+	}
+	return fields, nil
+}
+
+func firstString(values [][]byte) string {
+	if len(values) == 0 {
+		return "unknown error"
+	}
+	return strings.TrimSpace(string(values[0]))
+}
@@ -0,0 +1,20 @@
+package importer
+
+type Importer interface {
+	// Postman converts the requests of the Postman collection file into the tests of the test set, the variables of
+	// the collection being resolved with the Postman environment file when given. It returns the test set, the next
+	// test-set-N when none is given.
+	Postman(path, file, env, testSet string) (string, error)
+	// Curl converts the curl commands of the file into the tests of the test set.
+	Curl(path, file, testSet string) (string, error)
+	// HAR converts the entries of the HAR file into the tests of the test set, their responses being the expected
+	// ones. Only the entries whose url matches the urlFilter regex are converted when it is given.
+	HAR(path, file, testSet, urlFilter string) (string, error)
+	// GRPC generates a skeleton test for each method of the services of the gRPC server at the address, listed by
+	// its reflection service, whose request is a sample of the input message of the method.
+	GRPC(path, address, testSet string, useTLS bool) (string, error)
+	// OpenAPI generates a test for each operation of the OpenAPI 3 document, whose request is built from the examples
+	// and the schemas of the document. The requests are sent to the baseURL, the url of the first server of the
+	// document by default.
+	OpenAPI(path, file, baseURL, testSet string) (string, error)
+}
@@ -0,0 +1,86 @@
+package migrate
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"go.keploy.io/server/pkg/models"
+	"go.keploy.io/server/pkg/platform/yaml"
+	"go.uber.org/zap"
+)
+
+type migrator struct {
+	logger *zap.Logger
+}
+
+func NewMigrator(logger *zap.Logger) Migrator {
+	return &migrator{
+		logger: logger,
+	}
+}
+
+// yamlFiles returns the names of the yaml files of the directory, without their extension.
+func yamlFiles(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var names []string
+	for _, entry := range entries {
+		if !entry.IsDir() && filepath.Ext(entry.Name()) == ".yaml" {
+			names = append(names, strings.TrimSuffix(entry.Name(), ".yaml"))
+		}
+	}
+	return names, nil
+}
+
+func (m *migrator) Migrate(path string, testSets []string, dryRun bool) (int, error) {
+	if len(testSets) == 0 {
+		var err error
+		testSets, err = yaml.ReadSessionIndices(path, m.logger)
+		if err != nil {
+			return 0, fmt.Errorf("failed to list the test sets of %s: %v", path, err)
+		}
+	}
+	total := 0
+	for _, testSet := range testSets {
+		dir := filepath.Join(path, testSet)
+		if _, err := os.Stat(dir); err != nil {
+			return total, fmt.Errorf("the test set %s doesn't exist", testSet)
+		}
+		tests, err := yamlFiles(filepath.Join(dir, "tests"))
+		if err != nil {
+			return total, err
+		}
+		files := map[string][]string{filepath.Join(dir, "tests"): tests}
+		if _, err := os.Stat(filepath.Join(dir, "mocks.yaml")); err == nil {
+			files[dir] = []string{"mocks"}
+		}
+		migrated := 0
+		for fileDir, names := range files {
+			for _, name := range names {
+				count, err := yaml.MigrateFile(fileDir, name, dryRun)
+				if err != nil {
+					return total, fmt.Errorf("failed to migrate %s: %v", filepath.Join(fileDir, name+".yaml"), err)
+				}
+				migrated += count
+			}
+		}
+		total += migrated
+		if migrated == 0 {
+			m.logger.Info("the test set is up to date", zap.String("testSet", testSet), zap.Any("version", models.GetVersion()))
+			continue
+		}
+		if dryRun {
+			m.logger.Info("the test set would be migrated", zap.String("testSet", testSet), zap.Int("documents", migrated), zap.Any("version", models.GetVersion()))
+			continue
+		}
+		m.logger.Info("migrated the test set", zap.String("testSet", testSet), zap.Int("documents", migrated), zap.Any("version", models.GetVersion()))
+	}
+	return total, nil
+}
@@ -0,0 +1,7 @@
+package migrate
+
+type Migrator interface {
+	// Migrate upgrades the tests and mocks of the test sets, all of them when none is given, to the current version of
+	// the schema in place, and returns the number of the documents upgraded. Nothing is written on a dry run.
+	Migrate(path string, testSets []string, dryRun bool) (int, error)
+}
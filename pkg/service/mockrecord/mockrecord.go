@@ -15,6 +15,7 @@ import (
 	"go.keploy.io/server/pkg/platform/telemetry"
 	"go.keploy.io/server/pkg/platform/yaml"
 	"go.keploy.io/server/pkg/proxy"
+	"go.keploy.io/server/pkg/shutdown"
 	"go.uber.org/zap"
 )
 
@@ -39,6 +40,9 @@ func (s *mockRecorder) MockRecord(path string, proxyPort uint32, pid uint32, moc
 	tele := telemetry.NewTelemetry(enableTele, false, teleFS, s.logger, "", nil)
 	tele.Ping(false)
 	ys := yaml.NewYamlStore(path, path, "", mockName, s.logger, tele)
+	// the mocks recorded are moved from their journal into the yaml file once the recording ends
+	defer ys.FinalizeMocks()
+	defer shutdown.Register("recorded mocks", ys.FinalizeMocks)()
 	routineId := pkg.GenerateRandomID()
 
 	mocksTotal := make(map[string]int)
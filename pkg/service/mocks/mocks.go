@@ -0,0 +1,442 @@
+package mocks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"go.keploy.io/server/pkg/models"
+	"go.keploy.io/server/pkg/platform/yaml"
+	"go.uber.org/zap"
+	yamlLib "gopkg.in/yaml.v3"
+)
+
+const (
+	mocksFile  = "mocks"
+	testsDir   = "tests"
+	assetsDir  = "assets"
+	reportsDir = "testReports"
+)
+
+// timestampKeys are the keys of the specs of the mocks which change from a recording to the next for the same call.
+var timestampKeys = map[string]bool{
+	"reqtimestampmock": true,
+	"restimestampmock": true,
+	"created":          true,
+	"createdat":        true,
+	"timestamp":        true,
+}
+
+type mocks struct {
+	logger *zap.Logger
+}
+
+func NewMocks(logger *zap.Logger) Mocks {
+	return &mocks{
+		logger: logger,
+	}
+}
+
+func (m *mocks) Normalize(path string, testSets []string) error {
+	testSets, err := m.selectTestSets(path, testSets)
+	if err != nil {
+		return err
+	}
+	for _, testSet := range testSets {
+		dir := filepath.Join(path, testSet)
+		docs, err := readMocks(dir)
+		if err != nil {
+			return err
+		}
+		if len(docs) == 0 {
+			continue
+		}
+
+		var kept []*yaml.NetworkTrafficDoc
+		seen := map[string]int{}
+		duplicates := map[int]int{}
+		for _, doc := range docs {
+			identity, err := mockIdentity(doc)
+			if err != nil {
+				return fmt.Errorf("failed to read the mock %s of %s: %v", doc.Name, testSet, err)
+			}
+			if i, ok := seen[identity]; ok {
+				duplicates[i]++
+				continue
+			}
+			seen[identity] = len(kept)
+			kept = append(kept, doc)
+		}
+		nameMocks(kept)
+
+		if err := yaml.WriteDocs(dir, mocksFile, kept); err != nil {
+			return err
+		}
+		m.logger.Info("normalized the mocks of the test set", zap.String("testSet", testSet), zap.Int("mocks", len(kept)), zap.Int("duplicates removed", len(docs)-len(kept)))
+		for i, count := range duplicates {
+			// the calls made several times were consumed once per recorded mock
+			m.logger.Info("the mock was recorded several times, set its policy to repeatable if it is called more than once", zap.String("testSet", testSet), zap.String("mock", kept[i].Name), zap.Int("duplicates", count))
+		}
+	}
+	return nil
+}
+
+func (m *mocks) Prune(path string, testSets []string, dryRun bool) error {
+	testSets, err := m.selectTestSets(path, testSets)
+	if err != nil {
+		return err
+	}
+	consumed, err := m.consumedMocks(filepath.Join(path, reportsDir))
+	if err != nil {
+		return err
+	}
+	for _, testSet := range testSets {
+		dir := filepath.Join(path, testSet)
+		docs, err := readMocks(dir)
+		if err != nil {
+			return err
+		}
+		if len(docs) == 0 {
+			continue
+		}
+		if len(consumed[testSet]) == 0 {
+			m.logger.Warn("skipping the test set since no replay report lists its consumed mocks, replay it first", zap.String("testSet", testSet))
+			continue
+		}
+
+		names := map[string]bool{}
+		for _, doc := range docs {
+			if names[doc.Name] {
+				return fmt.Errorf("the mocks of %s don't have unique names, normalize them and replay the test set before pruning them", testSet)
+			}
+			names[doc.Name] = true
+		}
+		// the reports of the runs which predate the last changes of the mocks can't tell which ones are used
+		for name := range consumed[testSet] {
+			if !names[name] {
+				return fmt.Errorf("the replay reports of %s list the mock %s which it doesn't have, replay the test set before pruning its mocks", testSet, name)
+			}
+		}
+
+		var kept []*yaml.NetworkTrafficDoc
+		var pruned []string
+		for _, doc := range docs {
+			// the config mocks, like the handshakes of the connections, are kept
+			if consumed[testSet][doc.Name] || isConfigMock(doc) {
+				kept = append(kept, doc)
+				continue
+			}
+			pruned = append(pruned, doc.Name)
+		}
+		if dryRun {
+			m.logger.Info("the mocks of the test set which would be pruned", zap.String("testSet", testSet), zap.Strings("mocks", pruned))
+			continue
+		}
+		if len(pruned) == 0 {
+			continue
+		}
+		if err := yaml.WriteDocs(dir, mocksFile, kept); err != nil {
+			return err
+		}
+		m.logger.Info("pruned the mocks of the test set never consumed", zap.String("testSet", testSet), zap.Strings("mocks", pruned))
+	}
+	return nil
+}
+
+func (m *mocks) Merge(path string, testSets []string, into string) (string, error) {
+	if len(testSets) < 2 {
+		return "", errors.New("at least two test sets are needed to be merged")
+	}
+	testSets, err := m.selectTestSets(path, testSets)
+	if err != nil {
+		return "", err
+	}
+	if into == "" {
+		into, err = yaml.NewSessionIndex(path, m.logger)
+		if err != nil {
+			return "", err
+		}
+	}
+	intoDir := filepath.Join(path, into)
+	if _, err := os.Stat(intoDir); err == nil {
+		return "", fmt.Errorf("the test set %s already exists", into)
+	}
+
+	var mergedMocks []*yaml.NetworkTrafficDoc
+	index := 0
+	for _, testSet := range testSets {
+		dir := filepath.Join(path, testSet)
+
+		// the testcases are numbered in the order of the test sets, their variables following them
+		names, err := yaml.TestcaseNames(filepath.Join(dir, testsDir))
+		if err != nil {
+			return "", err
+		}
+		renamed := map[string]string{}
+		for _, name := range names {
+			docs, err := yaml.ReadDocs(filepath.Join(dir, testsDir), name)
+			if err != nil {
+				return "", fmt.Errorf("failed to read the testcase %s of %s: %v", name, testSet, err)
+			}
+			index++
+			newName := fmt.Sprintf("test-%d", index)
+			renamed[name] = newName
+			for _, doc := range docs {
+				doc.Name = newName
+				renameVariableSources(&doc.Spec, renamed)
+			}
+			if err := yaml.WriteDocs(filepath.Join(intoDir, testsDir), newName, docs); err != nil {
+				return "", err
+			}
+		}
+
+		docs, err := readMocks(dir)
+		if err != nil {
+			return "", err
+		}
+		mergedMocks = append(mergedMocks, docs...)
+
+		if err := copyAssets(filepath.Join(dir, assetsDir), filepath.Join(intoDir, assetsDir)); err != nil {
+			return "", err
+		}
+	}
+	nameMocks(mergedMocks)
+	if err := yaml.WriteDocs(intoDir, mocksFile, mergedMocks); err != nil {
+		return "", err
+	}
+	m.logger.Info("merged the test sets", zap.Strings("testSets", testSets), zap.String("into", into), zap.Int("testcases", index), zap.Int("mocks", len(mergedMocks)))
+	return into, nil
+}
+
+// selectTestSets returns the given test sets once checked, or all the test sets when none are given.
+func (m *mocks) Convert(path string, testSets []string, format string) error {
+	testSets, err := m.selectTestSets(path, testSets)
+	if err != nil {
+		return err
+	}
+	for _, testSet := range testSets {
+		converted, err := yaml.ConvertDocs(filepath.Join(path, testSet), mocksFile, format)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return fmt.Errorf("failed to convert the mocks of %s: %v", testSet, err)
+		}
+		if converted {
+			m.logger.Info("converted the mocks of the test set", zap.String("testSet", testSet), zap.String("format", format))
+		}
+	}
+	return nil
+}
+
+func (m *mocks) selectTestSets(path string, testSets []string) ([]string, error) {
+	if len(testSets) == 0 {
+		return yaml.ReadSessionIndices(path, m.logger)
+	}
+	for _, testSet := range testSets {
+		if strings.ContainsAny(testSet, `/\`) || testSet == "." || testSet == ".." {
+			return nil, fmt.Errorf("invalid test set name %q", testSet)
+		}
+		info, err := os.Stat(filepath.Join(path, testSet))
+		if err != nil || !info.IsDir() {
+			return nil, fmt.Errorf("the test set %s doesn't exist in %s", testSet, path)
+		}
+	}
+	return testSets, nil
+}
+
+// consumedMocks returns the names of the mocks consumed in the replays of each test set, read from its reports.
+func (m *mocks) consumedMocks(dir string) (map[string]map[string]bool, error) {
+	consumed := map[string]map[string]bool{}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return consumed, nil
+		}
+		return nil, err
+	}
+	reportFS := yaml.NewTestReportFS(m.logger)
+	for _, entry := range entries {
+		name := strings.TrimSuffix(entry.Name(), ".yaml")
+		if entry.IsDir() || name == entry.Name() {
+			continue
+		}
+		doc, err := reportFS.Read(context.Background(), dir, name)
+		if err != nil {
+			m.logger.Warn("failed to read the test report", zap.String("report", name), zap.Error(err))
+			continue
+		}
+		report, ok := doc.(*models.TestReport)
+		if !ok || report.TestSet == "" {
+			continue
+		}
+		if consumed[report.TestSet] == nil {
+			consumed[report.TestSet] = map[string]bool{}
+		}
+		for _, mock := range report.ConsumedMocks {
+			consumed[report.TestSet][mock] = true
+		}
+	}
+	return consumed, nil
+}
+
+func readMocks(dir string) ([]*yaml.NetworkTrafficDoc, error) {
+	docs, err := yaml.ReadDocs(dir, mocksFile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read the mocks of %s: %v", dir, err)
+	}
+	return docs, nil
+}
+
+// mockIdentity returns what identifies the call of a mock: its kind, version and spec without the timestamps.
+func mockIdentity(doc *yaml.NetworkTrafficDoc) (string, error) {
+	var spec interface{}
+	if err := doc.Spec.Decode(&spec); err != nil {
+		return "", err
+	}
+	identity, err := yamlLib.Marshal(map[string]interface{}{
+		"kind":    doc.Kind,
+		"version": doc.Version,
+		"spec":    withoutTimestamps(spec),
+	})
+	return string(identity), err
+}
+
+func withoutTimestamps(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		stripped := map[string]interface{}{}
+		for key, child := range v {
+			if !timestampKeys[strings.ToLower(key)] {
+				stripped[key] = withoutTimestamps(child)
+			}
+		}
+		return stripped
+	case []interface{}:
+		stripped := make([]interface{}, len(v))
+		for i, child := range v {
+			stripped[i] = withoutTimestamps(child)
+		}
+		return stripped
+	}
+	return value
+}
+
+func isConfigMock(doc *yaml.NetworkTrafficDoc) bool {
+	var spec struct {
+		Metadata map[string]string `yaml:"metadata"`
+	}
+	if err := doc.Spec.Decode(&spec); err != nil {
+		return false
+	}
+	return spec.Metadata["type"] == "config"
+}
+
+// nameMocks gives the mocks which are named after their file, or whose name is taken, unique names like mock-3.
+func nameMocks(docs []*yaml.NetworkTrafficDoc) {
+	count := map[string]int{}
+	for _, doc := range docs {
+		count[doc.Name]++
+	}
+	used := map[string]bool{}
+	for _, doc := range docs {
+		if doc.Name != "" && doc.Name != mocksFile && count[doc.Name] == 1 {
+			used[doc.Name] = true
+		}
+	}
+	next := 0
+	for _, doc := range docs {
+		if used[doc.Name] && count[doc.Name] == 1 {
+			continue
+		}
+		for {
+			name := fmt.Sprintf("mock-%d", next)
+			next++
+			if !used[name] {
+				doc.Name = name
+				used[name] = true
+				break
+			}
+		}
+	}
+}
+
+// renameVariableSources updates the testcases the variables of a testcase spec are taken from.
+func renameVariableSources(spec *yamlLib.Node, renamed map[string]string) {
+	variables := mappingValue(spec, "variables")
+	if variables == nil || variables.Kind != yamlLib.MappingNode {
+		return
+	}
+	for i := 1; i < len(variables.Content); i += 2 {
+		from := mappingValue(variables.Content[i], "from")
+		if from == nil || from.Kind != yamlLib.ScalarNode {
+			continue
+		}
+		if name, ok := renamed[from.Value]; ok {
+			from.Value = name
+		}
+	}
+}
+
+func mappingValue(node *yamlLib.Node, key string) *yamlLib.Node {
+	if node == nil || node.Kind != yamlLib.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// copyAssets copies the files holding the large bodies of the mocks, which are named after their content.
+func copyAssets(src, dst string) error {
+	entries, err := os.ReadDir(src)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	if err := os.MkdirAll(dst, 0777); err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		target := filepath.Join(dst, entry.Name())
+		if _, err := os.Stat(target); err == nil {
+			continue
+		}
+		if err := copyFile(filepath.Join(src, entry.Name()), target); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
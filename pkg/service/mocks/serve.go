@@ -0,0 +1,177 @@
+package mocks
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"syscall"
+
+	"go.keploy.io/server/pkg"
+	"go.keploy.io/server/pkg/models"
+	"go.keploy.io/server/pkg/platform/yaml/spec"
+	"go.uber.org/zap"
+)
+
+// skippedHeaders are the headers of the recorded responses which aren't served, the bodies being served decoded and
+// in one piece.
+var skippedHeaders = map[string]bool{
+	"Content-Length":    true,
+	"Content-Encoding":  true,
+	"Transfer-Encoding": true,
+}
+
+// httpMock is a recorded http call served by the mock server.
+type httpMock struct {
+	name string
+	url  *url.URL
+	req  models.HttpReq
+	resp models.HttpResp
+}
+
+func (m *mocks) Serve(path, testSet string, port uint32, host string) error {
+	dir := filepath.Join(path, testSet)
+	if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+		return fmt.Errorf("the test set %s doesn't exist in %s", testSet, path)
+	}
+	docs, err := readMocks(dir)
+	if err != nil {
+		return err
+	}
+	var served []httpMock
+	skipped := 0
+	for _, doc := range docs {
+		if doc.Kind != models.HTTP {
+			skipped++
+			continue
+		}
+		httpSpec := spec.HttpSpec{}
+		if err := doc.Spec.Decode(&httpSpec); err != nil {
+			return fmt.Errorf("failed to decode the mock %s of %s: %v", doc.Name, testSet, err)
+		}
+		mockURL, err := url.Parse(httpSpec.Request.URL)
+		if err != nil {
+			m.logger.Warn("skipping the mock whose url can't be parsed", zap.String("mock", doc.Name), zap.Error(err))
+			continue
+		}
+		if host != "" && mockURL.Hostname() != host && mockURL.Host != host {
+			continue
+		}
+		served = append(served, httpMock{name: doc.Name, url: mockURL, req: httpSpec.Request, resp: httpSpec.Response})
+	}
+	if skipped > 0 {
+		m.logger.Info("the mocks of the other protocols than http aren't served", zap.String("testSet", testSet), zap.Int("skipped", skipped))
+	}
+	if len(served) == 0 {
+		return fmt.Errorf("the test set %s has no http mock to serve", testSet)
+	}
+
+	listener, err := net.Listen("tcp", net.JoinHostPort("", strconv.Itoa(int(port))))
+	if err != nil {
+		return fmt.Errorf("failed to listen on the port %d: %v", port, err)
+	}
+	server := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		m.serveMock(w, req, served)
+	})}
+	m.logger.Info("serving the http mocks of the test set", zap.String("testSet", testSet), zap.Int("mocks", len(served)), zap.String("address", listener.Addr().String()))
+
+	stopper := make(chan os.Signal, 1)
+	signal.Notify(stopper, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(stopper)
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- server.Serve(listener)
+	}()
+
+	select {
+	case <-stopper:
+	case err := <-serveErr:
+		return fmt.Errorf("the mock server stopped: %v", err)
+	}
+	return server.Close()
+}
+
+// serveMock answers the request with the response of the mock matching it best, or 404 when none matches.
+func (m *mocks) serveMock(w http.ResponseWriter, req *http.Request, served []httpMock) {
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("failed to read the request: %v", err), http.StatusBadRequest)
+		return
+	}
+	mock := matchMock(served, req, body)
+	if mock == nil {
+		m.logger.Warn("no mock matches the request", zap.String("method", req.Method), zap.String("url", req.URL.String()))
+		http.Error(w, fmt.Sprintf("keploy: no recorded mock matches %s %s", req.Method, req.URL.Path), http.StatusNotFound)
+		return
+	}
+	m.logger.Debug("serving the mock", zap.String("mock", mock.name), zap.String("method", req.Method), zap.String("url", req.URL.String()))
+	for key, values := range pkg.ToHttpHeader(mock.resp.Header) {
+		if skippedHeaders[http.CanonicalHeaderKey(key)] {
+			continue
+		}
+		w.Header()[key] = values
+	}
+	w.WriteHeader(mock.resp.StatusCode)
+	w.Write([]byte(mock.resp.Body))
+}
+
+// matchMock returns the mock of the method and path of the request, with the same query parameters, which matches it
+// best: the ones with the same values of the query parameters and the same body first, the first recorded one among
+// them.
+func matchMock(served []httpMock, req *http.Request, body []byte) *httpMock {
+	var best *httpMock
+	bestScore := -1
+	query := req.URL.Query()
+	for i := range served {
+		mock := &served[i]
+		if mock.req.Method != models.Method(req.Method) || mock.url.Path != req.URL.Path {
+			continue
+		}
+		mockQuery := mock.url.Query()
+		if !sameKeys(mockQuery, query) {
+			continue
+		}
+		score := 0
+		if reflect.DeepEqual(mockQuery, query) {
+			score++
+		}
+		if sameBody(mock.req.Body, body) {
+			score += 2
+		}
+		if score > bestScore {
+			best, bestScore = mock, score
+		}
+	}
+	return best
+}
+
+func sameKeys(a, b url.Values) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for key := range a {
+		if _, ok := b[key]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// sameBody tells whether the bodies are equal, the JSON ones whatever the order of their keys and their spaces.
+func sameBody(recorded string, body []byte) bool {
+	if recorded == string(body) {
+		return true
+	}
+	var a, b interface{}
+	if json.Unmarshal([]byte(recorded), &a) != nil || json.Unmarshal(body, &b) != nil {
+		return false
+	}
+	return reflect.DeepEqual(a, b)
+}
@@ -0,0 +1,15 @@
+package mocks
+
+type Mocks interface {
+	// Normalize removes the duplicated mocks of the test sets and gives the remaining ones unique names.
+	Normalize(path string, testSets []string) error
+	// Prune removes the mocks of the test sets which no replay report lists as consumed.
+	Prune(path string, testSets []string, dryRun bool) error
+	// Merge copies the testcases and the mocks of the test sets into a new test set, and returns its name.
+	Merge(path string, testSets []string, into string) (string, error)
+	// Convert stores the mocks of the test sets in the format, yaml or cbor.
+	Convert(path string, testSets []string, format string) error
+	// Serve answers the http calls matching the http mocks of the test set on the port, those of the host when it's
+	// set, until keploy is interrupted.
+	Serve(path, testSet string, port uint32, host string) error
+}
@@ -95,6 +95,8 @@ func (s *mockTester) MockTest(path string, proxyPort, pid uint32, mockName strin
 		readConfigMocks = append(readConfigMocks, configmock)
 	}
 
+	dnsMocks, readConfigMocks := hooks.SplitDNSMocks(readConfigMocks)
+	loadedHooks.SetDNSMocks(dnsMocks)
 	loadedHooks.SetConfigMocks(readConfigMocks)
 	loadedHooks.SetTcsMocks(readTcsMocks)
 
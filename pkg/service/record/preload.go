@@ -0,0 +1,55 @@
+package record
+
+import (
+	"go.keploy.io/server/pkg/hooks/preload"
+	"go.keploy.io/server/pkg/models"
+	"go.keploy.io/server/pkg/platform/yaml"
+	"go.uber.org/zap"
+)
+
+// preloadLibraries returns the command running the application with the libraries of the deterministic randomness,
+// whose seed is saved with the test set so that keploy test feeds the application the same random bytes, of the
+// capture of the unix sockets and, when connectPort is set, of the capture of its connections by the proxy listening
+// on it preloaded in it.
+func (r *recorder) preloadLibraries(appCmd, testSetPath string, deterministicRandom bool, unixSockets []models.UnixSocket, connectPort uint32) string {
+	if appCmd == "" {
+		return appCmd
+	}
+	var libs []preload.Library
+	if deterministicRandom {
+		seed, err := yaml.NewSeed(testSetPath)
+		if err != nil {
+			r.Logger.Error("failed to save the seed of the test set", zap.Error(err))
+		} else if lib, err := preload.Random(seed); err != nil {
+			r.Logger.Error("failed to set up the deterministic randomness of the application", zap.Error(err))
+		} else {
+			libs = append(libs, lib)
+		}
+	}
+	if len(unixSockets) > 0 {
+		var paths []string
+		for _, socket := range unixSockets {
+			paths = append(paths, socket.Path)
+		}
+		lib, err := preload.Unix(paths)
+		if err != nil {
+			r.Logger.Error("failed to set up the capture of the unix sockets of the application", zap.Error(err))
+		} else {
+			libs = append(libs, lib)
+		}
+	}
+	if connectPort != 0 {
+		lib, err := preload.Connect(connectPort)
+		if err != nil {
+			r.Logger.Error("failed to set up the capture of the connections of the application", zap.Error(err))
+		} else {
+			libs = append(libs, lib)
+		}
+	}
+	cmd, err := preload.Command(appCmd, libs)
+	if err != nil {
+		r.Logger.Warn("the randomness of the application isn't deterministic, nor its unix sockets and connections captured", zap.Error(err))
+		return appCmd
+	}
+	return cmd
+}
@@ -4,16 +4,18 @@ import (
 	"context"
 	"os"
 	"os/signal"
+	"path/filepath"
 	"syscall"
-	"time"
 
 	"go.keploy.io/server/pkg"
 	"go.keploy.io/server/pkg/hooks"
+	"go.keploy.io/server/pkg/hooks/connection"
 	"go.keploy.io/server/pkg/models"
 	"go.keploy.io/server/pkg/platform/fs"
 	"go.keploy.io/server/pkg/platform/telemetry"
 	"go.keploy.io/server/pkg/platform/yaml"
 	"go.keploy.io/server/pkg/proxy"
+	"go.keploy.io/server/pkg/shutdown"
 	"go.uber.org/zap"
 )
 
@@ -29,24 +31,38 @@ func NewRecorder(logger *zap.Logger) Recorder {
 	}
 }
 
-func (r *recorder) CaptureTraffic(path string, proxyPort uint32, appCmd, appContainer, appNetwork string, Delay uint64, buildDelay time.Duration, ports []uint, filters *models.Filters, enableTele bool) {
+func (r *recorder) CaptureTraffic(opts RecordOptions) {
 
 	var ps *proxy.ProxySet
+	// the filters are shared by the hooks, the ingress and the watcher of the config file, which reloads them
+	filters := &opts.Filters
 	stopper := make(chan os.Signal, 1)
 	signal.Notify(stopper, os.Interrupt, os.Kill, syscall.SIGHUP, syscall.SIGINT, syscall.SIGQUIT, syscall.SIGTERM, syscall.SIGKILL)
 
 	models.SetMode(models.MODE_RECORD)
 	teleFS := fs.NewTeleFS(r.Logger)
-	tele := telemetry.NewTelemetry(enableTele, false, teleFS, r.Logger, "", nil)
+	tele := telemetry.NewTelemetry(opts.EnableTele, false, teleFS, r.Logger, "", nil)
 	tele.Ping(false)
 
-	dirName, err := yaml.NewSessionIndex(path, r.Logger)
+	dirName, err := yaml.NewSessionIndex(opts.Path, r.Logger)
 	if err != nil {
 		r.Logger.Error("Failed to create the session index file", zap.Error(err))
 		return
 	}
 
-	ys := yaml.NewYamlStore(path+"/"+dirName+"/tests", path+"/"+dirName, "", "", r.Logger, tele)
+	ys := yaml.NewYamlStore(opts.Path+"/"+dirName+"/tests", opts.Path+"/"+dirName, "", "", r.Logger, tele)
+	// the mocks recorded are moved from their journal into the yaml files once the recording ends
+	defer ys.FinalizeMocks()
+	defer shutdown.Register("recorded mocks", ys.FinalizeMocks)()
+	ys.Dedup = opts.Dedup
+	ys.BodyStorage = opts.BodyStorage
+	ys.MockFormat = opts.MockFormat
+	ys.Redactor, err = yaml.NewRedactor(opts.Redact, r.Logger)
+	if err != nil {
+		r.Logger.Error("failed to set up the redaction of the recorded tests and mocks", zap.Error(err))
+		return
+	}
+
 	routineId := pkg.GenerateRandomID()
 	// Initiate the hooks and update the vaccant ProxyPorts map
 	loadedHooks, err := hooks.NewHook(ys, routineId, r.Logger)
@@ -55,6 +71,22 @@ func (r *recorder) CaptureTraffic(path string, proxyPort uint32, appCmd, appCont
 		return
 	}
 
+	loadedHooks.SetDenoisePasses(opts.DenoisePasses)
+	connection.SetNoise(opts.Noise)
+	loadedHooks.SetCaptureMode(opts.CaptureMode)
+
+	var sh *connection.Shadow
+	if opts.Shadow.Candidate != "" {
+		// the recorded calls are replayed to the candidate build, whose responses are compared with the recorded ones
+		sh, err = connection.NewShadow(opts.Shadow, filepath.Join(opts.Path, "shadowReports"), dirName, r.Logger)
+		if err != nil {
+			r.Logger.Error("failed to set up the shadow of the candidate build", zap.Error(err))
+			return
+		}
+		defer sh.Close()
+		loadedHooks.SetShadow(sh)
+	}
+
 	// Recover from panic and gracefully shutdown
 	defer loadedHooks.Recover(routineId)
 
@@ -62,13 +94,22 @@ func (r *recorder) CaptureTraffic(path string, proxyPort uint32, appCmd, appCont
 	testsTotal := 0
 	ctx := context.WithValue(context.Background(), "mocksTotal", &mocksTotal)
 	ctx = context.WithValue(ctx, "testsTotal", &testsTotal)
+	if len(opts.Ingress.AppPorts) > 0 {
+		// the testcases of each port of the application are tagged with it, to be run apart
+		ctx = context.WithValue(ctx, "tagPorts", true)
+	}
+	if opts.HeaderFidelity {
+		// the order and the case of the headers are recorded along with them
+		ctx = context.WithValue(ctx, "headerFidelity", true)
+	}
 
 	select {
 	case <-stopper:
 		return
 	default:
-		// load the ebpf hooks into the kernel
-		if err := loadedHooks.LoadHooks(appCmd, appContainer, 0, ctx, filters); err != nil {
+		// load the ebpf hooks into the kernel, or capture the calls in userspace when they can't be loaded
+		if err := loadedHooks.LoadCapture(opts.Command, opts.ContainerName, opts.Pid, ctx, filters); err != nil {
+			r.Logger.Error("failed to capture the calls of the application", zap.Error(err))
 			return
 		}
 	}
@@ -79,20 +120,73 @@ func (r *recorder) CaptureTraffic(path string, proxyPort uint32, appCmd, appCont
 		return
 	default:
 		// start the BootProxy
-		ps = proxy.BootProxy(r.Logger, proxy.Option{Port: proxyPort}, appCmd, appContainer, 0, "", ports, loadedHooks, ctx, 0)
+		ps = proxy.BootProxy(r.Logger, proxy.Option{Port: opts.ProxyPort, ProtoDescriptors: opts.ProtoDescriptors, ProtobufRoutes: opts.ProtobufRoutes, PassThrough: opts.PassThrough, UnixSockets: opts.UnixSockets, MaxBuffer: opts.MaxBuffer, MaxMessage: opts.MaxMessage}, opts.Command, opts.ContainerName, opts.Pid, "", opts.PassThroughPorts, loadedHooks, ctx, 0)
 	}
 
-	//proxy fetches the destIp and destPort from the redirect proxy map
-	//Sending Proxy Ip & Port to the ebpf program
-	if err := loadedHooks.SendProxyInfo(ps.IP4, ps.Port, ps.IP6); err != nil {
-		return
+	if loadedHooks.IsUserspace() {
+		// the incoming calls are sent to the ingress, which records them
+		if opts.Ingress.AppPort == 0 {
+			r.Logger.Warn("the calls to the application aren't recorded as testcases without the eBPF hooks unless its port is given with --app-port")
+		} else {
+			port := opts.Ingress.Port
+			if port == 0 {
+				port = connection.DefaultIngressPort
+			}
+			// each port of the application gets an ingress of its own, on the ports following the first one
+			for i, appPort := range append([]uint32{opts.Ingress.AppPort}, opts.Ingress.AppPorts...) {
+				in := connection.NewIngress(port+uint32(i), appPort, ys, ctx, filters, opts.DenoisePasses, sh, r.Logger)
+				if err := in.Start(); err != nil {
+					r.Logger.Error("failed to listen for the calls to the application", zap.Uint32("appPort", appPort), zap.Error(err))
+					loadedHooks.Stop(true)
+					ps.StopProxyServer()
+					return
+				}
+				defer in.Stop()
+			}
+		}
+	} else {
+		//proxy fetches the destIp and destPort from the redirect proxy map
+		//Sending Proxy Ip & Port to the ebpf program
+		if err := loadedHooks.SendProxyInfo(ps.IP4, ps.Port, ps.IP6); err != nil {
+			return
+		}
+	}
+
+	if opts.ConfigFile != "" {
+		// the changes of the config file are applied to the recording until it ends
+		watcher, err := newConfigWatcher(opts.ConfigFile, filters, ps, opts.FlagNoise, opts.FlagPassThrough, opts.FlagPassThroughPorts, r.Logger)
+		if err != nil {
+			r.Logger.Warn("failed to watch the config file", zap.String("config", opts.ConfigFile), zap.Error(err))
+		} else {
+			stopWatching := make(chan struct{})
+			defer close(stopWatching)
+			go watcher.watch(stopWatching)
+		}
+	}
+
+	var connectPort uint32
+	if loadedHooks.CapturesWithPreload() {
+		connectPort = ps.Port
 	}
+	launchCmd := r.preloadLibraries(opts.Command, opts.Path+"/"+dirName, opts.DeterministicRandom, opts.UnixSockets, connectPort)
 
 	// Channels to communicate between different types of closing keploy
 	abortStopHooksInterrupt := make(chan bool) // channel to stop closing of keploy via interrupt
 	exitCmd := make(chan bool)                 // channel to exit this command
 	abortStopHooksForcefully := false          // boolen to stop closing of keploy via user app error
 
+	if opts.Command == "" && opts.Pid != 0 {
+		// the application is already running, its calls are recorded until keploy is stopped
+		r.Logger.Info("recording the calls of the running application", zap.Uint32("pid", opts.Pid))
+		<-stopper
+		loadedHooks.Stop(true)
+		if testsTotal != 0 {
+			tele.RecordedTestSuite(dirName, testsTotal, mocksTotal)
+		}
+		ps.StopProxyServer()
+		return
+	}
+
 	select {
 	case <-stopper:
 		loadedHooks.Stop(true)
@@ -102,7 +196,7 @@ func (r *recorder) CaptureTraffic(path string, proxyPort uint32, appCmd, appCont
 		// start user application
 		go func() {
 			stopApplication := false
-			if err := loadedHooks.LaunchUserApplication(appCmd, appContainer, appNetwork, Delay, buildDelay, false); err != nil {
+			if err := loadedHooks.LaunchUserApplication(launchCmd, opts.ContainerName, opts.NetworkName, opts.Delay, opts.BuildDelay, false); err != nil {
 				switch err {
 				case hooks.ErrInterrupted:
 					r.Logger.Info("keploy terminated user application")
@@ -140,7 +234,7 @@ func (r *recorder) CaptureTraffic(path string, proxyPort uint32, appCmd, appCont
 		return
 	case <-abortStopHooksInterrupt:
 		if testsTotal != 0 {
-			tele.RecordedTestSuite(path, testsTotal, mocksTotal)
+			tele.RecordedTestSuite(opts.Path, testsTotal, mocksTotal)
 		}
 
 	}
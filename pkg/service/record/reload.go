@@ -0,0 +1,113 @@
+package record
+
+import (
+	"os"
+	"time"
+
+	"go.keploy.io/server/pkg/hooks/connection"
+	"go.keploy.io/server/pkg/models"
+	"go.keploy.io/server/pkg/proxy"
+	"go.uber.org/zap"
+	yamlLib "gopkg.in/yaml.v3"
+)
+
+// configPollInterval is how often the watched config file is checked for changes.
+const configPollInterval = time.Second
+
+// configWatcher applies the changes of the record section of the config file to the recording in progress: its
+// noise, its pass through rules and ports, and its filters. The noise and the pass through rules of the flags are
+// kept along with the ones of the config file, and the pass through ports of the flags in place of them, like when
+// the recording starts. The other settings are only read when the recording starts.
+type configWatcher struct {
+	path    string
+	filters *models.Filters
+	proxy   *proxy.ProxySet
+	logger  *zap.Logger
+	// flagNoise, flagPassThrough and flagPorts are the values of the flags, flagPorts being empty when the pass
+	// through ports are the ones of the config file
+	flagNoise       []string
+	flagPassThrough []models.PassThroughRule
+	flagPorts       []uint
+	// modified and size tell the changes of the file apart
+	modified time.Time
+	size     int64
+}
+
+// newConfigWatcher returns the watcher of the config file, given the noise, the pass through rules and the pass
+// through ports of the flags.
+func newConfigWatcher(path string, filters *models.Filters, ps *proxy.ProxySet, flagNoise []string, flagPassThrough []models.PassThroughRule, flagPorts []uint, logger *zap.Logger) (*configWatcher, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	return &configWatcher{
+		path:            path,
+		filters:         filters,
+		proxy:           ps,
+		logger:          logger,
+		flagNoise:       flagNoise,
+		flagPassThrough: flagPassThrough,
+		flagPorts:       flagPorts,
+		modified:        info.ModTime(),
+		size:            info.Size(),
+	}, nil
+}
+
+// watch polls the config file for changes until done is closed, applying them to the recording.
+func (w *configWatcher) watch(done <-chan struct{}) {
+	ticker := time.NewTicker(configPollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+		}
+		info, err := os.Stat(w.path)
+		if err != nil {
+			// the file is being replaced, like by the editors saving it
+			continue
+		}
+		if info.ModTime().Equal(w.modified) && info.Size() == w.size {
+			continue
+		}
+		w.modified, w.size = info.ModTime(), info.Size()
+		w.reload()
+	}
+}
+
+// reload applies the config file to the recording, which keeps the last config applied when the file can't be read
+// or its filters are invalid.
+func (w *configWatcher) reload() {
+	conf, err := ReadRecordConfig(w.path)
+	if err != nil {
+		w.logger.Warn("failed to read the changed config file, the recording keeps the last one", zap.String("config", w.path), zap.Error(err))
+		return
+	}
+	if err := connection.ValidateFilters(conf.Filters); err != nil {
+		w.logger.Warn("invalid record filters in the changed config file, the recording keeps the last one", zap.String("config", w.path), zap.Error(err))
+		return
+	}
+	ports := w.flagPorts
+	if len(ports) == 0 {
+		ports = conf.PassThroughPorts
+	}
+	connection.ReloadFilters(w.filters, conf.Filters)
+	connection.SetNoise(append(append([]string{}, w.flagNoise...), conf.Noise...))
+	w.proxy.SetPassThrough(append(append([]models.PassThroughRule{}, w.flagPassThrough...), conf.PassThrough...), ports)
+	w.logger.Info("applied the changes of the config file to the recording", zap.String("config", w.path))
+}
+
+// ReadRecordConfig reads the record section of the config file at path.
+func ReadRecordConfig(path string) (*models.Record, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	var doc models.Config
+	if err := yamlLib.NewDecoder(file).Decode(&doc); err != nil {
+		return nil, err
+	}
+	return &doc.Record, nil
+}
@@ -1,11 +1,27 @@
 package record
 
 import (
-	"time"
-
 	"go.keploy.io/server/pkg/models"
 )
 
 type Recorder interface {
-	CaptureTraffic(path string, proxyPort uint32, appCmd, appContainer, networkName string, Delay uint64, buildDelay time.Duration, ports []uint, filters *models.Filters, enableTele bool)
+	CaptureTraffic(opts RecordOptions)
+}
+
+// RecordOptions are the settings of a recording: the record section of the config file merged with the flags, and the
+// ones only given by the flags.
+type RecordOptions struct {
+	models.Record
+	// FlagNoise, FlagPassThrough and FlagPassThroughPorts are the values of the flags, kept apart from the ones of the
+	// config file so that its changes are applied along with them
+	FlagNoise            []string
+	FlagPassThrough      []models.PassThroughRule
+	FlagPassThroughPorts []uint
+	// ConfigFile is the config file whose changes are applied to the recording, none when it's empty
+	ConfigFile string
+	// Shadow is the candidate build the recorded calls are replayed to, none when its candidate is empty
+	Shadow models.Shadow
+	// Pid of the running application which is recorded instead of being started by keploy
+	Pid        uint32
+	EnableTele bool
 }
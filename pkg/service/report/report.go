@@ -0,0 +1,134 @@
+package report
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"go.keploy.io/server/pkg/models"
+	"go.uber.org/zap"
+	yamlLib "gopkg.in/yaml.v3"
+)
+
+type reporter struct {
+	logger *zap.Logger
+}
+
+func NewReporter(logger *zap.Logger) Reporter {
+	return &reporter{
+		logger: logger,
+	}
+}
+
+func (r *reporter) Merge(shardReports []string, output string) (*models.RunReport, error) {
+	if len(shardReports) == 0 {
+		return nil, fmt.Errorf("no report of shard to merge")
+	}
+	merged := &models.RunReport{
+		Version: models.GetVersion(),
+		Status:  string(models.TestRunStatusPassed),
+	}
+	count := 0
+	shards := map[int]string{}
+	testSets := map[string]string{}
+	for _, path := range shardReports {
+		report, err := readRunReport(path)
+		if err != nil {
+			return nil, err
+		}
+		for _, shard := range report.Shards {
+			index, n, err := parseShard(shard)
+			if err != nil {
+				return nil, fmt.Errorf("the report %s has an invalid shard: %v", path, err)
+			}
+			if count == 0 {
+				count = n
+			}
+			if n != count {
+				return nil, fmt.Errorf("the report %s is of a run of %d shards rather than %d", path, n, count)
+			}
+			if previous, ok := shards[index]; ok {
+				return nil, fmt.Errorf("the shard %s is reported by both %s and %s", shard, previous, path)
+			}
+			shards[index] = path
+		}
+		for _, testReport := range report.TestReports {
+			if previous, ok := testSets[testReport.TestSet]; ok {
+				return nil, fmt.Errorf("the test set %s is reported by both %s and %s", testReport.TestSet, previous, path)
+			}
+			testSets[testReport.TestSet] = path
+			merged.Add(testReport)
+		}
+		// the shards which failed without a test report, like when the application halted, fail the run
+		if report.Status != string(models.TestRunStatusPassed) {
+			merged.Status = string(models.TestRunStatusFailed)
+		}
+	}
+
+	var missing []string
+	for index := 1; index <= count; index++ {
+		if _, ok := shards[index]; !ok {
+			missing = append(missing, fmt.Sprintf("%d/%d", index, count))
+		}
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("the reports of the shards %s are missing", strings.Join(missing, ", "))
+	}
+	for index := 1; index <= count; index++ {
+		merged.Shards = append(merged.Shards, fmt.Sprintf("%d/%d", index, count))
+	}
+	sort.SliceStable(merged.TestReports, func(i, j int) bool {
+		return testSetIndex(merged.TestReports[i].TestSet) < testSetIndex(merged.TestReports[j].TestSet)
+	})
+
+	data, err := yamlLib.Marshal(merged)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal the merged report: %v", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(output), os.ModePerm); err != nil {
+		return nil, fmt.Errorf("failed to create the directory of the merged report: %v", err)
+	}
+	if err := os.WriteFile(output, data, os.ModePerm); err != nil {
+		return nil, fmt.Errorf("failed to write the merged report: %v", err)
+	}
+	r.logger.Info("merged the reports of the shards", zap.String("path", output), zap.Int("shards", count), zap.Int("testSets", len(merged.TestReports)), zap.String("status", merged.Status))
+	return merged, nil
+}
+
+func readRunReport(path string) (*models.RunReport, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the report %s: %v", path, err)
+	}
+	var report models.RunReport
+	if err := yamlLib.Unmarshal(data, &report); err != nil {
+		return nil, fmt.Errorf("failed to decode the report %s: %v", path, err)
+	}
+	if len(report.Shards) == 0 {
+		return nil, fmt.Errorf("%s isn't the report of a shard", path)
+	}
+	return &report, nil
+}
+
+// parseShard parses a shard written like 2/4.
+func parseShard(shard string) (int, int, error) {
+	index, count, ok := strings.Cut(shard, "/")
+	i, err1 := strconv.Atoi(index)
+	n, err2 := strconv.Atoi(count)
+	if !ok || err1 != nil || err2 != nil || n < 1 || i < 1 || i > n {
+		return 0, 0, fmt.Errorf("invalid shard %q, expected i/n", shard)
+	}
+	return i, n, nil
+}
+
+// testSetIndex returns the index of a test set, like 3 for test-set-3, to report the test sets in their order.
+func testSetIndex(testSet string) int {
+	index, err := strconv.Atoi(strings.TrimPrefix(testSet, models.TestSetPattern))
+	if err != nil {
+		return -1
+	}
+	return index
+}
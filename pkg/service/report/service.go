@@ -0,0 +1,8 @@
+package report
+
+import "go.keploy.io/server/pkg/models"
+
+type Reporter interface {
+	// Merge combines the reports of the shards of a run into the report of the whole run, written to the output file.
+	Merge(shardReports []string, output string) (*models.RunReport, error)
+}
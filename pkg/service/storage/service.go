@@ -0,0 +1,10 @@
+package storage
+
+type Storage interface {
+	// Push uploads the test sets of the keploy directory to the storage, all of them when none is given. The objects
+	// of the files removed from the test sets are deleted.
+	Push(path string, testSets []string) error
+	// Pull downloads the test sets of the storage into the keploy directory, all of them when none is given, and
+	// returns the ones downloaded. The test sets already in the directory are only replaced when forced.
+	Pull(path string, testSets []string, force bool) ([]string, error)
+}
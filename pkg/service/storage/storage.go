@@ -0,0 +1,194 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"go.keploy.io/server/pkg/models"
+	"go.keploy.io/server/pkg/platform/storage"
+	"go.uber.org/zap"
+)
+
+// testSetName is the name of the directories which are run as test sets
+var testSetName = regexp.MustCompile(fmt.Sprintf(`^%s\d+$`, models.TestSetPattern))
+
+type remoteStorage struct {
+	logger  *zap.Logger
+	backend storage.Backend
+	prefix  string
+}
+
+func NewStorage(logger *zap.Logger, config models.Storage) (Storage, error) {
+	backend, err := storage.New(config)
+	if err != nil {
+		return nil, err
+	}
+	prefix := strings.Trim(config.Prefix, "/")
+	if prefix != "" {
+		prefix += "/"
+	}
+	return &remoteStorage{
+		logger:  logger,
+		backend: backend,
+		prefix:  prefix,
+	}, nil
+}
+
+// localTestSets returns the test sets of the keploy directory.
+func localTestSets(path string) ([]string, error) {
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, entry := range entries {
+		if entry.IsDir() && testSetName.MatchString(entry.Name()) {
+			names = append(names, entry.Name())
+		}
+	}
+	return names, nil
+}
+
+// remoteTestSets returns the test sets of the storage.
+func (r *remoteStorage) remoteTestSets(ctx context.Context) ([]string, error) {
+	keys, err := r.backend.List(ctx, r.prefix)
+	if err != nil {
+		return nil, err
+	}
+	found := map[string]bool{}
+	var names []string
+	for _, key := range keys {
+		name := strings.SplitN(strings.TrimPrefix(key, r.prefix), "/", 2)[0]
+		if testSetName.MatchString(name) && !found[name] {
+			found[name] = true
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+func (r *remoteStorage) Push(path string, testSets []string) error {
+	ctx := context.Background()
+	if len(testSets) == 0 {
+		var err error
+		testSets, err = localTestSets(path)
+		if err != nil {
+			return fmt.Errorf("failed to list the test sets of %s: %v", path, err)
+		}
+	}
+	for _, testSet := range testSets {
+		dir := filepath.Join(path, testSet)
+		if _, err := os.Stat(dir); err != nil {
+			return fmt.Errorf("the test set %s doesn't exist", testSet)
+		}
+		pushed := map[string]bool{}
+		err := filepath.Walk(dir, func(file string, info os.FileInfo, err error) error {
+			if err != nil || info.IsDir() {
+				return err
+			}
+			rel, err := filepath.Rel(path, file)
+			if err != nil {
+				return err
+			}
+			data, err := os.ReadFile(file)
+			if err != nil {
+				return err
+			}
+			key := r.prefix + filepath.ToSlash(rel)
+			if err := r.backend.Put(ctx, key, data); err != nil {
+				return err
+			}
+			pushed[key] = true
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("failed to push the test set %s: %v", testSet, err)
+		}
+		// the files removed from the test set since its last push, like the pruned mocks
+		keys, err := r.backend.List(ctx, r.prefix+testSet+"/")
+		if err != nil {
+			return fmt.Errorf("failed to list the objects of the test set %s: %v", testSet, err)
+		}
+		for _, key := range keys {
+			if pushed[key] {
+				continue
+			}
+			if err := r.backend.Delete(ctx, key); err != nil {
+				return fmt.Errorf("failed to delete the removed file %s of the test set %s: %v", key, testSet, err)
+			}
+		}
+		r.logger.Info("pushed the test set to the storage", zap.String("testSet", testSet), zap.Int("files", len(pushed)))
+	}
+	return nil
+}
+
+func (r *remoteStorage) Pull(path string, testSets []string, force bool) ([]string, error) {
+	ctx := context.Background()
+	if len(testSets) == 0 {
+		var err error
+		testSets, err = r.remoteTestSets(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list the test sets of the storage: %v", err)
+		}
+	}
+	var pulled []string
+	for _, testSet := range testSets {
+		dir := filepath.Join(path, testSet)
+		if _, err := os.Stat(dir); err == nil {
+			if !force {
+				r.logger.Debug("the test set is already in the keploy directory", zap.String("testSet", testSet))
+				continue
+			}
+		}
+		keys, err := r.backend.List(ctx, r.prefix+testSet+"/")
+		if err != nil {
+			return pulled, fmt.Errorf("failed to list the objects of the test set %s: %v", testSet, err)
+		}
+		if len(keys) == 0 {
+			r.logger.Warn("the test set isn't in the storage", zap.String("testSet", testSet))
+			continue
+		}
+		// the test set is downloaded next to the directory, which it replaces once complete
+		tempDir := dir + ".pull"
+		if err := os.RemoveAll(tempDir); err != nil {
+			return pulled, err
+		}
+		for _, key := range keys {
+			data, err := r.backend.Get(ctx, key)
+			if err != nil {
+				os.RemoveAll(tempDir)
+				return pulled, fmt.Errorf("failed to pull the test set %s: %v", testSet, err)
+			}
+			rel := strings.TrimPrefix(key, r.prefix+testSet+"/")
+			file := filepath.Join(tempDir, filepath.FromSlash(rel))
+			// the keys can't escape the directory of the test set
+			if !strings.HasPrefix(file, tempDir+string(os.PathSeparator)) {
+				continue
+			}
+			if err := os.MkdirAll(filepath.Dir(file), 0777); err != nil {
+				os.RemoveAll(tempDir)
+				return pulled, err
+			}
+			if err := os.WriteFile(file, data, 0644); err != nil {
+				os.RemoveAll(tempDir)
+				return pulled, err
+			}
+		}
+		if err := os.RemoveAll(dir); err != nil {
+			os.RemoveAll(tempDir)
+			return pulled, err
+		}
+		if err := os.Rename(tempDir, dir); err != nil {
+			return pulled, fmt.Errorf("failed to replace the test set %s: %v", testSet, err)
+		}
+		r.logger.Info("pulled the test set from the storage", zap.String("testSet", testSet), zap.Int("files", len(keys)))
+		pulled = append(pulled, testSet)
+	}
+	return pulled, nil
+}
@@ -0,0 +1,101 @@
+package test
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+
+	"go.keploy.io/server/pkg/models"
+	"go.uber.org/zap"
+)
+
+// validChaos returns the chaos config without its invalid faults.
+func (t *tester) validChaos(chaos models.Chaos) models.Chaos {
+	valid := models.Chaos{Enabled: chaos.Enabled}
+	if !chaos.Enabled {
+		return valid
+	}
+	for _, fault := range chaos.Faults {
+		switch fault.Type {
+		case models.FaultReset, models.FaultStatus, models.FaultTruncate:
+			if fault.Kind != "" && !strings.EqualFold(string(fault.Kind), string(models.HTTP)) {
+				t.logger.Warn("skipping the fault which only applies to the http mocks", zap.Any("fault", fault))
+				continue
+			}
+		case models.FaultLatency:
+			if fault.Latency <= 0 {
+				t.logger.Warn("skipping the latency fault without a positive latency", zap.Any("fault", fault))
+				continue
+			}
+		default:
+			t.logger.Warn("skipping the fault of an unknown type, among reset, status, truncate and latency", zap.Any("fault", fault))
+			continue
+		}
+		if fault.Type == models.FaultStatus && fault.Status == 0 {
+			fault.Status = 503
+		}
+		if fault.Status != 0 && (fault.Status < 100 || fault.Status > 599) {
+			t.logger.Warn("skipping the fault with an invalid status", zap.Any("fault", fault))
+			continue
+		}
+		if fault.Probability <= 0 || fault.Probability > 1 {
+			fault.Probability = 1
+		}
+		if _, err := wholeMatchRegex(fault.TestSet); err != nil {
+			t.logger.Warn("skipping the fault with an invalid test set regex", zap.Any("fault", fault), zap.Error(err))
+			continue
+		}
+		if _, err := wholeMatchRegex(fault.Name); err != nil {
+			t.logger.Warn("skipping the fault with an invalid name regex", zap.Any("fault", fault), zap.Error(err))
+			continue
+		}
+		if _, err := regexp.Compile(fault.Path); err != nil {
+			t.logger.Warn("skipping the fault with an invalid path regex", zap.Any("fault", fault), zap.Error(err))
+			continue
+		}
+		valid.Faults = append(valid.Faults, fault)
+	}
+	if len(valid.Faults) == 0 {
+		t.logger.Warn("the chaos mode is enabled without any valid fault, the mocks are replayed normally")
+	}
+	return valid
+}
+
+// applyFaults sets the faults of the mocks of the test set, from the first fault of the chaos config selecting each
+// of them.
+func (t *tester) applyFaults(mocks []*models.Mock, testSet string) {
+	for _, mock := range mocks {
+		mock.Fault = nil
+		for i := range t.chaos.Faults {
+			fault := &t.chaos.Faults[i]
+			if fault.Kind != "" && !strings.EqualFold(string(fault.Kind), string(mock.Kind)) {
+				continue
+			}
+			if fault.Type != models.FaultLatency && mock.Kind != models.HTTP {
+				continue
+			}
+			if !matchesWhole(fault.TestSet, testSet) || !matchesWhole(fault.Name, mock.Name) || !matchesPath(fault.Path, mock) {
+				continue
+			}
+			mock.Fault = fault
+			break
+		}
+	}
+}
+
+// matchesPath reports whether the regex matches the path of the url of the http mock, the empty regex matching any
+// mock.
+func matchesPath(pattern string, mock *models.Mock) bool {
+	if pattern == "" {
+		return true
+	}
+	if mock.Spec.HttpReq == nil {
+		return false
+	}
+	u, err := url.Parse(mock.Spec.HttpReq.URL)
+	if err != nil {
+		return false
+	}
+	re, err := regexp.Compile(pattern)
+	return err == nil && re.MatchString(u.Path)
+}
@@ -0,0 +1,78 @@
+package test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"go.keploy.io/server/pkg/service/coverage"
+	"go.uber.org/zap"
+)
+
+// coverageDir returns the directory of the coverage of the run, like InitialiseTest.
+func coverageDir(path, coverageReportPath string) string {
+	if coverageReportPath == "" {
+		return path + "/coverage-reports"
+	}
+	if !strings.HasPrefix(coverageReportPath, "/") {
+		if absPath, err := filepath.Abs(coverageReportPath); err == nil {
+			coverageReportPath = absPath
+		}
+	}
+	return coverageReportPath + "/coverage-reports"
+}
+
+// startCoverage makes the application started for the test set write its coverage data in the directory of the test
+// set, so that the coverage of each test set is told apart, and returns the command running it.
+func (t *tester) startCoverage(collector coverage.Collector, coverageReportPath, testSet, appCmd string) string {
+	dir := filepath.Join(coverageReportPath, testSet)
+	// the coverage data of the previous runs of the test set would be merged with the ones of this run
+	if err := os.RemoveAll(dir); err != nil {
+		t.logger.Warn("failed to clear the coverage of the previous run of the test set", zap.String("testSet", testSet), zap.Error(err))
+	}
+	if err := makeDirectory(dir); err != nil {
+		t.logger.Error("failed to create the coverage directory of the test set", zap.String("testSet", testSet), zap.Error(err))
+		return appCmd
+	}
+	cmd, err := collector.Start(appCmd, dir)
+	if err != nil {
+		t.logger.Error("failed to start collecting the coverage of the test set", zap.String("testSet", testSet), zap.Error(err))
+		return appCmd
+	}
+	return cmd
+}
+
+// flushCoverage converts the coverage data of the test set, once its application is stopped, into its coverage report,
+// like coverage-reports/test-set-1.out for the go binaries.
+func (t *tester) flushCoverage(collector coverage.Collector, coverageReportPath, testSet string) {
+	dir := filepath.Join(coverageReportPath, testSet)
+	if _, err := os.Stat(dir); err != nil {
+		return
+	}
+	if err := collector.Flush(dir); err != nil {
+		t.logger.Error("failed to collect the coverage of the test set", zap.String("testSet", testSet), zap.Error(err))
+	}
+}
+
+// mergeCoverage merges the coverage profiles of the test sets with the ones of the unit tests into
+// coverage-reports/coverage.out, and logs the coverage of the run.
+func (t *tester) mergeCoverage(coverageReportPath string, testSets []string, unitProfiles []string) {
+	var profiles []string
+	for _, testSet := range testSets {
+		profile := filepath.Join(coverageReportPath, testSet+".out")
+		if _, err := os.Stat(profile); err == nil {
+			profiles = append(profiles, profile)
+		}
+	}
+	if len(profiles) == 0 {
+		t.logger.Warn("no coverage was collected, check that the application is a go binary built with -cover")
+		return
+	}
+	profiles = append(profiles, unitProfiles...)
+	merged, err := t.coverage.Merge(profiles, filepath.Join(coverageReportPath, "coverage.out"))
+	if err != nil {
+		t.logger.Error("failed to merge the coverage profiles", zap.Error(err))
+		return
+	}
+	t.coverage.Report(merged, 0)
+}
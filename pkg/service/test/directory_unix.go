@@ -0,0 +1,19 @@
+//go:build !windows
+
+package test
+
+import (
+	"os"
+	"syscall"
+)
+
+// creates a directory if not exists with all user access
+func makeDirectory(path string) error {
+	oldUmask := syscall.Umask(0)
+	err := os.MkdirAll(path, 0777)
+	if err != nil {
+		return err
+	}
+	syscall.Umask(oldUmask)
+	return nil
+}
@@ -0,0 +1,8 @@
+package test
+
+import "os"
+
+// creates a directory if not exists, windows having no umask to clear
+func makeDirectory(path string) error {
+	return os.MkdirAll(path, 0777)
+}
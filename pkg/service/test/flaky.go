@@ -0,0 +1,170 @@
+package test
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"go.keploy.io/server/pkg"
+	"go.keploy.io/server/pkg/models"
+	"go.keploy.io/server/pkg/platform/yaml"
+	"go.uber.org/zap"
+	yamlLib "gopkg.in/yaml.v3"
+)
+
+// FlakeDetection sets the repeated runs of the testcases telling the flaky ones.
+type FlakeDetection struct {
+	// Runs is the number of times each testcase is run, the detection being off below 2
+	Runs int
+	// FixNoise adds the fields whose values change from a run to the next to the noise of the testcases
+	FixNoise bool
+}
+
+// flakeRun is the result of a run of a testcase.
+type flakeRun struct {
+	passed bool
+	resp   *models.HttpResp
+}
+
+// repeatTestcase runs the testcase again until it was run the number of runs of the flake detection, its mocks being
+// set again before each run, and returns the result of its runs along with the one of its first run.
+func (t *tester) repeatTestcase(cfg *SimulateRequestConfig, mocks []*models.Mock, first flakeRun) models.FlakyTest {
+	runs := []flakeRun{first}
+	for i := 1; i < t.flakeDetection.Runs; i++ {
+		cfg.LoadedHooks.SetTcsMocks(mocks)
+		resp, err := pkg.SimulateHttp(*cfg.Tc, cfg.TestSet, t.logger, cfg.ApiTimeout)
+		if err != nil && resp == nil {
+			runs = append(runs, flakeRun{})
+			continue
+		}
+		passed, _ := t.testHttp(*cfg.Tc, resp, cfg.NoiseConfig)
+		runs = append(runs, flakeRun{passed: passed, resp: resp})
+	}
+
+	result := models.FlakyTest{Name: cfg.Tc.Name, Status: models.FlakeStatusStable}
+	for _, run := range runs {
+		if run.passed {
+			result.Passed++
+		} else {
+			result.Failed++
+		}
+	}
+	result.Fields = flakyFields(runs, cfg.Tc.Noise)
+	if (result.Passed > 0 && result.Failed > 0) || len(result.Fields) > 0 {
+		result.Status = models.FlakeStatusFlaky
+		t.logger.Warn("the testcase is flaky", zap.String("testcase", cfg.Tc.Name), zap.String("test-set", cfg.TestSet), zap.Int("passed", result.Passed), zap.Int("failed", result.Failed), zap.Any("fields", result.Fields))
+	}
+	return result
+}
+
+// flakyFields returns the fields of the responses whose values changed from a run to the next, apart from the noisy
+// ones. The headers are written like header.Date, the values of the JSON bodies like body.data.id, and the other
+// bodies as body.
+func flakyFields(runs []flakeRun, noise map[string][]string) []models.FlakyField {
+	values := map[string][]string{}
+	var responses int
+	for _, run := range runs {
+		if run.resp == nil {
+			continue
+		}
+		responses++
+		fields := map[string]string{}
+		for name, value := range run.resp.Header {
+			fields["header."+name] = value
+		}
+		if leaves := pkg.JsonLeaves(run.resp.Body); leaves != nil {
+			for path, value := range leaves {
+				fields[path] = value
+			}
+		} else {
+			fields["body"] = run.resp.Body
+		}
+		for path, value := range fields {
+			values[path] = append(values[path], value)
+		}
+	}
+
+	var flaky []models.FlakyField
+	for path, vals := range values {
+		if isNoisy(path, noise) {
+			continue
+		}
+		distinct := map[string]bool{}
+		for _, value := range vals {
+			distinct[value] = true
+		}
+		// the field changed its value, or was missing from some responses
+		if len(distinct) > 1 || len(vals) < responses {
+			flaky = append(flaky, models.FlakyField{Path: path, Values: vals})
+		}
+	}
+	sort.Slice(flaky, func(i, j int) bool { return flaky[i].Path < flaky[j].Path })
+	return flaky
+}
+
+// isNoisy reports whether the field, or one of its parents, is noisy.
+func isNoisy(path string, noise map[string][]string) bool {
+	for field := range noise {
+		if field == "body" && strings.HasPrefix(path, "body") {
+			return true
+		}
+		if field == "header" && strings.HasPrefix(path, "header.") {
+			return true
+		}
+		if path == field || strings.HasPrefix(path, field+".") {
+			return true
+		}
+		if strings.EqualFold(path, "header."+field) {
+			return true
+		}
+	}
+	return false
+}
+
+// finishFlakeDetection writes the flake report of the test set next to its test report, and adds the flaky fields to
+// the noise of the testcases when asked to.
+func (t *tester) finishFlakeDetection(testSet, path, testReportPath, reportName string, tests []models.FlakyTest) {
+	report := models.FlakeReport{
+		Version: models.GetVersion(),
+		TestSet: testSet,
+		Runs:    t.flakeDetection.Runs,
+		Tests:   tests,
+	}
+	for _, test := range tests {
+		if test.Status == models.FlakeStatusFlaky {
+			report.Flaky++
+		} else {
+			report.Stable++
+		}
+	}
+	data, err := yamlLib.Marshal(report)
+	if err != nil {
+		t.logger.Error("failed to marshal the flake report", zap.Error(err))
+		return
+	}
+	reportPath := filepath.Join(testReportPath, "flakes-"+reportName+".yaml")
+	if err := os.WriteFile(reportPath, data, 0644); err != nil {
+		t.logger.Error("failed to write the flake report", zap.String("path", reportPath), zap.Error(err))
+		return
+	}
+	t.logger.Info("flake report for "+testSet, zap.Int("stable", report.Stable), zap.Int("flaky", report.Flaky), zap.String("path", reportPath))
+
+	if !t.flakeDetection.FixNoise {
+		return
+	}
+	for _, test := range tests {
+		if len(test.Fields) == 0 {
+			continue
+		}
+		fields := make([]string, 0, len(test.Fields))
+		for _, field := range test.Fields {
+			fields = append(fields, field.Path)
+		}
+		if err := yaml.AddNoise(filepath.Join(path, testSet, "tests"), test.Name, fields); err != nil {
+			t.logger.Error("failed to add the flaky fields to the noise of the testcase", zap.String("testcase", test.Name), zap.Error(err))
+			continue
+		}
+		t.logger.Info("added the flaky fields to the noise of the testcase", zap.String("testcase", test.Name), zap.Strings("fields", fields))
+	}
+}
@@ -0,0 +1,80 @@
+package test
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+
+	"go.keploy.io/server/pkg/models"
+	"go.keploy.io/server/pkg/proxy/integrations/graphql"
+)
+
+// operationResult counts the results of the testcases of a GraphQL operation.
+type operationResult struct {
+	Operation string
+	Passed    int
+	Failed    int
+}
+
+// graphQLOperation returns the GraphQL operation of the request, like "query GetUser", empty for the requests which
+// aren't GraphQL ones.
+func graphQLOperation(req models.HttpReq) string {
+	var (
+		gql *graphql.Request
+		ok  bool
+	)
+	if req.Method == http.MethodGet {
+		if parsedURL, err := url.Parse(req.URL); err == nil {
+			gql, ok = graphql.ParseQuery(parsedURL.Query())
+		}
+	} else {
+		gql, ok = graphql.Parse([]byte(req.Body))
+	}
+	if !ok {
+		return ""
+	}
+	return gql.Operation()
+}
+
+// operationResults groups the results of the GraphQL testcases by their operation, in the order of the operations.
+func operationResults(results []models.TestResult) []operationResult {
+	byOperation := map[string]*operationResult{}
+	for _, result := range results {
+		if result.GraphQL == "" {
+			continue
+		}
+		operation, ok := byOperation[result.GraphQL]
+		if !ok {
+			operation = &operationResult{Operation: result.GraphQL}
+			byOperation[result.GraphQL] = operation
+		}
+		if result.Status == models.TestStatusPassed {
+			operation.Passed++
+		} else {
+			operation.Failed++
+		}
+	}
+	operations := make([]operationResult, 0, len(byOperation))
+	for _, operation := range byOperation {
+		operations = append(operations, *operation)
+	}
+	sort.Slice(operations, func(i, j int) bool { return operations[i].Operation < operations[j].Operation })
+	return operations
+}
+
+// operationSummary returns the lines of the results of the GraphQL operations printed after the summary of the test
+// run, empty when no testcase calls a GraphQL api.
+func operationSummary(results []models.TestResult) string {
+	operations := operationResults(results)
+	if len(operations) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteString("  GRAPHQL OPERATIONS:\n")
+	for _, operation := range operations {
+		b.WriteString(fmt.Sprintf("\t%s: %d passed, %d failed\n", operation.Operation, operation.Passed, operation.Failed))
+	}
+	return b.String()
+}
@@ -0,0 +1,287 @@
+package test
+
+import (
+	"bytes"
+	"encoding/json"
+	"html/template"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"go.keploy.io/server/pkg/models"
+)
+
+// maxDiffLines bounds the lines of the bodies which are diffed, the longer bodies being shown without highlights.
+const maxDiffLines = 2000
+
+type htmlReport struct {
+	Report *models.TestReport
+	Tests  []htmlTest
+	// Operations are the results of the GraphQL operations
+	Operations []operationResult
+}
+
+type htmlTest struct {
+	Result     models.TestResult
+	Passed     bool
+	StatusCode models.IntResult
+	Headers    []models.HeaderResult
+	Body       []diffLine
+	Fields     []fieldDiff
+	Noise      []string
+}
+
+// diffLine is a line of the side by side comparison of the bodies, the missing side of the added or removed lines
+// being empty.
+type diffLine struct {
+	Expected string
+	Actual   string
+	// Kind is "same", "changed", "removed" or "added"
+	Kind string
+}
+
+// fieldDiff compares a field of the JSON bodies, its path being like body.data.id.
+type fieldDiff struct {
+	Path     string
+	Expected string
+	Actual   string
+	Equal    bool
+	Noisy    bool
+}
+
+// writeHtmlReport writes the HTML report of a test set in the reports directory next to the test reports one, like
+// keploy/reports/report-1.html.
+func writeHtmlReport(testReportPath string, report *models.TestReport) (string, error) {
+	data := htmlReport{Report: report, Operations: operationResults(report.Tests)}
+	for _, result := range report.Tests {
+		data.Tests = append(data.Tests, newHtmlTest(result))
+	}
+	var buf bytes.Buffer
+	if err := htmlReportTemplate.Execute(&buf, data); err != nil {
+		return "", err
+	}
+	dir := filepath.Join(filepath.Dir(testReportPath), "reports")
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return "", err
+	}
+	path := filepath.Join(dir, report.Name+".html")
+	return path, os.WriteFile(path, buf.Bytes(), os.ModePerm)
+}
+
+func newHtmlTest(result models.TestResult) htmlTest {
+	test := htmlTest{
+		Result:     result,
+		Passed:     result.Status == models.TestStatusPassed,
+		StatusCode: result.Result.StatusCode,
+		Headers:    result.Result.HeadersResult,
+	}
+	for key := range result.Noise {
+		test.Noise = append(test.Noise, key)
+	}
+	sort.Strings(test.Noise)
+	sort.SliceStable(test.Headers, func(i, j int) bool { return test.Headers[i].Expected.Key < test.Headers[j].Expected.Key })
+
+	for _, body := range result.Result.BodyResult {
+		expected, actual := prettyJson(body.Expected), prettyJson(body.Actual)
+		test.Body = diffLines(strings.Split(expected, "\n"), strings.Split(actual, "\n"))
+		if body.Type == models.BodyTypeJSON {
+			test.Fields = diffFields(body.Expected, body.Actual, result.Noise)
+		}
+	}
+	return test
+}
+
+// prettyJson indents the JSON bodies, the other bodies being returned as they are.
+func prettyJson(body string) string {
+	var buf bytes.Buffer
+	if err := json.Indent(&buf, []byte(body), "", "  "); err != nil {
+		return body
+	}
+	return buf.String()
+}
+
+// diffLines pairs the lines of the expected and actual bodies along their longest common subsequence.
+func diffLines(expected, actual []string) []diffLine {
+	if len(expected) > maxDiffLines || len(actual) > maxDiffLines {
+		var lines []diffLine
+		for i := 0; i < len(expected) || i < len(actual); i++ {
+			line := diffLine{Kind: "same"}
+			if i < len(expected) {
+				line.Expected = expected[i]
+			}
+			if i < len(actual) {
+				line.Actual = actual[i]
+			}
+			lines = append(lines, line)
+		}
+		return lines
+	}
+	// lcs[i][j] is the length of the longest common subsequence of expected[i:] and actual[j:]
+	lcs := make([][]int, len(expected)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(actual)+1)
+	}
+	for i := len(expected) - 1; i >= 0; i-- {
+		for j := len(actual) - 1; j >= 0; j-- {
+			if expected[i] == actual[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+	var lines []diffLine
+	var removed, added []string
+	// the removed lines followed by added ones are shown side by side as changed lines
+	flush := func() {
+		for k := 0; k < len(removed) || k < len(added); k++ {
+			switch {
+			case k < len(removed) && k < len(added):
+				lines = append(lines, diffLine{Expected: removed[k], Actual: added[k], Kind: "changed"})
+			case k < len(removed):
+				lines = append(lines, diffLine{Expected: removed[k], Kind: "removed"})
+			default:
+				lines = append(lines, diffLine{Actual: added[k], Kind: "added"})
+			}
+		}
+		removed, added = nil, nil
+	}
+	i, j := 0, 0
+	for i < len(expected) || j < len(actual) {
+		switch {
+		case i < len(expected) && j < len(actual) && expected[i] == actual[j]:
+			flush()
+			lines = append(lines, diffLine{Expected: expected[i], Actual: actual[j], Kind: "same"})
+			i++
+			j++
+		case j >= len(actual) || i < len(expected) && lcs[i+1][j] >= lcs[i][j+1]:
+			removed = append(removed, expected[i])
+			i++
+		default:
+			added = append(added, actual[j])
+			j++
+		}
+	}
+	flush()
+	return lines
+}
+
+// diffFields compares the fields of the JSON bodies, annotating the ones made noisy by the testcase.
+func diffFields(expected, actual string, noise models.Noise) []fieldDiff {
+	expectedFields, actualFields := map[string][]string{}, map[string][]string{}
+	if AddHttpBodyToMap(expected, expectedFields) != nil || AddHttpBodyToMap(actual, actualFields) != nil {
+		return nil
+	}
+	paths := map[string]bool{}
+	for path := range expectedFields {
+		paths[path] = true
+	}
+	for path := range actualFields {
+		paths[path] = true
+	}
+	var fields []fieldDiff
+	for path := range paths {
+		expectedValue, inExpected := expectedFields[path]
+		actualValue, inActual := actualFields[path]
+		field := fieldDiff{
+			Path:     path,
+			Expected: strings.Join(expectedValue, ", "),
+			Actual:   strings.Join(actualValue, ", "),
+			Noisy:    isNoisyField(path, noise),
+		}
+		field.Equal = inExpected && inActual && field.Expected == field.Actual
+		fields = append(fields, field)
+	}
+	sort.Slice(fields, func(i, j int) bool { return fields[i].Path < fields[j].Path })
+	return fields
+}
+
+// isNoisyField reports whether the field, or one of its parents, is a noisy field of the testcase.
+func isNoisyField(path string, noise models.Noise) bool {
+	for key := range noise {
+		if path == key || strings.HasPrefix(path, key+".") {
+			return true
+		}
+	}
+	return false
+}
+
+var htmlReportTemplate = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Keploy {{.Report.Name}} - {{.Report.TestSet}}</title>
+<style>
+body { font-family: -apple-system, "Segoe UI", Helvetica, Arial, sans-serif; margin: 24px; color: #24292f; }
+h1 { font-size: 22px; }
+summary { cursor: pointer; padding: 8px; font-weight: 600; }
+details { border: 1px solid #d0d7de; border-radius: 6px; margin-bottom: 12px; }
+details > div { padding: 0 12px 12px; }
+table { border-collapse: collapse; width: 100%; margin: 8px 0; font-size: 13px; }
+th, td { border: 1px solid #d0d7de; padding: 4px 8px; text-align: left; vertical-align: top; }
+pre { margin: 0; white-space: pre-wrap; word-break: break-all; font-size: 12px; }
+.passed { color: #1a7f37; }
+.failed { color: #cf222e; }
+.changed { background: #fff8c5; }
+.removed { background: #ffebe9; }
+.added { background: #dafbe1; }
+.noisy { color: #6e7781; font-style: italic; }
+.diff td { font-family: ui-monospace, Menlo, monospace; width: 50%; }
+</style>
+</head>
+<body>
+<h1>{{.Report.TestSet}} <span class="{{if eq .Report.Status "PASSED"}}passed{{else}}failed{{end}}">{{.Report.Status}}</span></h1>
+<p>{{.Report.Name}}: {{.Report.Total}} tests, {{.Report.Success}} passed, {{.Report.Failure}} failed</p>
+{{if .Operations}}
+<h3>GraphQL operations</h3>
+<table>
+<tr><th>Operation</th><th>Passed</th><th>Failed</th></tr>
+{{range .Operations}}<tr class="{{if .Failed}}changed{{end}}"><td>{{.Operation}}</td><td>{{.Passed}}</td><td>{{.Failed}}</td></tr>
+{{end}}
+</table>
+{{end}}
+{{range .Tests}}
+<details{{if not .Passed}} open{{end}}>
+<summary><span class="{{if .Passed}}passed{{else}}failed{{end}}">{{.Result.Status}}</span> {{.Result.TestCaseID}}: {{if .Result.GraphQL}}{{.Result.GraphQL}} ({{.Result.Req.Method}} {{.Result.Req.URL}}){{else}}{{.Result.Req.Method}} {{.Result.Req.URL}}{{end}}</summary>
+<div>
+<h3>Status code</h3>
+<table>
+<tr><th>Expected</th><th>Actual</th></tr>
+<tr class="{{if not .StatusCode.Normal}}changed{{end}}"><td>{{.StatusCode.Expected}}</td><td>{{.StatusCode.Actual}}</td></tr>
+</table>
+{{if .Headers}}
+<h3>Headers</h3>
+<table>
+<tr><th>Header</th><th>Expected</th><th>Actual</th></tr>
+{{range .Headers}}<tr class="{{if not .Normal}}changed{{end}}"><td>{{.Expected.Key}}</td><td>{{range .Expected.Value}}{{.}} {{end}}</td><td>{{range .Actual.Value}}{{.}} {{end}}</td></tr>
+{{end}}
+</table>
+{{end}}
+{{if .Body}}
+<h3>Body</h3>
+<table class="diff">
+<tr><th>Expected</th><th>Actual</th></tr>
+{{range .Body}}<tr class="{{if ne .Kind "same"}}{{.Kind}}{{end}}"><td><pre>{{.Expected}}</pre></td><td><pre>{{.Actual}}</pre></td></tr>
+{{end}}
+</table>
+{{end}}
+{{if .Fields}}
+<h3>Fields</h3>
+<table>
+<tr><th>Field</th><th>Expected</th><th>Actual</th></tr>
+{{range .Fields}}<tr class="{{if .Noisy}}noisy{{else if not .Equal}}changed{{end}}"><td>{{.Path}}{{if .Noisy}} (noise){{end}}</td><td><pre>{{.Expected}}</pre></td><td><pre>{{.Actual}}</pre></td></tr>
+{{end}}
+</table>
+{{end}}
+{{if .Noise}}
+<p class="noisy">Noise: {{range $i, $key := .Noise}}{{if $i}}, {{end}}{{$key}}{{end}}</p>
+{{end}}
+</div>
+</details>
+{{end}}
+</body>
+</html>
+`))
@@ -0,0 +1,165 @@
+package test
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// pathSegment is a key of an object, or an index of an array when index isn't -1, on the path of a JSON value.
+type pathSegment struct {
+	key   string
+	index int
+}
+
+type jsonPathTokenKind int
+
+const (
+	jsonPathChild jsonPathTokenKind = iota
+	jsonPathIndex
+	jsonPathWildcard
+	jsonPathDescendant
+)
+
+type jsonPathToken struct {
+	kind  jsonPathTokenKind
+	name  string
+	index int
+}
+
+// jsonPath is a compiled JSONPath expression. The supported subset is the root ($), the children (.name and
+// ['name']), the indexes ([0]), the wildcards (.* and [*]) and the recursive descent (..name).
+type jsonPath []jsonPathToken
+
+// isJsonPath reports whether the key of a noise map is a JSONPath expression rather than a dot-notation key.
+func isJsonPath(key string) bool {
+	return strings.HasPrefix(key, "$")
+}
+
+func parseJsonPath(expr string) (jsonPath, error) {
+	if !isJsonPath(expr) {
+		return nil, fmt.Errorf("the JSONPath %q doesn't start with $", expr)
+	}
+	var path jsonPath
+	rest := expr[1:]
+	for len(rest) > 0 {
+		switch {
+		case strings.HasPrefix(rest, ".."):
+			path = append(path, jsonPathToken{kind: jsonPathDescendant})
+			rest = rest[2:]
+			// the name following the descent is read as a child
+			if len(rest) > 0 && rest[0] != '[' {
+				rest = "." + rest
+			}
+		case rest[0] == '.':
+			end := strings.IndexAny(rest[1:], ".[")
+			if end < 0 {
+				end = len(rest) - 1
+			}
+			name := rest[1 : end+1]
+			rest = rest[end+1:]
+			switch name {
+			case "":
+				return nil, fmt.Errorf("the JSONPath %q has an empty name", expr)
+			case "*":
+				path = append(path, jsonPathToken{kind: jsonPathWildcard})
+			default:
+				path = append(path, jsonPathToken{kind: jsonPathChild, name: name})
+			}
+		case rest[0] == '[':
+			end := strings.IndexByte(rest, ']')
+			if end < 0 {
+				return nil, fmt.Errorf("the JSONPath %q has an unterminated [", expr)
+			}
+			selector := strings.TrimSpace(rest[1:end])
+			rest = rest[end+1:]
+			if selector == "*" {
+				path = append(path, jsonPathToken{kind: jsonPathWildcard})
+			} else if len(selector) >= 2 && (selector[0] == '\'' || selector[0] == '"') && selector[len(selector)-1] == selector[0] {
+				path = append(path, jsonPathToken{kind: jsonPathChild, name: selector[1 : len(selector)-1]})
+			} else if index, err := strconv.Atoi(selector); err == nil {
+				path = append(path, jsonPathToken{kind: jsonPathIndex, index: index})
+			} else {
+				return nil, fmt.Errorf("the JSONPath %q has an unsupported selector [%s]", expr, selector)
+			}
+		default:
+			return nil, fmt.Errorf("the JSONPath %q is invalid at %q", expr, rest)
+		}
+	}
+	return path, nil
+}
+
+// matches reports whether the JSONPath selects the value at the path, or one of its parents.
+func (p jsonPath) matches(path []pathSegment) bool {
+	for i := len(path); i > 0; i-- {
+		if p.matchesExactly(path[:i]) {
+			return true
+		}
+	}
+	return false
+}
+
+func (p jsonPath) matchesExactly(path []pathSegment) bool {
+	if len(p) == 0 {
+		return len(path) == 0
+	}
+	token := p[0]
+	if token.kind == jsonPathDescendant {
+		for i := 0; i <= len(path); i++ {
+			if p[1:].matchesExactly(path[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(path) == 0 {
+		return false
+	}
+	segment := path[0]
+	switch token.kind {
+	case jsonPathChild:
+		if segment.index != -1 || segment.key != token.name {
+			return false
+		}
+	case jsonPathIndex:
+		if segment.index != token.index {
+			return false
+		}
+	}
+	return p[1:].matchesExactly(path[1:])
+}
+
+// jsonPathField is a noisy field selected through a JSONPath expression, along with the regexes of its values.
+type jsonPathField struct {
+	path    jsonPath
+	regexes []string
+}
+
+// jsonPathNoise holds the JSONPath expressions of a noise map.
+type jsonPathNoise []jsonPathField
+
+func newJsonPathNoise(noise map[string][]string) (jsonPathNoise, error) {
+	paths := jsonPathNoise{}
+	for key, regexes := range noise {
+		if !isJsonPath(key) {
+			continue
+		}
+		path, err := parseJsonPath(key)
+		if err != nil {
+			return nil, err
+		}
+		paths = append(paths, jsonPathField{path: path, regexes: regexes})
+	}
+	return paths, nil
+}
+
+// match returns the regexes of the values of the noisy field at the path, the field being noisy whatever its value
+// when there are none.
+func (n jsonPathNoise) match(path []pathSegment) ([]string, bool) {
+	for _, field := range n {
+		if field.path.matches(path) {
+			return field.regexes, true
+		}
+	}
+	return []string{}, false
+}
@@ -55,7 +55,11 @@ func Match(exp, act string, noise map[string][]string, log *zap.Logger) (string,
 	if reflect.TypeOf(expected) != reflect.TypeOf(actual) {
 		return exp, act, false, nil
 	}
-	match, err := jsonMatch("", expected, actual, noise)
+	paths, err := newJsonPathNoise(noise)
+	if err != nil {
+		return exp, act, false, err
+	}
+	match, err := jsonMatch("", nil, expected, actual, noise, paths)
 	if err != nil {
 		return exp, act, false, err
 	}
@@ -70,8 +74,9 @@ func Match(exp, act string, noise map[string][]string, log *zap.Logger) (string,
 	return string(cleanExp), string(cleanAct), match, nil
 }
 
-// jsonMatch returns true if expected and actual JSON objects matches(are equal).
-func jsonMatch(key string, expected, actual interface{}, noiseMap map[string][]string) (bool, error) {
+// jsonMatch returns true if expected and actual JSON objects matches(are equal). The noisy fields are the ones of
+// the noise map, by their dot-notation key, and the ones selected by its JSONPath expressions.
+func jsonMatch(key string, path []pathSegment, expected, actual interface{}, noiseMap map[string][]string, paths jsonPathNoise) (bool, error) {
 
 	if reflect.TypeOf(expected) != reflect.TypeOf(actual) {
 		return false, errors.New("type not matched ")
@@ -87,6 +92,9 @@ func jsonMatch(key string, expected, actual interface{}, noiseMap map[string][]s
 	switch x.Kind() {
 	case reflect.Float64, reflect.String, reflect.Bool:
 		regexArr, isNoisy := CheckStringExist(key, noiseMap)
+		if !isNoisy {
+			regexArr, isNoisy = paths.match(path)
+		}
 		if isNoisy && len(regexArr) != 0 {
 			isNoisy, _ = MatchesAnyRegex(InterfaceToString(expected), regexArr)
 		}
@@ -98,11 +106,18 @@ func jsonMatch(key string, expected, actual interface{}, noiseMap map[string][]s
 		expMap := expected.(map[string]interface{})
 		actMap := actual.(map[string]interface{})
 		for k, v := range expMap {
+			childPath := append(append([]pathSegment{}, path...), pathSegment{key: k, index: -1})
+			// the fields selected by a JSONPath without regexes are ignored whatever their type, or their presence
+			if regexArr, isNoisy := paths.match(childPath); isNoisy && len(regexArr) == 0 {
+				delete(expMap, k)
+				delete(actMap, k)
+				continue
+			}
 			val, ok := actMap[k]
 			if !ok {
 				return false, nil
 			}
-			if x, er := jsonMatch(prefix+k, v, val, noiseMap); !x || er != nil {
+			if x, er := jsonMatch(prefix+k, childPath, v, val, noiseMap, paths); !x || er != nil {
 				return false, nil
 			}
 			// remove the noisy key from both expected and actual JSON.
@@ -116,6 +131,11 @@ func jsonMatch(key string, expected, actual interface{}, noiseMap map[string][]s
 		for k := range actMap {
 			_, ok := expMap[k]
 			if !ok {
+				childPath := append(append([]pathSegment{}, path...), pathSegment{key: k, index: -1})
+				if regexArr, isNoisy := paths.match(childPath); isNoisy && len(regexArr) == 0 {
+					delete(actMap, k)
+					continue
+				}
 				return false, nil
 			}
 		}
@@ -124,6 +144,9 @@ func jsonMatch(key string, expected, actual interface{}, noiseMap map[string][]s
 		if regexArr, isNoisy := CheckStringExist(key, noiseMap); isNoisy && len(regexArr) != 0 {
 			break
 		}
+		if regexArr, isNoisy := paths.match(path); isNoisy && len(regexArr) != 0 {
+			break
+		}
 		expSlice := reflect.ValueOf(expected)
 		actSlice := reflect.ValueOf(actual)
 		if expSlice.Len() != actSlice.Len() {
@@ -131,7 +154,8 @@ func jsonMatch(key string, expected, actual interface{}, noiseMap map[string][]s
 		}
 		isMatched := true
 		for i := 0; i < expSlice.Len(); i++ {
-			if x, err := jsonMatch(key, expSlice.Index(i).Interface(), actSlice.Index(i).Interface(), noiseMap); err == nil && !x {
+			itemPath := append(append([]pathSegment{}, path...), pathSegment{index: i})
+			if x, err := jsonMatch(key, itemPath, expSlice.Index(i).Interface(), actSlice.Index(i).Interface(), noiseMap, paths); err == nil && !x {
 				isMatched = false
 				break
 			}
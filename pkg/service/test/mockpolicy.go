@@ -0,0 +1,75 @@
+package test
+
+import (
+	"regexp"
+	"strings"
+
+	"go.keploy.io/server/pkg/models"
+	"go.uber.org/zap"
+)
+
+// validMockMatching returns the mock matching config without its invalid rules, defaulting to the any-order policy.
+func (t *tester) validMockMatching(matching models.MockMatching) models.MockMatching {
+	valid := models.MockMatching{Default: matching.Default}
+	if !isMockPolicy(valid.Default) {
+		if valid.Default != "" {
+			t.logger.Warn("unknown default mock policy, the mocks are consumed in any order", zap.Any("policy", valid.Default))
+		}
+		valid.Default = models.MockPolicyAnyOrder
+	}
+	for _, rule := range matching.Rules {
+		if !isMockPolicy(rule.Policy) {
+			t.logger.Warn("skipping the mock policy rule with an unknown policy", zap.Any("rule", rule))
+			continue
+		}
+		if _, err := wholeMatchRegex(rule.TestSet); err != nil {
+			t.logger.Warn("skipping the mock policy rule with an invalid test set regex", zap.Any("rule", rule), zap.Error(err))
+			continue
+		}
+		if _, err := wholeMatchRegex(rule.Name); err != nil {
+			t.logger.Warn("skipping the mock policy rule with an invalid name regex", zap.Any("rule", rule), zap.Error(err))
+			continue
+		}
+		valid.Rules = append(valid.Rules, rule)
+	}
+	return valid
+}
+
+// applyMockPolicies sets the policies of the mocks of the test set, from the first rule of the mock matching config
+// selecting each of them.
+func (t *tester) applyMockPolicies(mocks []*models.Mock, testSet string) {
+	for _, mock := range mocks {
+		mock.Policy = t.mockMatching.Default
+		for _, rule := range t.mockMatching.Rules {
+			if rule.Kind != "" && !strings.EqualFold(string(rule.Kind), string(mock.Kind)) {
+				continue
+			}
+			if !matchesWhole(rule.TestSet, testSet) || !matchesWhole(rule.Name, mock.Name) {
+				continue
+			}
+			mock.Policy = rule.Policy
+			break
+		}
+	}
+}
+
+func wholeMatchRegex(pattern string) (*regexp.Regexp, error) {
+	return regexp.Compile("^(?:" + pattern + ")$")
+}
+
+// matchesWhole reports whether the regex matches the whole value, the empty regex matching any value.
+func matchesWhole(pattern, value string) bool {
+	if pattern == "" {
+		return true
+	}
+	re, err := wholeMatchRegex(pattern)
+	return err == nil && re.MatchString(value)
+}
+
+func isMockPolicy(policy models.MockPolicy) bool {
+	switch policy {
+	case models.MockPolicyAnyOrder, models.MockPolicyStrictSequence, models.MockPolicyConnectionSequence, models.MockPolicyRepeatable:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,220 @@
+package test
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.keploy.io/server/pkg/platform/yaml"
+	"go.keploy.io/server/pkg/proxy/util"
+	"go.keploy.io/server/pkg/service/coverage"
+	"go.uber.org/zap"
+)
+
+// defaultProxyPort is the port of the proxy when none is given, see proxy.BootProxy.
+const defaultProxyPort = 16789
+
+// workerStartInterval separates the starts of the workers, so that they don't join the network of the application
+// at the same time.
+const workerStartInterval = 2 * time.Second
+
+var (
+	containerNameFlag = regexp.MustCompile(`--name\s+([^\s]+)`)
+	publishFlag       = regexp.MustCompile(`\s(-p|--publish)(\s+|=)[^\s]+`)
+)
+
+// Partition is the Index-th (from 1) of Count shares of the test sets, the selected test sets being dealt in turn.
+// The zero value holds all of them.
+type Partition struct {
+	Index int
+	Count int
+}
+
+// ParsePartition parses a partition written like 2/4.
+func ParsePartition(s string) (Partition, error) {
+	index, count, ok := strings.Cut(s, "/")
+	if !ok {
+		return Partition{}, fmt.Errorf("invalid partition %q, expected i/n", s)
+	}
+	i, err := strconv.Atoi(strings.TrimSpace(index))
+	if err != nil {
+		return Partition{}, fmt.Errorf("invalid partition %q, expected i/n", s)
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(count))
+	if err != nil {
+		return Partition{}, fmt.Errorf("invalid partition %q, expected i/n", s)
+	}
+	if n < 1 || i < 1 || i > n {
+		return Partition{}, fmt.Errorf("invalid partition %q, expected 1 <= i <= n", s)
+	}
+	return Partition{Index: i, Count: n}, nil
+}
+
+// Includes reports whether the i-th (from 0) selected test set belongs to the partition.
+func (p Partition) Includes(i int) bool {
+	return p.Count <= 1 || i%p.Count == p.Index-1
+}
+
+func (p Partition) String() string {
+	return fmt.Sprintf("%d/%d", p.Index, p.Count)
+}
+
+func (t *tester) TestParallel(path string, testReportPath string, appCmd string, options TestOptions, parallel int) bool {
+	isDocker, kind := util.IsDockerRelatedCommand(appCmd)
+	// the eBPF hooks redirect the calls of every process of the host for the native applications, and the
+	// containers of docker compose have fixed names, so only the containers started with docker run are isolated
+	if !isDocker || kind != "docker" {
+		t.logger.Error("the test sets can only be run in parallel for the applications started with docker run", zap.String("command", appCmd))
+		return false
+	}
+	if !containerNameFlag.MatchString(appCmd) {
+		t.logger.Error("the docker run command needs a --name to run the test sets in parallel", zap.String("command", appCmd))
+		return false
+	}
+
+	sessions, err := yaml.ReadSessionIndices(path, t.logger)
+	if err != nil {
+		t.logger.Error("failed to read the recorded sessions", zap.Error(err))
+		return false
+	}
+	testSets := shardTestSets(sessions, options.Tests, options.Shard)
+	selected := len(testSets)
+	workers := parallel
+	if selected < workers {
+		workers = selected
+	}
+	previousReports := reportNames(testReportPath)
+	if workers == 0 {
+		t.logger.Info("no test set to run")
+		return t.finishShard(testReportPath, options.Shard, testSets, previousReports, true)
+	}
+
+	executable, err := os.Executable()
+	if err != nil {
+		t.logger.Error("failed to find the keploy executable", zap.Error(err))
+		return false
+	}
+	proxyPort := options.ProxyPort
+	if proxyPort == 0 {
+		proxyPort = defaultProxyPort
+	}
+
+	t.logger.Info("running the test sets in parallel", zap.Int("testSets", selected), zap.Int("workers", workers))
+	results := make([]bool, workers)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		worker := Partition{Index: i + 1, Count: workers}
+		workerCmd, container := workerAppCmd(appCmd, worker.Index)
+		// the flags given last override the ones of the command line and of the config file
+		args := append(append([]string{}, os.Args[1:]...),
+			"--worker", worker.String(),
+			"--command", workerCmd,
+			"--containerName", container,
+			"--proxyport", strconv.Itoa(int(proxyPort)+i),
+		)
+		if options.MetricsAddr != "" {
+			args = append(args, "--metrics-addr", workerMetricsAddr(options.MetricsAddr, i))
+		}
+		cmd := exec.Command(executable, args...)
+		prefix := fmt.Sprintf("[worker %d] ", worker.Index)
+		stdout, stderr := newPrefixWriter(os.Stdout, prefix), newPrefixWriter(os.Stderr, prefix)
+		cmd.Stdout, cmd.Stderr = stdout, stderr
+
+		if i > 0 {
+			time.Sleep(workerStartInterval)
+		}
+		if err := cmd.Start(); err != nil {
+			t.logger.Error("failed to start the worker", zap.Int("worker", worker.Index), zap.Error(err))
+			stdout.Close()
+			stderr.Close()
+			continue
+		}
+		t.logger.Debug("started the worker", zap.Int("worker", worker.Index), zap.String("container", container), zap.Uint32("proxyPort", proxyPort+uint32(i)))
+		wg.Add(1)
+		go func(i int, cmd *exec.Cmd, stdout, stderr *prefixWriter) {
+			defer wg.Done()
+			err := cmd.Wait()
+			stdout.Close()
+			stderr.Close()
+			if err != nil {
+				t.logger.Warn("the test sets of the worker failed", zap.Int("worker", i+1), zap.Error(err))
+			}
+			results[i] = err == nil
+		}(i, cmd, stdout, stderr)
+	}
+	wg.Wait()
+
+	result := true
+	for _, passed := range results {
+		result = result && passed
+	}
+	t.logger.Info("test run completed", zap.Bool("passed overall", result))
+	if options.WithCoverage && coverage.IsGo(options.Language) {
+		t.mergeCoverage(coverageDir(path, options.CoverageReportPath), testSets, options.UnitCoverage)
+	}
+	return t.finishShard(testReportPath, options.Shard, testSets, previousReports, result)
+}
+
+// workerMetricsAddr returns the address the metrics of a worker are served on, the port of the address of the run
+// followed by the ones of the next workers.
+func workerMetricsAddr(address string, worker int) string {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return address
+	}
+	number, err := strconv.Atoi(port)
+	if err != nil {
+		return address
+	}
+	return net.JoinHostPort(host, strconv.Itoa(number+worker))
+}
+
+// workerAppCmd returns the docker run command of a worker along with the name of its container, the name of the
+// container being suffixed with the worker. The published ports are removed since the application is called on the
+// IP of its container.
+func workerAppCmd(appCmd string, worker int) (string, string) {
+	container := containerNameFlag.FindStringSubmatch(appCmd)[1] + "-keploy-" + strconv.Itoa(worker)
+	workerCmd := containerNameFlag.ReplaceAllLiteralString(appCmd, "--name "+container)
+	workerCmd = publishFlag.ReplaceAllString(workerCmd, "")
+	return workerCmd, container
+}
+
+// prefixWriter writes the lines of a worker prefixed with its name, so that the outputs of the workers can be told
+// apart.
+type prefixWriter struct {
+	mutex sync.Mutex
+	pipe  *io.PipeWriter
+}
+
+func newPrefixWriter(w io.Writer, prefix string) *prefixWriter {
+	reader, writer := io.Pipe()
+	go func() {
+		scanner := bufio.NewScanner(reader)
+		scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+		for scanner.Scan() {
+			fmt.Fprintln(w, prefix+scanner.Text())
+		}
+		// drain the output of the worker if a line is too long
+		io.Copy(w, reader)
+	}()
+	return &prefixWriter{pipe: writer}
+}
+
+func (p *prefixWriter) Write(b []byte) (int, error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	return p.pipe.Write(b)
+}
+
+// Close flushes the last line of the worker.
+func (p *prefixWriter) Close() error {
+	return p.pipe.Close()
+}
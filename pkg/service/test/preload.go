@@ -0,0 +1,64 @@
+package test
+
+import (
+	"path/filepath"
+
+	"go.keploy.io/server/pkg/hooks/preload"
+	"go.keploy.io/server/pkg/platform/yaml"
+	"go.uber.org/zap"
+)
+
+// preloadLibraries returns the command running the application of the test set with the libraries of the time
+// freezing, of the deterministic randomness, of the capture of the unix sockets and of the capture of its connections
+// in the preload capture mode preloaded in it.
+func (t *tester) preloadLibraries(appCmd, path, testSet string) string {
+	if appCmd == "" {
+		return appCmd
+	}
+	var libs []preload.Library
+	if t.timeFreezer != nil && t.timeFreezer.lib != nil {
+		libs = append(libs, *t.timeFreezer.lib)
+	}
+	if t.deterministicRandom {
+		seed, ok, err := yaml.ReadSeed(filepath.Join(path, testSet))
+		switch {
+		case err != nil:
+			t.logger.Error("failed to read the seed of the test set", zap.String("testSet", testSet), zap.Error(err))
+		case !ok:
+			t.logger.Warn("the test set wasn't recorded with --deterministic-random, the application generates its own random bytes", zap.String("testSet", testSet))
+		default:
+			lib, err := preload.Random(seed)
+			if err != nil {
+				t.logger.Error("failed to set up the deterministic randomness of the application", zap.Error(err))
+				break
+			}
+			libs = append(libs, lib)
+		}
+	}
+	if len(t.unixSockets) > 0 {
+		var paths []string
+		for _, socket := range t.unixSockets {
+			paths = append(paths, socket.Path)
+		}
+		lib, err := preload.Unix(paths)
+		if err != nil {
+			t.logger.Error("failed to set up the capture of the unix sockets of the application", zap.Error(err))
+		} else {
+			libs = append(libs, lib)
+		}
+	}
+	if t.connectPort != 0 {
+		lib, err := preload.Connect(t.connectPort)
+		if err != nil {
+			t.logger.Error("failed to set up the capture of the connections of the application", zap.Error(err))
+		} else {
+			libs = append(libs, lib)
+		}
+	}
+	cmd, err := preload.Command(appCmd, libs)
+	if err != nil {
+		t.logger.Warn("the time of the application isn't frozen, nor its randomness made deterministic or its unix sockets and connections mocked", zap.Error(err))
+		return appCmd
+	}
+	return cmd
+}
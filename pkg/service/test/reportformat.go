@@ -0,0 +1,183 @@
+package test
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"go.keploy.io/server/pkg/models"
+	"go.uber.org/zap"
+)
+
+// The formats of the test reports. The yaml reports are always written, since keploy reads them back.
+const (
+	ReportFormatYaml  = "yaml"
+	ReportFormatJson  = "json"
+	ReportFormatJUnit = "junit"
+	// ReportFormatHtml writes the side by side comparison of the responses in keploy/reports
+	ReportFormatHtml = "html"
+)
+
+// validReportFormats returns the known report formats, warning about the others.
+func (t *tester) validReportFormats(formats []string) []string {
+	var valid []string
+	for _, format := range formats {
+		format = strings.ToLower(strings.TrimSpace(format))
+		switch format {
+		case ReportFormatYaml, ReportFormatJson, ReportFormatJUnit, ReportFormatHtml:
+			valid = append(valid, format)
+		case "":
+		default:
+			t.logger.Warn("ignoring the unknown report format, the formats are yaml, json, junit and html", zap.String("format", format))
+		}
+	}
+	return valid
+}
+
+// writeReportFormats writes the test report in the other formats requested, next to its yaml file (report-1.json and
+// report-1.junit.xml), the HTML one being written in keploy/reports.
+func (t *tester) writeReportFormats(testReportPath string, report *models.TestReport) {
+	for _, format := range t.reportFormats {
+		var data []byte
+		var file string
+		var err error
+		switch format {
+		case ReportFormatJson:
+			file = report.Name + ".json"
+			data, err = json.MarshalIndent(report, "", "  ")
+		case ReportFormatJUnit:
+			file = report.Name + ".junit.xml"
+			data, err = junitReport(report)
+		case ReportFormatHtml:
+			path, err := writeHtmlReport(testReportPath, report)
+			if err != nil {
+				t.logger.Error("failed to write the test report", zap.String("format", format), zap.String("report", report.Name), zap.Error(err))
+				continue
+			}
+			t.logger.Info("the comparison of the responses of the test set is written", zap.String("testSet", report.TestSet), zap.String("path", path))
+			continue
+		default:
+			continue
+		}
+		if err == nil {
+			err = os.WriteFile(filepath.Join(testReportPath, file), data, os.ModePerm)
+		}
+		if err != nil {
+			t.logger.Error("failed to write the test report", zap.String("format", format), zap.String("report", report.Name), zap.Error(err))
+			continue
+		}
+		t.logger.Debug("test report written", zap.String("format", format), zap.String("path", filepath.Join(testReportPath, file)))
+	}
+}
+
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Name     string           `xml:"name,attr"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Time     string           `xml:"time,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	Errors    int             `xml:"errors,attr"`
+	Time      string          `xml:"time,attr"`
+	Timestamp string          `xml:"timestamp,attr,omitempty"`
+	Cases     []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	Classname string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Details string `xml:",chardata"`
+}
+
+// junitReport returns the JUnit XML report of a test set, its testcases being the test cases of the test set. A
+// test set which didn't pass without a failing testcase, like when the application halted, is reported as an error.
+func junitReport(report *models.TestReport) ([]byte, error) {
+	suite := junitTestSuite{
+		Name:  report.TestSet,
+		Tests: len(report.Tests),
+	}
+	var started, completed int64
+	for _, result := range report.Tests {
+		testCase := junitTestCase{
+			Name:      result.TestCaseID,
+			Classname: report.TestSet,
+			Time:      fmt.Sprintf("%d", result.Completed-result.Started),
+		}
+		if result.Status != models.TestStatusPassed {
+			suite.Failures++
+			testCase.Failure = &junitFailure{
+				Message: fmt.Sprintf("the response of %s %s doesn't match the recorded one", result.Req.Method, result.Req.URL),
+				Type:    string(result.Status),
+				Details: failureDetails(result.Result),
+			}
+		}
+		if started == 0 || result.Started < started {
+			started = result.Started
+		}
+		if result.Completed > completed {
+			completed = result.Completed
+		}
+		suite.Cases = append(suite.Cases, testCase)
+	}
+	if suite.Failures == 0 && report.Status != string(models.TestRunStatusPassed) {
+		suite.Errors = 1
+	}
+	suite.Time = fmt.Sprintf("%d", completed-started)
+	if started != 0 {
+		suite.Timestamp = time.Unix(started, 0).UTC().Format("2006-01-02T15:04:05")
+	}
+	suites := junitTestSuites{
+		Name:     "keploy",
+		Tests:    suite.Tests,
+		Failures: suite.Failures,
+		Time:     suite.Time,
+		Suites:   []junitTestSuite{suite},
+	}
+	data, err := xml.MarshalIndent(suites, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), append(data, '\n')...), nil
+}
+
+// failureDetails lists the parts of the response which don't match the recorded one.
+func failureDetails(result models.Result) string {
+	var details []string
+	if !result.StatusCode.Normal {
+		details = append(details, fmt.Sprintf("status code: expected %d, actual %d", result.StatusCode.Expected, result.StatusCode.Actual))
+	}
+	for _, header := range result.HeadersResult {
+		if !header.Normal {
+			details = append(details, fmt.Sprintf("header %s: expected %q, actual %q", header.Expected.Key, strings.Join(header.Expected.Value, ", "), strings.Join(header.Actual.Value, ", ")))
+		}
+	}
+	for _, body := range result.BodyResult {
+		if body.Normal {
+			continue
+		}
+		for _, schemaErr := range body.SchemaErrors {
+			details = append(details, fmt.Sprintf("%s: expected %s, actual %s", schemaErr.Path, schemaErr.Expected, schemaErr.Actual))
+		}
+		if len(body.SchemaErrors) == 0 {
+			details = append(details, fmt.Sprintf("body: expected %s\nactual %s", body.Expected, body.Actual))
+		}
+	}
+	return strings.Join(details, "\n")
+}
@@ -0,0 +1,299 @@
+package test
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/fatih/color"
+	"go.keploy.io/server/pkg/models"
+	"go.keploy.io/server/pkg/platform/yaml"
+	"go.uber.org/zap"
+)
+
+// errQuitReview ends the review from the view of a test.
+var errQuitReview = errors.New("quit the review")
+
+// failedTest is a failed test of the last report of its test set.
+type failedTest struct {
+	testSet string
+	report  string
+	result  models.TestResult
+}
+
+// testcaseDir is the directory of the yaml file of the testcase of the failed test.
+func (f failedTest) testcaseDir() string {
+	return filepath.Join(f.result.TestCasePath, "tests")
+}
+
+func (t *tester) Review(testReportPath string, testSets []string) error {
+	failed, err := t.lastFailures(testReportPath, testSets)
+	if err != nil {
+		t.logger.Error("failed to read the test reports", zap.String("path", testReportPath), zap.Error(err))
+		return err
+	}
+	if len(failed) == 0 {
+		t.logger.Info("no test failed in the last runs of the test sets", zap.String("path", testReportPath))
+		return nil
+	}
+
+	in := bufio.NewScanner(os.Stdin)
+	for {
+		printFailures(failed)
+		line, ok := prompt(in, "Choose a test by its number, or q to quit: ")
+		if !ok || line == "q" {
+			return nil
+		}
+		i, err := strconv.Atoi(line)
+		if err != nil || i < 1 || i > len(failed) {
+			fmt.Printf("%q isn't the number of a failed test\n", line)
+			continue
+		}
+		resolved, err := t.reviewTest(in, failed[i-1])
+		if err == errQuitReview {
+			return nil
+		}
+		if resolved {
+			failed = append(failed[:i-1], failed[i:]...)
+		}
+		if len(failed) == 0 {
+			fmt.Println("All the failed tests are reviewed, run keploy test again to check them.")
+			return nil
+		}
+	}
+}
+
+// lastFailures returns the failed tests of the last report of each test set, all of them when testSets is empty.
+func (t *tester) lastFailures(testReportPath string, testSets []string) ([]failedTest, error) {
+	entries, err := os.ReadDir(testReportPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	selected := map[string]bool{}
+	for _, testSet := range testSets {
+		selected[strings.TrimSpace(testSet)] = true
+	}
+
+	reportFS := yaml.NewTestReportFS(t.logger)
+	lastReports := map[string]*models.TestReport{}
+	lastIndexes := map[string]int{}
+	for _, entry := range entries {
+		name := strings.TrimSuffix(entry.Name(), ".yaml")
+		index, err := strconv.Atoi(strings.TrimPrefix(name, "report-"))
+		if entry.IsDir() || name == entry.Name() || err != nil {
+			continue
+		}
+		doc, err := reportFS.Read(context.Background(), testReportPath, name)
+		if err != nil {
+			t.logger.Warn("failed to read the test report", zap.String("report", name), zap.Error(err))
+			continue
+		}
+		report, ok := doc.(*models.TestReport)
+		if !ok || (len(selected) > 0 && !selected[report.TestSet]) {
+			continue
+		}
+		if last, ok := lastIndexes[report.TestSet]; !ok || index > last {
+			lastIndexes[report.TestSet] = index
+			report.Name = name
+			lastReports[report.TestSet] = report
+		}
+	}
+
+	var failed []failedTest
+	for testSet, report := range lastReports {
+		for _, result := range report.Tests {
+			if result.Status == models.TestStatusFailed {
+				failed = append(failed, failedTest{testSet: testSet, report: report.Name, result: result})
+			}
+		}
+	}
+	sort.Slice(failed, func(i, j int) bool {
+		if failed[i].testSet != failed[j].testSet {
+			return failed[i].testSet < failed[j].testSet
+		}
+		return failed[i].result.TestCaseID < failed[j].result.TestCaseID
+	})
+	return failed, nil
+}
+
+func printFailures(failed []failedTest) {
+	fmt.Println()
+	color.New(color.Bold).Println("Failed tests of the last runs")
+	for i, f := range failed {
+		status := f.result.Result.StatusCode
+		fmt.Printf("%3d) %s/%s  %s %s  %s\n", i+1, f.testSet, f.result.TestCaseID, f.result.Req.Method, f.result.Req.URL,
+			color.HiRedString("status %d -> %d", status.Expected, status.Actual))
+	}
+}
+
+// prompt reads the next line of the input, false once the input is closed.
+func prompt(in *bufio.Scanner, question string) (string, bool) {
+	fmt.Print(question)
+	if !in.Scan() {
+		fmt.Println()
+		return "", false
+	}
+	return strings.TrimSpace(in.Text()), true
+}
+
+// reviewTest shows the differences of the failed test and applies the actions chosen for it, returning whether its
+// testcase was changed or deleted.
+func (t *tester) reviewTest(in *bufio.Scanner, f failedTest) (bool, error) {
+	fields := mismatchedFields(f.result)
+	printTestDiffs(f, fields)
+	for {
+		line, ok := prompt(in, "n <fields> mark as noise, u update the expected response, d delete the test, b back, q quit: ")
+		if !ok {
+			return false, errQuitReview
+		}
+		action, args, _ := strings.Cut(line, " ")
+		switch action {
+		case "n":
+			noise, err := selectFields(args, fields)
+			if err != nil {
+				fmt.Println(err)
+				continue
+			}
+			if err := yaml.AddNoise(f.testcaseDir(), f.result.TestCaseID, noise); err != nil {
+				t.logger.Error("failed to add the fields to the noise of the testcase", zap.String("testcase", f.result.TestCaseID), zap.Error(err))
+				continue
+			}
+			t.logger.Info("added the fields to the noise of the testcase", zap.String("test-set", f.testSet), zap.String("testcase", f.result.TestCaseID), zap.Strings("fields", noise))
+			return true, nil
+		case "u":
+			if !t.updateResponse(f) {
+				continue
+			}
+			return true, nil
+		case "d":
+			answer, ok := prompt(in, fmt.Sprintf("Delete the testcase %s of %s? [y/N]: ", f.result.TestCaseID, f.testSet))
+			if !ok || !strings.EqualFold(answer, "y") {
+				continue
+			}
+			path := filepath.Join(f.testcaseDir(), f.result.TestCaseID+".yaml")
+			if err := os.Remove(path); err != nil {
+				t.logger.Error("failed to delete the testcase", zap.String("path", path), zap.Error(err))
+				continue
+			}
+			t.logger.Info("deleted the testcase", zap.String("test-set", f.testSet), zap.String("testcase", f.result.TestCaseID))
+			return true, nil
+		case "b":
+			return false, nil
+		case "q":
+			return false, errQuitReview
+		default:
+			fmt.Printf("unknown action %q\n", action)
+		}
+	}
+}
+
+// mismatchedFields returns the headers, like header.Date, and the fields of the body, like body.data.id, which don't
+// match in the failed test. The body is a single field when it isn't JSON.
+func mismatchedFields(result models.TestResult) []fieldDiff {
+	var fields []fieldDiff
+	for _, header := range result.Result.HeadersResult {
+		if header.Normal {
+			continue
+		}
+		fields = append(fields, fieldDiff{
+			Path:     "header." + header.Expected.Key,
+			Expected: strings.Join(header.Expected.Value, ","),
+			Actual:   strings.Join(header.Actual.Value, ","),
+		})
+	}
+	for _, body := range result.Result.BodyResult {
+		if body.Normal {
+			continue
+		}
+		if body.Type != models.BodyTypeJSON {
+			fields = append(fields, fieldDiff{Path: "body", Expected: body.Expected, Actual: body.Actual})
+			continue
+		}
+		for _, field := range diffFields(body.Expected, body.Actual, result.Noise) {
+			if !field.Equal && !field.Noisy {
+				fields = append(fields, field)
+			}
+		}
+	}
+	return fields
+}
+
+func printTestDiffs(f failedTest, fields []fieldDiff) {
+	diffs := NewDiffsPrinter(f.result.TestCaseID)
+	status := f.result.Result.StatusCode
+	if !status.Normal {
+		diffs.PushStatusDiff(fmt.Sprint(status.Expected), fmt.Sprint(status.Actual))
+	}
+	for _, header := range f.result.Result.HeadersResult {
+		if !header.Normal {
+			diffs.PushHeaderDiff(fmt.Sprint(header.Expected.Value), fmt.Sprint(header.Actual.Value), header.Expected.Key, f.result.Noise)
+		}
+	}
+	for _, body := range f.result.Result.BodyResult {
+		if !body.Normal {
+			diffs.PushBodyDiff(body.Expected, body.Actual, f.result.Noise)
+		}
+	}
+	fmt.Printf("\n%s/%s  %s %s  (%s)\n", f.testSet, f.result.TestCaseID, f.result.Req.Method, f.result.Req.URL, f.report)
+	diffs.Render()
+	if len(fields) == 0 {
+		return
+	}
+	color.New(color.Bold).Println("Mismatched fields")
+	for i, field := range fields {
+		fmt.Printf("%3d) %s  %s -> %s\n", i+1, field.Path, color.HiRedString("%q", field.Expected), color.HiGreenString("%q", field.Actual))
+	}
+}
+
+// selectFields returns the fields chosen by their numbers or paths, separated by spaces or commas.
+func selectFields(args string, fields []fieldDiff) ([]string, error) {
+	var selected []string
+	for _, arg := range strings.FieldsFunc(args, func(r rune) bool { return r == ' ' || r == ',' }) {
+		if i, err := strconv.Atoi(arg); err == nil {
+			if i < 1 || i > len(fields) {
+				return nil, fmt.Errorf("%d isn't the number of a mismatched field", i)
+			}
+			selected = append(selected, fields[i-1].Path)
+			continue
+		}
+		if arg != "body" && !strings.HasPrefix(arg, "body.") && !strings.HasPrefix(arg, "header.") {
+			return nil, fmt.Errorf("%q isn't a field, like body.data.id or header.Date", arg)
+		}
+		selected = append(selected, arg)
+	}
+	if len(selected) == 0 {
+		return nil, errors.New("choose the fields to mark as noise, like n 1,2 or n body.data.id")
+	}
+	return selected, nil
+}
+
+// applyActual replaces the status code, the mismatched headers and the body of the expected response with the ones of
+// the actual response of the test.
+func applyActual(resp *models.HttpResp, result models.Result) {
+	resp.StatusCode = result.StatusCode.Actual
+	for _, header := range result.HeadersResult {
+		if header.Normal {
+			continue
+		}
+		if resp.Header == nil {
+			resp.Header = map[string]string{}
+		}
+		if len(header.Actual.Value) == 0 {
+			delete(resp.Header, header.Expected.Key)
+			continue
+		}
+		resp.Header[header.Actual.Key] = strings.Join(header.Actual.Value, ",")
+	}
+	for _, body := range result.BodyResult {
+		resp.Body = body.Actual
+	}
+}
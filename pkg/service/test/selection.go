@@ -0,0 +1,130 @@
+package test
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"go.keploy.io/server/pkg/models"
+)
+
+// Selection selects the testcases run by their names and their tags, all of them when it is empty. A testcase is
+// selected when its name matches one of the patterns, if any, and it has one of the tags, if any.
+type Selection struct {
+	patterns []testPattern
+	tags     map[string]bool
+	// only restricts the selected testcases to these ones, named like test-set-0/test-3
+	only map[string]bool
+}
+
+// testPattern matches the names of the testcases, and those of their test set when it is qualified like
+// test-set-0/test-3.
+type testPattern struct {
+	testSet *regexp.Regexp
+	test    *regexp.Regexp
+}
+
+// ParseSelection returns the selection of the testcases whose names match one of the patterns, like test-1 or
+// test-1[0-9], the ones of a single test set being written like test-set-0/test-3, and which have one of the tags.
+func ParseSelection(patterns, tags []string) (Selection, error) {
+	var selection Selection
+	for _, pattern := range patterns {
+		pattern = strings.TrimSpace(pattern)
+		if pattern == "" {
+			continue
+		}
+		var p testPattern
+		testSet, test, qualified := strings.Cut(pattern, "/")
+		if qualified {
+			re, err := fullMatch(testSet)
+			if err != nil {
+				return Selection{}, err
+			}
+			p.testSet = re
+			pattern = test
+		}
+		re, err := fullMatch(pattern)
+		if err != nil {
+			return Selection{}, err
+		}
+		p.test = re
+		selection.patterns = append(selection.patterns, p)
+	}
+	for _, tag := range tags {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			if selection.tags == nil {
+				selection.tags = map[string]bool{}
+			}
+			selection.tags[tag] = true
+		}
+	}
+	return selection, nil
+}
+
+// Only restricts the selection to the testcases named like test-set-0/test-3, like the failing ones of a previous run.
+func (s Selection) Only(tests []string) Selection {
+	if len(tests) == 0 {
+		return s
+	}
+	s.only = map[string]bool{}
+	for _, test := range tests {
+		s.only[strings.TrimSpace(test)] = true
+	}
+	return s
+}
+
+// fullMatch compiles the pattern into the regex matching the whole names.
+func fullMatch(pattern string) (*regexp.Regexp, error) {
+	re, err := regexp.Compile("^(?:" + pattern + ")$")
+	if err != nil {
+		return nil, fmt.Errorf("invalid test pattern %q: %v", pattern, err)
+	}
+	return re, nil
+}
+
+// Empty reports whether the selection selects all the testcases.
+func (s Selection) Empty() bool {
+	return len(s.patterns) == 0 && len(s.tags) == 0 && len(s.only) == 0
+}
+
+// Includes reports whether the testcase of the test set is selected.
+func (s Selection) Includes(testSet string, tc *models.TestCase) bool {
+	if s.only != nil && !s.only[testSet+"/"+tc.Name] {
+		return false
+	}
+	if len(s.patterns) > 0 {
+		matched := false
+		for _, p := range s.patterns {
+			if (p.testSet == nil || p.testSet.MatchString(testSet)) && p.test.MatchString(tc.Name) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	if len(s.tags) > 0 {
+		for _, tag := range tc.Tags {
+			if s.tags[tag] {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+// Filter returns the testcases of the test set which are selected.
+func (s Selection) Filter(testSet string, tcs []*models.TestCase) []*models.TestCase {
+	if s.Empty() {
+		return tcs
+	}
+	selected := []*models.TestCase{}
+	for _, tc := range tcs {
+		if s.Includes(testSet, tc) {
+			selected = append(selected, tc)
+		}
+	}
+	return selected
+}
@@ -11,9 +11,19 @@ import (
 
 type Tester interface {
 	Test(path string, testReportPath string, appCmd string, options TestOptions, enableTele bool) bool
+	// TestParallel runs the test sets in parallel keploy processes, each one with its own proxy and application container.
+	TestParallel(path string, testReportPath string, appCmd string, options TestOptions, parallel int) bool
+	// TestWatch runs the tests again every time the application changes, until it is interrupted.
+	TestWatch(path string, testReportPath string, appCmd string, options TestOptions, watch WatchOptions) bool
 	RunTestSet(testSet, path, testReportPath, appCmd, appContainer, appNetwork string, delay uint64, buildDelay time.Duration, pid uint32, ys platform.TestCaseDB, loadedHook *hooks.Hook, testReportfs platform.TestReportDB, testRunChan chan string, apiTimeout uint64, ctx context.Context, testcases map[string]bool, noiseConfig models.GlobalNoise, serveTest bool) models.TestRunStatus
 	InitialiseTest(cfg *TestConfig) (InitialiseTestReturn, error)
 	InitialiseRunTestSet(cfg *RunTestSetConfig) InitialiseRunTestSetReturn
 	SimulateRequest(cfg *SimulateRequestConfig)
 	FetchTestResults(cfg *FetchTestResultsConfig) models.TestRunStatus
+	// Review lists the failed tests of the last report of the test sets, all of them when testSets is empty, and lets
+	// the user go through their differences to mark fields as noise, update the expected responses or delete the tests.
+	Review(testReportPath string, testSets []string) error
+	// UpdateExpected replaces the expected responses of the failed tests of the last report of the test sets with
+	// their actual responses, each one being confirmed unless yes is set, and returns the number of updated testcases.
+	UpdateExpected(testReportPath string, testSets []string, yes bool) (int, error)
 }
@@ -0,0 +1,125 @@
+package test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"go.keploy.io/server/pkg/models"
+	"go.keploy.io/server/pkg/platform/yaml"
+	"go.uber.org/zap"
+	yamlLib "gopkg.in/yaml.v3"
+)
+
+// ShardReportName returns the name of the report of a shard in the test reports directory, like shard-2-of-4.
+func ShardReportName(shard Partition) string {
+	return fmt.Sprintf("shard-%d-of-%d", shard.Index, shard.Count)
+}
+
+// finishShard writes the report of the shard of a sharded run, and returns whether the run passed.
+func (t *tester) finishShard(testReportPath string, shard Partition, testSets []string, previousReports map[string]bool, result bool) bool {
+	if shard.Count == 0 {
+		return result
+	}
+	if err := t.writeShardReport(testReportPath, shard, testSets, previousReports); err != nil {
+		t.logger.Error("failed to write the report of the shard", zap.Error(err))
+		return false
+	}
+	return result
+}
+
+// shardTestSets returns the selected test sets of the shard, in their order.
+func shardTestSets(sessions []string, tests map[string][]string, shard Partition) []string {
+	var testSets []string
+	selected := 0
+	for _, sessionIndex := range sessions {
+		if _, ok := tests[sessionIndex]; !ok && len(tests) != 0 {
+			continue
+		}
+		selected++
+		if shard.Includes(selected - 1) {
+			testSets = append(testSets, sessionIndex)
+		}
+	}
+	return testSets
+}
+
+// reportNames returns the names of the test reports of the directory.
+func reportNames(dir string) map[string]bool {
+	names := map[string]bool{}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return names
+	}
+	for _, entry := range entries {
+		if name := strings.TrimSuffix(entry.Name(), ".yaml"); !entry.IsDir() && name != entry.Name() {
+			names[name] = true
+		}
+	}
+	return names
+}
+
+// writeShardReport writes the report of the shard, which gathers the test reports of its test sets written since the
+// previous reports were listed.
+func (t *tester) writeShardReport(testReportPath string, shard Partition, testSets []string, previous map[string]bool) error {
+	inShard := map[string]bool{}
+	for _, testSet := range testSets {
+		inShard[testSet] = true
+	}
+	reportFS := yaml.NewTestReportFS(t.logger)
+	var reports []models.TestReport
+	for name := range reportNames(testReportPath) {
+		if previous[name] || !strings.HasPrefix(name, "report-") {
+			continue
+		}
+		doc, err := reportFS.Read(context.Background(), testReportPath, name)
+		if err != nil {
+			t.logger.Warn("failed to read the test report", zap.String("report", name), zap.Error(err))
+			continue
+		}
+		report, ok := doc.(*models.TestReport)
+		if !ok || !inShard[report.TestSet] {
+			continue
+		}
+		if report.Name == "" {
+			report.Name = name
+		}
+		reports = append(reports, *report)
+	}
+	// the test sets are reported in their order, whichever worker ran them
+	order := map[string]int{}
+	for i, testSet := range testSets {
+		order[testSet] = i
+	}
+	sort.SliceStable(reports, func(i, j int) bool { return order[reports[i].TestSet] < order[reports[j].TestSet] })
+
+	runReport := &models.RunReport{
+		Version: models.GetVersion(),
+		Shards:  []string{shard.String()},
+		Status:  string(models.TestRunStatusPassed),
+	}
+	for _, report := range reports {
+		runReport.Add(report)
+	}
+	// the test sets which didn't write a report, like the ones left once the application halted, fail the shard
+	if len(reports) < len(testSets) {
+		runReport.Status = string(models.TestRunStatusFailed)
+	}
+
+	data, err := yamlLib.Marshal(runReport)
+	if err != nil {
+		return fmt.Errorf("failed to marshal the report of the shard: %v", err)
+	}
+	if err := os.MkdirAll(testReportPath, os.ModePerm); err != nil {
+		return fmt.Errorf("failed to create the test reports directory: %v", err)
+	}
+	path := filepath.Join(testReportPath, ShardReportName(shard)+".yaml")
+	if err := os.WriteFile(path, data, os.ModePerm); err != nil {
+		return fmt.Errorf("failed to write the report of the shard: %v", err)
+	}
+	t.logger.Info("report of the shard written, merge it with the ones of the other shards through keploy report merge", zap.String("shard", shard.String()), zap.String("path", path), zap.Strings("testSets", testSets))
+	return nil
+}
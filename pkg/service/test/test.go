@@ -6,7 +6,6 @@ import (
 	"errors"
 	"fmt"
 	"os"
-	"os/exec"
 	"os/signal"
 	"path/filepath"
 	"strings"
@@ -20,23 +19,51 @@ import (
 	"github.com/wI2L/jsondiff"
 	"go.keploy.io/server/pkg"
 	"go.keploy.io/server/pkg/hooks"
+	"go.keploy.io/server/pkg/logging"
+	"go.keploy.io/server/pkg/metrics"
 	"go.keploy.io/server/pkg/models"
 	"go.keploy.io/server/pkg/platform"
 	"go.keploy.io/server/pkg/platform/fs"
 	"go.keploy.io/server/pkg/platform/telemetry"
 	"go.keploy.io/server/pkg/platform/yaml"
 	"go.keploy.io/server/pkg/proxy"
+	"go.keploy.io/server/pkg/service/coverage"
+	"go.keploy.io/server/pkg/shutdown"
 	"go.uber.org/zap"
 )
 
 var Emoji = "\U0001F430" + " Keploy:"
 
 type tester struct {
+	// logger logs the replay as the replayer subsystem, and base is the logger of the hooks and the proxy, which log as
+	// subsystems of their own
 	logger *zap.Logger
+	base   *zap.Logger
 	mutex  sync.Mutex
+	// mockMatching sets how the mocks of the test sets are consumed
+	mockMatching models.MockMatching
+	// chaos sets the mocks replayed as failures
+	chaos models.Chaos
+	// reportFormats are the formats the test reports are written in besides yaml
+	reportFormats []string
+	coverage      coverage.Coverage
+	// selection selects the testcases run by their names and tags
+	selection Selection
+	// flakeDetection runs each testcase several times to tell the flaky ones
+	flakeDetection FlakeDetection
+	// timeFreezer makes the application observe the recorded time of the testcases
+	timeFreezer *timeFreezer
+	// deterministicRandom feeds the application the random bytes generated from the seed of the recording
+	deterministicRandom bool
+	// unixSockets are the unix sockets of the dependencies of the application mocked by the proxy
+	unixSockets []models.UnixSocket
+	// connectPort is the port of the proxy which the preloaded connect() sends the connections of the application to,
+	// in the preload capture mode
+	connectPort uint32
 }
 type TestOptions struct {
 	MongoPassword      string
+	PostgresPassword   string
 	Delay              uint64
 	BuildDelay         time.Duration
 	PassThroughPorts   []uint
@@ -49,12 +76,52 @@ type TestOptions struct {
 	TestsetNoise       models.TestsetNoise
 	WithCoverage       bool
 	CoverageReportPath string
+	ProtoDescriptors   []string
+	ProtobufRoutes     []models.ProtobufRoute
+	HttpConfig         models.HttpConfig
+	SqlConfig          models.SqlConfig
+	Matchers           []models.MatcherRule
+	MockMatching       models.MockMatching
+	PassThrough        []models.PassThroughRule
+	// Shard is the share of the test sets run by a shard of a sharded run, all of them by default
+	Shard Partition
+	// Worker is the share of the test sets of the shard run by a worker of a parallel run, all of them by default
+	Worker Partition
+	// ReportFormats are the formats of the test reports, among yaml, json, junit and html
+	ReportFormats []string
+	// UnitCoverage are the go coverage profiles of the unit tests merged with the coverage of the run
+	UnitCoverage []string
+	// Selection selects the testcases run by their names and tags, all of them by default
+	Selection Selection
+	// FlakeDetection runs each testcase several times to tell the flaky ones, off by default
+	FlakeDetection FlakeDetection
+	// TimeFreezing makes the application observe the recorded time of the testcases, off by default
+	TimeFreezing models.TimeFreezing
+	// DeterministicRandom feeds the application the random bytes generated from the seed of the recording of the test
+	// sets recorded with one, off by default
+	DeterministicRandom bool
+	// SimulateLatency delays the responses of the mocks by their recorded latency multiplied by it, off when 0
+	SimulateLatency float64
+	// Chaos replays the mocks selected by its faults as failures, off unless enabled
+	Chaos models.Chaos
+	// UnixSockets are the unix sockets of the dependencies of the application mocked like its TCP connections
+	UnixSockets []models.UnixSocket
+	// CaptureMode is the mode of the capture of the calls of the application, see hooks.SetCaptureMode
+	CaptureMode string
+	// Language of the application, selecting the collector of its coverage
+	Language string
+	// Pid of the running application which is tested instead of being started by keploy, like in a kubernetes pod
+	Pid uint32
+	// MetricsAddr is the address the metrics of the run are served on, none when it's empty
+	MetricsAddr string
 }
 
 func NewTester(logger *zap.Logger) Tester {
 	return &tester{
-		logger: logger,
-		mutex:  sync.Mutex{},
+		logger:   logger.Named(logging.Replayer),
+		base:     logger,
+		mutex:    sync.Mutex{},
+		coverage: coverage.NewCoverage(logger),
 	}
 }
 
@@ -120,17 +187,19 @@ func (t *tester) InitialiseTest(cfg *TestConfig) (InitialiseTestReturn, error) {
 	returnVal.YamlStore = yamlStore
 	routineId := pkg.GenerateRandomID()
 	// Initiate the hooks
-	returnVal.LoadedHooks, err = hooks.NewHook(returnVal.YamlStore, routineId, t.logger)
+	returnVal.LoadedHooks, err = hooks.NewHook(returnVal.YamlStore, routineId, t.base)
 	if err != nil {
 		return returnVal, fmt.Errorf("error while creating hooks %v", err)
 	}
+	returnVal.LoadedHooks.SetCaptureMode(cfg.CaptureMode)
+	returnVal.LoadedHooks.SetLatencyScale(cfg.SimulateLatency)
 
 	select {
 	case <-stopper:
 		return returnVal, errors.New("Keploy was interupted by stopper")
 	default:
-		// load the ebpf hooks into the kernel
-		if err := returnVal.LoadedHooks.LoadHooks(cfg.AppCmd, cfg.AppContainer, 0, context.Background(), nil); err != nil {
+		// load the ebpf hooks into the kernel, or capture the calls in userspace when they can't be loaded
+		if err := returnVal.LoadedHooks.LoadCapture(cfg.AppCmd, cfg.AppContainer, cfg.Pid, context.Background(), nil); err != nil {
 			return returnVal, err
 		}
 	}
@@ -141,18 +210,23 @@ func (t *tester) InitialiseTest(cfg *TestConfig) (InitialiseTestReturn, error) {
 		return returnVal, errors.New("Keploy was interupted by stopper")
 	default:
 		// start the proxy
-		returnVal.ProxySet = proxy.BootProxy(t.logger, proxy.Option{Port: cfg.Proxyport, MongoPassword: cfg.MongoPassword}, cfg.AppCmd, cfg.AppContainer, 0, "", cfg.PassThroughPorts, returnVal.LoadedHooks, context.Background(), cfg.Delay)
+		returnVal.ProxySet = proxy.BootProxy(t.base, proxy.Option{Port: cfg.Proxyport, MongoPassword: cfg.MongoPassword, PostgresPassword: cfg.PostgresPassword, ProtoDescriptors: cfg.ProtoDescriptors, ProtobufRoutes: cfg.ProtobufRoutes, Http: cfg.HttpConfig, Sql: cfg.SqlConfig, Matchers: cfg.Matchers, PassThrough: cfg.PassThrough, UnixSockets: cfg.UnixSockets}, cfg.AppCmd, cfg.AppContainer, cfg.Pid, "", cfg.PassThroughPorts, returnVal.LoadedHooks, context.Background(), cfg.Delay)
 	}
 
-	// proxy update its state in the ProxyPorts map
-	//Sending Proxy Ip & Port to the ebpf program
-	if err := returnVal.LoadedHooks.SendProxyInfo(returnVal.ProxySet.IP4, returnVal.ProxySet.Port, returnVal.ProxySet.IP6); err != nil {
-		return returnVal, err
+	if returnVal.LoadedHooks.CapturesWithPreload() {
+		t.connectPort = returnVal.ProxySet.Port
 	}
+	if !returnVal.LoadedHooks.IsUserspace() {
+		// proxy update its state in the ProxyPorts map
+		//Sending Proxy Ip & Port to the ebpf program
+		if err := returnVal.LoadedHooks.SendProxyInfo(returnVal.ProxySet.IP4, returnVal.ProxySet.Port, returnVal.ProxySet.IP6); err != nil {
+			return returnVal, err
+		}
 
-	// filter the required destination ports
-	if err := returnVal.LoadedHooks.SendPassThroughPorts(cfg.PassThroughPorts); err != nil {
-		return returnVal, err
+		// filter the required destination ports
+		if err := returnVal.LoadedHooks.SendPassThroughPorts(cfg.PassThroughPorts); err != nil {
+			return returnVal, err
+		}
 	}
 
 	sessions, err := yaml.ReadSessionIndices(cfg.Path, t.logger)
@@ -211,10 +285,41 @@ func (t *tester) Test(path string, testReportPath string, appCmd string, options
 		PassThroughPorts:   options.PassThroughPorts,
 		ApiTimeout:         options.ApiTimeout,
 		MongoPassword:      options.MongoPassword,
+		PostgresPassword:   options.PostgresPassword,
 		WithCoverage:       options.WithCoverage,
 		CoverageReportPath: options.CoverageReportPath,
+		ProtoDescriptors:   options.ProtoDescriptors,
+		ProtobufRoutes:     options.ProtobufRoutes,
+		HttpConfig:         options.HttpConfig,
+		SqlConfig:          options.SqlConfig,
+		Matchers:           options.Matchers,
+		PassThrough:        options.PassThrough,
+		UnixSockets:        options.UnixSockets,
+		CaptureMode:        options.CaptureMode,
+		SimulateLatency:    options.SimulateLatency,
+		Pid:                options.Pid,
 		EnableTele:         enableTele,
 	}
+	t.mockMatching = t.validMockMatching(options.MockMatching)
+	t.chaos = t.validChaos(options.Chaos)
+	t.reportFormats = t.validReportFormats(options.ReportFormats)
+	t.selection = options.Selection
+	t.flakeDetection = options.FlakeDetection
+	t.deterministicRandom = options.DeterministicRandom
+	t.unixSockets = options.UnixSockets
+	if options.TimeFreezing.Enabled {
+		t.startTimeFreezing(options.TimeFreezing, appCmd)
+		defer t.stopTimeFreezing()
+	}
+	var collector coverage.Collector
+	if options.WithCoverage {
+		var err error
+		collector, err = t.coverage.Collector(options.Language)
+		if err != nil {
+			t.logger.Error("failed to collect the coverage of the application", zap.Error(err))
+			return false
+		}
+	}
 	initialisedValues, err := t.InitialiseTest(cfg)
 	// Recover from panic and gracefully shutdown
 	defer initialisedValues.LoadedHooks.Recover(pkg.GenerateRandomID())
@@ -222,18 +327,41 @@ func (t *tester) Test(path string, testReportPath string, appCmd string, options
 		t.logger.Error("failed to initialise the test", zap.Error(err))
 		return false
 	}
+	// the report of a shard gathers the test reports written by the run
+	previousReports := reportNames(testReportPath)
+	selected, sharded := 0, 0
+	var ranTestSets []string
 	for _, sessionIndex := range initialisedValues.Sessions {
 		// checking whether the provided testset match with a recorded testset.
 		testcases := ArrayToMap(options.Tests[sessionIndex])
 		if _, ok := options.Tests[sessionIndex]; !ok && len(options.Tests) != 0 {
 			continue
 		}
+		// the shards of a sharded run, then the workers of a parallel one, each take their share of the test sets
+		selected++
+		if !options.Shard.Includes(selected - 1) {
+			continue
+		}
+		sharded++
+		if !options.Worker.Includes(sharded - 1) {
+			continue
+		}
 		noiseConfig := options.GlobalNoise
 		if tsNoise, ok := options.TestsetNoise[sessionIndex]; ok {
 			noiseConfig = LeftJoinNoise(options.GlobalNoise, tsNoise)
 		}
 
-		testRunStatus := t.RunTestSet(sessionIndex, path, testReportPath, appCmd, options.AppContainer, options.AppNetwork, options.Delay, options.BuildDelay, 0, initialisedValues.YamlStore, initialisedValues.LoadedHooks, initialisedValues.TestReportFS, nil, options.ApiTimeout, initialisedValues.Ctx, testcases, noiseConfig, false)
+		runCmd := appCmd
+		if collector != nil {
+			runCmd = t.startCoverage(collector, cfg.CoverageReportPath, sessionIndex, appCmd)
+		}
+		runCmd = t.preloadLibraries(runCmd, path, sessionIndex)
+		ranTestSets = append(ranTestSets, sessionIndex)
+
+		testRunStatus := t.RunTestSet(sessionIndex, path, testReportPath, runCmd, options.AppContainer, options.AppNetwork, options.Delay, options.BuildDelay, options.Pid, initialisedValues.YamlStore, initialisedValues.LoadedHooks, initialisedValues.TestReportFS, nil, options.ApiTimeout, initialisedValues.Ctx, testcases, noiseConfig, false)
+		if collector != nil {
+			t.flushCoverage(collector, cfg.CoverageReportPath, sessionIndex)
+		}
 
 		switch testRunStatus {
 		case models.TestRunStatusAppHalted:
@@ -255,26 +383,14 @@ func (t *tester) Test(path string, testReportPath string, appCmd string, options
 		}
 	}
 	t.logger.Info("test run completed", zap.Bool("passed overall", result))
-	// log the overall code coverage for the test run of go binaries
-	if options.WithCoverage {
-		t.logger.Info("there is a opportunity to get the coverage here")
-		// logs the coverage using covdata
-		coverCmd := exec.Command("go", "tool", "covdata", "percent", "-i="+os.Getenv("GOCOVERDIR"))
-		output, err := coverCmd.Output()
-		if err != nil {
-			t.logger.Error("failed to get the coverage of the go binary", zap.Error(err), zap.Any("cmd", coverCmd.String()))
-		}
-		t.logger.Sugar().Infoln("\n", models.HighlightPassingString(string(output)))
-
-		// merges the coverage files into a single txt file which can be merged with the go-test coverage
-		generateCovTxtCmd := exec.Command("go", "tool", "covdata", "textfmt", "-i="+os.Getenv("GOCOVERDIR"), "-o="+os.Getenv("GOCOVERDIR")+"/total-coverage.txt")
-		output, err = generateCovTxtCmd.Output()
-		if err != nil {
-			t.logger.Error("failed to get the coverage of the go binary", zap.Error(err), zap.Any("cmd", coverCmd.String()))
-		}
-		if len(output) > 0 {
-			t.logger.Sugar().Infoln("\n", models.HighlightFailingString(string(output)))
-		}
+	// the report of a shard run by workers is written by the parallel run
+	if options.Worker.Count == 0 {
+		result = t.finishShard(testReportPath, options.Shard, shardTestSets(initialisedValues.Sessions, options.Tests, options.Shard), previousReports, result)
+	}
+	// log the overall code coverage for the test run of go binaries, the coverage of a parallel run is merged once its
+	// workers are done
+	if options.WithCoverage && coverage.IsGo(options.Language) && options.Worker.Count == 0 {
+		t.mergeCoverage(cfg.CoverageReportPath, ranTestSets, options.UnitCoverage)
 	}
 
 	if !initialisedValues.AbortStopHooksForcefully {
@@ -283,7 +399,7 @@ func (t *tester) Test(path string, testReportPath string, appCmd string, options
 		initialisedValues.LoadedHooks.Stop(true)
 		//stop listening for proxy server
 		initialisedValues.ProxySet.StopProxyServer()
-		return true
+		return result
 	}
 
 	<-initialisedValues.ExitCmd
@@ -307,12 +423,18 @@ func (t *tester) InitialiseRunTestSet(cfg *RunTestSetConfig) InitialiseRunTestSe
 		returnVal.InitialStatus = models.TestRunStatusFailed
 		return returnVal
 	}
-	returnVal.Tcs = readTcs
-	if len(returnVal.Tcs) == 0 {
+	if len(readTcs) == 0 {
 		t.logger.Info("No testcases are recorded for the user application", zap.Any("for session", cfg.TestSet))
 		returnVal.InitialStatus = models.TestRunStatusFailed
 		return returnVal
 	}
+	returnVal.Tcs = t.selection.Filter(cfg.TestSet, readTcs)
+	if len(returnVal.Tcs) == 0 {
+		// the test set is skipped without starting the application
+		t.logger.Info("no testcase of the test set is selected by the names and tags", zap.Any("test-set", cfg.TestSet))
+		returnVal.InitialStatus = models.TestRunStatusPassed
+		return returnVal
+	}
 
 	t.logger.Debug(fmt.Sprintf("the testcases for %s are: %v", cfg.TestSet, returnVal.Tcs))
 	var readConfigMocks []*models.Mock
@@ -338,8 +460,14 @@ func (t *tester) InitialiseRunTestSet(cfg *RunTestSetConfig) InitialiseRunTestSe
 		returnVal.InitialStatus = models.TestRunStatusFailed
 		return returnVal
 	}
+	returnVal.TcsMocks = readTcsMocks
 	t.logger.Debug(fmt.Sprintf("the config mocks for %s are: %v\nthe testcase mocks are: %v", cfg.TestSet, configMocks, returnVal.TcsMocks))
+	dnsMocks, readConfigMocks := hooks.SplitDNSMocks(readConfigMocks)
+	cfg.LoadedHooks.SetDNSMocks(dnsMocks)
+	t.applyFaults(readConfigMocks, cfg.TestSet)
 	cfg.LoadedHooks.SetConfigMocks(readConfigMocks)
+	t.applyMockPolicies(readTcsMocks, cfg.TestSet)
+	t.applyFaults(readTcsMocks, cfg.TestSet)
 	cfg.LoadedHooks.SetTcsMocks(readTcsMocks)
 	returnVal.ErrChan = make(chan error, 1)
 	t.logger.Debug("", zap.Any("app pid", cfg.Pid))
@@ -381,6 +509,19 @@ func (t *tester) InitialiseRunTestSet(cfg *RunTestSetConfig) InitialiseRunTestSe
 		returnVal.InitialStatus = models.TestRunStatusFailed
 		return returnVal
 	}
+	// the report of a test run cut short is marked as aborted rather than left running
+	returnVal.ReportMutex = &sync.Mutex{}
+	returnVal.RemoveAbortCleanup = shutdown.Register("test report", func() {
+		returnVal.ReportMutex.Lock()
+		defer returnVal.ReportMutex.Unlock()
+		if returnVal.TestReport.Status != string(models.TestRunStatusRunning) {
+			return
+		}
+		returnVal.TestReport.Status = string(models.TestRunStatusUserAbort)
+		if err := cfg.TestReportFS.Write(context.Background(), cfg.TestReportPath, returnVal.TestReport); err != nil {
+			t.logger.Error("failed to write the report of the aborted test run", zap.Error(err))
+		}
+	})
 
 	//if running keploy-tests along with unit tests
 	if cfg.ServeTest && cfg.TestRunChan != nil {
@@ -413,6 +554,13 @@ func (t *tester) SimulateRequest(cfg *SimulateRequestConfig) {
 		started := time.Now().UTC()
 		t.logger.Debug("Before simulating the request", zap.Any("Test case", cfg.Tc))
 
+		if len(cfg.Tc.Variables) > 0 {
+			err := pkg.RenderRequest(&cfg.Tc.HttpReq, t.variableValues(cfg))
+			if err != nil {
+				t.logger.Error("failed to render the variables of the testcase", zap.Error(err), zap.Any("testcase id", cfg.Tc.Name))
+			}
+		}
+
 		ok, _ := cfg.LoadedHooks.IsDockerRelatedCmd(cfg.AppCmd)
 		if ok || cfg.DockerID {
 			var err error
@@ -423,10 +571,17 @@ func (t *tester) SimulateRequest(cfg *SimulateRequestConfig) {
 			t.logger.Debug("", zap.Any("replaced URL in case of docker env", cfg.Tc.HttpReq.URL))
 		}
 		t.logger.Debug(fmt.Sprintf("the url of the testcase: %v", cfg.Tc.HttpReq.URL))
+		t.freezeTime(cfg.Tc)
+		simulated := time.Now()
 		resp, err := pkg.SimulateHttp(*cfg.Tc, cfg.TestSet, t.logger, cfg.ApiTimeout)
+		metrics.ReplayDuration.Observe(time.Since(simulated).Seconds())
 		t.logger.Debug("After simulating the request", zap.Any("test case id", cfg.Tc.Name))
 		t.logger.Debug("After GetResp of the request", zap.Any("test case id", cfg.Tc.Name))
 
+		if resp != nil && cfg.Responses != nil {
+			cfg.Responses[cfg.Tc.Name] = resp
+		}
+
 		if err != nil && resp == nil {
 			t.logger.Info("result", zap.Any("testcase id", models.HighlightFailingString(cfg.Tc.Name)), zap.Any("testset id", models.HighlightFailingString(cfg.TestSet)), zap.Any("passed", models.HighlightFailingString("false")))
 			return
@@ -448,6 +603,7 @@ func (t *tester) SimulateRequest(cfg *SimulateRequestConfig) {
 			*cfg.Failure++
 			*cfg.Status = models.TestRunStatusFailed
 		}
+		metrics.TestResults.Inc(string(testStatus))
 
 		cfg.TestReportFS.SetResult(cfg.TestReport.Name, &models.TestResult{
 			Kind:       models.HTTP,
@@ -456,6 +612,7 @@ func (t *tester) SimulateRequest(cfg *SimulateRequestConfig) {
 			Started:    started.Unix(),
 			Completed:  time.Now().UTC().Unix(),
 			TestCaseID: cfg.Tc.Name,
+			GraphQL:    graphQLOperation(cfg.Tc.HttpReq),
 			Req: models.HttpReq{
 				Method:     cfg.Tc.HttpReq.Method,
 				ProtoMajor: cfg.Tc.HttpReq.ProtoMajor,
@@ -485,6 +642,27 @@ func (t *tester) SimulateRequest(cfg *SimulateRequestConfig) {
 	}
 }
 
+// variableValues returns the values of the variables of the testcase, taken from the actual responses of the
+// testcases they were recorded from. The recorded values are used for the testcases which weren't run.
+func (t *tester) variableValues(cfg *SimulateRequestConfig) map[string]string {
+	values := map[string]string{}
+	for name, variable := range cfg.Tc.Variables {
+		values[name] = variable.Value
+		resp, ok := cfg.Responses[variable.From]
+		if !ok {
+			t.logger.Debug("the testcase of the variable wasn't run, using its recorded value", zap.String("variable", name), zap.String("from", variable.From))
+			continue
+		}
+		value, ok := pkg.LookupResponseValue(resp, variable.Path)
+		if !ok {
+			t.logger.Warn("the variable isn't in the response of its testcase, using its recorded value", zap.String("variable", name), zap.String("from", variable.From), zap.String("path", variable.Path))
+			continue
+		}
+		values[name] = value
+	}
+	return values
+}
+
 func (t *tester) FetchTestResults(cfg *FetchTestResultsConfig) models.TestRunStatus {
 	// store the result of the testrun as test-report
 	testResults, err := cfg.TestReportFS.GetResults(cfg.TestReport.Name)
@@ -500,13 +678,6 @@ func (t *tester) FetchTestResults(cfg *FetchTestResultsConfig) models.TestRunSta
 		}
 		readTestResults = append(readTestResults, *testResult)
 	}
-	cfg.TestReport.TestSet = cfg.TestSet
-	cfg.TestReport.Total = len(readTestResults)
-	cfg.TestReport.Status = string(*cfg.Status)
-	cfg.TestReport.Tests = readTestResults
-	cfg.TestReport.Success = *cfg.Success
-	cfg.TestReport.Failure = *cfg.Failure
-
 	resultForTele, ok := cfg.Ctx.Value("resultForTele").(*[]int)
 	if !ok {
 		t.logger.Debug("resultForTele is not of type *[]int")
@@ -514,7 +685,23 @@ func (t *tester) FetchTestResults(cfg *FetchTestResultsConfig) models.TestRunSta
 	(*resultForTele)[0] += *cfg.Success
 	(*resultForTele)[1] += *cfg.Failure
 
+	// the cleanup marking the report aborted doesn't write it while its final status is set
+	if cfg.ReportMutex != nil {
+		cfg.ReportMutex.Lock()
+	}
+	cfg.TestReport.TestSet = cfg.TestSet
+	cfg.TestReport.Total = len(readTestResults)
+	cfg.TestReport.Status = string(*cfg.Status)
+	cfg.TestReport.Tests = readTestResults
+	cfg.TestReport.Success = *cfg.Success
+	cfg.TestReport.Failure = *cfg.Failure
 	err = cfg.TestReportFS.Write(context.Background(), cfg.TestReportPath, cfg.TestReport)
+	if cfg.ReportMutex != nil {
+		cfg.ReportMutex.Unlock()
+	}
+	if err == nil {
+		t.writeReportFormats(cfg.TestReportPath, cfg.TestReport)
+	}
 
 	t.logger.Info("test report for "+cfg.TestSet+": ", zap.Any("name: ", cfg.TestReport.Name), zap.Any("path: ", cfg.Path+"/"+cfg.TestReport.Name))
 
@@ -525,6 +712,9 @@ func (t *tester) FetchTestResults(cfg *FetchTestResultsConfig) models.TestRunSta
 	}
 
 	pp.Printf("\n <=========================================> \n  TESTRUN SUMMARY. For testrun with id: %s\n"+"\tTotal tests: %s\n"+"\tTotal test passed: %s\n"+"\tTotal test failed: %s\n <=========================================> \n\n", cfg.TestReport.TestSet, cfg.TestReport.Total, cfg.TestReport.Success, cfg.TestReport.Failure)
+	if summary := operationSummary(readTestResults); summary != "" {
+		fmt.Print(summary + "\n")
+	}
 
 	if err != nil {
 		t.logger.Error(err.Error())
@@ -556,6 +746,7 @@ func (t *tester) RunTestSet(testSet, path, testReportPath, appCmd, appContainer,
 		Ctx:            ctx,
 		ServeTest:      serveTest,
 	}
+	loadedHooks.ResetConsumedMocks()
 	initialisedValues := t.InitialiseRunTestSet(cfg)
 	if initialisedValues.InitialStatus != "" {
 		return initialisedValues.InitialStatus
@@ -587,6 +778,8 @@ func (t *tester) RunTestSet(testSet, path, testReportPath, appCmd, appContainer,
 	t.logger.Debug("the userip of the user docker container", zap.Any("", userIp))
 
 	var entTcs, nonKeployTcs []string
+	var flakyTests []models.FlakyTest
+	responses := map[string]*models.HttpResp{}
 	for _, tc := range initialisedValues.Tcs {
 		if _, ok := testcases[tc.Name]; !ok && len(testcases) != 0 {
 			continue
@@ -602,6 +795,8 @@ func (t *tester) RunTestSet(testSet, path, testReportPath, appCmd, appContainer,
 			readTcsMocks = append(readTcsMocks, tcsmock)
 		}
 		readTcsMocks = FilterTcsMocks(tc, readTcsMocks, t.logger)
+		t.applyMockPolicies(readTcsMocks, testSet)
+		t.applyFaults(readTcsMocks, testSet)
 		loadedHooks.SetTcsMocks(readTcsMocks)
 		if tc.Version == "api.keploy-enterprise.io/v1beta1" {
 			entTcs = append(entTcs, tc.Name)
@@ -649,8 +844,13 @@ func (t *tester) RunTestSet(testSet, path, testReportPath, appCmd, appContainer,
 			Path:         path,
 			DockerID:     initialisedValues.DockerID,
 			NoiseConfig:  noiseConfig,
+			Responses:    responses,
 		}
+		succeeded := success
 		t.SimulateRequest(cfg)
+		if t.flakeDetection.Runs > 1 && tc.Kind == models.HTTP {
+			flakyTests = append(flakyTests, t.repeatTestcase(cfg, readTcsMocks, flakeRun{passed: success > succeeded, resp: responses[tc.Name]}))
+		}
 	}
 	if len(entTcs) > 0 {
 		t.logger.Warn("These testcases have been recorded with Keploy Enterprise, may not work properly with the open-source version", zap.Strings("enterprise mocks:", entTcs))
@@ -658,6 +858,9 @@ func (t *tester) RunTestSet(testSet, path, testReportPath, appCmd, appContainer,
 	if len(nonKeployTcs) > 0 {
 		t.logger.Warn("These testcases have not been recorded by Keploy, may not work properly with Keploy.", zap.Strings("non-keploy mocks:", nonKeployTcs))
 	}
+	// the mocks matched during the run let the ones never used be pruned
+	initialisedValues.TestReport.ConsumedMocks = loadedHooks.GetConsumedMocks()
+	countMissedMocks(initialisedValues.TcsMocks, initialisedValues.TestReport.ConsumedMocks)
 	resultsCfg := &FetchTestResultsConfig{
 		TestReportFS:   testReportFS,
 		TestReport:     initialisedValues.TestReport,
@@ -668,8 +871,14 @@ func (t *tester) RunTestSet(testSet, path, testReportPath, appCmd, appContainer,
 		Ctx:            ctx,
 		TestReportPath: testReportPath,
 		Path:           path,
+		ReportMutex:    initialisedValues.ReportMutex,
 	}
 	status = t.FetchTestResults(resultsCfg)
+	// the report holds its final status, which the cleanup no longer has to mark aborted
+	initialisedValues.RemoveAbortCleanup()
+	if t.flakeDetection.Runs > 1 {
+		t.finishFlakeDetection(testSet, path, testReportPath, initialisedValues.TestReport.Name, flakyTests)
+	}
 	return status
 }
 
@@ -678,6 +887,8 @@ func (t *tester) testHttp(tc models.TestCase, actualResponse *models.HttpResp, n
 	bodyType := models.BodyTypePlain
 	if json.Valid([]byte(actualResponse.Body)) {
 		bodyType = models.BodyTypeJSON
+	} else if isXMLBody(actualResponse.Header, actualResponse.Body) {
+		bodyType = models.BodyTypeXML
 	}
 	pass := true
 	hRes := &[]models.HeaderResult{}
@@ -711,7 +922,9 @@ func (t *tester) testHttp(tc models.TestCase, actualResponse *models.HttpResp, n
 
 	for field, regexArr := range noise {
 		a := strings.Split(field, ".")
-		if len(a) > 1 && a[0] == "body" {
+		if isJsonPath(field) || isXPath(field) {
+			bodyNoise[field] = regexArr
+		} else if len(a) > 1 && a[0] == "body" {
 			x := strings.Join(a[1:], ".")
 			bodyNoise[x] = regexArr
 		} else if a[0] == "header" {
@@ -722,14 +935,29 @@ func (t *tester) testHttp(tc models.TestCase, actualResponse *models.HttpResp, n
 	// stores the json body after removing the noise
 	cleanExp, cleanAct := "", ""
 	var err error
-	if !Contains(MapToArray(noise), "body") && bodyType == models.BodyTypeJSON {
+	var schemaErrs []models.SchemaError
+	if tc.AssertionMode == models.AssertionSchema && tc.Schema != nil {
+		// the body only has to match the schema inferred from the recorded one
+		if !Contains(MapToArray(noise), "body") {
+			schemaErrs = pkg.ValidateSchema(tc.Schema, actualResponse.Body)
+			pass = len(schemaErrs) == 0
+		}
+	} else if !Contains(MapToArray(noise), "body") && bodyType == models.BodyTypeJSON {
 		cleanExp, cleanAct, pass, err = Match(tc.HttpResp.Body, actualResponse.Body, bodyNoise, t.logger)
 		if err != nil {
+			t.logger.Error("failed to match the body of the response", zap.Error(err), zap.Any("testcase id", tc.Name))
 			return false, res
 		}
 		// debug log for cleanExp and cleanAct
 		t.logger.Debug("cleanExp", zap.Any("", cleanExp))
 		t.logger.Debug("cleanAct", zap.Any("", cleanAct))
+	} else if !Contains(MapToArray(noise), "body") && bodyType == models.BodyTypeXML {
+		// the XML bodies are compared on their canonical form, whatever the serializer which wrote them
+		cleanExp, cleanAct, pass, err = MatchXML(tc.HttpResp.Body, actualResponse.Body, bodyNoise)
+		if err != nil {
+			t.logger.Error("failed to match the XML body of the response", zap.Error(err), zap.Any("testcase id", tc.Name))
+			return false, res
+		}
 	} else {
 		if !Contains(MapToArray(noise), "body") && tc.HttpResp.Body != actualResponse.Body {
 			pass = false
@@ -737,6 +965,7 @@ func (t *tester) testHttp(tc models.TestCase, actualResponse *models.HttpResp, n
 	}
 
 	res.BodyResult[0].Normal = pass
+	res.BodyResult[0].SchemaErrors = schemaErrs
 
 	if !CompareHeaders(pkg.ToHttpHeader(tc.HttpResp.Header), pkg.ToHttpHeader(actualResponse.Header), hRes, headerNoise) {
 
@@ -788,7 +1017,16 @@ func (t *tester) testHttp(tc models.TestCase, actualResponse *models.HttpResp, n
 
 		if !res.BodyResult[0].Normal {
 
-			if json.Valid([]byte(actualResponse.Body)) {
+			if len(schemaErrs) > 0 {
+				var expected, actual []string
+				for _, schemaErr := range schemaErrs {
+					expected = append(expected, schemaErr.Path+": "+schemaErr.Expected)
+					actual = append(actual, schemaErr.Path+": "+schemaErr.Actual)
+				}
+				logDiffs.PushBodyDiff(strings.Join(expected, "\n"), strings.Join(actual, "\n"), bodyNoise)
+			} else if bodyType == models.BodyTypeXML {
+				logDiffs.PushBodyDiff(cleanExp, cleanAct, bodyNoise)
+			} else if json.Valid([]byte(actualResponse.Body)) {
 				patch, err := jsondiff.Compare(cleanExp, cleanAct)
 				if err != nil {
 					t.logger.Warn("failed to compute json diff", zap.Error(err))
@@ -0,0 +1,113 @@
+package test
+
+import (
+	"os"
+	"path/filepath"
+	"time"
+
+	"go.keploy.io/server/pkg/hooks/preload"
+	"go.keploy.io/server/pkg/models"
+	"go.uber.org/zap"
+)
+
+// DefaultTimeHeader is the header holding the recorded time of the testcases added to the replayed requests.
+const DefaultTimeHeader = "Keploy-Time"
+
+// libfaketimePaths are the usual paths of libfaketime, installed by the faketime package of the distributions.
+var libfaketimePaths = []string{
+	"/usr/lib/x86_64-linux-gnu/faketime/libfaketime.so.1",
+	"/usr/lib/aarch64-linux-gnu/faketime/libfaketime.so.1",
+	"/usr/lib/faketime/libfaketime.so.1",
+	"/usr/lib64/faketime/libfaketime.so.1",
+	"/usr/local/lib/faketime/libfaketime.so.1",
+}
+
+// timeFreezer makes the application observe the recorded time of the testcases: libfaketime, preloaded in the
+// application, reads the time from a file written before each testcase is replayed.
+type timeFreezer struct {
+	// lib is libfaketime, nil when it isn't installed
+	lib *preload.Library
+	// file holds the recorded time of the testcase being replayed, in the format of libfaketime
+	file string
+	// header is the header holding the recorded time added to the replayed requests
+	header string
+}
+
+// startTimeFreezing sets up libfaketime, preloaded in the applications started by keploy, to read the time from the
+// file of the freezer.
+func (t *tester) startTimeFreezing(config models.TimeFreezing, appCmd string) {
+	t.timeFreezer = &timeFreezer{header: config.Header}
+	if t.timeFreezer.header == "" {
+		t.timeFreezer.header = DefaultTimeHeader
+	}
+	if appCmd == "" {
+		// the application isn't started by keploy, only the header tells it the recorded time
+		return
+	}
+
+	lib := config.LibPath
+	if lib == "" {
+		for _, path := range libfaketimePaths {
+			if _, err := os.Stat(path); err == nil {
+				lib = path
+				break
+			}
+		}
+	}
+	if lib == "" {
+		t.logger.Warn("libfaketime isn't installed, only the header tells the application the recorded time of the testcases, install the faketime package or set the path of libfaketime", zap.String("header", t.timeFreezer.header))
+		return
+	}
+	dir, err := os.MkdirTemp("", "keploy-faketime-")
+	if err != nil {
+		t.logger.Error("failed to create the directory of the recorded time", zap.Error(err))
+		return
+	}
+	// the application reads the file as the user who invoked sudo
+	os.Chmod(dir, 0755)
+	file := filepath.Join(dir, "timestamp")
+	// the application starts at the current time, until the first testcase is replayed
+	if err := os.WriteFile(file, []byte(""), 0644); err != nil {
+		t.logger.Error("failed to write the recorded time", zap.Error(err))
+		os.RemoveAll(dir)
+		return
+	}
+	t.timeFreezer.file = file
+	t.timeFreezer.lib = &preload.Library{
+		Path: lib,
+		// the monotonic clocks are left real, so that the timers and the timeouts of the application keep working
+		Env: map[string]string{
+			"FAKETIME_TIMESTAMP_FILE":      file,
+			"FAKETIME_NO_CACHE":            "1",
+			"FAKETIME_DONT_FAKE_MONOTONIC": "1",
+		},
+		Dirs: []string{dir},
+	}
+}
+
+// freezeTime makes the application observe the recorded time of the testcase while it is replayed.
+func (t *tester) freezeTime(tc *models.TestCase) {
+	if t.timeFreezer == nil || tc.HttpReq.Timestamp.IsZero() {
+		return
+	}
+	if tc.HttpReq.Header == nil {
+		tc.HttpReq.Header = map[string]string{}
+	}
+	tc.HttpReq.Header[t.timeFreezer.header] = tc.HttpReq.Timestamp.UTC().Format(time.RFC3339Nano)
+	if t.timeFreezer.file == "" {
+		return
+	}
+	// the clock of the application starts at the recorded time and keeps ticking from there
+	timestamp := "@" + tc.HttpReq.Timestamp.Local().Format("2006-01-02 15:04:05")
+	if err := os.WriteFile(t.timeFreezer.file, []byte(timestamp), 0644); err != nil {
+		t.logger.Warn("failed to write the recorded time of the testcase", zap.String("testcase", tc.Name), zap.Error(err))
+	}
+}
+
+// stopTimeFreezing removes the file of the recorded time.
+func (t *tester) stopTimeFreezing() {
+	if t.timeFreezer != nil && t.timeFreezer.file != "" {
+		os.RemoveAll(filepath.Dir(t.timeFreezer.file))
+	}
+	t.timeFreezer = nil
+}
@@ -0,0 +1,48 @@
+package test
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+
+	"go.keploy.io/server/pkg/models"
+	"go.keploy.io/server/pkg/platform/yaml"
+	"go.uber.org/zap"
+)
+
+func (t *tester) UpdateExpected(testReportPath string, testSets []string, yes bool) (int, error) {
+	failed, err := t.lastFailures(testReportPath, testSets)
+	if err != nil {
+		t.logger.Error("failed to read the test reports", zap.String("path", testReportPath), zap.Error(err))
+		return 0, err
+	}
+	in := bufio.NewScanner(os.Stdin)
+	updated := 0
+	for _, f := range failed {
+		if !yes {
+			printTestDiffs(f, mismatchedFields(f.result))
+			answer, ok := prompt(in, fmt.Sprintf("Update the expected response of %s/%s with the actual one? [y/N]: ", f.testSet, f.result.TestCaseID))
+			if !ok {
+				break
+			}
+			if !strings.EqualFold(answer, "y") {
+				continue
+			}
+		}
+		if t.updateResponse(f) {
+			updated++
+		}
+	}
+	return updated, nil
+}
+
+// updateResponse replaces the expected response of the testcase of the failed test with its actual one.
+func (t *tester) updateResponse(f failedTest) bool {
+	if err := yaml.UpdateResponse(f.testcaseDir(), f.result.TestCaseID, func(resp *models.HttpResp) { applyActual(resp, f.result.Result) }); err != nil {
+		t.logger.Error("failed to update the expected response of the testcase", zap.String("testcase", f.result.TestCaseID), zap.Error(err))
+		return false
+	}
+	t.logger.Info("updated the expected response of the testcase with the actual one", zap.String("test-set", f.testSet), zap.String("testcase", f.result.TestCaseID))
+	return true
+}
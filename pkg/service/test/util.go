@@ -5,15 +5,15 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
-	"os"
 	"reflect"
 	"regexp"
 	"strconv"
 	"strings"
-	"syscall"
+	"sync"
 	"time"
 
 	"go.keploy.io/server/pkg/hooks"
+	"go.keploy.io/server/pkg/metrics"
 	"go.keploy.io/server/pkg/models"
 	"go.keploy.io/server/pkg/platform"
 	"go.keploy.io/server/pkg/platform/yaml"
@@ -29,6 +29,10 @@ type InitialiseRunTestSetReturn struct {
 	UserIP        string
 	InitialStatus models.TestRunStatus
 	TcsMocks      []*models.Mock
+	// ReportMutex guards the status of the test report, which the cleanup marking it aborted reads
+	ReportMutex *sync.Mutex
+	// RemoveAbortCleanup removes the cleanup once the final status of the test report is written
+	RemoveAbortCleanup func()
 }
 
 type InitialiseTestReturn struct {
@@ -49,6 +53,7 @@ type TestConfig struct {
 	TestReportPath     string
 	AppCmd             string
 	MongoPassword      string
+	PostgresPassword   string
 	AppContainer       string
 	AppNetwork         string
 	Delay              uint64
@@ -58,6 +63,16 @@ type TestConfig struct {
 	WithCoverage       bool
 	CoverageReportPath string
 	EnableTele         bool
+	ProtoDescriptors   []string
+	ProtobufRoutes     []models.ProtobufRoute
+	HttpConfig         models.HttpConfig
+	SqlConfig          models.SqlConfig
+	Matchers           []models.MatcherRule
+	PassThrough        []models.PassThroughRule
+	UnixSockets        []models.UnixSocket
+	CaptureMode        string
+	SimulateLatency    float64
+	Pid                uint32
 }
 
 type RunTestSetConfig struct {
@@ -94,6 +109,8 @@ type SimulateRequestConfig struct {
 	Path         string
 	DockerID     bool
 	NoiseConfig  models.GlobalNoise
+	// Responses holds the actual responses of the test cases run, which the variables of the next ones are taken from
+	Responses map[string]*models.HttpResp
 }
 
 type FetchTestResultsConfig struct {
@@ -106,6 +123,7 @@ type FetchTestResultsConfig struct {
 	Ctx            context.Context
 	TestReportPath string
 	Path           string
+	ReportMutex    *sync.Mutex
 }
 
 func FlattenHttpResponse(h http.Header, body string) (map[string][]string, error) {
@@ -209,7 +227,10 @@ func LeftJoinNoise(globalNoise models.GlobalNoise, tsNoise models.GlobalNoise) m
 
 func MatchesAnyRegex(str string, regexArray []string) (bool, string) {
 	for _, pattern := range regexArray {
-		re := regexp.MustCompile(pattern)
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			continue
+		}
 		if re.MatchString(str) {
 			return true, pattern
 		}
@@ -229,13 +250,37 @@ func CheckStringExist(s string, mp map[string][]string) ([]string, bool) {
 	if val, ok := mp[s]; ok {
 		return val, ok
 	}
-	ok, val := MatchesAnyRegex(s, MapToArray(mp))
+	// the JSONPath and XPath expressions are matched against the paths of the values rather than as regexes
+	var keys []string
+	for k := range mp {
+		if !isJsonPath(k) && !isXPath(k) {
+			keys = append(keys, k)
+		}
+	}
+	ok, val := MatchesAnyRegex(s, keys)
 	if ok {
 		return mp[val], ok
 	}
 	return []string{}, false
 }
 
+// checkHeaderNoise returns the regexes of the values of a noisy header. The keys of the noise are the names of the
+// headers, or regexes matching them, regardless of their case.
+func checkHeaderNoise(name string, noise map[string][]string) ([]string, bool) {
+	for field, regexArr := range noise {
+		if strings.EqualFold(field, name) {
+			return regexArr, true
+		}
+	}
+	for field, regexArr := range noise {
+		re, err := regexp.Compile("(?i)" + field)
+		if err == nil && re.MatchString(name) {
+			return regexArr, true
+		}
+	}
+	return []string{}, false
+}
+
 func CompareHeaders(h1 http.Header, h2 http.Header, res *[]models.HeaderResult, noise map[string][]string) bool {
 	if res == nil {
 		return false
@@ -243,7 +288,7 @@ func CompareHeaders(h1 http.Header, h2 http.Header, res *[]models.HeaderResult,
 	match := true
 	_, isHeaderNoisy := noise["header"]
 	for k, v := range h1 {
-		regexArr, isNoisy := CheckStringExist(k, noise)
+		regexArr, isNoisy := checkHeaderNoise(k, noise)
 		if isNoisy && len(regexArr) != 0 {
 			isNoisy, _ = MatchesAnyRegex(v[0], regexArr)
 		}
@@ -320,7 +365,7 @@ func CompareHeaders(h1 http.Header, h2 http.Header, res *[]models.HeaderResult,
 		}
 	}
 	for k, v := range h2 {
-		regexArr, isNoisy := CheckStringExist(k, noise)
+		regexArr, isNoisy := checkHeaderNoise(k, noise)
 		if isNoisy && len(regexArr) != 0 {
 			isNoisy, _ = MatchesAnyRegex(v[0], regexArr)
 		}
@@ -409,13 +454,15 @@ func FilterTcsMocks(tc *models.TestCase, m []*models.Mock, logger *zap.Logger) [
 	return filteredMocks
 }
 
-// creates a directory if not exists with all user access
-func makeDirectory(path string) error {
-	oldUmask := syscall.Umask(0)
-	err := os.MkdirAll(path, 0777)
-	if err != nil {
-		return err
+// countMissedMocks counts the tcs mocks of the test set which no call of the application matched, by kind.
+func countMissedMocks(mocks []*models.Mock, consumed []string) {
+	matched := make(map[string]bool, len(consumed))
+	for _, name := range consumed {
+		matched[name] = true
+	}
+	for _, mock := range mocks {
+		if !matched[mock.Name] {
+			metrics.MissedMocks.Inc(string(mock.Kind))
+		}
 	}
-	syscall.Umask(oldUmask)
-	return nil
 }
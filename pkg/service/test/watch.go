@@ -0,0 +1,229 @@
+package test
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	"go.keploy.io/server/pkg/models"
+	"go.keploy.io/server/pkg/platform/yaml"
+	"go.uber.org/zap"
+)
+
+// WatchOptions are the options of a watch run.
+type WatchOptions struct {
+	// Paths are the files and directories of the sources or the binary of the application which are watched
+	Paths []string
+	// BuildCmd rebuilds the application once a watched file changed, before the tests are run again
+	BuildCmd string
+	// Interval is how often the watched files are checked for changes
+	Interval time.Duration
+}
+
+// skippedDirs are the directories which are not watched, their files changing without the application changing.
+var skippedDirs = map[string]bool{
+	".git":         true,
+	".idea":        true,
+	".vscode":      true,
+	"keploy":       true,
+	"node_modules": true,
+	"vendor":       true,
+	"__pycache__":  true,
+	".nyc_output":  true,
+	"coverage":     true,
+	"target":       true,
+}
+
+// TestWatch runs the tests, then runs them again every time the watched files change until it is interrupted: the
+// application is rebuilt with the build command, and only the testcases which failed in the previous run are run
+// again, all of them once none fails. Each run is a keploy process started with the same flags.
+func (t *tester) TestWatch(path string, testReportPath string, appCmd string, options TestOptions, watch WatchOptions) bool {
+	executable, err := os.Executable()
+	if err != nil {
+		t.logger.Error("failed to find the keploy executable", zap.Error(err))
+		return false
+	}
+	if len(watch.Paths) == 0 {
+		watch.Paths = []string{"."}
+	}
+	if watch.Interval <= 0 {
+		watch.Interval = time.Second
+	}
+
+	// the runs are interrupted along with keploy, which stops watching
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(interrupt)
+	go func() {
+		select {
+		case <-interrupt:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	var failing []string
+	passed := false
+	for {
+		passed, failing = t.watchRun(ctx, executable, testReportPath, failing)
+		if ctx.Err() != nil {
+			return passed
+		}
+		if len(failing) > 0 {
+			t.logger.Info("waiting for a change of the application to run the failing testcases again", zap.Strings("failing", failing))
+		} else {
+			t.logger.Info("waiting for a change of the application to run the testcases again")
+		}
+
+		snapshot := watchSnapshot(watch.Paths)
+		for {
+			select {
+			case <-ctx.Done():
+				return passed
+			case <-time.After(watch.Interval):
+			}
+			changed := watchSnapshot(watch.Paths)
+			if !sameSnapshot(snapshot, changed) {
+				// the changes are waited for to settle, like the files saved together by an editor
+				for !sameSnapshot(changed, snapshot) {
+					snapshot = changed
+					select {
+					case <-ctx.Done():
+						return passed
+					case <-time.After(watch.Interval):
+					}
+					changed = watchSnapshot(watch.Paths)
+				}
+				if t.watchBuild(ctx, watch.BuildCmd) {
+					break
+				}
+				// the application is built again once it changes again
+				snapshot = watchSnapshot(watch.Paths)
+			}
+		}
+	}
+}
+
+// watchRun runs the testcases, only the failing ones when some are given, in a keploy process and returns whether
+// they passed along with the ones which failed.
+func (t *tester) watchRun(ctx context.Context, executable, testReportPath string, failing []string) (bool, []string) {
+	previousReports := reportNames(testReportPath)
+	// the flags given last override the ones of the command line and of the config file
+	args := append(append([]string{}, os.Args[1:]...), "--watch=false")
+	if len(failing) > 0 {
+		args = append(args, "--rerun", strings.Join(failing, ","))
+		t.logger.Info("running the failing testcases again", zap.Strings("testcases", failing))
+	} else {
+		t.logger.Info("running the testcases")
+	}
+	cmd := exec.CommandContext(ctx, executable, args...)
+	cmd.Stdout, cmd.Stderr = os.Stdout, os.Stderr
+	err := cmd.Run()
+	if ctx.Err() != nil {
+		return false, failing
+	}
+
+	stillFailing := failedTests(testReportPath, previousReports, t.logger)
+	if err != nil && len(stillFailing) == 0 {
+		// the run failed without a failing testcase, like when the application didn't start, so the same testcases
+		// are run again
+		t.logger.Warn("the test run failed", zap.Error(err))
+		return false, failing
+	}
+	if len(failing) > 0 && len(stillFailing) == 0 {
+		t.logger.Info("the failing testcases pass now, all the testcases are run again on the next change")
+	}
+	return err == nil, stillFailing
+}
+
+// watchBuild runs the build command of the application, and returns whether it succeeded.
+func (t *tester) watchBuild(ctx context.Context, buildCmd string) bool {
+	if buildCmd == "" {
+		return true
+	}
+	t.logger.Info("rebuilding the application", zap.String("command", buildCmd))
+	cmd := exec.CommandContext(ctx, "sh", "-c", buildCmd)
+	cmd.Stdout, cmd.Stderr = os.Stdout, os.Stderr
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() == nil {
+			t.logger.Error("failed to rebuild the application, the testcases are run once it is fixed", zap.Error(err))
+		}
+		return false
+	}
+	return true
+}
+
+// failedTests returns the failing testcases of the reports written since the previous reports were listed, named
+// like test-set-0/test-3.
+func failedTests(testReportPath string, previous map[string]bool, logger *zap.Logger) []string {
+	reportFS := yaml.NewTestReportFS(logger)
+	var failed []string
+	for name := range reportNames(testReportPath) {
+		if previous[name] || !strings.HasPrefix(name, "report-") {
+			continue
+		}
+		doc, err := reportFS.Read(context.Background(), testReportPath, name)
+		if err != nil {
+			logger.Warn("failed to read the test report", zap.String("report", name), zap.Error(err))
+			continue
+		}
+		report, ok := doc.(*models.TestReport)
+		if !ok {
+			continue
+		}
+		for _, result := range report.Tests {
+			if result.Status == models.TestStatusFailed {
+				failed = append(failed, report.TestSet+"/"+result.TestCaseID)
+			}
+		}
+	}
+	sort.Strings(failed)
+	return failed
+}
+
+// watchSnapshot returns the modification time and the size of the watched files.
+func watchSnapshot(paths []string) map[string]string {
+	snapshot := map[string]string{}
+	for _, root := range paths {
+		filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+			if err != nil {
+				return nil
+			}
+			if entry.IsDir() {
+				if path != root && (skippedDirs[entry.Name()] || strings.HasPrefix(entry.Name(), ".")) {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			info, err := entry.Info()
+			if err != nil {
+				return nil
+			}
+			snapshot[path] = fmt.Sprintf("%d %d", info.ModTime().UnixNano(), info.Size())
+			return nil
+		})
+	}
+	return snapshot
+}
+
+func sameSnapshot(a, b map[string]string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for path, stamp := range a {
+		if b[path] != stamp {
+			return false
+		}
+	}
+	return true
+}
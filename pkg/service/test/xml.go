@@ -0,0 +1,268 @@
+package test
+
+import (
+	"encoding/xml"
+	"errors"
+	"io"
+	"sort"
+	"strings"
+)
+
+// xsiNamespace is the namespace of the xsi:type attributes, whose values are qualified names.
+const xsiNamespace = "http://www.w3.org/2001/XMLSchema-instance"
+
+// xmlNode is an element of an XML body in its canonical form. It is named by its namespace rather than by the prefix
+// it was written with, its attributes are sorted and don't hold the namespace declarations, and its text is trimmed
+// of the whitespaces around it, so that the bodies of the different serializers of a document are the same.
+type xmlNode struct {
+	name     xml.Name
+	attrs    []xml.Attr
+	text     string
+	children []*xmlNode
+}
+
+// isXMLBody reports whether the body of the response is an XML document, from its content type or its prolog.
+func isXMLBody(header map[string]string, body string) bool {
+	for name, value := range header {
+		if strings.EqualFold(name, "Content-Type") {
+			return strings.Contains(strings.ToLower(value), "xml")
+		}
+	}
+	return strings.HasPrefix(strings.TrimSpace(body), "<?xml")
+}
+
+// parseXML returns the root element of the XML document in its canonical form.
+func parseXML(body string) (*xmlNode, error) {
+	decoder := xml.NewDecoder(strings.NewReader(body))
+	// the bodies in another charset than utf-8 are compared as they were sent
+	decoder.CharsetReader = func(_ string, input io.Reader) (io.Reader, error) {
+		return input, nil
+	}
+	var (
+		root  *xmlNode
+		stack []*xmlNode
+		texts []*strings.Builder
+		// scopes holds the namespaces declared by each open element, by prefix
+		scopes []map[string]string
+	)
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			node := &xmlNode{name: t.Name}
+			scope := map[string]string{}
+			for _, attr := range t.Attr {
+				if attr.Name.Space == "xmlns" {
+					scope[attr.Name.Local] = attr.Value
+				} else if attr.Name.Space == "" && attr.Name.Local == "xmlns" {
+					scope[""] = attr.Value
+				}
+			}
+			scopes = append(scopes, scope)
+			for _, attr := range t.Attr {
+				if attr.Name.Space == "xmlns" || (attr.Name.Space == "" && attr.Name.Local == "xmlns") {
+					continue
+				}
+				if attr.Name.Space == xsiNamespace && attr.Name.Local == "type" {
+					attr.Value = resolveQName(attr.Value, scopes)
+				}
+				node.attrs = append(node.attrs, attr)
+			}
+			sort.Slice(node.attrs, func(i, j int) bool {
+				if node.attrs[i].Name.Space != node.attrs[j].Name.Space {
+					return node.attrs[i].Name.Space < node.attrs[j].Name.Space
+				}
+				return node.attrs[i].Name.Local < node.attrs[j].Name.Local
+			})
+			if len(stack) == 0 {
+				if root != nil {
+					return nil, errors.New("the XML document has several root elements")
+				}
+				root = node
+			} else {
+				parent := stack[len(stack)-1]
+				parent.children = append(parent.children, node)
+			}
+			stack = append(stack, node)
+			texts = append(texts, &strings.Builder{})
+		case xml.EndElement:
+			node := stack[len(stack)-1]
+			node.text = strings.TrimSpace(texts[len(texts)-1].String())
+			stack, texts, scopes = stack[:len(stack)-1], texts[:len(texts)-1], scopes[:len(scopes)-1]
+		case xml.CharData:
+			if len(texts) > 0 {
+				texts[len(texts)-1].Write(t)
+			}
+		}
+	}
+	if root == nil {
+		return nil, errors.New("the XML document has no root element")
+	}
+	return root, nil
+}
+
+// resolveQName returns the qualified name with its namespace in place of its prefix, e.g. {urn:quotes}Quote.
+func resolveQName(value string, scopes []map[string]string) string {
+	prefix, local := "", value
+	if colon := strings.IndexByte(value, ':'); colon >= 0 {
+		prefix, local = value[:colon], value[colon+1:]
+	}
+	for i := len(scopes) - 1; i >= 0; i-- {
+		if space, ok := scopes[i][prefix]; ok {
+			return "{" + space + "}" + local
+		}
+	}
+	return value
+}
+
+// MatchXML compares the XML bodies on their canonical form, the nodes selected by the XPath expressions of the noise
+// being ignored. It returns the canonical bodies, which are printed in the diffs. The bodies which aren't both XML
+// documents are compared as they are.
+func MatchXML(exp, act string, noise map[string][]string) (string, string, bool, error) {
+	paths, err := newXPathNoise(noise)
+	if err != nil {
+		return exp, act, false, err
+	}
+	expected, err := parseXML(exp)
+	if err != nil {
+		return exp, act, exp == act, nil
+	}
+	actual, err := parseXML(act)
+	if err != nil {
+		return exp, act, exp == act, nil
+	}
+	root := []xmlStep{{name: expected.name.Local, index: 1, position: 1}}
+	if _, isNoisy := paths.match(root); expected.name != actual.name && !isNoisy {
+		return expected.String(), actual.String(), false, nil
+	}
+	return expected.String(), actual.String(), xmlMatch(root, expected, actual, paths), nil
+}
+
+// xmlMatch reports whether the elements at the path have the same attributes, text and children, the noisy nodes
+// aside.
+func xmlMatch(path []xmlStep, expected, actual *xmlNode, paths xPathNoise) bool {
+	regexArr, isNoisy := paths.match(path)
+	if isNoisy && len(regexArr) == 0 {
+		return true
+	}
+	if isNoisy {
+		isNoisy, _ = MatchesAnyRegex(expected.text, regexArr)
+	}
+	if expected.text != actual.text && !isNoisy {
+		return false
+	}
+
+	actualAttrs := map[xml.Name]string{}
+	for _, attr := range actual.attrs {
+		actualAttrs[attr.Name] = attr.Value
+	}
+	for _, attr := range expected.attrs {
+		attrPath := append(append([]xmlStep{}, path...), xmlStep{name: attr.Name.Local, attr: true})
+		regexArr, isNoisy := paths.match(attrPath)
+		value, ok := actualAttrs[attr.Name]
+		delete(actualAttrs, attr.Name)
+		if isNoisy && len(regexArr) == 0 {
+			continue
+		}
+		if !ok {
+			return false
+		}
+		if isNoisy {
+			isNoisy, _ = MatchesAnyRegex(attr.Value, regexArr)
+		}
+		if value != attr.Value && !isNoisy {
+			return false
+		}
+	}
+	// checks if there is an attribute which is only in the actual element
+	for name := range actualAttrs {
+		attrPath := append(append([]xmlStep{}, path...), xmlStep{name: name.Local, attr: true})
+		if regexArr, isNoisy := paths.match(attrPath); !isNoisy || len(regexArr) != 0 {
+			return false
+		}
+	}
+
+	// the elements are ordered, the noisy ones being ignored whether they are present or not
+	expChildren, expPaths := xmlChildren(path, expected, paths)
+	actChildren, _ := xmlChildren(path, actual, paths)
+	if len(expChildren) != len(actChildren) {
+		return false
+	}
+	for i := range expChildren {
+		if expChildren[i].name != actChildren[i].name {
+			return false
+		}
+		if !xmlMatch(expPaths[i], expChildren[i], actChildren[i], paths) {
+			return false
+		}
+	}
+	return true
+}
+
+// xmlChildren returns the children of the element along with their paths, without the ones ignored whatever their
+// value.
+func xmlChildren(path []xmlStep, node *xmlNode, paths xPathNoise) ([]*xmlNode, [][]xmlStep) {
+	var (
+		children []*xmlNode
+		steps    [][]xmlStep
+		indexes  = map[string]int{}
+	)
+	for i, child := range node.children {
+		indexes[child.name.Local]++
+		step := xmlStep{name: child.name.Local, index: indexes[child.name.Local], position: i + 1}
+		childPath := append(append([]xmlStep{}, path...), step)
+		if regexArr, isNoisy := paths.match(childPath); isNoisy && len(regexArr) == 0 {
+			continue
+		}
+		children = append(children, child)
+		steps = append(steps, childPath)
+	}
+	return children, steps
+}
+
+// String returns the indented canonical form of the element. The namespaces are declared as the default one where
+// they change, and the namespaced attributes are named by their namespace, e.g. {urn:quotes}id="1".
+func (n *xmlNode) String() string {
+	var b strings.Builder
+	n.write(&b, "", "")
+	return b.String()
+}
+
+func (n *xmlNode) write(b *strings.Builder, indent, parentSpace string) {
+	b.WriteString(indent + "<" + n.name.Local)
+	if n.name.Space != parentSpace {
+		b.WriteString(` xmlns="` + escapeXML(n.name.Space) + `"`)
+	}
+	for _, attr := range n.attrs {
+		name := attr.Name.Local
+		if attr.Name.Space != "" {
+			name = "{" + attr.Name.Space + "}" + name
+		}
+		b.WriteString(" " + name + `="` + escapeXML(attr.Value) + `"`)
+	}
+	if n.text == "" && len(n.children) == 0 {
+		b.WriteString("/>\n")
+		return
+	}
+	b.WriteString(">" + escapeXML(n.text))
+	if len(n.children) > 0 {
+		b.WriteString("\n")
+		for _, child := range n.children {
+			child.write(b, indent+"  ", n.name.Space)
+		}
+		b.WriteString(indent)
+	}
+	b.WriteString("</" + n.name.Local + ">\n")
+}
+
+func escapeXML(text string) string {
+	var b strings.Builder
+	_ = xml.EscapeText(&b, []byte(text))
+	return b.String()
+}
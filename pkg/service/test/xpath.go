@@ -0,0 +1,160 @@
+package test
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// xmlStep is an element, or an attribute when attr is set, on the path of an XML node. The index of an element is
+// its position, from 1, among its siblings of the same name, and its position the one among all its siblings.
+type xmlStep struct {
+	name     string
+	index    int
+	position int
+	attr     bool
+}
+
+type xPathTokenKind int
+
+const (
+	xPathChild xPathTokenKind = iota
+	xPathDescendant
+)
+
+type xPathToken struct {
+	kind xPathTokenKind
+	// name is the local name of the selected element or attribute, * selecting all of them
+	name  string
+	index int
+	attr  bool
+}
+
+// xPath is a compiled XPath expression. The supported subset is the absolute location paths made of the children
+// (/name), the descendants (//name), the wildcards (*), the positions ([1]) and the attributes (@name). The names are
+// compared without their prefix, the bodies of the different serializers not declaring the same ones.
+type xPath []xPathToken
+
+// isXPath reports whether the key of a noise map is an XPath expression rather than a dot-notation key.
+func isXPath(key string) bool {
+	return strings.HasPrefix(key, "/")
+}
+
+func parseXPath(expr string) (xPath, error) {
+	if !isXPath(expr) {
+		return nil, fmt.Errorf("the XPath %q doesn't start with /", expr)
+	}
+	var path xPath
+	rest := expr
+	for len(rest) > 0 {
+		if strings.HasPrefix(rest, "//") {
+			path = append(path, xPathToken{kind: xPathDescendant})
+			rest = rest[2:]
+		} else if rest[0] == '/' {
+			rest = rest[1:]
+		} else {
+			return nil, fmt.Errorf("the XPath %q is invalid at %q", expr, rest)
+		}
+		end := strings.IndexByte(rest, '/')
+		if end < 0 {
+			end = len(rest)
+		}
+		step := rest[:end]
+		rest = rest[end:]
+
+		token := xPathToken{kind: xPathChild}
+		if strings.HasPrefix(step, "@") {
+			token.attr = true
+			step = step[1:]
+		}
+		if open := strings.IndexByte(step, '['); open >= 0 {
+			if !strings.HasSuffix(step, "]") || token.attr {
+				return nil, fmt.Errorf("the XPath %q has an unsupported predicate in %q", expr, step)
+			}
+			index, err := strconv.Atoi(strings.TrimSpace(step[open+1 : len(step)-1]))
+			if err != nil || index < 1 {
+				return nil, fmt.Errorf("the XPath %q has an unsupported predicate in %q", expr, step)
+			}
+			token.index = index
+			step = step[:open]
+		}
+		if colon := strings.IndexByte(step, ':'); colon >= 0 {
+			step = step[colon+1:]
+		}
+		if step == "" {
+			return nil, fmt.Errorf("the XPath %q has an empty step", expr)
+		}
+		if strings.ContainsAny(step, "()=") {
+			return nil, fmt.Errorf("the XPath %q has an unsupported step %q", expr, step)
+		}
+		token.name = step
+		if token.attr && len(rest) > 0 {
+			return nil, fmt.Errorf("the XPath %q selects the children of an attribute", expr)
+		}
+		path = append(path, token)
+	}
+	return path, nil
+}
+
+// matches reports whether the XPath selects the node at the path.
+func (p xPath) matches(path []xmlStep) bool {
+	if len(p) == 0 {
+		return len(path) == 0
+	}
+	token := p[0]
+	if token.kind == xPathDescendant {
+		for i := 0; i < len(path); i++ {
+			if p[1:].matches(path[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(path) == 0 {
+		return false
+	}
+	step := path[0]
+	if step.attr != token.attr || (token.name != "*" && token.name != step.name) {
+		return false
+	}
+	// the positions of the wildcards count all the elements
+	if token.index != 0 && ((token.name == "*" && token.index != step.position) || (token.name != "*" && token.index != step.index)) {
+		return false
+	}
+	return p[1:].matches(path[1:])
+}
+
+// xPathField is a noisy node selected through an XPath expression, along with the regexes of its values.
+type xPathField struct {
+	path    xPath
+	regexes []string
+}
+
+// xPathNoise holds the XPath expressions of a noise map.
+type xPathNoise []xPathField
+
+func newXPathNoise(noise map[string][]string) (xPathNoise, error) {
+	paths := xPathNoise{}
+	for key, regexes := range noise {
+		if !isXPath(key) {
+			continue
+		}
+		path, err := parseXPath(key)
+		if err != nil {
+			return nil, err
+		}
+		paths = append(paths, xPathField{path: path, regexes: regexes})
+	}
+	return paths, nil
+}
+
+// match returns the regexes of the values of the noisy node at the path, the node being noisy whatever its value
+// when there are none.
+func (n xPathNoise) match(path []xmlStep) ([]string, bool) {
+	for _, field := range n {
+		if field.path.matches(path) {
+			return field.regexes, true
+		}
+	}
+	return []string{}, false
+}
@@ -0,0 +1,21 @@
+package testset
+
+type TestSets interface {
+	// List returns the test sets along with their number of testcases and mocks, and the status of their last run.
+	List(path string) ([]TestSetInfo, error)
+	// Remove deletes the test set.
+	Remove(path, testSet string) error
+	// Move renames the test set, along with its name in the test reports.
+	Move(path, from, to string) error
+	// Copy copies the test set into a new one.
+	Copy(path, from, to string) error
+}
+
+// TestSetInfo describes a test set.
+type TestSetInfo struct {
+	Name      string `json:"name"`
+	Testcases int    `json:"testcases"`
+	Mocks     int    `json:"mocks"`
+	// LastRun is the status of the last test report of the test set, empty when it hasn't been run
+	LastRun string `json:"lastRun,omitempty"`
+}
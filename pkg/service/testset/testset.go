@@ -0,0 +1,244 @@
+package testset
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"go.keploy.io/server/pkg/models"
+	"go.keploy.io/server/pkg/platform/yaml"
+	"go.uber.org/zap"
+)
+
+const reportsDir = "testReports"
+
+// testSetName is the name of the directories which are run as test sets
+var testSetName = regexp.MustCompile(fmt.Sprintf(`^%s\d+$`, models.TestSetPattern))
+
+type testSets struct {
+	logger *zap.Logger
+}
+
+func NewTestSets(logger *zap.Logger) TestSets {
+	return &testSets{
+		logger: logger,
+	}
+}
+
+func (t *testSets) List(path string) ([]TestSetInfo, error) {
+	names, err := yaml.ReadSessionIndices(path, t.logger)
+	if err != nil {
+		return nil, err
+	}
+	// the test sets are listed in the order of their index rather than of their name
+	indexes := map[string]int{}
+	for _, name := range names {
+		indexes[name], _ = strconv.Atoi(strings.TrimPrefix(name, models.TestSetPattern))
+	}
+	sort.Slice(names, func(i, j int) bool { return indexes[names[i]] < indexes[names[j]] })
+
+	lastRuns, err := t.lastRuns(filepath.Join(path, reportsDir))
+	if err != nil {
+		return nil, err
+	}
+	var infos []TestSetInfo
+	for _, name := range names {
+		testcases, err := yaml.TestcaseNames(filepath.Join(path, name, "tests"))
+		if err != nil {
+			return nil, err
+		}
+		mocks, err := yaml.ReadDocs(filepath.Join(path, name), "mocks")
+		if err != nil && !os.IsNotExist(err) {
+			t.logger.Warn("failed to read the mocks of the test set", zap.String("testSet", name), zap.Error(err))
+		}
+		infos = append(infos, TestSetInfo{
+			Name:      name,
+			Testcases: len(testcases),
+			Mocks:     len(mocks),
+			LastRun:   lastRuns[name],
+		})
+	}
+	return infos, nil
+}
+
+func (t *testSets) Remove(path, testSet string) error {
+	if err := checkExists(path, testSet); err != nil {
+		return err
+	}
+	if err := os.RemoveAll(filepath.Join(path, testSet)); err != nil {
+		return fmt.Errorf("failed to remove the test set %s: %v", testSet, err)
+	}
+	t.logger.Info("removed the test set", zap.String("testSet", testSet))
+	return nil
+}
+
+func (t *testSets) Move(path, from, to string) error {
+	if err := checkExists(path, from); err != nil {
+		return err
+	}
+	if err := checkNew(path, to); err != nil {
+		return err
+	}
+	if err := os.Rename(filepath.Join(path, from), filepath.Join(path, to)); err != nil {
+		return fmt.Errorf("failed to rename the test set %s: %v", from, err)
+	}
+	// the reports keep following the test set, like the consumed mocks the prune of the mocks relies on
+	renamed, err := t.renameInReports(path, from, to)
+	if err != nil {
+		return err
+	}
+	t.logger.Info("renamed the test set", zap.String("from", from), zap.String("to", to), zap.Int("test reports updated", renamed))
+	return nil
+}
+
+func (t *testSets) Copy(path, from, to string) error {
+	if err := checkExists(path, from); err != nil {
+		return err
+	}
+	if err := checkNew(path, to); err != nil {
+		return err
+	}
+	if err := copyDir(filepath.Join(path, from), filepath.Join(path, to)); err != nil {
+		// a half copied test set would be run
+		os.RemoveAll(filepath.Join(path, to))
+		return fmt.Errorf("failed to copy the test set %s: %v", from, err)
+	}
+	t.logger.Info("copied the test set", zap.String("from", from), zap.String("to", to))
+	return nil
+}
+
+// renameInReports updates the test set of its test reports, and returns how many were updated.
+func (t *testSets) renameInReports(path, from, to string) (int, error) {
+	dir := filepath.Join(path, reportsDir)
+	reportFS := yaml.NewTestReportFS(t.logger)
+	renamed := 0
+	err := forEachReport(dir, reportFS, t.logger, func(report *models.TestReport) error {
+		if report.TestSet != from {
+			return nil
+		}
+		report.TestSet = to
+		for i := range report.Tests {
+			if report.Tests[i].TestCasePath == filepath.Join(path, from) {
+				report.Tests[i].TestCasePath = filepath.Join(path, to)
+			}
+		}
+		if err := reportFS.Write(context.Background(), dir, report); err != nil {
+			return fmt.Errorf("failed to update the test report %s: %v", report.Name, err)
+		}
+		renamed++
+		return nil
+	})
+	return renamed, err
+}
+
+// lastRuns returns the status of the last test report of each test set.
+func (t *testSets) lastRuns(dir string) (map[string]string, error) {
+	lastRuns := map[string]string{}
+	lastIndexes := map[string]int{}
+	err := forEachReport(dir, yaml.NewTestReportFS(t.logger), t.logger, func(report *models.TestReport) error {
+		index, err := strconv.Atoi(strings.TrimPrefix(report.Name, "report-"))
+		if err != nil {
+			return nil
+		}
+		if last, ok := lastIndexes[report.TestSet]; !ok || index > last {
+			lastIndexes[report.TestSet] = index
+			lastRuns[report.TestSet] = report.Status
+		}
+		return nil
+	})
+	return lastRuns, err
+}
+
+func forEachReport(dir string, reportFS *yaml.TestReport, logger *zap.Logger, f func(report *models.TestReport) error) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	for _, entry := range entries {
+		name := strings.TrimSuffix(entry.Name(), ".yaml")
+		if entry.IsDir() || name == entry.Name() {
+			continue
+		}
+		doc, err := reportFS.Read(context.Background(), dir, name)
+		if err != nil {
+			logger.Warn("failed to read the test report", zap.String("report", name), zap.Error(err))
+			continue
+		}
+		report, ok := doc.(*models.TestReport)
+		if !ok {
+			continue
+		}
+		if report.Name == "" {
+			report.Name = name
+		}
+		if err := f(report); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func checkExists(path, testSet string) error {
+	if !testSetName.MatchString(testSet) {
+		return fmt.Errorf("invalid test set name %q, the test sets are named like %s0", testSet, models.TestSetPattern)
+	}
+	info, err := os.Stat(filepath.Join(path, testSet))
+	if err != nil || !info.IsDir() {
+		return fmt.Errorf("the test set %s doesn't exist in %s", testSet, path)
+	}
+	return nil
+}
+
+// checkNew checks that the test set can be created, its name being one of the test sets which are run.
+func checkNew(path, testSet string) error {
+	if !testSetName.MatchString(testSet) {
+		return fmt.Errorf("invalid test set name %q, the test sets are named like %s0 to be run", testSet, models.TestSetPattern)
+	}
+	if _, err := os.Stat(filepath.Join(path, testSet)); err == nil {
+		return fmt.Errorf("the test set %s already exists", testSet)
+	}
+	return nil
+}
+
+func copyDir(src, dst string) error {
+	return filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+		if info.IsDir() {
+			return os.MkdirAll(target, 0777)
+		}
+		return copyFile(path, target, info.Mode())
+	})
+}
+
+func copyFile(src, dst string, mode os.FileMode) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_EXCL, mode)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
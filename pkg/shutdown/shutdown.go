@@ -0,0 +1,120 @@
+// Package shutdown coordinates the cleanups of keploy on its way out, whichever the path: the end of the command, the
+// interrupt signals, or a panic. The subsystems holding a state which must not outlive keploy, like the eBPF hooks,
+// the application container, the mocks being journaled or the report of the test run, register a cleanup when they
+// take it. The cleanups are run once, the last registered first.
+package shutdown
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// GracePeriod is how long keploy is left to stop on its own after an interrupt signal, before the cleanups are run
+// and it exits.
+const GracePeriod = 30 * time.Second
+
+type cleanup struct {
+	id   int
+	name string
+	run  func()
+}
+
+var (
+	mutex    sync.Mutex
+	nextID   int
+	cleanups []cleanup
+	// ran is set once the cleanups ran, the ones registered after being run at once
+	ran bool
+)
+
+// Register adds the cleanup, and returns the function removing it, for the subsystems released on the normal path.
+// The cleanups must be safe to run after the subsystem was released.
+func Register(name string, run func()) func() {
+	mutex.Lock()
+	if ran {
+		mutex.Unlock()
+		run()
+		return func() {}
+	}
+	nextID++
+	id := nextID
+	cleanups = append(cleanups, cleanup{id: id, name: name, run: run})
+	mutex.Unlock()
+	return func() {
+		mutex.Lock()
+		defer mutex.Unlock()
+		for i, c := range cleanups {
+			if c.id == id {
+				cleanups = append(cleanups[:i], cleanups[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+// Run runs the registered cleanups, the last registered first, the first time it's called. A cleanup which panics
+// is logged, the others still being run.
+func Run(logger *zap.Logger, reason string) {
+	mutex.Lock()
+	if ran {
+		mutex.Unlock()
+		return
+	}
+	ran = true
+	pending := cleanups
+	cleanups = nil
+	mutex.Unlock()
+
+	if len(pending) > 0 {
+		logger.Debug("running the cleanups of keploy", zap.String("reason", reason), zap.Int("cleanups", len(pending)))
+	}
+	for i := len(pending) - 1; i >= 0; i-- {
+		runCleanup(logger, pending[i])
+	}
+}
+
+func runCleanup(logger *zap.Logger, c cleanup) {
+	defer func() {
+		if r := recover(); r != nil {
+			logger.Error("the cleanup panicked", zap.String("cleanup", c.name), zap.Any("panic", r))
+		}
+	}()
+	c.run()
+}
+
+// Exit runs the cleanups and exits keploy with the code.
+func Exit(logger *zap.Logger, code int, reason string) {
+	Run(logger, reason)
+	os.Exit(code)
+}
+
+// OnPanic, deferred at the start of a goroutine, runs the cleanups before its panic goes on.
+func OnPanic(logger *zap.Logger) {
+	if r := recover(); r != nil {
+		Run(logger, fmt.Sprintf("panic: %v", r))
+		panic(r)
+	}
+}
+
+// Listen watches the interrupt signals, which the commands handle themselves to stop gracefully. When keploy is still
+// running GracePeriod after the first one, or when another one comes, the cleanups are run and keploy exits.
+func Listen(logger *zap.Logger) {
+	signals := make(chan os.Signal, 2)
+	signal.Notify(signals, os.Interrupt, syscall.SIGHUP, syscall.SIGQUIT, syscall.SIGTERM)
+	go func() {
+		sig := <-signals
+		select {
+		case again := <-signals:
+			logger.Warn("interrupted again, cleaning up and exiting keploy", zap.String("signal", again.String()))
+		case <-time.After(GracePeriod):
+			logger.Warn("keploy didn't stop after the interrupt, cleaning up and exiting", zap.String("signal", sig.String()), zap.Duration("gracePeriod", GracePeriod))
+		}
+		Exit(logger, 1, "signal")
+	}()
+}
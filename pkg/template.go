@@ -0,0 +1,86 @@
+package pkg
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"go.keploy.io/server/pkg/models"
+)
+
+// JsonLeaves returns the string and number values of a JSON body along with their paths, like body.data.items.0.id.
+// It returns nil when the body isn't JSON.
+func JsonLeaves(body string) map[string]string {
+	value, ok := decodeJson(body)
+	if !ok {
+		return nil
+	}
+	leaves := map[string]string{}
+	addLeaves("body", value, leaves)
+	return leaves
+}
+
+func addLeaves(path string, value interface{}, leaves map[string]string) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, child := range v {
+			addLeaves(path+"."+key, child, leaves)
+		}
+	case []interface{}:
+		for i, child := range v {
+			addLeaves(path+"."+strconv.Itoa(i), child, leaves)
+		}
+	case string:
+		leaves[path] = v
+	case json.Number:
+		leaves[path] = v.String()
+	}
+}
+
+// LookupResponseValue returns the value at the path of a response, either header.<name> or a path of its JSON body.
+func LookupResponseValue(resp *models.HttpResp, path string) (string, bool) {
+	if strings.HasPrefix(path, "header.") {
+		value := ToHttpHeader(resp.Header).Get(strings.TrimPrefix(path, "header."))
+		return value, value != ""
+	}
+	value, ok := JsonLeaves(resp.Body)[path]
+	return value, ok
+}
+
+// RenderRequest replaces the {{.name}} references of the url, the headers and the body of the request with the
+// values of the variables.
+func RenderRequest(req *models.HttpReq, values map[string]string) error {
+	render := func(text string) (string, error) {
+		if !strings.Contains(text, "{{") {
+			return text, nil
+		}
+		tmpl, err := template.New("request").Option("missingkey=error").Parse(text)
+		if err != nil {
+			return "", err
+		}
+		var buf bytes.Buffer
+		if err := tmpl.Execute(&buf, values); err != nil {
+			return "", err
+		}
+		return buf.String(), nil
+	}
+
+	var err error
+	if req.URL, err = render(req.URL); err != nil {
+		return fmt.Errorf("failed to render the url: %v", err)
+	}
+	header := make(map[string]string, len(req.Header))
+	for key, value := range req.Header {
+		if header[key], err = render(value); err != nil {
+			return fmt.Errorf("failed to render the header %s: %v", key, err)
+		}
+	}
+	req.Header = header
+	if req.Body, err = render(req.Body); err != nil {
+		return fmt.Errorf("failed to render the body: %v", err)
+	}
+	return nil
+}
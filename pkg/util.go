@@ -12,6 +12,7 @@ import (
 
 	"github.com/araddon/dateparse"
 	"go.keploy.io/server/pkg/models"
+	"go.keploy.io/server/pkg/proxy/integrations/protohttp"
 	"go.uber.org/zap"
 )
 
@@ -63,7 +64,8 @@ func SimulateHttp(tc models.TestCase, testSet string, logger *zap.Logger, apiTim
 	resp := &models.HttpResp{}
 
 	logger.Info("starting test for of", zap.Any("test case", models.HighlightString(tc.Name)), zap.Any("test set", models.HighlightString(testSet)))
-	req, err := http.NewRequest(string(tc.HttpReq.Method), tc.HttpReq.URL, bytes.NewBufferString(tc.HttpReq.Body))
+	reqBody := protohttp.RequestBody(tc.HttpReq)
+	req, err := http.NewRequest(string(tc.HttpReq.Method), tc.HttpReq.URL, bytes.NewReader(reqBody))
 	if err != nil {
 		logger.Error("failed to create a http request from the yaml document", zap.Error(err))
 		return nil, err
@@ -112,7 +114,18 @@ func SimulateHttp(tc models.TestCase, testSet string, logger *zap.Logger, apiTim
 		}
 	}
 
-	httpResp, errHttpReq := client.Do(req)
+	var (
+		httpResp   *http.Response
+		errHttpReq error
+	)
+	if len(tc.HttpReq.HeaderOrder) > 0 {
+		// net/http sorts and canonicalises the headers it sends, the requests recorded with the header fidelity are
+		// written with the recorded order and case instead
+		logger.Debug("simulating request with the recorded order of its headers")
+		httpResp, errHttpReq = sendOrderedRequest(req, reqBody, tc.HttpReq.HeaderOrder, time.Second*time.Duration(apiTimeout))
+	} else {
+		httpResp, errHttpReq = client.Do(req)
+	}
 	if httpResp != nil {
 		// Cases covered, non-nil httpResp with non-nil errHttpReq and non-nil httpResp
 		// with nil errHttpReq
@@ -124,7 +137,7 @@ func SimulateHttp(tc models.TestCase, testSet string, logger *zap.Logger, apiTim
 
 		resp = &models.HttpResp{
 			StatusCode: httpResp.StatusCode,
-			Body:       string(respBody),
+			Body:       protohttp.ResponseBody(respBody, httpResp.Header.Get("Content-Type"), tc.HttpReq),
 			Header:     ToYamlHttpHeader(httpResp.Header),
 		}
 	} else if errHttpReq != nil {